@@ -23,6 +23,7 @@ require (
 	github.com/gookit/color v1.5.4
 	github.com/gorilla/websocket v1.5.3
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51
+	github.com/klauspost/compress v1.17.11
 	github.com/matthewhartstonge/argon2 v1.0.3
 	github.com/pion/ice/v2 v2.3.24
 	github.com/pion/interceptor v0.1.37
@@ -2,13 +2,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -22,6 +26,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/httpp"
+	"github.com/bluenviron/mediamtx/internal/protocols/websocket"
 	"github.com/bluenviron/mediamtx/internal/recordstore"
 	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
 	"github.com/bluenviron/mediamtx/internal/servers/hls"
@@ -31,6 +36,11 @@ import (
 	"github.com/bluenviron/mediamtx/internal/servers/webrtc"
 )
 
+const (
+	// number of log lines that are buffered for each log tail client.
+	logTailBufferSize = 256
+)
+
 func interfaceIsEmpty(i interface{}) bool {
 	return reflect.ValueOf(i).Kind() != reflect.Ptr || reflect.ValueOf(i).IsNil()
 }
@@ -81,6 +91,16 @@ func recordingsOfPath(
 type PathManager interface {
 	APIPathsList() (*defs.APIPathList, error)
 	APIPathsGet(string) (*defs.APIPath, error)
+	APIPathsEnable(string) error
+	APIPathsDisable(string) error
+	APIPathsRecordStart(string) error
+	APIPathsRecordStop(string) error
+	APIPathsRecordRotate(string) error
+	APIPathsEvents(string) (*defs.APIPathEventList, error)
+	APIPathsHistory(string, defs.APIPathHistoryMetric) (*defs.APIPathHistory, error)
+	APIPathsReaderHealth(string) (*defs.APIPathReaderHealth, error)
+	APIPathsCounters(string, bool) (*defs.APIPathCounters, error)
+	APIPathsConf(string) (*conf.Path, error)
 }
 
 // HLSServer contains methods used by the API and Metrics server.
@@ -103,6 +123,7 @@ type RTMPServer interface {
 	APIConnsList() (*defs.APIRTMPConnList, error)
 	APIConnsGet(uuid.UUID) (*defs.APIRTMPConn, error)
 	APIConnsKick(uuid.UUID) error
+	APIWaitGroupSize() int
 }
 
 // SRTServer contains methods used by the API and Metrics server.
@@ -110,6 +131,9 @@ type SRTServer interface {
 	APIConnsList() (*defs.APISRTConnList, error)
 	APIConnsGet(uuid.UUID) (*defs.APISRTConn, error)
 	APIConnsKick(uuid.UUID) error
+	APIIPsList() (*defs.APISRTIPList, error)
+	APIListenerStats() (*defs.APISRTListenerStats, error)
+	APIWaitGroupSize() int
 }
 
 // WebRTCServer contains methods used by the API and Metrics server.
@@ -123,6 +147,11 @@ type apiAuthManager interface {
 	Authenticate(req *auth.Request) error
 }
 
+type apiLogger interface {
+	AddPathSink(pathName string, bufferSize int) *logger.PathSink
+	RemovePathSink(s *logger.PathSink)
+}
+
 type apiParent interface {
 	logger.Writer
 	APIConfigSet(conf *conf.Conf)
@@ -130,35 +159,52 @@ type apiParent interface {
 
 // API is an API server.
 type API struct {
-	Address        string
-	Encryption     bool
-	ServerKey      string
-	ServerCert     string
-	AllowOrigin    string
-	TrustedProxies conf.IPNetworks
-	ReadTimeout    conf.StringDuration
-	Conf           *conf.Conf
-	AuthManager    apiAuthManager
-	PathManager    PathManager
-	RTSPServer     RTSPServer
-	RTSPSServer    RTSPServer
-	RTMPServer     RTMPServer
-	RTMPSServer    RTMPServer
-	HLSServer      HLSServer
-	WebRTCServer   WebRTCServer
-	SRTServer      SRTServer
-	Parent         apiParent
-
-	httpServer *httpp.Server
-	mutex      sync.RWMutex
+	Address          string
+	Encryption       bool
+	ServerKey        string
+	ServerCert       string
+	AllowOrigins     []string
+	AllowCredentials bool
+	AuthUser         conf.Credential
+	AuthPass         conf.Credential
+	AuthToken        conf.Credential
+	TrustedProxies   conf.IPNetworks
+	ReadTimeout      conf.StringDuration
+	Conf             *conf.Conf
+	AuthManager      apiAuthManager
+	PathManager      PathManager
+	RTSPServer       RTSPServer
+	RTSPSServer      RTSPServer
+	RTMPServer       RTMPServer
+	RTMPSServer      RTMPServer
+	HLSServer        HLSServer
+	WebRTCServer     WebRTCServer
+	SRTServer        SRTServer
+	Logger           apiLogger
+	Parent           apiParent
+
+	ctx          context.Context
+	ctxCancel    func()
+	httpServer   *httpp.Server
+	mutex        sync.RWMutex
+	ready        bool
+	shuttingDown bool
+	logTails     sync.WaitGroup
 }
 
 // Initialize initializes API.
 func (a *API) Initialize() error {
+	a.ctx, a.ctxCancel = context.WithCancel(context.Background())
+
 	router := gin.New()
 	router.SetTrustedProxies(a.TrustedProxies.ToTrustedProxies()) //nolint:errcheck
 
 	router.Use(a.middlewareOrigin)
+
+	// probes do not require authentication
+	router.GET("/healthz", a.onHealthz)
+	router.GET("/readyz", a.onReadyz)
+
 	router.Use(a.middlewareAuth)
 
 	group := router.Group("/v3")
@@ -169,6 +215,9 @@ func (a *API) Initialize() error {
 	group.GET("/config/pathdefaults/get", a.onConfigPathDefaultsGet)
 	group.PATCH("/config/pathdefaults/patch", a.onConfigPathDefaultsPatch)
 
+	group.GET("/config/export", a.onConfigExport)
+	group.POST("/config/diff", a.onConfigDiff)
+
 	group.GET("/config/paths/list", a.onConfigPathsList)
 	group.GET("/config/paths/get/*name", a.onConfigPathsGet)
 	group.POST("/config/paths/add/*name", a.onConfigPathsAdd)
@@ -178,6 +227,21 @@ func (a *API) Initialize() error {
 
 	group.GET("/paths/list", a.onPathsList)
 	group.GET("/paths/get/*name", a.onPathsGet)
+	group.POST("/paths/enable/*name", a.onPathsEnable)
+	group.POST("/paths/disable/*name", a.onPathsDisable)
+	group.POST("/paths/record/start/*name", a.onPathsRecordStart)
+	group.POST("/paths/record/stop/*name", a.onPathsRecordStop)
+	group.POST("/paths/record/rotate/*name", a.onPathsRecordRotate)
+	group.GET("/paths/events/*name", a.onPathsEvents)
+	group.GET("/paths/history/*name", a.onPathsHistory)
+	group.GET("/paths/readerhealth/*name", a.onPathsReaderHealth)
+	group.GET("/paths/counters/*name", a.onPathsCounters)
+	group.GET("/paths/config/*name", a.onPathsConfig)
+	group.POST("/paths/signurl/*name", a.onPathsSignURL)
+
+	if !interfaceIsEmpty(a.Logger) {
+		group.GET("/paths/logtail/*name", a.onPathsLogTail)
+	}
 
 	if !interfaceIsEmpty(a.HLSServer) {
 		group.GET("/hlsmuxers/list", a.onHLSMuxersList)
@@ -222,8 +286,12 @@ func (a *API) Initialize() error {
 		group.GET("/srtconns/list", a.onSRTConnsList)
 		group.GET("/srtconns/get/:id", a.onSRTConnsGet)
 		group.POST("/srtconns/kick/:id", a.onSRTConnsKick)
+		group.GET("/srtconns/ips", a.onSRTConnsIPs)
+		group.GET("/srtlistener/stats", a.onSRTListenerStats)
 	}
 
+	group.GET("/debug/resources", a.onDebugResources)
+
 	group.GET("/recordings/list", a.onRecordingsList)
 	group.GET("/recordings/get/*name", a.onRecordingsGet)
 	group.DELETE("/recordings/deletesegment", a.onRecordingDeleteSegment)
@@ -242,6 +310,7 @@ func (a *API) Initialize() error {
 	}
 	err := a.httpServer.Initialize()
 	if err != nil {
+		a.ctxCancel()
 		return err
 	}
 
@@ -253,9 +322,32 @@ func (a *API) Initialize() error {
 // Close closes the API.
 func (a *API) Close() {
 	a.Log(logger.Info, "listener is closing")
+
+	// log tails are hijacked connections, that are not closed by the HTTP server.
+	a.mutex.Lock()
+	a.ctxCancel()
+	a.mutex.Unlock()
+	a.logTails.Wait()
+
 	a.httpServer.Close()
 }
 
+// SetReady sets whether the server is ready, and is called by Core
+// once all listeners have been opened.
+func (a *API) SetReady(ready bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.ready = ready
+}
+
+// SetShuttingDown is called by Core when the server is shutting down.
+func (a *API) SetShuttingDown() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.ready = false
+	a.shuttingDown = true
+}
+
 // Log implements logger.Writer.
 func (a *API) Log(level logger.Level, format string, args ...interface{}) {
 	a.Parent.Log(level, "[API] "+format, args...)
@@ -271,8 +363,19 @@ func (a *API) writeError(ctx *gin.Context, status int, err error) {
 	})
 }
 
+func (a *API) queryRedactKeys() []string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.Conf.APIQueryRedactKeys
+}
+
 func (a *API) middlewareOrigin(ctx *gin.Context) {
-	ctx.Header("Access-Control-Allow-Origin", a.AllowOrigin)
+	if len(a.AllowOrigins) != 1 || a.AllowOrigins[0] != "*" {
+		a.middlewareOriginList(ctx)
+		return
+	}
+
+	ctx.Header("Access-Control-Allow-Origin", "*")
 	ctx.Header("Access-Control-Allow-Credentials", "true")
 
 	// preflight requests
@@ -285,12 +388,86 @@ func (a *API) middlewareOrigin(ctx *gin.Context) {
 	}
 }
 
-func (a *API) middlewareAuth(ctx *gin.Context) {
-	err := a.AuthManager.Authenticate(&auth.Request{
+func (a *API) originIsAllowed(origin string) bool {
+	for _, o := range a.AllowOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// middlewareOriginList allows cross-origin requests from the origins in AllowOrigins only.
+// Requests from other origins are processed, but browsers don't expose their responses.
+func (a *API) middlewareOriginList(ctx *gin.Context) {
+	ctx.Header("Vary", "Origin")
+
+	origin := ctx.Request.Header.Get("Origin")
+	allowed := origin != "" && a.originIsAllowed(origin)
+
+	if allowed {
+		ctx.Header("Access-Control-Allow-Origin", origin)
+		if a.AllowCredentials {
+			ctx.Header("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	// preflight requests
+	if ctx.Request.Method == http.MethodOptions &&
+		ctx.Request.Header.Get("Access-Control-Request-Method") != "" {
+		if !allowed {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		ctx.Header("Access-Control-Allow-Methods", "OPTIONS, GET, POST, PATCH, DELETE")
+		ctx.Header("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		ctx.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+}
+
+// authenticateOwn checks requests against the credentials of the API,
+// that are independent from the ones of paths.
+func (a *API) authenticateOwn(r *http.Request) error {
+	if a.AuthToken != "" {
+		if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			if !a.AuthToken.Check(strings.TrimPrefix(h, "Bearer ")) {
+				return &auth.Error{Message: "invalid token"}
+			}
+			return nil
+		}
+	}
+
+	if a.AuthUser != "" {
+		if user, pass, ok := r.BasicAuth(); ok {
+			if !a.AuthUser.Check(user) || !a.AuthPass.Check(pass) {
+				return &auth.Error{Message: "invalid credentials"}
+			}
+			return nil
+		}
+	}
+
+	return &auth.Error{
+		Message:        "credentials not provided",
+		AskCredentials: a.AuthUser != "",
+	}
+}
+
+func (a *API) authenticate(ctx *gin.Context) error {
+	if a.AuthUser != "" || a.AuthToken != "" {
+		return a.authenticateOwn(ctx.Request)
+	}
+
+	return a.AuthManager.Authenticate(&auth.Request{
 		IP:          net.ParseIP(ctx.ClientIP()),
 		Action:      conf.AuthActionAPI,
 		HTTPRequest: ctx.Request,
 	})
+}
+
+func (a *API) middlewareAuth(ctx *gin.Context) {
+	err := a.authenticate(ctx)
 	if err != nil {
 		if err.(*auth.Error).AskCredentials { //nolint:errorlint
 			ctx.Header("WWW-Authenticate", `Basic realm="mediamtx"`)
@@ -306,6 +483,58 @@ func (a *API) middlewareAuth(ctx *gin.Context) {
 	}
 }
 
+func (a *API) onHealthz(ctx *gin.Context) {
+	a.mutex.RLock()
+	shuttingDown := a.shuttingDown
+	a.mutex.RUnlock()
+
+	if shuttingDown {
+		ctx.JSON(http.StatusServiceUnavailable, &defs.APIError{Error: "server is shutting down"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) onReadyz(ctx *gin.Context) {
+	err := a.checkReady()
+	if err != nil {
+		// errors are not logged since probes are periodic
+		ctx.JSON(http.StatusServiceUnavailable, &defs.APIError{Error: err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) checkReady() error {
+	a.mutex.RLock()
+	ready := a.ready
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	if !ready {
+		return fmt.Errorf("server is not ready")
+	}
+
+	if c.APIReadyCheckSources {
+		for _, name := range sortedKeys(c.Paths) {
+			pathConf := c.Paths[name]
+
+			if !pathConf.HasStaticSource() || pathConf.SourceOnDemand {
+				continue
+			}
+
+			data, err := a.PathManager.APIPathsGet(name)
+			if err != nil || !data.Ready {
+				return fmt.Errorf("source of path '%s' is not ready", name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (a *API) onConfigGlobalGet(ctx *gin.Context) {
 	a.mutex.RLock()
 	c := a.Conf
@@ -379,6 +608,46 @@ func (a *API) onConfigPathDefaultsPatch(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onConfigExport(ctx *gin.Context) {
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	byts, err := configExport(c, ctx.Query("redact") != "false")
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/yaml", byts)
+}
+
+func (a *API) onConfigDiff(ctx *gin.Context) {
+	byts, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	newConf, err := conf.Parse(byts)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	data, err := configDiff(c, newConf, ctx.Query("redact") != "false")
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
 func (a *API) onConfigPathsList(ctx *gin.Context) {
 	a.mutex.RLock()
 	c := a.Conf
@@ -614,6 +883,325 @@ func (a *API) onPathsGet(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, data)
 }
 
+func (a *API) onPathsEnable(ctx *gin.Context) {
+	a.onPathsRecord(ctx, a.PathManager.APIPathsEnable)
+}
+
+func (a *API) onPathsDisable(ctx *gin.Context) {
+	a.onPathsRecord(ctx, a.PathManager.APIPathsDisable)
+}
+
+func (a *API) onPathsRecordStart(ctx *gin.Context) {
+	a.onPathsRecord(ctx, a.PathManager.APIPathsRecordStart)
+}
+
+func (a *API) onPathsRecordStop(ctx *gin.Context) {
+	a.onPathsRecord(ctx, a.PathManager.APIPathsRecordStop)
+}
+
+func (a *API) onPathsRecordRotate(ctx *gin.Context) {
+	a.onPathsRecord(ctx, a.PathManager.APIPathsRecordRotate)
+}
+
+func (a *API) onPathsRecord(ctx *gin.Context, fn func(string) error) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	err := fn(pathName)
+	if err != nil {
+		switch {
+		case errors.Is(err, conf.ErrPathNotFound):
+			a.writeError(ctx, http.StatusNotFound, err)
+		case errors.Is(err, defs.ErrRecordingNotActive):
+			a.writeError(ctx, http.StatusBadRequest, err)
+		case errors.Is(err, defs.ErrRecordingEnabledByConf):
+			a.writeError(ctx, http.StatusConflict, err)
+		default:
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (a *API) onPathsSignURL(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	var req defs.APIPathSignURLReq
+	err := json.NewDecoder(ctx.Request.Body).Decode(&req)
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Action == "" {
+		req.Action = conf.AuthActionRead
+	}
+
+	if req.Duration <= 0 {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid duration"))
+		return
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	if c.AuthSignedURLSecret == "" {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("signed URLs are disabled, set 'authSignedURLSecret'"))
+		return
+	}
+
+	_, _, err = conf.FindPathConf(c.Paths, pathName)
+	if err != nil {
+		var nerr conf.PathNotConfiguredError
+		if errors.As(err, &nerr) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(req.Duration)).Truncate(time.Second)
+
+	token, err := auth.SignURL(c.AuthSignedURLSecret, auth.SignedURLClaims{
+		Path:   pathName,
+		Action: req.Action,
+		Expiry: expiresAt.Unix(),
+		IP:     req.IP,
+	})
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &defs.APIPathSignedURL{
+		Token:     token,
+		Query:     url.Values{auth.SignedURLParam: []string{token}}.Encode(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (a *API) onPathsConfig(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	p, err := a.PathManager.APIPathsConf(pathName)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	data, err := toJSONMap(p)
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if ctx.Query("redact") != "false" {
+		data = redactConfigValue("", data).(map[string]interface{})
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onPathsEvents(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	data, err := a.PathManager.APIPathsEvents(pathName)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onPathsHistory(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	metric := defs.APIPathHistoryMetric(ctx.Query("metric"))
+	switch metric {
+	case defs.APIPathHistoryMetricBitrate, defs.APIPathHistoryMetricRTT:
+	default:
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid metric '%s'", metric))
+		return
+	}
+
+	data, err := a.PathManager.APIPathsHistory(pathName, metric)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onPathsReaderHealth(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	data, err := a.PathManager.APIPathsReaderHealth(pathName)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onPathsCounters(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	var reset bool
+	switch ctx.Query("reset") {
+	case "", "false":
+	case "true":
+		reset = true
+	default:
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid reset value '%s'", ctx.Query("reset")))
+		return
+	}
+
+	data, err := a.PathManager.APIPathsCounters(pathName, reset)
+	if err != nil {
+		if errors.Is(err, conf.ErrPathNotFound) {
+			a.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			a.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onPathsLogTail(ctx *gin.Context) {
+	pathName, ok := paramName(ctx)
+	if !ok {
+		a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid name"))
+		return
+	}
+
+	a.mutex.RLock()
+	c := a.Conf
+	a.mutex.RUnlock()
+
+	_, _, err := conf.FindPathConf(c.Paths, pathName)
+	if err != nil {
+		a.writeError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	a.mutex.Lock()
+	if a.ctx.Err() != nil {
+		a.mutex.Unlock()
+		a.writeError(ctx, http.StatusServiceUnavailable, fmt.Errorf("terminated"))
+		return
+	}
+	a.logTails.Add(1)
+	a.mutex.Unlock()
+	defer a.logTails.Done()
+
+	wc, err := websocket.NewServerConn(ctx.Writer, ctx.Request)
+	if err != nil {
+		// the upgrader already wrote the response
+		return
+	}
+	defer wc.Close()
+
+	sink := a.Logger.AddPathSink(pathName, logTailBufferSize)
+	defer a.Logger.RemovePathSink(sink)
+
+	// reading is needed to process pongs and to detect when the client disconnects
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var in interface{}
+			err := wc.ReadJSON(&in)
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case line := <-sink.Lines():
+			err = wc.WriteText([]byte(line))
+			if err != nil {
+				return
+			}
+
+		case <-readErr:
+			return
+
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
+
 func (a *API) onRTSPConnsList(ctx *gin.Context) {
 	data, err := a.RTSPServer.APIConnsList()
 	if err != nil {
@@ -667,6 +1255,11 @@ func (a *API) onRTSPSessionsList(ctx *gin.Context) {
 	}
 	data.PageCount = pageCount
 
+	redactKeys := a.queryRedactKeys()
+	for _, item := range data.Items {
+		item.QueryParams = parseQueryParams(item.Query, redactKeys)
+	}
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -687,6 +1280,8 @@ func (a *API) onRTSPSessionsGet(ctx *gin.Context) {
 		return
 	}
 
+	data.QueryParams = parseQueryParams(data.Query, a.queryRedactKeys())
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -763,6 +1358,11 @@ func (a *API) onRTSPSSessionsList(ctx *gin.Context) {
 	}
 	data.PageCount = pageCount
 
+	redactKeys := a.queryRedactKeys()
+	for _, item := range data.Items {
+		item.QueryParams = parseQueryParams(item.Query, redactKeys)
+	}
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -783,6 +1383,8 @@ func (a *API) onRTSPSSessionsGet(ctx *gin.Context) {
 		return
 	}
 
+	data.QueryParams = parseQueryParams(data.Query, a.queryRedactKeys())
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -821,6 +1423,11 @@ func (a *API) onRTMPConnsList(ctx *gin.Context) {
 	}
 	data.PageCount = pageCount
 
+	redactKeys := a.queryRedactKeys()
+	for _, item := range data.Items {
+		item.QueryParams = parseQueryParams(item.Query, redactKeys)
+	}
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -841,6 +1448,8 @@ func (a *API) onRTMPConnsGet(ctx *gin.Context) {
 		return
 	}
 
+	data.QueryParams = parseQueryParams(data.Query, a.queryRedactKeys())
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -879,6 +1488,11 @@ func (a *API) onRTMPSConnsList(ctx *gin.Context) {
 	}
 	data.PageCount = pageCount
 
+	redactKeys := a.queryRedactKeys()
+	for _, item := range data.Items {
+		item.QueryParams = parseQueryParams(item.Query, redactKeys)
+	}
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -899,6 +1513,8 @@ func (a *API) onRTMPSConnsGet(ctx *gin.Context) {
 		return
 	}
 
+	data.QueryParams = parseQueryParams(data.Query, a.queryRedactKeys())
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -975,6 +1591,11 @@ func (a *API) onWebRTCSessionsList(ctx *gin.Context) {
 	}
 	data.PageCount = pageCount
 
+	redactKeys := a.queryRedactKeys()
+	for _, item := range data.Items {
+		item.QueryParams = parseQueryParams(item.Query, redactKeys)
+	}
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -995,6 +1616,8 @@ func (a *API) onWebRTCSessionsGet(ctx *gin.Context) {
 		return
 	}
 
+	data.QueryParams = parseQueryParams(data.Query, a.queryRedactKeys())
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -1033,6 +1656,11 @@ func (a *API) onSRTConnsList(ctx *gin.Context) {
 	}
 	data.PageCount = pageCount
 
+	redactKeys := a.queryRedactKeys()
+	for _, item := range data.Items {
+		item.QueryParams = parseQueryParams(item.Query, redactKeys)
+	}
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -1053,6 +1681,8 @@ func (a *API) onSRTConnsGet(ctx *gin.Context) {
 		return
 	}
 
+	data.QueryParams = parseQueryParams(data.Query, a.queryRedactKeys())
+
 	ctx.JSON(http.StatusOK, data)
 }
 
@@ -1076,6 +1706,134 @@ func (a *API) onSRTConnsKick(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+func (a *API) onSRTConnsIPs(ctx *gin.Context) {
+	data, err := a.SRTServer.APIIPsList()
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	data.ItemCount = len(data.Items)
+	pageCount, err := paginate(&data.Items, ctx.Query("itemsPerPage"), ctx.Query("page"))
+	if err != nil {
+		a.writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	data.PageCount = pageCount
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func (a *API) onSRTListenerStats(ctx *gin.Context) {
+	data, err := a.SRTServer.APIListenerStats()
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+// countOpenFiles returns the number of file descriptors opened by the process,
+// if the operating system allows to list them.
+func countOpenFiles() *int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return nil
+	}
+
+	// the descriptor used to read the directory is not counted
+	n := len(entries) - 1
+	return &n
+}
+
+func (a *API) countConns() (map[string]int, error) {
+	ret := make(map[string]int)
+
+	for prefix, s := range map[string]RTSPServer{"rtsp": a.RTSPServer, "rtsps": a.RTSPSServer} {
+		if interfaceIsEmpty(s) {
+			continue
+		}
+
+		conns, err := s.APIConnsList()
+		if err != nil {
+			return nil, err
+		}
+		ret[prefix+"conns"] = len(conns.Items)
+
+		sessions, err := s.APISessionsList()
+		if err != nil {
+			return nil, err
+		}
+		ret[prefix+"sessions"] = len(sessions.Items)
+	}
+
+	for prefix, s := range map[string]RTMPServer{"rtmp": a.RTMPServer, "rtmps": a.RTMPSServer} {
+		if interfaceIsEmpty(s) {
+			continue
+		}
+
+		conns, err := s.APIConnsList()
+		if err != nil {
+			return nil, err
+		}
+		ret[prefix+"conns"] = len(conns.Items)
+	}
+
+	if !interfaceIsEmpty(a.HLSServer) {
+		muxers, err := a.HLSServer.APIMuxersList()
+		if err != nil {
+			return nil, err
+		}
+		ret["hlsmuxers"] = len(muxers.Items)
+	}
+
+	if !interfaceIsEmpty(a.WebRTCServer) {
+		sessions, err := a.WebRTCServer.APISessionsList()
+		if err != nil {
+			return nil, err
+		}
+		ret["webrtcsessions"] = len(sessions.Items)
+	}
+
+	if !interfaceIsEmpty(a.SRTServer) {
+		conns, err := a.SRTServer.APIConnsList()
+		if err != nil {
+			return nil, err
+		}
+		ret["srtconns"] = len(conns.Items)
+	}
+
+	return ret, nil
+}
+
+func (a *API) onDebugResources(ctx *gin.Context) {
+	conns, err := a.countConns()
+	if err != nil {
+		a.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	data := &defs.APIDebugResources{
+		Goroutines: runtime.NumGoroutine(),
+		OpenFiles:  countOpenFiles(),
+		Conns:      conns,
+		WaitGroups: make(map[string]int),
+	}
+
+	if !interfaceIsEmpty(a.RTMPServer) {
+		data.WaitGroups["rtmp"] = a.RTMPServer.APIWaitGroupSize()
+	}
+	if !interfaceIsEmpty(a.RTMPSServer) {
+		data.WaitGroups["rtmps"] = a.RTMPSServer.APIWaitGroupSize()
+	}
+	if !interfaceIsEmpty(a.SRTServer) {
+		data.WaitGroups["srt"] = a.SRTServer.APIWaitGroupSize()
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
 func (a *API) onRecordingsList(ctx *gin.Context) {
 	a.mutex.RLock()
 	c := a.Conf
@@ -1142,22 +1900,31 @@ func (a *API) onRecordingDeleteSegment(ctx *gin.Context) {
 		return
 	}
 
-	pathFormat := recordstore.PathAddExtension(
-		strings.ReplaceAll(pathConf.RecordPath, "%path", pathName),
-		pathConf.RecordFormat,
-	)
+	pathFormat := recordstore.PathFormatOfPath(pathConf, pathName)
 
 	segmentPath := recordstore.Path{
-		Start: start,
+		Start:    start,
+		Location: pathConf.RecordLocation(),
 	}.Encode(pathFormat)
 
-	err = os.Remove(segmentPath)
+	_, err = os.Stat(segmentPath)
 	if err != nil {
 		a.writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	ctx.Status(http.StatusOK)
+	// the segment is removed through RemoveSegment() since its initialization file
+	// may be shared with the following segments.
+	segments, _ := recordstore.FindSegments(pathConf, pathName)
+	for i, seg := range segments {
+		if seg.Start.Equal(start) {
+			recordstore.RemoveSegment(segments, i)
+			ctx.Status(http.StatusOK)
+			return
+		}
+	}
+
+	a.writeError(ctx, http.StatusBadRequest, fmt.Errorf("segment not found"))
 }
 
 // ReloadConf is called by core.
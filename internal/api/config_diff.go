@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+// global fields that are applied without closing any component.
+var configHotReloadableGlobalFields = map[string]struct{}{
+	"logLevel":             {},
+	"logDestinations":      {},
+	"logFile":              {},
+	"authInternalUsers":    {},
+	"authSignedURLSecret":  {},
+	"apiQueryRedactKeys":   {},
+	"apiReadyCheckSources": {},
+	"profiles":             {},
+}
+
+// path fields that are applied without recreating paths.
+// It must be kept in sync with pathConfCanBeUpdated().
+var configHotReloadablePathFields = map[string]struct{}{
+	"record":                 {},
+	"recordSchedule":         {},
+	"rpiCameraBrightness":    {},
+	"rpiCameraContrast":      {},
+	"rpiCameraSaturation":    {},
+	"rpiCameraSharpness":     {},
+	"rpiCameraExposure":      {},
+	"rpiCameraFlickerPeriod": {},
+	"rpiCameraAWB":           {},
+	"rpiCameraAWBGains":      {},
+	"rpiCameraDenoise":       {},
+	"rpiCameraShutter":       {},
+	"rpiCameraMetering":      {},
+	"rpiCameraGain":          {},
+	"rpiCameraEV":            {},
+	"rpiCameraFPS":           {},
+	"rpiCameraIDRPeriod":     {},
+	"rpiCameraBitrate":       {},
+}
+
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	byts, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret map[string]interface{}
+	err = json.Unmarshal(byts, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func configDiffFields(
+	oldVal interface{},
+	newVal interface{},
+	hotReloadable map[string]struct{},
+	redact bool,
+) ([]*defs.APIConfigDiffField, error) {
+	oldFields, err := toJSONMap(oldVal)
+	if err != nil {
+		return nil, err
+	}
+
+	newFields, err := toJSONMap(newVal)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range oldFields {
+		keys = append(keys, key)
+	}
+	for key := range newFields {
+		if _, ok := oldFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	ret := []*defs.APIConfigDiffField{}
+
+	for _, key := range keys {
+		o := oldFields[key]
+		n := newFields[key]
+
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+
+		if redact {
+			o = redactConfigValue(key, o)
+			n = redactConfigValue(key, n)
+		}
+
+		_, ok := hotReloadable[key]
+
+		ret = append(ret, &defs.APIConfigDiffField{
+			Name:            key,
+			Old:             o,
+			New:             n,
+			RestartRequired: !ok,
+		})
+	}
+
+	return ret, nil
+}
+
+func configDiffRestartRequired(fields []*defs.APIConfigDiffField) bool {
+	for _, f := range fields {
+		if f.RestartRequired {
+			return true
+		}
+	}
+	return false
+}
+
+// configDiff computes the difference between two configurations.
+// A change requires a restart when it causes the closure of a component
+// (a listener, a server) or the recreation of paths, disconnecting clients.
+func configDiff(oldConf *conf.Conf, newConf *conf.Conf, redact bool) (*defs.APIConfigDiff, error) {
+	global, err := configDiffFields(oldConf.Global(), newConf.Global(), configHotReloadableGlobalFields, redact)
+	if err != nil {
+		return nil, err
+	}
+
+	pathDefaults, err := configDiffFields(oldConf.PathDefaults, newConf.PathDefaults,
+		configHotReloadablePathFields, redact)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &defs.APIConfigDiff{
+		RestartRequired: configDiffRestartRequired(global),
+		Global:          global,
+		PathDefaults:    pathDefaults,
+		Paths:           []*defs.APIConfigDiffPath{},
+	}
+
+	names := sortedKeys(oldConf.Paths)
+	for _, name := range sortedKeys(newConf.Paths) {
+		if _, ok := oldConf.Paths[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldPath, oldOk := oldConf.Paths[name]
+		newPath, newOk := newConf.Paths[name]
+
+		var p *defs.APIConfigDiffPath
+
+		switch {
+		case !oldOk:
+			p = &defs.APIConfigDiffPath{
+				Name:   name,
+				Change: defs.APIConfigDiffPathChangeAdded,
+				Fields: []*defs.APIConfigDiffField{},
+			}
+
+		case !newOk:
+			p = &defs.APIConfigDiffPath{
+				Name:            name,
+				Change:          defs.APIConfigDiffPathChangeRemoved,
+				Fields:          []*defs.APIConfigDiffField{},
+				RestartRequired: true,
+			}
+
+		default:
+			var fields []*defs.APIConfigDiffField
+			fields, err = configDiffFields(oldPath, newPath, configHotReloadablePathFields, redact)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(fields) == 0 {
+				continue
+			}
+
+			p = &defs.APIConfigDiffPath{
+				Name:            name,
+				Change:          defs.APIConfigDiffPathChangeChanged,
+				Fields:          fields,
+				RestartRequired: configDiffRestartRequired(fields),
+			}
+		}
+
+		if p.RestartRequired {
+			ret.RestartRequired = true
+		}
+
+		ret.Paths = append(ret.Paths, p)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// suffixes of keys whose values are secrets.
+var configSecretKeySuffixes = []string{
+	"pass",
+	"password",
+	"passphrase",
+	"passphrases",
+	"token",
+	"secret",
+}
+
+// keys whose values are maps of secrets.
+var configSecretMapKeys = []string{
+	"metricsOTLPHeaders",
+	"srtReadUserPassphrases",
+	"srtPublishUserPassphrases",
+}
+
+func isConfigSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range configSecretKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isConfigSecretMapKey(key string) bool {
+	for _, k := range configSecretMapKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// redactConfigURL replaces passwords contained in URLs.
+func redactConfigURL(v string) string {
+	if !strings.Contains(v, "://") {
+		return v
+	}
+
+	u, err := url.Parse(v)
+	if err != nil || u.User == nil {
+		return v
+	}
+
+	if _, ok := u.User.Password(); !ok {
+		return v
+	}
+
+	u.User = url.UserPassword(u.User.Username(), redactedQueryValue)
+	return u.String()
+}
+
+// redactConfigValue replaces secrets contained in a value
+// decoded from the JSON or YAML representation of the configuration.
+func redactConfigValue(key string, v interface{}) interface{} {
+	switch tv := v.(type) {
+	case string:
+		if tv != "" && isConfigSecretKey(key) {
+			return redactedQueryValue
+		}
+		return redactConfigURL(tv)
+
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(tv))
+		for k, v2 := range tv {
+			if isConfigSecretMapKey(key) {
+				ret[k] = redactedQueryValue
+			} else {
+				ret[k] = redactConfigValue(k, v2)
+			}
+		}
+		return ret
+
+	case yaml.MapSlice:
+		ret := make(yaml.MapSlice, len(tv))
+		for i, item := range tv {
+			k, _ := item.Key.(string)
+			ret[i].Key = item.Key
+			if isConfigSecretMapKey(key) {
+				ret[i].Value = redactedQueryValue
+			} else {
+				ret[i].Value = redactConfigValue(k, item.Value)
+			}
+		}
+		return ret
+
+	case []interface{}:
+		ret := make([]interface{}, len(tv))
+		for i, v2 := range tv {
+			ret[i] = redactConfigValue(key, v2)
+		}
+		return ret
+
+	default:
+		return v
+	}
+}
+
+// toMapSlice converts a value into a YAML map that preserves the order of fields.
+func toMapSlice(v interface{}) (yaml.MapSlice, error) {
+	byts, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML
+	var ret yaml.MapSlice
+	err = yaml.Unmarshal(byts, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// configExport returns the effective configuration in YAML format,
+// with path configurations resolved against path defaults.
+func configExport(c *conf.Conf, redact bool) ([]byte, error) {
+	out, err := toMapSlice(c.Global())
+	if err != nil {
+		return nil, err
+	}
+
+	pathDefaults, err := toMapSlice(c.PathDefaults)
+	if err != nil {
+		return nil, err
+	}
+
+	out = append(out, yaml.MapItem{Key: "pathDefaults", Value: pathDefaults})
+
+	paths := make(yaml.MapSlice, len(c.Paths))
+
+	for i, name := range sortedKeys(c.Paths) {
+		var pconf yaml.MapSlice
+		pconf, err = toMapSlice(c.Paths[name])
+		if err != nil {
+			return nil, err
+		}
+
+		paths[i] = yaml.MapItem{Key: name, Value: pconf}
+	}
+
+	out = append(out, yaml.MapItem{Key: "paths", Value: paths})
+
+	if redact {
+		out = redactConfigValue("", out).(yaml.MapSlice)
+	}
+
+	return yaml.Marshal(out)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redactedQueryValue = "REDACTED"
+
+// parseQueryParams parses a raw query into a map,
+// replacing values of parameters listed in redactKeys.
+func parseQueryParams(rawQuery string, redactKeys []string) map[string][]string {
+	ret := make(map[string][]string)
+
+	// in case of errors, parameters that were parsed correctly are kept
+	q, _ := url.ParseQuery(rawQuery)
+
+	for key, vals := range q {
+		if isRedactedQueryKey(key, redactKeys) {
+			redacted := make([]string, len(vals))
+			for i := range redacted {
+				redacted[i] = redactedQueryValue
+			}
+			vals = redacted
+		}
+		ret[key] = vals
+	}
+
+	return ret
+}
+
+func isRedactedQueryKey(key string, redactKeys []string) bool {
+	for _, k := range redactKeys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/auth"
+)
+
+func TestParseQueryParams(t *testing.T) {
+	params := parseQueryParams("key=val&pass=secret&key=val2&Token=abc&empty=",
+		[]string{"pass", "token"})
+
+	require.Equal(t, map[string][]string{
+		"key":   {"val", "val2"},
+		"pass":  {redactedQueryValue},
+		"Token": {redactedQueryValue},
+		"empty": {""},
+	}, params)
+
+	require.Equal(t, map[string][]string{}, parseQueryParams("", []string{"pass"}))
+}
+
+func TestParseQueryParamsDefaultRedactKeys(t *testing.T) {
+	cnf := tempConf(t, "")
+
+	// tokens of signed URLs are redacted by default
+	params := parseQueryParams(url.Values{auth.SignedURLParam: []string{"mytoken"}}.Encode(),
+		cnf.APIQueryRedactKeys)
+
+	require.Equal(t, map[string][]string{
+		auth.SignedURLParam: {redactedQueryValue},
+	}, params)
+}
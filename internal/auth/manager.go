@@ -73,6 +73,9 @@ type Request struct {
 
 	// HTTP only
 	HTTPRequest *http.Request
+
+	// authentication method that overrides Manager.Method (optional).
+	Method *conf.AuthMethod
 }
 
 // Error is a authentication error.
@@ -153,6 +156,7 @@ type Manager struct {
 	HTTPExclude     []conf.AuthInternalUserPermission
 	JWTJWKS         string
 	JWTClaimKey     string
+	SignedURLSecret string
 	ReadTimeout     time.Duration
 	RTSPAuthMethods []auth.ValidateMethod
 
@@ -169,6 +173,14 @@ func (m *Manager) ReloadInternalUsers(u []conf.AuthInternalUser) {
 	m.InternalUsers = u
 }
 
+// ReloadSignedURLSecret reloads SignedURLSecret.
+// Changing the secret revokes all signed URLs generated with the previous one.
+func (m *Manager) ReloadSignedURLSecret(s string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.SignedURLSecret = s
+}
+
 // Authenticate authenticates a request.
 func (m *Manager) Authenticate(req *Request) error {
 	var rtspAuthHeader headers.Authorization
@@ -198,9 +210,29 @@ func (m *Manager) Authenticate(req *Request) error {
 		}
 	}
 
+	m.mutex.RLock()
+	signedURLSecret := m.SignedURLSecret
+	m.mutex.RUnlock()
+
+	// signed URLs are accepted in lieu of credentials
+	if signedURLSecret != "" {
+		if token := signedURLToken(req.Query); token != "" {
+			err := verifySignedURL(signedURLSecret, token, req, time.Now())
+			if err != nil {
+				return &Error{Message: err.Error()}
+			}
+			return nil
+		}
+	}
+
+	method := m.Method
+	if req.Method != nil {
+		method = *req.Method
+	}
+
 	var err error
 
-	switch m.Method {
+	switch method {
 	case conf.AuthMethodInternal:
 		err = m.authenticateInternal(req, &rtspAuthHeader)
 
@@ -414,3 +414,256 @@ func TestAuthJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMethodOverride(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	jwk, err := jwkset.NewJWKFromKey(key, jwkset.JWKOptions{
+		Metadata: jwkset.JWKMetadataOptions{
+			KID: "test-key-id",
+		},
+	})
+	require.NoError(t, err)
+
+	jwkSet := jwkset.NewMemoryStorage()
+	err = jwkSet.KeyWrite(context.Background(), jwk)
+	require.NoError(t, err)
+
+	httpServ := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response, err2 := jwkSet.JSONPublic(r.Context())
+			if err2 != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(response)
+		}),
+	}
+
+	ln, err := net.Listen("tcp", "localhost:4567")
+	require.NoError(t, err)
+
+	go httpServ.Serve(ln)
+	defer httpServ.Shutdown(context.Background())
+
+	type customClaims struct {
+		jwt.RegisteredClaims
+		MediaMTXPermissions []conf.AuthInternalUserPermission `json:"my_permission_key"`
+	}
+
+	claims := customClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+		MediaMTXPermissions: []conf.AuthInternalUserPermission{{
+			Action: conf.AuthActionRead,
+			Path:   "mypath",
+		}},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header[jwkset.HeaderKID] = "test-key-id"
+	ss, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	m := Manager{
+		Method: conf.AuthMethodInternal,
+		InternalUsers: []conf.AuthInternalUser{{
+			User: "myuser",
+			Pass: "mypass",
+			Permissions: []conf.AuthInternalUserPermission{
+				{Action: conf.AuthActionPublish, Path: "mypath"},
+				{Action: conf.AuthActionRead, Path: "mypath"},
+			},
+		}},
+		JWTJWKS:     "http://localhost:4567/jwks",
+		JWTClaimKey: "my_permission_key",
+	}
+
+	jwtMethod := conf.AuthMethodJWT
+
+	err = m.Authenticate(&Request{
+		User:   "myuser",
+		Pass:   "mypass",
+		IP:     net.ParseIP("127.0.0.1"),
+		Action: conf.AuthActionPublish,
+		Path:   "mypath",
+	})
+	require.NoError(t, err)
+
+	err = m.Authenticate(&Request{
+		IP:       net.ParseIP("127.0.0.1"),
+		Action:   conf.AuthActionRead,
+		Path:     "mypath",
+		Protocol: ProtocolSRT,
+		Query:    "jwt=" + ss,
+		Method:   &jwtMethod,
+	})
+	require.NoError(t, err)
+
+	err = m.Authenticate(&Request{
+		IP:       net.ParseIP("127.0.0.1"),
+		Action:   conf.AuthActionRead,
+		Path:     "mypath",
+		Protocol: ProtocolSRT,
+		Query:    "jwt=" + ss,
+	})
+	require.Error(t, err)
+
+	err = m.Authenticate(&Request{
+		User:   "myuser",
+		Pass:   "mypass",
+		IP:     net.ParseIP("127.0.0.1"),
+		Action: conf.AuthActionRead,
+		Path:   "mypath",
+		Method: &jwtMethod,
+	})
+	require.Error(t, err)
+}
+
+func TestAuthSignedURL(t *testing.T) {
+	now := time.Now()
+
+	token, err := SignURL("0123456789abcdef", SignedURLClaims{
+		Path:   "mypath",
+		Action: conf.AuthActionRead,
+		Expiry: now.Add(time.Hour).Unix(),
+		IP:     "127.0.0.1",
+	})
+	require.NoError(t, err)
+
+	expired, err := SignURL("0123456789abcdef", SignedURLClaims{
+		Path:   "mypath",
+		Action: conf.AuthActionRead,
+		Expiry: now.Add(-time.Second).Unix(),
+	})
+	require.NoError(t, err)
+
+	_, err = SignURL("0123456789abcdef", SignedURLClaims{
+		Path:   "mypath",
+		Action: conf.AuthActionPublish,
+		Expiry: now.Add(time.Hour).Unix(),
+	})
+	require.EqualError(t, err, "unsupported action: publish")
+
+	for _, ca := range []struct {
+		name   string
+		secret string
+		token  string
+		action conf.AuthAction
+		path   string
+		ip     string
+		err    string
+	}{
+		{
+			"ok",
+			"0123456789abcdef",
+			token,
+			conf.AuthActionRead,
+			"mypath",
+			"127.0.0.1",
+			"",
+		},
+		{
+			"expired",
+			"0123456789abcdef",
+			expired,
+			conf.AuthActionRead,
+			"mypath",
+			"127.0.0.1",
+			"authentication failed: signed URL is expired",
+		},
+		{
+			"wrong path",
+			"0123456789abcdef",
+			token,
+			conf.AuthActionRead,
+			"otherpath",
+			"127.0.0.1",
+			"authentication failed: signed URL doesn't allow path",
+		},
+		{
+			"wrong action",
+			"0123456789abcdef",
+			token,
+			conf.AuthActionPublish,
+			"mypath",
+			"127.0.0.1",
+			"authentication failed: signed URL doesn't allow action",
+		},
+		{
+			"wrong ip",
+			"0123456789abcdef",
+			token,
+			conf.AuthActionRead,
+			"mypath",
+			"127.0.0.2",
+			"authentication failed: signed URL doesn't allow IP",
+		},
+		{
+			"rotated secret",
+			"fedcba9876543210",
+			token,
+			conf.AuthActionRead,
+			"mypath",
+			"127.0.0.1",
+			"authentication failed: invalid signed URL signature",
+		},
+		{
+			"tampered",
+			"0123456789abcdef",
+			"x" + token,
+			conf.AuthActionRead,
+			"mypath",
+			"127.0.0.1",
+			"authentication failed: invalid signed URL signature",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			m := Manager{
+				Method: conf.AuthMethodInternal,
+				InternalUsers: []conf.AuthInternalUser{
+					{
+						User: "any",
+						Permissions: []conf.AuthInternalUserPermission{{
+							Action: conf.AuthActionPublish,
+						}},
+					},
+				},
+			}
+			m.ReloadSignedURLSecret(ca.secret)
+
+			err := m.Authenticate(&Request{
+				IP:       net.ParseIP(ca.ip),
+				Action:   ca.action,
+				Path:     ca.path,
+				Protocol: ProtocolSRT,
+				Query:    url.Values{SignedURLParam: []string{ca.token}}.Encode(),
+			})
+			if ca.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, ca.err)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		m := Manager{
+			Method: conf.AuthMethodInternal,
+		}
+
+		err := m.Authenticate(&Request{
+			IP:     net.ParseIP("127.0.0.1"),
+			Action: conf.AuthActionRead,
+			Path:   "mypath",
+			Query:  url.Values{SignedURLParam: []string{token}}.Encode(),
+		})
+		require.Error(t, err)
+	})
+}
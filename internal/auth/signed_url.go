@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// SignedURLParam is the query parameter that contains the token of a signed URL.
+const SignedURLParam = "signedtoken"
+
+// SignedURLClaims are the claims of a signed URL.
+type SignedURLClaims struct {
+	Path   string          `json:"path"`
+	Action conf.AuthAction `json:"action"`
+	Expiry int64           `json:"exp"`
+	IP     string          `json:"ip,omitempty"`
+}
+
+func signedURLSignature(secret string, payload string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SignURL generates the token of a signed URL.
+func SignURL(secret string, claims SignedURLClaims) (string, error) {
+	if claims.Action != conf.AuthActionRead && claims.Action != conf.AuthActionPlayback {
+		return "", fmt.Errorf("unsupported action: %s", claims.Action)
+	}
+
+	if claims.IP != "" && net.ParseIP(claims.IP) == nil {
+		return "", fmt.Errorf("invalid IP: %s", claims.IP)
+	}
+
+	enc, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(enc)
+
+	return payload + "." + signedURLSignature(secret, payload), nil
+}
+
+func signedURLToken(rawQuery string) string {
+	v, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return ""
+	}
+	return v.Get(SignedURLParam)
+}
+
+func verifySignedURL(secret string, token string, req *Request, now time.Time) error {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("invalid signed URL")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(signedURLSignature(secret, payload))) {
+		return fmt.Errorf("invalid signed URL signature")
+	}
+
+	enc, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("invalid signed URL")
+	}
+
+	var claims SignedURLClaims
+	err = json.Unmarshal(enc, &claims)
+	if err != nil {
+		return fmt.Errorf("invalid signed URL")
+	}
+
+	if now.Unix() >= claims.Expiry {
+		return fmt.Errorf("signed URL is expired")
+	}
+
+	if claims.Action != req.Action ||
+		(claims.Action != conf.AuthActionRead && claims.Action != conf.AuthActionPlayback) {
+		return fmt.Errorf("signed URL doesn't allow action")
+	}
+
+	if claims.Path != req.Path {
+		return fmt.Errorf("signed URL doesn't allow path")
+	}
+
+	if claims.IP != "" && !net.ParseIP(claims.IP).Equal(req.IP) {
+		return fmt.Errorf("signed URL doesn't allow IP")
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package captions
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	cea608Rows = 15
+)
+
+// characters of the basic set that differ from ASCII.
+var cea608BasicChars = map[byte]rune{
+	0x2A: 'á',
+	0x5C: 'é',
+	0x5E: 'í',
+	0x5F: 'ó',
+	0x60: 'ú',
+	0x7B: 'ç',
+	0x7C: '÷',
+	0x7D: 'Ñ',
+	0x7E: 'ñ',
+	0x7F: '█',
+}
+
+// special characters, from 0x30 to 0x3F.
+var cea608SpecialChars = []rune("®°½¿™¢£♪à èâêîôû")
+
+// extended characters, from 0x20 to 0x3F.
+var (
+	cea608ExtendedChars1 = []rune("ÁÉÓÚÜü‘¡*’—©℠•“”ÀÂÇÈÊËëÎÏïÔÙùÛ«»")
+	cea608ExtendedChars2 = []rune("ÃãÍÌìÒòÕõ{}\\^_|~ÄäÖöß¥¤¦ÅåØø┌┐└┘")
+)
+
+// rows of preamble address codes, indexed by the first byte and by bit 5 of the second byte.
+var cea608PACRows = map[byte][2]int{
+	0x10: {11, 11},
+	0x11: {1, 2},
+	0x12: {3, 4},
+	0x13: {12, 13},
+	0x14: {14, 15},
+	0x15: {5, 6},
+	0x16: {7, 8},
+	0x17: {9, 10},
+}
+
+// Cue is a caption that is displayed in a time interval.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+type cea608Memory struct {
+	rows [cea608Rows][]rune
+}
+
+func (m *cea608Memory) clear() {
+	for i := range m.rows {
+		m.rows[i] = nil
+	}
+}
+
+func (m *cea608Memory) text() string {
+	var lines []string
+	for _, row := range m.rows {
+		if line := strings.TrimSpace(string(row)); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type cea608Mode int
+
+const (
+	cea608ModeNone cea608Mode = iota
+	cea608ModePopOn
+	cea608ModeRollUp
+	cea608ModePaintOn
+)
+
+// CEA608Decoder decodes CEA-608 captions of channel 1 (CC1) into cues.
+// Pop-on, roll-up and paint-on captions are supported, while text mode is ignored.
+type CEA608Decoder struct {
+	mode           cea608Mode
+	displayed      cea608Memory
+	nonDisplayed   cea608Memory
+	displayedStart time.Duration
+	row            int
+	rollUpRows     int
+	channel        int
+	lastCtrl       CEA608Pair
+	lastCtrlValid  bool
+}
+
+// Decode decodes a pair and returns cues that have been completed.
+func (d *CEA608Decoder) Decode(pair CEA608Pair, pts time.Duration) []*Cue {
+	b1 := pair[0] & 0x7F // remove parity bit
+	b2 := pair[1] & 0x7F
+
+	if b1 == 0 && b2 == 0 {
+		return nil
+	}
+
+	if b1 >= 0x10 && b1 <= 0x1F {
+		// control codes are usually sent twice
+		cur := CEA608Pair{b1, b2}
+		if d.lastCtrlValid && d.lastCtrl == cur {
+			d.lastCtrlValid = false
+			return nil
+		}
+		d.lastCtrl = cur
+		d.lastCtrlValid = true
+
+		if (b1 & 0x08) != 0 {
+			d.channel = 2
+		} else {
+			d.channel = 1
+		}
+
+		if d.channel != 1 {
+			return nil
+		}
+
+		return d.decodeControl(b1&^0x08, b2, pts)
+	}
+
+	d.lastCtrlValid = false
+
+	if d.channel == 2 || b1 < 0x20 {
+		return nil
+	}
+
+	d.writeChar(cea608BasicChar(b1), pts)
+	if b2 >= 0x20 {
+		d.writeChar(cea608BasicChar(b2), pts)
+	}
+
+	return nil
+}
+
+// Displayed returns the caption that is currently displayed and the time it appeared.
+func (d *CEA608Decoder) Displayed() (string, time.Duration) {
+	return d.displayed.text(), d.displayedStart
+}
+
+func cea608BasicChar(b byte) rune {
+	if r, ok := cea608BasicChars[b]; ok {
+		return r
+	}
+	return rune(b)
+}
+
+func (d *CEA608Decoder) target() *cea608Memory {
+	if d.mode == cea608ModePopOn {
+		return &d.nonDisplayed
+	}
+	return &d.displayed
+}
+
+func (d *CEA608Decoder) writeChar(r rune, pts time.Duration) {
+	if d.mode == cea608ModeNone {
+		return
+	}
+
+	if d.mode != cea608ModePopOn && d.displayed.text() == "" {
+		d.displayedStart = pts
+	}
+
+	m := d.target()
+	m.rows[d.row] = append(m.rows[d.row], r)
+}
+
+func (d *CEA608Decoder) endDisplayed(pts time.Duration) []*Cue {
+	text := d.displayed.text()
+	if text == "" {
+		return nil
+	}
+
+	return []*Cue{{
+		Start: d.displayedStart,
+		End:   pts,
+		Text:  text,
+	}}
+}
+
+func (d *CEA608Decoder) decodeControl(b1 byte, b2 byte, pts time.Duration) []*Cue {
+	switch {
+	// miscellaneous control codes
+	case b1 == 0x14 && b2 >= 0x20 && b2 <= 0x2F:
+		return d.decodeMiscControl(b2, pts)
+
+	// preamble address codes
+	case b2 >= 0x40 && b2 <= 0x7F:
+		rows := cea608PACRows[b1]
+		row := rows[(b2>>5)&0x01] - 1
+
+		if d.mode == cea608ModeRollUp && row != d.row {
+			// move the roll-up window to the new base row
+			m := &d.displayed
+			var moved cea608Memory
+			for i := 0; i < d.rollUpRows; i++ {
+				src := d.row - i
+				dst := row - i
+				if src >= 0 && dst >= 0 {
+					moved.rows[dst] = m.rows[src]
+				}
+			}
+			*m = moved
+		}
+
+		d.row = row
+
+	// mid-row codes
+	case b1 == 0x11 && b2 >= 0x20 && b2 <= 0x2F:
+		d.writeChar(' ', pts)
+
+	// special characters
+	case b1 == 0x11 && b2 >= 0x30 && b2 <= 0x3F:
+		d.writeChar(cea608SpecialChars[b2-0x30], pts)
+
+	// extended characters, that replace the previous character
+	case (b1 == 0x12 || b1 == 0x13) && b2 >= 0x20 && b2 <= 0x3F:
+		m := d.target()
+		if n := len(m.rows[d.row]); n > 0 {
+			m.rows[d.row] = m.rows[d.row][:n-1]
+		}
+
+		if b1 == 0x12 {
+			d.writeChar(cea608ExtendedChars1[b2-0x20], pts)
+		} else {
+			d.writeChar(cea608ExtendedChars2[b2-0x20], pts)
+		}
+	}
+
+	return nil
+}
+
+func (d *CEA608Decoder) decodeMiscControl(b2 byte, pts time.Duration) []*Cue {
+	switch b2 {
+	case 0x20: // resume caption loading
+		d.mode = cea608ModePopOn
+
+	case 0x21: // backspace
+		m := d.target()
+		if n := len(m.rows[d.row]); n > 0 {
+			m.rows[d.row] = m.rows[d.row][:n-1]
+		}
+
+	case 0x25, 0x26, 0x27: // roll-up captions
+		var cues []*Cue
+
+		if d.mode != cea608ModeRollUp {
+			cues = d.endDisplayed(pts)
+			d.displayed.clear()
+			d.nonDisplayed.clear()
+			d.row = cea608Rows - 1
+		}
+
+		d.mode = cea608ModeRollUp
+		d.rollUpRows = int(b2-0x25) + 2
+		return cues
+
+	case 0x29: // resume direct captioning
+		d.mode = cea608ModePaintOn
+
+	case 0x2A, 0x2B: // text restart, resume text display
+		d.mode = cea608ModeNone
+
+	case 0x2C: // erase displayed memory
+		cues := d.endDisplayed(pts)
+		d.displayed.clear()
+		return cues
+
+	case 0x2D: // carriage return
+		if d.mode != cea608ModeRollUp {
+			return nil
+		}
+
+		cues := d.endDisplayed(pts)
+
+		m := &d.displayed
+		top := d.row - d.rollUpRows + 1
+		for i := 0; i < cea608Rows; i++ {
+			switch {
+			case i < top || i > d.row:
+				m.rows[i] = nil
+			case i < d.row:
+				m.rows[i] = m.rows[i+1]
+			default:
+				m.rows[i] = nil
+			}
+		}
+
+		d.displayedStart = pts
+		return cues
+
+	case 0x2E: // erase non-displayed memory
+		d.nonDisplayed.clear()
+
+	case 0x2F: // end of caption
+		cues := d.endDisplayed(pts)
+		d.displayed, d.nonDisplayed = d.nonDisplayed, d.displayed
+		d.displayedStart = pts
+		d.mode = cea608ModePopOn
+		return cues
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package captions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func withParity(b byte) byte {
+	n := 0
+	for i := 0; i < 7; i++ {
+		if (b & (1 << i)) != 0 {
+			n++
+		}
+	}
+	if n%2 == 0 {
+		return b | 0x80
+	}
+	return b
+}
+
+func seiNALU(pairs []CEA608Pair) []byte {
+	payload := []byte{
+		0xB5,       // country code
+		0x00, 0x31, // provider code
+		'G', 'A', '9', '4',
+		0x03,                    // user data type code
+		0x40 | byte(len(pairs)), // process_cc_data_flag, cc_count
+		0xFF,                    // em_data
+	}
+	for _, pair := range pairs {
+		payload = append(payload, 0xFC, withParity(pair[0]), withParity(pair[1]))
+	}
+	payload = append(payload, 0xFF) // marker bits
+
+	nalu := []byte{0x06, 0x04, byte(len(payload))}
+	nalu = append(nalu, payload...)
+	return append(nalu, 0x80)
+}
+
+func decodeAll(d *CEA608Decoder, frames [][]CEA608Pair) []*Cue {
+	var cues []*Cue
+	for i, pairs := range frames {
+		au := [][]byte{{0x01}, seiNALU(pairs)}
+		for _, pair := range ExtractH264CEA608(au) {
+			cues = append(cues, d.Decode(pair, time.Duration(i)*time.Second)...)
+		}
+	}
+	return cues
+}
+
+func TestExtractH264CEA608(t *testing.T) {
+	pairs := ExtractH264CEA608([][]byte{
+		{0x05},
+		seiNALU([]CEA608Pair{{0x14, 0x20}, {'A', 'B'}}),
+	})
+	require.Equal(t, []CEA608Pair{
+		{withParity(0x14), withParity(0x20)},
+		{withParity('A'), withParity('B')},
+	}, pairs)
+}
+
+func TestCEA608DecoderPopOn(t *testing.T) {
+	var d CEA608Decoder
+
+	cues := decodeAll(&d, [][]CEA608Pair{
+		{{0x14, 0x20}, {0x14, 0x20}, {0x14, 0x70}, {0x14, 0x70}, {'H', 'e'}, {'l', 'l'}, {'o', 0}},
+		{{0x13, 0x70}, {'w', 'o'}, {'r', 'l'}, {'d', 0x7E}, {0x11, 0x37}},
+		{{0x14, 0x2F}, {0x14, 0x2F}},
+		{},
+		{{0x14, 0x2C}, {0x14, 0x2C}},
+	})
+
+	require.Equal(t, []*Cue{{
+		Start: 2 * time.Second,
+		End:   4 * time.Second,
+		Text:  "worldñ♪\nHello",
+	}}, cues)
+}
+
+func TestCEA608DecoderRollUp(t *testing.T) {
+	var d CEA608Decoder
+
+	cues := decodeAll(&d, [][]CEA608Pair{
+		{{0x14, 0x25}, {0x14, 0x25}, {'o', 'n'}, {'e', 0}},
+		{{0x14, 0x2D}, {0x14, 0x2D}, {'t', 'w'}, {'o', 0}},
+		{{0x14, 0x2D}, {0x14, 0x2D}, {'t', 'h'}, {'r', 'e'}, {'e', 0}},
+		{{0x14, 0x2C}, {0x14, 0x2C}},
+	})
+
+	require.Equal(t, []*Cue{
+		{Start: 0, End: 1 * time.Second, Text: "one"},
+		{Start: 1 * time.Second, End: 2 * time.Second, Text: "one\ntwo"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "two\nthree"},
+	}, cues)
+}
+
+func TestMarshalWebVTT(t *testing.T) {
+	buf := MarshalWebVTT([]*Cue{
+		{Start: 1500 * time.Millisecond, End: 3723004 * time.Millisecond, Text: "a < b\nc"},
+	})
+	require.Equal(t, "WEBVTT\n"+
+		"\n"+
+		"00:00:01.500 --> 01:02:03.004\n"+
+		"a &lt; b\n"+
+		"c\n", string(buf))
+}
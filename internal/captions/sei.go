@@ -0,0 +1,123 @@
+// Package captions contains functions to extract and convert closed captions.
+package captions
+
+import (
+	"bytes"
+
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+)
+
+const (
+	seiPayloadTypeUserDataRegistered = 4
+	itutT35CountryCodeUS             = 0xB5
+	atscProviderCode                 = 0x0031
+	ccDataUserDataTypeCode           = 0x03
+	ccTypeNTSCField1                 = 0
+)
+
+var atscUserIdentifier = []byte("GA94")
+
+// CEA608Pair is a pair of CEA-608 bytes.
+type CEA608Pair [2]byte
+
+func parseSEIValue(buf []byte, pos int) (int, int, bool) {
+	v := 0
+	for {
+		if pos >= len(buf) {
+			return 0, 0, false
+		}
+		b := buf[pos]
+		pos++
+		v += int(b)
+		if b != 0xFF {
+			return v, pos, true
+		}
+	}
+}
+
+// parseCCData parses the cc_data() structure defined in CEA-708
+// and returns CEA-608 pairs of field 1.
+func parseCCData(buf []byte) []CEA608Pair {
+	if len(buf) < 2 {
+		return nil
+	}
+
+	processCCDataFlag := (buf[0] & 0x40) != 0
+	if !processCCDataFlag {
+		return nil
+	}
+
+	ccCount := int(buf[0] & 0x1F)
+	buf = buf[2:] // skip em_data
+
+	var ret []CEA608Pair
+
+	for i := 0; i < ccCount && len(buf) >= 3; i++ {
+		ccValid := (buf[0] & 0x04) != 0
+		ccType := buf[0] & 0x03
+
+		if ccValid && ccType == ccTypeNTSCField1 {
+			ret = append(ret, CEA608Pair{buf[1], buf[2]})
+		}
+
+		buf = buf[3:]
+	}
+
+	return ret
+}
+
+// parseUserDataRegistered parses a user_data_registered_itu_t_t35 SEI payload.
+func parseUserDataRegistered(buf []byte) []CEA608Pair {
+	if len(buf) < 1 || buf[0] != itutT35CountryCodeUS {
+		return nil
+	}
+	buf = buf[1:]
+
+	if len(buf) < 7 ||
+		(uint16(buf[0])<<8|uint16(buf[1])) != atscProviderCode ||
+		!bytes.Equal(buf[2:6], atscUserIdentifier) ||
+		buf[6] != ccDataUserDataTypeCode {
+		return nil
+	}
+
+	return parseCCData(buf[7:])
+}
+
+// ExtractH264CEA608 extracts CEA-608 pairs of field 1
+// from the SEI NALUs of a H264 access unit.
+func ExtractH264CEA608(au [][]byte) []CEA608Pair {
+	var ret []CEA608Pair
+
+	for _, nalu := range au {
+		if len(nalu) < 2 || h264.NALUType(nalu[0]&0x1F) != h264.NALUTypeSEI {
+			continue
+		}
+
+		buf := h264.EmulationPreventionRemove(nalu[1:])
+		pos := 0
+
+		// stop at rbsp_trailing_bits
+		for pos < len(buf) && buf[pos] != 0x80 {
+			var typ, size int
+			var ok bool
+
+			typ, pos, ok = parseSEIValue(buf, pos)
+			if !ok {
+				break
+			}
+
+			size, pos, ok = parseSEIValue(buf, pos)
+			if !ok || (pos+size) > len(buf) {
+				break
+			}
+
+			if typ == seiPayloadTypeUserDataRegistered {
+				ret = append(ret, parseUserDataRegistered(buf[pos:pos+size])...)
+			}
+
+			pos += size
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,32 @@
+package captions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var webVTTEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func webVTTTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		ms/3600000,
+		(ms/60000)%60,
+		(ms/1000)%60,
+		ms%1000)
+}
+
+// MarshalWebVTT encodes cues in the WebVTT format.
+func MarshalWebVTT(cues []*Cue) []byte {
+	var b strings.Builder
+
+	b.WriteString("WEBVTT\n")
+
+	for _, cue := range cues {
+		b.WriteString("\n" + webVTTTimestamp(cue.Start) + " --> " + webVTTTimestamp(cue.End) + "\n")
+		b.WriteString(webVTTEscaper.Replace(cue.Text) + "\n")
+	}
+
+	return []byte(b.String())
+}
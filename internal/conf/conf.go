@@ -2,12 +2,14 @@
 package conf
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -94,6 +96,16 @@ func mustParseCIDR(v string) net.IPNet {
 	return *ne
 }
 
+// isValidOrigin checks whether v is an origin in the form scheme://host[:port].
+func isValidOrigin(v string) bool {
+	u, err := url.Parse(v)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.User == nil && u.Path == "" && u.RawQuery == "" && u.Fragment == ""
+}
+
 func anyPathHasDeprecatedCredentials(pathDefaults Path, paths map[string]*OptionalPath) bool {
 	if pathDefaults.PublishUser != nil ||
 		pathDefaults.PublishPass != nil ||
@@ -178,24 +190,36 @@ type Conf struct {
 	AuthHTTPExclude           AuthInternalUserPermissions `json:"authHTTPExclude"`
 	AuthJWTJWKS               string                      `json:"authJWTJWKS"`
 	AuthJWTClaimKey           string                      `json:"authJWTClaimKey"`
+	AuthSignedURLSecret       string                      `json:"authSignedURLSecret"`
 
 	// Control API
-	API               bool       `json:"api"`
-	APIAddress        string     `json:"apiAddress"`
-	APIEncryption     bool       `json:"apiEncryption"`
-	APIServerKey      string     `json:"apiServerKey"`
-	APIServerCert     string     `json:"apiServerCert"`
-	APIAllowOrigin    string     `json:"apiAllowOrigin"`
-	APITrustedProxies IPNetworks `json:"apiTrustedProxies"`
+	API                  bool       `json:"api"`
+	APIAddress           string     `json:"apiAddress"`
+	APIEncryption        bool       `json:"apiEncryption"`
+	APIServerKey         string     `json:"apiServerKey"`
+	APIServerCert        string     `json:"apiServerCert"`
+	APIAllowOrigin       *string    `json:"apiAllowOrigin,omitempty"` // deprecated
+	APIAllowOrigins      []string   `json:"apiAllowOrigins"`
+	APIAllowCredentials  bool       `json:"apiAllowCredentials"`
+	APIAuthUser          Credential `json:"apiAuthUser"`
+	APIAuthPass          Credential `json:"apiAuthPass"`
+	APIAuthToken         Credential `json:"apiAuthToken"`
+	APITrustedProxies    IPNetworks `json:"apiTrustedProxies"`
+	APIQueryRedactKeys   []string   `json:"apiQueryRedactKeys"`
+	APIReadyCheckSources bool       `json:"apiReadyCheckSources"`
 
 	// Metrics
-	Metrics               bool       `json:"metrics"`
-	MetricsAddress        string     `json:"metricsAddress"`
-	MetricsEncryption     bool       `json:"metricsEncryption"`
-	MetricsServerKey      string     `json:"metricsServerKey"`
-	MetricsServerCert     string     `json:"metricsServerCert"`
-	MetricsAllowOrigin    string     `json:"metricsAllowOrigin"`
-	MetricsTrustedProxies IPNetworks `json:"metricsTrustedProxies"`
+	Metrics               bool           `json:"metrics"`
+	MetricsAddress        string         `json:"metricsAddress"`
+	MetricsEncryption     bool           `json:"metricsEncryption"`
+	MetricsServerKey      string         `json:"metricsServerKey"`
+	MetricsServerCert     string         `json:"metricsServerCert"`
+	MetricsAllowOrigin    string         `json:"metricsAllowOrigin"`
+	MetricsTrustedProxies IPNetworks     `json:"metricsTrustedProxies"`
+	MetricsOTLP           bool           `json:"metricsOTLP"`
+	MetricsOTLPEndpoint   string         `json:"metricsOTLPEndpoint"`
+	MetricsOTLPHeaders    HTTPHeaders    `json:"metricsOTLPHeaders"`
+	MetricsOTLPInterval   StringDuration `json:"metricsOTLPInterval"`
 
 	// PPROF
 	PPROF               bool       `json:"pprof"`
@@ -242,48 +266,73 @@ type Conf struct {
 	RTMPServerCert string     `json:"rtmpServerCert"`
 
 	// HLS server
-	HLS                bool           `json:"hls"`
-	HLSDisable         *bool          `json:"hlsDisable,omitempty"` // deprecated
-	HLSAddress         string         `json:"hlsAddress"`
-	HLSEncryption      bool           `json:"hlsEncryption"`
-	HLSServerKey       string         `json:"hlsServerKey"`
-	HLSServerCert      string         `json:"hlsServerCert"`
-	HLSAllowOrigin     string         `json:"hlsAllowOrigin"`
-	HLSTrustedProxies  IPNetworks     `json:"hlsTrustedProxies"`
-	HLSAlwaysRemux     bool           `json:"hlsAlwaysRemux"`
-	HLSVariant         HLSVariant     `json:"hlsVariant"`
-	HLSSegmentCount    int            `json:"hlsSegmentCount"`
-	HLSSegmentDuration StringDuration `json:"hlsSegmentDuration"`
-	HLSPartDuration    StringDuration `json:"hlsPartDuration"`
-	HLSSegmentMaxSize  StringSize     `json:"hlsSegmentMaxSize"`
-	HLSDirectory       string         `json:"hlsDirectory"`
-	HLSMuxerCloseAfter StringDuration `json:"hlsMuxerCloseAfter"`
+	HLS                        bool           `json:"hls"`
+	HLSDisable                 *bool          `json:"hlsDisable,omitempty"` // deprecated
+	HLSAddress                 string         `json:"hlsAddress"`
+	HLSEncryption              bool           `json:"hlsEncryption"`
+	HLSServerKey               string         `json:"hlsServerKey"`
+	HLSServerCert              string         `json:"hlsServerCert"`
+	HLSAllowOrigin             string         `json:"hlsAllowOrigin"`
+	HLSTrustedProxies          IPNetworks     `json:"hlsTrustedProxies"`
+	HLSAlwaysRemux             bool           `json:"hlsAlwaysRemux"`
+	HLSVariant                 HLSVariant     `json:"hlsVariant"`
+	HLSSegmentCount            int            `json:"hlsSegmentCount"`
+	HLSSegmentDuration         StringDuration `json:"hlsSegmentDuration"`
+	HLSPartDuration            StringDuration `json:"hlsPartDuration"`
+	HLSSegmentMaxSize          StringSize     `json:"hlsSegmentMaxSize"`
+	HLSDirectory               string         `json:"hlsDirectory"`
+	HLSSegmentsToDisk          bool           `json:"hlsSegmentsToDisk"`
+	HLSSegmentsToDiskDirectory string         `json:"hlsSegmentsToDiskDirectory"`
+	HLSMuxerCloseAfter         StringDuration `json:"hlsMuxerCloseAfter"`
+
+	HLSMultivariantPlaylistCacheControl string `json:"hlsMultivariantPlaylistCacheControl"`
+	HLSMediaPlaylistCacheControl        string `json:"hlsMediaPlaylistCacheControl"`
+	HLSSegmentCacheControl              string `json:"hlsSegmentCacheControl"`
 
 	// WebRTC server
-	WebRTC                      bool             `json:"webrtc"`
-	WebRTCDisable               *bool            `json:"webrtcDisable,omitempty"` // deprecated
-	WebRTCAddress               string           `json:"webrtcAddress"`
-	WebRTCEncryption            bool             `json:"webrtcEncryption"`
-	WebRTCServerKey             string           `json:"webrtcServerKey"`
-	WebRTCServerCert            string           `json:"webrtcServerCert"`
-	WebRTCAllowOrigin           string           `json:"webrtcAllowOrigin"`
-	WebRTCTrustedProxies        IPNetworks       `json:"webrtcTrustedProxies"`
-	WebRTCLocalUDPAddress       string           `json:"webrtcLocalUDPAddress"`
-	WebRTCLocalTCPAddress       string           `json:"webrtcLocalTCPAddress"`
-	WebRTCIPsFromInterfaces     bool             `json:"webrtcIPsFromInterfaces"`
-	WebRTCIPsFromInterfacesList []string         `json:"webrtcIPsFromInterfacesList"`
-	WebRTCAdditionalHosts       []string         `json:"webrtcAdditionalHosts"`
-	WebRTCICEServers2           WebRTCICEServers `json:"webrtcICEServers2"`
-	WebRTCHandshakeTimeout      StringDuration   `json:"webrtcHandshakeTimeout"`
-	WebRTCTrackGatherTimeout    StringDuration   `json:"webrtcTrackGatherTimeout"`
-	WebRTCICEUDPMuxAddress      *string          `json:"webrtcICEUDPMuxAddress,omitempty"`  // deprecated
-	WebRTCICETCPMuxAddress      *string          `json:"webrtcICETCPMuxAddress,omitempty"`  // deprecated
-	WebRTCICEHostNAT1To1IPs     *[]string        `json:"webrtcICEHostNAT1To1IPs,omitempty"` // deprecated
-	WebRTCICEServers            *[]string        `json:"webrtcICEServers,omitempty"`        // deprecated
+	WebRTC                      bool                     `json:"webrtc"`
+	WebRTCDisable               *bool                    `json:"webrtcDisable,omitempty"` // deprecated
+	WebRTCAddress               string                   `json:"webrtcAddress"`
+	WebRTCEncryption            bool                     `json:"webrtcEncryption"`
+	WebRTCServerKey             string                   `json:"webrtcServerKey"`
+	WebRTCServerCert            string                   `json:"webrtcServerCert"`
+	WebRTCAllowOrigin           string                   `json:"webrtcAllowOrigin"`
+	WebRTCTrustedProxies        IPNetworks               `json:"webrtcTrustedProxies"`
+	WebRTCLocalUDPAddress       string                   `json:"webrtcLocalUDPAddress"`
+	WebRTCLocalTCPAddress       string                   `json:"webrtcLocalTCPAddress"`
+	WebRTCIPsFromInterfaces     bool                     `json:"webrtcIPsFromInterfaces"`
+	WebRTCIPsFromInterfacesList []string                 `json:"webrtcIPsFromInterfacesList"`
+	WebRTCAdditionalHosts       []string                 `json:"webrtcAdditionalHosts"`
+	WebRTCICEServers2           WebRTCICEServers         `json:"webrtcICEServers2"`
+	WebRTCICETransportPolicy    WebRTCICETransportPolicy `json:"webrtcICETransportPolicy"`
+	WebRTCHandshakeTimeout      StringDuration           `json:"webrtcHandshakeTimeout"`
+	WebRTCTrackGatherTimeout    StringDuration           `json:"webrtcTrackGatherTimeout"`
+	WebRTCICEUDPMuxAddress      *string                  `json:"webrtcICEUDPMuxAddress,omitempty"`  // deprecated
+	WebRTCICETCPMuxAddress      *string                  `json:"webrtcICETCPMuxAddress,omitempty"`  // deprecated
+	WebRTCICEHostNAT1To1IPs     *[]string                `json:"webrtcICEHostNAT1To1IPs,omitempty"` // deprecated
+	WebRTCICEServers            *[]string                `json:"webrtcICEServers,omitempty"`        // deprecated
 
 	// SRT server
-	SRT        bool   `json:"srt"`
-	SRTAddress string `json:"srtAddress"`
+	SRT                       bool                   `json:"srt"`
+	SRTAddress                string                 `json:"srtAddress"`
+	SRTDrainTimeout           StringDuration         `json:"srtDrainTimeout"`
+	SRTBitrateSmoothingWindow StringDuration         `json:"srtBitrateSmoothingWindow"`
+	SRTPublishNamespace       string                 `json:"srtPublishNamespace"`
+	SRTAutoCreatePaths        bool                   `json:"srtAutoCreatePaths"`
+	SRTPublishBufferPolicy    SRTPublishBufferPolicy `json:"srtPublishBufferPolicy"`
+	SRTPublishBufferSize      StringSize             `json:"srtPublishBufferSize"`
+	SRTPublishStartTimeout    StringDuration         `json:"srtPublishStartTimeout"`
+	SRTAlarmRTT               StringDuration         `json:"srtAlarmRTT"`
+	SRTAlarmLossRate          float64                `json:"srtAlarmLossRate"`
+	SRTHandshakeTimeout       StringDuration         `json:"srtHandshakeTimeout"`
+	SRTHandshakeRateLimit     int                    `json:"srtHandshakeRateLimit"`
+	SRTMaxPendingHandshakes   int                    `json:"srtMaxPendingHandshakes"`
+	SRTMaxConnsPerIP          int                    `json:"srtMaxConnsPerIP"`
+	SRTConnsIPv6PrefixLength  int                    `json:"srtConnsIPv6PrefixLength"`
+	SRTUDPRecvBufferSize      StringSize             `json:"srtUDPRecvBufferSize"`
+	SRTUDPSendBufferSize      StringSize             `json:"srtUDPSendBufferSize"`
+	SRTUDPMaxPayloadSize      int                    `json:"srtUDPMaxPayloadSize"`
+	SRTTTL                    int                    `json:"srtTTL"`
 
 	// Record (deprecated)
 	Record                *bool           `json:"record,omitempty"`                // deprecated
@@ -293,6 +342,9 @@ type Conf struct {
 	RecordSegmentDuration *StringDuration `json:"recordSegmentDuration,omitempty"` // deprecated
 	RecordDeleteAfter     *StringDuration `json:"recordDeleteAfter,omitempty"`     // deprecated
 
+	// Profiles
+	Profiles map[string]*OptionalPath `json:"profiles"`
+
 	// Path defaults
 	PathDefaults Path `json:"pathDefaults"`
 
@@ -330,13 +382,17 @@ func (conf *Conf) setDefaults() {
 	conf.APIAddress = ":9997"
 	conf.APIServerKey = "server.key"
 	conf.APIServerCert = "server.crt"
-	conf.APIAllowOrigin = "*"
+	conf.APIAllowOrigins = []string{"*"}
+	conf.APIQueryRedactKeys = []string{"pass", "password", "token", "jwt", "signedtoken"}
 
 	// Metrics
 	conf.MetricsAddress = ":9998"
 	conf.MetricsServerKey = "server.key"
 	conf.MetricsServerCert = "server.crt"
 	conf.MetricsAllowOrigin = "*"
+	conf.MetricsOTLPEndpoint = "http://localhost:4318/v1/metrics"
+	conf.MetricsOTLPHeaders = HTTPHeaders{}
+	conf.MetricsOTLPInterval = 10 * StringDuration(time.Second)
 
 	// PPROF
 	conf.PPROFAddress = ":9999"
@@ -386,7 +442,11 @@ func (conf *Conf) setDefaults() {
 	conf.HLSSegmentDuration = 1 * StringDuration(time.Second)
 	conf.HLSPartDuration = 200 * StringDuration(time.Millisecond)
 	conf.HLSSegmentMaxSize = 50 * 1024 * 1024
+	conf.HLSSegmentsToDiskDirectory = "./hls"
 	conf.HLSMuxerCloseAfter = 60 * StringDuration(time.Second)
+	conf.HLSMultivariantPlaylistCacheControl = "max-age=30"
+	conf.HLSMediaPlaylistCacheControl = "no-cache"
+	conf.HLSSegmentCacheControl = "max-age=3600"
 
 	// WebRTC server
 	conf.WebRTC = true
@@ -405,6 +465,14 @@ func (conf *Conf) setDefaults() {
 	// SRT server
 	conf.SRT = true
 	conf.SRTAddress = ":8890"
+	conf.SRTAutoCreatePaths = true
+	conf.SRTPublishBufferSize = 1024 * 1024
+	conf.SRTPublishStartTimeout = 10 * StringDuration(time.Second)
+	conf.SRTHandshakeTimeout = 10 * StringDuration(time.Second)
+	conf.SRTConnsIPv6PrefixLength = 128
+
+	// Profiles
+	conf.Profiles = make(map[string]*OptionalPath)
 
 	conf.PathDefaults.setDefaults()
 }
@@ -467,7 +535,7 @@ func (conf *Conf) loadFromFile(fpath string, defaultConfPaths []string) (string,
 		}
 	}
 
-	err = yaml.Load(byts, conf)
+	err = conf.loadFromBytes(byts, true)
 	if err != nil {
 		return "", err
 	}
@@ -475,6 +543,46 @@ func (conf *Conf) loadFromFile(fpath string, defaultConfPaths []string) (string,
 	return fpath, nil
 }
 
+func (conf *Conf) loadFromBytes(byts []byte, allowValueFiles bool) error {
+	var temp interface{}
+	err := yaml.Load(byts, &temp)
+	if err != nil {
+		return err
+	}
+
+	if allowValueFiles {
+		err = loadValueFiles(temp, reflect.TypeOf(conf))
+		if err != nil {
+			return err
+		}
+	}
+
+	byts, err = json.Marshal(temp)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(byts, conf)
+}
+
+// Parse parses and validates a configuration in YAML or JSON format.
+// Environment variables and value files are not taken into account.
+func Parse(byts []byte) (*Conf, error) {
+	conf := &Conf{}
+
+	err := conf.loadFromBytes(byts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conf.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
 // Clone clones the configuration.
 func (conf Conf) Clone() *Conf {
 	enc, err := json.Marshal(conf)
@@ -575,6 +683,45 @@ func (conf *Conf) Validate() error {
 			return fmt.Errorf("'authJWTClaimKey' is empty")
 		}
 	}
+	if conf.AuthSignedURLSecret != "" && len(conf.AuthSignedURLSecret) < 16 {
+		return fmt.Errorf("'authSignedURLSecret' must be at least 16 characters long")
+	}
+
+	// Control API
+
+	if conf.APIAllowOrigin != nil {
+		origins := []string{}
+		if *conf.APIAllowOrigin != "" {
+			origins = []string{*conf.APIAllowOrigin}
+		}
+
+		if len(conf.APIAllowOrigins) != 0 &&
+			!reflect.DeepEqual(conf.APIAllowOrigins, []string{"*"}) &&
+			!reflect.DeepEqual(conf.APIAllowOrigins, origins) {
+			return fmt.Errorf("'apiAllowOrigin' and 'apiAllowOrigins' have conflicting values. " +
+				"'apiAllowOrigin' is deprecated, use 'apiAllowOrigins' only")
+		}
+
+		conf.APIAllowOrigins = origins
+		conf.APIAllowOrigin = nil
+	}
+	for _, origin := range conf.APIAllowOrigins {
+		if origin == "*" {
+			if conf.APIAllowCredentials {
+				return fmt.Errorf("'apiAllowOrigins' cannot contain '*' when 'apiAllowCredentials' is enabled")
+			}
+			continue
+		}
+		if !isValidOrigin(origin) {
+			return fmt.Errorf("invalid origin in 'apiAllowOrigins': '%s'", origin)
+		}
+	}
+	if conf.APIAllowCredentials && len(conf.APIAllowOrigins) == 0 {
+		return fmt.Errorf("'apiAllowCredentials' requires 'apiAllowOrigins'")
+	}
+	if (conf.APIAuthUser != "") != (conf.APIAuthPass != "") {
+		return fmt.Errorf("'apiAuthUser' and 'apiAuthPass' must be set together")
+	}
 
 	// RTSP
 
@@ -603,6 +750,18 @@ func (conf *Conf) Validate() error {
 		}
 	}
 
+	// Metrics
+
+	if conf.MetricsOTLP {
+		if !strings.HasPrefix(conf.MetricsOTLPEndpoint, "http://") &&
+			!strings.HasPrefix(conf.MetricsOTLPEndpoint, "https://") {
+			return fmt.Errorf("'metricsOTLPEndpoint' must be a HTTP URL")
+		}
+		if conf.MetricsOTLPInterval <= 0 {
+			return fmt.Errorf("'metricsOTLPInterval' must be greater than zero")
+		}
+	}
+
 	// RTMP
 
 	if conf.RTMPDisable != nil {
@@ -614,12 +773,39 @@ func (conf *Conf) Validate() error {
 	if conf.HLSDisable != nil {
 		conf.HLS = !*conf.HLSDisable
 	}
+	if conf.HLSSegmentDuration <= 0 {
+		return fmt.Errorf("'hlsSegmentDuration' must be greater than zero")
+	}
+	if conf.HLSPartDuration <= 0 {
+		return fmt.Errorf("'hlsPartDuration' must be greater than zero")
+	}
+	if conf.HLSSegmentMaxSize == 0 {
+		return fmt.Errorf("'hlsSegmentMaxSize' must be greater than zero")
+	}
+	if conf.HLSMuxerCloseAfter <= 0 {
+		return fmt.Errorf("'hlsMuxerCloseAfter' must be greater than zero")
+	}
+	if conf.HLSSegmentsToDisk {
+		if conf.HLSSegmentsToDiskDirectory == "" {
+			return fmt.Errorf("'hlsSegmentsToDiskDirectory' must be filled when 'hlsSegmentsToDisk' is enabled")
+		}
+		if conf.HLSDirectory != "" &&
+			filepath.Clean(conf.HLSDirectory) == filepath.Clean(conf.HLSSegmentsToDiskDirectory) {
+			return fmt.Errorf("'hlsSegmentsToDiskDirectory' must be different from 'hlsDirectory'")
+		}
+	}
 
 	// WebRTC
 
 	if conf.WebRTCDisable != nil {
 		conf.WebRTC = !*conf.WebRTCDisable
 	}
+	if conf.WebRTCHandshakeTimeout <= 0 {
+		return fmt.Errorf("'webrtcHandshakeTimeout' must be greater than zero")
+	}
+	if conf.WebRTCTrackGatherTimeout <= 0 {
+		return fmt.Errorf("'webrtcTrackGatherTimeout' must be greater than zero")
+	}
 	if conf.WebRTCICEUDPMuxAddress != nil {
 		conf.WebRTCLocalUDPAddress = *conf.WebRTCICEUDPMuxAddress
 	}
@@ -652,6 +838,19 @@ func (conf *Conf) Validate() error {
 			return fmt.Errorf("invalid ICE server: '%s'", server.URL)
 		}
 	}
+	if conf.WebRTCICETransportPolicy == WebRTCICETransportPolicyRelay {
+		hasTURN := false
+		for _, server := range conf.WebRTCICEServers2 {
+			if !server.ClientOnly &&
+				(strings.HasPrefix(server.URL, "turn:") || strings.HasPrefix(server.URL, "turns:")) {
+				hasTURN = true
+				break
+			}
+		}
+		if !hasTURN {
+			return fmt.Errorf("'webrtcICETransportPolicy' is 'relay' but no TURN server is present in 'webrtcICEServers2'")
+		}
+	}
 	if conf.WebRTCLocalUDPAddress == "" &&
 		conf.WebRTCLocalTCPAddress == "" &&
 		len(conf.WebRTCICEServers2) == 0 {
@@ -664,6 +863,52 @@ func (conf *Conf) Validate() error {
 		}
 	}
 
+	// SRT
+
+	if conf.SRTDrainTimeout < 0 {
+		return fmt.Errorf("'srtDrainTimeout' can't be negative")
+	}
+	if conf.SRTBitrateSmoothingWindow < 0 {
+		return fmt.Errorf("'srtBitrateSmoothingWindow' can't be negative")
+	}
+	if conf.SRTPublishBufferSize == 0 {
+		return fmt.Errorf("'srtPublishBufferSize' must be greater than zero")
+	}
+	if conf.SRTPublishStartTimeout < 0 {
+		return fmt.Errorf("'srtPublishStartTimeout' can't be negative")
+	}
+	if conf.SRTAlarmRTT < 0 {
+		return fmt.Errorf("'srtAlarmRTT' can't be negative")
+	}
+	if conf.SRTHandshakeTimeout <= 0 {
+		return fmt.Errorf("'srtHandshakeTimeout' must be greater than zero")
+	}
+	if conf.SRTHandshakeRateLimit < 0 {
+		return fmt.Errorf("'srtHandshakeRateLimit' must be greater than or equal to zero")
+	}
+	if conf.SRTMaxPendingHandshakes < 0 {
+		return fmt.Errorf("'srtMaxPendingHandshakes' must be greater than or equal to zero")
+	}
+	if conf.SRTMaxConnsPerIP < 0 {
+		return fmt.Errorf("'srtMaxConnsPerIP' must be greater than or equal to zero")
+	}
+	if conf.SRTConnsIPv6PrefixLength < 1 || conf.SRTConnsIPv6PrefixLength > 128 {
+		return fmt.Errorf("'srtConnsIPv6PrefixLength' must be between 1 and 128")
+	}
+	if conf.SRTUDPRecvBufferSize > math.MaxInt32 {
+		return fmt.Errorf("'srtUDPRecvBufferSize' must be less than %d", math.MaxInt32)
+	}
+	if conf.SRTUDPSendBufferSize > math.MaxInt32 {
+		return fmt.Errorf("'srtUDPSendBufferSize' must be less than %d", math.MaxInt32)
+	}
+	if conf.SRTUDPMaxPayloadSize != 0 &&
+		(conf.SRTUDPMaxPayloadSize < 204 || conf.SRTUDPMaxPayloadSize > 1472) {
+		return fmt.Errorf("'srtUDPMaxPayloadSize' must be between 204 and 1472")
+	}
+	if conf.SRTTTL != 0 && (conf.SRTTTL < 1 || conf.SRTTTL > 255) {
+		return fmt.Errorf("'srtTTL' must be between 1 and 255")
+	}
+
 	// Record (deprecated)
 
 	if conf.Record != nil {
@@ -685,6 +930,37 @@ func (conf *Conf) Validate() error {
 		conf.PathDefaults.RecordDeleteAfter = *conf.RecordDeleteAfter
 	}
 
+	// Publisher source (deprecated)
+
+	conf.PathDefaults.migratePublisherOverride()
+
+	// Profiles
+
+	for name, profile := range conf.Profiles {
+		if profile == nil {
+			profile = &OptionalPath{
+				Values: newOptionalPathValues(),
+			}
+			conf.Profiles[name] = profile
+		}
+
+		profile.migratePublisherOverride()
+
+		rva := reflect.ValueOf(profile.Values).Elem()
+		if rva.FieldByName("Profile").Interface().(*string) != nil {
+			return fmt.Errorf("profile '%s' can't reference another profile", name)
+		}
+		if rva.FieldByName("Name").Interface().(*string) != nil {
+			return fmt.Errorf("profile '%s' can't contain 'name'", name)
+		}
+	}
+
+	if conf.PathDefaults.Profile != "" {
+		if _, ok := conf.Profiles[conf.PathDefaults.Profile]; !ok {
+			return fmt.Errorf("profile '%s' doesn't exist", conf.PathDefaults.Profile)
+		}
+	}
+
 	hasAllOthers := false
 	for name := range conf.OptionalPaths {
 		if name == "all" || name == "all_others" || name == "~^.*$" {
@@ -706,7 +982,9 @@ func (conf *Conf) Validate() error {
 			conf.OptionalPaths[name] = optional
 		}
 
-		pconf := newPath(&conf.PathDefaults, optional)
+		optional.migratePublisherOverride()
+
+		pconf := newPath(&conf.PathDefaults, conf.Profiles, optional)
 		conf.Paths[name] = pconf
 
 		err := pconf.validate(conf, name, deprecatedCredentialsMode)
@@ -723,9 +1001,7 @@ func (conf *Conf) UnmarshalJSON(b []byte) error {
 	conf.setDefaults()
 
 	type alias Conf
-	d := json.NewDecoder(bytes.NewReader(b))
-	d.DisallowUnknownFields()
-	return d.Decode((*alias)(conf))
+	return decodeJSONStrict(b, (*alias)(conf))
 }
 
 // Global returns the global part of Conf.
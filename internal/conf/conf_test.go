@@ -3,8 +3,10 @@ package conf
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -48,38 +50,60 @@ func TestConfFromFile(t *testing.T) {
 		pa, ok := conf.Paths["cam1"]
 		require.Equal(t, true, ok)
 		require.Equal(t, &Path{
-			Name:                       "cam1",
-			Source:                     "publisher",
-			SourceOnDemandStartTimeout: 10 * StringDuration(time.Second),
-			SourceOnDemandCloseAfter:   10 * StringDuration(time.Second),
-			RecordPath:                 "./recordings/%path/%Y-%m-%d_%H-%M-%S-%f",
-			RecordFormat:               RecordFormatFMP4,
-			RecordPartDuration:         StringDuration(1 * time.Second),
-			RecordSegmentDuration:      3600000000000,
-			RecordDeleteAfter:          86400000000000,
-			OverridePublisher:          true,
-			RPICameraWidth:             1920,
-			RPICameraHeight:            1080,
-			RPICameraContrast:          1,
-			RPICameraSaturation:        1,
-			RPICameraSharpness:         1,
-			RPICameraExposure:          "normal",
-			RPICameraAWB:               "auto",
-			RPICameraAWBGains:          []float64{0, 0},
-			RPICameraDenoise:           "off",
-			RPICameraMetering:          "centre",
-			RPICameraFPS:               30,
-			RPICameraAfMode:            "continuous",
-			RPICameraAfRange:           "normal",
-			RPICameraAfSpeed:           "normal",
-			RPICameraTextOverlay:       "%Y-%m-%d %H:%M:%S - MediaMTX",
-			RPICameraCodec:             "auto",
-			RPICameraIDRPeriod:         60,
-			RPICameraBitrate:           5000000,
-			RPICameraProfile:           "main",
-			RPICameraLevel:             "4.1",
-			RunOnDemandStartTimeout:    5 * StringDuration(time.Second),
-			RunOnDemandCloseAfter:      10 * StringDuration(time.Second),
+			Name:                           "cam1",
+			Enabled:                        true,
+			Source:                         "publisher",
+			SourceOnDemandStartTimeout:     10 * StringDuration(time.Second),
+			TrackActiveTimeout:             5 * StringDuration(time.Second),
+			RTCPSenderReportPeriod:         10 * StringDuration(time.Second),
+			MPEGTSTrackPIDs:                MPEGTSPIDs{},
+			SourceOnDemandCloseAfter:       10 * StringDuration(time.Second),
+			SourceFailover:                 []string{},
+			SourceFailbackDelay:            30 * StringDuration(time.Second),
+			SRTReadGracePassphrases:        []string{},
+			SRTReadUserPassphrases:         SRTUserPassphrases{},
+			SRTDebugDumpMaxSize:            50 * 1024 * 1024,
+			SRTDebugDumpMaxDuration:        60 * StringDuration(time.Second),
+			SRTReadWarmupTimeout:           10 * StringDuration(time.Second),
+			RecordPath:                     "./recordings/%path/%Y-%m-%d_%H-%M-%S-%f",
+			RecordSnapshotPath:             "./snapshots/%path/%Y-%m-%d_%H-%M-%S-%f",
+			RecordFormat:                   RecordFormatFMP4,
+			RecordTracks:                   []string{},
+			RecordPartDuration:             StringDuration(1 * time.Second),
+			RecordSegmentDuration:          3600000000000,
+			RecordDeleteAfter:              86400000000000,
+			RecordSchedule:                 RecordSchedule{},
+			RecordPostProcessMaxConcurrent: 1,
+			MaxPublishBitrateGracePeriod:   5 * StringDuration(time.Second),
+			RecordPostProcessMaxRetries:    2,
+			PublisherConflictPolicy:        PublisherConflictPolicyTakeover,
+			OverridePublisher:              func() *bool { v := true; return &v }(),
+			PublisherAttemptsWindow:        StringDuration(1 * time.Minute),
+			PublisherBackoff:               10 * StringDuration(time.Second),
+			SRTPublishGracePassphrases:     []string{},
+			SRTPublishUserPassphrases:      SRTUserPassphrases{},
+			RPICameraWidth:                 1920,
+			RPICameraHeight:                1080,
+			RPICameraContrast:              1,
+			RPICameraSaturation:            1,
+			RPICameraSharpness:             1,
+			RPICameraExposure:              "normal",
+			RPICameraAWB:                   "auto",
+			RPICameraAWBGains:              []float64{0, 0},
+			RPICameraDenoise:               "off",
+			RPICameraMetering:              "centre",
+			RPICameraFPS:                   30,
+			RPICameraAfMode:                "continuous",
+			RPICameraAfRange:               "normal",
+			RPICameraAfSpeed:               "normal",
+			RPICameraTextOverlay:           "%Y-%m-%d %H:%M:%S - MediaMTX",
+			RPICameraCodec:                 "auto",
+			RPICameraIDRPeriod:             60,
+			RPICameraBitrate:               5000000,
+			RPICameraProfile:               "main",
+			RPICameraLevel:                 "4.1",
+			RunOnDemandStartTimeout:        5 * StringDuration(time.Second),
+			RunOnDemandCloseAfter:          10 * StringDuration(time.Second),
 		}, pa)
 	}()
 
@@ -120,8 +144,15 @@ func TestConfFromFileAndEnv(t *testing.T) {
 	// path parameter
 	t.Setenv("MTX_PATHS_CAM1_SOURCE", "rtsp://testing")
 
+	// path list parameter
+	t.Setenv("MTX_PATHS_CAM1_MPEGTSTRACKPIDS", "256,300")
+
+	// global map parameter
+	t.Setenv("MTX_METRICSOTLPHEADERS", "Authorization: Bearer token,X-Custom:value")
+
 	// deprecated global parameter
 	t.Setenv("MTX_RTMPDISABLE", "yes")
+	t.Setenv("MTX_APIALLOWORIGIN", "https://dashboard.example.com")
 
 	// deprecated path parameter
 	t.Setenv("MTX_PATHS_CAM2_DISABLEPUBLISHEROVERRIDE", "yes")
@@ -136,14 +167,65 @@ func TestConfFromFileAndEnv(t *testing.T) {
 
 	require.Equal(t, Protocols{Protocol(gortsplib.TransportTCP): {}}, conf.Protocols)
 	require.Equal(t, false, conf.RTMP)
+	require.Equal(t, []string{"https://dashboard.example.com"}, conf.APIAllowOrigins)
+	require.Equal(t, (*string)(nil), conf.APIAllowOrigin)
+	require.Equal(t, HTTPHeaders{
+		"Authorization": "Bearer token",
+		"X-Custom":      "value",
+	}, conf.MetricsOTLPHeaders)
 
 	pa, ok := conf.Paths["cam1"]
 	require.Equal(t, true, ok)
 	require.Equal(t, "rtsp://testing", pa.Source)
+	require.Equal(t, MPEGTSPIDs{256, 300}, pa.MPEGTSTrackPIDs)
 
 	pa, ok = conf.Paths["cam2"]
 	require.Equal(t, true, ok)
-	require.Equal(t, false, pa.OverridePublisher)
+	require.Equal(t, PublisherConflictPolicyReject, pa.PublisherConflictPolicy)
+}
+
+func TestConfProfiles(t *testing.T) {
+	tmpf, err := createTempFile([]byte(
+		"pathDefaults:\n" +
+			"  recordPath: ./defaults/%path/%Y-%m-%d_%H-%M-%S-%f\n" +
+			"  recordDeleteAfter: 48h\n" +
+			"profiles:\n" +
+			"  archive:\n" +
+			"    record: yes\n" +
+			"    recordFormat: mpegts\n" +
+			"    recordSegmentDuration: 10m\n" +
+			"    recordDeleteAfter: 72h\n" +
+			"paths:\n" +
+			"  cam1:\n" +
+			"    profile: archive\n" +
+			"  cam2:\n" +
+			"    profile: archive\n" +
+			"    recordSegmentDuration: 1h\n" +
+			"  cam3:\n"))
+	require.NoError(t, err)
+	defer os.Remove(tmpf)
+
+	conf, _, err := Load(tmpf, nil)
+	require.NoError(t, err)
+
+	pa := conf.Paths["cam1"]
+	require.Equal(t, "archive", pa.Profile)
+	require.Equal(t, true, pa.Record)
+	require.Equal(t, RecordFormatMPEGTS, pa.RecordFormat)
+	require.Equal(t, 10*StringDuration(time.Minute), pa.RecordSegmentDuration)
+	require.Equal(t, 72*StringDuration(time.Hour), pa.RecordDeleteAfter)
+	require.Equal(t, "./defaults/%path/%Y-%m-%d_%H-%M-%S-%f", pa.RecordPath)
+
+	pa = conf.Paths["cam2"]
+	require.Equal(t, true, pa.Record)
+	require.Equal(t, RecordFormatMPEGTS, pa.RecordFormat)
+	require.Equal(t, StringDuration(time.Hour), pa.RecordSegmentDuration)
+
+	pa = conf.Paths["cam3"]
+	require.Equal(t, "", pa.Profile)
+	require.Equal(t, false, pa.Record)
+	require.Equal(t, RecordFormatFMP4, pa.RecordFormat)
+	require.Equal(t, 48*StringDuration(time.Hour), pa.RecordDeleteAfter)
 }
 
 func TestConfFromEnvOnly(t *testing.T) {
@@ -193,6 +275,42 @@ func TestConfEncryption(t *testing.T) {
 	require.Equal(t, true, ok)
 }
 
+func TestConfValueFiles(t *testing.T) {
+	passphraseFile, err := createTempFile([]byte("mypassphrase123\n"))
+	require.NoError(t, err)
+	defer os.Remove(passphraseFile)
+
+	passFile, err := createTempFile([]byte("mypass\r\n"))
+	require.NoError(t, err)
+	defer os.Remove(passFile)
+
+	tmpf, err := createTempFile([]byte("authInternalUsers:\n" +
+		"- user: myuser\n" +
+		"  passFile: " + passFile + "\n" +
+		"  permissions:\n" +
+		"  - action: publish\n" +
+		"paths:\n" +
+		"  cam1:\n" +
+		"    srtPublishPassphraseFile: " + passphraseFile + "\n"))
+	require.NoError(t, err)
+	defer os.Remove(tmpf)
+
+	conf, _, err := Load(tmpf, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, Credential("mypass"), conf.AuthInternalUsers[0].Pass)
+	require.Equal(t, "mypassphrase123", conf.Paths["cam1"].SRTPublishPassphrase)
+
+	// parameters whose name ends with "File" are left untouched
+	tmpf2, err := createTempFile([]byte("logFile: mediamtx.log\n"))
+	require.NoError(t, err)
+	defer os.Remove(tmpf2)
+
+	conf, _, err = Load(tmpf2, nil)
+	require.NoError(t, err)
+	require.Equal(t, "mediamtx.log", conf.LogFile)
+}
+
 func TestConfDeprecatedAuth(t *testing.T) {
 	tmpf, err := createTempFile([]byte(
 		"paths:\n" +
@@ -253,6 +371,44 @@ func TestConfDeprecatedAuth(t *testing.T) {
 	}, conf.AuthInternalUsers)
 }
 
+func TestConfDeprecatedPublisherOverride(t *testing.T) {
+	tmpf, err := createTempFile([]byte(
+		"paths:\n" +
+			"  cam1:\n" +
+			"    overridePublisher: no\n" +
+			"  cam2:\n" +
+			"    overridePublisher: no\n" +
+			"    publisherConflictPolicy: takeover\n" +
+			"  cam3:\n" +
+			"    disablePublisherOverride: yes\n"))
+	require.NoError(t, err)
+	defer os.Remove(tmpf)
+
+	conf, _, err := Load(tmpf, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, PublisherConflictPolicyReject, conf.Paths["cam1"].PublisherConflictPolicy)
+	require.Equal(t, false, *conf.Paths["cam1"].OverridePublisher)
+	require.Equal(t, PublisherConflictPolicyTakeover, conf.Paths["cam2"].PublisherConflictPolicy)
+	require.Equal(t, true, *conf.Paths["cam2"].OverridePublisher)
+	require.Equal(t, PublisherConflictPolicyReject, conf.Paths["cam3"].PublisherConflictPolicy)
+	require.Equal(t, (*bool)(nil), conf.Paths["cam3"].DisablePublisherOverride)
+
+	// publisherConflictPolicy can be patched after deprecated parameters have been migrated
+	var p OptionalPath
+	err = json.Unmarshal([]byte(`{"publisherConflictPolicy": "takeover"}`), &p)
+	require.NoError(t, err)
+
+	newConf := conf.Clone()
+	err = newConf.PatchPath("cam1", &p)
+	require.NoError(t, err)
+	err = newConf.Validate()
+	require.NoError(t, err)
+
+	require.Equal(t, PublisherConflictPolicyTakeover, newConf.Paths["cam1"].PublisherConflictPolicy)
+	require.Equal(t, true, *newConf.Paths["cam1"].OverridePublisher)
+}
+
 func TestConfErrors(t *testing.T) {
 	for _, ca := range []struct {
 		name string
@@ -344,6 +500,178 @@ func TestConfErrors(t *testing.T) {
 				"    srtReadPassphrase: a\n",
 			`invalid 'readRTPassphrase': must be between 10 and 79 characters`,
 		},
+		{
+			"srt relay with static source",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    source: rtsp://localhost:8554/mystream\n" +
+				"    srtRelay: srt://localhost:8890\n",
+			"'srtRelay' can only be used when source is 'publisher'",
+		},
+		{
+			"invalid srt relay",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtRelay: rtsp://localhost:8554\n",
+			"'srtRelay' must be a SRT URL",
+		},
+		{
+			"hls segments to disk with hls directory",
+			"hlsDirectory: ./hls\n" +
+				"hlsSegmentsToDisk: yes\n" +
+				"hlsSegmentsToDiskDirectory: ./hls/\n",
+			"'hlsSegmentsToDiskDirectory' must be different from 'hlsDirectory'",
+		},
+		{
+			"webrtc relay without turn servers",
+			"webrtcICETransportPolicy: relay\n" +
+				"webrtcICEServers2:\n" +
+				"  - url: stun:stun.l.google.com:19302\n" +
+				"  - url: turn:myturn:3478\n" +
+				"    clientOnly: true\n",
+			"'webrtcICETransportPolicy' is 'relay' but no TURN server is present in 'webrtcICEServers2'",
+		},
+		{
+			"invalid size",
+			"hlsSegmentMaxSize: 10MBs\n",
+			"invalid 'hlsSegmentMaxSize': invalid size '10MBs': it must be a positive number " +
+				"followed by one of the suffixes B, KB, MB, GB, TB (i.e. 10MB)",
+		},
+		{
+			"invalid path size",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtDebugDumpMaxSize: 10X\n",
+			"invalid 'srtDebugDumpMaxSize': invalid size '10X': it must be a positive number " +
+				"followed by one of the suffixes B, KB, MB, GB, TB (i.e. 10MB)",
+		},
+		{
+			"invalid duration",
+			"readTimeout: 10\n",
+			"invalid 'readTimeout': invalid duration '10': it must be a number " +
+				"followed by one of the units ms, s, m, h (i.e. 10s)",
+		},
+		{
+			"zero size",
+			"srtPublishBufferSize: 0\n",
+			"'srtPublishBufferSize' must be greater than zero",
+		},
+		{
+			"zero hls segment duration",
+			"hlsSegmentDuration: 0s\n",
+			"'hlsSegmentDuration' must be greater than zero",
+		},
+		{
+			"negative srt drain timeout",
+			"srtDrainTimeout: -1s\n",
+			"'srtDrainTimeout' can't be negative",
+		},
+		{
+			"zero record segment duration",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    recordSegmentDuration: 0s\n",
+			"'recordSegmentDuration' must be greater than zero",
+		},
+		{
+			"negative run on demand close after",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    runOnDemandCloseAfter: -1s\n",
+			"'runOnDemandCloseAfter' can't be negative",
+		},
+		{
+			"negative publisher max attempts",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    publisherMaxAttempts: -1\n",
+			"'publisherMaxAttempts' can't be negative",
+		},
+		{
+			"negative publisher hold timeout",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    publisherHoldTimeout: -1s\n",
+			"'publisherHoldTimeout' can't be negative",
+		},
+		{
+			"zero publisher attempts window",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    publisherMaxAttempts: 3\n" +
+				"    publisherAttemptsWindow: 0s\n",
+			"'publisherAttemptsWindow' must be greater than zero",
+		},
+		{
+			"ts service name too long",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    tsServiceName: " + strings.Repeat("a", 65) + "\n",
+			"'tsServiceName' must be at most 64 bytes long",
+		},
+		{
+			"invalid srt ipv6 prefix length",
+			"srtConnsIPv6PrefixLength: 129\n",
+			"'srtConnsIPv6PrefixLength' must be between 1 and 128",
+		},
+		{
+			"invalid srt ttl",
+			"srtTTL: 256\n",
+			"'srtTTL' must be between 1 and 255",
+		},
+		{
+			"srt grace passphrases without primary",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtPublishGracePassphrases: [oldpassphrase123]\n",
+			`invalid 'srtPublishGracePassphrases': a primary passphrase is required`,
+		},
+		{
+			"invalid srt grace passphrase",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtReadPassphrase: newpassphrase123\n" +
+				"    srtReadGracePassphrases: [a]\n",
+			`invalid 'srtReadGracePassphrases': must be between 10 and 79 characters`,
+		},
+		{
+			"invalid srt user passphrase",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtPublishUserPassphrases:\n" +
+				"      myuser: short\n",
+			`invalid 'srtPublishUserPassphrases': passphrase of user 'myuser': must be between 10 and 79 characters`,
+		},
+		{
+			"path jwt auth method without jwks",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    readAuthMethod: jwt\n",
+			"'readAuthMethod' is 'jwt' but 'authJWTJWKS' or 'authJWTClaimKey' is empty",
+		},
+		{
+			"path http auth method with rtsp digest",
+			"rtspAuthMethods: [digest]\n" +
+				"authHTTPAddress: http://localhost:9120/auth\n" +
+				"paths:\n" +
+				"  mypath:\n" +
+				"    publishAuthMethod: http\n",
+			"when RTSP digest is enabled, the only supported value of 'publishAuthMethod' is 'internal'",
+		},
+		{
+			"invalid path auth method",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    publishAuthMethod: other\n",
+			"invalid 'publishAuthMethod': invalid auth method 'other'",
+		},
+		{
+			"proxy without command",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    proxy: yes\n",
+			"'proxyCommand' is required when 'proxy' is enabled",
+		},
 		{
 			"all_others aliases",
 			"paths:\n" +
@@ -367,6 +695,50 @@ func TestConfErrors(t *testing.T) {
 			`record path './recordings/%path/%Y-%m-%d_%H-%M-%S' is missing one of the` +
 				` mandatory elements for the playback server to work: %Y %m %d %H %M %S %f`,
 		},
+		{
+			"missing value file",
+			"paths:\n" +
+				"  my_path:\n" +
+				"    srtPublishPassphraseFile: /nonexisting/passphrase",
+			"unable to read 'srtPublishPassphraseFile': open /nonexisting/passphrase: no such file or directory",
+		},
+		{
+			"value and value file",
+			"paths:\n" +
+				"  my_path:\n" +
+				"    srtPublishPassphrase: mypassphrase123\n" +
+				"    srtPublishPassphraseFile: /nonexisting/passphrase",
+			"'srtPublishPassphrase' and 'srtPublishPassphraseFile' cannot be used together",
+		},
+		{
+			"invalid record time zone",
+			"paths:\n" +
+				"  my_path:\n" +
+				"    recordTimeZone: Nowhere/Nothing",
+			"invalid 'recordTimeZone': unknown time zone Nowhere/Nothing",
+		},
+		{
+			"invalid mpegts pmt pid",
+			"paths:\n" +
+				"  my_path:\n" +
+				"    mpegtsPMTPID: 8191",
+			"invalid 'mpegtsPMTPID': 8191, must be between 16 and 8190",
+		},
+		{
+			"mpegts track pid conflicting with pmt pid",
+			"paths:\n" +
+				"  my_path:\n" +
+				"    mpegtsPMTPID: 300\n" +
+				"    mpegtsTrackPIDs: [256, 300]",
+			"PID 300 in 'mpegtsTrackPIDs' conflicts with 'mpegtsPMTPID'",
+		},
+		{
+			"duplicate mpegts track pid",
+			"paths:\n" +
+				"  my_path:\n" +
+				"    mpegtsTrackPIDs: [256, 256]",
+			"PID 256 in 'mpegtsTrackPIDs' conflicts with another track",
+		},
 		{
 			"jwt claim key empty",
 			"authMethod: jwt\n" +
@@ -374,6 +746,86 @@ func TestConfErrors(t *testing.T) {
 				"authJWTClaimKey: \"\"",
 			"'authJWTClaimKey' is empty",
 		},
+		{
+			"srt pacing and cbr",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtPacing: yes\n" +
+				"    srtCBRBitrate: 1000000\n",
+			"'srtPacing' and 'srtCBRBitrate' can't be used together",
+		},
+		{
+			"negative max publish bitrate grace period",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    maxPublishBitrateGracePeriod: -1s\n",
+			"'maxPublishBitrateGracePeriod' can't be negative",
+		},
+		{
+			"srt gcm without passphrase",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtEncryptionMode: gcm\n",
+			"'srtEncryptionMode' is 'gcm', but no SRT passphrase is set",
+		},
+		{
+			"srt gcm unsupported",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    srtEncryptionMode: gcm\n" +
+				"    srtPublishPassphrase: testpassphrase123\n",
+			"'srtEncryptionMode' 'gcm' is not supported by the SRT library in use",
+		},
+		{
+			"unknown profile",
+			"paths:\n" +
+				"  mypath:\n" +
+				"    profile: missing\n",
+			"profile 'missing' doesn't exist",
+		},
+		{
+			"nested profile",
+			"profiles:\n" +
+				"  p1:\n" +
+				"    record: yes\n" +
+				"  p2:\n" +
+				"    profile: p1\n",
+			"profile 'p2' can't reference another profile",
+		},
+		{
+			"signed url secret too short",
+			"authSignedURLSecret: short\n",
+			"'authSignedURLSecret' must be at least 16 characters long",
+		},
+		{
+			"api wildcard origin with credentials",
+			"apiAllowOrigins: ['*']\n" +
+				"apiAllowCredentials: yes\n",
+			"'apiAllowOrigins' cannot contain '*' when 'apiAllowCredentials' is enabled",
+		},
+		{
+			"api invalid origin",
+			"apiAllowOrigins: ['https://dashboard.example.com/path']\n",
+			"invalid origin in 'apiAllowOrigins': 'https://dashboard.example.com/path'",
+		},
+		{
+			"api credentials without origins",
+			"apiAllowOrigins: []\n" +
+				"apiAllowCredentials: yes\n",
+			"'apiAllowCredentials' requires 'apiAllowOrigins'",
+		},
+		{
+			"api allow origin conflict",
+			"apiAllowOrigin: https://dashboard.example.com\n" +
+				"apiAllowOrigins: ['https://other.example.com']\n",
+			"'apiAllowOrigin' and 'apiAllowOrigins' have conflicting values. " +
+				"'apiAllowOrigin' is deprecated, use 'apiAllowOrigins' only",
+		},
+		{
+			"api user without pass",
+			"apiAuthUser: admin\n",
+			"'apiAuthUser' and 'apiAuthPass' must be set together",
+		},
 	} {
 		t.Run(ca.name, func(t *testing.T) {
 			tmpf, err := createTempFile([]byte(ca.conf))
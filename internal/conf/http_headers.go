@@ -0,0 +1,29 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HTTPHeaders is a parameter that contains a set of HTTP headers.
+type HTTPHeaders map[string]string
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *HTTPHeaders) UnmarshalEnv(_ string, v string) error {
+	*d = HTTPHeaders{}
+
+	if v == "" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid header '%s'", entry)
+		}
+
+		(*d)[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// fieldError is an error caused by the value of a field.
+type fieldError struct {
+	field string
+	err   error
+}
+
+// Error implements the error interface.
+func (e fieldError) Error() string {
+	return "invalid '" + e.field + "': " + e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e fieldError) Unwrap() error {
+	return e.err
+}
+
+// json.Decoder does not provide a dedicated type for unknown field errors.
+func isUnknownFieldError(err error) bool {
+	return strings.HasPrefix(err.Error(), "json: unknown field")
+}
+
+// findFieldError finds the field of a struct whose value can't be decoded.
+// Errors returned by json.Decoder do not contain the field that caused them.
+func findFieldError(b []byte, rt reflect.Type, err error) error {
+	if rt.Kind() != reflect.Struct {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if json.Unmarshal(b, &raw) != nil {
+		return err
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+		v, ok := raw[name]
+		if !ok {
+			continue
+		}
+
+		err2 := json.Unmarshal(v, reflect.New(f.Type).Interface())
+		if err2 != nil {
+			// the error has been caused by a nested field
+			var ferr fieldError
+			if errors.As(err2, &ferr) || isUnknownFieldError(err2) {
+				return err2
+			}
+
+			return fieldError{field: name, err: err2}
+		}
+	}
+
+	return err
+}
+
+// decodeJSONStrict decodes JSON into a struct and disallows unknown fields.
+// When the value of a field is invalid, the error contains the field name.
+func decodeJSONStrict(b []byte, dest interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	err := d.Decode(dest)
+	if err == nil {
+		return nil
+	}
+
+	var ferr fieldError
+	var serr *json.SyntaxError
+	if errors.As(err, &ferr) || errors.As(err, &serr) || isUnknownFieldError(err) {
+		return err
+	}
+
+	// type errors returned by custom unmarshalers do not contain the field name
+	var terr *json.UnmarshalTypeError
+	if errors.As(err, &terr) && terr.Field != "" {
+		return err
+	}
+
+	return findFieldError(b, reflect.TypeOf(dest).Elem(), err)
+}
@@ -0,0 +1,54 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MPEGTSPIDs is a parameter that contains a list of MPEG-TS PIDs.
+type MPEGTSPIDs []uint16
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *MPEGTSPIDs) UnmarshalEnv(_ string, v string) error {
+	if v == "" {
+		*d = MPEGTSPIDs{}
+		return nil
+	}
+
+	return json.Unmarshal([]byte("["+strings.Trim(v, ",")+"]"), (*[]uint16)(d))
+}
+
+const (
+	mpegtsDefaultPMTPID = 0x1000
+	mpegtsMinPID        = 0x0010
+	mpegtsMaxPID        = 0x1FFE
+)
+
+func checkMPEGTSPIDs(pmtPID uint, trackPIDs MPEGTSPIDs) error {
+	if pmtPID != 0 && (pmtPID < mpegtsMinPID || pmtPID > mpegtsMaxPID) {
+		return fmt.Errorf("invalid 'mpegtsPMTPID': %d, must be between %d and %d",
+			pmtPID, mpegtsMinPID, mpegtsMaxPID)
+	}
+
+	used := map[uint16]string{
+		mpegtsDefaultPMTPID: "the default PMT PID",
+	}
+	if pmtPID != 0 {
+		used[uint16(pmtPID)] = "'mpegtsPMTPID'"
+	}
+
+	for _, pid := range trackPIDs {
+		if pid < mpegtsMinPID || pid > mpegtsMaxPID {
+			return fmt.Errorf("invalid PID in 'mpegtsTrackPIDs': %d, must be between %d and %d",
+				pid, mpegtsMinPID, mpegtsMaxPID)
+		}
+
+		if other, ok := used[pid]; ok {
+			return fmt.Errorf("PID %d in 'mpegtsTrackPIDs' conflicts with %s", pid, other)
+		}
+		used[pid] = "another track"
+	}
+
+	return nil
+}
@@ -1,7 +1,6 @@
 package conf
 
 import (
-	"bytes"
 	"encoding/json"
 	"reflect"
 	"strings"
@@ -49,9 +48,7 @@ type OptionalGlobal struct {
 // UnmarshalJSON implements json.Unmarshaler.
 func (p *OptionalGlobal) UnmarshalJSON(b []byte) error {
 	p.Values = newOptionalGlobalValues()
-	d := json.NewDecoder(bytes.NewReader(b))
-	d.DisallowUnknownFields()
-	return d.Decode(p.Values)
+	return decodeJSONStrict(b, p.Values)
 }
 
 // MarshalJSON implements json.Marshaler.
@@ -1,7 +1,6 @@
 package conf
 
 import (
-	"bytes"
 	"encoding/json"
 	"reflect"
 	"strings"
@@ -48,12 +47,32 @@ type OptionalPath struct {
 	Values interface{}
 }
 
+// migratePublisherOverride moves deprecated parameters overridePublisher and
+// disablePublisherOverride into publisherConflictPolicy,
+// unless publisherConflictPolicy is set explicitly.
+func (p *OptionalPath) migratePublisherOverride() {
+	rva := reflect.ValueOf(p.Values).Elem()
+	overridePublisher := rva.FieldByName("OverridePublisher")
+	disablePublisherOverride := rva.FieldByName("DisablePublisherOverride")
+
+	policy, ok := publisherConflictPolicyFromDeprecated(
+		overridePublisher.Interface().(*bool), disablePublisherOverride.Interface().(*bool))
+	if !ok {
+		return
+	}
+
+	if policyField := rva.FieldByName("PublisherConflictPolicy"); policyField.IsNil() {
+		policyField.Set(reflect.ValueOf(&policy))
+	}
+
+	overridePublisher.SetZero()
+	disablePublisherOverride.SetZero()
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
 func (p *OptionalPath) UnmarshalJSON(b []byte) error {
 	p.Values = newOptionalPathValues()
-	d := json.NewDecoder(bytes.NewReader(b))
-	d.DisallowUnknownFields()
-	return d.Decode(p.Values)
+	return decodeJSONStrict(b, p.Values)
 }
 
 // UnmarshalEnv implements env.Unmarshaler.
@@ -7,12 +7,19 @@ import (
 	gourl "net/url"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // time zones are embedded since the database may be missing in containers
 
+	"github.com/bluenviron/gortsplib/v4/pkg/auth"
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 )
 
+// maximum number of SRT passphrases that are accepted in addition to the primary one,
+// during passphrase rotation.
+const srtMaxGracePassphrases = 4
+
 var rePathName = regexp.MustCompile(`^[0-9a-zA-Z_\-/\.~]+$`)
 
 func isValidPathName(name string) error {
@@ -35,6 +42,25 @@ func isValidPathName(name string) error {
 	return nil
 }
 
+func srtCheckGracePassphrases(passphrase string, gracePassphrases []string) error {
+	if len(gracePassphrases) != 0 && passphrase == "" {
+		return fmt.Errorf("a primary passphrase is required")
+	}
+
+	if len(gracePassphrases) > srtMaxGracePassphrases {
+		return fmt.Errorf("at most %d passphrases are allowed", srtMaxGracePassphrases)
+	}
+
+	for _, p := range gracePassphrases {
+		err := srtCheckPassphrase(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func srtCheckPassphrase(passphrase string) error {
 	switch {
 	case len(passphrase) < 10 || len(passphrase) > 79:
@@ -45,6 +71,16 @@ func srtCheckPassphrase(passphrase string) error {
 	}
 }
 
+// PathNotConfiguredError is returned when no path configuration matches a path name.
+type PathNotConfiguredError struct {
+	PathName string
+}
+
+// Error implements the error interface.
+func (e PathNotConfiguredError) Error() string {
+	return fmt.Sprintf("path '%s' is not configured", e.PathName)
+}
+
 // FindPathConf returns the configuration corresponding to the given path name.
 func FindPathConf(pathConfs map[string]*Path, name string) (*Path, []string, error) {
 	err := isValidPathName(name)
@@ -77,7 +113,7 @@ func FindPathConf(pathConfs map[string]*Path, name string) (*Path, []string, err
 		}
 	}
 
-	return nil, nil, fmt.Errorf("path '%s' is not configured", name)
+	return nil, nil, PathNotConfiguredError{PathName: name}
 }
 
 // Path is a path configuration.
@@ -87,23 +123,82 @@ type Path struct {
 	Name   string         `json:"name"` // filled by Check()
 
 	// General
-	Source                     string         `json:"source"`
-	SourceFingerprint          string         `json:"sourceFingerprint"`
-	SourceOnDemand             bool           `json:"sourceOnDemand"`
-	SourceOnDemandStartTimeout StringDuration `json:"sourceOnDemandStartTimeout"`
-	SourceOnDemandCloseAfter   StringDuration `json:"sourceOnDemandCloseAfter"`
-	MaxReaders                 int            `json:"maxReaders"`
-	SRTReadPassphrase          string         `json:"srtReadPassphrase"`
-	Fallback                   string         `json:"fallback"`
+	Profile                    string             `json:"profile"`
+	Enabled                    bool               `json:"enabled"`
+	Source                     string             `json:"source"`
+	SourceFingerprint          string             `json:"sourceFingerprint"`
+	SourceOnDemand             bool               `json:"sourceOnDemand"`
+	SourceOnDemandStartTimeout StringDuration     `json:"sourceOnDemandStartTimeout"`
+	SourceOnDemandCloseAfter   StringDuration     `json:"sourceOnDemandCloseAfter"`
+	SourceFailover             []string           `json:"sourceFailover"`
+	SourceFailbackDelay        StringDuration     `json:"sourceFailbackDelay"`
+	MaxReaders                 int                `json:"maxReaders"`
+	MaxEgressBytesPerHour      StringSize         `json:"maxEgressBytesPerHour"`
+	MaxEgressDropReaders       bool               `json:"maxEgressDropReaders"`
+	SRTReadPassphrase          string             `json:"srtReadPassphrase"`
+	SRTReadGracePassphrases    []string           `json:"srtReadGracePassphrases"`
+	SRTReadUserPassphrases     SRTUserPassphrases `json:"srtReadUserPassphrases"`
+	SRTRequireEncryption       bool               `json:"srtRequireEncryption"`
+	SRTEncryptionMode          SRTEncryptionMode  `json:"srtEncryptionMode"`
+	SRTSourceAllow             IPNetworks         `json:"srtSourceAllow"`
+	SRTSourceDeny              IPNetworks         `json:"srtSourceDeny"`
+	SRTDebugDump               bool               `json:"srtDebugDump"`
+	SRTDebugDumpPath           string             `json:"srtDebugDumpPath"`
+	SRTDebugDumpMaxSize        StringSize         `json:"srtDebugDumpMaxSize"`
+	SRTDebugDumpMaxDuration    StringDuration     `json:"srtDebugDumpMaxDuration"`
+	SRTCBRBitrate              uint               `json:"srtCBRBitrate"`
+	SRTPacing                  bool               `json:"srtPacing"`
+	SRTReadWarmup              bool               `json:"srtReadWarmup"`
+	SRTReadWarmupTimeout       StringDuration     `json:"srtReadWarmupTimeout"`
+	Fallback                   string             `json:"fallback"`
+	JitterBufferDelay          StringDuration     `json:"jitterBufferDelay"`
+	MaxTimestampCorrection     StringDuration     `json:"maxTimestampCorrection"`
+	TrackActiveTimeout         StringDuration     `json:"trackActiveTimeout"`
+	RTCPSenderReportPeriod     StringDuration     `json:"rtcpSenderReportPeriod"`
+	TargetKeyframeInterval     StringDuration     `json:"targetKeyframeInterval"`
+	MPEGTSPMTPID               uint               `json:"mpegtsPMTPID"`
+	MPEGTSTrackPIDs            MPEGTSPIDs         `json:"mpegtsTrackPIDs"`
+	TSServiceName              string             `json:"tsServiceName"`
+	TSProviderName             string             `json:"tsProviderName"`
 
 	// Record
-	Record                bool           `json:"record"`
-	Playback              *bool          `json:"playback,omitempty"` // deprecated
-	RecordPath            string         `json:"recordPath"`
-	RecordFormat          RecordFormat   `json:"recordFormat"`
-	RecordPartDuration    StringDuration `json:"recordPartDuration"`
-	RecordSegmentDuration StringDuration `json:"recordSegmentDuration"`
-	RecordDeleteAfter     StringDuration `json:"recordDeleteAfter"`
+	Record                         bool              `json:"record"`
+	Playback                       *bool             `json:"playback,omitempty"` // deprecated
+	RecordPath                     string            `json:"recordPath"`
+	RecordPathSanitize             bool              `json:"recordPathSanitize"`
+	RecordTimeZone                 string            `json:"recordTimeZone"`
+	RecordFormat                   RecordFormat      `json:"recordFormat"`
+	RecordCompression              RecordCompression `json:"recordCompression"`
+	RecordCaptions                 bool              `json:"recordCaptions"`
+	RecordTracks                   []string          `json:"recordTracks"`
+	RecordPartDuration             StringDuration    `json:"recordPartDuration"`
+	RecordFragmentDuration         StringDuration    `json:"recordFragmentDuration"`
+	RecordWriteSidx                bool              `json:"recordWriteSidx"`
+	RecordFillGaps                 bool              `json:"recordFillGaps"`
+	RecordWriteManifest            bool              `json:"recordWriteManifest"`
+	RecordSeparateInit             bool              `json:"recordSeparateInit"`
+	RecordSegmentDuration          StringDuration    `json:"recordSegmentDuration"`
+	RecordDeleteAfter              StringDuration    `json:"recordDeleteAfter"`
+	RecordMaxSegments              int               `json:"recordMaxSegments"`
+	RecordSchedule                 RecordSchedule    `json:"recordSchedule"`
+	RecordSnapshotInterval         int               `json:"recordSnapshotInterval"`
+	RecordSnapshotPath             string            `json:"recordSnapshotPath"`
+	RecordPostProcessCommand       string            `json:"recordPostProcessCommand"`
+	RecordPostProcessMaxConcurrent int               `json:"recordPostProcessMaxConcurrent"`
+	RecordPostProcessMaxRetries    int               `json:"recordPostProcessMaxRetries"`
+
+	// Transcode
+	Transcode        bool           `json:"transcode"`
+	TranscodeCodec   TranscodeCodec `json:"transcodeCodec"`
+	TranscodeCommand string         `json:"transcodeCommand"`
+
+	// Proxy
+	Proxy        bool   `json:"proxy"`
+	ProxyCommand string `json:"proxyCommand"`
+
+	// Authentication
+	PublishAuthMethod PathAuthMethod `json:"publishAuthMethod"`
+	ReadAuthMethod    PathAuthMethod `json:"readAuthMethod"`
 
 	// Authentication (deprecated)
 	PublishUser *Credential `json:"publishUser,omitempty"` // deprecated
@@ -114,9 +209,22 @@ type Path struct {
 	ReadIPs     *IPNetworks `json:"readIPs,omitempty"`     // deprecated
 
 	// Publisher source
-	OverridePublisher        bool   `json:"overridePublisher"`
-	DisablePublisherOverride *bool  `json:"disablePublisherOverride,omitempty"` // deprecated
-	SRTPublishPassphrase     string `json:"srtPublishPassphrase"`
+	PublisherConflictPolicy      PublisherConflictPolicy `json:"publisherConflictPolicy"`
+	OverridePublisher            *bool                   `json:"overridePublisher,omitempty"`        // deprecated
+	DisablePublisherOverride     *bool                   `json:"disablePublisherOverride,omitempty"` // deprecated
+	PublisherMaxAttempts         int                     `json:"publisherMaxAttempts"`
+	PublisherAttemptsWindow      StringDuration          `json:"publisherAttemptsWindow"`
+	PublisherBackoff             StringDuration          `json:"publisherBackoff"`
+	PublisherHoldTimeout         StringDuration          `json:"publisherHoldTimeout"`
+	StrictCodecValidation        bool                    `json:"strictCodecValidation"`
+	MaxPublishBitrate            uint                    `json:"maxPublishBitrate"`
+	MaxPublishBitrateGracePeriod StringDuration          `json:"maxPublishBitrateGracePeriod"`
+	SRTPublishPassphrase         string                  `json:"srtPublishPassphrase"`
+	SRTPublishGracePassphrases   []string                `json:"srtPublishGracePassphrases"`
+	SRTPublishUserPassphrases    SRTUserPassphrases      `json:"srtPublishUserPassphrases"`
+	WebRTCMaxBitrate             uint                    `json:"webrtcMaxBitrate"`
+	SRTRelay                     string                  `json:"srtRelay"`
+	SRTRelayMaxBitrate           uint                    `json:"srtRelayMaxBitrate"`
 
 	// RTSP source
 	RTSPTransport       RTSPTransport  `json:"rtspTransport"`
@@ -186,19 +294,44 @@ type Path struct {
 
 func (pconf *Path) setDefaults() {
 	// General
+	pconf.Enabled = true
 	pconf.Source = "publisher"
 	pconf.SourceOnDemandStartTimeout = 10 * StringDuration(time.Second)
 	pconf.SourceOnDemandCloseAfter = 10 * StringDuration(time.Second)
+	pconf.SourceFailover = []string{}
+	pconf.SourceFailbackDelay = 30 * StringDuration(time.Second)
+	pconf.SRTReadGracePassphrases = []string{}
+	pconf.SRTReadUserPassphrases = SRTUserPassphrases{}
+	pconf.SRTDebugDumpMaxSize = 50 * 1024 * 1024
+	pconf.SRTDebugDumpMaxDuration = 60 * StringDuration(time.Second)
+	pconf.SRTReadWarmupTimeout = 10 * StringDuration(time.Second)
+	pconf.TrackActiveTimeout = 5 * StringDuration(time.Second)
+	pconf.RTCPSenderReportPeriod = 10 * StringDuration(time.Second)
+	pconf.MPEGTSTrackPIDs = MPEGTSPIDs{}
 
 	// Record
 	pconf.RecordPath = "./recordings/%path/%Y-%m-%d_%H-%M-%S-%f"
+	pconf.RecordSnapshotPath = "./snapshots/%path/%Y-%m-%d_%H-%M-%S-%f"
 	pconf.RecordFormat = RecordFormatFMP4
+	pconf.RecordCompression = RecordCompressionNone
+	pconf.RecordTracks = []string{}
 	pconf.RecordPartDuration = StringDuration(1 * time.Second)
 	pconf.RecordSegmentDuration = 3600 * StringDuration(time.Second)
 	pconf.RecordDeleteAfter = 24 * 3600 * StringDuration(time.Second)
+	pconf.RecordSchedule = RecordSchedule{}
+	pconf.RecordPostProcessMaxConcurrent = 1
+	pconf.RecordPostProcessMaxRetries = 2
+
+	// Transcode
+	pconf.TranscodeCodec = TranscodeCodecH264
 
 	// Publisher source
-	pconf.OverridePublisher = true
+	pconf.PublisherConflictPolicy = PublisherConflictPolicyTakeover
+	pconf.PublisherAttemptsWindow = StringDuration(1 * time.Minute)
+	pconf.PublisherBackoff = StringDuration(10 * time.Second)
+	pconf.MaxPublishBitrateGracePeriod = StringDuration(5 * time.Second)
+	pconf.SRTPublishGracePassphrases = []string{}
+	pconf.SRTPublishUserPassphrases = SRTUserPassphrases{}
 
 	// Raspberry Pi Camera source
 	pconf.RPICameraWidth = 1920
@@ -227,13 +360,134 @@ func (pconf *Path) setDefaults() {
 	pconf.RunOnDemandCloseAfter = 10 * StringDuration(time.Second)
 }
 
-func newPath(defaults *Path, partial *OptionalPath) *Path {
+func newPath(defaults *Path, profiles map[string]*OptionalPath, partial *OptionalPath) *Path {
 	pconf := &Path{}
 	copyStructFields(pconf, defaults)
 	copyStructFields(pconf, partial.Values)
+
+	// settings of the profile override default settings
+	// and are overridden by settings of the path.
+	if profile, ok := profiles[pconf.Profile]; ok {
+		pconf = &Path{}
+		copyStructFields(pconf, defaults)
+		copyStructFields(pconf, profile.Values)
+		copyStructFields(pconf, partial.Values)
+	}
+
 	return pconf
 }
 
+// publisherConflictPolicyFromDeprecated returns the policy that corresponds to
+// deprecated parameters overridePublisher and disablePublisherOverride.
+func publisherConflictPolicyFromDeprecated(
+	overridePublisher *bool,
+	disablePublisherOverride *bool,
+) (PublisherConflictPolicy, bool) {
+	switch {
+	case overridePublisher != nil:
+		if *overridePublisher {
+			return PublisherConflictPolicyTakeover, true
+		}
+		return PublisherConflictPolicyReject, true
+
+	case disablePublisherOverride != nil:
+		if *disablePublisherOverride {
+			return PublisherConflictPolicyReject, true
+		}
+		return PublisherConflictPolicyTakeover, true
+	}
+
+	return 0, false
+}
+
+// migratePublisherOverride moves deprecated parameters overridePublisher and
+// disablePublisherOverride into publisherConflictPolicy.
+func (pconf *Path) migratePublisherOverride() {
+	if policy, ok := publisherConflictPolicyFromDeprecated(
+		pconf.OverridePublisher, pconf.DisablePublisherOverride); ok {
+		pconf.PublisherConflictPolicy = policy
+	}
+	pconf.OverridePublisher = nil
+	pconf.DisablePublisherOverride = nil
+}
+
+func checkSource(source string) error {
+	switch {
+	case source == "publisher":
+
+	case strings.HasPrefix(source, "rtsp://") ||
+		strings.HasPrefix(source, "rtsps://"):
+		_, err := base.ParseURL(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+	case strings.HasPrefix(source, "rtmp://") ||
+		strings.HasPrefix(source, "rtmps://"):
+		u, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+		if u.User != nil {
+			pass, _ := u.User.Password()
+			user := u.User.Username()
+			if user != "" && pass == "" ||
+				user == "" && pass != "" {
+				return fmt.Errorf("username and password must be both provided")
+			}
+		}
+
+	case strings.HasPrefix(source, "http://") ||
+		strings.HasPrefix(source, "https://"):
+		u, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+		if u.User != nil {
+			pass, _ := u.User.Password()
+			user := u.User.Username()
+			if user != "" && pass == "" ||
+				user == "" && pass != "" {
+				return fmt.Errorf("username and password must be both provided")
+			}
+		}
+
+	case strings.HasPrefix(source, "udp://"):
+		_, _, err := net.SplitHostPort(source[len("udp://"):])
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid UDP URL", source)
+		}
+
+	case strings.HasPrefix(source, "srt://"):
+
+		_, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+	case strings.HasPrefix(source, "whep://") ||
+		strings.HasPrefix(source, "wheps://"):
+		_, err := gourl.Parse(source)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", source)
+		}
+
+	case source == "redirect":
+
+	case source == "rpiCamera":
+
+	default:
+		return fmt.Errorf("invalid source: '%s'", source)
+	}
+
+	return nil
+}
+
 // Clone clones the configuration.
 func (pconf Path) Clone() *Path {
 	enc, err := json.Marshal(pconf)
@@ -252,6 +506,19 @@ func (pconf Path) Clone() *Path {
 	return &dest
 }
 
+// ActionAuthMethod returns the authentication method that applies to an action.
+// Actions that are not performed on paths (i.e. API) always use the global authentication method.
+func (pconf *Path) ActionAuthMethod(action AuthAction, global AuthMethod) AuthMethod {
+	switch action {
+	case AuthActionPublish:
+		return pconf.PublishAuthMethod.Resolve(global)
+
+	case AuthActionRead:
+		return pconf.ReadAuthMethod.Resolve(global)
+	}
+	return global
+}
+
 func (pconf *Path) validate(
 	conf *Conf,
 	name string,
@@ -279,82 +546,44 @@ func (pconf *Path) validate(
 
 	// General
 
+	if pconf.Profile != "" {
+		if _, ok := conf.Profiles[pconf.Profile]; !ok {
+			return fmt.Errorf("profile '%s' doesn't exist", pconf.Profile)
+		}
+	}
 	if pconf.Source != "publisher" && pconf.Source != "redirect" &&
 		pconf.Regexp != nil && !pconf.SourceOnDemand {
 		return fmt.Errorf("a path with a regular expression (or path 'all') and a static source" +
 			" must have 'sourceOnDemand' set to true")
 	}
-	switch {
-	case pconf.Source == "publisher":
-
-	case strings.HasPrefix(pconf.Source, "rtsp://") ||
-		strings.HasPrefix(pconf.Source, "rtsps://"):
-		_, err := base.ParseURL(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
-
-	case strings.HasPrefix(pconf.Source, "rtmp://") ||
-		strings.HasPrefix(pconf.Source, "rtmps://"):
-		u, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
+	err := checkSource(pconf.Source)
+	if err != nil {
+		return err
+	}
+	if len(pconf.SourceFailover) != 0 {
+		if !pconf.HasStaticSource() || pconf.Source == "rpiCamera" {
+			return fmt.Errorf("'sourceFailover' can be used only when source is a URL")
 		}
 
-		if u.User != nil {
-			pass, _ := u.User.Password()
-			user := u.User.Username()
-			if user != "" && pass == "" ||
-				user == "" && pass != "" {
-				return fmt.Errorf("username and password must be both provided")
+		for _, source := range pconf.SourceFailover {
+			if source == "publisher" || source == "redirect" || source == "rpiCamera" {
+				return fmt.Errorf("'sourceFailover' can contain URLs only")
 			}
-		}
-
-	case strings.HasPrefix(pconf.Source, "http://") ||
-		strings.HasPrefix(pconf.Source, "https://"):
-		u, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
-		if u.Scheme != "http" && u.Scheme != "https" {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
 
-		if u.User != nil {
-			pass, _ := u.User.Password()
-			user := u.User.Username()
-			if user != "" && pass == "" ||
-				user == "" && pass != "" {
-				return fmt.Errorf("username and password must be both provided")
+			err = checkSource(source)
+			if err != nil {
+				return fmt.Errorf("invalid 'sourceFailover': %w", err)
 			}
 		}
-
-	case strings.HasPrefix(pconf.Source, "udp://"):
-		_, _, err := net.SplitHostPort(pconf.Source[len("udp://"):])
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid UDP URL", pconf.Source)
-		}
-
-	case strings.HasPrefix(pconf.Source, "srt://"):
-
-		_, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
-
-	case strings.HasPrefix(pconf.Source, "whep://") ||
-		strings.HasPrefix(pconf.Source, "wheps://"):
-		_, err := gourl.Parse(pconf.Source)
-		if err != nil {
-			return fmt.Errorf("'%s' is not a valid URL", pconf.Source)
-		}
-
-	case pconf.Source == "redirect":
-
-	case pconf.Source == "rpiCamera":
-
-	default:
-		return fmt.Errorf("invalid source: '%s'", pconf.Source)
+	}
+	if pconf.SourceFailbackDelay <= 0 {
+		return fmt.Errorf("'sourceFailbackDelay' must be greater than zero")
+	}
+	if pconf.SourceOnDemandStartTimeout <= 0 {
+		return fmt.Errorf("'sourceOnDemandStartTimeout' must be greater than zero")
+	}
+	if pconf.SourceOnDemandCloseAfter < 0 {
+		return fmt.Errorf("'sourceOnDemandCloseAfter' can't be negative")
 	}
 	if pconf.SourceOnDemand {
 		if pconf.Source == "publisher" {
@@ -367,6 +596,14 @@ func (pconf *Path) validate(
 			return fmt.Errorf("invalid 'readRTPassphrase': %w", err)
 		}
 	}
+	err = srtCheckGracePassphrases(pconf.SRTReadPassphrase, pconf.SRTReadGracePassphrases)
+	if err != nil {
+		return fmt.Errorf("invalid 'srtReadGracePassphrases': %w", err)
+	}
+	err = pconf.SRTReadUserPassphrases.validate()
+	if err != nil {
+		return fmt.Errorf("invalid 'srtReadUserPassphrases': %w", err)
+	}
 	if pconf.Fallback != "" {
 		if strings.HasPrefix(pconf.Fallback, "/") {
 			err := isValidPathName(pconf.Fallback[1:])
@@ -380,9 +617,93 @@ func (pconf *Path) validate(
 			}
 		}
 	}
+	if pconf.JitterBufferDelay < 0 {
+		return fmt.Errorf("'jitterBufferDelay' can't be negative")
+	}
+	if pconf.MaxTimestampCorrection < 0 {
+		return fmt.Errorf("'maxTimestampCorrection' can't be negative")
+	}
+	if pconf.RTCPSenderReportPeriod <= 0 {
+		return fmt.Errorf("'rtcpSenderReportPeriod' must be greater than zero")
+	}
+	if pconf.TrackActiveTimeout <= 0 {
+		return fmt.Errorf("'trackActiveTimeout' must be greater than zero")
+	}
+	if pconf.TargetKeyframeInterval < 0 {
+		return fmt.Errorf("'targetKeyframeInterval' can't be negative")
+	}
+	err = checkMPEGTSPIDs(pconf.MPEGTSPMTPID, pconf.MPEGTSTrackPIDs)
+	if err != nil {
+		return err
+	}
+	if len(pconf.TSServiceName) > 64 {
+		return fmt.Errorf("'tsServiceName' must be at most 64 bytes long")
+	}
+	if len(pconf.TSProviderName) > 64 {
+		return fmt.Errorf("'tsProviderName' must be at most 64 bytes long")
+	}
 
 	// Record
 
+	if pconf.RecordPartDuration <= 0 {
+		return fmt.Errorf("'recordPartDuration' must be greater than zero")
+	}
+	if pconf.RecordSegmentDuration <= 0 {
+		return fmt.Errorf("'recordSegmentDuration' must be greater than zero")
+	}
+	if pconf.RecordFragmentDuration < 0 {
+		return fmt.Errorf("'recordFragmentDuration' can't be negative")
+	}
+	if pconf.RecordDeleteAfter < 0 {
+		return fmt.Errorf("'recordDeleteAfter' can't be negative")
+	}
+	if pconf.RecordMaxSegments < 0 {
+		return fmt.Errorf("'recordMaxSegments' can't be negative")
+	}
+	if pconf.RecordFragmentDuration != 0 && pconf.RecordFragmentDuration < pconf.RecordPartDuration {
+		return fmt.Errorf("'recordFragmentDuration' must be greater than or equal to 'recordPartDuration'")
+	}
+	if pconf.RecordWriteSidx && pconf.RecordFormat != RecordFormatFMP4 {
+		return fmt.Errorf("'recordWriteSidx' can be used only when 'recordFormat' is 'fmp4'")
+	}
+	if pconf.RecordFillGaps && pconf.RecordFormat != RecordFormatFMP4 {
+		return fmt.Errorf("'recordFillGaps' can be used only when 'recordFormat' is 'fmp4'")
+	}
+	if pconf.RecordWriteManifest && pconf.RecordFormat != RecordFormatFMP4 {
+		return fmt.Errorf("'recordWriteManifest' can be used only when 'recordFormat' is 'fmp4'")
+	}
+	if pconf.RecordSeparateInit && pconf.RecordFormat != RecordFormatFMP4 {
+		return fmt.Errorf("'recordSeparateInit' can be used only when 'recordFormat' is 'fmp4'")
+	}
+	if pconf.RecordCompression != RecordCompressionNone && pconf.RecordFormat != RecordFormatMPEGTS {
+		return fmt.Errorf("'recordCompression' can be used only when 'recordFormat' is 'mpegts'")
+	}
+	if pconf.RecordTimeZone != "" {
+		if _, err := time.LoadLocation(pconf.RecordTimeZone); err != nil {
+			return fmt.Errorf("invalid 'recordTimeZone': %w", err)
+		}
+	}
+	if pconf.RecordSnapshotInterval < 0 {
+		return fmt.Errorf("'recordSnapshotInterval' can't be negative")
+	}
+	if pconf.RecordSnapshotInterval != 0 && pconf.RecordSnapshotPath == "" {
+		return fmt.Errorf("'recordSnapshotPath' is required when 'recordSnapshotInterval' is set")
+	}
+	if pconf.RecordPostProcessMaxConcurrent <= 0 {
+		return fmt.Errorf("'recordPostProcessMaxConcurrent' must be greater than zero")
+	}
+	if pconf.RecordPostProcessMaxRetries < 0 {
+		return fmt.Errorf("'recordPostProcessMaxRetries' can't be negative")
+	}
+	for _, sel := range pconf.RecordTracks {
+		if sel == "" {
+			return fmt.Errorf("'recordTracks' contains an empty selector")
+		}
+		if n, err := strconv.Atoi(sel); err == nil && n <= 0 {
+			return fmt.Errorf("invalid track index in 'recordTracks': %d", n)
+		}
+	}
+
 	if conf.Playback {
 		if !strings.Contains(pconf.RecordPath, "%Y") ||
 			!strings.Contains(pconf.RecordPath, "%m") ||
@@ -397,6 +718,45 @@ func (pconf *Path) validate(
 		}
 	}
 
+	// Transcode
+
+	if pconf.Transcode && pconf.TranscodeCommand == "" {
+		return fmt.Errorf("'transcodeCommand' is required when 'transcode' is enabled")
+	}
+
+	// Proxy
+
+	if pconf.Proxy && pconf.ProxyCommand == "" {
+		return fmt.Errorf("'proxyCommand' is required when 'proxy' is enabled")
+	}
+
+	// Authentication
+
+	for _, m := range []struct {
+		key    string
+		method PathAuthMethod
+	}{
+		{"publishAuthMethod", pconf.PublishAuthMethod},
+		{"readAuthMethod", pconf.ReadAuthMethod},
+	} {
+		switch m.method {
+		case PathAuthMethodHTTP:
+			if conf.AuthHTTPAddress == "" {
+				return fmt.Errorf("'%s' is 'http' but 'authHTTPAddress' is empty", m.key)
+			}
+
+		case PathAuthMethodJWT:
+			if conf.AuthJWTJWKS == "" || conf.AuthJWTClaimKey == "" {
+				return fmt.Errorf("'%s' is 'jwt' but 'authJWTJWKS' or 'authJWTClaimKey' is empty", m.key)
+			}
+		}
+
+		if m.method.Resolve(conf.AuthMethod) != AuthMethodInternal &&
+			contains(conf.RTSPAuthMethods, auth.ValidateMethodDigestMD5) {
+			return fmt.Errorf("when RTSP digest is enabled, the only supported value of '%s' is 'internal'", m.key)
+		}
+	}
+
 	// Authentication (deprecated)
 
 	if deprecatedCredentialsMode {
@@ -467,8 +827,27 @@ func (pconf *Path) validate(
 
 	// Publisher source
 
-	if pconf.DisablePublisherOverride != nil {
-		pconf.OverridePublisher = !*pconf.DisablePublisherOverride
+	// deprecated parameters have already been migrated into publisherConflictPolicy.
+	// overridePublisher is filled in order to keep it available to API consumers.
+	overridePublisher := pconf.PublisherConflictPolicy == PublisherConflictPolicyTakeover
+	pconf.OverridePublisher = &overridePublisher
+	pconf.DisablePublisherOverride = nil
+	if pconf.PublisherMaxAttempts < 0 {
+		return fmt.Errorf("'publisherMaxAttempts' can't be negative")
+	}
+	if pconf.PublisherMaxAttempts != 0 {
+		if pconf.PublisherAttemptsWindow <= 0 {
+			return fmt.Errorf("'publisherAttemptsWindow' must be greater than zero")
+		}
+		if pconf.PublisherBackoff <= 0 {
+			return fmt.Errorf("'publisherBackoff' must be greater than zero")
+		}
+	}
+	if pconf.PublisherHoldTimeout < 0 {
+		return fmt.Errorf("'publisherHoldTimeout' can't be negative")
+	}
+	if pconf.MaxPublishBitrateGracePeriod < 0 {
+		return fmt.Errorf("'maxPublishBitrateGracePeriod' can't be negative")
 	}
 	if pconf.SRTPublishPassphrase != "" {
 		if pconf.Source != "publisher" {
@@ -480,6 +859,55 @@ func (pconf *Path) validate(
 			return fmt.Errorf("invalid 'srtPublishPassphrase': %w", err)
 		}
 	}
+	err = srtCheckGracePassphrases(pconf.SRTPublishPassphrase, pconf.SRTPublishGracePassphrases)
+	if err != nil {
+		return fmt.Errorf("invalid 'srtPublishGracePassphrases': %w", err)
+	}
+	if len(pconf.SRTPublishUserPassphrases) != 0 && pconf.Source != "publisher" {
+		return fmt.Errorf("'srtPublishUserPassphrases' can only be used when source is 'publisher'")
+	}
+	err = pconf.SRTPublishUserPassphrases.validate()
+	if err != nil {
+		return fmt.Errorf("invalid 'srtPublishUserPassphrases': %w", err)
+	}
+	if pconf.SRTRelay != "" {
+		if pconf.Source != "publisher" {
+			return fmt.Errorf("'srtRelay' can only be used when source is 'publisher'")
+		}
+		if !strings.HasPrefix(pconf.SRTRelay, "srt://") {
+			return fmt.Errorf("'srtRelay' must be a SRT URL")
+		}
+		_, err := gourl.Parse(pconf.SRTRelay)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid URL", pconf.SRTRelay)
+		}
+	}
+	if pconf.SRTDebugDump && pconf.SRTDebugDumpPath == "" {
+		return fmt.Errorf("'srtDebugDump' requires 'srtDebugDumpPath' to be set")
+	}
+	if pconf.SRTDebugDumpPath != "" && !strings.Contains(pconf.SRTDebugDumpPath, "%f") {
+		return fmt.Errorf("'srtDebugDumpPath' must contain %%f")
+	}
+	if pconf.SRTDebugDumpMaxSize == 0 {
+		return fmt.Errorf("'srtDebugDumpMaxSize' must be greater than zero")
+	}
+	if pconf.SRTDebugDumpMaxDuration <= 0 {
+		return fmt.Errorf("'srtDebugDumpMaxDuration' must be greater than zero")
+	}
+	if pconf.SRTReadWarmupTimeout <= 0 {
+		return fmt.Errorf("'srtReadWarmupTimeout' must be greater than zero")
+	}
+	if pconf.SRTPacing && pconf.SRTCBRBitrate != 0 {
+		return fmt.Errorf("'srtPacing' and 'srtCBRBitrate' can't be used together")
+	}
+	if pconf.SRTEncryptionMode == SRTEncryptionModeGCM {
+		if pconf.SRTReadPassphrase == "" && pconf.SRTPublishPassphrase == "" {
+			return fmt.Errorf("'srtEncryptionMode' is 'gcm', but no SRT passphrase is set")
+		}
+
+		// the SRT library in use implements AES-CTR only.
+		return fmt.Errorf("'srtEncryptionMode' 'gcm' is not supported by the SRT library in use")
+	}
 
 	// RTSP source
 
@@ -567,6 +995,12 @@ func (pconf *Path) validate(
 	if (pconf.RunOnDemand != "" || pconf.RunOnUnDemand != "") && pconf.Source != "publisher" {
 		return fmt.Errorf("'runOnDemand' and 'runOnUnDemand' can be used only when source is 'publisher'")
 	}
+	if pconf.RunOnDemandStartTimeout <= 0 {
+		return fmt.Errorf("'runOnDemandStartTimeout' must be greater than zero")
+	}
+	if pconf.RunOnDemandCloseAfter < 0 {
+		return fmt.Errorf("'runOnDemandCloseAfter' can't be negative")
+	}
 
 	return nil
 }
@@ -576,6 +1010,19 @@ func (pconf *Path) Equal(other *Path) bool {
 	return reflect.DeepEqual(pconf, other)
 }
 
+// RecordLocation returns the time zone of time variables of the recording path.
+func (pconf Path) RecordLocation() *time.Location {
+	if pconf.RecordTimeZone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(pconf.RecordTimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
 // HasStaticSource checks whether the path has a static source.
 func (pconf Path) HasStaticSource() bool {
 	return pconf.Source != "publisher" && pconf.Source != "redirect"
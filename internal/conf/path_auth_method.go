@@ -0,0 +1,88 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PathAuthMethod is the authentication method of an action performed on a path.
+type PathAuthMethod int
+
+// supported values.
+const (
+	PathAuthMethodDefault PathAuthMethod = iota
+	PathAuthMethodInternal
+	PathAuthMethodHTTP
+	PathAuthMethodJWT
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d PathAuthMethod) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case PathAuthMethodInternal:
+		out = "internal"
+
+	case PathAuthMethodHTTP:
+		out = "http"
+
+	case PathAuthMethodJWT:
+		out = "jwt"
+
+	default:
+		out = "default"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *PathAuthMethod) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "default":
+		*d = PathAuthMethodDefault
+
+	case "internal":
+		*d = PathAuthMethodInternal
+
+	case "http":
+		*d = PathAuthMethodHTTP
+
+	case "jwt":
+		*d = PathAuthMethodJWT
+
+	default:
+		return fmt.Errorf("invalid auth method '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *PathAuthMethod) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
+
+// Resolve returns the authentication method to use,
+// given the global authentication method.
+func (d PathAuthMethod) Resolve(global AuthMethod) AuthMethod {
+	switch d {
+	case PathAuthMethodInternal:
+		return AuthMethodInternal
+
+	case PathAuthMethodHTTP:
+		return AuthMethodHTTP
+
+	case PathAuthMethodJWT:
+		return AuthMethodJWT
+
+	default:
+		return global
+	}
+}
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PublisherConflictPolicy is the publisherConflictPolicy parameter.
+type PublisherConflictPolicy int
+
+// supported values.
+const (
+	PublisherConflictPolicyTakeover PublisherConflictPolicy = iota
+	PublisherConflictPolicyReject
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d PublisherConflictPolicy) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case PublisherConflictPolicyReject:
+		out = "reject"
+
+	default:
+		out = "takeover"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *PublisherConflictPolicy) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "reject":
+		*d = PublisherConflictPolicyReject
+
+	case "takeover":
+		*d = PublisherConflictPolicyTakeover
+
+	default:
+		return fmt.Errorf("invalid publisher conflict policy '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *PublisherConflictPolicy) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
@@ -0,0 +1,63 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RecordCompression is the recordCompression parameter.
+type RecordCompression int
+
+// supported values.
+const (
+	RecordCompressionNone RecordCompression = iota
+	RecordCompressionGzip
+	RecordCompressionZstd
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d RecordCompression) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case RecordCompressionGzip:
+		out = "gzip"
+
+	case RecordCompressionZstd:
+		out = "zstd"
+
+	default:
+		out = "none"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *RecordCompression) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "none":
+		*d = RecordCompressionNone
+
+	case "gzip":
+		*d = RecordCompressionGzip
+
+	case "zstd":
+		*d = RecordCompressionZstd
+
+	default:
+		return fmt.Errorf("invalid record compression '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *RecordCompression) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
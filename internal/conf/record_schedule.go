@@ -0,0 +1,221 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var recordScheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func parseRecordScheduleDays(s string) ([7]bool, error) {
+	var days [7]bool
+
+	if s == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(item, "-")
+
+		start, ok := recordScheduleDays[first]
+		if !ok {
+			return days, fmt.Errorf("invalid day '%s'", first)
+		}
+
+		end := start
+		if isRange {
+			end, ok = recordScheduleDays[last]
+			if !ok {
+				return days, fmt.Errorf("invalid day '%s'", last)
+			}
+		}
+
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+
+	return days, nil
+}
+
+func parseRecordScheduleClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s'", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// RecordScheduleWindow is a time window in which recording is enabled.
+type RecordScheduleWindow struct {
+	raw   string
+	days  [7]bool
+	start int // minutes since midnight
+	end   int // minutes since midnight
+}
+
+func (w *RecordScheduleWindow) unmarshal(s string) error {
+	fields := strings.Fields(s)
+
+	var daysStr, clockStr string
+
+	switch len(fields) {
+	case 1:
+		daysStr, clockStr = "*", fields[0]
+
+	case 2:
+		daysStr, clockStr = strings.ToLower(fields[0]), fields[1]
+
+	default:
+		return fmt.Errorf("invalid record schedule window '%s'", s)
+	}
+
+	days, err := parseRecordScheduleDays(daysStr)
+	if err != nil {
+		return fmt.Errorf("invalid record schedule window '%s': %w", s, err)
+	}
+
+	startStr, endStr, ok := strings.Cut(clockStr, "-")
+	if !ok {
+		return fmt.Errorf("invalid record schedule window '%s'", s)
+	}
+
+	start, err := parseRecordScheduleClock(startStr)
+	if err != nil {
+		return fmt.Errorf("invalid record schedule window '%s': %w", s, err)
+	}
+
+	end, err := parseRecordScheduleClock(endStr)
+	if err != nil {
+		return fmt.Errorf("invalid record schedule window '%s': %w", s, err)
+	}
+
+	w.raw = strings.Join(fields, " ")
+	w.days = days
+	w.start = start
+	w.end = end
+
+	return nil
+}
+
+// active returns whether the window contains the given time.
+// Windows whose end is not after their start end in the following day.
+func (w RecordScheduleWindow) active(t time.Time) bool {
+	minutes := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+
+	if w.start < w.end {
+		return w.days[day] && minutes >= w.start && minutes < w.end
+	}
+
+	return (w.days[day] && minutes >= w.start) ||
+		(w.days[(day+6)%7] && minutes < w.end)
+}
+
+// RecordSchedule is the recordSchedule parameter.
+type RecordSchedule []RecordScheduleWindow
+
+// MarshalJSON implements json.Marshaler.
+func (s RecordSchedule) MarshalJSON() ([]byte, error) {
+	out := make([]string, len(s))
+
+	for i, w := range s {
+		out[i] = w.raw
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *RecordSchedule) UnmarshalJSON(b []byte) error {
+	var in []string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*s = RecordSchedule{}
+
+	for _, item := range in {
+		var w RecordScheduleWindow
+		err := w.unmarshal(item)
+		if err != nil {
+			return err
+		}
+
+		*s = append(*s, w)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+// Windows are separated by semicolons, since commas are used inside day lists.
+func (s *RecordSchedule) UnmarshalEnv(_ string, v string) error {
+	if v == "" {
+		*s = RecordSchedule{}
+		return nil
+	}
+
+	byts, _ := json.Marshal(strings.Split(v, ";"))
+	return s.UnmarshalJSON(byts)
+}
+
+// Active returns whether the given time is inside at least one window.
+func (s RecordSchedule) Active(t time.Time) bool {
+	for _, w := range s {
+		if w.active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange returns the first instant after t in which Active changes value.
+// It returns the zero time when Active never changes.
+func (s RecordSchedule) NextChange(t time.Time) time.Time {
+	var candidates []time.Time
+
+	// boundaries are recurring every week, therefore looking
+	// a little more than one week ahead is enough.
+	for d := 0; d <= 8; d++ {
+		for _, w := range s {
+			for _, minutes := range []int{w.start, w.end} {
+				c := time.Date(t.Year(), t.Month(), t.Day()+d, minutes/60, minutes%60, 0, 0, t.Location())
+				if c.After(t) {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Before(candidates[j])
+	})
+
+	cur := s.Active(t)
+
+	for _, c := range candidates {
+		if s.Active(c) != cur {
+			return c
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,107 @@
+package conf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordScheduleUnmarshal(t *testing.T) {
+	var s RecordSchedule
+	err := json.Unmarshal([]byte(`["mon-fri  08:00-18:00", "22:00-06:00", "sat,sun 10:00-12:00"]`), &s)
+	require.NoError(t, err)
+	require.Len(t, s, 3)
+
+	byts, err := json.Marshal(s)
+	require.NoError(t, err)
+	require.Equal(t, `["mon-fri 08:00-18:00","22:00-06:00","sat,sun 10:00-12:00"]`, string(byts))
+
+	var s2 RecordSchedule
+	err = s2.UnmarshalEnv("", "mon-fri 08:00-18:00;22:00-06:00;sat,sun 10:00-12:00")
+	require.NoError(t, err)
+	require.Equal(t, s, s2)
+
+	// an empty value disables the schedule
+	err = s2.UnmarshalEnv("", "")
+	require.NoError(t, err)
+	require.Empty(t, s2)
+
+	for _, ca := range []string{
+		"08:00",
+		"08:00-25:00",
+		"foo 08:00-18:00",
+		"mon-foo 08:00-18:00",
+		"mon 08:00-18:00 foo",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			var s RecordSchedule
+			err := json.Unmarshal([]byte(`["`+ca+`"]`), &s)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRecordScheduleActive(t *testing.T) {
+	var s RecordSchedule
+	err := json.Unmarshal([]byte(`["mon-fri 08:00-12:00", "mon-fri 11:00-13:00", "fri 22:00-02:00"]`), &s)
+	require.NoError(t, err)
+
+	// 2024-01-05 is a friday
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	for _, ca := range []struct {
+		t      time.Time
+		active bool
+	}{
+		{at(5, 7, 59), false},
+		{at(5, 8, 0), true},
+		{at(5, 11, 30), true},
+		{at(5, 12, 30), true},
+		{at(5, 13, 0), false},
+		{at(5, 23, 0), true},
+		{at(6, 1, 59), true},
+		{at(6, 2, 0), false},
+		{at(6, 10, 0), false},
+		{at(4, 23, 0), false},
+	} {
+		require.Equal(t, ca.active, s.Active(ca.t), ca.t.String())
+	}
+}
+
+func TestRecordScheduleNextChange(t *testing.T) {
+	var s RecordSchedule
+	err := json.Unmarshal([]byte(`["mon-fri 08:00-12:00", "mon-fri 11:00-13:00", "fri 22:00-02:00"]`), &s)
+	require.NoError(t, err)
+
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// simulate a clock that jumps from one change to the next one,
+	// starting from a thursday evening.
+	now := at(4, 20, 0)
+
+	var changes []time.Time
+	for i := 0; i < 5; i++ {
+		now = s.NextChange(now)
+		changes = append(changes, now)
+	}
+
+	require.Equal(t, []time.Time{
+		at(5, 8, 0),
+		at(5, 13, 0),
+		at(5, 22, 0),
+		at(6, 2, 0),
+		at(8, 8, 0),
+	}, changes)
+
+	var always RecordSchedule
+	err = json.Unmarshal([]byte(`["00:00-00:00"]`), &always)
+	require.NoError(t, err)
+	require.True(t, always.Active(now))
+	require.True(t, always.NextChange(now).IsZero())
+}
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SRTEncryptionMode is the srtEncryptionMode parameter.
+type SRTEncryptionMode int
+
+// supported values.
+const (
+	SRTEncryptionModeCTR SRTEncryptionMode = iota
+	SRTEncryptionModeGCM
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d SRTEncryptionMode) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case SRTEncryptionModeGCM:
+		out = "gcm"
+
+	default:
+		out = "ctr"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *SRTEncryptionMode) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "gcm":
+		*d = SRTEncryptionModeGCM
+
+	case "ctr":
+		*d = SRTEncryptionModeCTR
+
+	default:
+		return fmt.Errorf("invalid SRT encryption mode '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *SRTEncryptionMode) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
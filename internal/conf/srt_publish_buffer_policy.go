@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SRTPublishBufferPolicy is the srtPublishBufferPolicy parameter.
+type SRTPublishBufferPolicy int
+
+// supported values.
+const (
+	SRTPublishBufferPolicyBlock SRTPublishBufferPolicy = iota
+	SRTPublishBufferPolicyDropOldest
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d SRTPublishBufferPolicy) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case SRTPublishBufferPolicyDropOldest:
+		out = "dropOldest"
+
+	default:
+		out = "block"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *SRTPublishBufferPolicy) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "dropOldest":
+		*d = SRTPublishBufferPolicyDropOldest
+
+	case "block":
+		*d = SRTPublishBufferPolicyBlock
+
+	default:
+		return fmt.Errorf("invalid SRT publish buffer policy '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *SRTPublishBufferPolicy) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
@@ -0,0 +1,44 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SRTUserPassphrases is a parameter that contains SRT passphrases of users.
+type SRTUserPassphrases map[string]string
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *SRTUserPassphrases) UnmarshalEnv(_ string, v string) error {
+	*d = SRTUserPassphrases{}
+
+	if v == "" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid entry '%s'", entry)
+		}
+
+		(*d)[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return nil
+}
+
+func (d SRTUserPassphrases) validate() error {
+	for user, passphrase := range d {
+		if user == "" {
+			return fmt.Errorf("user cannot be empty")
+		}
+
+		err := srtCheckPassphrase(passphrase)
+		if err != nil {
+			return fmt.Errorf("passphrase of user '%s': %w", user, err)
+		}
+	}
+
+	return nil
+}
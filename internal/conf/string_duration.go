@@ -2,6 +2,7 @@ package conf
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -18,12 +19,18 @@ func (d StringDuration) MarshalJSON() ([]byte, error) {
 func (d *StringDuration) UnmarshalJSON(b []byte) error {
 	var in string
 	if err := json.Unmarshal(b, &in); err != nil {
-		return err
+		// numbers are rejected since their unit would be ambiguous
+		var num json.Number
+		if json.Unmarshal(b, &num) != nil {
+			return err
+		}
+		in = num.String()
 	}
 
 	du, err := time.ParseDuration(in)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid duration '%s': it must be a number "+
+			"followed by one of the units ms, s, m, h (i.e. 10s)", in)
 	}
 	*d = StringDuration(du)
 
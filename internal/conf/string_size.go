@@ -2,11 +2,64 @@ package conf
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"code.cloudfoundry.org/bytefmt"
 )
 
+// multipliers of size suffixes.
+// For compatibility reasons, all suffixes are powers of 1024.
+var sizeSuffixes = map[string]uint64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+func parseSize(in string) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(in))
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	mult, ok := sizeSuffixes[strings.TrimSpace(s[i:])]
+	if !ok || i == 0 {
+		return 0, fmt.Errorf("invalid size '%s': it must be a positive number "+
+			"followed by one of the suffixes B, KB, MB, GB, TB (i.e. 10MB)", in)
+	}
+
+	v, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s': '%s' is not a number", in, s[:i])
+	}
+
+	v *= float64(mult)
+	if v >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid size '%s': value is too big", in)
+	}
+
+	return uint64(v), nil
+}
+
 // StringSize is a size that is unmarshaled from a string.
+// Sizes are expressed in bytes, with an optional suffix (B, KB, MB, GB, TB).
 type StringSize uint64
 
 // MarshalJSON implements json.Marshaler.
@@ -16,12 +69,19 @@ func (s StringSize) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON implements json.Unmarshaler.
 func (s *StringSize) UnmarshalJSON(b []byte) error {
+	// allow sizes without suffix to be expressed as numbers
+	var num uint64
+	if err := json.Unmarshal(b, &num); err == nil {
+		*s = StringSize(num)
+		return nil
+	}
+
 	var in string
 	if err := json.Unmarshal(b, &in); err != nil {
 		return err
 	}
 
-	v, err := bytefmt.ToBytes(in)
+	v, err := parseSize(in)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,87 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringSizeUnmarshal(t *testing.T) {
+	for _, ca := range []struct {
+		in  string
+		out StringSize
+	}{
+		{"1024", 1024},
+		{"10B", 10},
+		{"1K", 1024},
+		{"1KB", 1024},
+		{"1KiB", 1024},
+		{"10M", 10 * 1024 * 1024},
+		{"10MB", 10 * 1024 * 1024},
+		{"10mb", 10 * 1024 * 1024},
+		{"1.5M", 1536 * 1024},
+		{"10 MB", 10 * 1024 * 1024},
+		{"2G", 2 * 1024 * 1024 * 1024},
+		{"1TB", 1024 * 1024 * 1024 * 1024},
+	} {
+		t.Run(ca.in, func(t *testing.T) {
+			var s StringSize
+			err := s.UnmarshalJSON([]byte(`"` + ca.in + `"`))
+			require.NoError(t, err)
+			require.Equal(t, ca.out, s)
+		})
+	}
+
+	t.Run("number", func(t *testing.T) {
+		var s StringSize
+		err := s.UnmarshalJSON([]byte(`2048`))
+		require.NoError(t, err)
+		require.Equal(t, StringSize(2048), s)
+	})
+}
+
+func TestStringSizeUnmarshalErrors(t *testing.T) {
+	for _, ca := range []struct {
+		in  string
+		err string
+	}{
+		{
+			"10MBs",
+			"invalid size '10MBs': it must be a positive number followed by one of the suffixes B, KB, MB, GB, TB (i.e. 10MB)",
+		},
+		{
+			"MB",
+			"invalid size 'MB': it must be a positive number followed by one of the suffixes B, KB, MB, GB, TB (i.e. 10MB)",
+		},
+		{
+			"-10M",
+			"invalid size '-10M': it must be a positive number followed by one of the suffixes B, KB, MB, GB, TB (i.e. 10MB)",
+		},
+		{
+			"1.2.3M",
+			"invalid size '1.2.3M': '1.2.3' is not a number",
+		},
+		{
+			"100000000TB",
+			"invalid size '100000000TB': value is too big",
+		},
+	} {
+		t.Run(ca.in, func(t *testing.T) {
+			var s StringSize
+			err := s.UnmarshalJSON([]byte(`"` + ca.in + `"`))
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
+func TestStringSizeMarshal(t *testing.T) {
+	for _, v := range []StringSize{0, 512, 1024 * 1024, 50 * 1024 * 1024, 1536 * 1024} {
+		byts, err := v.MarshalJSON()
+		require.NoError(t, err)
+
+		var dec StringSize
+		err = dec.UnmarshalJSON(byts)
+		require.NoError(t, err)
+		require.Equal(t, v, dec)
+	}
+}
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TranscodeCodec is the transcodeCodec parameter.
+type TranscodeCodec int
+
+// supported values.
+const (
+	TranscodeCodecH264 TranscodeCodec = iota
+	TranscodeCodecH265
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d TranscodeCodec) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case TranscodeCodecH265:
+		out = "h265"
+
+	default:
+		out = "h264"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *TranscodeCodec) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "h265":
+		*d = TranscodeCodecH265
+
+	case "h264":
+		*d = TranscodeCodecH264
+
+	default:
+		return fmt.Errorf("invalid transcode codec '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *TranscodeCodec) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
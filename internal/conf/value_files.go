@@ -0,0 +1,133 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const valueFileSuffix = "File"
+
+func jsonFields(rt reflect.Type) map[string]reflect.Type {
+	ret := make(map[string]reflect.Type)
+	nf := rt.NumField()
+
+	for i := 0; i < nf; i++ {
+		f := rt.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+		if name != "" && name != "-" {
+			ret[name] = f.Type
+		}
+	}
+
+	return ret
+}
+
+func readValueFile(key string, fpath interface{}) (string, error) {
+	fpathStr, ok := fpath.(string)
+	if !ok {
+		return "", fmt.Errorf("'%s' must be a string", key)
+	}
+
+	byts, err := os.ReadFile(fpathStr)
+	if err != nil {
+		return "", fmt.Errorf("unable to read '%s': %w", key, err)
+	}
+
+	return strings.TrimRight(string(byts), "\r\n"), nil
+}
+
+// loadValueFiles replaces every "[key]File" entry of a generic configuration
+// with a "[key]" entry containing the content of the file, provided that
+// "[key]" is a string parameter.
+// This allows to store secrets outside of the configuration file.
+func loadValueFiles(v interface{}, rt reflect.Type) error {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	// OptionalPath contains the same fields of Path
+	if rt == reflect.TypeOf(OptionalPath{}) {
+		rt = reflect.TypeOf(Path{})
+	}
+
+	switch rt.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		fields := jsonFields(rt)
+
+		for key, val := range m {
+			if ft, ok := fields[key]; ok {
+				err := loadValueFiles(val, ft)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			base, ok := strings.CutSuffix(key, valueFileSuffix)
+			if !ok {
+				continue
+			}
+
+			ft, ok := fields[base]
+			if !ok {
+				continue
+			}
+
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() != reflect.String {
+				continue
+			}
+
+			if _, ok := m[base]; ok {
+				return fmt.Errorf("'%s' and '%s' cannot be used together", base, key)
+			}
+
+			content, err := readValueFile(key, val)
+			if err != nil {
+				return err
+			}
+
+			delete(m, key)
+			m[base] = content
+		}
+
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		for _, val := range m {
+			err := loadValueFiles(val, rt.Elem())
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice:
+		s, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+
+		for _, val := range s {
+			err := loadValueFiles(val, rt.Elem())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
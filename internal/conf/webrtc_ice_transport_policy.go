@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WebRTCICETransportPolicy is the webrtcICETransportPolicy parameter.
+type WebRTCICETransportPolicy int
+
+// supported values.
+const (
+	WebRTCICETransportPolicyAll WebRTCICETransportPolicy = iota
+	WebRTCICETransportPolicyRelay
+)
+
+// MarshalJSON implements json.Marshaler.
+func (d WebRTCICETransportPolicy) MarshalJSON() ([]byte, error) {
+	var out string
+
+	switch d {
+	case WebRTCICETransportPolicyRelay:
+		out = "relay"
+
+	default:
+		out = "all"
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *WebRTCICETransportPolicy) UnmarshalJSON(b []byte) error {
+	var in string
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	switch in {
+	case "relay":
+		*d = WebRTCICETransportPolicyRelay
+
+	case "all":
+		*d = WebRTCICETransportPolicyAll
+
+	default:
+		return fmt.Errorf("invalid WebRTC ICE transport policy '%s'", in)
+	}
+
+	return nil
+}
+
+// UnmarshalEnv implements env.Unmarshaler.
+func (d *WebRTCICETransportPolicy) UnmarshalEnv(_ string, v string) error {
+	return d.UnmarshalJSON([]byte(`"` + v + `"`))
+}
@@ -12,18 +12,24 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/asticode/go-astits"
 	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
 	srt "github.com/datarhei/gosrt"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"github.com/pion/rtp"
 	pwebrtc "github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 
+	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
 	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
 	"github.com/bluenviron/mediamtx/internal/protocols/whip"
@@ -342,6 +348,629 @@ func TestAPIPathsGet(t *testing.T) {
 	}
 }
 
+func TestAPIPathsConfig(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-api")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, ok := newInstance("api: yes\n" +
+		"recordPath: " + filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+		"paths:\n" +
+		"  '~^cam_(.+)$':\n" +
+		"    srtPublishPassphrase: mysecretpassphrase\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	source := gortsplib.Client{}
+	err = source.StartRecording("rtsp://localhost:8554/cam_one",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	type pathConf struct {
+		Name                 string `json:"name"`
+		Source               string `json:"source"`
+		Record               bool   `json:"record"`
+		SRTPublishPassphrase string `json:"srtPublishPassphrase"`
+	}
+
+	var out pathConf
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/config/cam_one", nil, &out)
+	require.Equal(t, pathConf{
+		Name:                 "cam_one",
+		Source:               "publisher",
+		SRTPublishPassphrase: "REDACTED",
+	}, out)
+
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/record/start/cam_one", nil, nil)
+
+	out = pathConf{}
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/config/cam_one?redact=false", nil, &out)
+	require.Equal(t, pathConf{
+		Name:                 "cam_one",
+		Source:               "publisher",
+		Record:               true,
+		SRTPublishPassphrase: "mysecretpassphrase",
+	}, out)
+
+	res, err := hc.Get("http://localhost:9997/v3/paths/config/cam_two")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	checkError(t, "path not found", res.Body)
+}
+
+func TestAPIPathsRecordRotate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-api")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, ok := newInstance("api: yes\n" +
+		"recordPath: " + filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	source := gortsplib.Client{}
+	err = source.StartRecording("rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	func() {
+		res, err2 := hc.Post("http://localhost:9997/v3/paths/record/rotate/mystream", "", nil)
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		checkError(t, "recording is not active", res.Body)
+	}()
+
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/record/start/mystream", nil, nil)
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/record/rotate/mystream", nil, nil)
+
+	func() {
+		res, err2 := hc.Post("http://localhost:9997/v3/paths/record/rotate/otherstream", "", nil)
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		checkError(t, "path not found", res.Body)
+	}()
+}
+
+func TestAPIPathsEnableDisable(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+	source := gortsplib.Client{}
+	err := source.StartRecording("rtsp://localhost:8554/mystream", desc)
+	require.NoError(t, err)
+	defer source.Close()
+
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/disable/mystream", nil, nil)
+
+	// the existing publisher is closed
+	err = source.Wait()
+	require.Error(t, err)
+
+	source2 := gortsplib.Client{}
+	err = source2.StartRecording("rtsp://localhost:8554/mystream", desc)
+	require.EqualError(t, err, "bad status code: 400 (Bad Request)")
+
+	describe := func() error {
+		u, err2 := base.ParseURL("rtsp://localhost:8554/mystream")
+		require.NoError(t, err2)
+
+		reader := gortsplib.Client{}
+		err2 = reader.Start(u.Scheme, u.Host)
+		require.NoError(t, err2)
+		defer reader.Close()
+
+		_, _, err2 = reader.Describe(u)
+		return err2
+	}
+
+	err = describe()
+	require.EqualError(t, err, "bad status code: 400 (Bad Request)")
+
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/enable/mystream", nil, nil)
+
+	source3 := gortsplib.Client{}
+	err = source3.StartRecording("rtsp://localhost:8554/mystream", desc)
+	require.NoError(t, err)
+	defer source3.Close()
+
+	err = describe()
+	require.NoError(t, err)
+}
+
+func TestAPIPathsHistory(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	source := gortsplib.Client{}
+	err := source.StartRecording("rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	var out defs.APIPathHistory
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/history/mystream?metric=bitrate", nil, &out)
+	require.Equal(t, defs.APIPathHistory{
+		Metric: defs.APIPathHistoryMetricBitrate,
+		Items:  []*defs.APIPathHistorySample{},
+	}, out)
+
+	func() {
+		res, err2 := hc.Get("http://localhost:9997/v3/paths/history/mystream?metric=other")
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		checkError(t, "invalid metric 'other'", res.Body)
+	}()
+
+	func() {
+		res, err2 := hc.Get("http://localhost:9997/v3/paths/history/otherstream?metric=bitrate")
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		checkError(t, "path not found", res.Body)
+	}()
+}
+
+func TestAPIPathsReaderHealth(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	source := gortsplib.Client{}
+	err := source.StartRecording("rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	for i := 0; i < 2; i++ {
+		conf := srt.DefaultConfig()
+		conf.StreamId = "read:mystream"
+
+		var reader srt.Conn
+		reader, err = srt.Dial("srt", "localhost:8890", conf)
+		require.NoError(t, err)
+		defer reader.Close()
+	}
+
+	rtspReader := gortsplib.Client{}
+	u, err := base.ParseURL("rtsp://localhost:8554/mystream")
+	require.NoError(t, err)
+	err = rtspReader.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer rtspReader.Close()
+	desc, _, err := rtspReader.Describe(u)
+	require.NoError(t, err)
+	err = rtspReader.SetupAll(desc.BaseURL, desc.Medias)
+	require.NoError(t, err)
+	_, err = rtspReader.Play(nil)
+	require.NoError(t, err)
+
+	time.Sleep(500 * time.Millisecond)
+
+	var conns defs.APISRTConnList
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/srtconns/list", nil, &conns)
+	require.Equal(t, 2, conns.ItemCount)
+
+	var health defs.APIPathReaderHealth
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/readerhealth/mystream", nil, &health)
+
+	require.Equal(t, 3, health.Readers)
+	require.Equal(t, 2, health.ReportingReaders)
+	require.Equal(t, conns.Items[0].PacketsSent+conns.Items[1].PacketsSent, health.PacketsSent)
+	require.Equal(t, conns.Items[0].PacketsSendLoss+conns.Items[1].PacketsSendLoss, health.PacketsLost)
+	require.Equal(t, conns.Items[0].PacketsRetrans+conns.Items[1].PacketsRetrans, health.PacketsRetransmitted)
+	require.NotNil(t, health.WorstMsRTT)
+	require.NotNil(t, health.AverageMsRTT)
+	require.InDelta(t, max(conns.Items[0].MsRTT, conns.Items[1].MsRTT), *health.WorstMsRTT, 5)
+	require.InDelta(t, (conns.Items[0].MsRTT+conns.Items[1].MsRTT)/2, *health.AverageMsRTT, 5)
+	require.GreaterOrEqual(t, *health.WorstMsRTT, *health.AverageMsRTT)
+
+	func() {
+		res, err2 := hc.Get("http://localhost:9997/v3/paths/readerhealth/otherstream")
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		checkError(t, "path not found", res.Body)
+	}()
+}
+
+func TestAPIPathsCounters(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	media0 := test.UniqueMediaH264()
+
+	source := gortsplib.Client{}
+	err := source.StartRecording("rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{media0}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	var seq uint16
+
+	writePacket := func() error {
+		seq++
+		return source.WritePacketRTP(media0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: seq,
+				Timestamp:      uint32(seq) * 3000,
+			},
+			Payload: []byte{5, 1, 2, 3, 4},
+		})
+	}
+
+	getBytesReceived := func(u string) uint64 {
+		var out struct {
+			BytesReceived uint64 `json:"bytesReceived"`
+		}
+		httpRequest(t, hc, http.MethodGet, u, nil, &out)
+		return out.BytesReceived
+	}
+
+	// counts accumulate
+	for i := 0; i < 3; i++ {
+		err = writePacket()
+		require.NoError(t, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	initial := getBytesReceived("http://localhost:9997/v3/paths/counters/mystream")
+	require.NotZero(t, initial)
+	require.Equal(t, getBytesReceived("http://localhost:9997/v3/paths/get/mystream"), initial)
+
+	// a reset returns the total, subsequent reads start from zero
+	require.Equal(t, initial, getBytesReceived("http://localhost:9997/v3/paths/counters/mystream?reset=true"))
+	require.Equal(t, uint64(0), getBytesReceived("http://localhost:9997/v3/paths/counters/mystream"))
+
+	// traffic is neither lost nor counted twice when resetting under concurrent writes
+	done := make(chan struct{})
+	writerDone := make(chan struct{})
+
+	go func() {
+		defer close(writerDone)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if writePacket() != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	var total uint64
+	for i := 0; i < 20; i++ {
+		total += getBytesReceived("http://localhost:9997/v3/paths/counters/mystream?reset=true")
+	}
+
+	close(done)
+	<-writerDone
+	time.Sleep(200 * time.Millisecond)
+
+	total += getBytesReceived("http://localhost:9997/v3/paths/counters/mystream")
+
+	require.NotZero(t, total)
+	require.Equal(t, getBytesReceived("http://localhost:9997/v3/paths/get/mystream")-initial, total)
+
+	func() {
+		res, err2 := hc.Get("http://localhost:9997/v3/paths/counters/mystream?reset=other")
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		checkError(t, "invalid reset value 'other'", res.Body)
+	}()
+
+	func() {
+		res, err2 := hc.Get("http://localhost:9997/v3/paths/counters/otherstream")
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		checkError(t, "path not found", res.Body)
+	}()
+}
+
+func TestAPIPathsGetDecodeErrors(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	conf := srt.DefaultConfig()
+	conf.StreamId = "publish:mypath"
+
+	conn, err := srt.Dial("srt", "localhost:8890", conf)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// SRT payloads must contain an integer number of MPEG-TS packets
+	bw := bufio.NewWriterSize(conn, 1316)
+	mux := astits.NewMuxer(context.Background(), bw)
+
+	err = mux.AddElementaryStream(astits.PMTElementaryStream{
+		ElementaryPID: 256,
+		StreamType:    astits.StreamTypeH264Video,
+	})
+	require.NoError(t, err)
+
+	mux.SetPCRPID(256)
+
+	_, err = mux.WriteTables()
+	require.NoError(t, err)
+
+	// a PES is decoded when the next one is received,
+	// therefore 4 PES generate 3 decode errors.
+	for i := 0; i < 4; i++ {
+		_, err = mux.WriteData(&astits.MuxerData{
+			PID: 256,
+			PES: &astits.PESData{
+				Header: &astits.PESHeader{
+					OptionalHeader: &astits.PESOptionalHeader{
+						MarkerBits:      2,
+						PTSDTSIndicator: astits.PTSDTSIndicatorOnlyPTS,
+						PTS:             &astits.ClockReference{Base: int64(i) * 90000},
+					},
+					StreamID: 224,
+				},
+				Data: []byte{1, 2, 3}, // invalid Annex-B
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	err = bw.Flush()
+	require.NoError(t, err)
+
+	type decodeError struct {
+		Message string `json:"message"`
+		Count   uint64 `json:"count"`
+	}
+
+	type path struct {
+		DecodeErrors []decodeError `json:"decodeErrors"`
+	}
+
+	var out path
+
+	for i := 0; i < 20; i++ {
+		httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+		if len(out.DecodeErrors) != 0 && out.DecodeErrors[0].Count == 3 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	require.Equal(t, 1, len(out.DecodeErrors))
+	require.NotEqual(t, "", out.DecodeErrors[0].Message)
+	require.Equal(t, uint64(3), out.DecodeErrors[0].Count)
+}
+
+func TestAPIPathsGetTrackStates(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  mypath:\n" +
+		"    trackActiveTimeout: 500ms\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	media0 := test.UniqueMediaH264()
+	media1 := test.UniqueMediaMPEG4Audio()
+
+	source := gortsplib.Client{}
+	err := source.StartRecording(
+		"rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{media0, media1}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	writeVideo := func() {
+		err2 := source.WritePacketRTP(media0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:     2,
+				Marker:      true,
+				PayloadType: 96,
+			},
+			Payload: []byte{5, 1, 2, 3, 4},
+		})
+		require.NoError(t, err2)
+	}
+
+	audioSeq := uint16(0)
+
+	writeAudio := func() {
+		audioSeq++
+		err2 := source.WritePacketRTP(media1, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: audioSeq,
+				Timestamp:      uint32(audioSeq) * 1024,
+			},
+			Payload: []byte{0x00, 0x10, 0x00, 0x20, 1, 2, 3, 4},
+		})
+		require.NoError(t, err2)
+	}
+
+	type trackState struct {
+		Codec        string     `json:"codec"`
+		Active       bool       `json:"active"`
+		LastSeen     *time.Time `json:"lastSeen"`
+		ReorderDepth string     `json:"reorderDepth"`
+	}
+
+	type path struct {
+		TrackStates []trackState `json:"trackStates"`
+	}
+
+	getStates := func(done func([]trackState) bool) []trackState {
+		var out path
+		for i := 0; i < 20; i++ {
+			httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+			if done(out.TrackStates) {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return out.TrackStates
+	}
+
+	writeVideo()
+	writeAudio()
+
+	states := getStates(func(s []trackState) bool {
+		return len(s) == 2 && s[0].Active && s[1].Active
+	})
+	require.Equal(t, 2, len(states))
+	require.Equal(t, "H264", states[0].Codec)
+	require.Equal(t, true, states[0].Active)
+	require.NotNil(t, states[0].LastSeen)
+	require.Equal(t, "MPEG-4 Audio", states[1].Codec)
+	require.Equal(t, true, states[1].Active)
+	require.NotNil(t, states[1].LastSeen)
+	require.Equal(t, "0s", states[0].ReorderDepth)
+	require.Equal(t, "0s", states[1].ReorderDepth)
+
+	videoLastSeen := *states[0].LastSeen
+
+	// keep feeding the audio track only
+	for i := 0; i < 10; i++ {
+		writeAudio()
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	writeAudio()
+
+	states = getStates(func(s []trackState) bool {
+		return len(s) == 2 && !s[0].Active
+	})
+	require.Equal(t, 2, len(states))
+	require.Equal(t, false, states[0].Active)
+	require.NotNil(t, states[0].LastSeen)
+	require.True(t, videoLastSeen.Equal(*states[0].LastSeen))
+	require.Equal(t, true, states[1].Active)
+	require.True(t, states[1].LastSeen.After(videoLastSeen))
+}
+
+func TestAPIPathsLogTail(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"authInternalUsers:\n" +
+		"- user: any\n" +
+		"  permissions:\n" +
+		"  - action: publish\n" +
+		"- user: myuser\n" +
+		"  pass: mypass\n" +
+		"  permissions:\n" +
+		"  - action: api\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	_, res, err := websocket.DefaultDialer.Dial("ws://localhost:9997/v3/paths/logtail/mypath", nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res.Body.Close()
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:9997", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("myuser", "mypass")
+
+	wc, res, err := websocket.DefaultDialer.Dial("ws://localhost:9997/v3/paths/logtail/mypath", req.Header)
+	require.NoError(t, err)
+	defer wc.Close()
+	res.Body.Close()
+
+	for _, pathName := range []string{"otherpath", "mypath"} {
+		source := gortsplib.Client{}
+		err = source.StartRecording("rtsp://localhost:8554/"+pathName,
+			&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+		require.NoError(t, err)
+		defer source.Close()
+	}
+
+	wc.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+
+	for {
+		var byts []byte
+		_, byts, err = wc.ReadMessage()
+		require.NoError(t, err)
+
+		line := string(byts)
+		require.NotContains(t, line, "otherpath")
+
+		if strings.Contains(line, "is publishing to path 'mypath'") {
+			break
+		}
+	}
+}
+
 func TestAPIProtocolListGet(t *testing.T) {
 	serverCertFpath, err := test.CreateTempFile(test.TLSCertPub)
 	require.NoError(t, err)
@@ -618,6 +1247,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"id":            out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["id"],
 							"path":          "mypath",
 							"query":         "key=val",
+							"queryParams":   map[string]interface{}{"key": []interface{}{"val"}},
 							"remoteAddr":    out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
 							"state":         "publish",
 							"transport":     "UDP",
@@ -652,6 +1282,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"id":            out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["id"],
 							"path":          "mypath",
 							"query":         "key=val",
+							"queryParams":   map[string]interface{}{"key": []interface{}{"val"}},
 							"remoteAddr":    out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
 							"state":         "publish",
 							"transport":     "TCP",
@@ -671,6 +1302,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"id":            out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["id"],
 							"path":          "mypath",
 							"query":         "key=val",
+							"queryParams":   map[string]interface{}{"key": []interface{}{"val"}},
 							"remoteAddr":    out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
 							"state":         "publish",
 						},
@@ -689,6 +1321,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"id":            out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["id"],
 							"path":          "mypath",
 							"query":         "key=val",
+							"queryParams":   map[string]interface{}{"key": []interface{}{"val"}},
 							"remoteAddr":    out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
 							"state":         "publish",
 						},
@@ -723,6 +1356,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"path":                      "mypath",
 							"peerConnectionEstablished": true,
 							"query":                     "key=val",
+							"queryParams":               map[string]interface{}{"key": []interface{}{"val"}},
 							"remoteAddr":                out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
 							"remoteCandidate":           out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteCandidate"],
 							"state":                     "read",
@@ -736,6 +1370,9 @@ func TestAPIProtocolListGet(t *testing.T) {
 					"pageCount": float64(1),
 					"items": []interface{}{
 						map[string]interface{}{
+							"alarm":                         false,
+							"bitrateRaw":                    float64(0),
+							"bitrateSmoothed":               float64(0),
 							"byteMSS":                       float64(1500),
 							"bytesAvailReceiveBuf":          float64(0),
 							"bytesAvailSendBuf":             float64(0),
@@ -753,6 +1390,7 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"bytesSent":                     float64(0),
 							"bytesSentUnique":               float64(0),
 							"created":                       out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["created"],
+							"encryption":                    "none",
 							"id":                            out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["id"],
 							"mbpsLinkCapacity":              float64(0),
 							"mbpsMaxBW":                     float64(-1),
@@ -791,13 +1429,20 @@ func TestAPIProtocolListGet(t *testing.T) {
 							"packetsSentUnique":             float64(0),
 							"path":                          "mypath",
 							"query":                         "key=val",
+							"queryParams":                   map[string]interface{}{"key": []interface{}{"val"}},
 							"remoteAddr":                    out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["remoteAddr"],
 							"state":                         "publish",
+							"uptime":                        out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["uptime"],
 							"usPacketsSendPeriod":           float64(10.967254638671875),
 							"usSndDuration":                 float64(0),
 						},
 					},
 				}, out1)
+
+				uptime, err := time.ParseDuration(
+					out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["uptime"].(string))
+				require.NoError(t, err)
+				require.GreaterOrEqual(t, uptime, 500*time.Millisecond)
 			}
 
 			var out2 interface{}
@@ -812,6 +1457,12 @@ func TestAPIProtocolListGet(t *testing.T) {
 					nil, &out2)
 			}
 
+			if ca == "srt" {
+				// uptime changes between requests
+				delete(out1.(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{}), "uptime")
+				delete(out2.(map[string]interface{}), "uptime")
+			}
+
 			require.Equal(t, out1.(map[string]interface{})["items"].([]interface{})[0], out2)
 		})
 	}
@@ -1155,3 +1806,81 @@ func TestAPIProtocolKickNotFound(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIDebugResources(t *testing.T) {
+	for _, ca := range []string{
+		"rtmp",
+		"srt",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			p, ok := newInstance("api: yes\n" +
+				"paths:\n" +
+				"  all_others:\n")
+			require.Equal(t, true, ok)
+			defer p.Close()
+
+			tr := &http.Transport{}
+			defer tr.CloseIdleConnections()
+			hc := &http.Client{Transport: tr}
+
+			type resources struct {
+				Goroutines int            `json:"goroutines"`
+				OpenFiles  *int           `json:"openFiles"`
+				Conns      map[string]int `json:"conns"`
+				WaitGroups map[string]int `json:"waitGroups"`
+			}
+
+			getResources := func() resources {
+				var out resources
+				httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/debug/resources", nil, &out)
+				return out
+			}
+
+			waitResources := func(cond func(r resources) bool) resources {
+				var r resources
+				for i := 0; i < 50; i++ {
+					r = getResources()
+					if cond(r) {
+						return r
+					}
+					time.Sleep(50 * time.Millisecond)
+				}
+				t.Errorf("unexpected resources: %+v", r)
+				return r
+			}
+
+			initial := getResources()
+			require.Equal(t, 0, initial.Conns[ca+"conns"])
+			require.NotZero(t, initial.Goroutines)
+			require.NotNil(t, initial.OpenFiles)
+
+			var closeConn func()
+
+			switch ca {
+			case "rtmp":
+				nconn, err := net.Dial("tcp", "localhost:1935")
+				require.NoError(t, err)
+				closeConn = func() { nconn.Close() }
+
+			case "srt":
+				conf := srt.DefaultConfig()
+				conf.StreamId = "publish:mypath"
+
+				conn, err := srt.Dial("srt", "localhost:8890", conf)
+				require.NoError(t, err)
+				closeConn = func() { conn.Close() }
+			}
+
+			opened := waitResources(func(r resources) bool {
+				return r.Conns[ca+"conns"] == 1
+			})
+			require.Greater(t, opened.WaitGroups[ca], initial.WaitGroups[ca])
+
+			closeConn()
+
+			waitResources(func(r resources) bool {
+				return r.Conns[ca+"conns"] == 0 && r.WaitGroups[ca] == initial.WaitGroups[ca]
+			})
+		})
+	}
+}
@@ -60,6 +60,7 @@ type Core struct {
 	conf            *conf.Conf
 	logger          *logger.Logger
 	externalCmdPool *externalcmd.Pool
+	pathsEnabled    *pathEnabledOverrides
 	authManager     *auth.Manager
 	metrics         *metrics.Metrics
 	pprof           *pprof.PPROF
@@ -135,6 +136,10 @@ func New(args []string) (*Core, bool) {
 		return nil, false
 	}
 
+	if p.api != nil {
+		p.api.SetReady(true)
+	}
+
 	go p.run()
 
 	return p, true
@@ -207,6 +212,10 @@ outer:
 
 	p.ctxCancel()
 
+	if p.api != nil {
+		p.api.SetShuttingDown()
+	}
+
 	p.closeResources(nil, false)
 }
 
@@ -249,6 +258,7 @@ func (p *Core) createResources(initial bool) error {
 		gin.SetMode(gin.ReleaseMode)
 
 		p.externalCmdPool = externalcmd.NewPool()
+		p.pathsEnabled = &pathEnabledOverrides{}
 	}
 
 	if p.authManager == nil {
@@ -259,21 +269,36 @@ func (p *Core) createResources(initial bool) error {
 			HTTPExclude:     p.conf.AuthHTTPExclude,
 			JWTJWKS:         p.conf.AuthJWTJWKS,
 			JWTClaimKey:     p.conf.AuthJWTClaimKey,
+			SignedURLSecret: p.conf.AuthSignedURLSecret,
 			ReadTimeout:     time.Duration(p.conf.ReadTimeout),
 			RTSPAuthMethods: p.conf.RTSPAuthMethods,
 		}
 	}
 
-	if p.conf.Metrics &&
+	if (p.conf.Metrics || p.conf.MetricsOTLP) &&
 		p.metrics == nil {
+		var address string
+		if p.conf.Metrics {
+			address = p.conf.MetricsAddress
+		}
+
+		var otlpEndpoint string
+		if p.conf.MetricsOTLP {
+			otlpEndpoint = p.conf.MetricsOTLPEndpoint
+		}
+
 		i := &metrics.Metrics{
-			Address:        p.conf.MetricsAddress,
+			Version:        string(version),
+			Address:        address,
 			Encryption:     p.conf.MetricsEncryption,
 			ServerKey:      p.conf.MetricsServerKey,
 			ServerCert:     p.conf.MetricsServerCert,
 			AllowOrigin:    p.conf.MetricsAllowOrigin,
 			TrustedProxies: p.conf.MetricsTrustedProxies,
 			ReadTimeout:    p.conf.ReadTimeout,
+			OTLPEndpoint:   otlpEndpoint,
+			OTLPHeaders:    p.conf.MetricsOTLPHeaders,
+			OTLPInterval:   p.conf.MetricsOTLPInterval,
 			AuthManager:    p.authManager,
 			Parent:         p,
 		}
@@ -284,6 +309,11 @@ func (p *Core) createResources(initial bool) error {
 		p.metrics = i
 	}
 
+	// the configuration hash is updated at every reload
+	if p.metrics != nil {
+		p.metrics.SetConf(p.conf)
+	}
+
 	if p.conf.PPROF &&
 		p.pprof == nil {
 		i := &pprof.PPROF{
@@ -343,6 +373,7 @@ func (p *Core) createResources(initial bool) error {
 			writeQueueSize:    p.conf.WriteQueueSize,
 			udpMaxPayloadSize: p.conf.UDPMaxPayloadSize,
 			pathConfs:         p.conf.Paths,
+			pathsEnabled:      p.pathsEnabled,
 			externalCmdPool:   p.externalCmdPool,
 			parent:            p,
 		}
@@ -353,6 +384,8 @@ func (p *Core) createResources(initial bool) error {
 		}
 	}
 
+	p.pathManager.setOutputs(pathOutputsFromConf(p.conf))
+
 	if p.conf.RTSP &&
 		(p.conf.Encryption == conf.EncryptionNo ||
 			p.conf.Encryption == conf.EncryptionOptional) &&
@@ -499,23 +532,28 @@ func (p *Core) createResources(initial bool) error {
 	if p.conf.HLS &&
 		p.hlsServer == nil {
 		i := &hls.Server{
-			Address:         p.conf.HLSAddress,
-			Encryption:      p.conf.HLSEncryption,
-			ServerKey:       p.conf.HLSServerKey,
-			ServerCert:      p.conf.HLSServerCert,
-			AllowOrigin:     p.conf.HLSAllowOrigin,
-			TrustedProxies:  p.conf.HLSTrustedProxies,
-			AlwaysRemux:     p.conf.HLSAlwaysRemux,
-			Variant:         p.conf.HLSVariant,
-			SegmentCount:    p.conf.HLSSegmentCount,
-			SegmentDuration: p.conf.HLSSegmentDuration,
-			PartDuration:    p.conf.HLSPartDuration,
-			SegmentMaxSize:  p.conf.HLSSegmentMaxSize,
-			Directory:       p.conf.HLSDirectory,
-			ReadTimeout:     p.conf.ReadTimeout,
-			MuxerCloseAfter: p.conf.HLSMuxerCloseAfter,
-			PathManager:     p.pathManager,
-			Parent:          p,
+			Address:                          p.conf.HLSAddress,
+			Encryption:                       p.conf.HLSEncryption,
+			ServerKey:                        p.conf.HLSServerKey,
+			ServerCert:                       p.conf.HLSServerCert,
+			AllowOrigin:                      p.conf.HLSAllowOrigin,
+			TrustedProxies:                   p.conf.HLSTrustedProxies,
+			AlwaysRemux:                      p.conf.HLSAlwaysRemux,
+			Variant:                          p.conf.HLSVariant,
+			SegmentCount:                     p.conf.HLSSegmentCount,
+			SegmentDuration:                  p.conf.HLSSegmentDuration,
+			PartDuration:                     p.conf.HLSPartDuration,
+			SegmentMaxSize:                   p.conf.HLSSegmentMaxSize,
+			Directory:                        p.conf.HLSDirectory,
+			SegmentsToDisk:                   p.conf.HLSSegmentsToDisk,
+			SegmentsToDiskDirectory:          p.conf.HLSSegmentsToDiskDirectory,
+			ReadTimeout:                      p.conf.ReadTimeout,
+			MuxerCloseAfter:                  p.conf.HLSMuxerCloseAfter,
+			MultivariantPlaylistCacheControl: p.conf.HLSMultivariantPlaylistCacheControl,
+			MediaPlaylistCacheControl:        p.conf.HLSMediaPlaylistCacheControl,
+			SegmentCacheControl:              p.conf.HLSSegmentCacheControl,
+			PathManager:                      p.pathManager,
+			Parent:                           p,
 		}
 		err = i.Initialize()
 		if err != nil {
@@ -546,6 +584,7 @@ func (p *Core) createResources(initial bool) error {
 			IPsFromInterfacesList: p.conf.WebRTCIPsFromInterfacesList,
 			AdditionalHosts:       p.conf.WebRTCAdditionalHosts,
 			ICEServers:            p.conf.WebRTCICEServers2,
+			ICETransportPolicy:    p.conf.WebRTCICETransportPolicy,
 			HandshakeTimeout:      p.conf.WebRTCHandshakeTimeout,
 			TrackGatherTimeout:    p.conf.WebRTCTrackGatherTimeout,
 			ExternalCmdPool:       p.externalCmdPool,
@@ -565,18 +604,40 @@ func (p *Core) createResources(initial bool) error {
 
 	if p.conf.SRT &&
 		p.srtServer == nil {
+		udpMaxPayloadSize := p.conf.UDPMaxPayloadSize
+		if p.conf.SRTUDPMaxPayloadSize != 0 {
+			udpMaxPayloadSize = p.conf.SRTUDPMaxPayloadSize
+		}
+
 		i := &srt.Server{
-			Address:             p.conf.SRTAddress,
-			RTSPAddress:         p.conf.RTSPAddress,
-			ReadTimeout:         p.conf.ReadTimeout,
-			WriteTimeout:        p.conf.WriteTimeout,
-			UDPMaxPayloadSize:   p.conf.UDPMaxPayloadSize,
-			RunOnConnect:        p.conf.RunOnConnect,
-			RunOnConnectRestart: p.conf.RunOnConnectRestart,
-			RunOnDisconnect:     p.conf.RunOnDisconnect,
-			ExternalCmdPool:     p.externalCmdPool,
-			PathManager:         p.pathManager,
-			Parent:              p,
+			Address:              p.conf.SRTAddress,
+			DrainTimeout:         p.conf.SRTDrainTimeout,
+			BitrateWindow:        p.conf.SRTBitrateSmoothingWindow,
+			PublishNamespace:     p.conf.SRTPublishNamespace,
+			AutoCreatePaths:      p.conf.SRTAutoCreatePaths,
+			PublishBufferPolicy:  p.conf.SRTPublishBufferPolicy,
+			PublishBufferSize:    p.conf.SRTPublishBufferSize,
+			PublishStartTimeout:  p.conf.SRTPublishStartTimeout,
+			AlarmRTT:             p.conf.SRTAlarmRTT,
+			AlarmLossRate:        p.conf.SRTAlarmLossRate,
+			HandshakeTimeout:     p.conf.SRTHandshakeTimeout,
+			HandshakeRateLimit:   p.conf.SRTHandshakeRateLimit,
+			MaxPendingHandshakes: p.conf.SRTMaxPendingHandshakes,
+			MaxConnsPerIP:        p.conf.SRTMaxConnsPerIP,
+			IPv6PrefixLength:     p.conf.SRTConnsIPv6PrefixLength,
+			RTSPAddress:          p.conf.RTSPAddress,
+			ReadTimeout:          p.conf.ReadTimeout,
+			WriteTimeout:         p.conf.WriteTimeout,
+			UDPMaxPayloadSize:    udpMaxPayloadSize,
+			UDPRecvBufferSize:    p.conf.SRTUDPRecvBufferSize,
+			UDPSendBufferSize:    p.conf.SRTUDPSendBufferSize,
+			TTL:                  p.conf.SRTTTL,
+			RunOnConnect:         p.conf.RunOnConnect,
+			RunOnConnectRestart:  p.conf.RunOnConnectRestart,
+			RunOnDisconnect:      p.conf.RunOnDisconnect,
+			ExternalCmdPool:      p.externalCmdPool,
+			PathManager:          p.pathManager,
+			Parent:               p,
 		}
 		err = i.Initialize()
 		if err != nil {
@@ -592,24 +653,29 @@ func (p *Core) createResources(initial bool) error {
 	if p.conf.API &&
 		p.api == nil {
 		i := &api.API{
-			Address:        p.conf.APIAddress,
-			Encryption:     p.conf.APIEncryption,
-			ServerKey:      p.conf.APIServerKey,
-			ServerCert:     p.conf.APIServerCert,
-			AllowOrigin:    p.conf.APIAllowOrigin,
-			TrustedProxies: p.conf.APITrustedProxies,
-			ReadTimeout:    p.conf.ReadTimeout,
-			Conf:           p.conf,
-			AuthManager:    p.authManager,
-			PathManager:    p.pathManager,
-			RTSPServer:     p.rtspServer,
-			RTSPSServer:    p.rtspsServer,
-			RTMPServer:     p.rtmpServer,
-			RTMPSServer:    p.rtmpsServer,
-			HLSServer:      p.hlsServer,
-			WebRTCServer:   p.webRTCServer,
-			SRTServer:      p.srtServer,
-			Parent:         p,
+			Address:          p.conf.APIAddress,
+			Encryption:       p.conf.APIEncryption,
+			ServerKey:        p.conf.APIServerKey,
+			ServerCert:       p.conf.APIServerCert,
+			AllowOrigins:     p.conf.APIAllowOrigins,
+			AllowCredentials: p.conf.APIAllowCredentials,
+			AuthUser:         p.conf.APIAuthUser,
+			AuthPass:         p.conf.APIAuthPass,
+			AuthToken:        p.conf.APIAuthToken,
+			TrustedProxies:   p.conf.APITrustedProxies,
+			ReadTimeout:      p.conf.ReadTimeout,
+			Conf:             p.conf,
+			AuthManager:      p.authManager,
+			PathManager:      p.pathManager,
+			RTSPServer:       p.rtspServer,
+			RTSPSServer:      p.rtspsServer,
+			RTMPServer:       p.rtmpServer,
+			RTMPSServer:      p.rtmpsServer,
+			HLSServer:        p.hlsServer,
+			WebRTCServer:     p.webRTCServer,
+			SRTServer:        p.srtServer,
+			Logger:           p.logger,
+			Parent:           p,
 		}
 		err = i.Initialize()
 		if err != nil {
@@ -629,10 +695,21 @@ func (p *Core) createResources(initial bool) error {
 }
 
 func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
-	closeLogger := newConf == nil ||
-		newConf.LogLevel != p.conf.LogLevel ||
-		!reflect.DeepEqual(newConf.LogDestinations, p.conf.LogDestinations) ||
-		newConf.LogFile != p.conf.LogFile
+	closeLogger := newConf == nil
+	if !closeLogger {
+		if newConf.LogLevel != p.conf.LogLevel {
+			p.logger.SetLevel(logger.Level(newConf.LogLevel))
+		}
+
+		if !reflect.DeepEqual(newConf.LogDestinations, p.conf.LogDestinations) ||
+			newConf.LogFile != p.conf.LogFile {
+			err := p.logger.SetDestinations(newConf.LogDestinations, newConf.LogFile)
+			if err != nil {
+				// let createResources() report the error
+				closeLogger = true
+			}
+		}
+	}
 
 	closeAuthManager := newConf == nil ||
 		newConf.AuthMethod != p.conf.AuthMethod ||
@@ -645,6 +722,9 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 	if !closeAuthManager && !reflect.DeepEqual(newConf.AuthInternalUsers, p.conf.AuthInternalUsers) {
 		p.authManager.ReloadInternalUsers(newConf.AuthInternalUsers)
 	}
+	if !closeAuthManager && newConf.AuthSignedURLSecret != p.conf.AuthSignedURLSecret {
+		p.authManager.ReloadSignedURLSecret(newConf.AuthSignedURLSecret)
+	}
 
 	closeMetrics := newConf == nil ||
 		newConf.Metrics != p.conf.Metrics ||
@@ -654,6 +734,10 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.MetricsServerCert != p.conf.MetricsServerCert ||
 		newConf.MetricsAllowOrigin != p.conf.MetricsAllowOrigin ||
 		!reflect.DeepEqual(newConf.MetricsTrustedProxies, p.conf.MetricsTrustedProxies) ||
+		newConf.MetricsOTLP != p.conf.MetricsOTLP ||
+		newConf.MetricsOTLPEndpoint != p.conf.MetricsOTLPEndpoint ||
+		!reflect.DeepEqual(newConf.MetricsOTLPHeaders, p.conf.MetricsOTLPHeaders) ||
+		newConf.MetricsOTLPInterval != p.conf.MetricsOTLPInterval ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
 		closeAuthManager ||
 		closeLogger
@@ -692,7 +776,6 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 	}
 
 	closePathManager := newConf == nil ||
-		newConf.LogLevel != p.conf.LogLevel ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		!reflect.DeepEqual(newConf.RTSPAuthMethods, p.conf.RTSPAuthMethods) ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
@@ -702,6 +785,10 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		closeMetrics ||
 		closeAuthManager ||
 		closeLogger
+	if !closePathManager && newConf.LogLevel != p.conf.LogLevel {
+		p.pathManager.setLogLevel(newConf.LogLevel)
+	}
+
 	if !closePathManager && !reflect.DeepEqual(newConf.Paths, p.conf.Paths) {
 		p.pathManager.ReloadPathConfs(newConf.Paths)
 	}
@@ -793,8 +880,13 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.HLSPartDuration != p.conf.HLSPartDuration ||
 		newConf.HLSSegmentMaxSize != p.conf.HLSSegmentMaxSize ||
 		newConf.HLSDirectory != p.conf.HLSDirectory ||
+		newConf.HLSSegmentsToDisk != p.conf.HLSSegmentsToDisk ||
+		newConf.HLSSegmentsToDiskDirectory != p.conf.HLSSegmentsToDiskDirectory ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
 		newConf.HLSMuxerCloseAfter != p.conf.HLSMuxerCloseAfter ||
+		newConf.HLSMultivariantPlaylistCacheControl != p.conf.HLSMultivariantPlaylistCacheControl ||
+		newConf.HLSMediaPlaylistCacheControl != p.conf.HLSMediaPlaylistCacheControl ||
+		newConf.HLSSegmentCacheControl != p.conf.HLSSegmentCacheControl ||
 		closePathManager ||
 		closeMetrics ||
 		closeLogger
@@ -814,6 +906,7 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		!reflect.DeepEqual(newConf.WebRTCIPsFromInterfacesList, p.conf.WebRTCIPsFromInterfacesList) ||
 		!reflect.DeepEqual(newConf.WebRTCAdditionalHosts, p.conf.WebRTCAdditionalHosts) ||
 		!reflect.DeepEqual(newConf.WebRTCICEServers2, p.conf.WebRTCICEServers2) ||
+		newConf.WebRTCICETransportPolicy != p.conf.WebRTCICETransportPolicy ||
 		newConf.WebRTCHandshakeTimeout != p.conf.WebRTCHandshakeTimeout ||
 		newConf.WebRTCTrackGatherTimeout != p.conf.WebRTCTrackGatherTimeout ||
 		closeMetrics ||
@@ -823,6 +916,24 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 	closeSRTServer := newConf == nil ||
 		newConf.SRT != p.conf.SRT ||
 		newConf.SRTAddress != p.conf.SRTAddress ||
+		newConf.SRTDrainTimeout != p.conf.SRTDrainTimeout ||
+		newConf.SRTPublishNamespace != p.conf.SRTPublishNamespace ||
+		newConf.SRTAutoCreatePaths != p.conf.SRTAutoCreatePaths ||
+		newConf.SRTPublishBufferPolicy != p.conf.SRTPublishBufferPolicy ||
+		newConf.SRTPublishBufferSize != p.conf.SRTPublishBufferSize ||
+		newConf.SRTPublishStartTimeout != p.conf.SRTPublishStartTimeout ||
+		newConf.SRTAlarmRTT != p.conf.SRTAlarmRTT ||
+		newConf.SRTAlarmLossRate != p.conf.SRTAlarmLossRate ||
+		newConf.SRTHandshakeTimeout != p.conf.SRTHandshakeTimeout ||
+		newConf.SRTHandshakeRateLimit != p.conf.SRTHandshakeRateLimit ||
+		newConf.SRTMaxPendingHandshakes != p.conf.SRTMaxPendingHandshakes ||
+		newConf.SRTMaxConnsPerIP != p.conf.SRTMaxConnsPerIP ||
+		newConf.SRTConnsIPv6PrefixLength != p.conf.SRTConnsIPv6PrefixLength ||
+		newConf.SRTBitrateSmoothingWindow != p.conf.SRTBitrateSmoothingWindow ||
+		newConf.SRTUDPRecvBufferSize != p.conf.SRTUDPRecvBufferSize ||
+		newConf.SRTUDPSendBufferSize != p.conf.SRTUDPSendBufferSize ||
+		newConf.SRTUDPMaxPayloadSize != p.conf.SRTUDPMaxPayloadSize ||
+		newConf.SRTTTL != p.conf.SRTTTL ||
 		newConf.RTSPAddress != p.conf.RTSPAddress ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
 		newConf.WriteTimeout != p.conf.WriteTimeout ||
@@ -839,7 +950,11 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 		newConf.APIEncryption != p.conf.APIEncryption ||
 		newConf.APIServerKey != p.conf.APIServerKey ||
 		newConf.APIServerCert != p.conf.APIServerCert ||
-		newConf.APIAllowOrigin != p.conf.APIAllowOrigin ||
+		!reflect.DeepEqual(newConf.APIAllowOrigins, p.conf.APIAllowOrigins) ||
+		newConf.APIAllowCredentials != p.conf.APIAllowCredentials ||
+		newConf.APIAuthUser != p.conf.APIAuthUser ||
+		newConf.APIAuthPass != p.conf.APIAuthPass ||
+		newConf.APIAuthToken != p.conf.APIAuthToken ||
 		!reflect.DeepEqual(newConf.APITrustedProxies, p.conf.APITrustedProxies) ||
 		newConf.ReadTimeout != p.conf.ReadTimeout ||
 		closeAuthManager ||
@@ -976,9 +1091,24 @@ func (p *Core) closeResources(newConf *conf.Conf, calledByAPI bool) {
 }
 
 func (p *Core) reloadConf(newConf *conf.Conf, calledByAPI bool) error {
+	// listeners may be recreated
+	if p.api != nil {
+		p.api.SetReady(false)
+	}
+
 	p.closeResources(newConf, calledByAPI)
 	p.conf = newConf
-	return p.createResources(false)
+
+	err := p.createResources(false)
+	if err != nil {
+		return err
+	}
+
+	if p.api != nil {
+		p.api.SetReady(true)
+	}
+
+	return nil
 }
 
 // APIConfigSet is called by api.
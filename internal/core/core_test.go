@@ -1,6 +1,7 @@
 package core
 
 import (
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/stretchr/testify/require"
 )
@@ -131,3 +134,42 @@ func TestCoreHotReloading(t *testing.T) {
 		defer conn.Close()
 	}()
 }
+
+func TestCoreReloadLogLevel(t *testing.T) {
+	logFile := filepath.Join(os.TempDir(), "mediamtx-loglevel.log")
+	defer os.Remove(logFile)
+
+	p, ok := newInstance("api: yes\n" +
+		"logDestinations: [file]\n" +
+		"logFile: " + logFile + "\n" +
+		"paths:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	httpRequest(t, hc, http.MethodPatch, "http://localhost:9997/v3/config/global/patch", map[string]interface{}{
+		"logLevel": "debug",
+	}, nil)
+
+	time.Sleep(500 * time.Millisecond)
+
+	c := gortsplib.Client{}
+	err := c.StartRecording("rtsp://localhost:8554/test1",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer c.Close()
+
+	byts, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.Contains(t, string(byts), "DEB [path test1] created")
+
+	// the request is processed after the log level change,
+	// therefore it can be used to synchronize
+	_, err = p.pathManager.APIPathsList()
+	require.NoError(t, err)
+	require.Equal(t, conf.LogLevel(logger.Debug), p.pathManager.logLevel)
+}
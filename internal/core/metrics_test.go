@@ -2,6 +2,7 @@ package core
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
 	"io"
@@ -74,6 +75,10 @@ func TestMetrics(t *testing.T) {
 	t.Run("initial", func(t *testing.T) {
 		bo := httpPullFile(t, hc, "http://localhost:9998/metrics")
 
+		require.Regexp(t, `^build_info\{version=".+?",commit=".*?",goversion=".+?"\} 1`+"\n"+
+			`config_info\{hash="[0-9a-f]+"\} 1`+"\n", string(bo))
+		bo = bo[bytes.Index(bo, []byte("\npaths"))+1:]
+
 		require.Equal(t, `paths 0
 hls_muxers 0
 hls_muxers_bytes_sent 0
@@ -245,7 +250,9 @@ webrtc_sessions_bytes_sent 0
 		bo := httpPullFile(t, hc, "http://localhost:9998/metrics")
 
 		require.Regexp(t,
-			`^paths\{name=".*?",state="ready"\} 1`+"\n"+
+			`^build_info\{.+?\} 1`+"\n"+
+				`config_info\{.+?\} 1`+"\n"+
+				`paths\{name=".*?",state="ready"\} 1`+"\n"+
 				`paths_bytes_received\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths_bytes_sent\{name=".*?",state="ready"\} [0-9]+`+"\n"+
 				`paths\{name=".*?",state="ready"\} 1`+"\n"+
@@ -368,6 +375,8 @@ webrtc_sessions_bytes_sent 0
 
 		bo := httpPullFile(t, hc, "http://localhost:9998/metrics")
 
-		require.Equal(t, "paths 0\n", string(bo))
+		require.Regexp(t, `^build_info\{.+?\} 1`+"\n"+
+			`config_info\{.+?\} 1`+"\n"+
+			`paths 0`+"\n$", string(bo))
 	})
 }
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net"
 	"strconv"
 	"strings"
@@ -11,16 +12,54 @@ import (
 
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/hooks"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
 	"github.com/bluenviron/mediamtx/internal/recorder"
+	"github.com/bluenviron/mediamtx/internal/srtrelay"
 	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/transcoder"
 )
 
+const (
+	// a new publisher that shows up within this amount of time
+	// from the departure of the previous one is considered a reconnection.
+	pathPublisherReconnectWindow = 10 * time.Second
+)
+
+// sameTracks checks whether two descriptions contain the same tracks,
+// allowing readers to switch from one to the other.
+func sameTracks(desc1 *description.Session, desc2 *description.Session) bool {
+	if len(desc1.Medias) != len(desc2.Medias) {
+		return false
+	}
+
+	for i, medi1 := range desc1.Medias {
+		medi2 := desc2.Medias[i]
+
+		if medi1.Type != medi2.Type || len(medi1.Formats) != len(medi2.Formats) {
+			return false
+		}
+
+		for j, forma1 := range medi1.Formats {
+			forma2 := medi2.Formats[j]
+
+			if forma1.Codec() != forma2.Codec() ||
+				forma1.PayloadType() != forma2.PayloadType() ||
+				forma1.ClockRate() != forma2.ClockRate() {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 func emptyTimer() *time.Timer {
 	t := time.NewTimer(0)
 	<-t.C
@@ -32,6 +71,7 @@ type pathParent interface {
 	pathReady(*path)
 	pathNotReady(*path)
 	closePath(*path)
+	enabledOutputs() pathOutputs
 }
 
 type pathOnDemandState int
@@ -63,6 +103,34 @@ type pathAPIPathsGetReq struct {
 	res  chan pathAPIPathsGetRes
 }
 
+type pathAPIPathsSetEnabledReq struct {
+	name    string
+	enabled bool
+	res     chan error
+}
+
+type pathAPIPathsRecordReq struct {
+	enable bool
+	res    chan error
+}
+
+type pathAPIPathsRecordRotateReq struct {
+	res chan error
+}
+
+type pathAPIPathsConfReq struct {
+	res chan *conf.Path
+}
+
+type pathAPIPathsReaderHealthReq struct {
+	res chan *defs.APIPathReaderHealth
+}
+
+type pathAPIPathsCountersReq struct {
+	reset bool
+	res   chan *defs.APIPathCounters
+}
+
 type path struct {
 	parentCtx         context.Context
 	logLevel          conf.LogLevel
@@ -83,8 +151,26 @@ type path struct {
 	confMutex                      sync.RWMutex
 	source                         defs.Source
 	publisherQuery                 string
+	publisherUser                  string
+	publisherEnv                   externalcmd.Environment
+	publisherDepartureTime         time.Time
+	publisherReconnects            uint64
+	publisherLimit                 *pathPublisherLimit
+	publisherDesc                  *description.Session
+	publisherGenerateRTPPackets    bool
+	publisherHoldTimer             *time.Timer
 	stream                         *stream.Stream
 	recorder                       *recorder.Recorder
+	recordPostProcessQueue         *externalcmd.Queue
+	srtRelay                       *srtrelay.Relay
+	transcoder                     *transcoder.Transcoder
+	proxySourceMedia               *description.Media
+	proxySourceFormat              format.Format
+	proxyMedia                     *description.Media
+	proxy                          *transcoder.Transcoder
+	proxyReaders                   map[defs.Reader]struct{}
+	recordTriggered                bool
+	recordScheduleTimer            *time.Timer
 	readyTime                      time.Time
 	onUnDemandHook                 func(string)
 	onNotReadyHook                 func()
@@ -94,9 +180,21 @@ type path struct {
 	onDemandStaticSourceState      pathOnDemandState
 	onDemandStaticSourceReadyTimer *time.Timer
 	onDemandStaticSourceCloseTimer *time.Timer
+	onDemandStaticSourceCloseTime  time.Time
 	onDemandPublisherState         pathOnDemandState
 	onDemandPublisherReadyTimer    *time.Timer
 	onDemandPublisherCloseTimer    *time.Timer
+	onDemandPublisherCloseTime     time.Time
+	events                         pathEvents
+	decodeErrors                   pathDecodeErrors
+	egress                         pathEgress
+	counters                       pathCounters
+	egressExceeded                 bool
+	egressTimer                    *time.Timer
+	history                        pathHistory
+	historyTimer                   *time.Timer
+	historyBytes                   uint64
+	historyTime                    time.Time
 
 	// in
 	chReloadConf              chan *conf.Path
@@ -110,6 +208,11 @@ type path struct {
 	chAddReader               chan defs.PathAddReaderReq
 	chRemoveReader            chan defs.PathRemoveReaderReq
 	chAPIPathsGet             chan pathAPIPathsGetReq
+	chAPIPathsRecord          chan pathAPIPathsRecordReq
+	chAPIPathsRecordRotate    chan pathAPIPathsRecordRotateReq
+	chAPIPathsConf            chan pathAPIPathsConfReq
+	chAPIPathsReaderHealth    chan pathAPIPathsReaderHealthReq
+	chAPIPathsCounters        chan pathAPIPathsCountersReq
 
 	// out
 	done chan struct{}
@@ -121,10 +224,17 @@ func (pa *path) initialize() {
 	pa.ctx = ctx
 	pa.ctxCancel = ctxCancel
 	pa.readers = make(map[defs.Reader]struct{})
+	pa.proxyReaders = make(map[defs.Reader]struct{})
 	pa.onDemandStaticSourceReadyTimer = emptyTimer()
 	pa.onDemandStaticSourceCloseTimer = emptyTimer()
 	pa.onDemandPublisherReadyTimer = emptyTimer()
 	pa.onDemandPublisherCloseTimer = emptyTimer()
+	pa.publisherHoldTimer = emptyTimer()
+	pa.recordScheduleTimer = emptyTimer()
+	pa.egress.windowStart = time.Now()
+	pa.counters.since = time.Now()
+	pa.egressTimer = emptyTimer()
+	pa.historyTimer = emptyTimer()
 	pa.chReloadConf = make(chan *conf.Path)
 	pa.chStaticSourceSetReady = make(chan defs.PathSourceStaticSetReadyReq)
 	pa.chStaticSourceSetNotReady = make(chan defs.PathSourceStaticSetNotReadyReq)
@@ -136,10 +246,17 @@ func (pa *path) initialize() {
 	pa.chAddReader = make(chan defs.PathAddReaderReq)
 	pa.chRemoveReader = make(chan defs.PathRemoveReaderReq)
 	pa.chAPIPathsGet = make(chan pathAPIPathsGetReq)
+	pa.chAPIPathsRecord = make(chan pathAPIPathsRecordReq)
+	pa.chAPIPathsRecordRotate = make(chan pathAPIPathsRecordRotateReq)
+	pa.chAPIPathsConf = make(chan pathAPIPathsConfReq)
+	pa.chAPIPathsReaderHealth = make(chan pathAPIPathsReaderHealthReq)
+	pa.chAPIPathsCounters = make(chan pathAPIPathsCountersReq)
 	pa.done = make(chan struct{})
 
 	pa.Log(logger.Debug, "created")
 
+	pa.updateEgressTimer()
+
 	pa.wg.Add(1)
 	go pa.run()
 }
@@ -161,6 +278,16 @@ func (pa *path) Name() string {
 	return pa.name
 }
 
+// AddEvent adds an event to the recent events of the path.
+func (pa *path) AddEvent(level logger.Level, format string, args ...interface{}) {
+	pa.events.add(level, format, args...)
+}
+
+// AddDecodeError adds a decode error to the recent decode errors of the path.
+func (pa *path) AddDecodeError(message string) {
+	pa.decodeErrors.add(message)
+}
+
 func (pa *path) run() {
 	defer close(pa.done)
 	defer pa.wg.Done()
@@ -202,6 +329,10 @@ func (pa *path) run() {
 	pa.onDemandStaticSourceCloseTimer.Stop()
 	pa.onDemandPublisherReadyTimer.Stop()
 	pa.onDemandPublisherCloseTimer.Stop()
+	pa.publisherHoldTimer.Stop()
+	pa.recordScheduleTimer.Stop()
+	pa.egressTimer.Stop()
+	pa.historyTimer.Stop()
 
 	onUnInitHook()
 
@@ -261,6 +392,24 @@ func (pa *path) runInner() error {
 		case <-pa.onDemandPublisherCloseTimer.C:
 			pa.doOnDemandPublisherCloseTimer()
 
+		case <-pa.publisherHoldTimer.C:
+			pa.doPublisherHoldTimer()
+
+			if pa.shouldClose() {
+				return fmt.Errorf("not in use")
+			}
+
+		case <-pa.recordScheduleTimer.C:
+			pa.updateRecording()
+
+		case <-pa.egressTimer.C:
+			pa.checkEgress()
+			pa.updateEgressTimer()
+
+		case <-pa.historyTimer.C:
+			pa.sampleHistory()
+			pa.historyTimer = time.NewTimer(pathHistorySampleInterval)
+
 		case newConf := <-pa.chReloadConf:
 			pa.doReloadConf(newConf)
 
@@ -314,6 +463,21 @@ func (pa *path) runInner() error {
 		case req := <-pa.chAPIPathsGet:
 			pa.doAPIPathsGet(req)
 
+		case req := <-pa.chAPIPathsRecord:
+			pa.doAPIPathsRecord(req)
+
+		case req := <-pa.chAPIPathsRecordRotate:
+			pa.doAPIPathsRecordRotate(req)
+
+		case req := <-pa.chAPIPathsConf:
+			pa.doAPIPathsConf(req)
+
+		case req := <-pa.chAPIPathsReaderHealth:
+			pa.doAPIPathsReaderHealth(req)
+
+		case req := <-pa.chAPIPathsCounters:
+			pa.doAPIPathsCounters(req)
+
 		case <-pa.ctx.Done():
 			return fmt.Errorf("terminated")
 		}
@@ -357,6 +521,16 @@ func (pa *path) doOnDemandPublisherCloseTimer() {
 	pa.onDemandPublisherStop("not needed by anyone")
 }
 
+func (pa *path) doPublisherHoldTimer() {
+	pa.publisherHoldTimer = emptyTimer()
+
+	if pa.stream != nil {
+		pa.AddEvent(logger.Info, "publisher did not reconnect within %v",
+			time.Duration(pa.conf.PublisherHoldTimeout))
+		pa.setNotReady()
+	}
+}
+
 func (pa *path) doReloadConf(newConf *conf.Path) {
 	pa.confMutex.Lock()
 	pa.conf = newConf
@@ -366,13 +540,11 @@ func (pa *path) doReloadConf(newConf *conf.Path) {
 		pa.source.(*staticSourceHandler).reloadConf(newConf)
 	}
 
-	if pa.conf.Record {
-		if pa.stream != nil && pa.recorder == nil {
-			pa.startRecording()
-		}
-	} else if pa.recorder != nil {
-		pa.recorder.Close()
-		pa.recorder = nil
+	pa.updateRecording()
+	pa.updateEgressTimer()
+
+	if pa.stream != nil {
+		pa.checkOutputCodecs()
 	}
 }
 
@@ -459,7 +631,11 @@ func (pa *path) doDescribe(req defs.PathDescribeReq) {
 
 func (pa *path) doRemovePublisher(req defs.PathRemovePublisherReq) {
 	if pa.source == req.Author {
-		pa.executeRemovePublisher()
+		if pa.stream != nil && pa.conf.PublisherHoldTimeout != 0 {
+			pa.holdStream()
+		} else {
+			pa.executeRemovePublisher()
+		}
 	}
 	close(req.Res)
 }
@@ -472,19 +648,47 @@ func (pa *path) doAddPublisher(req defs.PathAddPublisherReq) {
 		return
 	}
 
+	if pa.conf.PublisherMaxAttempts != 0 {
+		ok, retryAfter := pa.publisherLimit.allow(
+			time.Now(),
+			pa.conf.PublisherMaxAttempts,
+			time.Duration(pa.conf.PublisherAttemptsWindow),
+			time.Duration(pa.conf.PublisherBackoff))
+		if !ok {
+			pa.AddEvent(logger.Warn, "publisher rejected, too many attempts (%s)",
+				describeSourceOrReader(req.Author.APISourceDescribe()))
+			req.Res <- defs.PathAddPublisherRes{
+				Err: defs.PathPublisherThrottledError{PathName: pa.name, RetryAfter: retryAfter},
+			}
+			return
+		}
+	}
+
 	if pa.source != nil {
-		if !pa.conf.OverridePublisher {
+		if pa.conf.PublisherConflictPolicy == conf.PublisherConflictPolicyReject {
 			req.Res <- defs.PathAddPublisherRes{Err: fmt.Errorf("someone is already publishing to path '%s'", pa.name)}
 			return
 		}
 
 		pa.Log(logger.Info, "closing existing publisher")
 		pa.source.(defs.Publisher).Close()
-		pa.executeRemovePublisher()
+
+		// the stream is kept until the new publisher starts publishing,
+		// in order to allow readers to switch to it.
+		pa.executeDetachPublisher()
+	}
+
+	if !pa.publisherDepartureTime.IsZero() &&
+		time.Since(pa.publisherDepartureTime) <= pathPublisherReconnectWindow {
+		pa.publisherReconnects++
 	}
 
 	pa.source = req.Author
 	pa.publisherQuery = req.AccessRequest.Query
+	pa.publisherUser = req.AccessRequest.User
+	pa.publisherEnv = req.ExternalCmdEnv
+
+	pa.AddEvent(logger.Info, "publisher connected (%s)", describeSourceOrReader(req.Author.APISourceDescribe()))
 
 	req.Res <- defs.PathAddPublisherRes{Path: pa}
 }
@@ -495,10 +699,43 @@ func (pa *path) doStartPublisher(req defs.PathStartPublisherReq) {
 		return
 	}
 
-	err := pa.setReady(req.Desc, req.GenerateRTPPackets)
-	if err != nil {
-		req.Res <- defs.PathStartPublisherRes{Err: err}
-		return
+	if pa.conf.StrictCodecValidation {
+		err := stream.ValidateCodecs(req.Desc)
+		if err != nil {
+			pa.AddEvent(logger.Warn, "publisher rejected, invalid codec parameters (%s): %v",
+				describeSourceOrReader(req.Author.APISourceDescribe()), err)
+			req.Res <- defs.PathStartPublisherRes{Err: fmt.Errorf("invalid codec parameters: %w", err)}
+			return
+		}
+	}
+
+	pa.publisherHoldTimer.Stop()
+	pa.publisherHoldTimer = emptyTimer()
+
+	// when a publisher has been taken over or has reconnected during the hold,
+	// and the new one has the same tracks, keep the existing stream,
+	// allowing readers to switch seamlessly.
+	// This is not possible when the jitter buffer is enabled,
+	// since it would discard packets that don't follow the ones of the previous publisher.
+	if pa.stream != nil &&
+		pa.conf.JitterBufferDelay == 0 &&
+		req.GenerateRTPPackets == pa.publisherGenerateRTPPackets &&
+		sameTracks(req.Desc, pa.publisherDesc) {
+		pa.stream.AddDescAlias(req.Desc)
+		pa.AddEvent(logger.Info, "stream has been taken over by a new publisher with the same tracks")
+	} else {
+		if pa.stream != nil {
+			pa.setNotReady()
+		}
+
+		err := pa.setReady(req.Desc, req.GenerateRTPPackets)
+		if err != nil {
+			req.Res <- defs.PathStartPublisherRes{Err: err}
+			return
+		}
+
+		pa.publisherDesc = req.Desc
+		pa.publisherGenerateRTPPackets = req.GenerateRTPPackets
 	}
 
 	req.Author.Log(logger.Info, "is publishing to path '%s', %s",
@@ -579,6 +816,22 @@ func (pa *path) doAPIPathsGet(req pathAPIPathsGetReq) {
 				v := pa.source.APISourceDescribe()
 				return &v
 			}(),
+			SourceError: func() *string {
+				if source, ok := pa.source.(*staticSourceHandler); ok {
+					if err, _ := source.lastError(); err != nil {
+						v := err.Error()
+						return &v
+					}
+				}
+				return nil
+			}(),
+			SourceFailed: func() bool {
+				if source, ok := pa.source.(*staticSourceHandler); ok {
+					_, failed := source.lastError()
+					return failed
+				}
+				return false
+			}(),
 			Ready: pa.stream != nil,
 			ReadyTime: func() *time.Time {
 				if pa.stream == nil {
@@ -612,10 +865,130 @@ func (pa *path) doAPIPathsGet(req pathAPIPathsGetReq) {
 				}
 				return ret
 			}(),
+			PublisherReconnects: pa.publisherReconnects,
+			OnDemandCloseRemaining: func() *conf.StringDuration {
+				var closeTime time.Time
+				switch {
+				case pa.onDemandStaticSourceState == pathOnDemandStateClosing:
+					closeTime = pa.onDemandStaticSourceCloseTime
+				case pa.onDemandPublisherState == pathOnDemandStateClosing:
+					closeTime = pa.onDemandPublisherCloseTime
+				default:
+					return nil
+				}
+				v := conf.StringDuration(max(time.Until(closeTime), 0))
+				return &v
+			}(),
+			DecodeErrors: pa.decodeErrors.list(),
+			TrackStates: func() []*defs.APIPathTrackState {
+				ret := []*defs.APIPathTrackState{}
+				if pa.stream == nil {
+					return ret
+				}
+
+				codecs := defs.MediasToCodecs(pa.stream.Desc().Medias)
+				reorderDepths := pa.stream.TracksReorderDepth()
+				now := time.Now()
+
+				for i, lastReceived := range pa.stream.TracksLastReceived() {
+					item := &defs.APIPathTrackState{
+						Codec:        codecs[i],
+						ReorderDepth: conf.StringDuration(reorderDepths[i]),
+					}
+					if !lastReceived.IsZero() {
+						item.LastSeen = &lastReceived
+						item.Active = now.Sub(lastReceived) < time.Duration(pa.conf.TrackActiveTimeout)
+					}
+					ret = append(ret, item)
+				}
+
+				return ret
+			}(),
+			SRTRelay: func() *defs.APIPathSRTRelay {
+				if pa.srtRelay == nil {
+					return nil
+				}
+				return pa.srtRelay.APIItem()
+			}(),
+			PublisherLimit: func() *defs.APIPathPublisherLimit {
+				if pa.conf.PublisherMaxAttempts == 0 {
+					return nil
+				}
+				return pa.publisherLimit.apiItem(time.Now())
+			}(),
 		},
 	}
 }
 
+func (pa *path) doAPIPathsRecord(req pathAPIPathsRecordReq) {
+	pa.recordTriggered = req.enable
+	pa.updateRecording()
+
+	// the trigger has been removed, but the configuration keeps recording
+	if !req.enable && pa.recordingEnabledByConf(time.Now()) {
+		req.res <- defs.ErrRecordingEnabledByConf
+		return
+	}
+
+	req.res <- nil
+}
+
+func (pa *path) doAPIPathsRecordRotate(req pathAPIPathsRecordRotateReq) {
+	if pa.recorder == nil {
+		req.res <- defs.ErrRecordingNotActive
+		return
+	}
+
+	pa.recorder.Rotate()
+	req.res <- nil
+}
+
+func (pa *path) doAPIPathsReaderHealth(req pathAPIPathsReaderHealthReq) {
+	stats := make([]defs.ReaderLinkStats, 0, len(pa.readers))
+	for r := range pa.readers {
+		if lr, ok := r.(defs.LinkStatsReader); ok {
+			if s, ok := lr.LinkStats(); ok {
+				stats = append(stats, s)
+			}
+		}
+	}
+
+	req.res <- aggregateReaderHealth(len(pa.readers), stats)
+}
+
+func (pa *path) doAPIPathsCounters(req pathAPIPathsCountersReq) {
+	var bytesReceived, bytesSent uint64
+	if pa.stream != nil {
+		bytesReceived = pa.stream.BytesReceived()
+		bytesSent = pa.stream.BytesSent()
+	}
+
+	req.res <- pa.counters.get(bytesReceived, bytesSent, req.reset, time.Now())
+}
+
+// doAPIPathsConf returns the configuration that is in use by the path,
+// with the path name, regular expression groups and runtime overrides applied.
+func (pa *path) doAPIPathsConf(req pathAPIPathsConfReq) {
+	c := pa.conf.Clone()
+	c.Name = pa.name
+
+	var query string
+	if ssh, ok := pa.source.(*staticSourceHandler); ok {
+		query = ssh.query
+	}
+
+	c.Source = resolveSource(c.Source, pa.matches, query)
+	for i, source := range c.SourceFailover {
+		c.SourceFailover[i] = resolveSource(source, pa.matches, query)
+	}
+
+	if pa.recordTriggered {
+		c.Record = true
+	}
+
+	req.res <- c
+}
+
 func (pa *path) SafeConf() *conf.Path {
 	pa.confMutex.RLock()
 	defer pa.confMutex.RUnlock()
@@ -636,9 +1009,26 @@ func (pa *path) ExternalCmdEnv() externalcmd.Environment {
 		}
 	}
 
+	if user, id, ok := pa.publisherIdentity(); ok {
+		env["MTX_SOURCE_ID"] = id
+		env["MTX_SOURCE_USER"] = user
+		env["MTX_QUERY"] = pa.publisherQuery
+		maps.Copy(env, pa.publisherEnv)
+	}
+
 	return env
 }
 
+// publisherIdentity returns the user and the ID of the publisher, if any.
+func (pa *path) publisherIdentity() (string, string, bool) {
+	publisher, ok := pa.source.(defs.Publisher)
+	if !ok {
+		return "", "", false
+	}
+
+	return pa.publisherUser, publisher.APISourceDescribe().ID, true
+}
+
 func (pa *path) shouldClose() bool {
 	return pa.conf.Regexp != nil &&
 		pa.source == nil &&
@@ -659,6 +1049,7 @@ func (pa *path) onDemandStaticSourceStart(query string) {
 func (pa *path) onDemandStaticSourceScheduleClose() {
 	pa.onDemandStaticSourceCloseTimer.Stop()
 	pa.onDemandStaticSourceCloseTimer = time.NewTimer(time.Duration(pa.conf.SourceOnDemandCloseAfter))
+	pa.onDemandStaticSourceCloseTime = time.Now().Add(time.Duration(pa.conf.SourceOnDemandCloseAfter))
 
 	pa.onDemandStaticSourceState = pathOnDemandStateClosing
 }
@@ -692,6 +1083,7 @@ func (pa *path) onDemandPublisherStart(query string) {
 func (pa *path) onDemandPublisherScheduleClose() {
 	pa.onDemandPublisherCloseTimer.Stop()
 	pa.onDemandPublisherCloseTimer = time.NewTimer(time.Duration(pa.conf.RunOnDemandCloseAfter))
+	pa.onDemandPublisherCloseTime = time.Now().Add(time.Duration(pa.conf.RunOnDemandCloseAfter))
 
 	pa.onDemandPublisherState = pathOnDemandStateClosing
 }
@@ -709,24 +1101,83 @@ func (pa *path) onDemandPublisherStop(reason string) {
 }
 
 func (pa *path) setReady(desc *description.Session, allocateEncoder bool) error {
+	var transcodeSourceMedia *description.Media
+	var transcodeSourceFormat format.Format
+	var transcodeTargetMedia *description.Media
+
+	if pa.conf.Transcode {
+		var newDesc *description.Session
+		newDesc, transcodeSourceMedia, transcodeSourceFormat, transcodeTargetMedia = transcoder.ExtendDesc(
+			desc, pa.conf.TranscodeCodec)
+
+		if newDesc != nil {
+			desc = newDesc
+		} else {
+			pa.Log(logger.Info, "transcoding skipped: the stream doesn't contain a video track"+
+				" that needs to be transcoded")
+		}
+	}
+
+	if pa.conf.Proxy {
+		var newDesc *description.Session
+		newDesc, pa.proxySourceMedia, pa.proxySourceFormat, pa.proxyMedia = transcoder.ExtendDescProxy(desc)
+
+		if newDesc != nil {
+			desc = newDesc
+		} else {
+			pa.Log(logger.Info, "proxy skipped: the stream doesn't contain a video track that can be encoded")
+		}
+	}
+
 	var err error
 	pa.stream, err = stream.New(
 		pa.writeQueueSize,
 		pa.udpMaxPayloadSize,
 		desc,
 		allocateEncoder,
+		time.Duration(pa.conf.JitterBufferDelay),
+		time.Duration(pa.conf.MaxTimestampCorrection),
+		time.Duration(pa.conf.RTCPSenderReportPeriod),
 		logger.NewLimitedLogger(pa.source),
 	)
 	if err != nil {
 		return err
 	}
 
-	if pa.conf.Record {
-		pa.startRecording()
+	if transcodeTargetMedia != nil {
+		pa.transcoder = &transcoder.Transcoder{
+			Command:         pa.conf.TranscodeCommand,
+			ExternalCmdPool: pa.externalCmdPool,
+			ExternalCmdEnv:  pa.ExternalCmdEnv(),
+			Stream:          pa.stream,
+			SourceMedia:     transcodeSourceMedia,
+			SourceFormat:    transcodeSourceFormat,
+			TargetMedia:     transcodeTargetMedia,
+			Parent:          pa,
+		}
+		pa.transcoder.Initialize()
+	}
+
+	if pa.proxyMedia != nil {
+		pa.stream.SetProxyMedia(pa.proxySourceMedia, pa.proxyMedia)
+	}
+
+	if pa.conf.TargetKeyframeInterval != 0 {
+		pa.stream.SetTargetKeyFrameInterval(time.Duration(pa.conf.TargetKeyframeInterval))
+	}
+
+	pa.updateRecording()
+
+	if pa.conf.SRTRelay != "" {
+		pa.startSRTRelay()
 	}
 
 	pa.readyTime = time.Now()
 
+	pa.historyBytes = 0
+	pa.historyTime = pa.readyTime
+	pa.historyTimer = time.NewTimer(pathHistorySampleInterval)
+
 	pa.onNotReadyHook = hooks.OnReady(hooks.OnReadyParams{
 		Logger:          pa,
 		ExternalCmdPool: pa.externalCmdPool,
@@ -736,11 +1187,26 @@ func (pa *path) setReady(desc *description.Session, allocateEncoder bool) error
 		Query:           pa.publisherQuery,
 	})
 
+	pa.AddEvent(logger.Info, "stream is ready, %s", defs.MediasInfo(desc.Medias))
+
+	pa.checkOutputCodecs()
+
 	pa.parent.pathReady(pa)
 
 	return nil
 }
 
+// checkOutputCodecs warns about outputs that are not able to deliver tracks of the stream.
+// Codecs can change when the source reconnects, therefore incompatibilities are not errors.
+func (pa *path) checkOutputCodecs() {
+	outputs := enabledPathOutputs(pa.conf, pa.parent.enabledOutputs())
+
+	for _, w := range pathCodecWarnings(outputs, pa.stream.Desc().Medias) {
+		pa.Log(logger.Warn, "%s", w)
+		pa.AddEvent(logger.Warn, "%s", w)
+	}
+}
+
 func (pa *path) consumeOnHoldRequests() {
 	for _, req := range pa.describeRequestsOnHold {
 		req.Res <- defs.PathDescribeRes{
@@ -758,6 +1224,8 @@ func (pa *path) consumeOnHoldRequests() {
 func (pa *path) setNotReady() {
 	pa.parent.pathNotReady(pa)
 
+	pa.AddEvent(logger.Info, "stream is not ready")
+
 	for r := range pa.readers {
 		pa.executeRemoveReader(r)
 		r.Close()
@@ -765,25 +1233,212 @@ func (pa *path) setNotReady() {
 
 	pa.onNotReadyHook()
 
+	pa.publisherHoldTimer.Stop()
+	pa.publisherHoldTimer = emptyTimer()
+
+	pa.historyTimer.Stop()
+	pa.historyTimer = emptyTimer()
+
 	if pa.recorder != nil {
 		pa.recorder.Close()
 		pa.recorder = nil
 	}
 
+	if pa.srtRelay != nil {
+		pa.srtRelay.Close()
+		pa.srtRelay = nil
+	}
+
+	if pa.transcoder != nil {
+		pa.transcoder.Close()
+		pa.transcoder = nil
+	}
+
+	pa.proxySourceMedia = nil
+	pa.proxySourceFormat = nil
+	pa.proxyMedia = nil
+
 	if pa.stream != nil {
+		pa.egress.streamClosed(pa.stream.BytesSent(), time.Now())
+		pa.counters.streamClosed(pa.stream.BytesReceived(), pa.stream.BytesSent())
 		pa.stream.Close()
 		pa.stream = nil
 	}
 }
 
+// sampleHistory stores the current value of path metrics into the history.
+func (pa *path) sampleHistory() {
+	now := time.Now()
+	bytes := pa.stream.BytesReceived()
+
+	pa.history.add(defs.APIPathHistoryMetricBitrate,
+		float64(bytes-pa.historyBytes)*8/now.Sub(pa.historyTime).Seconds(), now)
+
+	pa.historyBytes = bytes
+	pa.historyTime = now
+
+	if source, ok := pa.source.(defs.LinkRTTSource); ok {
+		if rtt, ok := source.LinkRTT(); ok {
+			pa.history.add(defs.APIPathHistoryMetricRTT, float64(rtt)/float64(time.Millisecond), now)
+		}
+	}
+}
+
+func (pa *path) egressUsage() uint64 {
+	var streamBytes uint64
+	if pa.stream != nil {
+		streamBytes = pa.stream.BytesSent()
+	}
+	return pa.egress.usage(streamBytes, time.Now())
+}
+
+func (pa *path) updateEgressTimer() {
+	pa.egressTimer.Stop()
+	if pa.conf.MaxEgressBytesPerHour != 0 {
+		pa.egressTimer = time.NewTimer(pathEgressCheckPeriod)
+	} else {
+		pa.egressTimer = emptyTimer()
+	}
+}
+
+// checkEgress checks whether the egress cap has been reached or reset.
+func (pa *path) checkEgress() {
+	exceeded := pa.conf.MaxEgressBytesPerHour != 0 &&
+		pa.egressUsage() >= uint64(pa.conf.MaxEgressBytesPerHour)
+
+	if exceeded == pa.egressExceeded {
+		return
+	}
+
+	pa.egressExceeded = exceeded
+
+	if !exceeded {
+		pa.Log(logger.Info, "egress cap has been reset, readers are accepted again")
+		pa.AddEvent(logger.Info, "egress cap has been reset")
+		return
+	}
+
+	pa.Log(logger.Warn, "egress cap of %d bytes per hour reached, new readers are rejected",
+		pa.conf.MaxEgressBytesPerHour)
+	pa.AddEvent(logger.Warn, "egress cap of %d bytes per hour reached", pa.conf.MaxEgressBytesPerHour)
+
+	if pa.conf.MaxEgressDropReaders {
+		for r := range pa.readers {
+			pa.executeRemoveReader(r)
+			r.Close()
+		}
+	}
+}
+
+// recordingEnabledByConf returns whether the configuration requires the stream
+// to be recorded at the given time.
+func (pa *path) recordingEnabledByConf(now time.Time) bool {
+	if !pa.conf.Record {
+		return false
+	}
+
+	return len(pa.conf.RecordSchedule) == 0 || pa.conf.RecordSchedule.Active(now)
+}
+
+// recordingEnabled returns whether the stream has to be recorded at the given time.
+// Recording triggered through the API is performed regardless of the configuration.
+func (pa *path) recordingEnabled(now time.Time) bool {
+	return pa.recordTriggered || pa.recordingEnabledByConf(now)
+}
+
+// updateRecording starts or stops the recorder depending on the configuration,
+// the record schedule and the API trigger.
+// Stopping the recorder finalizes the current segment.
+func (pa *path) updateRecording() {
+	now := time.Now()
+
+	pa.recordScheduleTimer.Stop()
+	if pa.conf.Record && len(pa.conf.RecordSchedule) != 0 {
+		if next := pa.conf.RecordSchedule.NextChange(now); !next.IsZero() {
+			pa.recordScheduleTimer = time.NewTimer(next.Sub(now))
+		}
+	}
+
+	if pa.stream == nil {
+		return
+	}
+
+	if pa.recordingEnabled(now) {
+		if pa.recorder == nil {
+			pa.startRecording()
+		}
+	} else if pa.recorder != nil {
+		pa.recorder.Close()
+		pa.recorder = nil
+	}
+}
+
+func (pa *path) startSRTRelay() {
+	pa.srtRelay = &srtrelay.Relay{
+		URL:               pa.conf.SRTRelay,
+		MaxBitrate:        uint64(pa.conf.SRTRelayMaxBitrate),
+		WriteTimeout:      time.Duration(pa.writeTimeout),
+		UDPMaxPayloadSize: pa.udpMaxPayloadSize,
+		PIDs: mpegts.PIDs{
+			PMT:    uint16(pa.conf.MPEGTSPMTPID),
+			Tracks: pa.conf.MPEGTSTrackPIDs,
+		},
+		Service: mpegts.Service{
+			Name:     pa.conf.TSServiceName,
+			Provider: pa.conf.TSProviderName,
+		},
+		Stream: pa.stream,
+		Parent: pa,
+	}
+	pa.srtRelay.Initialize()
+}
+
 func (pa *path) startRecording() {
+	sourceUser, sourceID, _ := pa.publisherIdentity()
+
+	mpegtsPIDs := mpegts.PIDs{
+		PMT:    uint16(pa.conf.MPEGTSPMTPID),
+		Tracks: pa.conf.MPEGTSTrackPIDs,
+	}
+
+	if pa.conf.RecordPostProcessCommand != "" && pa.recordPostProcessQueue == nil {
+		pa.recordPostProcessQueue = &externalcmd.Queue{
+			Pool:          pa.externalCmdPool,
+			MaxConcurrent: pa.conf.RecordPostProcessMaxConcurrent,
+			MaxRetries:    pa.conf.RecordPostProcessMaxRetries,
+		}
+		pa.recordPostProcessQueue.Initialize()
+	}
+
 	pa.recorder = &recorder.Recorder{
-		PathFormat:      pa.conf.RecordPath,
-		Format:          pa.conf.RecordFormat,
-		PartDuration:    time.Duration(pa.conf.RecordPartDuration),
-		SegmentDuration: time.Duration(pa.conf.RecordSegmentDuration),
-		PathName:        pa.name,
-		Stream:          pa.stream,
+		PathFormat:       pa.conf.RecordPath,
+		PathSanitize:     pa.conf.RecordPathSanitize,
+		Location:         pa.conf.RecordLocation(),
+		Format:           pa.conf.RecordFormat,
+		Compression:      pa.conf.RecordCompression,
+		Captions:         pa.conf.RecordCaptions,
+		Tracks:           pa.conf.RecordTracks,
+		PartDuration:     time.Duration(pa.conf.RecordPartDuration),
+		FragmentDuration: time.Duration(pa.conf.RecordFragmentDuration),
+		WriteSidx:        pa.conf.RecordWriteSidx,
+		FillGaps:         pa.conf.RecordFillGaps,
+		WriteManifest:    pa.conf.RecordWriteManifest,
+		SeparateInit:     pa.conf.RecordSeparateInit,
+		MPEGTSPIDs:       mpegtsPIDs,
+		MPEGTSService: mpegts.Service{
+			Name:     pa.conf.TSServiceName,
+			Provider: pa.conf.TSProviderName,
+		},
+		SegmentDuration:    time.Duration(pa.conf.RecordSegmentDuration),
+		KeyFrameInterval:   time.Duration(pa.conf.TargetKeyframeInterval),
+		MaxSegments:        pa.conf.RecordMaxSegments,
+		SnapshotInterval:   pa.conf.RecordSnapshotInterval,
+		SnapshotPathFormat: pa.conf.RecordSnapshotPath,
+		PathName:           pa.name,
+		SourceUser:         sourceUser,
+		SourceID:           sourceID,
+		SourceQuery:        pa.publisherQuery,
+		Stream:             pa.stream,
 		OnSegmentCreate: func(segmentPath string) {
 			if pa.conf.RunOnRecordSegmentCreate != "" {
 				env := pa.ExternalCmdEnv()
@@ -812,14 +1467,71 @@ func (pa *path) startRecording() {
 					env,
 					nil)
 			}
+
+			if pa.recordPostProcessQueue != nil {
+				pa.runRecordPostProcess(segmentPath, segmentDuration)
+			}
 		},
-		Parent: pa,
+		Parent: &pathEventsLogger{pa: pa},
 	}
 	pa.recorder.Initialize()
 }
 
+func (pa *path) runRecordPostProcess(segmentPath string, segmentDuration time.Duration) {
+	cmdstr, err := externalcmd.ExpandArgs(pa.conf.RecordPostProcessCommand,
+		recordPostProcessValues(pa.conf, pa.name, segmentPath, segmentDuration))
+	if err != nil {
+		pa.Log(logger.Error, "invalid recordPostProcessCommand: %v", err)
+		return
+	}
+
+	env := pa.ExternalCmdEnv()
+	env["MTX_SEGMENT_PATH"] = segmentPath
+	env["MTX_SEGMENT_DURATION"] = strconv.FormatFloat(segmentDuration.Seconds(), 'f', -1, 64)
+
+	pa.Log(logger.Info, "recordPostProcessCommand launched for segment %s", segmentPath)
+	pa.recordPostProcessQueue.Run(cmdstr, env, func(err error) {
+		if err != nil {
+			pa.Log(logger.Error, "recordPostProcessCommand failed for segment %s: %v", segmentPath, err)
+		} else {
+			pa.Log(logger.Debug, "recordPostProcessCommand completed for segment %s", segmentPath)
+		}
+	})
+}
+
+func (pa *path) startProxy() {
+	pa.proxy = &transcoder.Transcoder{
+		Command:         pa.conf.ProxyCommand,
+		ExternalCmdPool: pa.externalCmdPool,
+		ExternalCmdEnv:  pa.ExternalCmdEnv(),
+		Stream:          pa.stream,
+		SourceMedia:     pa.proxySourceMedia,
+		SourceFormat:    pa.proxySourceFormat,
+		TargetMedia:     pa.proxyMedia,
+		Parent:          pa,
+		Name:            "proxy",
+	}
+	pa.proxy.Initialize()
+}
+
+func (pa *path) stopProxy() {
+	pa.proxy.Close()
+	pa.proxy = nil
+}
+
 func (pa *path) executeRemoveReader(r defs.Reader) {
 	delete(pa.readers, r)
+
+	if _, ok := pa.proxyReaders[r]; ok {
+		delete(pa.proxyReaders, r)
+
+		// the proxy encoder runs only while someone is reading the proxy track.
+		if len(pa.proxyReaders) == 0 {
+			pa.stopProxy()
+		}
+	}
+
+	pa.AddEvent(logger.Info, "reader removed (%s)", describeSourceOrReader(r.APIReaderDescribe()))
 }
 
 func (pa *path) executeRemovePublisher() {
@@ -827,7 +1539,29 @@ func (pa *path) executeRemovePublisher() {
 		pa.setNotReady()
 	}
 
+	pa.executeDetachPublisher()
+}
+
+// holdStream detaches the publisher and keeps the stream and its readers
+// for publisherHoldTimeout, allowing the publisher to reconnect without disrupting readers.
+func (pa *path) holdStream() {
+	pa.executeDetachPublisher()
+
+	pa.AddEvent(logger.Info, "holding stream for %v, waiting for the publisher to reconnect",
+		time.Duration(pa.conf.PublisherHoldTimeout))
+
+	pa.publisherHoldTimer.Stop()
+	pa.publisherHoldTimer = time.NewTimer(time.Duration(pa.conf.PublisherHoldTimeout))
+}
+
+func (pa *path) executeDetachPublisher() {
+	pa.AddEvent(logger.Info, "publisher disconnected (%s)", describeSourceOrReader(pa.source.APISourceDescribe()))
+
 	pa.source = nil
+	pa.publisherUser = ""
+	pa.publisherQuery = ""
+	pa.publisherEnv = nil
+	pa.publisherDepartureTime = time.Now()
 }
 
 func (pa *path) addReaderPost(req defs.PathAddReaderReq) {
@@ -844,8 +1578,31 @@ func (pa *path) addReaderPost(req defs.PathAddReaderReq) {
 		return
 	}
 
+	if pa.conf.MaxEgressBytesPerHour != 0 {
+		pa.checkEgress()
+		if pa.egressExceeded {
+			req.Res <- defs.PathAddReaderRes{Err: fmt.Errorf("maximum egress per hour reached")}
+			return
+		}
+	}
+
+	if req.Proxy && pa.proxyMedia == nil {
+		req.Res <- defs.PathAddReaderRes{Err: fmt.Errorf("proxy track is not available")}
+		return
+	}
+
 	pa.readers[req.Author] = struct{}{}
 
+	if req.Proxy {
+		pa.proxyReaders[req.Author] = struct{}{}
+
+		if pa.proxy == nil {
+			pa.startProxy()
+		}
+	}
+
+	pa.AddEvent(logger.Info, "reader added (%s)", describeSourceOrReader(req.Author.APIReaderDescribe()))
+
 	if pa.conf.HasOnDemandStaticSource() {
 		if pa.onDemandStaticSourceState == pathOnDemandStateClosing {
 			pa.onDemandStaticSourceState = pathOnDemandStateReady
@@ -996,3 +1753,80 @@ func (pa *path) APIPathsGet(req pathAPIPathsGetReq) (*defs.APIPath, error) {
 		return nil, fmt.Errorf("terminated")
 	}
 }
+
+// APIPathsRecordRotate is called by api.
+func (pa *path) APIPathsRecordRotate() error {
+	req := pathAPIPathsRecordRotateReq{
+		res: make(chan error),
+	}
+
+	select {
+	case pa.chAPIPathsRecordRotate <- req:
+		return <-req.res
+
+	case <-pa.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsConf is called by api.
+func (pa *path) APIPathsConf() (*conf.Path, error) {
+	req := pathAPIPathsConfReq{
+		res: make(chan *conf.Path),
+	}
+
+	select {
+	case pa.chAPIPathsConf <- req:
+		return <-req.res, nil
+
+	case <-pa.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsReaderHealth is called by api.
+func (pa *path) APIPathsReaderHealth() (*defs.APIPathReaderHealth, error) {
+	req := pathAPIPathsReaderHealthReq{
+		res: make(chan *defs.APIPathReaderHealth),
+	}
+
+	select {
+	case pa.chAPIPathsReaderHealth <- req:
+		return <-req.res, nil
+
+	case <-pa.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsCounters is called by api.
+func (pa *path) APIPathsCounters(reset bool) (*defs.APIPathCounters, error) {
+	req := pathAPIPathsCountersReq{
+		reset: reset,
+		res:   make(chan *defs.APIPathCounters),
+	}
+
+	select {
+	case pa.chAPIPathsCounters <- req:
+		return <-req.res, nil
+
+	case <-pa.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsRecord is called by api.
+func (pa *path) APIPathsRecord(enable bool) error {
+	req := pathAPIPathsRecordReq{
+		enable: enable,
+		res:    make(chan error),
+	}
+
+	select {
+	case pa.chAPIPathsRecord <- req:
+		return <-req.res
+
+	case <-pa.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
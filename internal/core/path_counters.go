@@ -0,0 +1,51 @@
+package core
+
+import (
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+// pathCounters accumulates the traffic of a path since its creation or the last reset,
+// across all the streams that the path had in the meanwhile.
+type pathCounters struct {
+	since time.Time
+
+	// bytes of streams that were closed after the last reset
+	prevBytesReceived uint64
+	prevBytesSent     uint64
+
+	// bytes of the current stream before the last reset
+	baseBytesReceived uint64
+	baseBytesSent     uint64
+}
+
+// get returns the counters, given the bytes received and sent by the current stream
+// since its creation, and optionally resets them.
+// Since the given values are a snapshot of the stream counters, traffic that
+// is received while the reset is in progress is counted in the following period.
+func (c *pathCounters) get(bytesReceived uint64, bytesSent uint64, reset bool, now time.Time) *defs.APIPathCounters {
+	ret := &defs.APIPathCounters{
+		Since:         c.since,
+		BytesReceived: c.prevBytesReceived + bytesReceived - c.baseBytesReceived,
+		BytesSent:     c.prevBytesSent + bytesSent - c.baseBytesSent,
+	}
+
+	if reset {
+		c.since = now
+		c.prevBytesReceived = 0
+		c.prevBytesSent = 0
+		c.baseBytesReceived = bytesReceived
+		c.baseBytesSent = bytesSent
+	}
+
+	return ret
+}
+
+// streamClosed must be called before the stream of the path is closed.
+func (c *pathCounters) streamClosed(bytesReceived uint64, bytesSent uint64) {
+	c.prevBytesReceived += bytesReceived - c.baseBytesReceived
+	c.prevBytesSent += bytesSent - c.baseBytesSent
+	c.baseBytesReceived = 0
+	c.baseBytesSent = 0
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+func TestPathCounters(t *testing.T) {
+	start := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := pathCounters{since: start}
+
+	now := start.Add(10 * time.Minute)
+	require.Equal(t, &defs.APIPathCounters{
+		Since:         start,
+		BytesReceived: 1000,
+		BytesSent:     2000,
+	}, c.get(1000, 2000, false, now))
+
+	// bytes of closed streams are kept
+	c.streamClosed(1500, 3000)
+	require.Equal(t, &defs.APIPathCounters{
+		Since:         start,
+		BytesReceived: 1700,
+		BytesSent:     3100,
+	}, c.get(200, 100, false, now))
+
+	// a reset returns the total
+	require.Equal(t, &defs.APIPathCounters{
+		Since:         start,
+		BytesReceived: 1800,
+		BytesSent:     3300,
+	}, c.get(300, 300, true, now))
+
+	// subsequent reads start from zero
+	require.Equal(t, &defs.APIPathCounters{
+		Since: now,
+	}, c.get(300, 300, false, now))
+
+	require.Equal(t, &defs.APIPathCounters{
+		Since:         now,
+		BytesReceived: 100,
+		BytesSent:     50,
+	}, c.get(400, 350, false, now))
+
+	// bytes of the current stream before the reset are not counted when it is closed
+	c.streamClosed(500, 400)
+	require.Equal(t, &defs.APIPathCounters{
+		Since:         now,
+		BytesReceived: 300,
+		BytesSent:     150,
+	}, c.get(100, 50, false, now))
+}
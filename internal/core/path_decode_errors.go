@@ -0,0 +1,63 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+const (
+	pathMaxDecodeErrors = 10
+)
+
+// pathDecodeErrors contains the most recent distinct decode errors of a path,
+// with the number of their occurrences. It can be accessed concurrently.
+type pathDecodeErrors struct {
+	mutex sync.Mutex
+	items []defs.APIPathDecodeError
+}
+
+func (e *pathDecodeErrors) add(message string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := time.Now()
+
+	for i, item := range e.items {
+		if item.Message == message {
+			item.Count++
+			item.LastTime = now
+
+			// move the error to the end, in order to keep items sorted by last occurrence
+			copy(e.items[i:], e.items[i+1:])
+			e.items[len(e.items)-1] = item
+			return
+		}
+	}
+
+	if len(e.items) >= pathMaxDecodeErrors {
+		e.items = e.items[1:]
+	}
+
+	e.items = append(e.items, defs.APIPathDecodeError{
+		Message:   message,
+		Count:     1,
+		FirstTime: now,
+		LastTime:  now,
+	})
+}
+
+// list returns decode errors sorted from the least to the most recent.
+func (e *pathDecodeErrors) list() []*defs.APIPathDecodeError {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ret := make([]*defs.APIPathDecodeError, len(e.items))
+	for i := range e.items {
+		item := e.items[i]
+		ret[i] = &item
+	}
+
+	return ret
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathDecodeErrors(t *testing.T) {
+	var e pathDecodeErrors
+
+	e.add("error A")
+	e.add("error B")
+	e.add("error A")
+
+	l := e.list()
+	require.Equal(t, 2, len(l))
+	require.Equal(t, "error B", l[0].Message)
+	require.Equal(t, uint64(1), l[0].Count)
+	require.Equal(t, "error A", l[1].Message)
+	require.Equal(t, uint64(2), l[1].Count)
+	require.False(t, l[1].LastTime.Before(l[1].FirstTime))
+
+	for i := 0; i < pathMaxDecodeErrors; i++ {
+		e.add("other error " + string(rune('a'+i)))
+	}
+
+	// least recent errors are discarded
+	l = e.list()
+	require.Equal(t, pathMaxDecodeErrors, len(l))
+	require.Equal(t, "other error a", l[0].Message)
+}
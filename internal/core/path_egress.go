@@ -0,0 +1,40 @@
+package core
+
+import (
+	"time"
+)
+
+const (
+	pathEgressWindow      = time.Hour
+	pathEgressCheckPeriod = 1 * time.Second
+)
+
+// pathEgress measures the bytes sent by a path to readers in fixed windows,
+// across all the streams that the path had during the window.
+type pathEgress struct {
+	windowStart time.Time
+
+	// bytes sent by streams that were closed during the window
+	prevBytes uint64
+
+	// bytes sent by the current stream before the window started
+	baseline uint64
+}
+
+// usage returns the bytes sent during the current window,
+// given the bytes sent by the current stream since its creation.
+func (e *pathEgress) usage(streamBytes uint64, now time.Time) uint64 {
+	if now.Sub(e.windowStart) >= pathEgressWindow {
+		e.windowStart = now
+		e.prevBytes = 0
+		e.baseline = streamBytes
+	}
+
+	return e.prevBytes + streamBytes - e.baseline
+}
+
+// streamClosed must be called before the stream of the path is closed.
+func (e *pathEgress) streamClosed(streamBytes uint64, now time.Time) {
+	e.prevBytes = e.usage(streamBytes, now)
+	e.baseline = 0
+}
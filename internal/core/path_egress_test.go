@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathEgress(t *testing.T) {
+	now := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := pathEgress{windowStart: now}
+
+	require.Equal(t, uint64(0), e.usage(0, now))
+
+	now = now.Add(10 * time.Minute)
+	require.Equal(t, uint64(1000), e.usage(1000, now))
+
+	// bytes of closed streams are kept until the end of the window
+	e.streamClosed(1500, now)
+	now = now.Add(10 * time.Minute)
+	require.Equal(t, uint64(1500), e.usage(0, now))
+	require.Equal(t, uint64(1700), e.usage(200, now))
+
+	// the window is reset
+	now = now.Add(40 * time.Minute)
+	require.Equal(t, uint64(0), e.usage(300, now))
+
+	now = now.Add(10 * time.Minute)
+	require.Equal(t, uint64(100), e.usage(400, now))
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// pathEnabledOverrides contains the enabled state of paths that has been set through the API,
+// that takes precedence over the 'enabled' setting of the configuration.
+// It is owned by Core, in order not to be lost when the configuration is reloaded
+// and the path manager is recreated.
+type pathEnabledOverrides struct {
+	mutex  sync.Mutex
+	values map[string]bool
+}
+
+func (o *pathEnabledOverrides) set(name string, enabled bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.values == nil {
+		o.values = make(map[string]bool)
+	}
+	o.values[name] = enabled
+}
+
+// enabled returns whether the path with the given name and configuration is enabled.
+func (o *pathEnabledOverrides) enabled(pathConf *conf.Path, name string) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if v, ok := o.values[name]; ok {
+		return v
+	}
+	return pathConf.Enabled
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+const (
+	pathMaxEvents = 100
+)
+
+// pathEvents is a ring buffer that contains the most recent events of a path.
+// It can be accessed concurrently.
+type pathEvents struct {
+	mutex sync.Mutex
+	items [pathMaxEvents]defs.APIPathEvent
+	start int
+	count int
+}
+
+func (e *pathEvents) add(level logger.Level, format string, args ...interface{}) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ev := defs.APIPathEvent{
+		Time:     time.Now(),
+		Severity: conf.LogLevel(level),
+		Message:  fmt.Sprintf(format, args...),
+	}
+
+	if e.count < pathMaxEvents {
+		e.items[(e.start+e.count)%pathMaxEvents] = ev
+		e.count++
+	} else {
+		e.items[e.start] = ev
+		e.start = (e.start + 1) % pathMaxEvents
+	}
+}
+
+// list returns events sorted from the oldest to the newest.
+func (e *pathEvents) list() []*defs.APIPathEvent {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ret := make([]*defs.APIPathEvent, e.count)
+	for i := 0; i < e.count; i++ {
+		ev := e.items[(e.start+i)%pathMaxEvents]
+		ret[i] = &ev
+	}
+
+	return ret
+}
+
+func describeSourceOrReader(d defs.APIPathSourceOrReader) string {
+	if d.ID == "" {
+		return d.Type
+	}
+	return d.Type + " " + d.ID
+}
+
+// pathEventsLogger is a logger.Writer that forwards messages to a path
+// and stores warnings and errors into path events.
+type pathEventsLogger struct {
+	pa *path
+}
+
+// Log implements logger.Writer.
+func (l *pathEventsLogger) Log(level logger.Level, format string, args ...interface{}) {
+	l.pa.Log(level, format, args...)
+
+	if level >= logger.Warn {
+		l.pa.AddEvent(level, format, args...)
+	}
+}
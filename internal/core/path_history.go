@@ -0,0 +1,80 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+const (
+	pathHistoryWindow         = 15 * time.Minute
+	pathHistorySampleInterval = 5 * time.Second
+	pathHistoryMaxSamples     = int(pathHistoryWindow/pathHistorySampleInterval) + 1
+)
+
+// pathHistorySamples is a ring buffer that contains the most recent samples of a metric.
+type pathHistorySamples struct {
+	items [pathHistoryMaxSamples]defs.APIPathHistorySample
+	start int
+	count int
+}
+
+func (s *pathHistorySamples) add(sample defs.APIPathHistorySample) {
+	if s.count < pathHistoryMaxSamples {
+		s.items[(s.start+s.count)%pathHistoryMaxSamples] = sample
+		s.count++
+	} else {
+		s.items[s.start] = sample
+		s.start = (s.start + 1) % pathHistoryMaxSamples
+	}
+}
+
+// pathHistory contains samples of the metrics of a path taken within pathHistoryWindow.
+// It can be accessed concurrently.
+type pathHistory struct {
+	mutex   sync.Mutex
+	metrics map[defs.APIPathHistoryMetric]*pathHistorySamples
+}
+
+func (h *pathHistory) add(metric defs.APIPathHistoryMetric, value float64, now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.metrics == nil {
+		h.metrics = make(map[defs.APIPathHistoryMetric]*pathHistorySamples)
+	}
+
+	s, ok := h.metrics[metric]
+	if !ok {
+		s = &pathHistorySamples{}
+		h.metrics[metric] = s
+	}
+
+	s.add(defs.APIPathHistorySample{
+		Time:  now,
+		Value: value,
+	})
+}
+
+// list returns samples that are not older than pathHistoryWindow, sorted from the oldest to the newest.
+func (h *pathHistory) list(metric defs.APIPathHistoryMetric, now time.Time) []*defs.APIPathHistorySample {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	ret := []*defs.APIPathHistorySample{}
+
+	s, ok := h.metrics[metric]
+	if !ok {
+		return ret
+	}
+
+	for i := 0; i < s.count; i++ {
+		sample := s.items[(s.start+i)%pathHistoryMaxSamples]
+		if now.Sub(sample.Time) <= pathHistoryWindow {
+			ret = append(ret, &sample)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+func TestPathHistory(t *testing.T) {
+	now := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	var h pathHistory
+
+	require.Equal(t, []*defs.APIPathHistorySample{}, h.list(defs.APIPathHistoryMetricBitrate, now))
+
+	for i := 0; i < 3; i++ {
+		h.add(defs.APIPathHistoryMetricBitrate, float64(i*1000), now)
+		now = now.Add(pathHistorySampleInterval)
+	}
+	h.add(defs.APIPathHistoryMetricRTT, 20, now)
+
+	require.Equal(t, []*defs.APIPathHistorySample{
+		{Time: now.Add(-3 * pathHistorySampleInterval), Value: 0},
+		{Time: now.Add(-2 * pathHistorySampleInterval), Value: 1000},
+		{Time: now.Add(-1 * pathHistorySampleInterval), Value: 2000},
+	}, h.list(defs.APIPathHistoryMetricBitrate, now))
+
+	require.Equal(t, []*defs.APIPathHistorySample{
+		{Time: now, Value: 20},
+	}, h.list(defs.APIPathHistoryMetricRTT, now))
+
+	// samples older than the window expire
+	now = now.Add(pathHistoryWindow - pathHistorySampleInterval)
+
+	require.Equal(t, []*defs.APIPathHistorySample{
+		{Time: now.Add(-pathHistoryWindow), Value: 2000},
+	}, h.list(defs.APIPathHistoryMetricBitrate, now))
+
+	now = now.Add(pathHistorySampleInterval)
+	require.Equal(t, []*defs.APIPathHistorySample{}, h.list(defs.APIPathHistoryMetricBitrate, now))
+}
+
+func TestPathHistoryMaxSamples(t *testing.T) {
+	now := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	var h pathHistory
+
+	for i := 0; i < pathHistoryMaxSamples+10; i++ {
+		h.add(defs.APIPathHistoryMetricBitrate, float64(i), now)
+		now = now.Add(time.Millisecond)
+	}
+
+	samples := h.list(defs.APIPathHistoryMetricBitrate, now)
+	require.Equal(t, pathHistoryMaxSamples, len(samples))
+	require.Equal(t, float64(10), samples[0].Value)
+	require.Equal(t, float64(pathHistoryMaxSamples+9), samples[len(samples)-1].Value)
+}
@@ -3,8 +3,11 @@ package core
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -14,10 +17,12 @@ import (
 	"github.com/bluenviron/mediamtx/internal/stream"
 )
 
+// fields must be kept in sync with configHotReloadablePathFields of the api package.
 func pathConfCanBeUpdated(oldPathConf *conf.Path, newPathConf *conf.Path) bool {
 	clone := oldPathConf.Clone()
 
 	clone.Record = newPathConf.Record
+	clone.RecordSchedule = newPathConf.RecordSchedule
 
 	clone.RPICameraBrightness = newPathConf.RPICameraBrightness
 	clone.RPICameraContrast = newPathConf.RPICameraContrast
@@ -57,28 +62,33 @@ type pathManager struct {
 	writeQueueSize    int
 	udpMaxPayloadSize int
 	pathConfs         map[string]*conf.Path
+	pathsEnabled      *pathEnabledOverrides
 	externalCmdPool   *externalcmd.Pool
 	parent            pathManagerParent
 
-	ctx         context.Context
-	ctxCancel   func()
-	wg          sync.WaitGroup
-	hlsManager  pathManagerHLSServer
-	paths       map[string]*path
-	pathsByConf map[string]map[*path]struct{}
+	ctx             context.Context
+	ctxCancel       func()
+	wg              sync.WaitGroup
+	hlsManager      pathManagerHLSServer
+	paths           map[string]*path
+	pathsByConf     map[string]map[*path]struct{}
+	publisherLimits map[string]*pathPublisherLimit
+	outputs         atomic.Pointer[pathOutputs]
 
 	// in
-	chReloadConf   chan map[string]*conf.Path
-	chSetHLSServer chan pathManagerHLSServer
-	chClosePath    chan *path
-	chPathReady    chan *path
-	chPathNotReady chan *path
-	chFindPathConf chan defs.PathFindPathConfReq
-	chDescribe     chan defs.PathDescribeReq
-	chAddReader    chan defs.PathAddReaderReq
-	chAddPublisher chan defs.PathAddPublisherReq
-	chAPIPathsList chan pathAPIPathsListReq
-	chAPIPathsGet  chan pathAPIPathsGetReq
+	chReloadConf         chan map[string]*conf.Path
+	chSetLogLevel        chan conf.LogLevel
+	chSetHLSServer       chan pathManagerHLSServer
+	chClosePath          chan *path
+	chPathReady          chan *path
+	chPathNotReady       chan *path
+	chFindPathConf       chan defs.PathFindPathConfReq
+	chDescribe           chan defs.PathDescribeReq
+	chAddReader          chan defs.PathAddReaderReq
+	chAddPublisher       chan defs.PathAddPublisherReq
+	chAPIPathsList       chan pathAPIPathsListReq
+	chAPIPathsGet        chan pathAPIPathsGetReq
+	chAPIPathsSetEnabled chan pathAPIPathsSetEnabledReq
 }
 
 func (pm *pathManager) initialize() {
@@ -88,7 +98,9 @@ func (pm *pathManager) initialize() {
 	pm.ctxCancel = ctxCancel
 	pm.paths = make(map[string]*path)
 	pm.pathsByConf = make(map[string]map[*path]struct{})
+	pm.publisherLimits = make(map[string]*pathPublisherLimit)
 	pm.chReloadConf = make(chan map[string]*conf.Path)
+	pm.chSetLogLevel = make(chan conf.LogLevel)
 	pm.chSetHLSServer = make(chan pathManagerHLSServer)
 	pm.chClosePath = make(chan *path)
 	pm.chPathReady = make(chan *path)
@@ -99,9 +111,10 @@ func (pm *pathManager) initialize() {
 	pm.chAddPublisher = make(chan defs.PathAddPublisherReq)
 	pm.chAPIPathsList = make(chan pathAPIPathsListReq)
 	pm.chAPIPathsGet = make(chan pathAPIPathsGetReq)
+	pm.chAPIPathsSetEnabled = make(chan pathAPIPathsSetEnabledReq)
 
 	for _, pathConf := range pm.pathConfs {
-		if pathConf.Regexp == nil {
+		if pathConf.Regexp == nil && pm.pathsEnabled.enabled(pathConf, pathConf.Name) {
 			pm.createPath(pathConf, pathConf.Name, nil)
 		}
 	}
@@ -132,6 +145,9 @@ outer:
 		case newPaths := <-pm.chReloadConf:
 			pm.doReloadConf(newPaths)
 
+		case level := <-pm.chSetLogLevel:
+			pm.doSetLogLevel(level)
+
 		case m := <-pm.chSetHLSServer:
 			pm.doSetHLSServer(m)
 
@@ -162,6 +178,9 @@ outer:
 		case req := <-pm.chAPIPathsGet:
 			pm.doAPIPathsGet(req)
 
+		case req := <-pm.chAPIPathsSetEnabled:
+			pm.doAPIPathsSetEnabled(req)
+
 		case <-pm.ctx.Done():
 			break outer
 		}
@@ -201,12 +220,40 @@ func (pm *pathManager) doReloadConf(newPaths map[string]*conf.Path) {
 
 	// add new paths
 	for pathConfName, pathConf := range pm.pathConfs {
-		if _, ok := pm.paths[pathConfName]; !ok && pathConf.Regexp == nil {
+		if _, ok := pm.paths[pathConfName]; !ok && pathConf.Regexp == nil &&
+			pm.pathsEnabled.enabled(pathConf, pathConfName) {
 			pm.createPath(pathConf, pathConfName, nil)
 		}
 	}
 }
 
+func (pm *pathManager) doSetLogLevel(level conf.LogLevel) {
+	pm.logLevel = level
+
+	// the log level is passed to the Raspberry Pi Camera when the source starts,
+	// therefore paths that use it must be recreated
+	var recreate []*path
+	for _, pa := range pm.paths {
+		if pathConfUsesRPICamera(pa.conf) {
+			recreate = append(recreate, pa)
+		}
+	}
+
+	for _, pa := range recreate {
+		pm.removePath(pa)
+		pa.close()
+		pa.wait() // avoid conflicts between sources
+
+		if pa.conf.Regexp == nil {
+			pm.createPath(pa.conf, pa.name, nil)
+		}
+	}
+}
+
+func pathConfUsesRPICamera(pathConf *conf.Path) bool {
+	return pathConf.Source == "rpiCamera" || slices.Contains(pathConf.SourceFailover, "rpiCamera")
+}
+
 func (pm *pathManager) doSetHLSServer(m pathManagerHLSServer) {
 	pm.hlsManager = m
 }
@@ -230,6 +277,14 @@ func (pm *pathManager) doPathNotReady(pa *path) {
 	}
 }
 
+// authenticate authenticates a request with the authentication method
+// that the path configuration assigns to the action.
+func (pm *pathManager) authenticate(pathConf *conf.Path, req *auth.Request) error {
+	method := pathConf.ActionAuthMethod(req.Action, pm.authManager.Method)
+	req.Method = &method
+	return pm.authManager.Authenticate(req)
+}
+
 func (pm *pathManager) doFindPathConf(req defs.PathFindPathConfReq) {
 	pathConf, _, err := conf.FindPathConf(pm.pathConfs, req.AccessRequest.Name)
 	if err != nil {
@@ -237,7 +292,7 @@ func (pm *pathManager) doFindPathConf(req defs.PathFindPathConfReq) {
 		return
 	}
 
-	err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+	err = pm.authenticate(pathConf, req.AccessRequest.ToAuthRequest())
 	if err != nil {
 		req.Res <- defs.PathFindPathConfRes{Err: err}
 		return
@@ -253,12 +308,17 @@ func (pm *pathManager) doDescribe(req defs.PathDescribeReq) {
 		return
 	}
 
-	err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+	err = pm.authenticate(pathConf, req.AccessRequest.ToAuthRequest())
 	if err != nil {
 		req.Res <- defs.PathDescribeRes{Err: err}
 		return
 	}
 
+	if !pm.pathsEnabled.enabled(pathConf, req.AccessRequest.Name) {
+		req.Res <- defs.PathDescribeRes{Err: defs.PathDisabledError{PathName: req.AccessRequest.Name}}
+		return
+	}
+
 	// create path if it doesn't exist
 	if _, ok := pm.paths[req.AccessRequest.Name]; !ok {
 		pm.createPath(pathConf, req.AccessRequest.Name, pathMatches)
@@ -274,14 +334,27 @@ func (pm *pathManager) doAddReader(req defs.PathAddReaderReq) {
 		return
 	}
 
+	if req.CheckConf != nil {
+		err = req.CheckConf(pathConf)
+		if err != nil {
+			req.Res <- defs.PathAddReaderRes{Err: err}
+			return
+		}
+	}
+
 	if !req.AccessRequest.SkipAuth {
-		err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+		err = pm.authenticate(pathConf, req.AccessRequest.ToAuthRequest())
 		if err != nil {
 			req.Res <- defs.PathAddReaderRes{Err: err}
 			return
 		}
 	}
 
+	if !pm.pathsEnabled.enabled(pathConf, req.AccessRequest.Name) {
+		req.Res <- defs.PathAddReaderRes{Err: defs.PathDisabledError{PathName: req.AccessRequest.Name}}
+		return
+	}
+
 	// create path if it doesn't exist
 	if _, ok := pm.paths[req.AccessRequest.Name]; !ok {
 		pm.createPath(pathConf, req.AccessRequest.Name, pathMatches)
@@ -297,12 +370,39 @@ func (pm *pathManager) doAddPublisher(req defs.PathAddPublisherReq) {
 		return
 	}
 
+	if req.NoDynamicPaths && pathConf.Regexp != nil {
+		req.Res <- defs.PathAddPublisherRes{
+			Err: fmt.Errorf("path '%s' is not listed by name and dynamic creation of paths is disabled",
+				req.AccessRequest.Name),
+		}
+		return
+	}
+
+	if req.CheckConf != nil {
+		err = req.CheckConf(pathConf)
+		if err != nil {
+			req.Res <- defs.PathAddPublisherRes{Err: err}
+			return
+		}
+	}
+
+	user := req.AccessRequest.User
+
 	if !req.AccessRequest.SkipAuth {
-		err = pm.authManager.Authenticate(req.AccessRequest.ToAuthRequest())
+		authReq := req.AccessRequest.ToAuthRequest()
+		err = pm.authenticate(pathConf, authReq)
 		if err != nil {
 			req.Res <- defs.PathAddPublisherRes{Err: err}
 			return
 		}
+
+		// credentials may have been extracted from RTSP or HTTP requests
+		user = authReq.User
+	}
+
+	if !pm.pathsEnabled.enabled(pathConf, req.AccessRequest.Name) {
+		req.Res <- defs.PathAddPublisherRes{Err: defs.PathDisabledError{PathName: req.AccessRequest.Name}}
+		return
 	}
 
 	// create path if it doesn't exist
@@ -310,7 +410,10 @@ func (pm *pathManager) doAddPublisher(req defs.PathAddPublisherReq) {
 		pm.createPath(pathConf, req.AccessRequest.Name, pathMatches)
 	}
 
-	req.Res <- defs.PathAddPublisherRes{Path: pm.paths[req.AccessRequest.Name]}
+	req.Res <- defs.PathAddPublisherRes{
+		Path: pm.paths[req.AccessRequest.Name],
+		User: user,
+	}
 }
 
 func (pm *pathManager) doAPIPathsList(req pathAPIPathsListReq) {
@@ -333,6 +436,37 @@ func (pm *pathManager) doAPIPathsGet(req pathAPIPathsGetReq) {
 	req.res <- pathAPIPathsGetRes{path: path}
 }
 
+func (pm *pathManager) doAPIPathsSetEnabled(req pathAPIPathsSetEnabledReq) {
+	pathConf, _, err := conf.FindPathConf(pm.pathConfs, req.name)
+	if err != nil {
+		req.res <- conf.ErrPathNotFound
+		return
+	}
+
+	pm.pathsEnabled.set(req.name, req.enabled)
+
+	pa, ok := pm.paths[req.name]
+
+	switch {
+	case !req.enabled && ok:
+		// publishers, readers and the static source are closed with the path
+		pm.removePath(pa)
+		pa.close()
+		pa.wait() // avoid conflicts between sources
+
+	case req.enabled && !ok && pathConf.Regexp == nil:
+		pm.createPath(pathConf, req.name, nil)
+	}
+
+	if req.enabled {
+		pm.Log(logger.Info, "path '%s' has been enabled", req.name)
+	} else {
+		pm.Log(logger.Info, "path '%s' has been disabled", req.name)
+	}
+
+	req.res <- nil
+}
+
 func (pm *pathManager) createPath(
 	pathConf *conf.Path,
 	name string,
@@ -352,6 +486,7 @@ func (pm *pathManager) createPath(
 		wg:                &pm.wg,
 		externalCmdPool:   pm.externalCmdPool,
 		parent:            pm,
+		publisherLimit:    pm.publisherLimit(name),
 	}
 	pa.initialize()
 
@@ -369,6 +504,24 @@ func (pm *pathManager) removePath(pa *path) {
 		delete(pm.pathsByConf, pa.conf.Name)
 	}
 	delete(pm.paths, pa.name)
+
+	now := time.Now()
+	for name, l := range pm.publisherLimits {
+		if _, ok := pm.paths[name]; !ok && l.idle(now) {
+			delete(pm.publisherLimits, name)
+		}
+	}
+}
+
+// publisherLimit returns the limit of publisher attempts of a path,
+// that is kept after the path is closed, as long as it affects future attempts.
+func (pm *pathManager) publisherLimit(name string) *pathPublisherLimit {
+	l, ok := pm.publisherLimits[name]
+	if !ok {
+		l = &pathPublisherLimit{}
+		pm.publisherLimits[name] = l
+	}
+	return l
 }
 
 // ReloadPathConfs is called by core.
@@ -379,6 +532,14 @@ func (pm *pathManager) ReloadPathConfs(pathConfs map[string]*conf.Path) {
 	}
 }
 
+// setLogLevel is called by core.
+func (pm *pathManager) setLogLevel(level conf.LogLevel) {
+	select {
+	case pm.chSetLogLevel <- level:
+	case <-pm.ctx.Done():
+	}
+}
+
 // pathReady is called by path.
 func (pm *pathManager) pathReady(pa *path) {
 	select {
@@ -452,6 +613,8 @@ func (pm *pathManager) AddPublisher(req defs.PathAddPublisherReq) (defs.Path, er
 			return nil, res.Err
 		}
 
+		req.AccessRequest.User = res.User
+
 		return res.Path.(*path).addPublisher(req)
 
 	case <-pm.ctx.Done():
@@ -476,6 +639,19 @@ func (pm *pathManager) AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.
 	}
 }
 
+// setOutputs is called by core.
+func (pm *pathManager) setOutputs(outputs pathOutputs) {
+	pm.outputs.Store(&outputs)
+}
+
+// enabledOutputs is called by path.
+func (pm *pathManager) enabledOutputs() pathOutputs {
+	if outputs := pm.outputs.Load(); outputs != nil {
+		return *outputs
+	}
+	return pathOutputs{}
+}
+
 // setHLSServer is called by hlsManager.
 func (pm *pathManager) setHLSServer(s pathManagerHLSServer) {
 	select {
@@ -537,3 +713,190 @@ func (pm *pathManager) APIPathsGet(name string) (*defs.APIPath, error) {
 		return nil, fmt.Errorf("terminated")
 	}
 }
+
+// APIPathsConf is called by api.
+func (pm *pathManager) APIPathsConf(name string) (*conf.Path, error) {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		return res.path.APIPathsConf()
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsEnable is called by api.
+func (pm *pathManager) APIPathsEnable(name string) error {
+	return pm.apiPathsSetEnabled(name, true)
+}
+
+// APIPathsDisable is called by api.
+func (pm *pathManager) APIPathsDisable(name string) error {
+	return pm.apiPathsSetEnabled(name, false)
+}
+
+func (pm *pathManager) apiPathsSetEnabled(name string, enabled bool) error {
+	req := pathAPIPathsSetEnabledReq{
+		name:    name,
+		enabled: enabled,
+		res:     make(chan error),
+	}
+
+	select {
+	case pm.chAPIPathsSetEnabled <- req:
+		return <-req.res
+
+	case <-pm.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsRecordStart is called by api.
+func (pm *pathManager) APIPathsRecordStart(name string) error {
+	return pm.apiPathsRecord(name, true)
+}
+
+// APIPathsRecordStop is called by api.
+func (pm *pathManager) APIPathsRecordStop(name string) error {
+	return pm.apiPathsRecord(name, false)
+}
+
+func (pm *pathManager) apiPathsRecord(name string, enable bool) error {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return res.err
+		}
+
+		return res.path.APIPathsRecord(enable)
+
+	case <-pm.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsRecordRotate is called by api.
+func (pm *pathManager) APIPathsRecordRotate(name string) error {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return res.err
+		}
+
+		return res.path.APIPathsRecordRotate()
+
+	case <-pm.ctx.Done():
+		return fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsHistory is called by api.
+func (pm *pathManager) APIPathsHistory(name string, metric defs.APIPathHistoryMetric) (*defs.APIPathHistory, error) {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		return &defs.APIPathHistory{
+			Metric: metric,
+			Items:  res.path.history.list(metric, time.Now()),
+		}, nil
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsReaderHealth is called by api.
+func (pm *pathManager) APIPathsReaderHealth(name string) (*defs.APIPathReaderHealth, error) {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		return res.path.APIPathsReaderHealth()
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsCounters is called by api.
+func (pm *pathManager) APIPathsCounters(name string, reset bool) (*defs.APIPathCounters, error) {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		return res.path.APIPathsCounters(reset)
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIPathsEvents is called by api.
+func (pm *pathManager) APIPathsEvents(name string) (*defs.APIPathEventList, error) {
+	req := pathAPIPathsGetReq{
+		name: name,
+		res:  make(chan pathAPIPathsGetRes),
+	}
+
+	select {
+	case pm.chAPIPathsGet <- req:
+		res := <-req.res
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		return &defs.APIPathEventList{
+			Items: res.path.events.list(),
+		}, nil
+
+	case <-pm.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// pathOutputs are the outputs that are enabled in the global configuration.
+type pathOutputs struct {
+	hls    bool
+	webrtc bool
+	rtmp   bool
+	srt    bool
+}
+
+func pathOutputsFromConf(c *conf.Conf) pathOutputs {
+	return pathOutputs{
+		hls:    c.HLS,
+		webrtc: c.WebRTC,
+		rtmp:   c.RTMP,
+		srt:    c.SRT,
+	}
+}
+
+// pathOutput is an output that delivers the stream of a path.
+type pathOutput struct {
+	name     string
+	supports func(format.Format) bool
+}
+
+func isMPEGTSFormat(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.H265, *format.H264, *format.MPEG4Video, *format.MPEG1Video,
+		*format.Opus, *format.MPEG4Audio, *format.MPEG1Audio, *format.AC3:
+		return true
+	}
+	return false
+}
+
+func isFMP4Format(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.AV1, *format.VP9, *format.VP8, *format.H265, *format.H264,
+		*format.MPEG4Video, *format.MPEG1Video, *format.MJPEG,
+		*format.Opus, *format.MPEG4Audio, *format.MPEG1Audio, *format.AC3,
+		*format.G722, *format.G711, *format.LPCM:
+		return true
+	}
+	return false
+}
+
+func isHLSFormat(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.AV1, *format.VP9, *format.H265, *format.H264,
+		*format.Opus, *format.MPEG4Audio:
+		return true
+	}
+	return false
+}
+
+func isWebRTCFormat(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.AV1, *format.VP9, *format.VP8, *format.H265, *format.H264,
+		*format.Opus, *format.G722, *format.G711, *format.LPCM:
+		return true
+	}
+	return false
+}
+
+func isRTMPFormat(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.H264, *format.MPEG4Audio, *format.MPEG1Audio:
+		return true
+	}
+	return false
+}
+
+// enabledPathOutputs returns the outputs that can deliver the stream of a path.
+// Outputs that support every codec (i.e. RTSP) are not returned.
+func enabledPathOutputs(pathConf *conf.Path, outputs pathOutputs) []pathOutput {
+	var ret []pathOutput
+
+	if pathConf.Record {
+		if pathConf.RecordFormat == conf.RecordFormatMPEGTS {
+			ret = append(ret, pathOutput{"recording", isMPEGTSFormat})
+		} else {
+			ret = append(ret, pathOutput{"recording", isFMP4Format})
+		}
+	}
+
+	if pathConf.SRTRelay != "" {
+		ret = append(ret, pathOutput{"SRT relay", isMPEGTSFormat})
+	}
+
+	if outputs.hls {
+		ret = append(ret, pathOutput{"HLS", isHLSFormat})
+	}
+
+	if outputs.webrtc {
+		ret = append(ret, pathOutput{"WebRTC", isWebRTCFormat})
+	}
+
+	if outputs.rtmp {
+		ret = append(ret, pathOutput{"RTMP", isRTMPFormat})
+	}
+
+	if outputs.srt {
+		ret = append(ret, pathOutput{"SRT", isMPEGTSFormat})
+	}
+
+	return ret
+}
+
+// pathCodecWarnings returns a warning for each output that is not able
+// to deliver one or more tracks of a stream.
+func pathCodecWarnings(outputs []pathOutput, medias []*description.Media) []string {
+	var ret []string
+
+	for _, out := range outputs {
+		var unsupported []string
+		total := 0
+
+		for _, medi := range medias {
+			for _, forma := range medi.Formats {
+				total++
+				if !out.supports(forma) {
+					unsupported = append(unsupported, forma.Codec())
+				}
+			}
+		}
+
+		switch {
+		case len(unsupported) == 0:
+		case len(unsupported) == total:
+			ret = append(ret, fmt.Sprintf("%s does not support any codec of the stream (%s)",
+				out.name, strings.Join(unsupported, ", ")))
+		default:
+			ret = append(ret, fmt.Sprintf("%s does not support codecs %s, that will be skipped",
+				out.name, strings.Join(unsupported, ", ")))
+		}
+	}
+
+	return ret
+}
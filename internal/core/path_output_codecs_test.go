@@ -0,0 +1,92 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/test"
+)
+
+func TestPathCodecWarnings(t *testing.T) {
+	g711Media := &description.Media{
+		Type: description.MediaTypeAudio,
+		Formats: []format.Format{&format.G711{
+			PayloadTyp:   8,
+			MULaw:        false,
+			SampleRate:   8000,
+			ChannelCount: 1,
+		}},
+	}
+
+	mpeg4VideoMedia := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{&format.MPEG4Video{PayloadTyp: 96}},
+	}
+
+	for _, ca := range []struct {
+		name     string
+		pathConf conf.Path
+		outputs  pathOutputs
+		medias   []*description.Media
+		warnings []string
+	}{
+		{
+			"compatible",
+			conf.Path{},
+			pathOutputs{hls: true, rtmp: true, srt: true},
+			[]*description.Media{test.MediaH264, test.MediaMPEG4Audio},
+			nil,
+		},
+		{
+			"hls with g711",
+			conf.Path{},
+			pathOutputs{hls: true},
+			[]*description.Media{test.MediaH264, g711Media},
+			[]string{"HLS does not support codecs G711, that will be skipped"},
+		},
+		{
+			"hls with g711 only",
+			conf.Path{},
+			pathOutputs{hls: true, webrtc: true},
+			[]*description.Media{g711Media},
+			[]string{"HLS does not support any codec of the stream (G711)"},
+		},
+		{
+			"webrtc with mpeg-4 video",
+			conf.Path{},
+			pathOutputs{webrtc: true},
+			[]*description.Media{mpeg4VideoMedia, g711Media},
+			[]string{"WebRTC does not support codecs MPEG-4 Video, that will be skipped"},
+		},
+		{
+			"disabled outputs",
+			conf.Path{},
+			pathOutputs{},
+			[]*description.Media{mpeg4VideoMedia, g711Media},
+			nil,
+		},
+		{
+			"mpeg-ts recording with g711",
+			conf.Path{Record: true, RecordFormat: conf.RecordFormatMPEGTS},
+			pathOutputs{},
+			[]*description.Media{test.MediaH264, g711Media},
+			[]string{"recording does not support codecs G711, that will be skipped"},
+		},
+		{
+			"fmp4 recording with g711",
+			conf.Path{Record: true, RecordFormat: conf.RecordFormatFMP4},
+			pathOutputs{},
+			[]*description.Media{test.MediaH264, g711Media},
+			nil,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			outputs := enabledPathOutputs(&ca.pathConf, ca.outputs)
+			require.Equal(t, ca.warnings, pathCodecWarnings(outputs, ca.medias))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+const (
+	pathPublisherMaxBackoff = 5 * time.Minute
+)
+
+// pathPublisherLimit limits the publisher attempts of a path within a window.
+// When the limit is exceeded, attempts are rejected for a backoff period
+// that doubles every time the limit is exceeded again, and is reset
+// when a window passes without admitted attempts.
+// It is owned by pathManager, since paths are closed when publishers leave.
+type pathPublisherLimit struct {
+	mutex        sync.Mutex
+	window       time.Duration
+	attempts     []time.Time
+	backoff      time.Duration
+	backoffUntil time.Time
+	rejected     uint64
+}
+
+func (l *pathPublisherLimit) removeExpired(now time.Time) {
+	i := 0
+	for i < len(l.attempts) && now.Sub(l.attempts[i]) >= l.window {
+		i++
+	}
+	l.attempts = l.attempts[i:]
+}
+
+// allow returns whether an attempt can be admitted.
+// When it can't, the time after which attempts are admitted again is returned too.
+func (l *pathPublisherLimit) allow(
+	now time.Time,
+	maxAttempts int,
+	window time.Duration,
+	minBackoff time.Duration,
+) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.window = window
+
+	if now.Before(l.backoffUntil) {
+		l.rejected++
+		return false, l.backoffUntil.Sub(now)
+	}
+
+	l.removeExpired(now)
+
+	if len(l.attempts) == 0 {
+		l.backoff = 0
+	}
+
+	if len(l.attempts) >= maxAttempts {
+		if l.backoff == 0 {
+			l.backoff = minBackoff
+		} else {
+			l.backoff = min(l.backoff*2, max(pathPublisherMaxBackoff, minBackoff))
+		}
+		l.backoffUntil = now.Add(l.backoff)
+		l.rejected++
+		return false, l.backoff
+	}
+
+	l.attempts = append(l.attempts, now)
+	return true, 0
+}
+
+// idle returns whether the limit has no effect on future attempts.
+func (l *pathPublisherLimit) idle(now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.removeExpired(now)
+	return len(l.attempts) == 0 && !now.Before(l.backoffUntil)
+}
+
+func (l *pathPublisherLimit) apiItem(now time.Time) *defs.APIPathPublisherLimit {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.removeExpired(now)
+
+	item := &defs.APIPathPublisherLimit{
+		Attempts: len(l.attempts),
+		Rejected: l.rejected,
+	}
+
+	if now.Before(l.backoffUntil) {
+		v := conf.StringDuration(l.backoffUntil.Sub(now))
+		item.BackoffRemaining = &v
+	}
+
+	return item
+}
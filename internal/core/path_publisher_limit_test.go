@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+func TestPathPublisherLimit(t *testing.T) {
+	now := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := &pathPublisherLimit{}
+
+	allow := func() (bool, time.Duration) {
+		return l.allow(now, 3, time.Minute, 10*time.Second)
+	}
+
+	for i := 0; i < 3; i++ {
+		ok, _ := allow()
+		require.True(t, ok)
+		now = now.Add(time.Second)
+	}
+
+	ok, retryAfter := allow()
+	require.False(t, ok)
+	require.Equal(t, 10*time.Second, retryAfter)
+
+	now = now.Add(4 * time.Second)
+	ok, retryAfter = allow()
+	require.False(t, ok)
+	require.Equal(t, 6*time.Second, retryAfter)
+
+	backoffRemaining := conf.StringDuration(6 * time.Second)
+	require.Equal(t, &defs.APIPathPublisherLimit{
+		Attempts:         3,
+		Rejected:         2,
+		BackoffRemaining: &backoffRemaining,
+	}, l.apiItem(now))
+
+	// the limit is exceeded again after the backoff, that is doubled
+	now = now.Add(6 * time.Second)
+	ok, retryAfter = allow()
+	require.False(t, ok)
+	require.Equal(t, 20*time.Second, retryAfter)
+	require.False(t, l.idle(now))
+
+	// attempts are admitted again when the window has passed
+	now = now.Add(time.Minute)
+	require.True(t, l.idle(now))
+
+	ok, _ = allow()
+	require.True(t, ok)
+
+	for i := 0; i < 2; i++ {
+		ok, _ = allow()
+		require.True(t, ok)
+	}
+
+	// the backoff has been reset
+	ok, retryAfter = allow()
+	require.False(t, ok)
+	require.Equal(t, 10*time.Second, retryAfter)
+}
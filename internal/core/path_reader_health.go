@@ -0,0 +1,39 @@
+package core
+
+import (
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+// aggregateReaderHealth combines the link statistics of the readers of a path.
+// readerCount is the total number of readers, including the ones that are not able to report statistics.
+func aggregateReaderHealth(readerCount int, stats []defs.ReaderLinkStats) *defs.APIPathReaderHealth {
+	ret := &defs.APIPathReaderHealth{
+		Readers:          readerCount,
+		ReportingReaders: len(stats),
+	}
+
+	if len(stats) == 0 {
+		return ret
+	}
+
+	var worstRTT time.Duration
+	var totalRTT time.Duration
+
+	for _, s := range stats {
+		worstRTT = max(worstRTT, s.RTT)
+		totalRTT += s.RTT
+		ret.PacketsSent += s.PacketsSent
+		ret.PacketsLost += s.PacketsLost
+		ret.PacketsRetransmitted += s.PacketsRetransmitted
+	}
+
+	worst := float64(worstRTT) / float64(time.Millisecond)
+	ret.WorstMsRTT = &worst
+
+	average := float64(totalRTT) / float64(len(stats)) / float64(time.Millisecond)
+	ret.AverageMsRTT = &average
+
+	return ret
+}
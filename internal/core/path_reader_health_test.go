@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+func TestAggregateReaderHealth(t *testing.T) {
+	require.Equal(t, &defs.APIPathReaderHealth{
+		Readers: 1,
+	}, aggregateReaderHealth(1, nil))
+
+	worst := float64(30)
+	average := float64(20)
+
+	require.Equal(t, &defs.APIPathReaderHealth{
+		Readers:              3,
+		ReportingReaders:     2,
+		WorstMsRTT:           &worst,
+		AverageMsRTT:         &average,
+		PacketsSent:          300,
+		PacketsLost:          7,
+		PacketsRetransmitted: 5,
+	}, aggregateReaderHealth(3, []defs.ReaderLinkStats{
+		{
+			RTT:                  10 * time.Millisecond,
+			PacketsSent:          100,
+			PacketsLost:          3,
+			PacketsRetransmitted: 2,
+		},
+		{
+			RTT:                  30 * time.Millisecond,
+			PacketsSent:          200,
+			PacketsLost:          4,
+			PacketsRetransmitted: 3,
+		},
+	}))
+}
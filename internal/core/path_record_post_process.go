@@ -0,0 +1,36 @@
+package core
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
+)
+
+// recordPostProcessValues returns the values of the placeholders of recordPostProcessCommand.
+func recordPostProcessValues(
+	pathConf *conf.Path,
+	pathName string,
+	segmentPath string,
+	segmentDuration time.Duration,
+) map[byte]string {
+	pa := recordstore.Path{Location: pathConf.RecordLocation()}
+	ok := pa.Decode(recordstore.PathAddExtension(
+		pathConf.RecordPath,
+		pathConf.RecordFormat,
+		pathConf.RecordCompression,
+	), segmentPath)
+	if !ok {
+		pa.Start = time.Now().Add(-segmentDuration)
+	}
+
+	return map[byte]string{
+		'f': segmentPath,
+		'd': filepath.Dir(segmentPath),
+		'p': pathName,
+		's': pa.Start.Format(time.RFC3339Nano),
+		'l': strconv.FormatFloat(segmentDuration.Seconds(), 'f', -1, 64),
+	}
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"net"
@@ -16,13 +17,17 @@ import (
 	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/gortsplib/v4/pkg/headers"
 	"github.com/bluenviron/gortsplib/v4/pkg/sdp"
+	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
 	srt "github.com/datarhei/gosrt"
 	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 
+	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
 	"github.com/bluenviron/mediamtx/internal/protocols/whip"
 	"github.com/bluenviron/mediamtx/internal/test"
@@ -451,6 +456,42 @@ func TestPathRunOnRead(t *testing.T) {
 	}
 }
 
+func TestPathSRTStreamIDMetadata(t *testing.T) {
+	onRead := filepath.Join(os.TempDir(), "on_read")
+	defer os.Remove(onRead)
+
+	func() {
+		p, ok := newInstance(fmt.Sprintf(
+			"paths:\n"+
+				"  test:\n"+
+				"    runOnRead: sh -c 'echo \"$MTX_SRT_STREAMID_LOC $MTX_SRT_STREAMID_TOKEN\" > %s'\n",
+			onRead))
+		require.Equal(t, true, ok)
+		defer p.Close()
+
+		source := gortsplib.Client{}
+
+		err := source.StartRecording(
+			"rtsp://localhost:8554/test",
+			&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+		require.NoError(t, err)
+		defer source.Close()
+
+		conf := srt.DefaultConfig()
+		conf.StreamId = "#!::m=request,r=test,loc=roof,token=secret"
+
+		reader, err := srt.Dial("srt", "localhost:8890", conf)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		time.Sleep(500 * time.Millisecond)
+	}()
+
+	byts, err := os.ReadFile(onRead)
+	require.NoError(t, err)
+	require.Equal(t, "roof REDACTED\n", string(byts))
+}
+
 func TestPathRunOnRecordSegment(t *testing.T) {
 	onRecordSegmentCreate := filepath.Join(os.TempDir(), "on_record_segment_create")
 	defer os.Remove(onRecordSegmentCreate)
@@ -513,6 +554,118 @@ func TestPathRunOnRecordSegment(t *testing.T) {
 	require.Equal(t, "3", parts[1])
 }
 
+func TestPathRecordPostProcess(t *testing.T) {
+	out := filepath.Join(os.TempDir(), "record_post_process")
+	defer os.Remove(out)
+
+	// arguments are written by a script, since variables of the command are replaced by the server.
+	script := filepath.Join(os.TempDir(), "record_post_process.sh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > "+out+"\n"), 0o755)
+	require.NoError(t, err)
+	defer os.Remove(script)
+
+	recordDir, err := os.MkdirTemp("", "rtsp-path-record")
+	require.NoError(t, err)
+	defer os.RemoveAll(recordDir)
+
+	func() {
+		p, ok := newInstance("record: yes\n" +
+			"recordPath: " + filepath.Join(recordDir, "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+			"paths:\n" +
+			"  test:\n" +
+			"    recordPostProcessCommand: " + script + " %f %d %p %s %l 100%%\n")
+		require.Equal(t, true, ok)
+		defer p.Close()
+
+		media0 := test.UniqueMediaH264()
+
+		source := gortsplib.Client{}
+
+		err = source.StartRecording(
+			"rtsp://localhost:8554/test",
+			&description.Session{Medias: []*description.Media{media0}})
+		require.NoError(t, err)
+		defer source.Close()
+
+		for i := 0; i < 4; i++ {
+			err = source.WritePacketRTP(media0, &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					Marker:         true,
+					PayloadType:    96,
+					SequenceNumber: 1123 + uint16(i),
+					Timestamp:      45343 + 90000*uint32(i),
+					SSRC:           563423,
+				},
+				Payload: []byte{5},
+			})
+			require.NoError(t, err)
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}()
+
+	byts, err := os.ReadFile(out)
+	require.NoError(t, err)
+	args := strings.Split(string(byts[:len(byts)-1]), "\n")
+	require.Len(t, args, 6)
+
+	require.Equal(t, true, strings.HasPrefix(args[0], filepath.Join(recordDir, "test")+"/"))
+	require.Equal(t, true, strings.HasSuffix(args[0], ".mp4"))
+	require.Equal(t, filepath.Dir(args[0]), args[1])
+	require.Equal(t, "test", args[2])
+
+	start, err := time.Parse(time.RFC3339Nano, args[3])
+	require.NoError(t, err)
+	require.Equal(t, start.Format("2006-01-02_15-04-05")+fmt.Sprintf("-%06d.mp4", start.Nanosecond()/1000),
+		filepath.Base(args[0]))
+
+	require.Equal(t, "3", args[4])
+	require.Equal(t, "100%", args[5])
+}
+
+func TestPathStrictCodecValidation(t *testing.T) {
+	for _, ca := range []string{
+		"valid",
+		"invalid sps",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			p, ok := newInstance("paths:\n" +
+				"  all_others:\n" +
+				"    strictCodecValidation: yes\n")
+			require.Equal(t, true, ok)
+			defer p.Close()
+
+			forma := &format.H264{
+				PayloadTyp:        96,
+				SPS:               test.FormatH264.SPS,
+				PPS:               []byte{0x68, 0xce, 0x3c, 0x80},
+				PacketizationMode: 1,
+			}
+			if ca == "invalid sps" {
+				// SPS that can be decoded but contains an invalid profile
+				forma.SPS = append([]byte{0x67, 0x01}, test.FormatH264.SPS[2:]...)
+			}
+
+			source := gortsplib.Client{}
+
+			err := source.StartRecording(
+				"rtsp://localhost:8554/mystream",
+				&description.Session{Medias: []*description.Media{{
+					Type:    description.MediaTypeVideo,
+					Formats: []format.Format{forma},
+				}}})
+
+			if ca == "valid" {
+				require.NoError(t, err)
+				defer source.Close()
+			} else {
+				require.EqualError(t, err, "bad status code: 400 (Bad Request)")
+			}
+		})
+	}
+}
+
 func TestPathMaxReaders(t *testing.T) {
 	p, ok := newInstance("paths:\n" +
 		"  all_others:\n" +
@@ -553,6 +706,79 @@ func TestPathMaxReaders(t *testing.T) {
 	}
 }
 
+func TestPathMaxEgress(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  all_others:\n" +
+		"    maxEgressBytesPerHour: 1000B\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	media0 := test.UniqueMediaH264()
+
+	source := gortsplib.Client{}
+
+	err := source.StartRecording(
+		"rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{media0}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/mystream")
+	require.NoError(t, err)
+
+	newReader := func() (*gortsplib.Client, error) {
+		reader := &gortsplib.Client{}
+
+		err2 := reader.Start(u.Scheme, u.Host)
+		require.NoError(t, err2)
+
+		desc, _, err2 := reader.Describe(u)
+		require.NoError(t, err2)
+
+		err2 = reader.SetupAll(desc.BaseURL, desc.Medias)
+		if err2 != nil {
+			reader.Close()
+			return nil, err2
+		}
+
+		return reader, nil
+	}
+
+	reader1, err := newReader()
+	require.NoError(t, err)
+	defer reader1.Close()
+
+	_, err = reader1.Play(nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		err = source.WritePacketRTP(media0, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: uint16(i),
+			},
+			Payload: append([]byte{5}, make([]byte, 200)...),
+		})
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		var out defs.APIPath
+		httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mystream", nil, &out)
+		return out.BytesSent >= 1000
+	}, 5*time.Second, 100*time.Millisecond)
+
+	_, err = newReader()
+	require.Error(t, err)
+}
+
 func TestPathRecord(t *testing.T) {
 	dir, err := os.MkdirTemp("", "rtsp-path-record")
 	require.NoError(t, err)
@@ -594,7 +820,7 @@ func TestPathRecord(t *testing.T) {
 
 	time.Sleep(500 * time.Millisecond)
 
-	files, err := os.ReadDir(filepath.Join(dir, "mystream"))
+	files, err := readSegmentFiles(filepath.Join(dir, "mystream"))
 	require.NoError(t, err)
 	require.Equal(t, 1, len(files))
 
@@ -631,120 +857,806 @@ func TestPathRecord(t *testing.T) {
 
 	time.Sleep(500 * time.Millisecond)
 
-	files, err = os.ReadDir(filepath.Join(dir, "mystream"))
+	files, err = readSegmentFiles(filepath.Join(dir, "mystream"))
 	require.NoError(t, err)
 	require.Equal(t, 2, len(files))
 }
 
-func TestPathFallback(t *testing.T) {
-	for _, ca := range []string{
-		"absolute",
-		"relative",
-		"source",
-	} {
-		t.Run(ca, func(t *testing.T) {
-			var conf string
+// readSegmentFiles returns the files of a recording directory,
+// except markers of segments that are being written.
+func readSegmentFiles(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
 
-			switch ca {
-			case "absolute":
-				conf = "paths:\n" +
-					"  path1:\n" +
-					"    fallback: rtsp://localhost:8554/path2\n" +
-					"  path2:\n"
+	var files []os.DirEntry
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".writing") {
+			files = append(files, e)
+		}
+	}
 
-			case "relative":
-				conf = "paths:\n" +
-					"  path1:\n" +
-					"    fallback: /path2\n" +
-					"  path2:\n"
+	return files, nil
+}
 
-			case "source":
-				conf = "paths:\n" +
-					"  path1:\n" +
-					"    fallback: /path2\n" +
-					"    source: rtsp://localhost:3333/nonexistent\n" +
-					"  path2:\n"
-			}
+func TestPathRecordTrigger(t *testing.T) {
+	dir, err := os.MkdirTemp("", "rtsp-path-record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
 
-			p1, ok := newInstance(conf)
-			require.Equal(t, true, ok)
-			defer p1.Close()
+	p, ok := newInstance("api: yes\n" +
+		"recordPath: " + filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+		"paths:\n" +
+		"  forced:\n" +
+		"    record: yes\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
 
-			source := gortsplib.Client{}
-			err := source.StartRecording("rtsp://localhost:8554/path2",
-				&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
-			require.NoError(t, err)
-			defer source.Close()
+	media0 := test.UniqueMediaH264()
 
-			u, err := base.ParseURL("rtsp://localhost:8554/path1")
-			require.NoError(t, err)
+	source := gortsplib.Client{}
 
-			dest := gortsplib.Client{}
-			err = dest.Start(u.Scheme, u.Host)
-			require.NoError(t, err)
-			defer dest.Close()
+	err = source.StartRecording(
+		"rtsp://localhost:8554/mystream",
+		&description.Session{Medias: []*description.Media{media0}})
+	require.NoError(t, err)
+	defer source.Close()
 
-			desc, _, err := dest.Describe(u)
+	writePackets := func(start int) {
+		for i := start; i < start+4; i++ {
+			err = source.WritePacketRTP(media0, &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					Marker:         true,
+					PayloadType:    96,
+					SequenceNumber: 1123 + uint16(i),
+					Timestamp:      45343 + 90000*uint32(i),
+					SSRC:           563423,
+				},
+				Payload: []byte{5},
+			})
 			require.NoError(t, err)
-			require.Equal(t, 1, len(desc.Medias))
-		})
+		}
 	}
-}
 
-func TestPathResolveSource(t *testing.T) {
-	var stream *gortsplib.ServerStream
+	writePackets(0)
 
-	s := gortsplib.Server{
-		Handler: &testServer{
-			onDescribe: func(ctx *gortsplib.ServerHandlerOnDescribeCtx,
-			) (*base.Response, *gortsplib.ServerStream, error) {
-				require.Equal(t, "key=val", ctx.Query)
-				require.Equal(t, "/a", ctx.Path)
-				return &base.Response{
-					StatusCode: base.StatusOK,
-				}, stream, nil
-			},
-			onSetup: func(_ *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
-				return &base.Response{
-					StatusCode: base.StatusOK,
-				}, stream, nil
-			},
-			onPlay: func(_ *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
-				return &base.Response{
-					StatusCode: base.StatusOK,
-				}, nil
-			},
-		},
-		RTSPAddress: "127.0.0.1:8555",
-	}
+	time.Sleep(500 * time.Millisecond)
 
-	err := s.Start()
-	require.NoError(t, err)
-	defer s.Close()
+	_, err = os.Stat(filepath.Join(dir, "mystream"))
+	require.True(t, os.IsNotExist(err))
 
-	stream = gortsplib.NewServerStream(&s, &description.Session{Medias: []*description.Media{test.MediaH264}})
-	defer stream.Close()
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
 
-	p, ok := newInstance(
-		"paths:\n" +
-			"  '~^test_(.+)$':\n" +
-			"    source: rtsp://127.0.0.1:8555/$G1?$MTX_QUERY\n" +
-			"    sourceOnDemand: yes\n" +
-			"  'all':\n")
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/record/start/mystream", nil, nil)
+
+	time.Sleep(500 * time.Millisecond)
+
+	writePackets(4)
+
+	time.Sleep(500 * time.Millisecond)
+
+	httpRequest(t, hc, http.MethodPost, "http://localhost:9997/v3/paths/record/stop/mystream", nil, nil)
+
+	time.Sleep(500 * time.Millisecond)
+
+	writePackets(8)
+
+	time.Sleep(500 * time.Millisecond)
+
+	files, err := readSegmentFiles(filepath.Join(dir, "mystream"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+
+	res, err := hc.Post("http://localhost:9997/v3/paths/record/start/nonexisting", "", nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// recording enabled by the configuration can't be stopped
+	forcedSource := gortsplib.Client{}
+
+	err = forcedSource.StartRecording(
+		"rtsp://localhost:8554/forced",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer forcedSource.Close()
+
+	res2, err := hc.Post("http://localhost:9997/v3/paths/record/stop/forced", "", nil)
+	require.NoError(t, err)
+	defer res2.Body.Close()
+	require.Equal(t, http.StatusConflict, res2.StatusCode)
+}
+
+func TestPathRecordPublisherUser(t *testing.T) {
+	dir, err := os.MkdirTemp("", "rtsp-path-record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, ok := newInstance("record: yes\n" +
+		"recordPath: " + filepath.Join(dir, "%path/%user_%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+		"paths:\n" +
+		"  live/cam1:\n" +
+		"    publishUser: myuser\n" +
+		"    publishPass: mypass\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	conf := srt.DefaultConfig()
+	conf.StreamId = "publish:live/cam1:myuser:mypass"
+
+	conn, err := srt.Dial("srt", "localhost:8890", conf)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	track := &mpegts.Track{
+		Codec: &mpegts.CodecH264{},
+	}
+
+	bw := bufio.NewWriter(conn)
+	w := mpegts.NewWriter(bw, []*mpegts.Track{track})
+
+	for i := 0; i < 8; i++ {
+		err = w.WriteH264(track, int64(i)*90000, int64(i)*90000, true, [][]byte{
+			test.FormatH264.SPS,
+			test.FormatH264.PPS,
+			{5}, // IDR
+		})
+		require.NoError(t, err)
+
+		err = bw.Flush()
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	files, err := readSegmentFiles(filepath.Join(dir, "live", "cam1"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	require.True(t, strings.HasPrefix(files[0].Name(), "myuser_"))
+}
+
+func TestPathRecordPublisherQuery(t *testing.T) {
+	dir, err := os.MkdirTemp("", "rtsp-path-record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	onConnect := filepath.Join(os.TempDir(), "on_connect")
+	defer os.Remove(onConnect)
+
+	func() {
+		p, ok := newInstance("record: yes\n" +
+			"recordPath: " + filepath.Join(dir, "%path/%q_cam-%query-%Y-%m-%d_%H-%M-%S-%f") + "\n" +
+			"runOnConnect: sh -c 'echo \"$MTX_QUERY\" > " + onConnect + "'\n" +
+			"paths:\n" +
+			"  test:\n")
+		require.Equal(t, true, ok)
+		defer p.Close()
+
+		conf := srt.DefaultConfig()
+		conf.StreamId = "publish:test:cam=roof&token=abc"
+
+		conn, err := srt.Dial("srt", "localhost:8890", conf)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		track := &mpegts.Track{
+			Codec: &mpegts.CodecH264{},
+		}
+
+		bw := bufio.NewWriter(conn)
+		w := mpegts.NewWriter(bw, []*mpegts.Track{track})
+
+		for i := 0; i < 8; i++ {
+			err = w.WriteH264(track, int64(i)*90000, int64(i)*90000, true, [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			})
+			require.NoError(t, err)
+
+			err = bw.Flush()
+			require.NoError(t, err)
+
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}()
+
+	byts, err := os.ReadFile(onConnect)
+	require.NoError(t, err)
+	require.Equal(t, "cam=roof&token=abc\n", string(byts))
+
+	files, err := readSegmentFiles(filepath.Join(dir, "test"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	require.True(t, strings.HasPrefix(files[0].Name(), "roof-cam=roof&token=abc-"))
+}
+
+func TestPathProxy(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-proxy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the test encoder ignores its input and returns a pre-encoded H264 stream,
+	// one frame at a time, in order to keep writing to readers.
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	track := &mpegts.Track{Codec: &mpegts.CodecH264{}}
+	w := mpegts.NewWriter(bw, []*mpegts.Track{track})
+
+	for i := 0; i < 200; i++ {
+		err = w.WriteH264(track, int64(i)*9000, int64(i)*9000, true,
+			[][]byte{test.FormatH264.SPS, test.FormatH264.PPS, {5, 3}})
+		require.NoError(t, err)
+
+		err = bw.Flush()
+		require.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("frame%03d.ts", i)), buf.Bytes(), 0o644)
+		require.NoError(t, err)
+		buf.Reset()
+	}
+
+	countPath := filepath.Join(dir, "count")
+
+	p, ok := newInstance("paths:\n" +
+		"  test:\n" +
+		"    proxy: yes\n" +
+		"    proxyCommand: sh -c 'echo started >> " + countPath + "; " +
+		"ls " + filepath.Join(dir, "frame*.ts") + " | xargs -I{} sh -c \"cat {}; sleep 0.05\"; cat > /dev/null'\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	source := gortsplib.Client{}
+
+	err = source.StartRecording(
+		"rtsp://localhost:8554/test",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer source.Close()
+
+	time.Sleep(500 * time.Millisecond)
+
+	// the encoder is started only when someone reads the proxy track
+	_, err = os.Stat(countPath)
+	require.True(t, os.IsNotExist(err))
+
+	for i := 0; i < 2; i++ {
+		func() {
+			conf := srt.DefaultConfig()
+			conf.StreamId = "read:test:quality=proxy"
+
+			reader, err := srt.Dial("srt", "localhost:8890", conf)
+			require.NoError(t, err)
+			defer reader.Close()
+
+			r, err := mpegts.NewReader(reader)
+			require.NoError(t, err)
+
+			// the source track is replaced by the proxy track
+			require.Equal(t, []*mpegts.Track{{
+				PID:   256,
+				Codec: &mpegts.CodecH264{},
+			}}, r.Tracks())
+
+			received := make(chan [][]byte, 10)
+
+			r.OnDataH264(r.Tracks()[0], func(_ int64, _ int64, au [][]byte) error {
+				received <- au
+				return nil
+			})
+
+			go func() {
+				for r.Read() == nil {
+				}
+			}()
+
+			select {
+			case au := <-received:
+				require.Equal(t, [][]byte{test.FormatH264.SPS, test.FormatH264.PPS, {5, 3}}, au)
+			case <-time.After(5 * time.Second):
+				t.Fatal("proxy track not received")
+			}
+		}()
+
+		// the encoder is stopped when the last reader of the proxy track leaves,
+		// and started again by the next one.
+		byts, err := os.ReadFile(countPath)
+		require.NoError(t, err)
+		require.Equal(t, i+1, strings.Count(string(byts), "started"))
+
+		// wait for the server to detect that the reader has left
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func TestPathFallback(t *testing.T) {
+	for _, ca := range []string{
+		"absolute",
+		"relative",
+		"source",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			var conf string
+
+			switch ca {
+			case "absolute":
+				conf = "paths:\n" +
+					"  path1:\n" +
+					"    fallback: rtsp://localhost:8554/path2\n" +
+					"  path2:\n"
+
+			case "relative":
+				conf = "paths:\n" +
+					"  path1:\n" +
+					"    fallback: /path2\n" +
+					"  path2:\n"
+
+			case "source":
+				conf = "paths:\n" +
+					"  path1:\n" +
+					"    fallback: /path2\n" +
+					"    source: rtsp://localhost:3333/nonexistent\n" +
+					"  path2:\n"
+			}
+
+			p1, ok := newInstance(conf)
+			require.Equal(t, true, ok)
+			defer p1.Close()
+
+			source := gortsplib.Client{}
+			err := source.StartRecording("rtsp://localhost:8554/path2",
+				&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+			require.NoError(t, err)
+			defer source.Close()
+
+			u, err := base.ParseURL("rtsp://localhost:8554/path1")
+			require.NoError(t, err)
+
+			dest := gortsplib.Client{}
+			err = dest.Start(u.Scheme, u.Host)
+			require.NoError(t, err)
+			defer dest.Close()
+
+			desc, _, err := dest.Describe(u)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(desc.Medias))
+		})
+	}
+}
+
+func TestPathResolveSource(t *testing.T) {
+	var stream *gortsplib.ServerStream
+
+	s := gortsplib.Server{
+		Handler: &testServer{
+			onDescribe: func(ctx *gortsplib.ServerHandlerOnDescribeCtx,
+			) (*base.Response, *gortsplib.ServerStream, error) {
+				require.Equal(t, "key=val", ctx.Query)
+				require.Equal(t, "/a", ctx.Path)
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onSetup: func(_ *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(_ *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "127.0.0.1:8555",
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	stream = gortsplib.NewServerStream(&s, &description.Session{Medias: []*description.Media{test.MediaH264}})
+	defer stream.Close()
+
+	p, ok := newInstance(
+		"paths:\n" +
+			"  '~^test_(.+)$':\n" +
+			"    source: rtsp://127.0.0.1:8555/$G1?$MTX_QUERY\n" +
+			"    sourceOnDemand: yes\n" +
+			"  'all':\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	reader := gortsplib.Client{}
+
+	u, err := base.ParseURL("rtsp://127.0.0.1:8554/test_a?key=val")
+	require.NoError(t, err)
+
+	err = reader.Start(u.Scheme, u.Host)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	_, _, err = reader.Describe(u)
+	require.NoError(t, err)
+}
+
+func TestPathSourceOnDemandCloseAfter(t *testing.T) {
+	var stream *gortsplib.ServerStream
+
+	s := gortsplib.Server{
+		Handler: &testServer{
+			onDescribe: func(_ *gortsplib.ServerHandlerOnDescribeCtx,
+			) (*base.Response, *gortsplib.ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onSetup: func(_ *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, stream, nil
+			},
+			onPlay: func(_ *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		},
+		RTSPAddress: "127.0.0.1:8555",
+	}
+
+	err := s.Start()
+	require.NoError(t, err)
+	defer s.Close()
+
+	stream = gortsplib.NewServerStream(&s, &description.Session{Medias: []*description.Media{test.MediaH264}})
+	defer stream.Close()
+
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  mypath:\n" +
+		"    source: rtsp://127.0.0.1:8555/stream\n" +
+		"    sourceOnDemand: yes\n" +
+		"    sourceOnDemandCloseAfter: 2s\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	read := func() {
+		reader := gortsplib.Client{}
+
+		u, err2 := base.ParseURL("rtsp://127.0.0.1:8554/mypath")
+		require.NoError(t, err2)
+
+		err2 = reader.Start(u.Scheme, u.Host)
+		require.NoError(t, err2)
+		defer reader.Close()
+
+		desc, _, err2 := reader.Describe(u)
+		require.NoError(t, err2)
+
+		err2 = reader.SetupAll(desc.BaseURL, desc.Medias)
+		require.NoError(t, err2)
+
+		_, err2 = reader.Play(nil)
+		require.NoError(t, err2)
+	}
+
+	getPath := func() defs.APIPath {
+		var out defs.APIPath
+		httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+		return out
+	}
+
+	read()
+
+	// the timer starts after the path has processed the removal of the reader
+	var out defs.APIPath
+	require.Eventually(t, func() bool {
+		out = getPath()
+		return out.OnDemandCloseRemaining != nil
+	}, 1*time.Second, 10*time.Millisecond)
+	require.Equal(t, true, out.Ready)
+	require.LessOrEqual(t, time.Duration(*out.OnDemandCloseRemaining), 2*time.Second)
+
+	time.Sleep(1 * time.Second)
+
+	// a reader that arrives within the window resets the timer
+	read()
+
+	time.Sleep(1500 * time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		out = getPath()
+		return out.OnDemandCloseRemaining != nil
+	}, 400*time.Millisecond, 10*time.Millisecond)
+	require.Equal(t, true, out.Ready)
+
+	require.Eventually(t, func() bool {
+		out = getPath()
+		return !out.Ready
+	}, 5*time.Second, 100*time.Millisecond)
+
+	require.Nil(t, out.OnDemandCloseRemaining)
+}
+
+func TestPathSourceFailover(t *testing.T) {
+	newSourceServer := func(address string) (*gortsplib.Server, *gortsplib.ServerStream) {
+		var stream *gortsplib.ServerStream
+
+		s := &gortsplib.Server{
+			Handler: &testServer{
+				onDescribe: func(_ *gortsplib.ServerHandlerOnDescribeCtx,
+				) (*base.Response, *gortsplib.ServerStream, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, stream, nil
+				},
+				onSetup: func(_ *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, stream, nil
+				},
+				onPlay: func(_ *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				},
+			},
+			RTSPAddress: address,
+		}
+
+		err := s.Start()
+		require.NoError(t, err)
+
+		stream = gortsplib.NewServerStream(s, &description.Session{Medias: []*description.Media{test.MediaH264}})
+
+		return s, stream
+	}
+
+	primary, primaryStream := newSourceServer("127.0.0.1:8555")
+
+	secondary, secondaryStream := newSourceServer("127.0.0.1:8556")
+	defer secondary.Close()
+	defer secondaryStream.Close()
+
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  mypath:\n" +
+		"    source: rtsp://127.0.0.1:8555/stream\n" +
+		"    sourceFailover: [rtsp://127.0.0.1:8556/stream]\n" +
+		"    sourceFailbackDelay: 1s\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	waitActiveSource := func(activeSource string) {
+		require.Eventually(t, func() bool {
+			var out defs.APIPath
+			httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+			return out.Ready && out.Source != nil && out.Source.ActiveSource == activeSource
+		}, 10*time.Second, 100*time.Millisecond)
+	}
+
+	waitActiveSource("rtsp://127.0.0.1:8555/stream")
+
+	// primary goes down
+	primaryStream.Close()
+	primary.Close()
+
+	waitActiveSource("rtsp://127.0.0.1:8556/stream")
+
+	// primary recovers
+	primary, primaryStream = newSourceServer("127.0.0.1:8555")
+	defer primary.Close()
+	defer primaryStream.Close()
+
+	waitActiveSource("rtsp://127.0.0.1:8555/stream")
+}
+
+func TestPathSourceErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name       string
+		statusCode int
+	}{
+		{"not found", http.StatusNotFound},
+		{"service unavailable", http.StatusServiceUnavailable},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			requests := make(chan struct{}, 10)
+
+			s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests <- struct{}{}
+				w.WriteHeader(ca.statusCode)
+			})}
+
+			ln, err := net.Listen("tcp", "localhost:5780")
+			require.NoError(t, err)
+
+			go s.Serve(ln)
+			defer s.Shutdown(context.Background())
+
+			p, ok := newInstance("api: yes\n" +
+				"paths:\n" +
+				"  mypath:\n" +
+				"    source: http://localhost:5780/stream.m3u8\n")
+			require.Equal(t, true, ok)
+			defer p.Close()
+
+			tr := &http.Transport{}
+			defer tr.CloseIdleConnections()
+			hc := &http.Client{Transport: tr}
+
+			<-requests
+
+			expectedErr := fmt.Sprintf("bad status code: %d", ca.statusCode)
+
+			var out defs.APIPath
+			require.Eventually(t, func() bool {
+				httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+				return out.SourceError != nil
+			}, 5*time.Second, 100*time.Millisecond)
+			require.Equal(t, expectedErr, *out.SourceError)
+
+			if ca.statusCode == http.StatusNotFound {
+				require.Equal(t, true, out.SourceFailed)
+
+				select {
+				case <-requests:
+					t.Errorf("source has been retried")
+				case <-time.After(staticSourceHandlerRetryPause + time.Second):
+				}
+			} else {
+				require.Equal(t, false, out.SourceFailed)
+
+				select {
+				case <-requests:
+				case <-time.After(staticSourceHandlerRetryPause + 2*time.Second):
+					t.Errorf("source has not been retried")
+				}
+			}
+		})
+	}
+}
+
+func TestPathPublisherReconnects(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  mypath:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	for i := 0; i < 3; i++ {
+		source := gortsplib.Client{}
+		err := source.StartRecording("rtsp://localhost:8554/mypath",
+			&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+		require.NoError(t, err)
+
+		var out defs.APIPath
+		httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+		require.Equal(t, uint64(i), out.PublisherReconnects)
+
+		source.Close()
+	}
+}
+
+func TestPathPublisherMaxAttempts(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"pathDefaults:\n" +
+		"  publisherMaxAttempts: 2\n" +
+		"paths:\n" +
+		"  mypath:\n" +
+		"  all_others:\n")
+	require.Equal(t, true, ok)
+	defer p.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	// the state of dynamic paths is kept after they are closed
+	for _, pathName := range []string{"mypath", "otherpath"} {
+		t.Run(pathName, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				source := gortsplib.Client{}
+				err := source.StartRecording("rtsp://localhost:8554/"+pathName,
+					&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+				if i != 2 {
+					require.NoError(t, err)
+				} else {
+					require.EqualError(t, err, "bad status code: 503 (Service Unavailable)")
+				}
+				source.Close()
+			}
+		})
+	}
+
+	var out defs.APIPath
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/mypath", nil, &out)
+	require.NotNil(t, out.PublisherLimit)
+	require.Equal(t, 2, out.PublisherLimit.Attempts)
+	require.Equal(t, uint64(1), out.PublisherLimit.Rejected)
+	require.NotNil(t, out.PublisherLimit.BackoffRemaining)
+}
+
+func TestPathEvents(t *testing.T) {
+	p, ok := newInstance("api: yes\n" +
+		"paths:\n" +
+		"  mypath:\n")
 	require.Equal(t, true, ok)
 	defer p.Close()
 
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	// each iteration produces 4 events, in order to fill the buffer
+	for i := 0; i < 30; i++ {
+		source := gortsplib.Client{}
+		err := source.StartRecording("rtsp://localhost:8554/mypath",
+			&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+		require.NoError(t, err)
+		source.Close()
+	}
+
+	source := gortsplib.Client{}
+	err := source.StartRecording("rtsp://localhost:8554/mypath",
+		&description.Session{Medias: []*description.Media{test.UniqueMediaH264()}})
+	require.NoError(t, err)
+	defer source.Close()
+
 	reader := gortsplib.Client{}
 
-	u, err := base.ParseURL("rtsp://127.0.0.1:8554/test_a?key=val")
+	u, err := base.ParseURL("rtsp://localhost:8554/mypath")
 	require.NoError(t, err)
 
 	err = reader.Start(u.Scheme, u.Host)
 	require.NoError(t, err)
 	defer reader.Close()
 
-	_, _, err = reader.Describe(u)
+	desc, _, err := reader.Describe(u)
+	require.NoError(t, err)
+
+	err = reader.SetupAll(desc.BaseURL, desc.Medias)
 	require.NoError(t, err)
+
+	_, err = reader.Play(nil)
+	require.NoError(t, err)
+
+	var out defs.APIPathEventList
+	httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/events/mypath?itemsPerPage=1000", nil, &out)
+	require.Equal(t, pathMaxEvents, out.ItemCount)
+	require.Equal(t, pathMaxEvents, len(out.Items))
+
+	for i := 1; i < len(out.Items); i++ {
+		require.False(t, out.Items[i].Time.Before(out.Items[i-1].Time))
+	}
+
+	n := len(out.Items)
+	require.Equal(t, conf.LogLevel(logger.Info), out.Items[n-1].Severity)
+	require.Regexp(t, "^reader added \\(rtspSession .+\\)$", out.Items[n-1].Message)
+	require.Equal(t, "stream is ready, 1 track (H264)", out.Items[n-2].Message)
+	require.Regexp(t, "^publisher connected \\(rtspSession .+\\)$", out.Items[n-3].Message)
+	require.Regexp(t, "^publisher disconnected \\(rtspSession .+\\)$", out.Items[n-4].Message)
 }
 
 func TestPathOverridePublisher(t *testing.T) {
@@ -849,3 +1761,247 @@ func TestPathOverridePublisher(t *testing.T) {
 		})
 	}
 }
+
+func TestPathPublisherConflictPolicy(t *testing.T) {
+	for _, ca := range []string{
+		"takeover",
+		"reject",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			p, ok := newInstance("rtmp: no\n" +
+				"paths:\n" +
+				"  all_others:\n" +
+				"    publisherConflictPolicy: " + ca + "\n")
+			require.Equal(t, true, ok)
+			defer p.Close()
+
+			medi := test.UniqueMediaH264()
+
+			s1 := gortsplib.Client{}
+
+			err := s1.StartRecording("rtsp://localhost:8554/teststream",
+				&description.Session{Medias: []*description.Media{medi}})
+			require.NoError(t, err)
+			defer s1.Close()
+
+			frameRecv := make(chan []byte, 1)
+
+			c := gortsplib.Client{}
+
+			u, err := base.ParseURL("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+
+			err = c.Start(u.Scheme, u.Host)
+			require.NoError(t, err)
+			defer c.Close()
+
+			desc, _, err := c.Describe(u)
+			require.NoError(t, err)
+
+			err = c.SetupAll(desc.BaseURL, desc.Medias)
+			require.NoError(t, err)
+
+			c.OnPacketRTP(desc.Medias[0], desc.Medias[0].Formats[0], func(pkt *rtp.Packet) {
+				frameRecv <- pkt.Payload
+			})
+
+			_, err = c.Play(nil)
+			require.NoError(t, err)
+
+			// the reader is connected before the second publisher shows up
+			s2 := gortsplib.Client{}
+
+			err = s2.StartRecording("rtsp://localhost:8554/teststream",
+				&description.Session{Medias: []*description.Media{medi}})
+
+			publisher := &s1
+
+			if ca == "takeover" {
+				require.NoError(t, err)
+				defer s2.Close()
+
+				err = s1.Wait()
+				require.EqualError(t, err, "EOF")
+
+				publisher = &s2
+			} else {
+				require.Error(t, err)
+			}
+
+			err = publisher.WritePacketRTP(medi, &rtp.Packet{
+				Header: rtp.Header{
+					Version:        0x02,
+					PayloadType:    96,
+					SequenceNumber: 57899,
+					Timestamp:      345234345,
+					SSRC:           978651231,
+					Marker:         true,
+				},
+				Payload: []byte{5, 11, 12, 13, 14},
+			})
+			require.NoError(t, err)
+
+			// in both cases, the reader keeps receiving frames without reconnecting
+			require.Equal(t, []byte{5, 11, 12, 13, 14}, <-frameRecv)
+		})
+	}
+}
+
+func TestPathPublisherHoldTimeout(t *testing.T) {
+	for _, ca := range []string{
+		"reconnect",
+		"timeout",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			p, ok := newInstance("rtmp: no\n" +
+				"paths:\n" +
+				"  all_others:\n" +
+				"    publisherHoldTimeout: 1s\n")
+			require.Equal(t, true, ok)
+			defer p.Close()
+
+			medi := test.UniqueMediaH264()
+
+			s1 := gortsplib.Client{}
+
+			err := s1.StartRecording("rtsp://localhost:8554/teststream",
+				&description.Session{Medias: []*description.Media{medi}})
+			require.NoError(t, err)
+			defer s1.Close()
+
+			frameRecv := make(chan []byte, 1)
+
+			c := gortsplib.Client{}
+
+			u, err := base.ParseURL("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+
+			err = c.Start(u.Scheme, u.Host)
+			require.NoError(t, err)
+			defer c.Close()
+
+			desc, _, err := c.Describe(u)
+			require.NoError(t, err)
+
+			err = c.SetupAll(desc.BaseURL, desc.Medias)
+			require.NoError(t, err)
+
+			c.OnPacketRTP(desc.Medias[0], desc.Medias[0].Formats[0], func(pkt *rtp.Packet) {
+				frameRecv <- pkt.Payload
+			})
+
+			_, err = c.Play(nil)
+			require.NoError(t, err)
+
+			s1.Close()
+
+			readerErr := make(chan error, 1)
+			go func() {
+				readerErr <- c.Wait()
+			}()
+
+			if ca == "timeout" {
+				select {
+				case <-readerErr:
+					t.Errorf("reader disconnected before the timeout")
+				case <-time.After(500 * time.Millisecond):
+				}
+
+				select {
+				case <-readerErr:
+				case <-time.After(2 * time.Second):
+					t.Errorf("reader not disconnected after the timeout")
+				}
+				return
+			}
+
+			time.Sleep(500 * time.Millisecond)
+
+			s2 := gortsplib.Client{}
+
+			err = s2.StartRecording("rtsp://localhost:8554/teststream",
+				&description.Session{Medias: []*description.Media{medi}})
+			require.NoError(t, err)
+			defer s2.Close()
+
+			// the reader is kept after the hold timeout, since the publisher has reconnected
+			time.Sleep(1 * time.Second)
+
+			err = s2.WritePacketRTP(medi, &rtp.Packet{
+				Header: rtp.Header{
+					Version:        0x02,
+					PayloadType:    96,
+					SequenceNumber: 57899,
+					Timestamp:      345234345,
+					SSRC:           978651231,
+					Marker:         true,
+				},
+				Payload: []byte{5, 11, 12, 13, 14},
+			})
+			require.NoError(t, err)
+
+			select {
+			case buf := <-frameRecv:
+				require.Equal(t, []byte{5, 11, 12, 13, 14}, buf)
+			case err := <-readerErr:
+				t.Errorf("reader disconnected: %v", err)
+			}
+		})
+	}
+}
+
+func TestPathSRTAutoCreatePaths(t *testing.T) {
+	for _, ca := range []string{
+		"matching",
+		"not matching",
+		"disabled",
+		"disabled static",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			autoCreate := "yes"
+			if ca == "disabled" || ca == "disabled static" {
+				autoCreate = "no"
+			}
+
+			p, ok := newInstance("api: yes\n" +
+				"srtAutoCreatePaths: " + autoCreate + "\n" +
+				"paths:\n" +
+				"  static:\n" +
+				"  '~^live/.+$':\n")
+			require.Equal(t, true, ok)
+			defer p.Close()
+
+			var pathName string
+			switch ca {
+			case "matching", "disabled":
+				pathName = "live/cam1"
+			case "not matching":
+				pathName = "other/cam1"
+			case "disabled static":
+				pathName = "static"
+			}
+
+			conf := srt.DefaultConfig()
+			conf.StreamId = "publish:" + pathName
+
+			conn, err := srt.Dial("srt", "localhost:8890", conf)
+
+			if ca == "matching" || ca == "disabled static" {
+				require.NoError(t, err)
+				defer conn.Close()
+
+				tr := &http.Transport{}
+				defer tr.CloseIdleConnections()
+				hc := &http.Client{Transport: tr}
+
+				var out struct {
+					Name string `json:"name"`
+				}
+				httpRequest(t, hc, http.MethodGet, "http://localhost:9997/v3/paths/get/"+pathName, nil, &out)
+				require.Equal(t, pathName, out.Name)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
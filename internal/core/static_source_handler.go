@@ -2,9 +2,13 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -20,7 +24,8 @@ import (
 )
 
 const (
-	staticSourceHandlerRetryPause = 5 * time.Second
+	staticSourceHandlerRetryPause    = 5 * time.Second
+	staticSourceHandlerMaxRetryPause = 60 * time.Second
 )
 
 func resolveSource(s string, matches []string, query string) string {
@@ -35,6 +40,72 @@ func resolveSource(s string, matches []string, query string) string {
 	return s
 }
 
+// staticSourceHealthCheck checks whether a source is reachable.
+// Sources that are not based on TCP cannot be checked in advance
+// and are always considered reachable.
+func staticSourceHealthCheck(source string, timeout time.Duration) error {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil
+	}
+
+	var defaultPort string
+
+	switch u.Scheme {
+	case "rtsp":
+		defaultPort = "554"
+
+	case "rtsps":
+		defaultPort = "322"
+
+	case "rtmp":
+		defaultPort = "1935"
+
+	case "http", "whep":
+		defaultPort = "80"
+
+	case "rtmps", "https", "wheps":
+		defaultPort = "443"
+
+	default:
+		return nil
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+
+	nconn, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), port), timeout)
+	if err != nil {
+		return err
+	}
+	nconn.Close()
+
+	return nil
+}
+
+// nextSource returns the next source that has not failed permanently
+// and whether the list of sources has been walked through entirely.
+func nextSource(failed []bool, active int) (int, bool, bool) {
+	for i := 1; i <= len(failed); i++ {
+		next := (active + i) % len(failed)
+		if !failed[next] {
+			return next, next <= active, true
+		}
+	}
+	return 0, false, false
+}
+
+// redactSource removes the password from a source URL.
+func redactSource(source string) string {
+	u, err := url.Parse(source)
+	if err != nil || u.User == nil {
+		return source
+	}
+	return u.Redacted()
+}
+
 type staticSourceHandlerParent interface {
 	logger.Writer
 	staticSourceHandlerSetReady(context.Context, defs.PathSourceStaticSetReadyReq)
@@ -53,10 +124,18 @@ type staticSourceHandler struct {
 
 	ctx       context.Context
 	ctxCancel func()
-	instance  defs.StaticSource
+	sources   []string
+	instances []defs.StaticSource
 	running   bool
 	query     string
 
+	activeMutex sync.RWMutex
+	active      int
+
+	errMutex sync.RWMutex
+	lastErr  error
+	gaveUp   bool
+
 	// in
 	chReloadConf          chan *conf.Path
 	chInstanceSetReady    chan defs.PathSourceStaticSetReadyReq
@@ -71,52 +150,61 @@ func (s *staticSourceHandler) initialize() {
 	s.chInstanceSetReady = make(chan defs.PathSourceStaticSetReadyReq)
 	s.chInstanceSetNotReady = make(chan defs.PathSourceStaticSetNotReadyReq)
 
+	// sources are tried in priority order
+	s.sources = append([]string{s.conf.Source}, s.conf.SourceFailover...)
+
+	for _, source := range s.sources {
+		s.instances = append(s.instances, s.newInstance(source))
+	}
+}
+
+func (s *staticSourceHandler) newInstance(source string) defs.StaticSource {
 	switch {
-	case strings.HasPrefix(s.conf.Source, "rtsp://") ||
-		strings.HasPrefix(s.conf.Source, "rtsps://"):
-		s.instance = &rtspsource.Source{
+	case strings.HasPrefix(source, "rtsp://") ||
+		strings.HasPrefix(source, "rtsps://"):
+		return &rtspsource.Source{
 			ReadTimeout:    s.readTimeout,
 			WriteTimeout:   s.writeTimeout,
 			WriteQueueSize: s.writeQueueSize,
 			Parent:         s,
 		}
 
-	case strings.HasPrefix(s.conf.Source, "rtmp://") ||
-		strings.HasPrefix(s.conf.Source, "rtmps://"):
-		s.instance = &rtmpsource.Source{
+	case strings.HasPrefix(source, "rtmp://") ||
+		strings.HasPrefix(source, "rtmps://"):
+		return &rtmpsource.Source{
 			ReadTimeout:  s.readTimeout,
 			WriteTimeout: s.writeTimeout,
 			Parent:       s,
 		}
 
-	case strings.HasPrefix(s.conf.Source, "http://") ||
-		strings.HasPrefix(s.conf.Source, "https://"):
-		s.instance = &hlssource.Source{
+	case strings.HasPrefix(source, "http://") ||
+		strings.HasPrefix(source, "https://"):
+		return &hlssource.Source{
 			ReadTimeout: s.readTimeout,
 			Parent:      s,
 		}
 
-	case strings.HasPrefix(s.conf.Source, "udp://"):
-		s.instance = &udpsource.Source{
+	case strings.HasPrefix(source, "udp://"):
+		return &udpsource.Source{
 			ReadTimeout: s.readTimeout,
 			Parent:      s,
 		}
 
-	case strings.HasPrefix(s.conf.Source, "srt://"):
-		s.instance = &srtsource.Source{
+	case strings.HasPrefix(source, "srt://"):
+		return &srtsource.Source{
 			ReadTimeout: s.readTimeout,
 			Parent:      s,
 		}
 
-	case strings.HasPrefix(s.conf.Source, "whep://") ||
-		strings.HasPrefix(s.conf.Source, "wheps://"):
-		s.instance = &webrtcsource.Source{
+	case strings.HasPrefix(source, "whep://") ||
+		strings.HasPrefix(source, "wheps://"):
+		return &webrtcsource.Source{
 			ReadTimeout: s.readTimeout,
 			Parent:      s,
 		}
 
-	case s.conf.Source == "rpiCamera":
-		s.instance = &rpicamerasource.Source{
+	case source == "rpiCamera":
+		return &rpicamerasource.Source{
 			LogLevel: s.logLevel,
 			Parent:   s,
 		}
@@ -140,7 +228,7 @@ func (s *staticSourceHandler) start(onDemand bool, query string) {
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	s.done = make(chan struct{})
 
-	s.instance.Log(logger.Info, "started%s",
+	s.activeInstance().Log(logger.Info, "started%s",
 		func() string {
 			if onDemand {
 				return " on demand"
@@ -158,7 +246,7 @@ func (s *staticSourceHandler) stop(reason string) {
 
 	s.running = false
 
-	s.instance.Log(logger.Info, "stopped: %s", reason)
+	s.activeInstance().Log(logger.Info, "stopped: %s", reason)
 
 	s.ctxCancel()
 
@@ -166,6 +254,33 @@ func (s *staticSourceHandler) stop(reason string) {
 	<-s.done
 }
 
+func (s *staticSourceHandler) activeInstance() defs.StaticSource {
+	s.activeMutex.RLock()
+	defer s.activeMutex.RUnlock()
+	return s.instances[s.active]
+}
+
+func (s *staticSourceHandler) setActive(active int) {
+	s.activeMutex.Lock()
+	defer s.activeMutex.Unlock()
+	s.active = active
+}
+
+func (s *staticSourceHandler) setLastError(err error, failed bool) {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	s.lastErr = err
+	s.gaveUp = failed
+}
+
+// lastError returns the last error of the source and whether
+// the source has stopped retrying due to permanent errors.
+func (s *staticSourceHandler) lastError() (error, bool) {
+	s.errMutex.RLock()
+	defer s.errMutex.RUnlock()
+	return s.lastErr, s.gaveUp
+}
+
 // Log implements logger.Writer.
 func (s *staticSourceHandler) Log(level logger.Level, format string, args ...interface{}) {
 	s.parent.Log(level, format, args...)
@@ -178,19 +293,36 @@ func (s *staticSourceHandler) run() {
 	var runCtxCancel func()
 	runErr := make(chan error)
 	runReloadConf := make(chan *conf.Path)
+	healthCheckRes := make(chan error, 1)
+
+	// whether the active source is being closed in order to switch back to the primary one
+	switching := false
+	failbackTimer := emptyTimer()
+
+	// sources that returned a permanent error are not tried again
+	failed := make([]bool, len(s.sources))
+	halted := false
+	retryPause := staticSourceHandlerRetryPause
+
+	s.setLastError(nil, false)
 
 	recreate := func() {
-		resolvedSource := resolveSource(s.conf.Source, s.matches, s.query)
+		resolvedSource := resolveSource(s.sources[s.active], s.matches, s.query)
+		instance := s.instances[s.active]
 
 		runCtx, runCtxCancel = context.WithCancel(context.Background())
 		go func() {
-			runErr <- s.instance.Run(defs.StaticSourceRunParams{
+			runErr <- instance.Run(defs.StaticSourceRunParams{
 				Context:        runCtx,
 				ResolvedSource: resolvedSource,
 				Conf:           s.conf,
 				ReloadConf:     runReloadConf,
 			})
 		}()
+
+		if s.active != 0 && !failed[0] {
+			failbackTimer = time.NewTimer(time.Duration(s.conf.SourceFailbackDelay))
+		}
 	}
 
 	recreate()
@@ -202,11 +334,50 @@ func (s *staticSourceHandler) run() {
 		select {
 		case err := <-runErr:
 			runCtxCancel()
-			s.instance.Log(logger.Error, err.Error())
-			recreating = true
-			recreateTimer = time.NewTimer(staticSourceHandlerRetryPause)
+			failbackTimer.Stop()
+
+			if switching {
+				switching = false
+				s.setActive(0)
+				recreate()
+				break
+			}
+
+			var perr defs.StaticSourcePermanentError
+			if errors.As(err, &perr) {
+				s.instances[s.active].Log(logger.Error, "%v (permanent error, the source will not be retried)", err)
+				failed[s.active] = true
+			} else {
+				s.instances[s.active].Log(logger.Error, err.Error())
+			}
+
+			next, wrapped, ok := nextSource(failed, s.active)
+			if !ok {
+				s.instances[s.active].Log(logger.Error, "all sources failed permanently, giving up")
+				s.setLastError(err, true)
+				halted = true
+				break
+			}
+
+			s.setLastError(err, false)
+
+			if next != s.active {
+				s.setActive(next)
+				s.instances[next].Log(logger.Warn, "switching to source '%s'", redactSource(s.sources[next]))
+			}
+
+			// when all sources have failed, wait before trying again,
+			// doubling the pause at every attempt.
+			if wrapped {
+				recreating = true
+				recreateTimer = time.NewTimer(retryPause)
+				retryPause = min(retryPause*2, staticSourceHandlerMaxRetryPause)
+			} else {
+				recreate()
+			}
 
 		case req := <-s.chInstanceSetReady:
+			retryPause = staticSourceHandlerRetryPause
 			s.parent.staticSourceHandlerSetReady(s.ctx, req)
 
 		case req := <-s.chInstanceSetNotReady:
@@ -214,7 +385,7 @@ func (s *staticSourceHandler) run() {
 
 		case newConf := <-s.chReloadConf:
 			s.conf = newConf
-			if !recreating {
+			if !recreating && !halted {
 				cReloadConf := runReloadConf
 				cInnerCtx := runCtx
 				go func() {
@@ -229,8 +400,35 @@ func (s *staticSourceHandler) run() {
 			recreate()
 			recreating = false
 
+		case <-failbackTimer.C:
+			primary := resolveSource(s.sources[0], s.matches, s.query)
+			timeout := time.Duration(s.readTimeout)
+			ctx := s.ctx
+			go func() {
+				select {
+				case healthCheckRes <- staticSourceHealthCheck(primary, timeout):
+				case <-ctx.Done():
+				}
+			}()
+
+		case err := <-healthCheckRes:
+			if recreating || halted || switching || s.active == 0 {
+				break
+			}
+
+			if err != nil {
+				s.instances[s.active].Log(logger.Debug, "primary source is still not available: %v", err)
+				failbackTimer = time.NewTimer(time.Duration(s.conf.SourceFailbackDelay))
+				break
+			}
+
+			s.instances[0].Log(logger.Info, "primary source is available again, switching back to '%s'",
+				redactSource(s.sources[0]))
+			switching = true
+			runCtxCancel()
+
 		case <-s.ctx.Done():
-			if !recreating {
+			if !recreating && !halted {
 				runCtxCancel()
 				<-runErr
 			}
@@ -256,7 +454,17 @@ func (s *staticSourceHandler) reloadConf(newConf *conf.Path) {
 
 // APISourceDescribe instanceements source.
 func (s *staticSourceHandler) APISourceDescribe() defs.APIPathSourceOrReader {
-	return s.instance.APISourceDescribe()
+	s.activeMutex.RLock()
+	active := s.active
+	s.activeMutex.RUnlock()
+
+	ret := s.instances[active].APISourceDescribe()
+
+	if len(s.sources) > 1 {
+		ret.ActiveSource = redactSource(s.sources[active])
+	}
+
+	return ret
 }
 
 // setReady is called by a staticSource.
@@ -267,7 +475,8 @@ func (s *staticSourceHandler) SetReady(req defs.PathSourceStaticSetReadyReq) def
 		res := <-req.Res
 
 		if res.Err == nil {
-			s.instance.Log(logger.Info, "ready: %s", defs.MediasInfo(req.Desc.Medias))
+			s.setLastError(nil, false)
+			s.activeInstance().Log(logger.Info, "ready: %s", defs.MediasInfo(req.Desc.Medias))
 		}
 
 		return res
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextSource(t *testing.T) {
+	for _, ca := range []struct {
+		name    string
+		failed  []bool
+		active  int
+		next    int
+		wrapped bool
+		ok      bool
+	}{
+		{"single", []bool{false}, 0, 0, true, true},
+		{"single failed", []bool{true}, 0, 0, false, false},
+		{"failover", []bool{false, false}, 0, 1, false, true},
+		{"failover wrapped", []bool{false, false}, 1, 0, true, true},
+		{"primary failed", []bool{true, false}, 1, 1, true, true},
+		{"skip failed", []bool{false, true, false}, 0, 2, false, true},
+		{"all failed", []bool{true, true}, 1, 0, false, false},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			next, wrapped, ok := nextSource(ca.failed, ca.active)
+			require.Equal(t, ca.ok, ok)
+			if ok {
+				require.Equal(t, ca.next, next)
+				require.Equal(t, ca.wrapped, wrapped)
+			}
+		})
+	}
+}
@@ -20,23 +20,113 @@ type APIPathConfList struct {
 	Items     []*conf.Path `json:"items"`
 }
 
+// APIConfigDiffField is a configuration field that differs.
+type APIConfigDiffField struct {
+	Name            string      `json:"name"`
+	Old             interface{} `json:"old"`
+	New             interface{} `json:"new"`
+	RestartRequired bool        `json:"restartRequired"`
+}
+
+// APIConfigDiffPathChange is the change of a path configuration.
+type APIConfigDiffPathChange string
+
+// changes.
+const (
+	APIConfigDiffPathChangeAdded   APIConfigDiffPathChange = "added"
+	APIConfigDiffPathChangeRemoved APIConfigDiffPathChange = "removed"
+	APIConfigDiffPathChangeChanged APIConfigDiffPathChange = "changed"
+)
+
+// APIConfigDiffPath is a path configuration that differs.
+type APIConfigDiffPath struct {
+	Name            string                  `json:"name"`
+	Change          APIConfigDiffPathChange `json:"change"`
+	Fields          []*APIConfigDiffField   `json:"fields"`
+	RestartRequired bool                    `json:"restartRequired"`
+}
+
+// APIConfigDiff is the difference between the running configuration and another one.
+type APIConfigDiff struct {
+	RestartRequired bool                  `json:"restartRequired"`
+	Global          []*APIConfigDiffField `json:"global"`
+	PathDefaults    []*APIConfigDiffField `json:"pathDefaults"`
+	Paths           []*APIConfigDiffPath  `json:"paths"`
+}
+
 // APIPathSourceOrReader is a source or a reader.
 type APIPathSourceOrReader struct {
-	Type string `json:"type"`
-	ID   string `json:"id"`
+	Type         string `json:"type"`
+	ID           string `json:"id"`
+	ActiveSource string `json:"activeSource"`
 }
 
 // APIPath is a path.
 type APIPath struct {
-	Name          string                  `json:"name"`
-	ConfName      string                  `json:"confName"`
-	Source        *APIPathSourceOrReader  `json:"source"`
-	Ready         bool                    `json:"ready"`
-	ReadyTime     *time.Time              `json:"readyTime"`
-	Tracks        []string                `json:"tracks"`
-	BytesReceived uint64                  `json:"bytesReceived"`
-	BytesSent     uint64                  `json:"bytesSent"`
-	Readers       []APIPathSourceOrReader `json:"readers"`
+	Name                   string                  `json:"name"`
+	ConfName               string                  `json:"confName"`
+	Source                 *APIPathSourceOrReader  `json:"source"`
+	SourceError            *string                 `json:"sourceError"`
+	SourceFailed           bool                    `json:"sourceFailed"`
+	Ready                  bool                    `json:"ready"`
+	ReadyTime              *time.Time              `json:"readyTime"`
+	Tracks                 []string                `json:"tracks"`
+	BytesReceived          uint64                  `json:"bytesReceived"`
+	BytesSent              uint64                  `json:"bytesSent"`
+	Readers                []APIPathSourceOrReader `json:"readers"`
+	PublisherReconnects    uint64                  `json:"publisherReconnects"`
+	OnDemandCloseRemaining *conf.StringDuration    `json:"onDemandCloseRemaining"`
+	DecodeErrors           []*APIPathDecodeError   `json:"decodeErrors"`
+	TrackStates            []*APIPathTrackState    `json:"trackStates"`
+	SRTRelay               *APIPathSRTRelay        `json:"srtRelay"`
+	PublisherLimit         *APIPathPublisherLimit  `json:"publisherLimit"`
+}
+
+// APIPathTrackState is the state of a track of a path.
+type APIPathTrackState struct {
+	Codec        string              `json:"codec"`
+	Active       bool                `json:"active"`
+	LastSeen     *time.Time          `json:"lastSeen"`
+	ReorderDepth conf.StringDuration `json:"reorderDepth"`
+}
+
+// APIPathSRTRelayState is the state of a SRT relay.
+type APIPathSRTRelayState string
+
+// states.
+const (
+	APIPathSRTRelayStateConnecting APIPathSRTRelayState = "connecting"
+	APIPathSRTRelayStateConnected  APIPathSRTRelayState = "connected"
+	APIPathSRTRelayStateWaiting    APIPathSRTRelayState = "waiting"
+)
+
+// APIPathSRTRelay is the SRT relay of a path.
+type APIPathSRTRelay struct {
+	State      APIPathSRTRelayState `json:"state"`
+	Reconnects uint64               `json:"reconnects"`
+	LastError  *string              `json:"lastError"`
+	BytesSent  uint64               `json:"bytesSent"`
+	MsRTT      float64              `json:"msRTT"`
+}
+
+// APIPathPublisherLimit is the state of the limit of publisher attempts of a path.
+type APIPathPublisherLimit struct {
+	// attempts admitted within the window
+	Attempts int `json:"attempts"`
+
+	// attempts rejected since the limit was created
+	Rejected uint64 `json:"rejected"`
+
+	// time after which attempts are admitted again, when attempts are being rejected
+	BackoffRemaining *conf.StringDuration `json:"backoffRemaining"`
+}
+
+// APIPathDecodeError is a decode error of a path, with the number of its occurrences.
+type APIPathDecodeError struct {
+	Message   string    `json:"message"`
+	Count     uint64    `json:"count"`
+	FirstTime time.Time `json:"firstTime"`
+	LastTime  time.Time `json:"lastTime"`
 }
 
 // APIPathList is a list of paths.
@@ -46,6 +136,85 @@ type APIPathList struct {
 	Items     []*APIPath `json:"items"`
 }
 
+// APIPathEvent is an event of a path.
+type APIPathEvent struct {
+	Time     time.Time     `json:"time"`
+	Severity conf.LogLevel `json:"severity"`
+	Message  string        `json:"message"`
+}
+
+// APIPathEventList is a list of path events.
+type APIPathEventList struct {
+	ItemCount int             `json:"itemCount"`
+	PageCount int             `json:"pageCount"`
+	Items     []*APIPathEvent `json:"items"`
+}
+
+// APIPathHistoryMetric is a metric whose history is kept.
+type APIPathHistoryMetric string
+
+// path history metrics.
+const (
+	// bits per second received by the path.
+	APIPathHistoryMetricBitrate APIPathHistoryMetric = "bitrate"
+
+	// round-trip time of the link of the publisher, in milliseconds.
+	// It is available with SRT publishers only.
+	APIPathHistoryMetricRTT APIPathHistoryMetric = "rtt"
+)
+
+// APIPathHistorySample is a sample of a path metric.
+type APIPathHistorySample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// APIPathHistory contains the recent samples of a path metric.
+type APIPathHistory struct {
+	Metric    APIPathHistoryMetric    `json:"metric"`
+	ItemCount int                     `json:"itemCount"`
+	PageCount int                     `json:"pageCount"`
+	Items     []*APIPathHistorySample `json:"items"`
+}
+
+// APIPathCounters contains the traffic counters of a path.
+type APIPathCounters struct {
+	// creation of the path or last reset of the counters.
+	Since         time.Time `json:"since"`
+	BytesReceived uint64    `json:"bytesReceived"`
+	BytesSent     uint64    `json:"bytesSent"`
+}
+
+// APIPathReaderHealth is an aggregate of the link statistics of the readers of a path.
+type APIPathReaderHealth struct {
+	// number of readers of the path.
+	Readers int `json:"readers"`
+
+	// number of readers that are able to report link statistics.
+	// Statistics below are computed on these readers only.
+	ReportingReaders int `json:"reportingReaders"`
+
+	WorstMsRTT           *float64 `json:"worstMsRTT"`
+	AverageMsRTT         *float64 `json:"averageMsRTT"`
+	PacketsSent          uint64   `json:"packetsSent"`
+	PacketsLost          uint64   `json:"packetsLost"`
+	PacketsRetransmitted uint64   `json:"packetsRetransmitted"`
+}
+
+// APIPathSignURLReq is a request to generate a signed URL for a path.
+type APIPathSignURLReq struct {
+	Action   conf.AuthAction     `json:"action"`
+	Duration conf.StringDuration `json:"duration"`
+	IP       string              `json:"ip"`
+}
+
+// APIPathSignedURL is a signed URL of a path.
+type APIPathSignedURL struct {
+	Token     string    `json:"token"`
+	Query     string    `json:"query"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 // APIHLSMuxer is an HLS muxer.
 type APIHLSMuxer struct {
 	Path        string    `json:"path"`
@@ -73,14 +242,15 @@ const (
 
 // APIRTMPConn is a RTMP connection.
 type APIRTMPConn struct {
-	ID            uuid.UUID        `json:"id"`
-	Created       time.Time        `json:"created"`
-	RemoteAddr    string           `json:"remoteAddr"`
-	State         APIRTMPConnState `json:"state"`
-	Path          string           `json:"path"`
-	Query         string           `json:"query"`
-	BytesReceived uint64           `json:"bytesReceived"`
-	BytesSent     uint64           `json:"bytesSent"`
+	ID            uuid.UUID           `json:"id"`
+	Created       time.Time           `json:"created"`
+	RemoteAddr    string              `json:"remoteAddr"`
+	State         APIRTMPConnState    `json:"state"`
+	Path          string              `json:"path"`
+	Query         string              `json:"query"`
+	QueryParams   map[string][]string `json:"queryParams"`
+	BytesReceived uint64              `json:"bytesReceived"`
+	BytesSent     uint64              `json:"bytesSent"`
 }
 
 // APIRTMPConnList is a list of RTMP connections.
@@ -124,6 +294,7 @@ type APIRTSPSession struct {
 	State         APIRTSPSessionState `json:"state"`
 	Path          string              `json:"path"`
 	Query         string              `json:"query"`
+	QueryParams   map[string][]string `json:"queryParams"`
 	Transport     *string             `json:"transport"`
 	BytesReceived uint64              `json:"bytesReceived"`
 	BytesSent     uint64              `json:"bytesSent"`
@@ -146,14 +317,27 @@ const (
 	APISRTConnStatePublish APISRTConnState = "publish"
 )
 
+// APISRTConnEncryption is the encryption mode of a SRT connection.
+type APISRTConnEncryption string
+
+// encryption modes.
+const (
+	APISRTConnEncryptionNone APISRTConnEncryption = "none"
+	APISRTConnEncryptionCTR  APISRTConnEncryption = "ctr"
+)
+
 // APISRTConn is a SRT connection.
 type APISRTConn struct {
-	ID         uuid.UUID       `json:"id"`
-	Created    time.Time       `json:"created"`
-	RemoteAddr string          `json:"remoteAddr"`
-	State      APISRTConnState `json:"state"`
-	Path       string          `json:"path"`
-	Query      string          `json:"query"`
+	ID          uuid.UUID            `json:"id"`
+	Created     time.Time            `json:"created"`
+	Uptime      conf.StringDuration  `json:"uptime"`
+	RemoteAddr  string               `json:"remoteAddr"`
+	State       APISRTConnState      `json:"state"`
+	Path        string               `json:"path"`
+	Query       string               `json:"query"`
+	QueryParams map[string][]string  `json:"queryParams"`
+	Encryption  APISRTConnEncryption `json:"encryption"`
+	Alarm       bool                 `json:"alarm"`
 
 	// The metric names/comments are pulled from GoSRT
 
@@ -275,6 +459,11 @@ type APISRTConn struct {
 	PacketsSendLossRate float64 `json:"packetsSendLossRate"`
 	// Percentage of retransmitted data vs. received data
 	PacketsReceivedLossRate float64 `json:"packetsReceivedLossRate"`
+
+	// Instantaneous bitrate of the media flow (received when publishing, sent when reading), in Mbps
+	BitrateRaw float64 `json:"bitrateRaw"`
+	// Bitrate of the media flow averaged over srtBitrateSmoothingWindow, in Mbps
+	BitrateSmoothed float64 `json:"bitrateSmoothed"`
 }
 
 // APISRTConnList is a list of SRT connections.
@@ -284,6 +473,32 @@ type APISRTConnList struct {
 	Items     []*APISRTConn `json:"items"`
 }
 
+// APISRTIP contains the number of SRT connections of an IP.
+// IPv6 addresses are grouped by prefix when srtConnsIPv6PrefixLength is less than 128.
+type APISRTIP struct {
+	IP    string `json:"ip"`
+	Conns int    `json:"conns"`
+}
+
+// APISRTIPList is a list of IPs with SRT connections.
+type APISRTIPList struct {
+	ItemCount int         `json:"itemCount"`
+	PageCount int         `json:"pageCount"`
+	Items     []*APISRTIP `json:"items"`
+}
+
+// APISRTListenerStats contains aggregate statistics of the SRT listener.
+type APISRTListenerStats struct {
+	// Number of current connections, by state
+	Conns map[APISRTConnState]int `json:"conns"`
+	// Bytes received by current and closed connections, including all the headers (IP, TCP, SRT)
+	BytesReceived uint64 `json:"bytesReceived"`
+	// Bytes sent by current and closed connections, including all the headers (IP, TCP, SRT)
+	BytesSent uint64 `json:"bytesSent"`
+	// Number of rejected connections, by reason
+	Rejections map[string]uint64 `json:"rejections"`
+}
+
 // APIWebRTCSessionState is the state of a WebRTC connection.
 type APIWebRTCSessionState string
 
@@ -304,6 +519,7 @@ type APIWebRTCSession struct {
 	State                     APIWebRTCSessionState `json:"state"`
 	Path                      string                `json:"path"`
 	Query                     string                `json:"query"`
+	QueryParams               map[string][]string   `json:"queryParams"`
 	BytesReceived             uint64                `json:"bytesReceived"`
 	BytesSent                 uint64                `json:"bytesSent"`
 }
@@ -332,3 +548,11 @@ type APIRecordingList struct {
 	PageCount int             `json:"pageCount"`
 	Items     []*APIRecording `json:"items"`
 }
+
+// APIDebugResources is a snapshot of the resources in use, that allows to detect leaks.
+type APIDebugResources struct {
+	Goroutines int            `json:"goroutines"`
+	OpenFiles  *int           `json:"openFiles"`
+	Conns      map[string]int `json:"conns"`
+	WaitGroups map[string]int `json:"waitGroups"`
+}
@@ -1,9 +1,11 @@
 package defs
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
@@ -12,9 +14,17 @@ import (
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/stream"
 )
 
+// ErrRecordingNotActive is returned when an operation requires recording to be active.
+var ErrRecordingNotActive = errors.New("recording is not active")
+
+// ErrRecordingEnabledByConf is returned when recording can't be stopped
+// since it is enabled by the configuration.
+var ErrRecordingEnabledByConf = errors.New("recording is enabled by the configuration")
+
 // PathNoOnePublishingError is returned when no one is publishing.
 type PathNoOnePublishingError struct {
 	PathName string
@@ -25,6 +35,28 @@ func (e PathNoOnePublishingError) Error() string {
 	return fmt.Sprintf("no one is publishing to path '%s'", e.PathName)
 }
 
+// PathDisabledError is returned when a path is disabled.
+type PathDisabledError struct {
+	PathName string
+}
+
+// Error implements the error interface.
+func (e PathDisabledError) Error() string {
+	return fmt.Sprintf("path '%s' is disabled", e.PathName)
+}
+
+// PathPublisherThrottledError is returned when a publisher exceeds
+// the number of attempts allowed by the path.
+type PathPublisherThrottledError struct {
+	PathName   string
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e PathPublisherThrottledError) Error() string {
+	return fmt.Sprintf("too many publisher attempts to path '%s', retry in %v", e.PathName, e.RetryAfter)
+}
+
 // Path is a path.
 type Path interface {
 	Name() string
@@ -34,6 +66,8 @@ type Path interface {
 	StopPublisher(req PathStopPublisherReq)
 	RemovePublisher(req PathRemovePublisherReq)
 	RemoveReader(req PathRemoveReaderReq)
+	AddEvent(level logger.Level, format string, args ...interface{})
+	AddDecodeError(message string)
 }
 
 // PathAccessRequest is a path access request.
@@ -109,6 +143,7 @@ type PathDescribeReq struct {
 // PathAddPublisherRes contains the response of AddPublisher().
 type PathAddPublisherRes struct {
 	Path Path
+	User string // user the publisher authenticated with
 	Err  error
 }
 
@@ -116,7 +151,20 @@ type PathAddPublisherRes struct {
 type PathAddPublisherReq struct {
 	Author        Publisher
 	AccessRequest PathAccessRequest
-	Res           chan PathAddPublisherRes
+
+	// reject paths that are matched by a regular expression
+	// instead of being listed by name.
+	NoDynamicPaths bool
+
+	// called with the path configuration before authentication,
+	// in order to reject requests early.
+	CheckConf func(*conf.Path) error
+
+	// additional environment variables of the publisher,
+	// passed to hooks of the path.
+	ExternalCmdEnv externalcmd.Environment
+
+	Res chan PathAddPublisherRes
 }
 
 // PathRemovePublisherReq contains arguments of RemovePublisher().
@@ -156,7 +204,15 @@ type PathAddReaderRes struct {
 type PathAddReaderReq struct {
 	Author        Reader
 	AccessRequest PathAccessRequest
-	Res           chan PathAddReaderRes
+
+	// called with the path configuration before authentication,
+	// in order to reject requests early.
+	CheckConf func(*conf.Path) error
+
+	// whether the reader reads the proxy track instead of the source one.
+	Proxy bool
+
+	Res chan PathAddReaderRes
 }
 
 // PathRemoveReaderReq contains arguments of RemoveReader().
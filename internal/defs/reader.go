@@ -1,7 +1,38 @@
 package defs
 
+import (
+	"fmt"
+	"time"
+)
+
 // Reader is an entity that can read a stream.
 type Reader interface {
 	Close()
 	APIReaderDescribe() APIPathSourceOrReader
 }
+
+// ReaderLinkStats contains statistics about the link of a reader.
+type ReaderLinkStats struct {
+	RTT                  time.Duration
+	PacketsSent          uint64
+	PacketsLost          uint64
+	PacketsRetransmitted uint64
+}
+
+// LinkStatsReader is a reader that is able to report statistics about its link.
+type LinkStatsReader interface {
+	LinkStats() (ReaderLinkStats, bool)
+}
+
+// ParseReaderQuality parses the quality requested by a reader.
+// It returns true when the reader asks for the proxy track.
+func ParseReaderQuality(raw string) (bool, error) {
+	switch raw {
+	case "", "source":
+		return false, nil
+
+	case "proxy":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid quality '%s'", raw)
+}
@@ -0,0 +1,15 @@
+package defs
+
+import "strings"
+
+// IsSecretKey checks whether a key provided by a client (i.e. a query parameter)
+// is likely to contain a secret, that must not be printed.
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"pass", "pwd", "secret", "token", "key", "auth"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
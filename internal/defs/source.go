@@ -3,6 +3,7 @@ package defs
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
@@ -20,6 +21,11 @@ type Source interface {
 	APISourceDescribe() APIPathSourceOrReader
 }
 
+// LinkRTTSource is a source that is able to report the round-trip time of its link.
+type LinkRTTSource interface {
+	LinkRTT() (time.Duration, bool)
+}
+
 // FormatsToCodecs returns the name of codecs of given formats.
 func FormatsToCodecs(formats []format.Format) []string {
 	ret := make([]string, len(formats))
@@ -28,3 +28,19 @@ type StaticSourceRunParams struct {
 	Conf           *conf.Path
 	ReloadConf     chan *conf.Path
 }
+
+// StaticSourcePermanentError is an error of a static source that can't be solved by retrying,
+// like a missing resource or unsupported codecs.
+type StaticSourcePermanentError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e StaticSourcePermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap implements the error interface.
+func (e StaticSourcePermanentError) Unwrap() error {
+	return e.Err
+}
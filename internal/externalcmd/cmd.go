@@ -4,6 +4,7 @@ package externalcmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"time"
 )
@@ -26,10 +27,15 @@ type Cmd struct {
 	cmdstr  string
 	restart bool
 	env     Environment
+	stdin   io.Reader
+	stdout  io.Writer
 	onExit  func(error)
 
 	// in
 	terminate chan struct{}
+
+	// out
+	done chan struct{}
 }
 
 // NewCmd allocates a Cmd.
@@ -39,6 +45,33 @@ func NewCmd(
 	restart bool,
 	env Environment,
 	onExit OnExitFunc,
+) *Cmd {
+	return newCmd(pool, cmdstr, restart, env, nil, nil, onExit)
+}
+
+// NewPipedCmd allocates a Cmd whose standard input and output are
+// connected to the given reader and writer.
+// The command is never restarted, and onExit is called every time
+// the command exits, unless it is closed.
+func NewPipedCmd(
+	pool *Pool,
+	cmdstr string,
+	env Environment,
+	stdin io.Reader,
+	stdout io.Writer,
+	onExit OnExitFunc,
+) *Cmd {
+	return newCmd(pool, cmdstr, false, env, stdin, stdout, onExit)
+}
+
+func newCmd(
+	pool *Pool,
+	cmdstr string,
+	restart bool,
+	env Environment,
+	stdin io.Reader,
+	stdout io.Writer,
+	onExit OnExitFunc,
 ) *Cmd {
 	// replace variables in both Linux and Windows, in order to allow using the
 	// same commands on both of them.
@@ -58,8 +91,11 @@ func NewCmd(
 		cmdstr:    cmdstr,
 		restart:   restart,
 		env:       env,
+		stdin:     stdin,
+		stdout:    stdout,
 		onExit:    onExit,
 		terminate: make(chan struct{}),
+		done:      make(chan struct{}),
 	}
 
 	pool.wg.Add(1)
@@ -74,8 +110,14 @@ func (e *Cmd) Close() {
 	close(e.terminate)
 }
 
+// Wait waits for the command to exit.
+func (e *Cmd) Wait() {
+	<-e.done
+}
+
 func (e *Cmd) run() {
 	defer e.pool.wg.Done()
+	defer close(e.done)
 
 	env := append([]string(nil), os.Environ()...)
 	for key, val := range e.env {
@@ -91,6 +133,8 @@ func (e *Cmd) run() {
 		if !e.restart {
 			if err != nil {
 				e.onExit(err)
+			} else if e.stdout != nil {
+				e.onExit(fmt.Errorf("command exited with code 0"))
 			}
 			return
 		}
@@ -21,7 +21,21 @@ func (e *Cmd) runOSSpecific(env []string) error {
 	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
 
 	cmd.Env = env
+
+	var sp *stdinPipe
+	if e.stdin != nil {
+		sp = &stdinPipe{r: e.stdin}
+		err = sp.initialize(cmd)
+		if err != nil {
+			return err
+		}
+		defer sp.close()
+	}
+
 	cmd.Stdout = os.Stdout
+	if e.stdout != nil {
+		cmd.Stdout = e.stdout
+	}
 	cmd.Stderr = os.Stderr
 
 	// set process group in order to allow killing subprocesses
@@ -32,6 +46,10 @@ func (e *Cmd) runOSSpecific(env []string) error {
 		return err
 	}
 
+	if sp != nil {
+		sp.start()
+	}
+
 	cmdDone := make(chan int)
 	go func() {
 		cmdDone <- func() int {
@@ -80,7 +80,21 @@ func (e *Cmd) runOSSpecific(env []string) error {
 	}
 
 	cmd.Env = env
+
+	var sp *stdinPipe
+	if e.stdin != nil {
+		sp = &stdinPipe{r: e.stdin}
+		err := sp.initialize(cmd)
+		if err != nil {
+			return err
+		}
+		defer sp.close()
+	}
+
 	cmd.Stdout = os.Stdout
+	if e.stdout != nil {
+		cmd.Stdout = e.stdout
+	}
 	cmd.Stderr = os.Stderr
 
 	// create a process group to kill all subprocesses
@@ -94,6 +108,10 @@ func (e *Cmd) runOSSpecific(env []string) error {
 		return err
 	}
 
+	if sp != nil {
+		sp.start()
+	}
+
 	err = addProcessToGroup(g, cmd.Process)
 	if err != nil {
 		return err
@@ -0,0 +1,62 @@
+package externalcmd
+
+import (
+	"time"
+)
+
+// Queue runs commands that perform a task and then exit,
+// limiting the number of commands that run at the same time.
+// A command that fails is run again, up to MaxRetries times.
+type Queue struct {
+	Pool          *Pool
+	MaxConcurrent int
+	MaxRetries    int
+
+	retryPause time.Duration
+
+	slots chan struct{}
+}
+
+// Initialize initializes Queue.
+func (q *Queue) Initialize() {
+	if q.retryPause == 0 {
+		q.retryPause = restartPause
+	}
+
+	q.slots = make(chan struct{}, q.MaxConcurrent)
+}
+
+// Run runs a command in the background, as soon as a slot is available.
+// onExit is called once, when the command succeeds or after the last attempt fails.
+// In case of success, onExit is called with a nil error.
+func (q *Queue) Run(cmdstr string, env Environment, onExit OnExitFunc) {
+	if onExit == nil {
+		onExit = func(_ error) {}
+	}
+
+	q.Pool.wg.Add(1)
+
+	go q.run(cmdstr, env, onExit)
+}
+
+func (q *Queue) run(cmdstr string, env Environment, onExit OnExitFunc) {
+	defer q.Pool.wg.Done()
+
+	q.slots <- struct{}{}
+	defer func() { <-q.slots }()
+
+	for attempt := 0; ; attempt++ {
+		var err error
+		c := NewCmd(q.Pool, cmdstr, false, env, func(cerr error) {
+			err = cerr
+		})
+		c.Wait()
+
+		if err == nil || attempt >= q.MaxRetries {
+			onExit(err)
+			return
+		}
+
+		time.Sleep(q.retryPause)
+	}
+}
@@ -0,0 +1,40 @@
+package externalcmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+)
+
+// stdinPipe connects a reader to the standard input of a command through an OS pipe.
+// Unlike assigning the reader to exec.Cmd.Stdin, this allows Wait() to return
+// as soon as the command exits, without waiting for a pending read to complete.
+type stdinPipe struct {
+	r io.Reader
+
+	pr *os.File
+	pw *os.File
+}
+
+func (p *stdinPipe) initialize(cmd *exec.Cmd) error {
+	var err error
+	p.pr, p.pw, err = os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	cmd.Stdin = p.pr
+	return nil
+}
+
+// start must be called after the command has been started.
+func (p *stdinPipe) start() {
+	p.pr.Close()
+
+	go io.Copy(p.pw, p.r) //nolint:errcheck
+}
+
+func (p *stdinPipe) close() {
+	p.pr.Close()
+	p.pw.Close()
+}
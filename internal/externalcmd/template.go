@@ -0,0 +1,48 @@
+package externalcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// ExpandArgs replaces placeholders inside the arguments of a command.
+// Placeholders are in the form %c, where c is a key of values, while %% is replaced with %.
+// The command is split into arguments before replacing placeholders, therefore
+// a value is always passed to the command as part of a single argument,
+// even when it contains spaces or quotes.
+func ExpandArgs(cmdstr string, values map[byte]string) (string, error) {
+	parts, err := shellquote.Split(cmdstr)
+	if err != nil {
+		return "", err
+	}
+
+	for i, part := range parts {
+		var b strings.Builder
+
+		for j := 0; j < len(part); j++ {
+			if part[j] != '%' || j == (len(part)-1) {
+				b.WriteByte(part[j])
+				continue
+			}
+
+			j++
+
+			if part[j] == '%' {
+				b.WriteByte('%')
+				continue
+			}
+
+			v, ok := values[part[j]]
+			if !ok {
+				return "", fmt.Errorf("unknown placeholder: %%%c", part[j])
+			}
+			b.WriteString(v)
+		}
+
+		parts[i] = b.String()
+	}
+
+	return shellquote.Join(parts...), nil
+}
@@ -17,6 +17,7 @@ type OnConnectParams struct {
 	RunOnDisconnect     string
 	RTSPAddress         string
 	Desc                defs.APIPathSourceOrReader
+	Query               string
 }
 
 // OnConnect is the OnConnect hook.
@@ -30,6 +31,7 @@ func OnConnect(params OnConnectParams) func() {
 			"RTSP_PORT":     port,
 			"MTX_CONN_TYPE": params.Desc.Type,
 			"MTX_CONN_ID":   params.Desc.ID,
+			"MTX_QUERY":     params.Query,
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gookit/color"
@@ -12,48 +13,124 @@ import (
 
 // Logger is a log handler.
 type Logger struct {
-	level Level
+	level atomic.Int32
 
 	destinations []destination
+	router       pathRouter
+	sinks        map[*PathSink]struct{}
 	mutex        sync.Mutex
 }
 
-// New allocates a log handler.
-func New(level Level, destinations []Destination, filePath string) (*Logger, error) {
-	lh := &Logger{
-		level: level,
-	}
+func newDestinations(destinations []Destination, filePath string) ([]destination, error) {
+	var ret []destination
 
 	for _, destType := range destinations {
 		switch destType {
 		case DestinationStdout:
-			lh.destinations = append(lh.destinations, newDestionationStdout())
+			ret = append(ret, newDestionationStdout())
 
 		case DestinationFile:
 			dest, err := newDestinationFile(filePath)
 			if err != nil {
-				lh.Close()
+				closeDestinations(ret)
 				return nil, err
 			}
-			lh.destinations = append(lh.destinations, dest)
+			ret = append(ret, dest)
 
 		case DestinationSyslog:
 			dest, err := newDestinationSyslog()
 			if err != nil {
-				lh.Close()
+				closeDestinations(ret)
 				return nil, err
 			}
-			lh.destinations = append(lh.destinations, dest)
+			ret = append(ret, dest)
 		}
 	}
 
+	return ret, nil
+}
+
+func closeDestinations(destinations []destination) {
+	for _, dest := range destinations {
+		dest.close()
+	}
+}
+
+// New allocates a log handler.
+func New(level Level, destinations []Destination, filePath string) (*Logger, error) {
+	dests, err := newDestinations(destinations, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	lh := &Logger{
+		destinations: dests,
+		router:       pathRouter{conns: make(map[string]string)},
+		sinks:        make(map[*PathSink]struct{}),
+	}
+	lh.level.Store(int32(level))
+
 	return lh, nil
 }
 
 // Close closes a log handler.
 func (lh *Logger) Close() {
-	for _, dest := range lh.destinations {
-		dest.close()
+	lh.mutex.Lock()
+	defer lh.mutex.Unlock()
+
+	closeDestinations(lh.destinations)
+	lh.destinations = nil
+}
+
+// SetLevel changes the minimum level of entries that are written.
+// It can be called while the log handler is in use.
+func (lh *Logger) SetLevel(level Level) {
+	lh.level.Store(int32(level))
+}
+
+// SetDestinations replaces the destinations of log entries.
+// It can be called while the log handler is in use.
+// In case of errors, existing destinations are left untouched.
+func (lh *Logger) SetDestinations(destinations []Destination, filePath string) error {
+	dests, err := newDestinations(destinations, filePath)
+	if err != nil {
+		return err
+	}
+
+	lh.mutex.Lock()
+	defer lh.mutex.Unlock()
+
+	closeDestinations(lh.destinations)
+	lh.destinations = dests
+
+	return nil
+}
+
+// AddPathSink adds a sink that receives log lines related to a path,
+// buffering up to bufferSize lines.
+// It can be called while the log handler is in use.
+func (lh *Logger) AddPathSink(pathName string, bufferSize int) *PathSink {
+	s := &PathSink{
+		pathName: pathName,
+		ch:       make(chan string, bufferSize),
+	}
+
+	lh.mutex.Lock()
+	defer lh.mutex.Unlock()
+
+	lh.sinks[s] = struct{}{}
+
+	return s
+}
+
+// RemovePathSink removes a sink.
+func (lh *Logger) RemovePathSink(s *PathSink) {
+	lh.mutex.Lock()
+	defer lh.mutex.Unlock()
+
+	if _, ok := lh.sinks[s]; ok {
+		delete(lh.sinks, s)
+		close(s.ch)
 	}
 }
 
@@ -142,16 +219,52 @@ func writeContent(buf *bytes.Buffer, format string, args []interface{}) {
 
 // Log writes a log entry.
 func (lh *Logger) Log(level Level, format string, args ...interface{}) {
-	if level < lh.level {
+	// associations between connections and paths are tracked regardless of level
+	updateRouter := lh.router.mayUpdate(format)
+	minLevel := Level(lh.level.Load())
+
+	if level < minLevel && !updateRouter {
 		return
 	}
 
 	lh.mutex.Lock()
 	defer lh.mutex.Unlock()
 
+	var content string
+	if updateRouter || len(lh.sinks) != 0 {
+		content = fmt.Sprintf(format, args...)
+	}
+
+	if updateRouter {
+		lh.router.bind(content)
+		defer lh.router.unbind(content)
+	}
+
+	if level < minLevel {
+		return
+	}
+
 	t := time.Now()
 
 	for _, dest := range lh.destinations {
 		dest.log(t, level, format, args...)
 	}
+
+	if len(lh.sinks) != 0 {
+		var line string
+
+		for s := range lh.sinks {
+			if lh.router.matches(content, s.pathName) {
+				if line == "" {
+					var buf bytes.Buffer
+					writeTime(&buf, t, false)
+					writeLevel(&buf, level, false)
+					buf.WriteString(content)
+					line = buf.String()
+				}
+
+				s.push(line)
+			}
+		}
+	}
 }
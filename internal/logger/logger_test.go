@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerSetLevel(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "mediamtx.log")
+
+	l, err := New(Info, []Destination{DestinationFile}, fpath)
+	require.NoError(t, err)
+	defer l.Close()
+
+	l.Log(Debug, "first")
+
+	byts, err := os.ReadFile(fpath)
+	require.NoError(t, err)
+	require.NotContains(t, string(byts), "first")
+
+	l.SetLevel(Debug)
+
+	l.Log(Debug, "second")
+
+	byts, err = os.ReadFile(fpath)
+	require.NoError(t, err)
+	require.Contains(t, string(byts), "DEB second\n")
+}
+
+func TestLoggerSetDestinations(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fpath1 := filepath.Join(dir, "first.log")
+	fpath2 := filepath.Join(dir, "second.log")
+
+	l, err := New(Info, []Destination{DestinationFile}, fpath1)
+	require.NoError(t, err)
+	defer l.Close()
+
+	err = l.SetDestinations([]Destination{DestinationFile}, fpath2)
+	require.NoError(t, err)
+
+	l.Log(Info, "test")
+
+	byts, err := os.ReadFile(fpath1)
+	require.NoError(t, err)
+	require.Empty(t, byts)
+
+	byts, err = os.ReadFile(fpath2)
+	require.NoError(t, err)
+	require.Contains(t, string(byts), "INF test\n")
+
+	err = l.SetDestinations([]Destination{DestinationFile}, filepath.Join(dir, "nonexisting", "file.log"))
+	require.Error(t, err)
+
+	l.Log(Info, "after error")
+
+	byts, err = os.ReadFile(fpath2)
+	require.NoError(t, err)
+	require.Contains(t, string(byts), "INF after error\n")
+}
+
+func TestLoggerPathSink(t *testing.T) {
+	l, err := New(Info, nil, "")
+	require.NoError(t, err)
+	defer l.Close()
+
+	s := l.AddPathSink("mypath", 10)
+	defer l.RemovePathSink(s)
+
+	l.Log(Info, "[path mypath] ready")
+	l.Log(Info, "[path otherpath] ready")
+	l.Log(Info, "[RTSP] [session 1234] is reading from path '%s', 1 track", "mypath")
+	l.Log(Info, "[RTSP] [session 5678] is reading from path '%s', 1 track", "otherpath")
+	l.Log(Info, "[RTSP] [session 1234] something")
+	l.Log(Info, "[RTSP] [session 5678] something")
+	l.Log(Info, "[HLS] [muxer mypath] created")
+	l.Log(Info, "[RTSP] [session 1234] destroyed: %v", "terminated")
+	l.Log(Info, "[RTSP] [session 1234] after close")
+	l.Log(Debug, "[path mypath] debug")
+
+	var lines []string
+	for i := 0; i < 5; i++ {
+		line := <-s.Lines()
+		lines = append(lines, line[len("2010/01/01 00:00:00 "):])
+	}
+
+	require.Equal(t, []string{
+		"INF [path mypath] ready",
+		"INF [RTSP] [session 1234] is reading from path 'mypath', 1 track",
+		"INF [RTSP] [session 1234] something",
+		"INF [HLS] [muxer mypath] created",
+		"INF [RTSP] [session 1234] destroyed: terminated",
+	}, lines)
+
+	select {
+	case line := <-s.Lines():
+		t.Errorf("unexpected line: %v", line)
+	default:
+	}
+}
+
+func TestLoggerPathSinkDrop(t *testing.T) {
+	l, err := New(Info, nil, "")
+	require.NoError(t, err)
+	defer l.Close()
+
+	s := l.AddPathSink("mypath", 2)
+	defer l.RemovePathSink(s)
+
+	for i := 0; i < 5; i++ {
+		l.Log(Info, "[path mypath] line %d", i)
+	}
+
+	require.Contains(t, <-s.Lines(), "line 0")
+	require.Contains(t, <-s.Lines(), "line 1")
+
+	l.Log(Info, "[path mypath] line 5")
+
+	require.Equal(t, "(3 lines dropped)", <-s.Lines())
+	require.Contains(t, <-s.Lines(), "line 5")
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathSink receives log lines related to a path.
+// Lines are buffered. When the buffer is full, lines are dropped
+// and a marker with the number of dropped lines is emitted as soon as there's room again.
+type PathSink struct {
+	pathName string
+	ch       chan string
+	dropped  int
+}
+
+// Lines returns a channel that receives log lines.
+// The channel is closed when the sink is removed.
+func (s *PathSink) Lines() <-chan string {
+	return s.ch
+}
+
+func (s *PathSink) push(line string) {
+	if s.dropped != 0 {
+		select {
+		case s.ch <- fmt.Sprintf("(%d lines dropped)", s.dropped):
+			s.dropped = 0
+		default:
+			s.dropped++
+			return
+		}
+	}
+
+	select {
+	case s.ch <- line:
+	default:
+		s.dropped++
+	}
+}
+
+// connPrefix returns the first two bracketed groups of a line,
+// that identify the connection or session that emitted it (i.e. "[RTSP] [session 12345678] ").
+func connPrefix(line string) (string, string, bool) {
+	i := strings.Index(line, "] ")
+	if i < 0 || line[0] != '[' {
+		return "", "", false
+	}
+
+	j := strings.Index(line[i+2:], "] ")
+	if j < 0 || line[i+2] != '[' {
+		return "", "", false
+	}
+
+	n := i + 2 + j + 2
+	return line[:n], line[n:], true
+}
+
+func quotedPathName(s string, prefix string) (string, bool) {
+	s, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return "", false
+	}
+
+	i := strings.IndexByte(s, '\'')
+	if i < 0 {
+		return "", false
+	}
+
+	return s[:i], true
+}
+
+// pathRouter associates log lines with paths.
+// Lines of paths and HLS muxers contain the path name,
+// while lines of connections and sessions are associated with a path
+// once they start reading from or publishing to it, until they are closed.
+type pathRouter struct {
+	conns map[string]string
+}
+
+// mayUpdate returns whether a log entry with given format may change associations.
+func (r *pathRouter) mayUpdate(format string) bool {
+	return strings.Contains(format, "is reading from path '") ||
+		strings.Contains(format, "is publishing to path '") ||
+		strings.Contains(format, "closed: ") ||
+		strings.Contains(format, "destroyed: ")
+}
+
+// bind associates a connection or session with a path.
+// It must be called before routing the line.
+func (r *pathRouter) bind(line string) {
+	prefix, rest, ok := connPrefix(line)
+	if !ok {
+		return
+	}
+
+	if pathName, ok := quotedPathName(rest, "is reading from path '"); ok {
+		r.conns[prefix] = pathName
+	} else if pathName, ok := quotedPathName(rest, "is publishing to path '"); ok {
+		r.conns[prefix] = pathName
+	}
+}
+
+// unbind removes the association of a closed connection or session.
+// It must be called after routing the line, in order to route the line itself.
+func (r *pathRouter) unbind(line string) {
+	prefix, rest, ok := connPrefix(line)
+	if !ok {
+		return
+	}
+
+	if strings.HasPrefix(rest, "closed: ") || strings.HasPrefix(rest, "destroyed: ") {
+		delete(r.conns, prefix)
+	}
+}
+
+func (r *pathRouter) matches(line string, pathName string) bool {
+	if strings.Contains(line, "[path "+pathName+"] ") ||
+		strings.Contains(line, "[muxer "+pathName+"] ") {
+		return true
+	}
+
+	prefix, _, ok := connPrefix(line)
+	if !ok {
+		return false
+	}
+
+	cur, ok := r.conns[prefix]
+	return ok && cur == pathName
+}
@@ -2,10 +2,16 @@
 package metrics
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"net"
 	"net/http"
 	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/debug"
 	"strconv"
 	"sync"
 	"time"
@@ -24,12 +30,101 @@ func interfaceIsEmpty(i interface{}) bool {
 	return reflect.ValueOf(i).Kind() != reflect.Ptr || reflect.ValueOf(i).IsNil()
 }
 
-func metric(key string, tags string, value int64) string {
-	return key + tags + " " + strconv.FormatInt(value, 10) + "\n"
+type label struct {
+	key   string
+	value string
 }
 
-func metricFloat(key string, tags string, value float64) string {
-	return key + tags + " " + strconv.FormatFloat(value, 'f', -1, 64) + "\n"
+type labels []label
+
+type sample struct {
+	key        string
+	tags       labels
+	value      int64
+	valueFloat float64
+	isFloat    bool
+}
+
+func (s sample) prometheus() string {
+	out := s.key
+
+	if len(s.tags) != 0 {
+		out += "{"
+		for i, t := range s.tags {
+			if i != 0 {
+				out += ","
+			}
+			out += t.key + "=\"" + t.value + "\""
+		}
+		out += "}"
+	}
+
+	if s.isFloat {
+		out += " " + strconv.FormatFloat(s.valueFloat, 'f', -1, 64) + "\n"
+	} else {
+		out += " " + strconv.FormatInt(s.value, 10) + "\n"
+	}
+
+	return out
+}
+
+func metric(key string, tags labels, value int64) sample {
+	return sample{
+		key:   key,
+		tags:  tags,
+		value: value,
+	}
+}
+
+func metricFloat(key string, tags labels, value float64) sample {
+	return sample{
+		key:        key,
+		tags:       tags,
+		valueFloat: value,
+		isFloat:    true,
+	}
+}
+
+func buildInfoLabels(version string) labels {
+	var commit string
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				commit = s.Value
+			}
+		}
+	}
+
+	return labels{
+		{"version", version},
+		{"commit", commit},
+		{"goversion", runtime.Version()},
+	}
+}
+
+// configHash returns a hash of the configuration,
+// that allows to detect differences between configurations of different nodes.
+func configHash(c *conf.Conf) string {
+	byts, _ := json.Marshal(c)
+	h := sha256.Sum256(byts)
+	return hex.EncodeToString(h[:])
+}
+
+// sampleFilter filters samples by path.
+// Samples that are not related to a path are included only when global is true.
+// A nil filter includes every sample.
+type sampleFilter struct {
+	path   *regexp.Regexp
+	global bool
+}
+
+func (f *sampleFilter) includePath(name string) bool {
+	return f == nil || f.path.MatchString(name)
+}
+
+func (f *sampleFilter) includeGlobal() bool {
+	return f == nil || f.global
 }
 
 type metricsAuthManager interface {
@@ -41,7 +136,11 @@ type metricsParent interface {
 }
 
 // Metrics is a metrics provider.
+// Metrics are exposed through a Prometheus-compatible HTTP listener
+// when Address is not empty, and pushed to an OTLP collector
+// when OTLPEndpoint is not empty.
 type Metrics struct {
+	Version        string
 	Address        string
 	Encryption     bool
 	ServerKey      string
@@ -49,11 +148,17 @@ type Metrics struct {
 	AllowOrigin    string
 	TrustedProxies conf.IPNetworks
 	ReadTimeout    conf.StringDuration
+	OTLPEndpoint   string
+	OTLPHeaders    conf.HTTPHeaders
+	OTLPInterval   conf.StringDuration
 	AuthManager    metricsAuthManager
 	Parent         metricsParent
 
 	httpServer   *httpp.Server
+	otlpExporter *otlpExporter
+	buildInfo    labels
 	mutex        sync.Mutex
+	configHash   string
 	pathManager  api.PathManager
 	rtspServer   api.RTSPServer
 	rtspsServer  api.RTSPServer
@@ -66,6 +171,34 @@ type Metrics struct {
 
 // Initialize initializes metrics.
 func (m *Metrics) Initialize() error {
+	m.buildInfo = buildInfoLabels(m.Version)
+
+	if m.Address != "" {
+		err := m.initializeHTTP()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.OTLPEndpoint != "" {
+		m.otlpExporter = &otlpExporter{
+			endpoint: m.OTLPEndpoint,
+			headers:  m.OTLPHeaders,
+			interval: time.Duration(m.OTLPInterval),
+			collect: func() []sample {
+				return m.collect(nil)
+			},
+			parent: m,
+		}
+		m.otlpExporter.initialize()
+
+		m.Log(logger.Info, "pushing metrics to "+m.OTLPEndpoint)
+	}
+
+	return nil
+}
+
+func (m *Metrics) initializeHTTP() error {
 	router := gin.New()
 	router.SetTrustedProxies(m.TrustedProxies.ToTrustedProxies()) //nolint:errcheck
 
@@ -98,8 +231,14 @@ func (m *Metrics) Initialize() error {
 
 // Close closes Metrics.
 func (m *Metrics) Close() {
-	m.Log(logger.Info, "listener is closing")
-	m.httpServer.Close()
+	if m.otlpExporter != nil {
+		m.otlpExporter.close()
+	}
+
+	if m.httpServer != nil {
+		m.Log(logger.Info, "listener is closing")
+		m.httpServer.Close()
+	}
 }
 
 // Log implements logger.Writer.
@@ -142,39 +281,60 @@ func (m *Metrics) middlewareAuth(ctx *gin.Context) {
 	}
 }
 
-func (m *Metrics) onMetrics(ctx *gin.Context) {
-	out := ""
+func (m *Metrics) collect(filter *sampleFilter) []sample {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-	data, err := m.pathManager.APIPathsList()
-	if err == nil && len(data.Items) != 0 {
-		for _, i := range data.Items {
-			var state string
-			if i.Ready {
-				state = "ready"
-			} else {
-				state = "notReady"
-			}
+	var out []sample
 
-			tags := "{name=\"" + i.Name + "\",state=\"" + state + "\"}"
-			out += metric("paths", tags, 1)
-			out += metric("paths_bytes_received", tags, int64(i.BytesReceived))
-			out += metric("paths_bytes_sent", tags, int64(i.BytesSent))
+	if filter.includeGlobal() {
+		out = append(out, metric("build_info", m.buildInfo, 1))
+
+		if m.configHash != "" {
+			out = append(out, metric("config_info", labels{{"hash", m.configHash}}, 1))
+		}
+	}
+
+	if !interfaceIsEmpty(m.pathManager) {
+		data, err := m.pathManager.APIPathsList()
+		if err == nil && len(data.Items) != 0 {
+			for _, i := range data.Items {
+				if !filter.includePath(i.Name) {
+					continue
+				}
+
+				var state string
+				if i.Ready {
+					state = "ready"
+				} else {
+					state = "notReady"
+				}
+
+				tags := labels{{"name", i.Name}, {"state", state}}
+				out = append(out, metric("paths", tags, 1))
+				out = append(out, metric("paths_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metric("paths_bytes_sent", tags, int64(i.BytesSent)))
+			}
+		} else if filter.includeGlobal() {
+			out = append(out, metric("paths", nil, 0))
 		}
-	} else {
-		out += metric("paths", "", 0)
 	}
 
 	if !interfaceIsEmpty(m.hlsManager) {
 		data, err := m.hlsManager.APIMuxersList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{name=\"" + i.Path + "\"}"
-				out += metric("hls_muxers", tags, 1)
-				out += metric("hls_muxers_bytes_sent", tags, int64(i.BytesSent))
+				if !filter.includePath(i.Path) {
+					continue
+				}
+
+				tags := labels{{"name", i.Path}}
+				out = append(out, metric("hls_muxers", tags, 1))
+				out = append(out, metric("hls_muxers_bytes_sent", tags, int64(i.BytesSent)))
 			}
-		} else {
-			out += metric("hls_muxers", "", 0)
-			out += metric("hls_muxers_bytes_sent", "", 0)
+		} else if filter.includeGlobal() {
+			out = append(out, metric("hls_muxers", nil, 0))
+			out = append(out, metric("hls_muxers_bytes_sent", nil, 0))
 		}
 	}
 
@@ -183,15 +343,19 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspServer.APIConnsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\"}"
-					out += metric("rtsp_conns", tags, 1)
-					out += metric("rtsp_conns_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsp_conns_bytes_sent", tags, int64(i.BytesSent))
+					if !filter.includeGlobal() {
+						continue
+					}
+
+					tags := labels{{"id", i.ID.String()}}
+					out = append(out, metric("rtsp_conns", tags, 1))
+					out = append(out, metric("rtsp_conns_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metric("rtsp_conns_bytes_sent", tags, int64(i.BytesSent)))
 				}
-			} else {
-				out += metric("rtsp_conns", "", 0)
-				out += metric("rtsp_conns_bytes_received", "", 0)
-				out += metric("rtsp_conns_bytes_sent", "", 0)
+			} else if filter.includeGlobal() {
+				out = append(out, metric("rtsp_conns", nil, 0))
+				out = append(out, metric("rtsp_conns_bytes_received", nil, 0))
+				out = append(out, metric("rtsp_conns_bytes_sent", nil, 0))
 			}
 		}()
 
@@ -199,15 +363,19 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspServer.APISessionsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-					out += metric("rtsp_sessions", tags, 1)
-					out += metric("rtsp_sessions_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsp_sessions_bytes_sent", tags, int64(i.BytesSent))
+					if !filter.includePath(i.Path) {
+						continue
+					}
+
+					tags := labels{{"id", i.ID.String()}, {"state", string(i.State)}}
+					out = append(out, metric("rtsp_sessions", tags, 1))
+					out = append(out, metric("rtsp_sessions_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metric("rtsp_sessions_bytes_sent", tags, int64(i.BytesSent)))
 				}
-			} else {
-				out += metric("rtsp_sessions", "", 0)
-				out += metric("rtsp_sessions_bytes_received", "", 0)
-				out += metric("rtsp_sessions_bytes_sent", "", 0)
+			} else if filter.includeGlobal() {
+				out = append(out, metric("rtsp_sessions", nil, 0))
+				out = append(out, metric("rtsp_sessions_bytes_received", nil, 0))
+				out = append(out, metric("rtsp_sessions_bytes_sent", nil, 0))
 			}
 		}()
 	}
@@ -217,15 +385,19 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspsServer.APIConnsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\"}"
-					out += metric("rtsps_conns", tags, 1)
-					out += metric("rtsps_conns_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsps_conns_bytes_sent", tags, int64(i.BytesSent))
+					if !filter.includeGlobal() {
+						continue
+					}
+
+					tags := labels{{"id", i.ID.String()}}
+					out = append(out, metric("rtsps_conns", tags, 1))
+					out = append(out, metric("rtsps_conns_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metric("rtsps_conns_bytes_sent", tags, int64(i.BytesSent)))
 				}
-			} else {
-				out += metric("rtsps_conns", "", 0)
-				out += metric("rtsps_conns_bytes_received", "", 0)
-				out += metric("rtsps_conns_bytes_sent", "", 0)
+			} else if filter.includeGlobal() {
+				out = append(out, metric("rtsps_conns", nil, 0))
+				out = append(out, metric("rtsps_conns_bytes_received", nil, 0))
+				out = append(out, metric("rtsps_conns_bytes_sent", nil, 0))
 			}
 		}()
 
@@ -233,15 +405,19 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 			data, err := m.rtspsServer.APISessionsList()
 			if err == nil && len(data.Items) != 0 {
 				for _, i := range data.Items {
-					tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-					out += metric("rtsps_sessions", tags, 1)
-					out += metric("rtsps_sessions_bytes_received", tags, int64(i.BytesReceived))
-					out += metric("rtsps_sessions_bytes_sent", tags, int64(i.BytesSent))
+					if !filter.includePath(i.Path) {
+						continue
+					}
+
+					tags := labels{{"id", i.ID.String()}, {"state", string(i.State)}}
+					out = append(out, metric("rtsps_sessions", tags, 1))
+					out = append(out, metric("rtsps_sessions_bytes_received", tags, int64(i.BytesReceived)))
+					out = append(out, metric("rtsps_sessions_bytes_sent", tags, int64(i.BytesSent)))
 				}
-			} else {
-				out += metric("rtsps_sessions", "", 0)
-				out += metric("rtsps_sessions_bytes_received", "", 0)
-				out += metric("rtsps_sessions_bytes_sent", "", 0)
+			} else if filter.includeGlobal() {
+				out = append(out, metric("rtsps_sessions", nil, 0))
+				out = append(out, metric("rtsps_sessions_bytes_received", nil, 0))
+				out = append(out, metric("rtsps_sessions_bytes_sent", nil, 0))
 			}
 		}()
 	}
@@ -250,15 +426,19 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.rtmpServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("rtmp_conns", tags, 1)
-				out += metric("rtmp_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("rtmp_conns_bytes_sent", tags, int64(i.BytesSent))
+				if !filter.includePath(i.Path) {
+					continue
+				}
+
+				tags := labels{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("rtmp_conns", tags, 1))
+				out = append(out, metric("rtmp_conns_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metric("rtmp_conns_bytes_sent", tags, int64(i.BytesSent)))
 			}
-		} else {
-			out += metric("rtmp_conns", "", 0)
-			out += metric("rtmp_conns_bytes_received", "", 0)
-			out += metric("rtmp_conns_bytes_sent", "", 0)
+		} else if filter.includeGlobal() {
+			out = append(out, metric("rtmp_conns", nil, 0))
+			out = append(out, metric("rtmp_conns_bytes_received", nil, 0))
+			out = append(out, metric("rtmp_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -266,15 +446,19 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.rtmpsServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("rtmps_conns", tags, 1)
-				out += metric("rtmps_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("rtmps_conns_bytes_sent", tags, int64(i.BytesSent))
+				if !filter.includePath(i.Path) {
+					continue
+				}
+
+				tags := labels{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("rtmps_conns", tags, 1))
+				out = append(out, metric("rtmps_conns_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metric("rtmps_conns_bytes_sent", tags, int64(i.BytesSent)))
 			}
-		} else {
-			out += metric("rtmps_conns", "", 0)
-			out += metric("rtmps_conns_bytes_received", "", 0)
-			out += metric("rtmps_conns_bytes_sent", "", 0)
+		} else if filter.includeGlobal() {
+			out = append(out, metric("rtmps_conns", nil, 0))
+			out = append(out, metric("rtmps_conns_bytes_received", nil, 0))
+			out = append(out, metric("rtmps_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -282,64 +466,68 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.srtServer.APIConnsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("srt_conns", tags, 1)
-				out += metric("srt_conns_packets_sent", tags, int64(i.PacketsSent))
-				out += metric("srt_conns_packets_received", tags, int64(i.PacketsReceived))
-				out += metric("srt_conns_packets_sent_unique", tags, int64(i.PacketsSentUnique))
-				out += metric("srt_conns_packets_received_unique", tags, int64(i.PacketsReceivedUnique))
-				out += metric("srt_conns_packets_send_loss", tags, int64(i.PacketsSendLoss))
-				out += metric("srt_conns_packets_received_loss", tags, int64(i.PacketsReceivedLoss))
-				out += metric("srt_conns_packets_retrans", tags, int64(i.PacketsRetrans))
-				out += metric("srt_conns_packets_received_retrans", tags, int64(i.PacketsReceivedRetrans))
-				out += metric("srt_conns_packets_sent_ack", tags, int64(i.PacketsSentACK))
-				out += metric("srt_conns_packets_received_ack", tags, int64(i.PacketsReceivedACK))
-				out += metric("srt_conns_packets_sent_nak", tags, int64(i.PacketsSentNAK))
-				out += metric("srt_conns_packets_received_nak", tags, int64(i.PacketsReceivedNAK))
-				out += metric("srt_conns_packets_sent_km", tags, int64(i.PacketsSentKM))
-				out += metric("srt_conns_packets_received_km", tags, int64(i.PacketsReceivedKM))
-				out += metric("srt_conns_us_snd_duration", tags, int64(i.UsSndDuration))
-				out += metric("srt_conns_packets_send_drop", tags, int64(i.PacketsSendDrop))
-				out += metric("srt_conns_packets_received_drop", tags, int64(i.PacketsReceivedDrop))
-				out += metric("srt_conns_packets_received_undecrypt", tags, int64(i.PacketsReceivedUndecrypt))
-				out += metric("srt_conns_bytes_sent", tags, int64(i.BytesSent))
-				out += metric("srt_conns_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("srt_conns_bytes_sent_unique", tags, int64(i.BytesSentUnique))
-				out += metric("srt_conns_bytes_received_unique", tags, int64(i.BytesReceivedUnique))
-				out += metric("srt_conns_bytes_received_loss", tags, int64(i.BytesReceivedLoss))
-				out += metric("srt_conns_bytes_retrans", tags, int64(i.BytesRetrans))
-				out += metric("srt_conns_bytes_received_retrans", tags, int64(i.BytesReceivedRetrans))
-				out += metric("srt_conns_bytes_send_drop", tags, int64(i.BytesSendDrop))
-				out += metric("srt_conns_bytes_received_drop", tags, int64(i.BytesReceivedDrop))
-				out += metric("srt_conns_bytes_received_undecrypt", tags, int64(i.BytesReceivedUndecrypt))
-				out += metricFloat("srt_conns_us_packets_send_period", tags, i.UsPacketsSendPeriod)
-				out += metric("srt_conns_packets_flow_window", tags, int64(i.PacketsFlowWindow))
-				out += metric("srt_conns_packets_flight_size", tags, int64(i.PacketsFlightSize))
-				out += metricFloat("srt_conns_ms_rtt", tags, i.MsRTT)
-				out += metricFloat("srt_conns_mbps_send_rate", tags, i.MbpsSendRate)
-				out += metricFloat("srt_conns_mbps_receive_rate", tags, i.MbpsReceiveRate)
-				out += metricFloat("srt_conns_mbps_link_capacity", tags, i.MbpsLinkCapacity)
-				out += metric("srt_conns_bytes_avail_send_buf", tags, int64(i.BytesAvailSendBuf))
-				out += metric("srt_conns_bytes_avail_receive_buf", tags, int64(i.BytesAvailReceiveBuf))
-				out += metricFloat("srt_conns_mbps_max_bw", tags, i.MbpsMaxBW)
-				out += metric("srt_conns_bytes_mss", tags, int64(i.ByteMSS))
-				out += metric("srt_conns_packets_send_buf", tags, int64(i.PacketsSendBuf))
-				out += metric("srt_conns_bytes_send_buf", tags, int64(i.BytesSendBuf))
-				out += metric("srt_conns_ms_send_buf", tags, int64(i.MsSendBuf))
-				out += metric("srt_conns_ms_send_tsb_pd_delay", tags, int64(i.MsSendTsbPdDelay))
-				out += metric("srt_conns_packets_receive_buf", tags, int64(i.PacketsReceiveBuf))
-				out += metric("srt_conns_bytes_receive_buf", tags, int64(i.BytesReceiveBuf))
-				out += metric("srt_conns_ms_receive_buf", tags, int64(i.MsReceiveBuf))
-				out += metric("srt_conns_ms_receive_tsb_pd_delay", tags, int64(i.MsReceiveTsbPdDelay))
-				out += metric("srt_conns_packets_reorder_tolerance", tags, int64(i.PacketsReorderTolerance))
-				out += metric("srt_conns_packets_received_avg_belated_time", tags, int64(i.PacketsReceivedAvgBelatedTime))
-				out += metricFloat("srt_conns_packets_send_loss_rate", tags, i.PacketsSendLossRate)
-				out += metricFloat("srt_conns_packets_received_loss_rate", tags, i.PacketsReceivedLossRate)
+				if !filter.includePath(i.Path) {
+					continue
+				}
+
+				tags := labels{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("srt_conns", tags, 1))
+				out = append(out, metric("srt_conns_packets_sent", tags, int64(i.PacketsSent)))
+				out = append(out, metric("srt_conns_packets_received", tags, int64(i.PacketsReceived)))
+				out = append(out, metric("srt_conns_packets_sent_unique", tags, int64(i.PacketsSentUnique)))
+				out = append(out, metric("srt_conns_packets_received_unique", tags, int64(i.PacketsReceivedUnique)))
+				out = append(out, metric("srt_conns_packets_send_loss", tags, int64(i.PacketsSendLoss)))
+				out = append(out, metric("srt_conns_packets_received_loss", tags, int64(i.PacketsReceivedLoss)))
+				out = append(out, metric("srt_conns_packets_retrans", tags, int64(i.PacketsRetrans)))
+				out = append(out, metric("srt_conns_packets_received_retrans", tags, int64(i.PacketsReceivedRetrans)))
+				out = append(out, metric("srt_conns_packets_sent_ack", tags, int64(i.PacketsSentACK)))
+				out = append(out, metric("srt_conns_packets_received_ack", tags, int64(i.PacketsReceivedACK)))
+				out = append(out, metric("srt_conns_packets_sent_nak", tags, int64(i.PacketsSentNAK)))
+				out = append(out, metric("srt_conns_packets_received_nak", tags, int64(i.PacketsReceivedNAK)))
+				out = append(out, metric("srt_conns_packets_sent_km", tags, int64(i.PacketsSentKM)))
+				out = append(out, metric("srt_conns_packets_received_km", tags, int64(i.PacketsReceivedKM)))
+				out = append(out, metric("srt_conns_us_snd_duration", tags, int64(i.UsSndDuration)))
+				out = append(out, metric("srt_conns_packets_send_drop", tags, int64(i.PacketsSendDrop)))
+				out = append(out, metric("srt_conns_packets_received_drop", tags, int64(i.PacketsReceivedDrop)))
+				out = append(out, metric("srt_conns_packets_received_undecrypt", tags, int64(i.PacketsReceivedUndecrypt)))
+				out = append(out, metric("srt_conns_bytes_sent", tags, int64(i.BytesSent)))
+				out = append(out, metric("srt_conns_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metric("srt_conns_bytes_sent_unique", tags, int64(i.BytesSentUnique)))
+				out = append(out, metric("srt_conns_bytes_received_unique", tags, int64(i.BytesReceivedUnique)))
+				out = append(out, metric("srt_conns_bytes_received_loss", tags, int64(i.BytesReceivedLoss)))
+				out = append(out, metric("srt_conns_bytes_retrans", tags, int64(i.BytesRetrans)))
+				out = append(out, metric("srt_conns_bytes_received_retrans", tags, int64(i.BytesReceivedRetrans)))
+				out = append(out, metric("srt_conns_bytes_send_drop", tags, int64(i.BytesSendDrop)))
+				out = append(out, metric("srt_conns_bytes_received_drop", tags, int64(i.BytesReceivedDrop)))
+				out = append(out, metric("srt_conns_bytes_received_undecrypt", tags, int64(i.BytesReceivedUndecrypt)))
+				out = append(out, metricFloat("srt_conns_us_packets_send_period", tags, i.UsPacketsSendPeriod))
+				out = append(out, metric("srt_conns_packets_flow_window", tags, int64(i.PacketsFlowWindow)))
+				out = append(out, metric("srt_conns_packets_flight_size", tags, int64(i.PacketsFlightSize)))
+				out = append(out, metricFloat("srt_conns_ms_rtt", tags, i.MsRTT))
+				out = append(out, metricFloat("srt_conns_mbps_send_rate", tags, i.MbpsSendRate))
+				out = append(out, metricFloat("srt_conns_mbps_receive_rate", tags, i.MbpsReceiveRate))
+				out = append(out, metricFloat("srt_conns_mbps_link_capacity", tags, i.MbpsLinkCapacity))
+				out = append(out, metric("srt_conns_bytes_avail_send_buf", tags, int64(i.BytesAvailSendBuf)))
+				out = append(out, metric("srt_conns_bytes_avail_receive_buf", tags, int64(i.BytesAvailReceiveBuf)))
+				out = append(out, metricFloat("srt_conns_mbps_max_bw", tags, i.MbpsMaxBW))
+				out = append(out, metric("srt_conns_bytes_mss", tags, int64(i.ByteMSS)))
+				out = append(out, metric("srt_conns_packets_send_buf", tags, int64(i.PacketsSendBuf)))
+				out = append(out, metric("srt_conns_bytes_send_buf", tags, int64(i.BytesSendBuf)))
+				out = append(out, metric("srt_conns_ms_send_buf", tags, int64(i.MsSendBuf)))
+				out = append(out, metric("srt_conns_ms_send_tsb_pd_delay", tags, int64(i.MsSendTsbPdDelay)))
+				out = append(out, metric("srt_conns_packets_receive_buf", tags, int64(i.PacketsReceiveBuf)))
+				out = append(out, metric("srt_conns_bytes_receive_buf", tags, int64(i.BytesReceiveBuf)))
+				out = append(out, metric("srt_conns_ms_receive_buf", tags, int64(i.MsReceiveBuf)))
+				out = append(out, metric("srt_conns_ms_receive_tsb_pd_delay", tags, int64(i.MsReceiveTsbPdDelay)))
+				out = append(out, metric("srt_conns_packets_reorder_tolerance", tags, int64(i.PacketsReorderTolerance)))
+				out = append(out, metric("srt_conns_packets_received_avg_belated_time", tags, int64(i.PacketsReceivedAvgBelatedTime)))
+				out = append(out, metricFloat("srt_conns_packets_send_loss_rate", tags, i.PacketsSendLossRate))
+				out = append(out, metricFloat("srt_conns_packets_received_loss_rate", tags, i.PacketsReceivedLossRate))
 			}
-		} else {
-			out += metric("srt_conns", "", 0)
-			out += metric("srt_conns_bytes_received", "", 0)
-			out += metric("srt_conns_bytes_sent", "", 0)
+		} else if filter.includeGlobal() {
+			out = append(out, metric("srt_conns", nil, 0))
+			out = append(out, metric("srt_conns_bytes_received", nil, 0))
+			out = append(out, metric("srt_conns_bytes_sent", nil, 0))
 		}
 	}
 
@@ -347,22 +535,61 @@ func (m *Metrics) onMetrics(ctx *gin.Context) {
 		data, err := m.webRTCServer.APISessionsList()
 		if err == nil && len(data.Items) != 0 {
 			for _, i := range data.Items {
-				tags := "{id=\"" + i.ID.String() + "\",state=\"" + string(i.State) + "\"}"
-				out += metric("webrtc_sessions", tags, 1)
-				out += metric("webrtc_sessions_bytes_received", tags, int64(i.BytesReceived))
-				out += metric("webrtc_sessions_bytes_sent", tags, int64(i.BytesSent))
+				if !filter.includePath(i.Path) {
+					continue
+				}
+
+				tags := labels{{"id", i.ID.String()}, {"state", string(i.State)}}
+				out = append(out, metric("webrtc_sessions", tags, 1))
+				out = append(out, metric("webrtc_sessions_bytes_received", tags, int64(i.BytesReceived)))
+				out = append(out, metric("webrtc_sessions_bytes_sent", tags, int64(i.BytesSent)))
 			}
-		} else {
-			out += metric("webrtc_sessions", "", 0)
-			out += metric("webrtc_sessions_bytes_received", "", 0)
-			out += metric("webrtc_sessions_bytes_sent", "", 0)
+		} else if filter.includeGlobal() {
+			out = append(out, metric("webrtc_sessions", nil, 0))
+			out = append(out, metric("webrtc_sessions_bytes_received", nil, 0))
+			out = append(out, metric("webrtc_sessions_bytes_sent", nil, 0))
+		}
+	}
+
+	return out
+}
+
+func (m *Metrics) onMetrics(ctx *gin.Context) {
+	var filter *sampleFilter
+
+	// like in Prometheus, the regular expression must match the entire path name
+	if q := ctx.Query("path"); q != "" {
+		re, err := regexp.Compile("^(?:" + q + ")$")
+		if err != nil {
+			ctx.Writer.WriteHeader(http.StatusBadRequest)
+			io.WriteString(ctx.Writer, "invalid path filter: "+err.Error()) //nolint:errcheck
+			return
+		}
+
+		filter = &sampleFilter{
+			path:   re,
+			global: ctx.Query("global") == "true",
 		}
 	}
 
+	out := ""
+	for _, s := range m.collect(filter) {
+		out += s.prometheus()
+	}
+
 	ctx.Writer.WriteHeader(http.StatusOK)
 	io.WriteString(ctx.Writer, out) //nolint:errcheck
 }
 
+// SetConf is called by core.
+func (m *Metrics) SetConf(c *conf.Conf) {
+	h := configHash(c)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.configHash = h
+}
+
 // SetPathManager is called by core.
 func (m *Metrics) SetPathManager(s api.PathManager) {
 	m.mutex.Lock()
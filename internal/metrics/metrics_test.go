@@ -1,12 +1,20 @@
 package metrics
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/stretchr/testify/require"
 )
@@ -47,3 +55,310 @@ func TestPreflightRequest(t *testing.T) {
 	require.Equal(t, "Authorization", res.Header.Get("Access-Control-Allow-Headers"))
 	require.Equal(t, byts, []byte{})
 }
+
+type dummyPathManager struct {
+	items []*defs.APIPath
+}
+
+func (pm *dummyPathManager) APIPathsList() (*defs.APIPathList, error) {
+	if pm.items != nil {
+		return &defs.APIPathList{Items: pm.items}, nil
+	}
+
+	return &defs.APIPathList{
+		Items: []*defs.APIPath{{
+			Name:          "mypath",
+			Ready:         true,
+			BytesReceived: 123,
+			BytesSent:     456,
+		}},
+	}, nil
+}
+
+func (*dummyPathManager) APIPathsGet(string) (*defs.APIPath, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsEnable(string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsDisable(string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsRecordStart(string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsRecordStop(string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsRecordRotate(string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsEvents(string) (*defs.APIPathEventList, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsHistory(string, defs.APIPathHistoryMetric) (*defs.APIPathHistory, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsReaderHealth(string) (*defs.APIPathReaderHealth, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsCounters(string, bool) (*defs.APIPathCounters, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*dummyPathManager) APIPathsConf(string) (*conf.Path, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func TestBuildAndConfigInfo(t *testing.T) {
+	m := Metrics{
+		Version:     "v1.2.3",
+		Address:     "localhost:9998",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		AuthManager: test.NilAuthManager,
+		Parent:      test.NilLogger,
+	}
+	err := m.Initialize()
+	require.NoError(t, err)
+	defer m.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	get := func() string {
+		res, err2 := hc.Get("http://localhost:9998/metrics")
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		byts, err2 := io.ReadAll(res.Body)
+		require.NoError(t, err2)
+		return string(byts)
+	}
+
+	c1 := &conf.Conf{LogLevel: conf.LogLevel(logger.Info)}
+	m.SetConf(c1)
+
+	out := get()
+	require.Regexp(t, `^build_info\{version="v1\.2\.3",commit="[0-9a-f]*",goversion="go.+?"\} 1`+"\n"+
+		`config_info\{hash="[0-9a-f]{64}"\} 1`+"\n$", out)
+
+	hash1 := out[strings.Index(out, "config_info"):]
+
+	c2 := &conf.Conf{LogLevel: conf.LogLevel(logger.Debug)}
+	m.SetConf(c2)
+
+	out = get()
+	require.NotEqual(t, hash1, out[strings.Index(out, "config_info"):])
+
+	m.SetConf(c1)
+
+	out = get()
+	require.Equal(t, hash1, out[strings.Index(out, "config_info"):])
+}
+
+func TestPathFilter(t *testing.T) {
+	m := Metrics{
+		Version:     "v1.2.3",
+		Address:     "localhost:9998",
+		ReadTimeout: conf.StringDuration(10 * time.Second),
+		AuthManager: test.NilAuthManager,
+		Parent:      test.NilLogger,
+	}
+	err := m.Initialize()
+	require.NoError(t, err)
+	defer m.Close()
+
+	m.SetPathManager(&dummyPathManager{items: []*defs.APIPath{
+		{Name: "tenant1/cam1", Ready: true, BytesReceived: 1},
+		{Name: "tenant1/cam2", Ready: true, BytesReceived: 2},
+		{Name: "tenant2/cam1", Ready: true, BytesReceived: 3},
+		{Name: "other/tenant1/cam1", Ready: true, BytesReceived: 4},
+	}})
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	get := func(query string) (int, string) {
+		res, err2 := hc.Get("http://localhost:9998/metrics" + query)
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		byts, err2 := io.ReadAll(res.Body)
+		require.NoError(t, err2)
+		return res.StatusCode, string(byts)
+	}
+
+	t.Run("full", func(t *testing.T) {
+		code, out := get("")
+		require.Equal(t, http.StatusOK, code)
+		require.Contains(t, out, "build_info{")
+		for _, name := range []string{"tenant1/cam1", "tenant1/cam2", "tenant2/cam1", "other/tenant1/cam1"} {
+			require.Contains(t, out, `paths{name="`+name+`",state="ready"} 1`)
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		code, out := get("?path=" + url.QueryEscape("tenant1/.*"))
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, `paths{name="tenant1/cam1",state="ready"} 1`+"\n"+
+			`paths_bytes_received{name="tenant1/cam1",state="ready"} 1`+"\n"+
+			`paths_bytes_sent{name="tenant1/cam1",state="ready"} 0`+"\n"+
+			`paths{name="tenant1/cam2",state="ready"} 1`+"\n"+
+			`paths_bytes_received{name="tenant1/cam2",state="ready"} 2`+"\n"+
+			`paths_bytes_sent{name="tenant1/cam2",state="ready"} 0`+"\n", out)
+	})
+
+	t.Run("filtered with global", func(t *testing.T) {
+		code, out := get("?path=" + url.QueryEscape("tenant2/.*") + "&global=true")
+		require.Equal(t, http.StatusOK, code)
+		require.Regexp(t, `^build_info\{version="v1\.2\.3",.+?\} 1`+"\n"+
+			`paths\{name="tenant2/cam1",state="ready"\} 1`+"\n"+
+			`paths_bytes_received\{name="tenant2/cam1",state="ready"\} 3`+"\n"+
+			`paths_bytes_sent\{name="tenant2/cam1",state="ready"\} 0`+"\n$", out)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		code, _ := get("?path=" + url.QueryEscape("("))
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestOTLPExporter(t *testing.T) {
+	received := make(chan otlpExportRequest, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/v1/metrics", r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "Bearer mytoken", r.Header.Get("Authorization"))
+
+		var req otlpExportRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		require.NoError(t, err)
+
+		select {
+		case received <- req:
+		default:
+		}
+	}))
+	defer ts.Close()
+
+	m := Metrics{
+		Version:      "v1.2.3",
+		OTLPEndpoint: ts.URL + "/v1/metrics",
+		OTLPHeaders:  conf.HTTPHeaders{"Authorization": "Bearer mytoken"},
+		OTLPInterval: conf.StringDuration(100 * time.Millisecond),
+		AuthManager:  test.NilAuthManager,
+		Parent:       test.NilLogger,
+	}
+	err := m.Initialize()
+	require.NoError(t, err)
+	defer m.Close()
+
+	m.SetPathManager(&dummyPathManager{})
+
+	var req otlpExportRequest
+	timeout := time.After(2 * time.Second)
+
+	for {
+		select {
+		case req = <-received:
+		case <-timeout:
+			t.Fatal("metrics not received")
+		}
+
+		// ignore exports performed before the path manager was set
+		if len(req.ResourceMetrics[0].ScopeMetrics[0].Metrics) > 1 {
+			break
+		}
+	}
+
+	require.Equal(t, "service.name", req.ResourceMetrics[0].Resource.Attributes[0].Key)
+
+	int64Ptr := func(v string) *string { return &v }
+
+	buildInfo := req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+	require.Equal(t, "build_info", buildInfo.Name)
+	require.Equal(t, otlpKeyValue{Key: "version", Value: otlpAnyValue{StringValue: "v1.2.3"}},
+		buildInfo.Gauge.DataPoints[0].Attributes[0])
+
+	require.Equal(t, []otlpMetric{
+		{
+			Name: "paths",
+			Gauge: otlpGauge{DataPoints: []otlpNumberDataPoint{{
+				Attributes: []otlpKeyValue{
+					{Key: "name", Value: otlpAnyValue{StringValue: "mypath"}},
+					{Key: "state", Value: otlpAnyValue{StringValue: "ready"}},
+				},
+				TimeUnixNano: req.ResourceMetrics[0].ScopeMetrics[0].Metrics[1].Gauge.DataPoints[0].TimeUnixNano,
+				AsInt:        int64Ptr("1"),
+			}}},
+		},
+		{
+			Name: "paths_bytes_received",
+			Gauge: otlpGauge{DataPoints: []otlpNumberDataPoint{{
+				Attributes: []otlpKeyValue{
+					{Key: "name", Value: otlpAnyValue{StringValue: "mypath"}},
+					{Key: "state", Value: otlpAnyValue{StringValue: "ready"}},
+				},
+				TimeUnixNano: req.ResourceMetrics[0].ScopeMetrics[0].Metrics[2].Gauge.DataPoints[0].TimeUnixNano,
+				AsInt:        int64Ptr("123"),
+			}}},
+		},
+		{
+			Name: "paths_bytes_sent",
+			Gauge: otlpGauge{DataPoints: []otlpNumberDataPoint{{
+				Attributes: []otlpKeyValue{
+					{Key: "name", Value: otlpAnyValue{StringValue: "mypath"}},
+					{Key: "state", Value: otlpAnyValue{StringValue: "ready"}},
+				},
+				TimeUnixNano: req.ResourceMetrics[0].ScopeMetrics[0].Metrics[3].Gauge.DataPoints[0].TimeUnixNano,
+				AsInt:        int64Ptr("456"),
+			}}},
+		},
+	}, req.ResourceMetrics[0].ScopeMetrics[0].Metrics[1:])
+}
+
+func TestOTLPExporterCollectorError(t *testing.T) {
+	var n int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	warnings := int32(0)
+
+	m := Metrics{
+		OTLPEndpoint: ts.URL + "/v1/metrics",
+		OTLPInterval: conf.StringDuration(50 * time.Millisecond),
+		AuthManager:  test.NilAuthManager,
+		Parent: test.Logger(func(l logger.Level, _ string, _ ...interface{}) {
+			if l == logger.Warn {
+				atomic.AddInt32(&warnings, 1)
+			}
+		}),
+	}
+	err := m.Initialize()
+	require.NoError(t, err)
+
+	m.SetPathManager(&dummyPathManager{})
+
+	time.Sleep(300 * time.Millisecond)
+	m.Close()
+
+	require.Greater(t, atomic.LoadInt32(&n), int32(1))
+	require.Equal(t, int32(1), atomic.LoadInt32(&warnings))
+}
@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsInt        *string        `json:"asInt,omitempty"`
+	AsDouble     *float64       `json:"asDouble,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpExportRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// otlpMarshal converts samples into an OTLP/HTTP JSON export request.
+// Every sample is exported as a gauge, like in the Prometheus output.
+func otlpMarshal(samples []sample, now time.Time) ([]byte, error) {
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+
+	var metrics []otlpMetric
+	metricIndex := make(map[string]int)
+
+	for _, s := range samples {
+		dp := otlpNumberDataPoint{
+			TimeUnixNano: ts,
+		}
+
+		for _, t := range s.tags {
+			dp.Attributes = append(dp.Attributes, otlpKeyValue{
+				Key:   t.key,
+				Value: otlpAnyValue{StringValue: t.value},
+			})
+		}
+
+		if s.isFloat {
+			v := s.valueFloat
+			dp.AsDouble = &v
+		} else {
+			v := strconv.FormatInt(s.value, 10)
+			dp.AsInt = &v
+		}
+
+		i, ok := metricIndex[s.key]
+		if !ok {
+			i = len(metrics)
+			metricIndex[s.key] = i
+			metrics = append(metrics, otlpMetric{Name: s.key})
+		}
+
+		metrics[i].Gauge.DataPoints = append(metrics[i].Gauge.DataPoints, dp)
+	}
+
+	return json.Marshal(otlpExportRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{{
+					Key:   "service.name",
+					Value: otlpAnyValue{StringValue: "mediamtx"},
+				}},
+			},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpScope{Name: "mediamtx"},
+				Metrics: metrics,
+			}},
+		}},
+	})
+}
+
+// otlpExporter periodically pushes metrics to an OTLP collector.
+// Exports are performed in a dedicated routine, therefore a slow or
+// unreachable collector delays the next export only.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	interval time.Duration
+	collect  func() []sample
+	parent   logger.Writer
+
+	ctx       context.Context
+	ctxCancel func()
+	done      chan struct{}
+}
+
+func (e *otlpExporter) initialize() {
+	e.ctx, e.ctxCancel = context.WithCancel(context.Background())
+	e.done = make(chan struct{})
+
+	go e.run()
+}
+
+func (e *otlpExporter) close() {
+	e.ctxCancel()
+	<-e.done
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+
+	hc := &http.Client{
+		Timeout: e.interval,
+	}
+	defer hc.CloseIdleConnections()
+
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+
+	// log only state changes in order not to flood the log
+	failing := false
+
+	for {
+		select {
+		case <-t.C:
+			err := e.export(hc)
+			if err != nil {
+				if !failing {
+					e.parent.Log(logger.Warn, "OTLP export failed: %v", err)
+					failing = true
+				}
+			} else if failing {
+				e.parent.Log(logger.Info, "OTLP export succeeded")
+				failing = false
+			}
+
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *otlpExporter) export(hc *http.Client) error {
+	byts, err := otlpMarshal(e.collect(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodPost, e.endpoint, bytes.NewReader(byts))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, val := range e.headers {
+		req.Header.Set(key, val)
+	}
+
+	res, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("bad status code: %d", res.StatusCode)
+	}
+
+	return nil
+}
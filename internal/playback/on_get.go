@@ -42,6 +42,7 @@ func parseDuration(raw string) (time.Duration, error) {
 
 func seekAndMux(
 	recordFormat conf.RecordFormat,
+	inits *segmentFMP4Inits,
 	segments []*recordstore.Segment,
 	start time.Time,
 	duration time.Duration,
@@ -57,7 +58,7 @@ func seekAndMux(
 		}
 		defer f.Close()
 
-		firstInit, err = segmentFMP4ReadInit(f)
+		firstInit, _, err = inits.read(f, segments[0])
 		if err != nil {
 			return err
 		}
@@ -81,7 +82,7 @@ func seekAndMux(
 			defer f.Close()
 
 			var init *fmp4.Init
-			init, err = segmentFMP4ReadInit(f)
+			init, _, err = inits.read(f, seg)
 			if err != nil {
 				return err
 			}
@@ -163,7 +164,8 @@ func (s *Server) onGet(ctx *gin.Context) {
 		return
 	}
 
-	err = seekAndMux(pathConf.RecordFormat, segments, start, duration, m)
+	err = seekAndMux(pathConf.RecordFormat, &segmentFMP4Inits{pathConf: pathConf, pathName: pathName},
+		segments, start, duration, m)
 	if err != nil {
 		// user aborted the download
 		var neterr *net.OpError
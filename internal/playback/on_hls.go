@@ -0,0 +1,333 @@
+package playback
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
+	"github.com/gin-gonic/gin"
+)
+
+type hlsEntry struct {
+	discontinuity bool
+	initURI       string
+	initSize      uint64
+	start         time.Time
+	duration      time.Duration
+	uri           string
+	offset        uint64
+	size          uint64
+}
+
+func hlsQuery(pathName string, seg *recordstore.Segment) string {
+	v := url.Values{}
+	v.Add("path", pathName)
+	v.Add("start", seg.Start.Format(time.RFC3339Nano))
+	return v.Encode()
+}
+
+func hlsSegmentURI(pathName string, seg *recordstore.Segment) string {
+	return "segment.mp4?" + hlsQuery(pathName, seg)
+}
+
+func hlsInitURI(pathName string, owner *recordstore.Segment) string {
+	return "init.mp4?" + hlsQuery(pathName, owner)
+}
+
+// hlsEntries returns the parts of the segments that overlap the given timespan.
+// Each part is served as a byte range of the segment that contains it.
+// The initialization section is served as a byte range of the segment too,
+// or as a separate file when it is not embedded into segments.
+func hlsEntries(
+	pathName string,
+	inits *segmentFMP4Inits,
+	segments []*recordstore.Segment,
+	start time.Time,
+	duration time.Duration,
+) ([]*hlsEntry, error) {
+	end := start.Add(duration)
+	var out []*hlsEntry
+
+	for _, seg := range segments {
+		err := func() error {
+			f, err := os.Open(seg.Fpath)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			init, owner, err := inits.read(f, seg)
+			if err != nil {
+				return err
+			}
+
+			initSize, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+
+			uri := hlsSegmentURI(pathName, seg)
+			initURI := uri
+			if owner != nil {
+				initURI = hlsInitURI(pathName, owner)
+			}
+
+			parts, err := segmentFMP4ReadParts(f, init)
+			if err != nil {
+				return err
+			}
+
+			first := true
+			var prevEnd time.Duration
+			pendingOffset := int64(-1)
+
+			for _, part := range parts {
+				// parts without samples are merged with the following one
+				if part.end <= prevEnd {
+					if pendingOffset < 0 {
+						pendingOffset = int64(part.offset)
+					}
+					continue
+				}
+
+				offset := part.offset
+				if pendingOffset >= 0 {
+					offset = uint64(pendingOffset)
+					pendingOffset = -1
+				}
+
+				partStart := seg.Start.Add(prevEnd)
+				partDuration := part.end - prevEnd
+				prevEnd = part.end
+
+				if !partStart.Add(partDuration).After(start) || !partStart.Before(end) {
+					continue
+				}
+
+				entry := &hlsEntry{
+					start:    partStart,
+					duration: partDuration,
+					uri:      uri,
+					offset:   offset,
+					size:     part.offset + part.size - offset,
+				}
+
+				// timestamps restart from zero in every segment,
+				// therefore each segment begins a discontinuity.
+				if first {
+					entry.discontinuity = (out != nil)
+					entry.initURI = initURI
+					entry.initSize = uint64(initSize)
+					first = false
+				}
+
+				out = append(out, entry)
+			}
+
+			return nil
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if out == nil {
+		return nil, recordstore.ErrNoSegmentsFound
+	}
+
+	return out, nil
+}
+
+func hlsPlaylist(entries []*hlsEntry) []byte {
+	var targetDuration time.Duration
+	for _, entry := range entries {
+		if entry.duration > targetDuration {
+			targetDuration = entry.duration
+		}
+	}
+
+	ret := "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-TARGETDURATION:" + strconv.FormatInt(int64(math.Ceil(targetDuration.Seconds())), 10) + "\n" +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXT-X-PLAYLIST-TYPE:VOD\n"
+
+	for _, entry := range entries {
+		if entry.initURI != "" {
+			if entry.discontinuity {
+				ret += "#EXT-X-DISCONTINUITY\n"
+			}
+
+			ret += "#EXT-X-MAP:URI=\"" + entry.initURI + "\""
+			if entry.initSize != 0 {
+				ret += ",BYTERANGE=\"" + strconv.FormatUint(entry.initSize, 10) + "@0\""
+			}
+			ret += "\n" +
+				"#EXT-X-PROGRAM-DATE-TIME:" + entry.start.Format("2006-01-02T15:04:05.999Z07:00") + "\n"
+		}
+
+		ret += "#EXTINF:" + strconv.FormatFloat(entry.duration.Seconds(), 'f', -1, 64) + ",\n" +
+			"#EXT-X-BYTERANGE:" + strconv.FormatUint(entry.size, 10) + "@" +
+			strconv.FormatUint(entry.offset, 10) + "\n" +
+			entry.uri + "\n"
+	}
+
+	ret += "#EXT-X-ENDLIST\n"
+
+	return []byte(ret)
+}
+
+func (s *Server) findHLSPathConf(ctx *gin.Context, pathName string) (*conf.Path, bool) {
+	pathConf, err := s.safeFindPathConf(pathName)
+	if err != nil {
+		s.writeError(ctx, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if pathConf.RecordFormat != conf.RecordFormatFMP4 {
+		s.writeError(ctx, http.StatusBadRequest, fmt.Errorf("MPEG-TS format is not supported yet"))
+		return nil, false
+	}
+
+	// byte ranges can't be extracted from compressed segments
+	if pathConf.RecordCompression != conf.RecordCompressionNone {
+		s.writeError(ctx, http.StatusBadRequest, fmt.Errorf("compressed recordings can't be served with HLS"))
+		return nil, false
+	}
+
+	return pathConf, true
+}
+
+func (s *Server) onHLSIndex(ctx *gin.Context) {
+	pathName := ctx.Query("path")
+
+	if !s.doAuth(ctx, pathName) {
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+	if err != nil {
+		s.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid start: %w", err))
+		return
+	}
+
+	duration, err := parseDuration(ctx.Query("duration"))
+	if err != nil {
+		s.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid duration: %w", err))
+		return
+	}
+
+	pathConf, ok := s.findHLSPathConf(ctx, pathName)
+	if !ok {
+		return
+	}
+
+	segments, err := recordstore.FindSegmentsInTimespan(pathConf, pathName, start, duration)
+	if err != nil {
+		if errors.Is(err, recordstore.ErrNoSegmentsFound) {
+			s.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			s.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	entries, err := hlsEntries(pathName, &segmentFMP4Inits{pathConf: pathConf, pathName: pathName},
+		segments, start, duration)
+	if err != nil {
+		if errors.Is(err, recordstore.ErrNoSegmentsFound) {
+			s.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			s.writeError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/vnd.apple.mpegurl", hlsPlaylist(entries))
+}
+
+// findHLSSegment finds the segment referenced by a request.
+// Segments are looked up by start time, in order not to expose file paths.
+func (s *Server) findHLSSegment(ctx *gin.Context) (*recordstore.Segment, bool) {
+	pathName := ctx.Query("path")
+
+	if !s.doAuth(ctx, pathName) {
+		return nil, false
+	}
+
+	start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+	if err != nil {
+		s.writeError(ctx, http.StatusBadRequest, fmt.Errorf("invalid start: %w", err))
+		return nil, false
+	}
+
+	pathConf, ok := s.findHLSPathConf(ctx, pathName)
+	if !ok {
+		return nil, false
+	}
+
+	segments, err := recordstore.FindSegments(pathConf, pathName)
+	if err != nil {
+		if errors.Is(err, recordstore.ErrNoSegmentsFound) {
+			s.writeError(ctx, http.StatusNotFound, err)
+		} else {
+			s.writeError(ctx, http.StatusBadRequest, err)
+		}
+		return nil, false
+	}
+
+	for _, seg := range segments {
+		if seg.Start.Equal(start) {
+			return seg, true
+		}
+	}
+
+	s.writeError(ctx, http.StatusNotFound, recordstore.ErrNoSegmentsFound)
+	return nil, false
+}
+
+func (s *Server) serveHLSFile(ctx *gin.Context, fpath string) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		s.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		s.writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "video/mp4")
+	http.ServeContent(ctx.Writer, ctx.Request, "", fi.ModTime(), f)
+}
+
+func (s *Server) onHLSSegment(ctx *gin.Context) {
+	seg, ok := s.findHLSSegment(ctx)
+	if !ok {
+		return
+	}
+
+	s.serveHLSFile(ctx, seg.Fpath)
+}
+
+// onHLSInit serves the initialization file owned by a segment,
+// in case the initialization section is not embedded into segments.
+func (s *Server) onHLSInit(ctx *gin.Context) {
+	seg, ok := s.findHLSSegment(ctx)
+	if !ok {
+		return
+	}
+
+	s.serveHLSFile(ctx, recordstore.InitPath(seg.Fpath))
+}
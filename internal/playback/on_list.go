@@ -31,6 +31,7 @@ type listEntry struct {
 
 func computeDurationAndConcatenate(
 	recordFormat conf.RecordFormat,
+	inits *segmentFMP4Inits,
 	segments []*recordstore.Segment,
 ) ([]listEntry, error) {
 	if recordFormat == conf.RecordFormatFMP4 {
@@ -45,7 +46,7 @@ func computeDurationAndConcatenate(
 				}
 				defer f.Close()
 
-				init, err := segmentFMP4ReadInit(f)
+				init, _, err := inits.read(f, seg)
 				if err != nil {
 					return err
 				}
@@ -113,7 +114,8 @@ func (s *Server) onList(ctx *gin.Context) {
 		return
 	}
 
-	entries, err := computeDurationAndConcatenate(pathConf.RecordFormat, segments)
+	entries, err := computeDurationAndConcatenate(pathConf.RecordFormat,
+		&segmentFMP4Inits{pathConf: pathConf, pathName: pathName, segments: segments}, segments)
 	if err != nil {
 		s.writeError(ctx, http.StatusInternalServerError, err)
 		return
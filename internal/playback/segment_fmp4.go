@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"reflect"
 	"time"
 
 	"github.com/abema/go-mp4"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/recordstore"
 )
 
@@ -18,7 +20,10 @@ const (
 	concatenationTolerance    = 500 * time.Millisecond
 )
 
-var errTerminated = errors.New("terminated")
+var (
+	errTerminated   = errors.New("terminated")
+	errSeparateInit = errors.New("initialization section is stored into a separate file")
+)
 
 type readSeekerAt interface {
 	io.Reader
@@ -60,6 +65,11 @@ func segmentFMP4CanBeConcatenated(
 		!curStart.After(prevEnd.Add(concatenationTolerance))
 }
 
+func isFragmentBox(typ []byte) bool {
+	return bytes.Equal(typ, []byte{'m', 'o', 'o', 'f'}) ||
+		bytes.Equal(typ, []byte{'s', 'i', 'd', 'x'})
+}
+
 func segmentFMP4ReadInit(r io.ReadSeeker) (*fmp4.Init, error) {
 	buf := make([]byte, 8)
 	_, err := io.ReadFull(r, buf)
@@ -70,6 +80,9 @@ func segmentFMP4ReadInit(r io.ReadSeeker) (*fmp4.Init, error) {
 	// find ftyp
 
 	if !bytes.Equal(buf[4:], []byte{'f', 't', 'y', 'p'}) {
+		if isFragmentBox(buf[4:]) {
+			return nil, errSeparateInit
+		}
 		return nil, fmt.Errorf("ftyp box not found")
 	}
 
@@ -114,6 +127,59 @@ func segmentFMP4ReadInit(r io.ReadSeeker) (*fmp4.Init, error) {
 	return &init, nil
 }
 
+// segmentFMP4Inits reads initialization sections of segments,
+// that are either embedded into segments or stored into separate files.
+type segmentFMP4Inits struct {
+	pathConf *conf.Path
+	pathName string
+	segments []*recordstore.Segment
+}
+
+// read reads the initialization section that applies to a segment.
+// It also returns the segment that owns the separate initialization file,
+// or nil when the initialization section is embedded into the segment.
+func (i *segmentFMP4Inits) read(
+	f io.ReadSeeker,
+	seg *recordstore.Segment,
+) (*fmp4.Init, *recordstore.Segment, error) {
+	init, err := segmentFMP4ReadInit(f)
+	if !errors.Is(err, errSeparateInit) {
+		return init, nil, err
+	}
+
+	// the owner of the initialization file may precede the requested timespan,
+	// therefore it is searched among all segments of the path.
+	if i.segments == nil {
+		i.segments, err = recordstore.FindSegments(i.pathConf, i.pathName)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	owner, err := recordstore.FindInit(i.segments, seg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	initFile, err := os.Open(recordstore.InitPath(owner.Fpath))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer initFile.Close()
+
+	init, err = segmentFMP4ReadInit(initFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return init, owner, nil
+}
+
 func segmentFMP4ReadMaxDuration(
 	r io.ReadSeeker,
 	init *fmp4.Init,
@@ -126,33 +192,41 @@ func segmentFMP4ReadMaxDuration(
 		return 0, err
 	}
 
-	if !bytes.Equal(buf[4:], []byte{'f', 't', 'y', 'p'}) {
-		return 0, fmt.Errorf("ftyp box not found")
-	}
+	if isFragmentBox(buf[4:]) {
+		// the initialization section is stored into a separate file
+		_, err = r.Seek(0, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		if !bytes.Equal(buf[4:], []byte{'f', 't', 'y', 'p'}) {
+			return 0, fmt.Errorf("ftyp box not found")
+		}
 
-	ftypSize := uint32(buf[0])<<24 | uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])
+		ftypSize := uint32(buf[0])<<24 | uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])
 
-	_, err = r.Seek(int64(ftypSize), io.SeekStart)
-	if err != nil {
-		return 0, err
-	}
+		_, err = r.Seek(int64(ftypSize), io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
 
-	// find and skip moov
+		// find and skip moov
 
-	_, err = io.ReadFull(r, buf)
-	if err != nil {
-		return 0, err
-	}
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return 0, err
+		}
 
-	if !bytes.Equal(buf[4:], []byte{'m', 'o', 'o', 'v'}) {
-		return 0, fmt.Errorf("moov box not found")
-	}
+		if !bytes.Equal(buf[4:], []byte{'m', 'o', 'o', 'v'}) {
+			return 0, fmt.Errorf("moov box not found")
+		}
 
-	moovSize := uint32(buf[0])<<24 | uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])
+		moovSize := uint32(buf[0])<<24 | uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])
 
-	_, err = r.Seek(int64(moovSize)-8, io.SeekCurrent)
-	if err != nil {
-		return 0, err
+		_, err = r.Seek(int64(moovSize)-8, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	// find last valid moof and mdat
@@ -171,6 +245,17 @@ func segmentFMP4ReadMaxDuration(
 			break
 		}
 
+		// skip segment index
+		if lastMoofPos < 0 && bytes.Equal(buf[4:], []byte{'s', 'i', 'd', 'x'}) {
+			sidxSize := uint32(buf[0])<<24 | uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])
+
+			_, err = r.Seek(int64(sidxSize)-8, io.SeekCurrent)
+			if err != nil {
+				break
+			}
+			continue
+		}
+
 		if !bytes.Equal(buf[4:], []byte{'m', 'o', 'o', 'f'}) {
 			return 0, fmt.Errorf("moof box not found")
 		}
@@ -344,6 +429,156 @@ func segmentFMP4ReadMaxDuration(
 	return maxElapsed, nil
 }
 
+// segmentFMP4Part is a moof and mdat pair of a segment.
+type segmentFMP4Part struct {
+	offset uint64
+	size   uint64
+	end    time.Duration // maximum elapsed time since the start of the segment
+}
+
+func segmentFMP4ReadParts(
+	r io.ReadSeeker,
+	init *fmp4.Init,
+) ([]*segmentFMP4Part, error) {
+	fileSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []*segmentFMP4Part
+	moofOffset := uint64(0)
+	var tfhd *mp4.Tfhd
+	var tfdt *mp4.Tfdt
+	var maxElapsed time.Duration
+
+	_, err = mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type.String() {
+		case "moof":
+			moofOffset = h.BoxInfo.Offset
+			return h.Expand()
+
+		case "traf":
+			return h.Expand()
+
+		case "tfhd":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tfhd = box.(*mp4.Tfhd)
+
+		case "tfdt":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			tfdt = box.(*mp4.Tfdt)
+
+		case "trun":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			trun := box.(*mp4.Trun)
+
+			track := findInitTrack(init.Tracks, int(tfhd.TrackID))
+			if track == nil {
+				return nil, fmt.Errorf("invalid track ID: %v", tfhd.TrackID)
+			}
+
+			elapsed := int64(tfdt.BaseMediaDecodeTimeV1)
+
+			for _, entry := range trun.Entries {
+				elapsed += int64(entry.SampleDuration)
+			}
+
+			elapsedGo := durationMp4ToGo(elapsed, track.TimeScale)
+
+			if elapsedGo > maxElapsed {
+				maxElapsed = elapsedGo
+			}
+
+		case "mdat":
+			end := h.BoxInfo.Offset + h.BoxInfo.Size
+
+			// part is still being written
+			if end > uint64(fileSize) {
+				return nil, errTerminated
+			}
+
+			parts = append(parts, &segmentFMP4Part{
+				offset: moofOffset,
+				size:   end - moofOffset,
+				end:    maxElapsed,
+			})
+		}
+		return nil, nil
+	})
+	if err != nil && !errors.Is(err, errTerminated) {
+		return nil, err
+	}
+
+	if parts == nil {
+		return nil, fmt.Errorf("no moof boxes found")
+	}
+
+	return parts, nil
+}
+
+// segmentFMP4SidxSeek uses the segment index (sidx) to find the offset of the last fragment
+// that starts with a random access point and that doesn't start after segmentStartOffset.
+// It returns 0 when the segment doesn't contain a segment index.
+func segmentFMP4SidxSeek(
+	r io.ReadSeeker,
+	segmentStartOffset time.Duration,
+) (uint64, error) {
+	var ret uint64
+
+	_, err := mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type.String() {
+		case "ftyp", "moov":
+			return nil, nil
+
+		case "sidx":
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
+			}
+			sidx := box.(*mp4.Sidx)
+
+			offset := h.BoxInfo.Offset + h.BoxInfo.Size + sidx.GetFirstOffset()
+			t := int64(sidx.GetEarliestPresentationTime())
+			target := durationGoToMp4(segmentStartOffset, sidx.Timescale)
+			ret = offset
+
+			for _, ref := range sidx.References {
+				if t > target {
+					break
+				}
+
+				if ref.StartsWithSAP {
+					ret = offset
+				}
+
+				offset += uint64(ref.ReferencedSize)
+				t += int64(ref.SubsegmentDuration)
+			}
+		}
+
+		return nil, errTerminated
+	})
+	if err != nil && !errors.Is(err, errTerminated) {
+		return 0, err
+	}
+
+	return ret, nil
+}
+
 func segmentFMP4SeekAndMuxParts(
 	r readSeekerAt,
 	segmentStartOffset time.Duration,
@@ -351,6 +586,17 @@ func segmentFMP4SeekAndMuxParts(
 	init *fmp4.Init,
 	m muxer,
 ) (time.Duration, error) {
+	// when a segment index is available, skip fragments that are not needed
+	baseOffset, err := segmentFMP4SidxSeek(r, segmentStartOffset)
+	if err != nil {
+		return 0, err
+	}
+
+	fileSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
 	var segmentStartOffsetMP4 int64
 	var durationMP4 int64
 	moofOffset := uint64(0)
@@ -361,10 +607,12 @@ func segmentFMP4SeekAndMuxParts(
 	var maxMuxerDTS time.Duration
 	breakAtNextMdat := false
 
-	_, err := mp4.ReadBoxStructure(r, func(h *mp4.ReadHandle) (interface{}, error) {
+	sr := io.NewSectionReader(r, int64(baseOffset), fileSize-int64(baseOffset))
+
+	_, err = mp4.ReadBoxStructure(sr, func(h *mp4.ReadHandle) (interface{}, error) {
 		switch h.BoxInfo.Type.String() {
 		case "moof":
-			moofOffset = h.BoxInfo.Offset
+			moofOffset = baseOffset + h.BoxInfo.Offset
 			return h.Expand()
 
 		case "traf":
@@ -1,13 +1,18 @@
 package playback
 
 import (
+	"bytes"
 	"io"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/abema/go-mp4"
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
 	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/stretchr/testify/require"
 )
 
 func writeBenchInit(f io.WriteSeeker) {
@@ -73,3 +78,102 @@ func BenchmarkFMP4ReadInit(b *testing.B) {
 		}()
 	}
 }
+
+func TestSegmentFMP4Sidx(t *testing.T) {
+	init := fmp4.Init{
+		Tracks: []*fmp4.InitTrack{{
+			ID:        1,
+			TimeScale: 90000,
+			Codec: &fmp4.CodecH264{
+				SPS: test.FormatH264.SPS,
+				PPS: test.FormatH264.PPS,
+			},
+		}},
+	}
+
+	var buf seekablebuffer.Buffer
+	err := init.Marshal(&buf)
+	require.NoError(t, err)
+
+	// three fragments of 2 seconds each. The second one doesn't start with a IDR.
+	var fragments [][]byte
+
+	for i, isNonSyncSample := range []bool{false, true, false} {
+		var buf2 seekablebuffer.Buffer
+		part := fmp4.Part{
+			SequenceNumber: uint32(i),
+			Tracks: []*fmp4.PartTrack{{
+				ID:       1,
+				BaseTime: uint64(i) * 2 * 90000,
+				Samples: []*fmp4.PartSample{
+					{
+						Duration:        90000,
+						IsNonSyncSample: isNonSyncSample,
+						Payload:         []byte{1, 2},
+					},
+					{
+						Duration:        90000,
+						IsNonSyncSample: true,
+						Payload:         []byte{3, 4},
+					},
+				},
+			}},
+		}
+		err = part.Marshal(&buf2)
+		require.NoError(t, err)
+		fragments = append(fragments, buf2.Bytes())
+	}
+
+	sidx := &mp4.Sidx{
+		FullBox:        mp4.FullBox{Version: 1},
+		ReferenceID:    1,
+		Timescale:      90000,
+		ReferenceCount: uint16(len(fragments)),
+	}
+	for i, fragment := range fragments {
+		sidx.References = append(sidx.References, mp4.SidxReference{
+			ReferencedSize:     uint32(len(fragment)),
+			SubsegmentDuration: 2 * 90000,
+			StartsWithSAP:      i != 1,
+		})
+	}
+
+	var buf3 seekablebuffer.Buffer
+	w := mp4.NewWriter(&buf3)
+	_, err = w.StartBox(&mp4.BoxInfo{Type: mp4.BoxTypeSidx()})
+	require.NoError(t, err)
+	_, err = mp4.Marshal(w, sidx, mp4.Context{})
+	require.NoError(t, err)
+	_, err = w.EndBox()
+	require.NoError(t, err)
+
+	byts := append(buf.Bytes(), buf3.Bytes()...)
+	firstOffset := uint64(len(byts))
+	for _, fragment := range fragments {
+		byts = append(byts, fragment...)
+	}
+
+	maxDuration, err := segmentFMP4ReadMaxDuration(bytes.NewReader(byts), &init)
+	require.NoError(t, err)
+	require.Equal(t, 6*time.Second, maxDuration)
+
+	for _, ca := range []struct {
+		offset   time.Duration
+		expected uint64
+	}{
+		{0, firstOffset},
+		{1 * time.Second, firstOffset},
+		{3 * time.Second, firstOffset},
+		{4 * time.Second, firstOffset + uint64(len(fragments[0])+len(fragments[1]))},
+		{5 * time.Second, firstOffset + uint64(len(fragments[0])+len(fragments[1]))},
+	} {
+		offset, err := segmentFMP4SidxSeek(bytes.NewReader(byts), ca.offset)
+		require.NoError(t, err)
+		require.Equal(t, ca.expected, offset)
+	}
+
+	// segments without a segment index are read from the beginning
+	offset, err := segmentFMP4SidxSeek(bytes.NewReader(append(buf.Bytes(), fragments[0]...)), 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), offset)
+}
@@ -45,6 +45,9 @@ func (s *Server) Initialize() error {
 
 	router.GET("/list", s.onList)
 	router.GET("/get", s.onGet)
+	router.GET("/hls/index.m3u8", s.onHLSIndex)
+	router.GET("/hls/segment.mp4", s.onHLSSegment)
+	router.GET("/hls/init.mp4", s.onHLSInit)
 
 	network, address := restrictnetwork.Restrict("tcp", s.Address)
 
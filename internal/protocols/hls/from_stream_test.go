@@ -22,6 +22,9 @@ func TestFromStreamNoSupportedCodecs(t *testing.T) {
 			Formats: []format.Format{&format.VP8{}},
 		}}},
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -55,6 +58,9 @@ func TestFromStreamSkipUnsupportedTracks(t *testing.T) {
 			},
 		}},
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -0,0 +1,165 @@
+package mpegts
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	nullPID = 0x1FFF
+)
+
+var nullPacket = func() [packetSize]byte {
+	var pkt [packetSize]byte
+	pkt[0] = 0x47
+	pkt[1] = byte(nullPID >> 8)
+	pkt[2] = byte(nullPID & 0xFF)
+	pkt[3] = 0x10 // payload only
+	for i := 4; i < packetSize; i++ {
+		pkt[i] = 0xFF
+	}
+	return pkt
+}()
+
+// CBRWriter turns a MPEG-TS stream into a constant-bitrate one.
+// Packets are paced in order not to exceed the bitrate,
+// and null packets are inserted when there's no data to write.
+// Padding is inserted before each write, therefore the bitrate is
+// constant as long as the stream is written regularly.
+type CBRWriter struct {
+	// Underlying writer.
+	W io.Writer
+
+	// Bitrate, in bits per second.
+	Bitrate uint64
+
+	// Maximum size of each write to the underlying writer.
+	// It is rounded down to a multiple of the packet size.
+	MaxWriteSize int
+
+	// Maximum time a write can be delayed in order to respect the bitrate.
+	// When exceeded, the stream bitrate is higher than Bitrate and an error is returned.
+	MaxDelay time.Duration
+
+	chunkPackets int
+	start        time.Time
+	sent         uint64
+	buf          []byte
+	partial      []byte
+}
+
+// Initialize initializes CBRWriter.
+func (w *CBRWriter) Initialize() {
+	w.chunkPackets = w.MaxWriteSize / packetSize
+	if w.chunkPackets < 1 {
+		w.chunkPackets = 1
+	}
+
+	w.buf = make([]byte, 0, w.chunkPackets*packetSize)
+}
+
+// scheduled returns the time at which the n-th packet has to be written.
+func (w *CBRWriter) scheduled(n uint64) time.Time {
+	return w.start.Add(time.Duration(multiplyAndDivide(
+		int64(n)*packetSize*8, int64(time.Second), int64(w.Bitrate))))
+}
+
+// due returns the count of packets that should have been written by the given time.
+func (w *CBRWriter) due(t time.Time) uint64 {
+	return uint64(multiplyAndDivide(
+		int64(t.Sub(w.start)), int64(w.Bitrate), int64(time.Second))) / (packetSize * 8)
+}
+
+func (w *CBRWriter) writePadding(now time.Time) error {
+	due := w.due(now)
+
+	for w.sent < due {
+		n := min(due-w.sent, uint64(w.chunkPackets))
+
+		w.buf = w.buf[:0]
+		for i := uint64(0); i < n; i++ {
+			w.buf = append(w.buf, nullPacket[:]...)
+		}
+
+		_, err := w.W.Write(w.buf)
+		if err != nil {
+			return err
+		}
+
+		w.sent += n
+	}
+
+	return nil
+}
+
+func (w *CBRWriter) writeChunk(chunk []byte) error {
+	delay := time.Until(w.scheduled(w.sent))
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	_, err := w.W.Write(chunk)
+	if err != nil {
+		return err
+	}
+
+	w.sent += uint64(len(chunk) / packetSize)
+	return nil
+}
+
+// Write implements io.Writer.
+func (w *CBRWriter) Write(p []byte) (int, error) {
+	written := len(p)
+
+	count := uint64((len(w.partial) + len(p)) / packetSize)
+	if count != 0 {
+		now := time.Now()
+
+		if w.start.IsZero() {
+			w.start = now
+		}
+
+		err := w.writePadding(now)
+		if err != nil {
+			return 0, err
+		}
+
+		if w.scheduled(w.sent+count-1).Sub(now) > w.MaxDelay {
+			return 0, fmt.Errorf("stream bitrate is higher than the constant bitrate (%d bit/s)", w.Bitrate)
+		}
+	}
+
+	if len(w.partial) != 0 {
+		n := min(packetSize-len(w.partial), len(p))
+		w.partial = append(w.partial, p[:n]...)
+		p = p[n:]
+
+		if len(w.partial) < packetSize {
+			return written, nil
+		}
+
+		err := w.writeChunk(w.partial)
+		if err != nil {
+			return 0, err
+		}
+		w.partial = w.partial[:0]
+	}
+
+	for len(p) >= packetSize {
+		n := min(len(p)/packetSize, w.chunkPackets) * packetSize
+
+		err := w.writeChunk(p[:n])
+		if err != nil {
+			return 0, err
+		}
+
+		p = p[n:]
+	}
+
+	if len(p) != 0 {
+		w.partial = append(w.partial, p...)
+	}
+
+	return written, nil
+}
@@ -0,0 +1,132 @@
+package mpegts
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func dataPacket(counter byte) []byte {
+	pkt := make([]byte, packetSize)
+	pkt[0] = 0x47
+	pkt[1] = 0x01
+	pkt[2] = 0x00
+	pkt[3] = 0x10 | (counter & 0x0F)
+	return pkt
+}
+
+type maxSizeWriter struct {
+	buf     bytes.Buffer
+	maxSize int
+}
+
+func (w *maxSizeWriter) Write(p []byte) (int, error) {
+	if len(p) > w.maxSize {
+		w.maxSize = len(p)
+	}
+	return w.buf.Write(p)
+}
+
+func TestCBRWriter(t *testing.T) {
+	const bitrate = 1000000
+
+	var out maxSizeWriter
+
+	w := &CBRWriter{
+		W:            &out,
+		Bitrate:      bitrate,
+		MaxWriteSize: 1316,
+		MaxDelay:     time.Second,
+	}
+	w.Initialize()
+
+	start := time.Now()
+
+	// write 10 packets every 20ms, that is about half of the bitrate.
+	for i := 0; i < 25; i++ {
+		var chunk []byte
+		for j := 0; j < 10; j++ {
+			chunk = append(chunk, dataPacket(byte(i*10+j))...)
+		}
+
+		// split writes in order to test packets that are not aligned.
+		_, err := w.Write(chunk[:100])
+		require.NoError(t, err)
+		_, err = w.Write(chunk[100:])
+		require.NoError(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// a final write flushes padding.
+	_, err := w.Write(dataPacket(250))
+	require.NoError(t, err)
+
+	elapsed := time.Since(start)
+
+	byts := out.buf.Bytes()
+	require.Equal(t, 0, len(byts)%packetSize)
+	require.LessOrEqual(t, out.maxSize, 1316)
+
+	dataCount := 0
+	nullCount := 0
+
+	for i := 0; i < len(byts); i += packetSize {
+		pkt := byts[i : i+packetSize]
+		require.Equal(t, byte(0x47), pkt[0])
+
+		pid := uint16(pkt[1]&0x1F)<<8 | uint16(pkt[2])
+		if pid == nullPID {
+			require.Equal(t, nullPacket[:], pkt)
+			nullCount++
+		} else {
+			require.Equal(t, dataPacket(byte(dataCount)), pkt)
+			dataCount++
+		}
+	}
+
+	require.Equal(t, 251, dataCount)
+	require.NotZero(t, nullCount)
+
+	rate := float64(len(byts)*8) / elapsed.Seconds()
+	require.InDelta(t, bitrate, rate, bitrate*0.1)
+}
+
+func TestCBRWriterPacing(t *testing.T) {
+	const bitrate = 188 * 8 * 100 // 100 packets per second
+
+	var out maxSizeWriter
+
+	w := &CBRWriter{
+		W:            &out,
+		Bitrate:      bitrate,
+		MaxWriteSize: 1316,
+		MaxDelay:     time.Second,
+	}
+	w.Initialize()
+
+	var chunk []byte
+	for i := 0; i < 21; i++ {
+		chunk = append(chunk, dataPacket(byte(i))...)
+	}
+
+	start := time.Now()
+
+	_, err := w.Write(chunk)
+	require.NoError(t, err)
+
+	// the first 7 packets are written immediately, the others are delayed.
+	require.GreaterOrEqual(t, time.Since(start), 140*time.Millisecond)
+	require.Equal(t, chunk, out.buf.Bytes())
+
+	// a write that exceeds the bitrate for longer than MaxDelay fails.
+	chunk = nil
+	for i := 0; i < 200; i++ {
+		chunk = append(chunk, dataPacket(byte(i))...)
+	}
+
+	_, err = w.Write(chunk)
+	require.EqualError(t, err, "stream bitrate is higher than the constant bitrate (150400 bit/s)")
+}
@@ -25,12 +25,21 @@ func multiplyAndDivide(v, m, d int64) int64 {
 }
 
 // FromStream maps a MediaMTX stream to a MPEG-TS writer.
+// Only the given medias of the stream are read.
+// PIDs of tracks and of the program map table can be set with pids.
+// When service is not empty, a service description table is written.
+// onRandomAccess, if not nil, is called before writing a H265 or H264 random access unit;
+// returning an error stops the reader before the unit is written.
 func FromStream(
 	strea *stream.Stream,
 	reader stream.Reader,
+	medias []*description.Media,
+	pids PIDs,
+	service Service,
 	bw *bufio.Writer,
 	sconn srt.Conn,
 	writeTimeout time.Duration,
+	onRandomAccess func() error,
 ) error {
 	var w *mcmpegts.Writer
 	var tracks []*mcmpegts.Track
@@ -47,7 +56,7 @@ func FromStream(
 		strea.AddReader(reader, media, forma, readFunc)
 	}
 
-	for _, media := range strea.Desc().Medias {
+	for _, media := range medias {
 		for _, forma := range media.Formats {
 			clockRate := forma.ClockRate()
 
@@ -76,6 +85,13 @@ func FromStream(
 							dtsExtractor = h265.NewDTSExtractor2()
 						}
 
+						if randomAccess && onRandomAccess != nil {
+							err := onRandomAccess()
+							if err != nil {
+								return err
+							}
+						}
+
 						dts, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
 						if err != nil {
 							return err
@@ -118,6 +134,13 @@ func FromStream(
 							dtsExtractor = h264.NewDTSExtractor2()
 						}
 
+						if idrPresent && onRandomAccess != nil {
+							err := onRandomAccess()
+							if err != nil {
+								return err
+							}
+						}
+
 						dts, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
 						if err != nil {
 							return err
@@ -319,7 +342,7 @@ func FromStream(
 	}
 
 	n := 1
-	for _, medi := range strea.Desc().Medias {
+	for _, medi := range medias {
 		for _, forma := range medi.Formats {
 			if _, ok := setuppedFormats[forma]; !ok {
 				reader.Log(logger.Warn, "skipping track %d (%s)", n, forma.Codec())
@@ -328,7 +351,7 @@ func FromStream(
 		}
 	}
 
-	w = mcmpegts.NewWriter(bw, tracks)
+	w = NewWriter(bw, tracks, pids, service)
 
 	return nil
 }
@@ -21,6 +21,9 @@ func TestFromStreamNoSupportedCodecs(t *testing.T) {
 			Formats: []format.Format{&format.VP8{}},
 		}}},
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -29,7 +32,7 @@ func TestFromStreamNoSupportedCodecs(t *testing.T) {
 		t.Error("should not happen")
 	})
 
-	err = FromStream(stream, l, nil, nil, 0)
+	err = FromStream(stream, l, stream.Desc().Medias, PIDs{}, Service{}, nil, nil, 0, nil)
 	require.Equal(t, errNoSupportedCodecs, err)
 }
 
@@ -48,6 +51,9 @@ func TestFromStreamSkipUnsupportedTracks(t *testing.T) {
 			},
 		}},
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -62,7 +68,7 @@ func TestFromStreamSkipUnsupportedTracks(t *testing.T) {
 		n++
 	})
 
-	err = FromStream(stream, l, nil, nil, 0)
+	err = FromStream(stream, l, stream.Desc().Medias, PIDs{}, Service{}, nil, nil, 0, nil)
 	require.NoError(t, err)
 	defer stream.RemoveReader(l)
 
@@ -0,0 +1,220 @@
+package mpegts
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	pcrBaseMask = (1 << 33) - 1
+
+	// writes that are scheduled within this interval are not delayed,
+	// since sleeping for shorter intervals is not accurate.
+	pacedWriterJitter = 2 * time.Millisecond
+
+	// intervals between PCRs longer than this are considered discontinuities.
+	pacedWriterMaxPCRInterval = 1 * time.Second
+
+	// packets are written without pacing when no PCR is received
+	// within this amount of packets.
+	pacedWriterMaxPendingPackets = 2048
+)
+
+// packetPCR returns the PID and the base of the PCR of a packet, if present.
+func packetPCR(pkt []byte) (uint16, uint64, bool) {
+	pid := uint16(pkt[1]&0x1F)<<8 | uint16(pkt[2])
+
+	// adaptation field is present and contains at least flags and PCR
+	if (pkt[3]>>4)&0x02 == 0 || pkt[4] < 7 || pkt[5]&0x10 == 0 {
+		return 0, 0, false
+	}
+
+	base := uint64(pkt[6])<<25 | uint64(pkt[7])<<17 | uint64(pkt[8])<<9 |
+		uint64(pkt[9])<<1 | uint64(pkt[10])>>7
+
+	return pid, base, true
+}
+
+// PacedWriter smooths a MPEG-TS stream toward its natural bitrate.
+// Packets between two PCRs are buffered and written evenly over the
+// interval between the PCRs, therefore output is delayed by one PCR interval.
+// Differently from CBRWriter, no padding is inserted.
+type PacedWriter struct {
+	// Underlying writer.
+	W io.Writer
+
+	// Maximum size of each write to the underlying writer.
+	// It is rounded down to a multiple of the packet size.
+	MaxWriteSize int
+
+	// Maximum time a write can be delayed in order to pace the stream.
+	// When exceeded, pacing is restarted from the current time.
+	MaxDelay time.Duration
+
+	// When closed, pending delays are interrupted and an error is returned.
+	Done <-chan struct{}
+
+	chunkPackets int
+	partial      []byte
+	pending      []byte
+
+	hasPCR  bool
+	pcrPID  uint16
+	lastPCR uint64
+
+	// time at which packets that follow the last PCR can start being written.
+	next time.Time
+}
+
+// Initialize initializes PacedWriter.
+func (w *PacedWriter) Initialize() {
+	w.chunkPackets = w.MaxWriteSize / packetSize
+	if w.chunkPackets < 1 {
+		w.chunkPackets = 1
+	}
+}
+
+func (w *PacedWriter) sleep(d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+
+	case <-w.Done:
+		return errors.New("terminated")
+	}
+}
+
+// writePending writes pending packets evenly from start to start+dur.
+func (w *PacedWriter) writePending(start time.Time, dur time.Duration) error {
+	chunkSize := w.chunkPackets * packetSize
+	n := (len(w.pending) + chunkSize - 1) / chunkSize
+
+	for i := 0; i < n; i++ {
+		delay := time.Until(start.Add(dur * time.Duration(i) / time.Duration(n)))
+		if delay > pacedWriterJitter {
+			err := w.sleep(delay)
+			if err != nil {
+				return err
+			}
+		}
+
+		end := min((i+1)*chunkSize, len(w.pending))
+
+		_, err := w.W.Write(w.pending[i*chunkSize : end])
+		if err != nil {
+			return err
+		}
+	}
+
+	w.pending = w.pending[:0]
+	return nil
+}
+
+// restart writes pending packets immediately and restarts pacing from the current time.
+func (w *PacedWriter) restart(pcr uint64) error {
+	err := w.writePending(time.Now(), 0)
+	if err != nil {
+		return err
+	}
+
+	w.lastPCR = pcr
+	w.next = time.Now()
+	return nil
+}
+
+func (w *PacedWriter) writePacket(pkt []byte) error {
+	pid, pcr, ok := packetPCR(pkt)
+
+	switch {
+	case !ok || (w.hasPCR && pid != w.pcrPID):
+		if len(w.pending) >= pacedWriterMaxPendingPackets*packetSize {
+			err := w.writePending(time.Now(), 0)
+			if err != nil {
+				return err
+			}
+		}
+
+	case !w.hasPCR:
+		w.hasPCR = true
+		w.pcrPID = pid
+
+		err := w.restart(pcr)
+		if err != nil {
+			return err
+		}
+
+	default:
+		diff := (pcr - w.lastPCR) & pcrBaseMask
+		dur := time.Duration(multiplyAndDivide(int64(diff), int64(time.Second), 90000))
+
+		if diff > pcrBaseMask/2 || dur > pacedWriterMaxPCRInterval {
+			err := w.restart(pcr)
+			if err != nil {
+				return err
+			}
+			break
+		}
+
+		now := time.Now()
+		start := w.next
+		if start.Before(now) {
+			start = now
+		}
+
+		// do not delay writes by more than MaxDelay
+		if start.Add(dur).Sub(now) > w.MaxDelay {
+			start = now
+			dur = min(dur, w.MaxDelay)
+		}
+
+		err := w.writePending(start, dur)
+		if err != nil {
+			return err
+		}
+
+		w.lastPCR = pcr
+		w.next = start.Add(dur)
+	}
+
+	w.pending = append(w.pending, pkt...)
+	return nil
+}
+
+// Write implements io.Writer.
+func (w *PacedWriter) Write(p []byte) (int, error) {
+	written := len(p)
+
+	if len(w.partial) != 0 {
+		n := min(packetSize-len(w.partial), len(p))
+		w.partial = append(w.partial, p[:n]...)
+		p = p[n:]
+
+		if len(w.partial) < packetSize {
+			return written, nil
+		}
+
+		err := w.writePacket(w.partial)
+		if err != nil {
+			return 0, err
+		}
+		w.partial = w.partial[:0]
+	}
+
+	for len(p) >= packetSize {
+		err := w.writePacket(p[:packetSize])
+		if err != nil {
+			return 0, err
+		}
+		p = p[packetSize:]
+	}
+
+	if len(p) != 0 {
+		w.partial = append(w.partial, p...)
+	}
+
+	return written, nil
+}
@@ -0,0 +1,144 @@
+package mpegts
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func pcrPacket(base uint64) []byte {
+	pkt := make([]byte, packetSize)
+	pkt[0] = 0x47
+	pkt[1] = 0x01
+	pkt[2] = 0x00
+	pkt[3] = 0x30 // adaptation field and payload
+	pkt[4] = 7
+	pkt[5] = 0x10 // PCR flag
+	pkt[6] = byte(base >> 25)
+	pkt[7] = byte(base >> 17)
+	pkt[8] = byte(base >> 9)
+	pkt[9] = byte(base >> 1)
+	pkt[10] = byte(base<<7) | 0x7E
+	return pkt
+}
+
+// timedWriter records the time of each write.
+type timedWriter struct {
+	buf   bytes.Buffer
+	times []time.Time
+}
+
+func (w *timedWriter) Write(p []byte) (int, error) {
+	w.times = append(w.times, time.Now())
+	return w.buf.Write(p)
+}
+
+func (w *timedWriter) maxInterval() time.Duration {
+	var ret time.Duration
+	for i := 1; i < len(w.times); i++ {
+		ret = max(ret, w.times[i].Sub(w.times[i-1]))
+	}
+	return ret
+}
+
+// burst returns a PCR packet followed by 69 data packets, that are 100ms of stream.
+func burst(i int) []byte {
+	ret := pcrPacket(uint64(i) * 9000)
+	for j := 0; j < 69; j++ {
+		ret = append(ret, dataPacket(byte(j))...)
+	}
+	return ret
+}
+
+func TestPacedWriter(t *testing.T) {
+	// bursts are written every 100ms, directly or through PacedWriter,
+	// regardless of the time spent writing, like a live source.
+	writeBursts := func(w io.Writer) {
+		bw := bufio.NewWriterSize(w, 1316)
+		start := time.Now()
+
+		for i := 0; i < 6; i++ {
+			_, err := bw.Write(burst(i))
+			require.NoError(t, err)
+
+			err = bw.Flush()
+			require.NoError(t, err)
+
+			time.Sleep(time.Until(start.Add(time.Duration(i+1) * 100 * time.Millisecond)))
+		}
+	}
+
+	var direct timedWriter
+	writeBursts(&direct)
+
+	var paced timedWriter
+	w := &PacedWriter{
+		W:            &paced,
+		MaxWriteSize: 1316,
+		MaxDelay:     time.Second,
+		Done:         make(chan struct{}),
+	}
+	w.Initialize()
+	writeBursts(w)
+
+	// packets of the last burst are pending until the next PCR.
+	var expected []byte
+	for i := 0; i < 5; i++ {
+		expected = append(expected, burst(i)...)
+	}
+	require.Equal(t, expected, paced.buf.Bytes())
+
+	// without pacing, each burst is followed by a 100ms gap.
+	require.Greater(t, direct.maxInterval(), 80*time.Millisecond)
+
+	// with pacing, the 10 writes of each burst are spread over 100ms.
+	require.Less(t, paced.maxInterval(), 40*time.Millisecond)
+}
+
+func TestPacedWriterMaxDelay(t *testing.T) {
+	var out timedWriter
+	w := &PacedWriter{
+		W:            &out,
+		MaxWriteSize: 1316,
+		MaxDelay:     100 * time.Millisecond,
+		Done:         make(chan struct{}),
+	}
+	w.Initialize()
+
+	_, err := w.Write(burst(0))
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	// the PCR interval is 500ms, writes are delayed by MaxDelay at most.
+	_, err = w.Write(pcrPacket(5 * 9000))
+	require.NoError(t, err)
+
+	require.Less(t, time.Since(start), 200*time.Millisecond)
+	require.Equal(t, burst(0), out.buf.Bytes())
+}
+
+func TestPacedWriterDone(t *testing.T) {
+	done := make(chan struct{})
+
+	var out timedWriter
+	w := &PacedWriter{
+		W:            &out,
+		MaxWriteSize: 1316,
+		MaxDelay:     time.Second,
+		Done:         done,
+	}
+	w.Initialize()
+
+	_, err := w.Write(burst(0))
+	require.NoError(t, err)
+
+	close(done)
+
+	_, err = w.Write(pcrPacket(5 * 9000))
+	require.EqualError(t, err, "terminated")
+}
@@ -0,0 +1,220 @@
+package mpegts
+
+import (
+	"io"
+
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+)
+
+const (
+	// DefaultPMTPID is the PID of the program map table when a custom one is not set.
+	DefaultPMTPID = 0x1000
+
+	// MinPID is the minimum PID that can be assigned to tables and tracks.
+	MinPID = 0x0010
+
+	// MaxPID is the maximum PID that can be assigned to tables and tracks.
+	MaxPID = 0x1FFE
+
+	firstTrackPID = 256
+	packetSize    = 188
+)
+
+// PIDs contains custom PIDs of a MPEG-TS stream.
+type PIDs struct {
+	// PID of the program map table. When zero, the default one is used.
+	PMT uint16
+
+	// PIDs of tracks, in the same order of tracks.
+	// Tracks without a PID are assigned one automatically.
+	// DefaultPMTPID can't be used.
+	Tracks []uint16
+}
+
+func (p PIDs) pmt() uint16 {
+	if p.PMT != 0 {
+		return p.PMT
+	}
+	return DefaultPMTPID
+}
+
+// assign sets the PID of tracks.
+// Automatically assigned PIDs do not collide with custom ones.
+// The default PID of the program map table is never assigned to tracks,
+// since it is replaced when writing.
+func (p PIDs) assign(tracks []*mcmpegts.Track) {
+	used := map[uint16]struct{}{
+		DefaultPMTPID: {},
+		p.pmt():       {},
+	}
+
+	for i, track := range tracks {
+		if i < len(p.Tracks) {
+			track.PID = p.Tracks[i]
+			used[track.PID] = struct{}{}
+		}
+	}
+
+	next := uint16(firstTrackPID)
+
+	for i, track := range tracks {
+		if i < len(p.Tracks) {
+			continue
+		}
+
+		for {
+			if _, ok := used[next]; !ok {
+				break
+			}
+			next++
+		}
+
+		track.PID = next
+		used[next] = struct{}{}
+	}
+}
+
+// NewWriter allocates a MPEG-TS writer that uses the given PIDs.
+// When service is not empty, a service description table is written too.
+func NewWriter(bw io.Writer, tracks []*mcmpegts.Track, pids PIDs, service Service) *mcmpegts.Writer {
+	pids.assign(tracks)
+
+	if !service.isEmpty() {
+		bw = &sdtWriter{
+			w:       bw,
+			service: service,
+		}
+	}
+
+	if pids.pmt() != DefaultPMTPID {
+		bw = &pmtPIDWriter{
+			w:   bw,
+			pid: pids.pmt(),
+		}
+	}
+
+	return mcmpegts.NewWriter(bw, tracks)
+}
+
+var crc32Table = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		v := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if (v & 0x80000000) != 0 {
+				v = (v << 1) ^ 0x04C11DB7
+			} else {
+				v <<= 1
+			}
+		}
+		table[i] = v
+	}
+	return table
+}()
+
+// crc32MPEG2 computes the checksum of PSI sections.
+func crc32MPEG2(buf []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range buf {
+		crc = (crc << 8) ^ crc32Table[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// pmtPIDWriter replaces the default PID of the program map table with a custom one.
+// The PID is replaced in program map table packets and in the program association table.
+type pmtPIDWriter struct {
+	w   io.Writer
+	pid uint16
+
+	buf [packetSize]byte
+	n   int
+}
+
+// Write implements io.Writer.
+func (w *pmtPIDWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		n := copy(w.buf[w.n:], p)
+		w.n += n
+		p = p[n:]
+		written += n
+
+		if w.n == packetSize {
+			w.n = 0
+			w.processPacket(w.buf[:])
+
+			_, err := w.w.Write(w.buf[:])
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (w *pmtPIDWriter) processPacket(pkt []byte) {
+	pid := uint16(pkt[1]&0x1F)<<8 | uint16(pkt[2])
+
+	switch pid {
+	case DefaultPMTPID:
+		pkt[1] = (pkt[1] & 0xE0) | byte(w.pid>>8)
+		pkt[2] = byte(w.pid)
+
+	case 0:
+		w.processPAT(pkt)
+	}
+}
+
+func (w *pmtPIDWriter) processPAT(pkt []byte) {
+	// only packets that start a section are supported,
+	// since the program association table always fits into a single packet.
+	if (pkt[1] & 0x40) == 0 {
+		return
+	}
+
+	pos := 4
+
+	// adaptation field
+	if ((pkt[3] >> 4) & 0x02) != 0 {
+		pos += 1 + int(pkt[pos])
+	}
+
+	// pointer field
+	if pos >= packetSize {
+		return
+	}
+	pos += 1 + int(pkt[pos])
+
+	if (pos + 3) > packetSize {
+		return
+	}
+
+	sectionStart := pos
+	sectionLen := int(pkt[pos+1]&0x0F)<<8 | int(pkt[pos+2])
+	sectionEnd := sectionStart + 3 + sectionLen
+
+	if sectionLen < 9 || sectionEnd > packetSize {
+		return
+	}
+
+	crcPos := sectionEnd - 4
+
+	for pos = sectionStart + 8; (pos + 4) <= crcPos; pos += 4 {
+		programNumber := uint16(pkt[pos])<<8 | uint16(pkt[pos+1])
+		programPID := uint16(pkt[pos+2]&0x1F)<<8 | uint16(pkt[pos+3])
+
+		if programNumber != 0 && programPID == DefaultPMTPID {
+			pkt[pos+2] = (pkt[pos+2] & 0xE0) | byte(w.pid>>8)
+			pkt[pos+3] = byte(w.pid)
+		}
+	}
+
+	crc := crc32MPEG2(pkt[sectionStart:crcPos])
+	pkt[crcPos] = byte(crc >> 24)
+	pkt[crcPos+1] = byte(crc >> 16)
+	pkt[crcPos+2] = byte(crc >> 8)
+	pkt[crcPos+3] = byte(crc)
+}
@@ -0,0 +1,77 @@
+package mpegts
+
+import (
+	"bytes"
+	"testing"
+
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWriterPIDs(t *testing.T) {
+	for _, ca := range []struct {
+		name      string
+		pids      PIDs
+		pmtPID    uint16
+		trackPIDs []uint16
+	}{
+		{
+			"default",
+			PIDs{},
+			DefaultPMTPID,
+			[]uint16{256, 257},
+		},
+		{
+			"custom",
+			PIDs{
+				PMT:    256,
+				Tracks: []uint16{0x200},
+			},
+			256,
+			[]uint16{0x200, 257},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			tracks := []*mcmpegts.Track{
+				{Codec: &mcmpegts.CodecH264{}},
+				{Codec: &mcmpegts.CodecOpus{ChannelCount: 2}},
+			}
+
+			var buf bytes.Buffer
+			w := NewWriter(&buf, tracks, ca.pids, Service{})
+
+			err := w.WriteH264(tracks[0], 90000, 90000, true, [][]byte{
+				{5, 1}, // IDR
+			})
+			require.NoError(t, err)
+
+			err = w.WriteOpus(tracks[1], 90000, [][]byte{{1, 2}})
+			require.NoError(t, err)
+
+			byts := buf.Bytes()
+			require.Equal(t, 0, len(byts)%packetSize)
+
+			pids := make(map[uint16]struct{})
+			for i := 0; i < len(byts); i += packetSize {
+				pids[uint16(byts[i+1]&0x1F)<<8|uint16(byts[i+2])] = struct{}{}
+			}
+
+			expected := map[uint16]struct{}{
+				0:         {},
+				ca.pmtPID: {},
+			}
+			for _, pid := range ca.trackPIDs {
+				expected[pid] = struct{}{}
+			}
+			require.Equal(t, expected, pids)
+
+			r, err := mcmpegts.NewReader(bytes.NewReader(byts))
+			require.NoError(t, err)
+
+			require.Equal(t, []*mcmpegts.Track{
+				{PID: ca.trackPIDs[0], Codec: &mcmpegts.CodecH264{}},
+				{PID: ca.trackPIDs[1], Codec: &mcmpegts.CodecOpus{ChannelCount: 2}},
+			}, r.Tracks())
+		})
+	}
+}
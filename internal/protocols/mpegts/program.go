@@ -0,0 +1,41 @@
+package mpegts
+
+import (
+	"fmt"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+)
+
+// SelectProgram returns the medias that belong to a program.
+// Programs are delimited by video medias: each one begins with a video media
+// and contains the following non-video medias, while medias that precede
+// the first video media belong to the first program.
+// Programs are numbered from 1; 0 selects all medias.
+func SelectProgram(medias []*description.Media, program int) ([]*description.Media, error) {
+	if program == 0 {
+		return medias, nil
+	}
+
+	var ret []*description.Media
+	cur := 1
+	videoFound := false
+
+	for _, media := range medias {
+		if media.Type == description.MediaTypeVideo {
+			if videoFound {
+				cur++
+			}
+			videoFound = true
+		}
+
+		if cur == program {
+			ret = append(ret, media)
+		}
+	}
+
+	if ret == nil {
+		return nil, fmt.Errorf("program %d not found", program)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,33 @@
+package mpegts
+
+import (
+	"testing"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectProgram(t *testing.T) {
+	audio1 := &description.Media{Type: description.MediaTypeAudio}
+	video1 := &description.Media{Type: description.MediaTypeVideo}
+	audio2 := &description.Media{Type: description.MediaTypeAudio}
+	video2 := &description.Media{Type: description.MediaTypeVideo}
+	audio3 := &description.Media{Type: description.MediaTypeAudio}
+
+	medias := []*description.Media{audio1, video1, audio2, video2, audio3}
+
+	ret, err := SelectProgram(medias, 0)
+	require.NoError(t, err)
+	require.Equal(t, medias, ret)
+
+	ret, err = SelectProgram(medias, 1)
+	require.NoError(t, err)
+	require.Equal(t, []*description.Media{audio1, video1, audio2}, ret)
+
+	ret, err = SelectProgram(medias, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*description.Media{video2, audio3}, ret)
+
+	_, err = SelectProgram(medias, 3)
+	require.EqualError(t, err, "program 3 not found")
+}
@@ -0,0 +1,163 @@
+package mpegts
+
+import (
+	"io"
+)
+
+const (
+	sdtPID             = 0x0011
+	sdtTableID         = 0x42
+	originalNetworkID  = 0xFF01
+	serviceDescriptor  = 0x48
+	serviceTypeDigital = 0x01
+)
+
+// Service contains metadata of the MPEG-TS service, written into a service description table.
+// Name and Provider must be at most 64 bytes long, in order to fit into a single packet.
+type Service struct {
+	// Name of the service.
+	Name string
+
+	// Name of the service provider.
+	Provider string
+}
+
+func (s Service) isEmpty() bool {
+	return s.Name == "" && s.Provider == ""
+}
+
+// dvbString encodes a string as described in ETSI EN 300 468, annex A.
+// ASCII strings use the default character table, while the others are encoded in UTF-8.
+func dvbString(s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7E {
+			return append([]byte{0x15}, s...)
+		}
+	}
+	return []byte(s)
+}
+
+// sdtWriter writes a service description table after every program association table.
+// The transport stream ID and the program number are taken from the program association table.
+type sdtWriter struct {
+	w       io.Writer
+	service Service
+
+	buf     [packetSize]byte
+	n       int
+	counter byte
+}
+
+// Write implements io.Writer.
+func (w *sdtWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		n := copy(w.buf[w.n:], p)
+		w.n += n
+		p = p[n:]
+		written += n
+
+		if w.n == packetSize {
+			w.n = 0
+
+			_, err := w.w.Write(w.buf[:])
+			if err != nil {
+				return written, err
+			}
+
+			sdt := w.processPacket(w.buf[:])
+			if sdt != nil {
+				_, err = w.w.Write(sdt)
+				if err != nil {
+					return written, err
+				}
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (w *sdtWriter) processPacket(pkt []byte) []byte {
+	pid := uint16(pkt[1]&0x1F)<<8 | uint16(pkt[2])
+
+	// only packets that start a section are supported,
+	// since the program association table always fits into a single packet.
+	if pid != 0 || (pkt[1]&0x40) == 0 {
+		return nil
+	}
+
+	pos := 4
+
+	// adaptation field
+	if ((pkt[3] >> 4) & 0x02) != 0 {
+		pos += 1 + int(pkt[pos])
+	}
+
+	// pointer field
+	if pos >= packetSize {
+		return nil
+	}
+	pos += 1 + int(pkt[pos])
+
+	if (pos + 12) > packetSize {
+		return nil
+	}
+
+	tsID := uint16(pkt[pos+3])<<8 | uint16(pkt[pos+4])
+	programNumber := uint16(pkt[pos+8])<<8 | uint16(pkt[pos+9])
+
+	return w.marshalSDT(tsID, programNumber)
+}
+
+func (w *sdtWriter) marshalSDT(tsID uint16, programNumber uint16) []byte {
+	provider := dvbString(w.service.Provider)
+	name := dvbString(w.service.Name)
+
+	descriptor := []byte{serviceDescriptor, byte(3 + len(provider) + len(name)), serviceTypeDigital}
+	descriptor = append(descriptor, byte(len(provider)))
+	descriptor = append(descriptor, provider...)
+	descriptor = append(descriptor, byte(len(name)))
+	descriptor = append(descriptor, name...)
+
+	sectionLen := 8 + 5 + len(descriptor) + 4
+
+	section := []byte{
+		sdtTableID,
+		0xF0 | byte(sectionLen>>8), // section syntax indicator, reserved
+		byte(sectionLen),
+		byte(tsID >> 8),
+		byte(tsID),
+		0xC1, // reserved, version 0, current next indicator
+		0x00, // section number
+		0x00, // last section number
+		byte(originalNetworkID >> 8),
+		byte(originalNetworkID & 0xFF),
+		0xFF, // reserved
+		byte(programNumber >> 8),
+		byte(programNumber),
+		0xFC,                            // reserved, no EIT
+		0x80 | byte(len(descriptor)>>8), // running, not scrambled
+		byte(len(descriptor)),
+	}
+	section = append(section, descriptor...)
+
+	crc := crc32MPEG2(section)
+	section = append(section, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+
+	pkt := make([]byte, packetSize)
+	pkt[0] = 0x47
+	pkt[1] = 0x40 | byte(sdtPID>>8) // payload unit start indicator
+	pkt[2] = byte(sdtPID & 0xFF)
+	pkt[3] = 0x10 | w.counter // payload only
+	pkt[4] = 0x00             // pointer field
+	n := copy(pkt[5:], section)
+	for i := 5 + n; i < packetSize; i++ {
+		pkt[i] = 0xFF
+	}
+
+	w.counter = (w.counter + 1) & 0x0F
+
+	return pkt
+}
@@ -0,0 +1,94 @@
+package mpegts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/asticode/go-astits"
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWriterService(t *testing.T) {
+	for _, ca := range []struct {
+		name     string
+		service  Service
+		provider []byte
+		svcName  []byte
+	}{
+		{
+			"ascii",
+			Service{Name: "My channel", Provider: "My provider"},
+			[]byte("My provider"),
+			[]byte("My channel"),
+		},
+		{
+			"utf-8",
+			Service{Name: "Canal número 1", Provider: "Provedor"},
+			[]byte("Provedor"),
+			append([]byte{0x15}, "Canal número 1"...),
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			tracks := []*mcmpegts.Track{
+				{Codec: &mcmpegts.CodecH264{}},
+			}
+
+			var buf bytes.Buffer
+			w := NewWriter(&buf, tracks, PIDs{PMT: 0x20}, ca.service)
+
+			err := w.WriteH264(tracks[0], 90000, 90000, true, [][]byte{
+				{5, 1}, // IDR
+			})
+			require.NoError(t, err)
+
+			dem := astits.NewDemuxer(context.Background(), bytes.NewReader(buf.Bytes()))
+
+			var sdt *astits.SDTData
+
+			for {
+				data, err := dem.NextData()
+				if errors.Is(err, astits.ErrNoMorePackets) {
+					break
+				}
+				require.NoError(t, err)
+
+				if data.SDT != nil {
+					sdt = data.SDT
+				}
+			}
+
+			require.NotNil(t, sdt)
+			require.Equal(t, uint16(originalNetworkID), sdt.OriginalNetworkID)
+			require.Len(t, sdt.Services, 1)
+			require.Equal(t, uint16(1), sdt.Services[0].ServiceID)
+			require.Len(t, sdt.Services[0].Descriptors, 1)
+			require.Equal(t, &astits.DescriptorService{
+				Name:     ca.svcName,
+				Provider: ca.provider,
+				Type:     serviceTypeDigital,
+			}, sdt.Services[0].Descriptors[0].Service)
+		})
+	}
+}
+
+func TestNewWriterNoService(t *testing.T) {
+	tracks := []*mcmpegts.Track{
+		{Codec: &mcmpegts.CodecH264{}},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, tracks, PIDs{}, Service{})
+
+	err := w.WriteH264(tracks[0], 90000, 90000, true, [][]byte{
+		{5, 1}, // IDR
+	})
+	require.NoError(t, err)
+
+	byts := buf.Bytes()
+	for i := 0; i < len(byts); i += packetSize {
+		require.NotEqual(t, uint16(sdtPID), uint16(byts[i+1]&0x1F)<<8|uint16(byts[i+2]))
+	}
+}
@@ -2,9 +2,11 @@ package webrtc
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/gortsplib/v4/pkg/format/rtpav1"
 	"github.com/bluenviron/gortsplib/v4/pkg/format/rtph264"
@@ -13,6 +15,7 @@ import (
 	"github.com/bluenviron/gortsplib/v4/pkg/format/rtpvp8"
 	"github.com/bluenviron/gortsplib/v4/pkg/format/rtpvp9"
 	"github.com/bluenviron/mediacommon/pkg/codecs/g711"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/stream"
 	"github.com/bluenviron/mediamtx/internal/unit"
@@ -40,13 +43,35 @@ func randUint32() (uint32, error) {
 	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]), nil
 }
 
+// h265FMTP returns the fmtp line of a H265 track, as described in RFC7798.
+// When parameter sets are available, profile and tier are taken from the SPS,
+// in order to pick the matching client codec, and parameter sets are sent out of band.
+// level-id is left out since it states the decoding capability of the receiver
+// and would prevent exact matches with client codecs.
+func h265FMTP(forma *format.H265) string {
+	vps, sps, pps := forma.SafeParams()
+
+	var spsp h265.SPS
+	if vps == nil || sps == nil || pps == nil || spsp.Unmarshal(sps) != nil {
+		return "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST"
+	}
+
+	return fmt.Sprintf("profile-id=%d;tier-flag=%d;tx-mode=SRST;sprop-vps=%s;sprop-sps=%s;sprop-pps=%s",
+		spsp.ProfileTierLevel.GeneralProfileIdc,
+		spsp.ProfileTierLevel.GeneralTierFlag,
+		base64.StdEncoding.EncodeToString(vps),
+		base64.StdEncoding.EncodeToString(sps),
+		base64.StdEncoding.EncodeToString(pps))
+}
+
 func setupVideoTrack(
 	stream *stream.Stream,
 	reader stream.Reader,
+	desc *description.Session,
 	pc *PeerConnection,
 ) (format.Format, error) {
 	var av1Format *format.AV1
-	media := stream.Desc().FindFormat(&av1Format)
+	media := desc.FindFormat(&av1Format)
 
 	if av1Format != nil {
 		track := &OutgoingTrack{
@@ -94,7 +119,7 @@ func setupVideoTrack(
 	}
 
 	var vp9Format *format.VP9
-	media = stream.Desc().FindFormat(&vp9Format)
+	media = desc.FindFormat(&vp9Format)
 
 	if vp9Format != nil {
 		track := &OutgoingTrack{
@@ -144,7 +169,7 @@ func setupVideoTrack(
 	}
 
 	var vp8Format *format.VP8
-	media = stream.Desc().FindFormat(&vp8Format)
+	media = desc.FindFormat(&vp8Format)
 
 	if vp8Format != nil {
 		track := &OutgoingTrack{
@@ -192,14 +217,14 @@ func setupVideoTrack(
 	}
 
 	var h265Format *format.H265
-	media = stream.Desc().FindFormat(&h265Format)
+	media = desc.FindFormat(&h265Format)
 
 	if h265Format != nil { //nolint:dupl
 		track := &OutgoingTrack{
 			Caps: webrtc.RTPCodecCapability{
 				MimeType:    webrtc.MimeTypeH265,
 				ClockRate:   90000,
-				SDPFmtpLine: "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST",
+				SDPFmtpLine: h265FMTP(h265Format),
 			},
 		}
 		pc.OutgoingTracks = append(pc.OutgoingTracks, track)
@@ -251,7 +276,7 @@ func setupVideoTrack(
 	}
 
 	var h264Format *format.H264
-	media = stream.Desc().FindFormat(&h264Format)
+	media = desc.FindFormat(&h264Format)
 
 	if h264Format != nil { //nolint:dupl
 		track := &OutgoingTrack{
@@ -315,10 +340,11 @@ func setupVideoTrack(
 func setupAudioTrack(
 	stream *stream.Stream,
 	reader stream.Reader,
+	desc *description.Session,
 	pc *PeerConnection,
 ) (format.Format, error) {
 	var opusFormat *format.Opus
-	media := stream.Desc().FindFormat(&opusFormat)
+	media := desc.FindFormat(&opusFormat)
 
 	if opusFormat != nil {
 		var caps webrtc.RTPCodecCapability
@@ -371,7 +397,7 @@ func setupAudioTrack(
 	}
 
 	var g722Format *format.G722
-	media = stream.Desc().FindFormat(&g722Format)
+	media = desc.FindFormat(&g722Format)
 
 	if g722Format != nil {
 		track := &OutgoingTrack{
@@ -398,7 +424,7 @@ func setupAudioTrack(
 	}
 
 	var g711Format *format.G711
-	media = stream.Desc().FindFormat(&g711Format)
+	media = desc.FindFormat(&g711Format)
 
 	if g711Format != nil {
 		// These are the sample rates and channels supported by Chrome.
@@ -534,7 +560,7 @@ func setupAudioTrack(
 	}
 
 	var lpcmFormat *format.LPCM
-	media = stream.Desc().FindFormat(&lpcmFormat)
+	media = desc.FindFormat(&lpcmFormat)
 
 	if lpcmFormat != nil {
 		if lpcmFormat.BitDepth != 16 {
@@ -611,18 +637,22 @@ func setupAudioTrack(
 	return nil, nil
 }
 
-// FromStream maps a MediaMTX stream to a WebRTC connection
+// FromStream maps a MediaMTX stream to a WebRTC connection.
+// Tracks are picked among the given medias.
 func FromStream(
 	stream *stream.Stream,
 	reader stream.Reader,
+	medias []*description.Media,
 	pc *PeerConnection,
 ) error {
-	videoFormat, err := setupVideoTrack(stream, reader, pc)
+	desc := &description.Session{Medias: medias}
+
+	videoFormat, err := setupVideoTrack(stream, reader, desc, pc)
 	if err != nil {
 		return err
 	}
 
-	audioFormat, err := setupAudioTrack(stream, reader, pc)
+	audioFormat, err := setupAudioTrack(stream, reader, desc, pc)
 	if err != nil {
 		return err
 	}
@@ -632,7 +662,7 @@ func FromStream(
 	}
 
 	n := 1
-	for _, media := range stream.Desc().Medias {
+	for _, media := range medias {
 		for _, forma := range media.Formats {
 			if forma != videoFormat && forma != audioFormat {
 				reader.Log(logger.Warn, "skipping track %d (%s)", n, forma.Codec())
@@ -9,6 +9,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/stream"
 	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,6 +22,9 @@ func TestFromStreamNoSupportedCodecs(t *testing.T) {
 			Formats: []format.Format{&format.MJPEG{}},
 		}}},
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -29,7 +33,7 @@ func TestFromStreamNoSupportedCodecs(t *testing.T) {
 		t.Error("should not happen")
 	})
 
-	err = FromStream(stream, l, nil)
+	err = FromStream(stream, l, stream.Desc().Medias, nil)
 	require.Equal(t, errNoSupportedCodecsFrom, err)
 }
 
@@ -48,6 +52,9 @@ func TestFromStreamSkipUnsupportedTracks(t *testing.T) {
 			},
 		}},
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -64,7 +71,7 @@ func TestFromStreamSkipUnsupportedTracks(t *testing.T) {
 
 	pc := &PeerConnection{}
 
-	err = FromStream(stream, l, pc)
+	err = FromStream(stream, l, stream.Desc().Medias, pc)
 	require.NoError(t, err)
 	defer stream.RemoveReader(l)
 
@@ -83,6 +90,9 @@ func TestFromStream(t *testing.T) {
 					}},
 				},
 				false,
+				0,
+				0,
+				0,
 				test.NilLogger,
 			)
 			require.NoError(t, err)
@@ -90,7 +100,7 @@ func TestFromStream(t *testing.T) {
 
 			pc := &PeerConnection{}
 
-			err = FromStream(stream, nil, pc)
+			err = FromStream(stream, nil, stream.Desc().Medias, pc)
 			require.NoError(t, err)
 			defer stream.RemoveReader(nil)
 
@@ -98,3 +108,37 @@ func TestFromStream(t *testing.T) {
 		})
 	}
 }
+
+func TestFromStreamH265Params(t *testing.T) {
+	stream, err := stream.New(
+		512,
+		1460,
+		&description.Session{
+			Medias: []*description.Media{{
+				Formats: []format.Format{test.FormatH265},
+			}},
+		},
+		false,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	pc := &PeerConnection{}
+
+	err = FromStream(stream, nil, stream.Desc().Medias, pc)
+	require.NoError(t, err)
+	defer stream.RemoveReader(nil)
+
+	require.Equal(t, webrtc.RTPCodecCapability{
+		MimeType:  "video/H265",
+		ClockRate: 90000,
+		SDPFmtpLine: "profile-id=2;tier-flag=0;tx-mode=SRST;" +
+			"sprop-vps=QAEMAf//AiAAAAMAsAAAAwAAAwB7GLAk;" +
+			"sprop-sps=QgEBAiAAAAMAsAAAAwAAAwB7oAeCAIh9tnGLkkSAU4iIks8kppJyyRJJItyRqkj8oiP/AAEAAWoCAgIB;" +
+			"sprop-pps=RAHAJS8FMkA=",
+	}, pc.OutgoingTracks[0].Caps)
+}
@@ -258,6 +258,14 @@ func (*IncomingTrack) PTSEqualsDTS(*rtp.Packet) bool {
 	return true
 }
 
+func (t *IncomingTrack) requestKeyFrame() error {
+	return t.writeRTCP([]rtcp.Packet{
+		&rtcp.PictureLossIndication{
+			MediaSSRC: uint32(t.track.SSRC()),
+		},
+	})
+}
+
 func (t *IncomingTrack) start() {
 	// read incoming RTCP packets to make interceptors work
 	go func() {
@@ -277,11 +285,7 @@ func (t *IncomingTrack) start() {
 			defer keyframeTicker.Stop()
 
 			for range keyframeTicker.C {
-				err := t.writeRTCP([]rtcp.Packet{
-					&rtcp.PictureLossIndication{
-						MediaSSRC: uint32(t.track.SSRC()),
-					},
-				})
+				err := t.requestKeyFrame()
 				if err != nil {
 					return
 				}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/pion/ice/v2"
 	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 
@@ -20,6 +21,7 @@ import (
 
 const (
 	webrtcStreamID = "mediamtx"
+	rembInterval   = 1 * time.Second
 )
 
 func stringInSlice(a string, list []string) bool {
@@ -70,6 +72,7 @@ type trackRecvPair struct {
 // PeerConnection is a wrapper around webrtc.PeerConnection.
 type PeerConnection struct {
 	ICEServers            []webrtc.ICEServer
+	ICETransportPolicy    webrtc.ICETransportPolicy
 	ICEUDPMux             ice.UDPMux
 	ICETCPMux             ice.TCPMux
 	HandshakeTimeout      conf.StringDuration
@@ -80,6 +83,7 @@ type PeerConnection struct {
 	AdditionalHosts       []string
 	Publish               bool
 	OutgoingTracks        []*OutgoingTrack
+	MaxBitrate            uint
 	Log                   logger.Writer
 
 	wr                *webrtc.PeerConnection
@@ -179,6 +183,11 @@ func (co *PeerConnection) Start() error {
 		}
 	} else {
 		for _, codec := range incomingVideoCodecs {
+			// REMB is needed to communicate the maximum bitrate to the sender
+			if co.MaxBitrate != 0 {
+				codec.RTCPFeedback = append(codec.RTCPFeedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBGoogREMB})
+			}
+
 			err := mediaEngine.RegisterCodec(codec, webrtc.RTPCodecTypeVideo)
 			if err != nil {
 				return err
@@ -206,7 +215,8 @@ func (co *PeerConnection) Start() error {
 		webrtc.WithInterceptorRegistry(interceptorRegistry))
 
 	co.wr, err = api.NewPeerConnection(webrtc.Configuration{
-		ICEServers: co.ICEServers,
+		ICEServers:         co.ICEServers,
+		ICETransportPolicy: co.ICETransportPolicy,
 	})
 	if err != nil {
 		return err
@@ -493,6 +503,48 @@ func (co *PeerConnection) StartReading() {
 	for _, track := range co.incomingTracks {
 		track.start()
 	}
+
+	if co.MaxBitrate != 0 {
+		go co.runREMB()
+	}
+}
+
+// RequestKeyFrame asks the sender to send a key frame on all incoming video tracks.
+func (co *PeerConnection) RequestKeyFrame() {
+	for _, track := range co.incomingTracks {
+		if track.track.Kind() == webrtc.RTPCodecTypeVideo {
+			track.requestKeyFrame() //nolint:errcheck
+		}
+	}
+}
+
+// runREMB periodically sends the maximum bitrate to the sender.
+func (co *PeerConnection) runREMB() {
+	ssrcs := make([]uint32, len(co.incomingTracks))
+	for i, track := range co.incomingTracks {
+		ssrcs[i] = uint32(track.track.SSRC())
+	}
+
+	t := time.NewTicker(rembInterval)
+	defer t.Stop()
+
+	for {
+		err := co.wr.WriteRTCP([]rtcp.Packet{
+			&rtcp.ReceiverEstimatedMaximumBitrate{
+				Bitrate: float32(co.MaxBitrate),
+				SSRCs:   ssrcs,
+			},
+		})
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-t.C:
+		case <-co.ctx.Done():
+			return
+		}
+	}
 }
 
 // RemoteCandidate returns the remote candidate.
@@ -7,6 +7,8 @@ import (
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
@@ -99,3 +101,154 @@ func TestPeerConnectionFallbackCodecs(t *testing.T) {
 		},
 	}, s.MediaDescriptions)
 }
+
+func TestPeerConnectionMaxBitrate(t *testing.T) {
+	mediaEngine := &webrtc.MediaEngine{}
+	err := mediaEngine.RegisterDefaultCodecs()
+	require.NoError(t, err)
+
+	settingsEngine := webrtc.SettingEngine{}
+	settingsEngine.SetICEUDPRandom(true)
+	settingsEngine.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+
+	pub, err := webrtc.NewAPI(
+		webrtc.WithMediaEngine(mediaEngine),
+		webrtc.WithSettingEngine(settingsEngine),
+	).NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer pub.Close() //nolint:errcheck
+
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{
+		MimeType:  webrtc.MimeTypeVP8,
+		ClockRate: 90000,
+	}, "video", "mediamtx")
+	require.NoError(t, err)
+
+	sender, err := pub.AddTrack(track)
+	require.NoError(t, err)
+
+	offer, err := pub.CreateOffer(nil)
+	require.NoError(t, err)
+
+	gatherComplete := webrtc.GatheringCompletePromise(pub)
+
+	err = pub.SetLocalDescription(offer)
+	require.NoError(t, err)
+
+	<-gatherComplete
+
+	pc := &PeerConnection{
+		HandshakeTimeout:   conf.StringDuration(10 * time.Second),
+		TrackGatherTimeout: conf.StringDuration(2 * time.Second),
+		LocalRandomUDP:     true,
+		IPsFromInterfaces:  true,
+		Publish:            false,
+		MaxBitrate:         500000,
+		Log:                test.NilLogger,
+	}
+	err = pc.Start()
+	require.NoError(t, err)
+	defer pc.Close()
+
+	answer, err := pc.CreateFullAnswer(context.Background(), pub.LocalDescription())
+	require.NoError(t, err)
+
+	require.Contains(t, answer.SDP, "a=rtcp-fb:96 goog-remb")
+
+	err = pub.SetRemoteDescription(*answer)
+	require.NoError(t, err)
+
+	err = pc.WaitUntilReady(context.Background())
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			track.WriteRTP(&rtp.Packet{ //nolint:errcheck
+				Header: rtp.Header{
+					Version:        2,
+					Marker:         true,
+					SequenceNumber: uint16(i),
+					Timestamp:      uint32(i) * 4500,
+				},
+				Payload: []byte{1},
+			})
+		}
+	}()
+
+	_, err = pc.GatherIncomingTracks(context.Background())
+	require.NoError(t, err)
+
+	pc.StartReading()
+
+	for {
+		pkts, _, err := sender.ReadRTCP()
+		require.NoError(t, err)
+
+		for _, pkt := range pkts {
+			if remb, ok := pkt.(*rtcp.ReceiverEstimatedMaximumBitrate); ok {
+				require.Equal(t, float32(500000), remb.Bitrate)
+				require.Equal(t, []uint32{uint32(sender.GetParameters().Encodings[0].SSRC)}, remb.SSRCs)
+				return
+			}
+		}
+	}
+}
+
+func TestPeerConnectionRelayOnly(t *testing.T) {
+	pc1 := &PeerConnection{
+		HandshakeTimeout:   conf.StringDuration(10 * time.Second),
+		TrackGatherTimeout: conf.StringDuration(2 * time.Second),
+		LocalRandomUDP:     true,
+		IPsFromInterfaces:  true,
+		Publish:            false,
+		Log:                test.NilLogger,
+	}
+	err := pc1.Start()
+	require.NoError(t, err)
+	defer pc1.Close()
+
+	pc2 := &PeerConnection{
+		ICETransportPolicy: webrtc.ICETransportPolicyRelay,
+		HandshakeTimeout:   conf.StringDuration(1 * time.Second),
+		TrackGatherTimeout: conf.StringDuration(2 * time.Second),
+		LocalRandomUDP:     true,
+		IPsFromInterfaces:  true,
+		Publish:            true,
+		OutgoingTracks: []*OutgoingTrack{{
+			Caps: webrtc.RTPCodecCapability{
+				MimeType:  webrtc.MimeTypeVP8,
+				ClockRate: 90000,
+			},
+		}},
+		Log: test.NilLogger,
+	}
+	err = pc2.Start()
+	require.NoError(t, err)
+	defer pc2.Close()
+
+	require.Equal(t, webrtc.ICETransportPolicyRelay, pc2.wr.GetConfiguration().ICETransportPolicy)
+
+	offer, err := pc1.CreatePartialOffer()
+	require.NoError(t, err)
+
+	// without TURN servers, no candidate is gathered
+	answer, err := pc2.CreateFullAnswer(context.Background(), offer)
+	require.NoError(t, err)
+	require.NotContains(t, answer.SDP, "a=candidate")
+
+	err = pc1.SetAnswer(answer)
+	require.NoError(t, err)
+
+	// the handshake fails instead of hanging
+	err = pc2.WaitUntilReady(context.Background())
+	require.EqualError(t, err, "deadline exceeded while waiting connection")
+}
@@ -106,6 +106,11 @@ func (c *ServerConn) WriteJSON(in interface{}) error {
 		return err
 	}
 
+	return c.WriteText(byts)
+}
+
+// WriteText writes a text message.
+func (c *ServerConn) WriteText(byts []byte) error {
 	select {
 	case c.write <- byts:
 		return <-c.writeErr
@@ -28,6 +28,7 @@ const (
 type Client struct {
 	HTTPClient *http.Client
 	URL        *url.URL
+	MaxBitrate uint
 	Log        logger.Writer
 
 	pc               *webrtc.PeerConnection
@@ -127,6 +128,7 @@ func (c *Client) Read(ctx context.Context) ([]*webrtc.IncomingTrack, error) {
 		LocalRandomUDP:     true,
 		IPsFromInterfaces:  true,
 		Publish:            false,
+		MaxBitrate:         c.MaxBitrate,
 		Log:                c.Log,
 	}
 	err = c.pc.Start()
@@ -1,23 +1,24 @@
 package whip
 
 import (
-	"encoding/json"
 	"fmt"
-	"regexp"
+	"strings"
 
 	"github.com/pion/webrtc/v3"
 )
 
-func quoteCredential(v string) string {
-	b, _ := json.Marshal(v)
-	s := string(b)
-	return s[1 : len(s)-1]
-}
-
-func unquoteCredential(v string) string {
-	var s string
-	json.Unmarshal([]byte("\""+v+"\""), &s) //nolint:errcheck
-	return s
+// quoteString encodes a quoted-string, as described in RFC 9110, section 5.6.4.
+func quoteString(v string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(v); i++ {
+		if v[i] == '"' || v[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(v[i])
+	}
+	b.WriteByte('"')
+	return b.String()
 }
 
 // LinkHeaderMarshal encodes a link header.
@@ -27,8 +28,8 @@ func LinkHeaderMarshal(iceServers []webrtc.ICEServer) []string {
 	for i, server := range iceServers {
 		link := "<" + server.URLs[0] + ">; rel=\"ice-server\""
 		if server.Username != "" {
-			link += "; username=\"" + quoteCredential(server.Username) + "\"" +
-				"; credential=\"" + quoteCredential(server.Credential.(string)) + "\"; credential-type=\"password\""
+			link += "; username=" + quoteString(server.Username) +
+				"; credential=" + quoteString(server.Credential.(string)) + "; credential-type=\"password\""
 		}
 		ret[i] = link
 	}
@@ -36,30 +37,187 @@ func LinkHeaderMarshal(iceServers []webrtc.ICEServer) []string {
 	return ret
 }
 
-var reLink = regexp.MustCompile(`^<(.+?)>; rel="ice-server"(; username="(.+?)"` +
-	`; credential="(.+?)"; credential-type="password")?`)
+type linkHeaderParser struct {
+	s   string
+	pos int
+}
 
-// LinkHeaderUnmarshal decodes a link header.
-func LinkHeaderUnmarshal(link []string) ([]webrtc.ICEServer, error) {
-	ret := make([]webrtc.ICEServer, len(link))
+func (p *linkHeaderParser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *linkHeaderParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *linkHeaderParser) peek() byte {
+	return p.s[p.pos]
+}
+
+func (p *linkHeaderParser) readURL() (string, error) {
+	if p.done() || p.peek() != '<' {
+		return "", fmt.Errorf("URL not found")
+	}
+
+	end := strings.IndexByte(p.s[p.pos:], '>')
+	if end < 0 {
+		return "", fmt.Errorf("URL is not terminated")
+	}
+
+	u := p.s[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+	return u, nil
+}
+
+func (p *linkHeaderParser) readToken() string {
+	start := p.pos
+	for !p.done() && !strings.ContainsRune("=;, \t\"", rune(p.peek())) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *linkHeaderParser) readQuotedString() (string, error) {
+	p.pos++
+
+	var b strings.Builder
+	for {
+		if p.done() {
+			return "", fmt.Errorf("quoted string is not terminated")
+		}
+
+		c := p.peek()
+		p.pos++
+
+		switch c {
+		case '"':
+			return b.String(), nil
+
+		case '\\':
+			if p.done() {
+				return "", fmt.Errorf("quoted string is not terminated")
+			}
+			b.WriteByte(p.peek())
+			p.pos++
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
 
-	for i, li := range link {
-		m := reLink.FindStringSubmatch(li)
-		if m == nil {
-			return nil, fmt.Errorf("invalid link header: '%s'", li)
+// readLink reads a link and its parameters, as described in RFC 8288, section 3.
+func (p *linkHeaderParser) readLink() (string, map[string]string, error) {
+	p.skipSpaces()
+
+	u, err := p.readURL()
+	if err != nil {
+		return "", nil, err
+	}
+
+	params := make(map[string]string)
+
+	for {
+		p.skipSpaces()
+
+		if p.done() {
+			return u, params, nil
+		}
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+			return u, params, nil
+
+		case ';':
+			p.pos++
+
+		default:
+			return "", nil, fmt.Errorf("unexpected character '%c'", p.peek())
+		}
+
+		p.skipSpaces()
+
+		name := strings.ToLower(p.readToken())
+		if name == "" {
+			return "", nil, fmt.Errorf("empty parameter name")
+		}
+
+		p.skipSpaces()
+
+		var value string
+
+		if !p.done() && p.peek() == '=' {
+			p.pos++
+			p.skipSpaces()
+
+			if !p.done() && p.peek() == '"' {
+				value, err = p.readQuotedString()
+				if err != nil {
+					return "", nil, err
+				}
+			} else {
+				value = p.readToken()
+			}
 		}
 
-		s := webrtc.ICEServer{
-			URLs: []string{m[1]},
+		// occurrences after the first one must be ignored
+		if _, ok := params[name]; !ok {
+			params[name] = value
 		}
+	}
+}
 
-		if m[3] != "" {
-			s.Username = unquoteCredential(m[3])
-			s.Credential = unquoteCredential(m[4])
-			s.CredentialType = webrtc.ICECredentialTypePassword
+func isICEServerRelation(rel string) bool {
+	for _, r := range strings.Fields(rel) {
+		if strings.EqualFold(r, "ice-server") {
+			return true
 		}
+	}
+	return false
+}
+
+// LinkHeaderUnmarshal decodes a link header.
+// Links with a relation different than "ice-server" are ignored.
+func LinkHeaderUnmarshal(link []string) ([]webrtc.ICEServer, error) {
+	ret := []webrtc.ICEServer{}
 
-		ret[i] = s
+	for _, li := range link {
+		p := &linkHeaderParser{s: li}
+
+		for {
+			p.skipSpaces()
+			if p.done() {
+				break
+			}
+
+			u, params, err := p.readLink()
+			if err != nil {
+				return nil, fmt.Errorf("invalid link header: '%s': %w", li, err)
+			}
+
+			if !isICEServerRelation(params["rel"]) {
+				continue
+			}
+
+			s := webrtc.ICEServer{
+				URLs: []string{u},
+			}
+
+			if username, ok := params["username"]; ok {
+				if ct, ok := params["credential-type"]; ok && ct != "password" {
+					return nil, fmt.Errorf("invalid link header: '%s': unsupported credential type '%s'", li, ct)
+				}
+
+				s.Username = username
+				s.Credential = params["credential"]
+				s.CredentialType = webrtc.ICECredentialTypePassword
+			}
+
+			ret = append(ret, s)
+		}
 	}
 
 	return ret, nil
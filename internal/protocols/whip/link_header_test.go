@@ -30,6 +30,20 @@ var linkHeaderCases = []struct {
 			},
 		},
 	},
+	{
+		"escaping",
+		[]string{
+			`<turn:turn.example.com:3478?transport=udp>; rel="ice-server"; username="1700000000:a\\b"; ` +
+				`credential="p&<>\"w=d+/"; credential-type="password"`,
+		},
+		[]webrtc.ICEServer{
+			{
+				URLs:       []string{"turn:turn.example.com:3478?transport=udp"},
+				Username:   "1700000000:a\\b",
+				Credential: "p&<>\"w=d+/",
+			},
+		},
+	},
 }
 
 func TestLinkHeaderUnmarshal(t *testing.T) {
@@ -42,6 +56,56 @@ func TestLinkHeaderUnmarshal(t *testing.T) {
 	}
 }
 
+func TestLinkHeaderUnmarshalMultiple(t *testing.T) {
+	dec, err := LinkHeaderUnmarshal([]string{
+		`<https://example.com/style.css>; rel=preload, <stun:stun.example.com>;rel=ice-server`,
+		`<turn:turn.example.com>; credential-type="password"; credential="mypwd"; rel="ice-server"; ` +
+			`username=myuser`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []webrtc.ICEServer{
+		{
+			URLs: []string{"stun:stun.example.com"},
+		},
+		{
+			URLs:       []string{"turn:turn.example.com"},
+			Username:   "myuser",
+			Credential: "mypwd",
+		},
+	}, dec)
+}
+
+func TestLinkHeaderUnmarshalErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		enc  string
+		err  string
+	}{
+		{
+			"missing url",
+			`stun:stun.example.com; rel="ice-server"`,
+			"invalid link header: 'stun:stun.example.com; rel=\"ice-server\"': URL not found",
+		},
+		{
+			"unterminated string",
+			`<turn:turn.example.com>; rel="ice-server"; username="myuser`,
+			"invalid link header: '<turn:turn.example.com>; rel=\"ice-server\"; username=\"myuser': " +
+				"quoted string is not terminated",
+		},
+		{
+			"unsupported credential type",
+			`<turn:turn.example.com>; rel="ice-server"; username="myuser"; credential="a"; credential-type="oauth"`,
+			"invalid link header: '<turn:turn.example.com>; rel=\"ice-server\"; username=\"myuser\"; " +
+				"credential=\"a\"; credential-type=\"oauth\"': unsupported credential type 'oauth'",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			_, err := LinkHeaderUnmarshal([]string{ca.enc})
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
+
 func TestLinkHeaderMarshal(t *testing.T) {
 	for _, ca := range linkHeaderCases {
 		t.Run(ca.name, func(t *testing.T) {
@@ -3,7 +3,6 @@ package recordcleaner
 
 import (
 	"context"
-	"os"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -123,10 +122,16 @@ func (c *Cleaner) processPath(now time.Time, pathName string) error {
 		return err
 	}
 
-	for _, seg := range segments {
+	for i, seg := range segments {
 		if now.Sub(seg.Start) > time.Duration(pathConf.RecordDeleteAfter) {
+			// segments can be written by other processes
+			if recordstore.SegmentInUse(seg.Fpath, time.Now()) {
+				c.Log(logger.Debug, "skipping %s since it is being written", seg.Fpath)
+				continue
+			}
+
 			c.Log(logger.Debug, "removing %s", seg.Fpath)
-			os.Remove(seg.Fpath)
+			recordstore.RemoveSegment(segments, i)
 		}
 	}
 
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/stretchr/testify/require"
 )
@@ -105,3 +106,58 @@ func TestCleanerMultipleEntriesSamePath(t *testing.T) {
 	_, err = os.Stat(filepath.Join(dir, "path2", "2009-05-19_22-15-25-000427.mp4"))
 	require.NoError(t, err)
 }
+
+func TestCleanerSkipSegmentsInUse(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2009, 5, 20, 22, 15, 25, 427000, time.Local)
+	}
+
+	dir, err := os.MkdirTemp("", "mediamtx-cleaner")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	inUsePath := filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000125.mp4")
+	stalePath := filepath.Join(dir, "mypath", "2008-05-20_22-15-26-000125.mp4")
+
+	for _, fpath := range []string{inUsePath, stalePath} {
+		err = os.WriteFile(fpath, []byte{1}, 0o644)
+		require.NoError(t, err)
+
+		err = recordstore.CreateWritingMarker(fpath)
+		require.NoError(t, err)
+	}
+
+	err = recordstore.RefreshWritingMarker(stalePath, time.Now().Add(-recordstore.WritingMarkerStaleTimeout))
+	require.NoError(t, err)
+
+	c := &Cleaner{
+		PathConfs: map[string]*conf.Path{
+			"mypath": {
+				Name:              "mypath",
+				RecordPath:        filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+				RecordFormat:      conf.RecordFormatFMP4,
+				RecordDeleteAfter: conf.StringDuration(10 * time.Second),
+			},
+		},
+		Parent: test.NilLogger,
+	}
+	c.Initialize()
+	defer c.Close()
+
+	time.Sleep(500 * time.Millisecond)
+
+	_, err = os.Stat(inUsePath)
+	require.NoError(t, err)
+
+	_, err = os.Stat(recordstore.WritingMarkerPath(inUsePath))
+	require.NoError(t, err)
+
+	_, err = os.Stat(stalePath)
+	require.Error(t, err)
+
+	_, err = os.Stat(recordstore.WritingMarkerPath(stalePath))
+	require.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package recorder
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/captions"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
+)
+
+type formatCaptionsEntry struct {
+	pts   time.Duration
+	pairs []captions.CEA608Pair
+}
+
+// formatCaptions extracts CEA-608 captions from a H264 track
+// and writes them into a WebVTT file next to each segment.
+type formatCaptions struct {
+	decoder captions.CEA608Decoder
+	pending []formatCaptionsEntry
+	cues    []*captions.Cue
+}
+
+func (c *formatCaptions) writeH264(au [][]byte, pts time.Duration, dts time.Duration) {
+	pairs := captions.ExtractH264CEA608(au)
+	if len(pairs) != 0 {
+		c.pending = append(c.pending, formatCaptionsEntry{
+			pts:   pts,
+			pairs: pairs,
+		})
+	}
+
+	// captions must be decoded in presentation order.
+	// Since following access units have a greater DTS,
+	// entries with a PTS lower than the current DTS can be decoded.
+	sort.SliceStable(c.pending, func(i, j int) bool {
+		return c.pending[i].pts < c.pending[j].pts
+	})
+
+	n := 0
+	for _, e := range c.pending {
+		if e.pts > dts {
+			break
+		}
+
+		for _, pair := range e.pairs {
+			c.cues = append(c.cues, c.decoder.Decode(pair, e.pts)...)
+		}
+		n++
+	}
+
+	c.pending = c.pending[n:]
+}
+
+// writeSegment writes cues that are displayed between start and end.
+func (c *formatCaptions) writeSegment(segmentPath string, start time.Duration, end time.Duration) error {
+	var segmentCues []*captions.Cue
+
+	clip := func(cueStart time.Duration, cueEnd time.Duration, text string) {
+		if cueStart < start {
+			cueStart = start
+		}
+		if cueEnd > end {
+			cueEnd = end
+		}
+		if cueEnd > cueStart {
+			segmentCues = append(segmentCues, &captions.Cue{
+				Start: cueStart - start,
+				End:   cueEnd - start,
+				Text:  text,
+			})
+		}
+	}
+
+	n := 0
+	for _, cue := range c.cues {
+		clip(cue.Start, cue.End, cue.Text)
+
+		// keep cues that continue in the next segment
+		if cue.End > end {
+			c.cues[n] = cue
+			n++
+		}
+	}
+	c.cues = c.cues[:n]
+
+	// caption that is still displayed
+	if text, displayedStart := c.decoder.Displayed(); text != "" {
+		clip(displayedStart, end, text)
+	}
+
+	if len(segmentCues) == 0 {
+		return nil
+	}
+
+	return os.WriteFile(recordstore.CaptionsPath(segmentPath), captions.MarshalWebVTT(segmentCues), 0o644)
+}
@@ -97,8 +97,10 @@ type formatFMP4 struct {
 
 	tracks             []*formatFMP4Track
 	hasVideo           bool
+	captions           *formatCaptions
 	currentSegment     *formatFMP4Segment
 	nextSequenceNumber uint32
+	separateInit       []byte
 }
 
 func (f *formatFMP4) initialize() bool {
@@ -135,8 +137,17 @@ func (f *formatFMP4) initialize() bool {
 		}
 	}
 
+	if len(f.ri.selectedFormats) == 0 {
+		f.ri.Log(logger.Error, "no tracks match recordTracks, skipping recording")
+		return false
+	}
+
 	for _, media := range f.ri.rec.Stream.Desc().Medias {
 		for _, forma := range media.Formats {
+			if !f.ri.isSelected(forma) {
+				continue
+			}
+
 			clockRate := forma.ClockRate()
 
 			switch forma := forma.(type) {
@@ -377,6 +388,12 @@ func (f *formatFMP4) initialize() bool {
 
 				var dtsExtractor *h264.DTSExtractor2
 
+				var captions *formatCaptions
+				if f.ri.rec.Captions && f.captions == nil {
+					captions = &formatCaptions{}
+					f.captions = captions
+				}
+
 				f.ri.rec.Stream.AddReader(
 					f.ri,
 					media,
@@ -421,6 +438,12 @@ func (f *formatFMP4) initialize() bool {
 							return err
 						}
 
+						if captions != nil {
+							captions.writeH264(tunit.AU,
+								timestampToDuration(tunit.PTS, clockRate),
+								timestampToDuration(dts, clockRate))
+						}
+
 						sampl, err := fmp4.NewPartSampleH26x(
 							int32(tunit.PTS-dts),
 							randomAccess,
@@ -858,7 +881,7 @@ func (f *formatFMP4) initialize() bool {
 	n := 1
 	for _, medi := range f.ri.rec.Stream.Desc().Medias {
 		for _, forma := range medi.Formats {
-			if _, ok := setuppedFormatsMap[forma]; !ok {
+			if _, ok := setuppedFormatsMap[forma]; !ok && f.ri.isSelected(forma) {
 				f.ri.Log(logger.Warn, "skipping track %d (%s)", n, forma.Codec())
 			}
 			n++
@@ -871,6 +894,27 @@ func (f *formatFMP4) initialize() bool {
 	return true
 }
 
+// fillGaps writes empty samples into tracks that stopped receiving samples
+// while another track keeps receiving them.
+func (f *formatFMP4) fillGaps(cur *formatFMP4Track, dtsDuration time.Duration) error {
+	for _, track := range f.tracks {
+		if track == cur || track.nextSample == nil {
+			continue
+		}
+
+		trackDTSDuration := timestampToDuration(track.nextSample.dts, int(track.initTrack.TimeScale))
+
+		if (dtsDuration - trackDTSDuration) >= fmp4GapDuration {
+			err := track.fill(dtsDuration)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (f *formatFMP4) close() {
 	if f.currentSegment != nil {
 		for _, track := range f.tracks {
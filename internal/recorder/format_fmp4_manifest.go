@@ -0,0 +1,134 @@
+package recorder
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+
+	"github.com/bluenviron/mediamtx/internal/recordstore"
+)
+
+// manifestTrack contains the parameters of a track,
+// as they are written into the initialization section of the segment.
+// Binary parameters are encoded in hex.
+type manifestTrack struct {
+	ID             int    `json:"id"`
+	TimeScale      uint32 `json:"timeScale"`
+	Codec          string `json:"codec"`
+	VPS            string `json:"vps,omitempty"`
+	SPS            string `json:"sps,omitempty"`
+	PPS            string `json:"pps,omitempty"`
+	SequenceHeader string `json:"sequenceHeader,omitempty"`
+	Config         string `json:"config,omitempty"`
+	Width          int    `json:"width,omitempty"`
+	Height         int    `json:"height,omitempty"`
+	SampleRate     int    `json:"sampleRate,omitempty"`
+	ChannelCount   int    `json:"channelCount,omitempty"`
+	BitDepth       int    `json:"bitDepth,omitempty"`
+}
+
+// manifest describes a segment, in order to allow to process it without parsing it.
+type manifest struct {
+	Start    time.Time       `json:"start"`
+	Duration float64         `json:"duration"`
+	Tracks   []manifestTrack `json:"tracks"`
+}
+
+func newManifestTrack(initTrack *fmp4.InitTrack) manifestTrack {
+	t := manifestTrack{
+		ID:        initTrack.ID,
+		TimeScale: initTrack.TimeScale,
+	}
+
+	switch codec := initTrack.Codec.(type) {
+	case *fmp4.CodecAV1:
+		t.Codec = "AV1"
+		t.SequenceHeader = hex.EncodeToString(codec.SequenceHeader)
+
+	case *fmp4.CodecVP9:
+		t.Codec = "VP9"
+		t.Width = codec.Width
+		t.Height = codec.Height
+		t.BitDepth = int(codec.BitDepth)
+
+	case *fmp4.CodecH265:
+		t.Codec = "H265"
+		t.VPS = hex.EncodeToString(codec.VPS)
+		t.SPS = hex.EncodeToString(codec.SPS)
+		t.PPS = hex.EncodeToString(codec.PPS)
+
+	case *fmp4.CodecH264:
+		t.Codec = "H264"
+		t.SPS = hex.EncodeToString(codec.SPS)
+		t.PPS = hex.EncodeToString(codec.PPS)
+
+	case *fmp4.CodecMPEG4Video:
+		t.Codec = "MPEG-4 Video"
+		t.Config = hex.EncodeToString(codec.Config)
+
+	case *fmp4.CodecMPEG1Video:
+		t.Codec = "MPEG-1/2 Video"
+		t.Config = hex.EncodeToString(codec.Config)
+
+	case *fmp4.CodecMJPEG:
+		t.Codec = "M-JPEG"
+		t.Width = codec.Width
+		t.Height = codec.Height
+
+	case *fmp4.CodecOpus:
+		t.Codec = "Opus"
+		t.ChannelCount = codec.ChannelCount
+
+	case *fmp4.CodecMPEG4Audio:
+		t.Codec = "MPEG-4 Audio"
+		if enc, err := codec.Config.Marshal(); err == nil {
+			t.Config = hex.EncodeToString(enc)
+		}
+		t.SampleRate = codec.SampleRate
+		t.ChannelCount = codec.ChannelCount
+
+	case *fmp4.CodecMPEG1Audio:
+		t.Codec = "MPEG-1/2 Audio"
+		t.SampleRate = codec.SampleRate
+		t.ChannelCount = codec.ChannelCount
+
+	case *fmp4.CodecAC3:
+		t.Codec = "AC-3"
+		t.SampleRate = codec.SampleRate
+		t.ChannelCount = codec.ChannelCount
+
+	case *fmp4.CodecLPCM:
+		t.Codec = "LPCM"
+		t.SampleRate = codec.SampleRate
+		t.ChannelCount = codec.ChannelCount
+		t.BitDepth = codec.BitDepth
+	}
+
+	return t
+}
+
+// newManifestTracks is called when the initialization section is written,
+// since codec parameters can change before the segment is complete.
+func newManifestTracks(tracks []*formatFMP4Track) []manifestTrack {
+	ret := make([]manifestTrack, len(tracks))
+	for i, track := range tracks {
+		ret[i] = newManifestTrack(track.initTrack)
+	}
+	return ret
+}
+
+func writeManifest(segmentPath string, start time.Time, duration time.Duration, tracks []manifestTrack) error {
+	byts, err := json.MarshalIndent(manifest{
+		Start:    start,
+		Duration: duration.Seconds(),
+		Tracks:   tracks,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(recordstore.ManifestPath(segmentPath), byts, 0o644)
+}
@@ -40,23 +40,84 @@ func writePart(
 	return err
 }
 
+// formatFMP4Part is a fMP4 fragment (moof + mdat), that is written to disk
+// when it reaches the fragment duration, or earlier when it reaches the part
+// duration. In this case, the fragment is rewritten in place every time it is flushed.
 type formatFMP4Part struct {
 	s              *formatFMP4Segment
 	sequenceNumber uint32
 	startDTS       time.Duration
 
-	partTracks map[*formatFMP4Track]*fmp4.PartTrack
-	endDTS     time.Duration
+	partTracks   map[*formatFMP4Track]*fmp4.PartTrack
+	endDTS       time.Duration
+	flushed      bool
+	offset       int64
+	lastFlushDTS time.Duration
 }
 
 func (p *formatFMP4Part) initialize() {
 	p.partTracks = make(map[*formatFMP4Track]*fmp4.PartTrack)
+	p.lastFlushDTS = p.startDTS
 }
 
 func (p *formatFMP4Part) close() error {
+	err := p.flush()
+	if err != nil {
+		return err
+	}
+
+	if p.s.f.ri.rec.WriteSidx {
+		end, err := p.s.fi.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		p.s.fragments = append(p.s.fragments, p.sidxFragment(end-p.offset))
+	}
+
+	return nil
+}
+
+func (p *formatFMP4Part) sidxFragment(size int64) *formatFMP4Fragment {
+	track := sidxReferenceTrack(p.s.f.tracks)
+
+	fragment := &formatFMP4Fragment{
+		offset: p.offset,
+		size:   size,
+	}
+
+	partTrack, ok := p.partTracks[track]
+	if !ok {
+		fragment.baseTime = uint64(multiplyAndDivide(int64(p.startDTS-p.s.startDTS),
+			int64(track.initTrack.TimeScale), int64(time.Second)))
+		return fragment
+	}
+
+	fragment.baseTime = partTrack.BaseTime
+
+	for _, sample := range partTrack.Samples {
+		fragment.duration += uint64(sample.Duration)
+	}
+
+	// a fragment starts with a random access point when all its video tracks start with a sync sample.
+	fragment.startsWithSAP = true
+
+	for _, track := range p.s.f.tracks {
+		if track.initTrack.Codec.IsVideo() {
+			partTrack, ok := p.partTracks[track]
+			if !ok || partTrack.Samples[0].IsNonSyncSample {
+				fragment.startsWithSAP = false
+			}
+		}
+	}
+
+	return fragment
+}
+
+func (p *formatFMP4Part) flush() error {
 	if p.s.fi == nil {
-		p.s.path = recordstore.Path{Start: p.s.startNTP}.Encode(p.s.f.ri.pathFormat)
-		p.s.f.ri.Log(logger.Debug, "creating segment %s", p.s.path)
+		p.s.path = recordstore.Path{Start: p.s.startNTP, Location: p.s.f.ri.rec.Location}.Encode(p.s.f.ri.pathFormat)
+		p.s.f.ri.Log(logger.Debug, "creating segment %s", p.s.f.ri.logPath(p.s.startNTP))
 
 		err := os.MkdirAll(filepath.Dir(p.s.path), 0o755)
 		if err != nil {
@@ -68,18 +129,48 @@ func (p *formatFMP4Part) close() error {
 			return err
 		}
 
+		p.s.marker.create(p.s.path)
 		p.s.f.ri.rec.OnSegmentCreate(p.s.path)
 
-		err = writeInit(fi, p.s.f.tracks)
+		if p.s.f.ri.rec.SeparateInit {
+			err = p.s.f.writeSeparateInit(p.s.path)
+		} else {
+			err = writeInit(fi, p.s.f.tracks)
+		}
 		if err != nil {
 			fi.Close()
 			return err
 		}
 
+		if p.s.f.ri.rec.WriteManifest {
+			p.s.manifest = newManifestTracks(p.s.f.tracks)
+		}
+
 		p.s.fi = fi
 	}
 
-	return writePart(p.s.fi, p.sequenceNumber, p.partTracks)
+	var err error
+
+	if p.flushed {
+		_, err = p.s.fi.Seek(p.offset, io.SeekStart)
+	} else {
+		p.offset, err = p.s.fi.Seek(0, io.SeekCurrent)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the fragment only grows between flushes,
+	// therefore the previous version is always overwritten entirely.
+	err = writePart(p.s.fi, p.sequenceNumber, p.partTracks)
+	if err != nil {
+		return err
+	}
+
+	p.flushed = true
+	p.lastFlushDTS = p.endDTS
+
+	return nil
 }
 
 func (p *formatFMP4Part) write(track *formatFMP4Track, sample *sample, dtsDuration time.Duration) error {
@@ -102,3 +193,7 @@ func (p *formatFMP4Part) write(track *formatFMP4Track, sample *sample, dtsDurati
 func (p *formatFMP4Part) duration() time.Duration {
 	return p.endDTS - p.startDTS
 }
+
+func (p *formatFMP4Part) unflushedDuration() time.Duration {
+	return p.endDTS - p.lastFlushDTS
+}
@@ -1,6 +1,7 @@
 package recorder
 
 import (
+	"bytes"
 	"io"
 	"os"
 	"time"
@@ -9,9 +10,10 @@ import (
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
 
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
 )
 
-func writeInit(f io.Writer, tracks []*formatFMP4Track) error {
+func marshalInit(tracks []*formatFMP4Track) ([]byte, error) {
 	fmp4Tracks := make([]*fmp4.InitTrack, len(tracks))
 	for i, track := range tracks {
 		fmp4Tracks[i] = track.initTrack
@@ -23,27 +25,62 @@ func writeInit(f io.Writer, tracks []*formatFMP4Track) error {
 
 	var buf seekablebuffer.Buffer
 	err := init.Marshal(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeInit(f io.Writer, tracks []*formatFMP4Track) error {
+	byts, err := marshalInit(tracks)
 	if err != nil {
 		return err
 	}
 
-	_, err = f.Write(buf.Bytes())
+	_, err = f.Write(byts)
 	return err
 }
 
+// writeSeparateInit writes the initialization section into a file next to the segment.
+// The file is shared by the following segments of the session,
+// therefore it is written again only when codec parameters change.
+func (f *formatFMP4) writeSeparateInit(segmentPath string) error {
+	byts, err := marshalInit(f.tracks)
+	if err != nil {
+		return err
+	}
+
+	if f.separateInit != nil && bytes.Equal(byts, f.separateInit) {
+		return nil
+	}
+
+	err = os.WriteFile(recordstore.InitPath(segmentPath), byts, 0o644)
+	if err != nil {
+		return err
+	}
+
+	f.separateInit = byts
+	return nil
+}
+
 type formatFMP4Segment struct {
 	f        *formatFMP4
 	startDTS time.Duration
 	startNTP time.Time
 
-	path    string
-	fi      *os.File
-	curPart *formatFMP4Part
-	lastDTS time.Duration
+	path      string
+	fi        *os.File
+	curPart   *formatFMP4Part
+	marker    writingMarker
+	lastDTS   time.Duration
+	fragments []*formatFMP4Fragment
+	manifest  []manifestTrack
 }
 
 func (s *formatFMP4Segment) initialize() {
 	s.lastDTS = s.startDTS
+	s.marker.ri = s.f.ri
 }
 
 func (s *formatFMP4Segment) close() error {
@@ -54,16 +91,37 @@ func (s *formatFMP4Segment) close() error {
 	}
 
 	if s.fi != nil {
-		s.f.ri.Log(logger.Debug, "closing segment %s", s.path)
+		s.f.ri.Log(logger.Debug, "closing segment %s", s.f.ri.logPath(s.startNTP))
 		err2 := s.fi.Close()
 		if err == nil {
 			err = err2
 		}
 
+		if err == nil && s.f.ri.rec.WriteSidx && len(s.fragments) != 0 {
+			err = writeSidx(s.path, sidxReferenceTrack(s.f.tracks), s.fragments)
+		}
+
 		if err2 == nil {
+			if s.f.captions != nil {
+				err3 := s.f.captions.writeSegment(s.path, s.startDTS, s.lastDTS)
+				if err == nil {
+					err = err3
+				}
+			}
+
 			duration := s.lastDTS - s.startDTS
-			s.f.ri.rec.OnSegmentComplete(s.path, duration)
+
+			if s.f.ri.rec.WriteManifest {
+				err3 := writeManifest(s.path, s.startNTP, duration, s.manifest)
+				if err == nil {
+					err = err3
+				}
+			}
+
+			s.f.ri.rec.onSegmentComplete(s.path, duration)
 		}
+
+		s.marker.remove()
 	}
 
 	return err
@@ -71,6 +129,7 @@ func (s *formatFMP4Segment) close() error {
 
 func (s *formatFMP4Segment) write(track *formatFMP4Track, sample *sample, dtsDuration time.Duration) error {
 	s.lastDTS = dtsDuration
+	s.marker.refresh()
 
 	if s.curPart == nil {
 		s.curPart = &formatFMP4Part{
@@ -80,7 +139,7 @@ func (s *formatFMP4Segment) write(track *formatFMP4Track, sample *sample, dtsDur
 		}
 		s.curPart.initialize()
 		s.f.nextSequenceNumber++
-	} else if s.curPart.duration() >= s.f.ri.rec.PartDuration {
+	} else if s.curPart.duration() >= s.f.ri.rec.FragmentDuration {
 		err := s.curPart.close()
 		s.curPart = nil
 
@@ -95,6 +154,11 @@ func (s *formatFMP4Segment) write(track *formatFMP4Track, sample *sample, dtsDur
 		}
 		s.curPart.initialize()
 		s.f.nextSequenceNumber++
+	} else if s.curPart.unflushedDuration() >= s.f.ri.rec.PartDuration {
+		err := s.curPart.flush()
+		if err != nil {
+			return err
+		}
 	}
 
 	return s.curPart.write(track, sample, dtsDuration)
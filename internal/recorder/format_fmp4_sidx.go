@@ -0,0 +1,121 @@
+package recorder
+
+import (
+	"io"
+	"os"
+
+	"github.com/abema/go-mp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
+)
+
+// formatFMP4Fragment describes a fragment of a segment, in order to reference it in the sidx box.
+type formatFMP4Fragment struct {
+	offset        int64
+	size          int64
+	baseTime      uint64 // in the timescale of the reference track
+	duration      uint64 // in the timescale of the reference track
+	startsWithSAP bool
+}
+
+// sidxReferenceTrack returns the track whose timescale is used in the sidx box.
+func sidxReferenceTrack(tracks []*formatFMP4Track) *formatFMP4Track {
+	for _, track := range tracks {
+		if track.initTrack.Codec.IsVideo() {
+			return track
+		}
+	}
+	return tracks[0]
+}
+
+func marshalSidx(w io.WriteSeeker, track *formatFMP4Track, fragments []*formatFMP4Fragment) error {
+	sidx := &mp4.Sidx{
+		FullBox: mp4.FullBox{
+			Version: 1,
+		},
+		ReferenceID:                uint32(track.initTrack.ID),
+		Timescale:                  track.initTrack.TimeScale,
+		EarliestPresentationTimeV1: fragments[0].baseTime,
+		FirstOffsetV1:              0,
+		ReferenceCount:             uint16(len(fragments)),
+		References:                 make([]mp4.SidxReference, len(fragments)),
+	}
+
+	for i, fragment := range fragments {
+		sidx.References[i] = mp4.SidxReference{
+			ReferenceType:      false,
+			ReferencedSize:     uint32(fragment.size),
+			SubsegmentDuration: uint32(fragment.duration),
+			StartsWithSAP:      fragment.startsWithSAP,
+		}
+		if fragment.startsWithSAP {
+			sidx.References[i].SAPType = 1
+		}
+	}
+
+	mw := mp4.NewWriter(w)
+
+	_, err := mw.StartBox(&mp4.BoxInfo{Type: mp4.BoxTypeSidx()})
+	if err != nil {
+		return err
+	}
+
+	_, err = mp4.Marshal(mw, sidx, mp4.Context{})
+	if err != nil {
+		return err
+	}
+
+	_, err = mw.EndBox()
+	return err
+}
+
+// writeSidx rewrites a segment, inserting a sidx box between
+// the initialization section and the first fragment.
+// The segment is written into a temporary file that then replaces the original one,
+// in order not to leave a corrupted segment behind in case of failure.
+func writeSidx(fpath string, track *formatFMP4Track, fragments []*formatFMP4Fragment) error {
+	var buf seekablebuffer.Buffer
+	err := marshalSidx(&buf, track, fragments)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(fpath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := fpath + ".tmp"
+
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		_, err2 := io.CopyN(dst, src, fragments[0].offset)
+		if err2 != nil {
+			return err2
+		}
+
+		_, err2 = dst.Write(buf.Bytes())
+		if err2 != nil {
+			return err2
+		}
+
+		_, err2 = io.Copy(dst, src)
+		return err2
+	}()
+
+	err2 := dst.Close()
+	if err == nil {
+		err = err2
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, fpath)
+}
@@ -1,9 +1,15 @@
 package recorder
 
 import (
+	"time"
+
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 )
 
+// a track is considered interrupted when it receives no samples for this duration,
+// while other tracks keep receiving them.
+const fmp4GapDuration = 1 * time.Second
+
 type formatFMP4Track struct {
 	f         *formatFMP4
 	initTrack *fmp4.InitTrack
@@ -12,8 +18,51 @@ type formatFMP4Track struct {
 }
 
 func (t *formatFMP4Track) write(sample *sample) error {
+	// a filler has already been written in place of this sample
+	if t.nextSample != nil && t.nextSample.filler && sample.dts <= t.nextSample.dts {
+		return nil
+	}
+
+	err := t.writeSample(sample)
+	if err != nil {
+		return err
+	}
+
+	if t.f.ri.rec.FillGaps {
+		return t.f.fillGaps(t, timestampToDuration(sample.dts, int(t.initTrack.TimeScale)))
+	}
+
+	return nil
+}
+
+// fill writes an empty sample at the given DTS.
+// The duration of the previous sample is limited to the filler DTS,
+// therefore the track is kept aligned with the other ones.
+func (t *formatFMP4Track) fill(dtsDuration time.Duration) error {
+	prevDTSDuration := timestampToDuration(t.nextSample.dts, int(t.initTrack.TimeScale))
+
+	return t.writeSample(&sample{
+		PartSample: &fmp4.PartSample{
+			IsNonSyncSample: t.initTrack.Codec.IsVideo(),
+		},
+		dts:    multiplyAndDivide(int64(dtsDuration), int64(t.initTrack.TimeScale), int64(time.Second)),
+		ntp:    t.nextSample.ntp.Add(dtsDuration - prevDTSDuration),
+		filler: true,
+	})
+}
+
+// dtsNTP returns the absolute time of the DTS of a sample.
+// The NTP timestamp of a sample refers to its PTS, that differs from DTS
+// by a variable amount when the track contains B-frames. Segments start from
+// the DTS of their first sample, therefore their start time must be computed
+// from it, otherwise the segment timeline would be shifted by the reordering depth.
+func (t *formatFMP4Track) dtsNTP(sample *sample) time.Time {
+	return sample.ntp.Add(-timestampToDuration(int64(sample.PTSOffset), int(t.initTrack.TimeScale)))
+}
+
+func (t *formatFMP4Track) writeSample(sample *sample) error {
 	// wait the first video sample before setting hasVideo
-	if t.initTrack.Codec.IsVideo() {
+	if t.initTrack.Codec.IsVideo() && !sample.filler {
 		t.f.hasVideo = true
 	}
 
@@ -29,7 +78,7 @@ func (t *formatFMP4Track) write(sample *sample) error {
 		t.f.currentSegment = &formatFMP4Segment{
 			f:        t.f,
 			startDTS: dtsDuration,
-			startNTP: sample.ntp,
+			startNTP: t.dtsNTP(sample),
 		}
 		t.f.currentSegment.initialize()
 		// BaseTime is negative, this is not supported by fMP4. Reject the sample silently.
@@ -46,7 +95,8 @@ func (t *formatFMP4Track) write(sample *sample) error {
 
 	if (!t.f.hasVideo || t.initTrack.Codec.IsVideo()) &&
 		!t.nextSample.IsNonSyncSample &&
-		(nextDTSDuration-t.f.currentSegment.startDTS) >= t.f.ri.rec.SegmentDuration {
+		(t.f.ri.rec.takeRotation() ||
+			t.f.ri.rec.segmentDurationReached(nextDTSDuration-t.f.currentSegment.startDTS, t.initTrack.Codec.IsVideo())) {
 		t.f.currentSegment.lastDTS = nextDTSDuration
 		err := t.f.currentSegment.close()
 		if err != nil {
@@ -56,7 +106,7 @@ func (t *formatFMP4Track) write(sample *sample) error {
 		t.f.currentSegment = &formatFMP4Segment{
 			f:        t.f,
 			startDTS: nextDTSDuration,
-			startNTP: t.nextSample.ntp,
+			startNTP: t.dtsNTP(t.nextSample),
 		}
 		t.f.currentSegment.initialize()
 	}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	mtxmpegts "github.com/bluenviron/mediamtx/internal/protocols/mpegts"
 	"github.com/bluenviron/mediamtx/internal/unit"
 )
 
@@ -58,6 +59,7 @@ type formatMPEGTS struct {
 	bw             *bufio.Writer
 	mw             *mpegts.Writer
 	hasVideo       bool
+	captions       *formatCaptions
 	currentSegment *formatMPEGTSSegment
 }
 
@@ -77,8 +79,17 @@ func (f *formatMPEGTS) initialize() bool {
 		return track
 	}
 
+	if len(f.ri.selectedFormats) == 0 {
+		f.ri.Log(logger.Error, "no tracks match recordTracks, skipping recording")
+		return false
+	}
+
 	for _, media := range f.ri.rec.Stream.Desc().Medias {
 		for _, forma := range media.Formats {
+			if !f.ri.isSelected(forma) {
+				continue
+			}
+
 			clockRate := forma.ClockRate()
 
 			switch forma := forma.(type) {
@@ -132,6 +143,12 @@ func (f *formatMPEGTS) initialize() bool {
 
 				var dtsExtractor *h264.DTSExtractor2
 
+				var captions *formatCaptions
+				if f.ri.rec.Captions && f.captions == nil {
+					captions = &formatCaptions{}
+					f.captions = captions
+				}
+
 				f.ri.rec.Stream.AddReader(
 					f.ri,
 					media,
@@ -156,6 +173,12 @@ func (f *formatMPEGTS) initialize() bool {
 							return err
 						}
 
+						if captions != nil {
+							captions.writeH264(tunit.AU,
+								timestampToDuration(tunit.PTS, clockRate),
+								timestampToDuration(dts, clockRate))
+						}
+
 						return f.write(
 							timestampToDuration(dts, clockRate),
 							tunit.NTP,
@@ -387,7 +410,7 @@ func (f *formatMPEGTS) initialize() bool {
 	n := 1
 	for _, medi := range f.ri.rec.Stream.Desc().Medias {
 		for _, forma := range medi.Formats {
-			if _, ok := setuppedFormatsMap[forma]; !ok {
+			if _, ok := setuppedFormatsMap[forma]; !ok && f.ri.isSelected(forma) {
 				f.ri.Log(logger.Warn, "skipping track %d (%s)", n, forma.Codec())
 			}
 			n++
@@ -396,7 +419,7 @@ func (f *formatMPEGTS) initialize() bool {
 
 	f.dw = &dynamicWriter{}
 	f.bw = bufio.NewWriterSize(f.dw, mpegtsMaxBufferSize)
-	f.mw = mpegts.NewWriter(f.bw, tracks)
+	f.mw = mtxmpegts.NewWriter(f.bw, tracks, f.ri.rec.MPEGTSPIDs, f.ri.rec.MPEGTSService)
 
 	f.ri.Log(logger.Info, "recording %s",
 		defs.FormatsInfo(setuppedFormats))
@@ -431,7 +454,7 @@ func (f *formatMPEGTS) write(
 		f.currentSegment.initialize()
 	case (!f.hasVideo || isVideo) &&
 		randomAccess &&
-		(dtsDuration-f.currentSegment.startDTS) >= f.ri.rec.SegmentDuration:
+		(f.ri.rec.takeRotation() || f.ri.rec.segmentDurationReached(dtsDuration-f.currentSegment.startDTS, isVideo)):
 		f.currentSegment.lastDTS = dtsDuration
 		err := f.currentSegment.close()
 		if err != nil {
@@ -446,7 +469,7 @@ func (f *formatMPEGTS) write(
 		f.currentSegment.initialize()
 
 	case (dtsDuration - f.currentSegment.lastFlush) >= f.ri.rec.PartDuration:
-		err := f.bw.Flush()
+		err := f.currentSegment.flush()
 		if err != nil {
 			return err
 		}
@@ -16,6 +16,8 @@ type formatMPEGTSSegment struct {
 
 	path      string
 	fi        *os.File
+	w         recordstore.SegmentWriter
+	marker    writingMarker
 	lastFlush time.Duration
 	lastDTS   time.Duration
 }
@@ -23,6 +25,7 @@ type formatMPEGTSSegment struct {
 func (s *formatMPEGTSSegment) initialize() {
 	s.lastFlush = s.startDTS
 	s.lastDTS = s.startDTS
+	s.marker.ri = s.f.ri
 	s.f.dw.setTarget(s)
 }
 
@@ -30,25 +33,52 @@ func (s *formatMPEGTSSegment) close() error {
 	err := s.f.bw.Flush()
 
 	if s.fi != nil {
-		s.f.ri.Log(logger.Debug, "closing segment %s", s.path)
-		err2 := s.fi.Close()
+		s.f.ri.Log(logger.Debug, "closing segment %s", s.f.ri.logPath(s.startNTP))
+		err2 := s.w.Close()
+		if err == nil {
+			err = err2
+		}
+
+		err2 = s.fi.Close()
 		if err == nil {
 			err = err2
 		}
 
 		if err2 == nil {
+			if s.f.captions != nil {
+				err3 := s.f.captions.writeSegment(s.path, s.startDTS, s.lastDTS)
+				if err == nil {
+					err = err3
+				}
+			}
+
 			duration := s.lastDTS - s.startDTS
-			s.f.ri.rec.OnSegmentComplete(s.path, duration)
+			s.f.ri.rec.onSegmentComplete(s.path, duration)
 		}
+
+		s.marker.remove()
 	}
 
 	return err
 }
 
+func (s *formatMPEGTSSegment) flush() error {
+	err := s.f.bw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if s.w != nil {
+		return s.w.Flush()
+	}
+
+	return nil
+}
+
 func (s *formatMPEGTSSegment) Write(p []byte) (int, error) {
 	if s.fi == nil {
-		s.path = recordstore.Path{Start: s.startNTP}.Encode(s.f.ri.pathFormat)
-		s.f.ri.Log(logger.Debug, "creating segment %s", s.path)
+		s.path = recordstore.Path{Start: s.startNTP, Location: s.f.ri.rec.Location}.Encode(s.f.ri.pathFormat)
+		s.f.ri.Log(logger.Debug, "creating segment %s", s.f.ri.logPath(s.startNTP))
 
 		err := os.MkdirAll(filepath.Dir(s.path), 0o755)
 		if err != nil {
@@ -60,10 +90,20 @@ func (s *formatMPEGTSSegment) Write(p []byte) (int, error) {
 			return 0, err
 		}
 
+		w, err := recordstore.NewSegmentWriter(fi, s.f.ri.rec.Compression)
+		if err != nil {
+			fi.Close()
+			return 0, err
+		}
+
+		s.marker.create(s.path)
 		s.f.ri.rec.OnSegmentCreate(s.path)
 
 		s.fi = fi
+		s.w = w
+	} else {
+		s.marker.refresh()
 	}
 
-	return s.fi.Write(p)
+	return s.w.Write(p)
 }
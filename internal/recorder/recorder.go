@@ -2,13 +2,23 @@
 package recorder
 
 import (
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
 	"github.com/bluenviron/mediamtx/internal/stream"
 )
 
+// suffix added to segments that cannot be repaired.
+const corruptedSuffix = ".corrupted"
+
+// segments modified after this time belong to the current run of the server.
+var runStart = time.Now()
+
 // OnSegmentCreateFunc is the prototype of the function passed as OnSegmentCreate
 type OnSegmentCreateFunc = func(path string)
 
@@ -17,19 +27,39 @@ type OnSegmentCompleteFunc = func(path string, duration time.Duration)
 
 // Recorder writes recordings to disk.
 type Recorder struct {
-	PathFormat        string
-	Format            conf.RecordFormat
-	PartDuration      time.Duration
-	SegmentDuration   time.Duration
-	PathName          string
-	Stream            *stream.Stream
-	OnSegmentCreate   OnSegmentCreateFunc
-	OnSegmentComplete OnSegmentCompleteFunc
-	Parent            logger.Writer
+	PathFormat         string
+	PathSanitize       bool
+	Location           *time.Location
+	Format             conf.RecordFormat
+	Compression        conf.RecordCompression
+	Captions           bool
+	Tracks             []string
+	PartDuration       time.Duration
+	FragmentDuration   time.Duration
+	WriteSidx          bool
+	FillGaps           bool
+	WriteManifest      bool
+	SeparateInit       bool
+	MPEGTSPIDs         mpegts.PIDs
+	MPEGTSService      mpegts.Service
+	SegmentDuration    time.Duration
+	KeyFrameInterval   time.Duration
+	MaxSegments        int
+	SnapshotInterval   int
+	SnapshotPathFormat string
+	PathName           string
+	SourceUser         string
+	SourceID           string
+	SourceQuery        string
+	Stream             *stream.Stream
+	OnSegmentCreate    OnSegmentCreateFunc
+	OnSegmentComplete  OnSegmentCompleteFunc
+	Parent             logger.Writer
 
 	restartPause time.Duration
 
 	currentInstance *recorderInstance
+	rotate          int32
 
 	terminate chan struct{}
 	done      chan struct{}
@@ -45,6 +75,9 @@ func (r *Recorder) Initialize() {
 		r.OnSegmentComplete = func(string, time.Duration) {
 		}
 	}
+	if r.FragmentDuration == 0 {
+		r.FragmentDuration = r.PartDuration
+	}
 	if r.restartPause == 0 {
 		r.restartPause = 2 * time.Second
 	}
@@ -52,6 +85,10 @@ func (r *Recorder) Initialize() {
 	r.terminate = make(chan struct{})
 	r.done = make(chan struct{})
 
+	if r.Format == conf.RecordFormatFMP4 {
+		r.repairLastSegment()
+	}
+
 	r.currentInstance = &recorderInstance{
 		rec: r,
 	}
@@ -72,6 +109,77 @@ func (r *Recorder) Close() {
 	<-r.done
 }
 
+// Rotate closes the current segment and starts a new one,
+// regardless of the segment duration.
+// When there are video tracks, the new segment starts with the next random access unit,
+// therefore a key frame is requested to the publisher.
+func (r *Recorder) Rotate() {
+	r.Log(logger.Info, "rotating segment")
+	atomic.StoreInt32(&r.rotate, 1)
+	r.Stream.RequestKeyFrame()
+}
+
+// takeRotation returns whether a rotation has been requested, and resets the request.
+func (r *Recorder) takeRotation() bool {
+	return atomic.CompareAndSwapInt32(&r.rotate, 1, 0)
+}
+
+// segmentDurationReached returns whether the current segment can be closed.
+// When KeyFrameInterval is set, segments of video tracks are closed
+// at the key frame that is nearest to SegmentDuration, since the next one
+// is expected to be farther.
+func (r *Recorder) segmentDurationReached(duration time.Duration, isVideo bool) bool {
+	if isVideo && r.KeyFrameInterval != 0 {
+		return duration >= (r.SegmentDuration - min(r.KeyFrameInterval, r.SegmentDuration)/2)
+	}
+	return duration >= r.SegmentDuration
+}
+
+// pathValue returns a value that can be inserted into segment paths.
+func (r *Recorder) pathValue(v string) string {
+	if r.PathSanitize {
+		return recordstore.SanitizePathName(v)
+	}
+	return v
+}
+
+// repairLastSegment repairs the last segment of the path,
+// that may have been left incomplete by a crash during a previous run of the server.
+func (r *Recorder) repairLastSegment() {
+	segments, err := recordstore.FindSegments(&conf.Path{
+		RecordPath:         r.PathFormat,
+		RecordPathSanitize: r.PathSanitize,
+		RecordFormat:       r.Format,
+	}, r.PathName)
+	if err != nil {
+		return
+	}
+
+	fpath := segments[len(segments)-1].Fpath
+
+	// do not touch segments that may be in use by another recorder
+	fi, err := os.Stat(fpath)
+	if err != nil || !fi.ModTime().Before(runStart) {
+		return
+	}
+
+	truncated, err := repairFMP4Segment(fpath)
+	if err != nil {
+		r.Log(logger.Warn, "segment %s cannot be repaired (%v), moving it to %s",
+			fpath, err, fpath+corruptedSuffix)
+
+		err = os.Rename(fpath, fpath+corruptedSuffix)
+		if err != nil {
+			r.Log(logger.Warn, err.Error())
+		}
+		return
+	}
+
+	if truncated {
+		r.Log(logger.Warn, "segment %s was incomplete and has been truncated to its last complete fragment", fpath)
+	}
+}
+
 func (r *Recorder) run() {
 	defer close(r.done)
 
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -13,16 +14,20 @@ import (
 
 type sample struct {
 	*fmp4.PartSample
-	dts int64
-	ntp time.Time
+	dts    int64
+	ntp    time.Time
+	filler bool
 }
 
 type recorderInstance struct {
 	rec *Recorder
 
-	pathFormat string
-	format     format
-	skip       bool
+	pathFormat      string
+	logPathFormat   string
+	format          format
+	skip            bool
+	selectedFormats map[rtspformat.Format]struct{}
+	snapshotter     *snapshotter
 
 	terminate chan struct{}
 	done      chan struct{}
@@ -37,13 +42,18 @@ func (ri *recorderInstance) initialize() {
 	ri.pathFormat = ri.rec.PathFormat
 
 	ri.pathFormat = recordstore.PathAddExtension(
-		strings.ReplaceAll(ri.pathFormat, "%path", ri.rec.PathName),
+		strings.ReplaceAll(ri.pathFormat, "%path", ri.rec.pathValue(ri.rec.PathName)),
 		ri.rec.Format,
+		ri.rec.Compression,
 	)
 
+	ri.pathFormat, ri.logPathFormat = replaceSourceVariables(ri.pathFormat, ri.rec)
+
 	ri.terminate = make(chan struct{})
 	ri.done = make(chan struct{})
 
+	ri.selectedFormats = selectTracks(ri.rec.Stream.Desc().Medias, ri.rec.Tracks)
+
 	switch ri.rec.Format {
 	case conf.RecordFormatMPEGTS:
 		ri.format = &formatMPEGTS{
@@ -62,6 +72,13 @@ func (ri *recorderInstance) initialize() {
 
 	if !ri.skip {
 		ri.rec.Stream.StartReader(ri)
+
+		if ri.rec.SnapshotInterval > 0 {
+			ri.snapshotter = &snapshotter{ri: ri}
+			if !ri.snapshotter.initialize() {
+				ri.snapshotter = nil
+			}
+		}
 	}
 
 	go ri.run()
@@ -72,6 +89,16 @@ func (ri *recorderInstance) close() {
 	<-ri.done
 }
 
+func (ri *recorderInstance) isSelected(forma rtspformat.Format) bool {
+	_, ok := ri.selectedFormats[forma]
+	return ok
+}
+
+// logPath returns the path of the segment that starts at the given time, for logging purposes.
+func (ri *recorderInstance) logPath(start time.Time) string {
+	return recordstore.Path{Start: start, Location: ri.rec.Location}.Encode(ri.logPathFormat)
+}
+
 func (ri *recorderInstance) run() {
 	defer close(ri.done)
 
@@ -83,6 +110,10 @@ func (ri *recorderInstance) run() {
 		case <-ri.terminate:
 		}
 
+		if ri.snapshotter != nil {
+			ri.snapshotter.close()
+		}
+
 		ri.rec.Stream.RemoveReader(ri)
 	} else {
 		<-ri.terminate
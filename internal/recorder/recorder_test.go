@@ -1,21 +1,30 @@
 package recorder
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/abema/go-mp4"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
 	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
 	"github.com/bluenviron/mediacommon/pkg/formats/fmp4"
+	"github.com/bluenviron/mediacommon/pkg/formats/fmp4/seekablebuffer"
 	"github.com/stretchr/testify/require"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
 	"github.com/bluenviron/mediamtx/internal/stream"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/bluenviron/mediamtx/internal/unit"
@@ -129,6 +138,9 @@ func TestRecorder(t *testing.T) {
 				1460,
 				desc,
 				true,
+				0,
+				0,
+				0,
 				test.NilLogger,
 			)
 			require.NoError(t, err)
@@ -175,6 +187,7 @@ func TestRecorder(t *testing.T) {
 					default:
 						require.Equal(t, filepath.Join(dir, "mypath", "2010-05-20_22-15-25-000000."+ext), segPath)
 					}
+					require.True(t, recordstore.SegmentInUse(segPath, time.Now()))
 					segCreated <- struct{}{}
 				},
 				OnSegmentComplete: func(segPath string, du time.Duration) {
@@ -308,6 +321,9 @@ func TestRecorder(t *testing.T) {
 
 			_, err = os.Stat(filepath.Join(dir, "mypath", "2010-05-20_22-15-25-000000."+ext))
 			require.NoError(t, err)
+
+			_, err = os.Stat(recordstore.WritingMarkerPath(filepath.Join(dir, "mypath", "2010-05-20_22-15-25-000000."+ext)))
+			require.Error(t, err)
 		})
 	}
 }
@@ -342,6 +358,9 @@ func TestRecorderFMP4NegativeDTS(t *testing.T) {
 		1460,
 		desc,
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -410,6 +429,121 @@ func TestRecorderFMP4NegativeDTS(t *testing.T) {
 	require.Equal(t, true, found)
 }
 
+func TestRecorderFMP4BFrames(t *testing.T) {
+	sps := []byte{
+		0x67, 0x64, 0x00, 0x28, 0xac, 0xd9, 0x40, 0x78,
+		0x02, 0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00,
+		0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60,
+		0xc6, 0x58,
+	}
+	idr := []byte{0x65, 0x88, 0x84, 0x00, 0x33, 0xff}
+
+	forma := &rtspformat.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+		SPS:               sps,
+		PPS:               test.FormatH264.PPS,
+	}
+
+	desc := &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeVideo,
+		Formats: []rtspformat.Format{forma},
+	}}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+
+	w := &Recorder{
+		PathFormat:      recordPath,
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 150 * time.Millisecond,
+		PathName:        "mypath",
+		Stream:          stream,
+		Parent:          test.NilLogger,
+	}
+	w.Initialize()
+
+	// units in decoding order, with PTS in milliseconds
+	for _, ca := range []struct {
+		pts float64
+		au  [][]byte
+	}{
+		{333.333333, [][]byte{sps, idr}},
+		{366.666666, [][]byte{{0x41, 0x9a, 0x21, 0x6c, 0x45, 0xff}}},
+		{400, [][]byte{{0x41, 0x9a, 0x42, 0x3c, 0x21, 0x93}}},
+		{433.333333, [][]byte{{0x41, 0x9a, 0x63, 0x49, 0xe1, 0x0f}}},
+		{533.333333, [][]byte{{0x41, 0x9a, 0x86, 0x49, 0xe1, 0x0f}}},
+		{500, [][]byte{{0x41, 0x9e, 0xa5, 0x42, 0x7f, 0xf9}}},
+		{466.666666, [][]byte{{0x01, 0x9e, 0xc4, 0x69, 0x13, 0xff}}},
+		{600, [][]byte{{0x41, 0x9a, 0xc8, 0x4b, 0xa8, 0x42}}},
+		{599.999999, [][]byte{idr}},
+		{633.333333, [][]byte{idr}},
+	} {
+		pts := int64(ca.pts * 90000 / 1000)
+		stream.WriteUnit(desc.Medias[0], forma, &unit.H264{
+			Base: unit.Base{
+				PTS: pts,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC).Add(timestampToDuration(pts, 90000)),
+			},
+			AU: ca.au,
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	// reordering depth is the distance between 533.33ms and 466.66ms
+	require.Equal(t, []time.Duration{66666666 * time.Nanosecond}, stream.TracksReorderDepth())
+
+	byts, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-333322.mp4"))
+	require.NoError(t, err)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+
+	var ptss []int64
+	prevDTS := int64(-1)
+
+	for _, part := range parts {
+		for _, track := range part.Tracks {
+			dts := int64(track.BaseTime)
+			for _, sa := range track.Samples {
+				require.Greater(t, dts, prevDTS)
+				prevDTS = dts
+				ptss = append(ptss, dts+int64(sa.PTSOffset))
+				dts += int64(sa.Duration)
+			}
+		}
+	}
+
+	// PTS are relative to the DTS of the first sample, that is equal to its PTS.
+	require.Equal(t, []int64{0, 3000, 6001, 9000, 18000, 15001, 12000, 24001}, ptss)
+
+	// the second segment starts from the DTS of the second IDR, that is 66.66ms before its PTS,
+	// and immediately after the end of the first segment.
+	_, err = os.Stat(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-533322.mp4"))
+	require.NoError(t, err)
+}
+
 func TestRecorderSkipTracksPartial(t *testing.T) {
 	for _, ca := range []string{"fmp4", "mpegts"} {
 		t.Run(ca, func(t *testing.T) {
@@ -429,6 +563,9 @@ func TestRecorderSkipTracksPartial(t *testing.T) {
 				1460,
 				desc,
 				true,
+				0,
+				0,
+				0,
 				test.NilLogger,
 			)
 			require.NoError(t, err)
@@ -489,6 +626,9 @@ func TestRecorderSkipTracksFull(t *testing.T) {
 				1460,
 				desc,
 				true,
+				0,
+				0,
+				0,
 				test.NilLogger,
 			)
 			require.NoError(t, err)
@@ -533,3 +673,1729 @@ func TestRecorderSkipTracksFull(t *testing.T) {
 		})
 	}
 }
+
+func TestRecorderTracks(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type: description.MediaTypeVideo,
+			Formats: []rtspformat.Format{&rtspformat.H264{
+				PayloadTyp:        96,
+				PacketizationMode: 1,
+			}},
+		},
+		{
+			Type: description.MediaTypeVideo,
+			Formats: []rtspformat.Format{&rtspformat.H265{
+				PayloadTyp: 96,
+			}},
+		},
+		{
+			Type: description.MediaTypeAudio,
+			Formats: []rtspformat.Format{&rtspformat.MPEG4Audio{
+				PayloadTyp: 96,
+				Config: &mpeg4audio.Config{
+					Type:         2,
+					SampleRate:   44100,
+					ChannelCount: 2,
+				},
+				SizeLength:       13,
+				IndexLength:      3,
+				IndexDeltaLength: 3,
+			}},
+		},
+	}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+	segmentPath := filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4")
+
+	w := &Recorder{
+		PathFormat:      recordPath,
+		Format:          conf.RecordFormatFMP4,
+		Tracks:          []string{"H264"},
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 10 * time.Second,
+		PathName:        "mypath",
+		Stream:          stream,
+		Parent:          test.NilLogger,
+	}
+	w.Initialize()
+
+	for i := 0; i < 4; i++ {
+		pts := int64(i) * 100 * 90000 / 1000
+
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: pts,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC),
+			},
+			AU: [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			},
+		})
+
+		stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.H265{
+			Base: unit.Base{
+				PTS: pts,
+			},
+			AU: [][]byte{
+				test.FormatH265.VPS,
+				test.FormatH265.SPS,
+				test.FormatH265.PPS,
+				{byte(h265.NALUType_CRA_NUT) << 1, 0}, // IDR
+			},
+		})
+
+		stream.WriteUnit(desc.Medias[2], desc.Medias[2].Formats[0], &unit.MPEG4Audio{
+			Base: unit.Base{
+				PTS: pts * 44100 / 90000,
+			},
+			AUs: [][]byte{{1, 2, 3, 4}},
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	byts, err := os.ReadFile(segmentPath)
+	require.NoError(t, err)
+
+	var init fmp4.Init
+	err = init.Unmarshal(bytes.NewReader(byts))
+	require.NoError(t, err)
+
+	require.Equal(t, fmp4.Init{
+		Tracks: []*fmp4.InitTrack{{
+			ID:        1,
+			TimeScale: 90000,
+			Codec: &fmp4.CodecH264{
+				SPS: test.FormatH264.SPS,
+				PPS: test.FormatH264.PPS,
+			},
+		}},
+	}, init)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+	require.NotEmpty(t, parts)
+
+	for _, part := range parts {
+		for _, track := range part.Tracks {
+			require.Equal(t, 1, track.ID)
+		}
+	}
+}
+
+func TestRecorderTracksNoMatch(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.H264{}},
+	}}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	n := 0
+
+	l := test.Logger(func(l logger.Level, format string, args ...interface{}) {
+		if n == 0 {
+			require.Equal(t, logger.Error, l)
+			require.Equal(t, "[recorder] no tracks match recordTracks, skipping recording", fmt.Sprintf(format, args...))
+		}
+		n++
+	})
+
+	w := &Recorder{
+		PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		Format:          conf.RecordFormatFMP4,
+		Tracks:          []string{"audio"},
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 1 * time.Second,
+		PathName:        "mypath",
+		Stream:          stream,
+		Parent:          l,
+	}
+	w.Initialize()
+	defer w.Close()
+
+	require.Equal(t, 1, n)
+}
+
+func TestRecorderTimeZone(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	w := &Recorder{
+		PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		Location:        loc,
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 10 * time.Second,
+		PathName:        "mypath",
+		Stream:          stream,
+		Parent:          test.NilLogger,
+	}
+	w.Initialize()
+
+	for i := 0; i < 2; i++ {
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: int64(i) * 90000,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC),
+			},
+			AU: [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			},
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	// the segment path is rendered in the configured time zone, regardless of the one of NTP
+	_, err = os.Stat(filepath.Join(dir, "mypath", "2008-05-21_07-15-25-000000.mp4"))
+	require.NoError(t, err)
+}
+
+func TestRecorderPathSanitize(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pathConf := &conf.Path{
+		RecordPath:         filepath.Join(dir, "%path/%user_%Y-%m-%d_%H-%M-%S-%f"),
+		RecordPathSanitize: true,
+		RecordFormat:       conf.RecordFormatFMP4,
+	}
+
+	w := &Recorder{
+		PathFormat:      pathConf.RecordPath,
+		PathSanitize:    true,
+		Location:        time.UTC,
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 10 * time.Second,
+		PathName:        "live/cam./con",
+		SourceUser:      "john doe:admin",
+		Stream:          stream,
+		Parent:          test.NilLogger,
+	}
+	w.Initialize()
+
+	for i := 0; i < 2; i++ {
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: int64(i) * 90000,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC),
+			},
+			AU: [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			},
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	fpath := filepath.Join(dir, "live", "cam+2E", "+63on", "john_doe_admin_2008-05-20_22-15-25-000000.mp4")
+	_, err = os.Stat(fpath)
+	require.NoError(t, err)
+
+	// segments can be found by using the original path name
+	segments, err := recordstore.FindSegments(pathConf, "live/cam./con")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(segments))
+	require.Equal(t, fpath, segments[0].Fpath)
+}
+
+func TestRecorderSnapshots(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.MJPEG{}},
+	}}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 16)), nil)
+	require.NoError(t, err)
+	frame := buf.Bytes()
+
+	w := &Recorder{
+		PathFormat:         filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		Format:             conf.RecordFormatFMP4,
+		PartDuration:       100 * time.Millisecond,
+		SegmentDuration:    10 * time.Second,
+		SnapshotInterval:   3,
+		SnapshotPathFormat: filepath.Join(dir, "snapshots/%path/%Y-%m-%d_%H-%M-%S-%f"),
+		PathName:           "mypath",
+		Stream:             stream,
+		Parent:             test.NilLogger,
+	}
+	w.Initialize()
+
+	for i := 0; i < 7; i++ {
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.MJPEG{
+			Base: unit.Base{
+				PTS: int64(i) * 90000,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25+i, 0, time.UTC),
+			},
+			Frame: frame,
+		})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	entries, err := os.ReadDir(filepath.Join(dir, "snapshots", "mypath"))
+	require.NoError(t, err)
+
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+
+	// a snapshot is written every 3 key frames, starting from the first one
+	require.Equal(t, []string{
+		time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC).Local().Format("2006-01-02_15-04-05") + "-000000.jpg",
+		time.Date(2008, 5, 20, 22, 15, 28, 0, time.UTC).Local().Format("2006-01-02_15-04-05") + "-000000.jpg",
+		time.Date(2008, 5, 20, 22, 15, 31, 0, time.UTC).Local().Format("2006-01-02_15-04-05") + "-000000.jpg",
+	}, names)
+
+	byts, err := os.ReadFile(filepath.Join(dir, "snapshots", "mypath", names[0]))
+	require.NoError(t, err)
+	require.Equal(t, frame, byts)
+}
+
+func TestRecorderFMP4FragmentDuration(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type: description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}},
+	}}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+	segmentPath := filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4")
+
+	w := &Recorder{
+		PathFormat:       recordPath,
+		Format:           conf.RecordFormatFMP4,
+		PartDuration:     100 * time.Millisecond,
+		FragmentDuration: 500 * time.Millisecond,
+		SegmentDuration:  10 * time.Second,
+		PathName:         "mypath",
+		Stream:           stream,
+		Parent:           test.NilLogger,
+	}
+	w.Initialize()
+
+	writeFrame := func(i int) {
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: int64(i) * 100 * 90000 / 1000,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC),
+			},
+			AU: [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			},
+		})
+	}
+
+	for i := 0; i < 4; i++ {
+		writeFrame(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	// the first fragment is not complete yet, but it is already on disk
+	byts, err := os.ReadFile(segmentPath)
+	require.NoError(t, err)
+
+	var parts fmp4.Parts
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(parts))
+	require.Equal(t, 2, len(parts[0].Tracks[0].Samples))
+
+	for i := 4; i < 16; i++ {
+		writeFrame(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	byts, err = os.ReadFile(segmentPath)
+	require.NoError(t, err)
+
+	parts = nil
+	err = parts.Unmarshal(byts)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(parts))
+
+	for i, part := range parts {
+		require.Equal(t, uint32(i), part.SequenceNumber)
+
+		var duration uint32
+		for _, sample := range part.Tracks[0].Samples {
+			duration += sample.Duration
+		}
+
+		// a fragment is closed when the distance between its first and last sample
+		// reaches the fragment duration, then the duration of the last sample is added.
+		if i != len(parts)-1 {
+			require.Equal(t, uint32(600*90000/1000), duration)
+		}
+	}
+}
+
+func TestRecorderFMP4Sidx(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type: description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}},
+	}}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+	segmentPath := filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4")
+
+	w := &Recorder{
+		PathFormat:       recordPath,
+		Format:           conf.RecordFormatFMP4,
+		PartDuration:     100 * time.Millisecond,
+		FragmentDuration: 500 * time.Millisecond,
+		SegmentDuration:  10 * time.Second,
+		WriteSidx:        true,
+		PathName:         "mypath",
+		Stream:           stream,
+		Parent:           test.NilLogger,
+	}
+	w.Initialize()
+
+	for i := 0; i < 16; i++ {
+		au := [][]byte{{1}} // non-IDR
+		if (i % 4) == 0 {
+			au = [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			}
+		}
+
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: int64(i) * 100 * 90000 / 1000,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC),
+			},
+			AU: au,
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	f, err := os.Open(segmentPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	type fragment struct {
+		offset        uint64
+		size          uint64
+		duration      uint32
+		startsWithSAP bool
+	}
+
+	var sidx *mp4.Sidx
+	var sidxEnd uint64
+	var fragments []*fragment
+
+	_, err = mp4.ReadBoxStructure(f, func(h *mp4.ReadHandle) (interface{}, error) {
+		switch h.BoxInfo.Type.String() {
+		case "sidx":
+			require.Nil(t, fragments)
+			box, _, err2 := h.ReadPayload()
+			require.NoError(t, err2)
+			sidx = box.(*mp4.Sidx)
+			sidxEnd = h.BoxInfo.Offset + h.BoxInfo.Size
+
+		case "moof":
+			fragments = append(fragments, &fragment{offset: h.BoxInfo.Offset})
+			return h.Expand()
+
+		case "traf":
+			return h.Expand()
+
+		case "trun":
+			box, _, err2 := h.ReadPayload()
+			require.NoError(t, err2)
+			trun := box.(*mp4.Trun)
+
+			cur := fragments[len(fragments)-1]
+			cur.startsWithSAP = (trun.Entries[0].SampleFlags & (1 << 16)) == 0
+			for _, e := range trun.Entries {
+				cur.duration += e.SampleDuration
+			}
+
+		case "mdat":
+			cur := fragments[len(fragments)-1]
+			cur.size = h.BoxInfo.Offset + h.BoxInfo.Size - cur.offset
+		}
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, sidx)
+	require.Equal(t, 3, len(fragments))
+	require.Equal(t, uint32(1), sidx.ReferenceID)
+	require.Equal(t, uint32(90000), sidx.Timescale)
+	require.Equal(t, uint64(0), sidx.GetEarliestPresentationTime())
+	require.Equal(t, fragments[0].offset, sidxEnd+sidx.GetFirstOffset())
+	require.Equal(t, len(fragments), int(sidx.ReferenceCount))
+
+	for i, fragment := range fragments {
+		ref := sidx.References[i]
+		require.False(t, ref.ReferenceType)
+		require.Equal(t, fragment.size, uint64(ref.ReferencedSize))
+		require.Equal(t, fragment.duration, ref.SubsegmentDuration)
+		require.Equal(t, fragment.startsWithSAP, ref.StartsWithSAP)
+	}
+
+	// the second fragment starts with a non-IDR frame
+	require.Equal(t, []bool{true, false, true}, []bool{
+		sidx.References[0].StartsWithSAP,
+		sidx.References[1].StartsWithSAP,
+		sidx.References[2].StartsWithSAP,
+	})
+}
+
+func TestRecorderFMP4Repair(t *testing.T) {
+	for _, ca := range []string{
+		"truncated",
+		"truncated separate init",
+		"unrecoverable",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			desc := &description.Session{Medias: []*description.Media{{
+				Type: description.MediaTypeVideo,
+				Formats: []rtspformat.Format{&rtspformat.H264{
+					PayloadTyp:        96,
+					PacketizationMode: 1,
+				}},
+			}}}
+
+			stream, err := stream.New(
+				512,
+				1460,
+				desc,
+				true,
+				0,
+				0,
+				0,
+				test.NilLogger,
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+			segmentPath := filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4")
+
+			init := fmp4.Init{
+				Tracks: []*fmp4.InitTrack{{
+					ID:        1,
+					TimeScale: 90000,
+					Codec: &fmp4.CodecH264{
+						SPS: test.FormatH264.SPS,
+						PPS: test.FormatH264.PPS,
+					},
+				}},
+			}
+
+			var buf seekablebuffer.Buffer
+			err = init.Marshal(&buf)
+			require.NoError(t, err)
+			byts := buf.Bytes()
+
+			// the initialization section is stored into a separate file
+			if ca == "truncated separate init" {
+				byts = nil
+			}
+
+			for i := 0; i < 3; i++ {
+				part := fmp4.Part{
+					SequenceNumber: uint32(i),
+					Tracks: []*fmp4.PartTrack{{
+						ID:       1,
+						BaseTime: uint64(i) * 90000,
+						Samples: []*fmp4.PartSample{{
+							Duration: 90000,
+							Payload:  []byte{0, 0, 0, 1, 5},
+						}},
+					}},
+				}
+
+				buf = seekablebuffer.Buffer{}
+				err = part.Marshal(&buf)
+				require.NoError(t, err)
+				byts = append(byts, buf.Bytes()...)
+			}
+
+			var completeSize int
+			if ca != "unrecoverable" {
+				// the recording is interrupted while writing the third fragment
+				completeSize = len(byts) - len(buf.Bytes())
+				byts = byts[:len(byts)-len(buf.Bytes())/2]
+			} else {
+				// the recording is interrupted while writing the initialization section
+				byts = byts[:20]
+			}
+
+			err = os.MkdirAll(filepath.Dir(segmentPath), 0o755)
+			require.NoError(t, err)
+
+			err = os.WriteFile(segmentPath, byts, 0o644)
+			require.NoError(t, err)
+
+			// the segment belongs to a previous run of the server
+			err = os.Chtimes(segmentPath, runStart.Add(-time.Hour), runStart.Add(-time.Hour))
+			require.NoError(t, err)
+
+			w := &Recorder{
+				PathFormat:      recordPath,
+				Format:          conf.RecordFormatFMP4,
+				PartDuration:    100 * time.Millisecond,
+				SegmentDuration: 1 * time.Second,
+				PathName:        "mypath",
+				Stream:          stream,
+				Parent:          test.NilLogger,
+			}
+			w.Initialize()
+			w.Close()
+
+			if ca != "unrecoverable" {
+				byts, err = os.ReadFile(segmentPath)
+				require.NoError(t, err)
+				require.Equal(t, completeSize, len(byts))
+
+				if ca == "truncated" {
+					var init2 fmp4.Init
+					err = init2.Unmarshal(bytes.NewReader(byts))
+					require.NoError(t, err)
+					require.Equal(t, init, init2)
+				}
+
+				var parts fmp4.Parts
+				err = parts.Unmarshal(byts)
+				require.NoError(t, err)
+				require.Equal(t, 2, len(parts))
+			} else {
+				_, err = os.Stat(segmentPath)
+				require.True(t, os.IsNotExist(err))
+
+				var byts2 []byte
+				byts2, err = os.ReadFile(segmentPath + corruptedSuffix)
+				require.NoError(t, err)
+				require.Equal(t, byts, byts2)
+			}
+		})
+	}
+}
+
+func TestRecorderMPEGTSCompression(t *testing.T) {
+	for _, ca := range []string{"gzip", "zstd"} {
+		t.Run(ca, func(t *testing.T) {
+			desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+			stream, err := stream.New(
+				512,
+				1460,
+				desc,
+				true,
+				0,
+				0,
+				0,
+				test.NilLogger,
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			var compression conf.RecordCompression
+			if ca == "gzip" {
+				compression = conf.RecordCompressionGzip
+			} else {
+				compression = conf.RecordCompressionZstd
+			}
+
+			newRecorder := func(pathName string, compression conf.RecordCompression) *Recorder {
+				w := &Recorder{
+					PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+					Format:          conf.RecordFormatMPEGTS,
+					Compression:     compression,
+					PartDuration:    100 * time.Millisecond,
+					SegmentDuration: 1 * time.Hour,
+					PathName:        pathName,
+					Stream:          stream,
+					Parent:          test.NilLogger,
+				}
+				w.Initialize()
+				return w
+			}
+
+			// the same stream is recorded with and without compression
+			w1 := newRecorder("plain", conf.RecordCompressionNone)
+			w2 := newRecorder("compressed", compression)
+
+			for i := 0; i < 10; i++ {
+				stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+					Base: unit.Base{
+						PTS: int64(i) * 100 * 90000 / 1000,
+						NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC),
+					},
+					AU: [][]byte{
+						test.FormatH264.SPS,
+						test.FormatH264.PPS,
+						{5}, // IDR
+					},
+				})
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			w1.Close()
+			w2.Close()
+
+			plain, err := os.ReadFile(filepath.Join(dir, "plain", "2008-05-20_22-15-25-000000.ts"))
+			require.NoError(t, err)
+			require.NotEmpty(t, plain)
+
+			segments, err := recordstore.FindSegments(&conf.Path{
+				RecordPath:        filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+				RecordFormat:      conf.RecordFormatMPEGTS,
+				RecordCompression: compression,
+			}, "compressed")
+			require.NoError(t, err)
+			require.Len(t, segments, 1)
+
+			ext := ".ts.gz"
+			if ca == "zstd" {
+				ext = ".ts.zst"
+			}
+			require.Equal(t, filepath.Join(dir, "compressed", "2008-05-20_22-15-25-000000"+ext), segments[0].Fpath)
+
+			r, err := recordstore.OpenSegment(segments[0].Fpath)
+			require.NoError(t, err)
+			defer r.Close()
+
+			decompressed, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, plain, decompressed)
+		})
+	}
+}
+
+func TestRecorderRotate(t *testing.T) {
+	for _, ca := range []string{"fmp4", "mpegts"} {
+		t.Run(ca, func(t *testing.T) {
+			desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+			stream, err := stream.New(
+				512,
+				1460,
+				desc,
+				true,
+				0,
+				0,
+				0,
+				test.NilLogger,
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			var format conf.RecordFormat
+			var ext string
+			if ca == "fmp4" {
+				format = conf.RecordFormatFMP4
+				ext = "mp4"
+			} else {
+				format = conf.RecordFormatMPEGTS
+				ext = "ts"
+			}
+
+			var created []string
+			var completed []string
+
+			w := &Recorder{
+				PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+				Format:          format,
+				PartDuration:    100 * time.Millisecond,
+				SegmentDuration: 1 * time.Hour,
+				PathName:        "mypath",
+				Stream:          stream,
+				OnSegmentCreate: func(fpath string) {
+					created = append(created, filepath.Base(fpath))
+				},
+				OnSegmentComplete: func(fpath string, _ time.Duration) {
+					completed = append(completed, filepath.Base(fpath))
+				},
+				Parent: test.NilLogger,
+			}
+			w.Initialize()
+
+			writeUnits := func(start int, count int) {
+				for i := start; i < (start + count); i++ {
+					stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+						Base: unit.Base{
+							PTS: int64(i) * 90000,
+							NTP: time.Date(2008, 5, 20, 22, 15, 25+i, 0, time.UTC),
+						},
+						AU: [][]byte{
+							test.FormatH264.SPS,
+							test.FormatH264.PPS,
+							{5}, // IDR
+						},
+					})
+				}
+			}
+
+			writeUnits(0, 3)
+			time.Sleep(50 * time.Millisecond)
+
+			w.Rotate()
+
+			writeUnits(3, 3)
+			time.Sleep(50 * time.Millisecond)
+
+			w.Close()
+
+			// the new segment starts with the first unit written after the rotation
+			require.Equal(t, []string{
+				"2008-05-20_22-15-25-000000." + ext,
+				"2008-05-20_22-15-28-000000." + ext,
+			}, created)
+			require.Equal(t, created, completed)
+		})
+	}
+}
+
+func captionsSEI(pairs [][2]byte) []byte {
+	payload := []byte{0xB5, 0x00, 0x31, 'G', 'A', '9', '4', 0x03, 0x40 | byte(len(pairs)), 0xFF}
+	for _, pair := range pairs {
+		payload = append(payload, 0xFC, pair[0], pair[1])
+	}
+	payload = append(payload, 0xFF)
+
+	nalu := []byte{0x06, 0x04, byte(len(payload))}
+	nalu = append(nalu, payload...)
+	return append(nalu, 0x80)
+}
+
+func TestRecorderCaptions(t *testing.T) {
+	for _, ca := range []string{"fmp4", "mpegts"} {
+		t.Run(ca, func(t *testing.T) {
+			desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+			stream, err := stream.New(
+				512,
+				1460,
+				desc,
+				true,
+				0,
+				0,
+				0,
+				test.NilLogger,
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+
+			var format conf.RecordFormat
+			var ext string
+			if ca == "fmp4" {
+				format = conf.RecordFormatFMP4
+				ext = ".mp4"
+			} else {
+				format = conf.RecordFormatMPEGTS
+				ext = ".ts"
+			}
+
+			w := &Recorder{
+				PathFormat:      recordPath,
+				Format:          format,
+				Captions:        true,
+				PartDuration:    100 * time.Millisecond,
+				SegmentDuration: 1 * time.Second,
+				PathName:        "mypath",
+				Stream:          stream,
+				Parent:          test.NilLogger,
+			}
+			w.Initialize()
+
+			// a pop-on caption is displayed from 0.6s to 1.4s,
+			// across the first and the second segment.
+			frameCaptions := map[int][][2]byte{
+				1: {{0x14, 0x20}, {0x14, 0x20}, {0x14, 0x70}, {0x14, 0x70}, {'H', 'i'}, {'!', 0}},
+				3: {{0x14, 0x2F}, {0x14, 0x2F}},
+				7: {{0x14, 0x2C}, {0x14, 0x2C}},
+			}
+
+			for i := 0; i < 11; i++ {
+				au := [][]byte{
+					test.FormatH264.SPS,
+					test.FormatH264.PPS,
+					{5}, // IDR
+				}
+				if pairs, ok := frameCaptions[i]; ok {
+					au = append(au, captionsSEI(pairs))
+				}
+
+				stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+					Base: unit.Base{
+						PTS: int64(i) * 200 * 90000 / 1000,
+						NTP: time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC).Add(time.Duration(i) * 200 * time.Millisecond),
+					},
+					AU: au,
+				})
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			w.Close()
+
+			buf, err := os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000"+ext+".vtt"))
+			require.NoError(t, err)
+			require.Equal(t, "WEBVTT\n"+
+				"\n"+
+				"00:00:00.600 --> 00:00:01.000\n"+
+				"Hi!\n", string(buf))
+
+			buf, err = os.ReadFile(filepath.Join(dir, "mypath", "2008-05-20_22-15-26-000000"+ext+".vtt"))
+			require.NoError(t, err)
+			require.Equal(t, "WEBVTT\n"+
+				"\n"+
+				"00:00:00.000 --> 00:00:00.400\n"+
+				"Hi!\n", string(buf))
+		})
+	}
+}
+
+func TestRecorderSourceVariables(t *testing.T) {
+	pathFormat, logPathFormat := replaceSourceVariables(
+		"%path/%user/%q_cam-%q_token-%q_missing/%query_%connid",
+		&Recorder{
+			SourceUser:  "myuser",
+			SourceID:    "myid",
+			SourceQuery: "cam=roof/1&token=abc&x=%2E%2E",
+		})
+
+	require.Equal(t, "%path/myuser/roof_1-abc-/cam=roof_1&token=abc&x=_2E_2E_myid", pathFormat)
+	require.Equal(t, "%path/[user]/roof_1-[redacted]-/cam=roof_1&token=[redacted]&x=_2E_2E_myid", logPathFormat)
+
+	pathFormat, _ = replaceSourceVariables(
+		"/rec/%path/%user/%Y",
+		&Recorder{
+			SourceUser: "../../etc/x",
+		})
+
+	require.Equal(t, "/rec/%path/______etc_x/%Y", pathFormat)
+}
+
+func TestRecorderFMP4FillGaps(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type: description.MediaTypeVideo,
+			Formats: []rtspformat.Format{&rtspformat.H264{
+				PayloadTyp:        96,
+				PacketizationMode: 1,
+			}},
+		},
+		{
+			Type: description.MediaTypeAudio,
+			Formats: []rtspformat.Format{&rtspformat.MPEG4Audio{
+				PayloadTyp: 96,
+				Config: &mpeg4audio.Config{
+					Type:         2,
+					SampleRate:   44100,
+					ChannelCount: 2,
+				},
+				SizeLength:       13,
+				IndexLength:      3,
+				IndexDeltaLength: 3,
+			}},
+		},
+	}}
+
+	for _, ca := range []string{"disabled", "enabled"} {
+		t.Run(ca, func(t *testing.T) {
+			stream, err := stream.New(
+				512,
+				1460,
+				desc,
+				true,
+				0,
+				0,
+				0,
+				test.NilLogger,
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+			segmentPath := filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4")
+
+			w := &Recorder{
+				PathFormat:      recordPath,
+				Format:          conf.RecordFormatFMP4,
+				PartDuration:    100 * time.Millisecond,
+				SegmentDuration: 10 * time.Second,
+				FillGaps:        ca == "enabled",
+				PathName:        "mypath",
+				Stream:          stream,
+				Parent:          test.NilLogger,
+			}
+			w.Initialize()
+
+			ntp := time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC)
+
+			// video has a frame every 100ms, audio has a frame every 1024 samples.
+			// Video is interrupted between 1s and 4s, while audio is not.
+			audioPTS := int64(0)
+
+			for i := 0; i < 50; i++ {
+				pts := int64(i) * 100 * 90000 / 1000
+
+				if i < 10 || i >= 40 {
+					stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+						Base: unit.Base{
+							PTS: pts,
+							NTP: ntp.Add(time.Duration(i) * 100 * time.Millisecond),
+						},
+						AU: [][]byte{
+							test.FormatH264.SPS,
+							test.FormatH264.PPS,
+							{5}, // IDR
+						},
+					})
+				}
+
+				for audioPTS*90000/44100 < pts+100*90000/1000 {
+					stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.MPEG4Audio{
+						Base: unit.Base{
+							PTS: audioPTS,
+							NTP: ntp.Add(time.Duration(audioPTS) * time.Second / 44100),
+						},
+						AUs: [][]byte{{1, 2, 3, 4}},
+					})
+					audioPTS += 1024
+				}
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			w.Close()
+
+			byts, err := os.ReadFile(segmentPath)
+			require.NoError(t, err)
+
+			var parts fmp4.Parts
+			err = parts.Unmarshal(byts)
+			require.NoError(t, err)
+
+			// read parts in order, as a player would do,
+			// and compute the lag of the video track with respect to the audio track.
+			var videoEnd time.Duration
+			var audioEnd time.Duration
+			var maxLag time.Duration
+			videoFrames := 0
+
+			for _, part := range parts {
+				for _, track := range part.Tracks {
+					timeScale := uint64(90000)
+					if track.ID == 2 {
+						timeScale = 44100
+					}
+
+					end := track.BaseTime
+					for _, sample := range track.Samples {
+						end += uint64(sample.Duration)
+						if track.ID == 1 && len(sample.Payload) != 0 {
+							videoFrames++
+						}
+					}
+
+					if track.ID == 1 {
+						// base times are computed with a precision of one tick
+						require.GreaterOrEqual(t, durationMP4ToGo(track.BaseTime+1, timeScale), videoEnd)
+						videoEnd = durationMP4ToGo(end, timeScale)
+					} else {
+						audioEnd = durationMP4ToGo(end, timeScale)
+					}
+				}
+
+				maxLag = max(maxLag, audioEnd-videoEnd)
+			}
+
+			// the last frame is not written since its duration is unknown
+			require.Equal(t, 19, videoFrames)
+
+			if ca == "enabled" {
+				require.LessOrEqual(t, maxLag, fmp4GapDuration+100*time.Millisecond)
+			} else {
+				require.Greater(t, maxLag, 2*time.Second)
+			}
+		})
+	}
+}
+
+func durationMP4ToGo(v uint64, timeScale uint64) time.Duration {
+	return time.Duration(v) * time.Second / time.Duration(timeScale)
+}
+
+func TestRecorderFMP4Manifest(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		{
+			Type: description.MediaTypeVideo,
+			Formats: []rtspformat.Format{&rtspformat.H264{
+				PayloadTyp:        96,
+				PacketizationMode: 1,
+			}},
+		},
+		{
+			Type: description.MediaTypeVideo,
+			Formats: []rtspformat.Format{&rtspformat.H265{
+				PayloadTyp: 96,
+			}},
+		},
+		{
+			Type: description.MediaTypeAudio,
+			Formats: []rtspformat.Format{&rtspformat.MPEG4Audio{
+				PayloadTyp: 96,
+				Config: &mpeg4audio.Config{
+					Type:         2,
+					SampleRate:   44100,
+					ChannelCount: 2,
+				},
+				SizeLength:       13,
+				IndexLength:      3,
+				IndexDeltaLength: 3,
+			}},
+		},
+	}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recordPath := filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f")
+	segmentPath := filepath.Join(dir, "mypath", "2008-05-20_22-15-25-000000.mp4")
+
+	segDone := make(chan struct{}, 2)
+
+	w := &Recorder{
+		PathFormat:      recordPath,
+		Format:          conf.RecordFormatFMP4,
+		WriteManifest:   true,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 1 * time.Second,
+		PathName:        "mypath",
+		Stream:          stream,
+		OnSegmentComplete: func(segPath string, _ time.Duration) {
+			// the manifest is written before the segment is reported as complete
+			_, err2 := os.Stat(recordstore.ManifestPath(segPath))
+			require.NoError(t, err2)
+			segDone <- struct{}{}
+		},
+		Parent: test.NilLogger,
+	}
+	w.Initialize()
+	defer w.Close()
+
+	ntp := time.Date(2008, 5, 20, 22, 15, 25, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		pts := int64(i) * 90000
+
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: pts,
+				NTP: ntp.Add(time.Duration(i) * time.Second),
+			},
+			AU: [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			},
+		})
+
+		stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.H265{
+			Base: unit.Base{
+				PTS: pts,
+			},
+			AU: [][]byte{
+				test.FormatH265.VPS,
+				test.FormatH265.SPS,
+				test.FormatH265.PPS,
+				{byte(h265.NALUType_CRA_NUT) << 1, 0}, // IDR
+			},
+		})
+
+		stream.WriteUnit(desc.Medias[2], desc.Medias[2].Formats[0], &unit.MPEG4Audio{
+			Base: unit.Base{
+				PTS: pts * 44100 / 90000,
+			},
+			AUs: [][]byte{{1, 2, 3, 4}},
+		})
+	}
+
+	<-segDone
+
+	var init fmp4.Init
+
+	func() {
+		f, err2 := os.Open(segmentPath)
+		require.NoError(t, err2)
+		defer f.Close()
+
+		err2 = init.Unmarshal(f)
+		require.NoError(t, err2)
+	}()
+
+	require.Len(t, init.Tracks, 3)
+	h264Codec := init.Tracks[0].Codec.(*fmp4.CodecH264)
+	h265Codec := init.Tracks[1].Codec.(*fmp4.CodecH265)
+	aacCodec := init.Tracks[2].Codec.(*fmp4.CodecMPEG4Audio)
+
+	aacConfig, err := aacCodec.Config.Marshal()
+	require.NoError(t, err)
+
+	buf, err := os.ReadFile(recordstore.ManifestPath(segmentPath))
+	require.NoError(t, err)
+
+	var m manifest
+	err = json.Unmarshal(buf, &m)
+	require.NoError(t, err)
+
+	require.Equal(t, manifest{
+		Start:    ntp,
+		Duration: 1,
+		Tracks: []manifestTrack{
+			{
+				ID:        init.Tracks[0].ID,
+				TimeScale: init.Tracks[0].TimeScale,
+				Codec:     "H264",
+				SPS:       hex.EncodeToString(h264Codec.SPS),
+				PPS:       hex.EncodeToString(h264Codec.PPS),
+			},
+			{
+				ID:        init.Tracks[1].ID,
+				TimeScale: init.Tracks[1].TimeScale,
+				Codec:     "H265",
+				VPS:       hex.EncodeToString(h265Codec.VPS),
+				SPS:       hex.EncodeToString(h265Codec.SPS),
+				PPS:       hex.EncodeToString(h265Codec.PPS),
+			},
+			{
+				ID:           init.Tracks[2].ID,
+				TimeScale:    init.Tracks[2].TimeScale,
+				Codec:        "MPEG-4 Audio",
+				Config:       hex.EncodeToString(aacConfig),
+				SampleRate:   44100,
+				ChannelCount: 2,
+			},
+		},
+	}, m)
+
+	require.Equal(t, hex.EncodeToString(test.FormatH264.SPS), m.Tracks[0].SPS)
+	require.Equal(t, hex.EncodeToString(test.FormatH265.VPS), m.Tracks[1].VPS)
+}
+
+func TestRecorderKeyFrameInterval(t *testing.T) {
+	// key frames with an irregular interval of about 2 seconds
+	keyFrames := []float64{0, 2.1, 3.9, 6.2, 7.8, 10.1, 12.0, 14.2, 15.9, 18.1, 20.0}
+
+	for _, formatName := range []string{"fmp4", "mpegts"} {
+		for _, ca := range []struct {
+			name      string
+			interval  time.Duration
+			durations []float64
+		}{
+			{
+				"disabled",
+				0,
+				[]float64{6.2, 5.8, 6.1},
+			},
+			{
+				"enabled",
+				2 * time.Second,
+				[]float64{3.9, 3.9, 4.2, 3.9, 4.1},
+			},
+		} {
+			t.Run(formatName+"_"+ca.name, func(t *testing.T) {
+				format := conf.RecordFormatFMP4
+				if formatName == "mpegts" {
+					format = conf.RecordFormatMPEGTS
+				}
+
+				desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+				stream, err := stream.New(
+					512,
+					1460,
+					desc,
+					true,
+					0,
+					0,
+					0,
+					test.NilLogger,
+				)
+				require.NoError(t, err)
+				defer stream.Close()
+
+				dir, err := os.MkdirTemp("", "mediamtx-agent")
+				require.NoError(t, err)
+				defer os.RemoveAll(dir)
+
+				var durations []float64
+
+				w := &Recorder{
+					PathFormat:       filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+					Format:           format,
+					PartDuration:     100 * time.Millisecond,
+					SegmentDuration:  4 * time.Second,
+					KeyFrameInterval: ca.interval,
+					PathName:         "mypath",
+					Stream:           stream,
+					OnSegmentComplete: func(_ string, duration time.Duration) {
+						durations = append(durations, duration.Seconds())
+					},
+					Parent: test.NilLogger,
+				}
+				w.Initialize()
+
+				for i, kf := range keyFrames {
+					stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+						Base: unit.Base{
+							PTS: int64(kf * 90000),
+							NTP: time.Date(2008, 5, 20, 22, 15, 25+i, 0, time.UTC),
+						},
+						AU: [][]byte{
+							test.FormatH264.SPS,
+							test.FormatH264.PPS,
+							{5}, // IDR
+						},
+					})
+				}
+
+				time.Sleep(50 * time.Millisecond)
+
+				w.Close()
+
+				// the last segment is closed by Close()
+				require.InDeltaSlice(t, ca.durations, durations[:len(durations)-1], 0.001)
+
+				if ca.interval != 0 {
+					for _, d := range durations[:len(durations)-1] {
+						require.InDelta(t, 4, d, ca.interval.Seconds()/2)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRecorderMaxSegments(t *testing.T) {
+	for _, formatName := range []string{"fmp4", "mpegts"} {
+		t.Run(formatName, func(t *testing.T) {
+			format := conf.RecordFormatFMP4
+			if formatName == "mpegts" {
+				format = conf.RecordFormatMPEGTS
+			}
+
+			desc := &description.Session{Medias: []*description.Media{test.UniqueMediaH264()}}
+
+			stream, err := stream.New(
+				512,
+				1460,
+				desc,
+				true,
+				0,
+				0,
+				0,
+				test.NilLogger,
+			)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			dir, err := os.MkdirTemp("", "mediamtx-agent")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			var completed []string
+
+			w := &Recorder{
+				PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+				Format:          format,
+				PartDuration:    100 * time.Millisecond,
+				SegmentDuration: 1 * time.Second,
+				WriteManifest:   format == conf.RecordFormatFMP4,
+				MaxSegments:     3,
+				PathName:        "mypath",
+				Stream:          stream,
+				OnSegmentComplete: func(segmentPath string, _ time.Duration) {
+					// the segment that has just been completed is never removed
+					_, err2 := os.Stat(segmentPath)
+					require.NoError(t, err2)
+
+					completed = append(completed, segmentPath)
+				},
+				Parent: test.NilLogger,
+			}
+			w.Initialize()
+
+			for i := 0; i < 8; i++ {
+				stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+					Base: unit.Base{
+						PTS: int64(i) * 90000,
+						NTP: time.Date(2008, 5, 20, 22, 15, 25+i, 0, time.UTC),
+					},
+					AU: [][]byte{
+						test.FormatH264.SPS,
+						test.FormatH264.PPS,
+						{5}, // IDR
+					},
+				})
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			w.Close()
+
+			require.Greater(t, len(completed), 3)
+
+			entries, err := os.ReadDir(filepath.Join(dir, "mypath"))
+			require.NoError(t, err)
+
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+
+			expected := make([]string, 0, 6)
+			for _, segmentPath := range completed[len(completed)-3:] {
+				expected = append(expected, filepath.Base(segmentPath))
+				if format == conf.RecordFormatFMP4 {
+					expected = append(expected, filepath.Base(recordstore.ManifestPath(segmentPath)))
+				}
+			}
+
+			require.ElementsMatch(t, expected, names)
+		})
+	}
+}
+
+func TestRecorderFMP4SeparateInit(t *testing.T) {
+	sps1 := []byte{
+		0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02,
+		0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+		0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20,
+	}
+
+	sps2 := []byte{
+		0x67, 0x64, 0x00, 0x0c, 0xac, 0x3b, 0x50, 0xb0,
+		0x4b, 0x42, 0x00, 0x00, 0x03, 0x00, 0x02, 0x00,
+		0x00, 0x03, 0x00, 0x3d, 0x08,
+	}
+
+	desc := &description.Session{Medias: []*description.Media{{
+		Type: description.MediaTypeVideo,
+		Formats: []rtspformat.Format{&rtspformat.H264{
+			PayloadTyp:        96,
+			SPS:               sps1,
+			PPS:               test.FormatH264.PPS,
+			PacketizationMode: 1,
+		}},
+	}}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	dir, err := os.MkdirTemp("", "mediamtx-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var completed []string
+
+	w := &Recorder{
+		PathFormat:      filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		Format:          conf.RecordFormatFMP4,
+		PartDuration:    100 * time.Millisecond,
+		SegmentDuration: 1 * time.Second,
+		SeparateInit:    true,
+		PathName:        "mypath",
+		Stream:          stream,
+		OnSegmentComplete: func(segmentPath string, _ time.Duration) {
+			completed = append(completed, segmentPath)
+		},
+		Parent: test.NilLogger,
+	}
+	w.Initialize()
+
+	for i := 0; i < 6; i++ {
+		sps := sps1
+		if i >= 3 {
+			sps = sps2 // codec parameters change
+		}
+
+		stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+			Base: unit.Base{
+				PTS: int64(i) * 90000,
+				NTP: time.Date(2008, 5, 20, 22, 15, 25+i, 0, time.UTC),
+			},
+			AU: [][]byte{
+				sps,
+				test.FormatH264.PPS,
+				{5}, // IDR
+			},
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Close()
+
+	segmentPath := func(sec int) string {
+		return filepath.Join(dir, "mypath", fmt.Sprintf("2008-05-20_22-15-%02d-000000.mp4", sec))
+	}
+
+	require.Equal(t, []string{
+		segmentPath(25),
+		segmentPath(26),
+		segmentPath(27),
+		segmentPath(28),
+		segmentPath(29),
+	}, completed)
+
+	// an initialization file is written by the first segment and when codec parameters change.
+	// Samples are written when the following one is received, therefore the change
+	// is applied to the segment that has not been created yet when the change is received.
+	for _, segmentPath := range completed {
+		_, err = os.Stat(recordstore.InitPath(segmentPath))
+		if segmentPath == completed[0] || segmentPath == completed[2] {
+			require.NoError(t, err)
+		} else {
+			require.Error(t, err, segmentPath)
+		}
+	}
+
+	for _, ca := range []struct {
+		initOwner string
+		segments  []string
+		sps       []byte
+	}{
+		{completed[0], completed[:2], sps1},
+		{completed[2], completed[2:], sps2},
+	} {
+		var buf []byte
+		buf, err = os.ReadFile(recordstore.InitPath(ca.initOwner))
+		require.NoError(t, err)
+
+		var init fmp4.Init
+		err = init.Unmarshal(bytes.NewReader(buf))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(init.Tracks))
+		require.Equal(t, ca.sps, init.Tracks[0].Codec.(*fmp4.CodecH264).SPS)
+
+		for _, segmentPath := range ca.segments {
+			buf, err = os.ReadFile(segmentPath)
+			require.NoError(t, err)
+			require.Equal(t, []byte{'m', 'o', 'o', 'f'}, buf[4:8])
+
+			// the segment is playable when appended to the initialization section
+			var parts fmp4.Parts
+			err = parts.Unmarshal(buf)
+			require.NoError(t, err)
+			require.NotEmpty(t, parts)
+
+			for _, part := range parts {
+				for _, track := range part.Tracks {
+					require.Equal(t, init.Tracks[0].ID, track.ID)
+					require.NotEmpty(t, track.Samples)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+type fmp4Box struct {
+	typ  string
+	size int64
+}
+
+func readFMP4BoxHeader(r io.Reader, remaining int64) (*fmp4Box, error) {
+	buf := make([]byte, 8)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	box := &fmp4Box{
+		typ:  string(buf[4:]),
+		size: int64(binary.BigEndian.Uint32(buf)),
+	}
+
+	switch box.size {
+	case 0: // box extends to the end of the file
+		box.size = remaining
+
+	case 1: // 64-bit size
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return nil, err
+		}
+		box.size = int64(binary.BigEndian.Uint64(buf))
+		if box.size < 16 {
+			return nil, fmt.Errorf("invalid box size")
+		}
+		return box, nil
+	}
+
+	if box.size < 8 {
+		return nil, fmt.Errorf("invalid box size")
+	}
+
+	return box, nil
+}
+
+// fmp4ValidSize returns the size of the longest prefix of a fMP4 segment
+// that is made of an initialization section (ftyp + moov),
+// an optional segment index (sidx) and complete fragments (moof + mdat), and the number of these fragments.
+// The initialization section is absent from segments that share a separate initialization file.
+func fmp4ValidSize(f io.ReadSeeker, fileSize int64) (int64, int, error) {
+	var offset int64
+	var validSize int64
+	fragmentCount := 0
+	expected := []string{"ftyp", "moov"}
+	initDone := false
+
+	for offset < fileSize {
+		_, err := f.Seek(offset, io.SeekStart)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		box, err := readFMP4BoxHeader(f, fileSize-offset)
+		if err != nil {
+			break
+		}
+
+		if box.size > (fileSize - offset) {
+			break
+		}
+
+		if offset == 0 && (box.typ == "moof" || box.typ == "sidx") {
+			initDone = true
+			expected = []string{"moof", "mdat"}
+		}
+
+		// a segment index may be placed between the initialization section and the first fragment
+		if box.typ == "sidx" && initDone && fragmentCount == 0 && expected[0] == "moof" {
+			offset += box.size
+			continue
+		}
+
+		if box.typ != expected[0] {
+			break
+		}
+
+		offset += box.size
+		expected = expected[1:]
+
+		if len(expected) == 0 {
+			if initDone {
+				fragmentCount++
+			}
+			initDone = true
+			validSize = offset
+			expected = []string{"moof", "mdat"}
+		}
+	}
+
+	if !initDone {
+		return 0, 0, fmt.Errorf("initialization section is missing or incomplete")
+	}
+
+	return validSize, fragmentCount, nil
+}
+
+// repairFMP4Segment truncates a segment to its last complete fragment,
+// in order to make it playable again after its recording was interrupted abruptly.
+// It returns whether the segment has been truncated, or an error if the segment cannot be repaired.
+func repairFMP4Segment(fpath string) (bool, error) {
+	f, err := os.OpenFile(fpath, os.O_RDWR, 0)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	validSize, fragmentCount, err := fmp4ValidSize(f, fi.Size())
+	if err != nil {
+		return false, err
+	}
+
+	if fragmentCount == 0 {
+		return false, errors.New("segment doesn't contain any complete fragment")
+	}
+
+	if validSize == fi.Size() {
+		return false, nil
+	}
+
+	err = f.Truncate(validSize)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,52 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
+)
+
+// onSegmentComplete is called when a segment is complete.
+func (r *Recorder) onSegmentComplete(segmentPath string, duration time.Duration) {
+	if r.MaxSegments != 0 {
+		r.removeExcessSegments()
+	}
+
+	r.OnSegmentComplete(segmentPath, duration)
+}
+
+// removeExcessSegments removes the oldest segments of the path,
+// until at most MaxSegments segments are left.
+// Segments that are being written, including the one that has just been completed,
+// whose marker is still in place, are never removed.
+func (r *Recorder) removeExcessSegments() {
+	segments, err := recordstore.FindSegments(&conf.Path{
+		RecordPath:         r.PathFormat,
+		RecordPathSanitize: r.PathSanitize,
+		RecordFormat:       r.Format,
+		RecordCompression:  r.Compression,
+	}, r.PathName)
+	if err != nil {
+		return
+	}
+
+	excess := len(segments) - r.MaxSegments
+	now := time.Now()
+
+	for i, seg := range segments {
+		if excess <= 0 {
+			break
+		}
+
+		// segments can be written by other processes
+		if recordstore.SegmentInUse(seg.Fpath, now) {
+			continue
+		}
+
+		r.Log(logger.Debug, "removing %s", seg.Fpath)
+		recordstore.RemoveSegment(segments, i)
+		excess--
+	}
+}
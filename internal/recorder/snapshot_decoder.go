@@ -0,0 +1,36 @@
+package recorder
+
+import (
+	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// snapshotDecoder converts key frames of a video track into JPEG images.
+type snapshotDecoder interface {
+	isKeyFrame(u unit.Unit) bool
+	decode(u unit.Unit) ([]byte, error)
+}
+
+// newSnapshotDecoder returns a decoder for the given format,
+// or nil if snapshots are not supported by the format.
+// Decoders of additional codecs can be plugged in here.
+func newSnapshotDecoder(forma rtspformat.Format) snapshotDecoder {
+	switch forma.(type) {
+	case *rtspformat.MJPEG:
+		return &snapshotDecoderMJPEG{}
+	}
+
+	return nil
+}
+
+// M-JPEG frames are JPEG images and are all key frames.
+type snapshotDecoderMJPEG struct{}
+
+func (snapshotDecoderMJPEG) isKeyFrame(u unit.Unit) bool {
+	return u.(*unit.MJPEG).Frame != nil
+}
+
+func (snapshotDecoderMJPEG) decode(u unit.Unit) ([]byte, error) {
+	return u.(*unit.MJPEG).Frame, nil
+}
@@ -0,0 +1,139 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// snapshotter writes a JPEG snapshot every N key frames.
+// It reads the stream independently from the recorder and decodes frames
+// in a separate worker, that discards frames when it can't keep up,
+// in order not to slow down recording.
+type snapshotter struct {
+	ri *recorderInstance
+
+	pathFormat    string
+	logPathFormat string
+	decoder       snapshotDecoder
+	count         int
+	queue         chan unit.Unit
+	dropLogger    logger.Writer
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+// Log implements logger.Writer.
+func (s *snapshotter) Log(level logger.Level, format string, args ...interface{}) {
+	s.ri.Log(level, format, args...)
+}
+
+func (s *snapshotter) initialize() bool {
+	var media *description.Media
+	var forma rtspformat.Format
+
+	for _, me := range s.ri.rec.Stream.Desc().Medias {
+		for _, fo := range me.Formats {
+			if s.ri.isSelected(fo) && s.decoder == nil {
+				s.decoder = newSnapshotDecoder(fo)
+				media = me
+				forma = fo
+			}
+		}
+	}
+
+	if s.decoder == nil {
+		s.Log(logger.Warn, "snapshots are not supported by any of the recorded tracks")
+		return false
+	}
+
+	s.pathFormat = s.ri.rec.SnapshotPathFormat + ".jpg"
+	s.pathFormat = strings.ReplaceAll(s.pathFormat, "%path", s.ri.rec.pathValue(s.ri.rec.PathName))
+	s.pathFormat, s.logPathFormat = replaceSourceVariables(s.pathFormat, s.ri.rec)
+
+	s.queue = make(chan unit.Unit, 1)
+	s.dropLogger = logger.NewLimitedLogger(s)
+	s.terminate = make(chan struct{})
+	s.done = make(chan struct{})
+
+	s.ri.rec.Stream.AddReader(s, media, forma, s.onUnit)
+	s.ri.rec.Stream.StartReader(s)
+
+	go s.run()
+
+	return true
+}
+
+func (s *snapshotter) close() {
+	s.ri.rec.Stream.RemoveReader(s)
+	close(s.terminate)
+	<-s.done
+}
+
+func (s *snapshotter) onUnit(u unit.Unit) error {
+	if !s.decoder.isKeyFrame(u) {
+		return nil
+	}
+
+	s.count++
+	if (s.count-1)%s.ri.rec.SnapshotInterval != 0 {
+		return nil
+	}
+
+	select {
+	case s.queue <- u:
+	default:
+		s.dropLogger.Log(logger.Warn, "snapshot worker is too slow, discarding snapshot")
+	}
+
+	return nil
+}
+
+func (s *snapshotter) run() {
+	defer close(s.done)
+
+	for {
+		select {
+		case u := <-s.queue:
+			err := s.write(u)
+			if err != nil {
+				s.Log(logger.Warn, "unable to write snapshot: %v", err)
+			}
+
+		case <-s.terminate:
+			return
+		}
+	}
+}
+
+func (s *snapshotter) write(u unit.Unit) error {
+	byts, err := s.decoder.decode(u)
+	if err != nil {
+		return err
+	}
+
+	pa := recordstore.Path{Start: u.GetNTP(), Location: s.ri.rec.Location}
+	fpath := pa.Encode(s.pathFormat)
+
+	err = os.MkdirAll(filepath.Dir(fpath), 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(fpath, byts, 0o644)
+	if err != nil {
+		return err
+	}
+
+	s.Log(logger.Debug, "snapshot written: %s", pa.Encode(s.logPathFormat))
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package recorder
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+const (
+	redactedUser  = "[user]"
+	redactedValue = "[redacted]"
+)
+
+var reQueryKey = regexp.MustCompile(`%q_([A-Za-z0-9_]+)`)
+
+// sanitizeSourceValue makes a value provided by the source safe to be used inside a path,
+// by replacing characters that are not letters, digits, '-', '_', '=', '&' with '_'.
+func sanitizeSourceValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'),
+			r == '-', r == '_', r == '=', r == '&':
+			return r
+		}
+		return '_'
+	}, v)
+}
+
+// redactQuery redacts values of query parameters that look like secrets.
+func redactQuery(query string) string {
+	parts := strings.Split(query, "&")
+	for i, part := range parts {
+		if key, _, ok := strings.Cut(part, "="); ok && defs.IsSecretKey(key) {
+			parts[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// replaceSourceVariables replaces variables that depend on the source
+// (%connid, %user, %query, %q_<key>).
+// It returns the resulting path format and a version of it that can be printed in logs,
+// in which the user and values of secret query parameters are redacted.
+func replaceSourceVariables(pathFormat string, rec *Recorder) (string, string) {
+	pathFormat = strings.ReplaceAll(pathFormat, "%connid", rec.SourceID)
+
+	// in case of errors, parameters that were parsed correctly are kept
+	q, _ := url.ParseQuery(rec.SourceQuery)
+
+	logPathFormat := reQueryKey.ReplaceAllStringFunc(pathFormat, func(m string) string {
+		key := m[len("%q_"):]
+		if defs.IsSecretKey(key) {
+			return redactedValue
+		}
+		return sanitizeSourceValue(q.Get(key))
+	})
+	pathFormat = reQueryKey.ReplaceAllStringFunc(pathFormat, func(m string) string {
+		return sanitizeSourceValue(q.Get(m[len("%q_"):]))
+	})
+
+	query := sanitizeSourceValue(rec.SourceQuery)
+	logPathFormat = strings.ReplaceAll(logPathFormat, "%query", redactQuery(query))
+	pathFormat = strings.ReplaceAll(pathFormat, "%query", query)
+
+	// the user is not printed in logs
+	logPathFormat = strings.ReplaceAll(logPathFormat, "%user", redactedUser)
+	pathFormat = strings.ReplaceAll(pathFormat, "%user", sanitizeSourceValue(rec.SourceUser))
+
+	return pathFormat, logPathFormat
+}
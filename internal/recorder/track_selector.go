@@ -0,0 +1,49 @@
+package recorder
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	rtspformat "github.com/bluenviron/gortsplib/v4/pkg/format"
+)
+
+// trackMatchesSelector checks whether a track matches a selector,
+// that is a media type, a codec or a track index starting from 1.
+func trackMatchesSelector(sel string, index int, media *description.Media, forma rtspformat.Format) bool {
+	if n, err := strconv.Atoi(sel); err == nil {
+		return n == index
+	}
+
+	switch strings.ToLower(sel) {
+	case string(description.MediaTypeVideo), string(description.MediaTypeAudio), string(description.MediaTypeApplication):
+		return strings.EqualFold(string(media.Type), sel)
+	}
+
+	return strings.EqualFold(forma.Codec(), sel)
+}
+
+// selectTracks returns the formats that match at least one selector.
+// When there are no selectors, all formats are returned.
+func selectTracks(medias []*description.Media, selectors []string) map[rtspformat.Format]struct{} {
+	ret := make(map[rtspformat.Format]struct{})
+	index := 1
+
+	for _, media := range medias {
+		for _, forma := range media.Formats {
+			if len(selectors) == 0 {
+				ret[forma] = struct{}{}
+			} else {
+				for _, sel := range selectors {
+					if trackMatchesSelector(sel, index, media, forma) {
+						ret[forma] = struct{}{}
+						break
+					}
+				}
+			}
+			index++
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,55 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
+)
+
+// writingMarker signals to other processes that a segment is being written.
+// Markers are advisory, therefore errors are logged and do not stop the recording.
+type writingMarker struct {
+	ri *recorderInstance
+
+	segmentPath string
+	lastRefresh time.Time
+}
+
+func (m *writingMarker) create(segmentPath string) {
+	err := recordstore.CreateWritingMarker(segmentPath)
+	if err != nil {
+		m.ri.Log(logger.Warn, "unable to create marker: %v", err)
+		return
+	}
+
+	m.segmentPath = segmentPath
+	m.lastRefresh = time.Now()
+}
+
+func (m *writingMarker) refresh() {
+	if m.segmentPath == "" {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(m.lastRefresh) < recordstore.WritingMarkerRefreshPeriod {
+		return
+	}
+
+	m.lastRefresh = now
+
+	err := recordstore.RefreshWritingMarker(m.segmentPath, now)
+	if err != nil {
+		m.ri.Log(logger.Warn, "unable to refresh marker: %v", err)
+	}
+}
+
+func (m *writingMarker) remove() {
+	if m.segmentPath == "" {
+		return
+	}
+
+	recordstore.RemoveWritingMarker(m.segmentPath)
+	m.segmentPath = ""
+}
@@ -0,0 +1,98 @@
+package recordstore
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// SegmentWriter is a writer that compresses segments.
+type SegmentWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+type uncompressedWriter struct {
+	io.Writer
+}
+
+func (uncompressedWriter) Flush() error {
+	return nil
+}
+
+func (uncompressedWriter) Close() error {
+	return nil
+}
+
+// NewSegmentWriter returns a writer that compresses data with the given compression.
+// Closing the writer flushes pending data but doesn't close the underlying writer.
+func NewSegmentWriter(w io.Writer, compression conf.RecordCompression) (SegmentWriter, error) {
+	switch compression {
+	case conf.RecordCompressionGzip:
+		return gzip.NewWriter(w), nil
+
+	case conf.RecordCompressionZstd:
+		return zstd.NewWriter(w)
+
+	default:
+		return uncompressedWriter{w}, nil
+	}
+}
+
+type segmentReader struct {
+	io.Reader
+	onClose func() error
+}
+
+func (r *segmentReader) Close() error {
+	return r.onClose()
+}
+
+// OpenSegment opens a segment for reading.
+// Compressed segments are decompressed transparently.
+func OpenSegment(fpath string) (io.ReadCloser, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(fpath, ".gz"):
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		return &segmentReader{
+			Reader: gr,
+			onClose: func() error {
+				gr.Close()
+				return f.Close()
+			},
+		}, nil
+
+	case strings.HasSuffix(fpath, ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		return &segmentReader{
+			Reader: zr,
+			onClose: func() error {
+				zr.Close()
+				return f.Close()
+			},
+		}, nil
+
+	default:
+		return f, nil
+	}
+}
@@ -0,0 +1,82 @@
+package recordstore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// InitPath returns the path of the file that contains the initialization section
+// of a fMP4 segment and of the following ones, when it is not embedded into segments.
+func InitPath(segmentPath string) string {
+	return segmentPath + ".init.mp4"
+}
+
+func fileExists(fpath string) bool {
+	_, err := os.Stat(fpath)
+	return err == nil
+}
+
+// segmentHasInit checks whether a fMP4 segment begins with an initialization section.
+func segmentHasInit(segmentPath string) bool {
+	f, err := os.Open(segmentPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, 8)
+	_, err = io.ReadFull(f, buf)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(buf[4:], []byte{'f', 't', 'y', 'p'})
+}
+
+// FindInit returns the segment whose initialization file applies to a segment
+// that doesn't contain an initialization section, that is the segment itself
+// or the nearest previous segment that has an initialization file.
+// segments must be sorted by start time, as returned by FindSegments.
+func FindInit(segments []*Segment, seg *Segment) (*Segment, error) {
+	i := len(segments) - 1
+	for i >= 0 && segments[i].Start.After(seg.Start) {
+		i--
+	}
+
+	for ; i >= 0; i-- {
+		if fileExists(InitPath(segments[i].Fpath)) {
+			return segments[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("initialization file of segment %s not found", seg.Fpath)
+}
+
+// RemoveSegment removes segments[i] together with its captions, manifest and marker.
+// When the segment has an initialization file that is shared with the following segment,
+// the file is handed over to the following segment, otherwise it is removed.
+func RemoveSegment(segments []*Segment, i int) {
+	fpath := segments[i].Fpath
+
+	os.Remove(fpath)
+	os.Remove(CaptionsPath(fpath))
+	os.Remove(ManifestPath(fpath))
+	RemoveWritingMarker(fpath)
+
+	initPath := InitPath(fpath)
+	if !fileExists(initPath) {
+		return
+	}
+
+	if i+1 < len(segments) {
+		next := segments[i+1].Fpath
+		if !fileExists(InitPath(next)) && !segmentHasInit(next) {
+			os.Rename(initPath, InitPath(next)) //nolint:errcheck
+			return
+		}
+	}
+
+	os.Remove(initPath)
+}
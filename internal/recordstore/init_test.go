@@ -0,0 +1,81 @@
+package recordstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindInitAndRemoveSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-recordstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.Mkdir(filepath.Join(dir, "mypath"), 0o755)
+	require.NoError(t, err)
+
+	moof := []byte{0, 0, 0, 8, 'm', 'o', 'o', 'f'}
+	ftyp := []byte{0, 0, 0, 8, 'f', 't', 'y', 'p'}
+
+	// a session with a separate initialization file, followed by a segment with an embedded one
+	for _, name := range []string{
+		"2015-05-19_22-15-25-000000.mp4",
+		"2015-05-19_22-15-26-000000.mp4",
+		"2015-05-19_22-15-27-000000.mp4",
+	} {
+		err = os.WriteFile(filepath.Join(dir, "mypath", name), moof, 0o644)
+		require.NoError(t, err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "mypath", "2015-05-19_22-15-28-000000.mp4"), ftyp, 0o644)
+	require.NoError(t, err)
+
+	err = os.WriteFile(InitPath(filepath.Join(dir, "mypath", "2015-05-19_22-15-25-000000.mp4")), ftyp, 0o644)
+	require.NoError(t, err)
+
+	pathConf := &conf.Path{
+		Name:         "mypath",
+		RecordPath:   filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+		RecordFormat: conf.RecordFormatFMP4,
+	}
+
+	// initialization files are not decoded as segments
+	segments, err := FindSegments(pathConf, "mypath")
+	require.NoError(t, err)
+	require.Equal(t, 4, len(segments))
+
+	owner, err := FindInit(segments, segments[2])
+	require.NoError(t, err)
+	require.Equal(t, segments[0], owner)
+
+	// the initialization file is handed over to the following segment
+	RemoveSegment(segments, 0)
+
+	segments, err = FindSegments(pathConf, "mypath")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(segments))
+	require.Equal(t, time.Date(2015, 5, 19, 22, 15, 26, 0, time.Local), segments[0].Start)
+
+	owner, err = FindInit(segments, segments[1])
+	require.NoError(t, err)
+	require.Equal(t, segments[0], owner)
+
+	RemoveSegment(segments, 0)
+	RemoveSegment(segments, 1)
+
+	// the initialization file is removed when the following segment has an embedded one
+	segments, err = FindSegments(pathConf, "mypath")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(segments))
+
+	_, err = FindInit(segments, segments[0])
+	require.Error(t, err)
+
+	entries, err := os.ReadDir(filepath.Join(dir, "mypath"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/bluenviron/mediamtx/internal/conf"
 )
 
+var reQueryKey = regexp.MustCompile(`%q_[A-Za-z0-9_]+`)
+
 func leadingZeros(v int, size int) string {
 	out := strconv.FormatInt(int64(v), 10)
 	if len(out) >= size {
@@ -24,14 +26,34 @@ func leadingZeros(v int, size int) string {
 }
 
 // PathAddExtension adds the file extension to the path.
-func PathAddExtension(path string, format conf.RecordFormat) string {
+func PathAddExtension(path string, format conf.RecordFormat, compression conf.RecordCompression) string {
 	switch format {
 	case conf.RecordFormatMPEGTS:
-		return path + ".ts"
+		path += ".ts"
 
 	default:
-		return path + ".mp4"
+		path += ".mp4"
+	}
+
+	switch compression {
+	case conf.RecordCompressionGzip:
+		path += ".gz"
+
+	case conf.RecordCompressionZstd:
+		path += ".zst"
 	}
+
+	return path
+}
+
+// CaptionsPath returns the path of the WebVTT file that contains captions of a segment.
+func CaptionsPath(segmentPath string) string {
+	return segmentPath + ".vtt"
+}
+
+// ManifestPath returns the path of the JSON file that describes tracks of a segment.
+func ManifestPath(segmentPath string) string {
+	return segmentPath + ".json"
 }
 
 // CommonPath returns the common path between all segments with given recording path.
@@ -66,6 +88,10 @@ func CommonPath(v string) string {
 type Path struct {
 	Start time.Time
 	Path  string
+
+	// time zone of time variables.
+	// When nil, Start is encoded in its own time zone and decoded in the local one.
+	Location *time.Location
 }
 
 // Decode decodes a Path.
@@ -92,6 +118,10 @@ func (p *Path) Decode(format string, v string) bool {
 	}
 
 	re = strings.ReplaceAll(re, "%path", "(.*?)")
+	re = strings.ReplaceAll(re, "%user", "(.*?)")
+	re = strings.ReplaceAll(re, "%connid", "(.*?)")
+	re = strings.ReplaceAll(re, "%query", "(.*?)")
+	re = reQueryKey.ReplaceAllString(re, "(.*?)")
 	re = strings.ReplaceAll(re, "%Y", "([0-9]{4})")
 	re = strings.ReplaceAll(re, "%m", "([0-9]{2})")
 	re = strings.ReplaceAll(re, "%d", "([0-9]{2})")
@@ -100,7 +130,8 @@ func (p *Path) Decode(format string, v string) bool {
 	re = strings.ReplaceAll(re, "%S", "([0-9]{2})")
 	re = strings.ReplaceAll(re, "%f", "([0-9]{6})")
 	re = strings.ReplaceAll(re, "%s", "([0-9]{10})")
-	r := regexp.MustCompile(re)
+	// sidecar files (captions, markers) must not be decoded as segments
+	r := regexp.MustCompile(re + "$")
 
 	var groupMapping []string
 	cur := format
@@ -114,6 +145,10 @@ func (p *Path) Decode(format string, v string) bool {
 
 		for _, va := range []string{
 			"%path",
+			"%user",
+			"%connid",
+			"%query",
+			"%q_",
 			"%Y",
 			"%m",
 			"%d",
@@ -192,7 +227,11 @@ func (p *Path) Decode(format string, v string) bool {
 	if unixSec > 0 {
 		p.Start = time.Unix(unixSec, 0)
 	} else {
-		p.Start = time.Date(year, month, day, hour, minute, second, micros*1000, time.Local)
+		loc := p.Location
+		if loc == nil {
+			loc = time.Local
+		}
+		p.Start = time.Date(year, month, day, hour, minute, second, micros*1000, loc)
 	}
 
 	return true
@@ -200,14 +239,19 @@ func (p *Path) Decode(format string, v string) bool {
 
 // Encode encodes a path.
 func (p Path) Encode(format string) string {
+	start := p.Start
+	if p.Location != nil {
+		start = start.In(p.Location)
+	}
+
 	format = strings.ReplaceAll(format, "%path", p.Path)
-	format = strings.ReplaceAll(format, "%Y", strconv.FormatInt(int64(p.Start.Year()), 10))
-	format = strings.ReplaceAll(format, "%m", leadingZeros(int(p.Start.Month()), 2))
-	format = strings.ReplaceAll(format, "%d", leadingZeros(p.Start.Day(), 2))
-	format = strings.ReplaceAll(format, "%H", leadingZeros(p.Start.Hour(), 2))
-	format = strings.ReplaceAll(format, "%M", leadingZeros(p.Start.Minute(), 2))
-	format = strings.ReplaceAll(format, "%S", leadingZeros(p.Start.Second(), 2))
-	format = strings.ReplaceAll(format, "%f", leadingZeros(p.Start.Nanosecond()/1000, 6))
-	format = strings.ReplaceAll(format, "%s", strconv.FormatInt(p.Start.Unix(), 10))
+	format = strings.ReplaceAll(format, "%Y", strconv.FormatInt(int64(start.Year()), 10))
+	format = strings.ReplaceAll(format, "%m", leadingZeros(int(start.Month()), 2))
+	format = strings.ReplaceAll(format, "%d", leadingZeros(start.Day(), 2))
+	format = strings.ReplaceAll(format, "%H", leadingZeros(start.Hour(), 2))
+	format = strings.ReplaceAll(format, "%M", leadingZeros(start.Minute(), 2))
+	format = strings.ReplaceAll(format, "%S", leadingZeros(start.Second(), 2))
+	format = strings.ReplaceAll(format, "%f", leadingZeros(start.Nanosecond()/1000, 6))
+	format = strings.ReplaceAll(format, "%s", strconv.FormatInt(start.Unix(), 10))
 	return format
 }
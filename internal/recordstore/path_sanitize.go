@@ -0,0 +1,101 @@
+package recordstore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// sanitizeEscape is the character that starts escape sequences of sanitized names.
+// It is not allowed in path names, therefore sanitized path names can be reverted.
+const sanitizeEscape = '+'
+
+// names of devices that can't be used as file names on Windows.
+var windowsReservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {}, "COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {}, "LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+func isUnsafePathChar(b byte) bool {
+	switch b {
+	case '\\', ':', '*', '?', '"', '<', '>', '|', '%', ' ', sanitizeEscape:
+		return true
+	}
+	return b < 0x20 || b == 0x7F
+}
+
+func writeEscaped(buf *strings.Builder, b byte) {
+	fmt.Fprintf(buf, "%c%02X", sanitizeEscape, b)
+}
+
+// SanitizePathName makes a name safe to be used inside file paths on Windows and NFS shares.
+// The following characters are replaced with '+' followed by their hexadecimal code
+// (i.e. ':' becomes '+3A'):
+//   - \ : * ? " < > | % + spaces and control characters;
+//   - dots at the end of a directory or file name, that are stripped by Windows;
+//   - the first character of Windows device names (CON, PRN, AUX, NUL, COM1-9, LPT1-9).
+//
+// Slashes are preserved, since they separate directories.
+// The operation can be reverted with UnsanitizePathName.
+func SanitizePathName(name string) string {
+	var buf strings.Builder
+
+	for _, part := range strings.SplitAfter(name, "/") {
+		elem := strings.TrimSuffix(part, "/")
+
+		if _, ok := windowsReservedNames[strings.ToUpper(elem)]; ok {
+			writeEscaped(&buf, elem[0])
+			elem = elem[1:]
+		}
+
+		for i := 0; i < len(elem); i++ {
+			b := elem[i]
+			if isUnsafePathChar(b) || (b == '.' && i == len(elem)-1) {
+				writeEscaped(&buf, b)
+			} else {
+				buf.WriteByte(b)
+			}
+		}
+
+		if strings.HasSuffix(part, "/") {
+			buf.WriteByte('/')
+		}
+	}
+
+	return buf.String()
+}
+
+// UnsanitizePathName reverts SanitizePathName.
+func UnsanitizePathName(name string) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(name); i++ {
+		if name[i] == sanitizeEscape && (i+2) < len(name) {
+			if v, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				buf.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		buf.WriteByte(name[i])
+	}
+
+	return buf.String()
+}
+
+// PathFormatOfPath returns the format of segment paths of a path,
+// in which %path is replaced with the path name and the extension is added.
+func PathFormatOfPath(pathConf *conf.Path, pathName string) string {
+	if pathConf.RecordPathSanitize {
+		pathName = SanitizePathName(pathName)
+	}
+
+	return PathAddExtension(
+		strings.ReplaceAll(pathConf.RecordPath, "%path", pathName),
+		pathConf.RecordFormat,
+		pathConf.RecordCompression,
+	)
+}
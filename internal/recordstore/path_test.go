@@ -51,3 +51,97 @@ func TestPathEncode(t *testing.T) {
 		})
 	}
 }
+
+func TestPathDecodeSourceVariables(t *testing.T) {
+	var dec Path
+	ok := dec.Decode("%path/%user/%Y-%m-%d_%H-%M-%S-%f_%connid.mp4",
+		"mypath/myuser/2008-11-07_11-22-04-123456_1e7ba4ec-9bf2-4a50-a0a4-3a1b9c0167f3.mp4")
+	require.Equal(t, true, ok)
+	require.Equal(t, Path{
+		Start: time.Date(2008, 11, 0o7, 11, 22, 4, 123456000, time.Local),
+		Path:  "mypath",
+	}, dec)
+}
+
+func TestPathDecodeQueryVariables(t *testing.T) {
+	var dec Path
+	ok := dec.Decode("%path/%q_cam/%Y-%m-%d_%H-%M-%S-%f_%query.mp4",
+		"mypath/roof/2008-11-07_11-22-04-123456_cam=roof&token=abc.mp4")
+	require.Equal(t, true, ok)
+	require.Equal(t, Path{
+		Start: time.Date(2008, 11, 0o7, 11, 22, 4, 123456000, time.Local),
+		Path:  "mypath",
+	}, dec)
+}
+
+func TestPathDecodeSidecarFiles(t *testing.T) {
+	for _, enc := range []string{
+		"mypath/2008-11-07_11-22-04-123456.mp4.vtt",
+		"mypath/2008-11-07_11-22-04-123456.mp4.writing",
+	} {
+		var dec Path
+		ok := dec.Decode("%path/%Y-%m-%d_%H-%M-%S-%f.mp4", enc)
+		require.Equal(t, false, ok)
+	}
+}
+
+func TestSanitizePathName(t *testing.T) {
+	for _, ca := range [][2]string{
+		{"live/cam1", "live/cam1"},
+		{"cam 1:front", "cam+201+3Afront"},
+		{`a\b*c?d"e<f>g|h%i+j`, "a+5Cb+2Ac+3Fd+22e+3Cf+3Eg+7Ch+25i+2Bj"},
+		{"cam./..", "cam+2E/.+2E"},
+		{"aux/COM1/console", "+61ux/+43OM1/console"},
+		{"tab\tx", "tab+09x"},
+	} {
+		t.Run(ca[0], func(t *testing.T) {
+			require.Equal(t, ca[1], SanitizePathName(ca[0]))
+			require.Equal(t, ca[0], UnsanitizePathName(ca[1]))
+		})
+	}
+}
+
+func TestPathTimeZone(t *testing.T) {
+	start := time.Date(2008, 11, 7, 23, 22, 4, 0, time.UTC)
+	format := "%path/%Y-%m-%d_%H-%M-%S.mp4"
+
+	for _, ca := range []struct {
+		name string
+		loc  *time.Location
+		enc  string
+	}{
+		{
+			"utc",
+			time.UTC,
+			"mypath/2008-11-07_23-22-04.mp4",
+		},
+		{
+			"america/new_york",
+			func() *time.Location {
+				loc, err := time.LoadLocation("America/New_York")
+				require.NoError(t, err)
+				return loc
+			}(),
+			"mypath/2008-11-07_18-22-04.mp4",
+		},
+		{
+			"asia/tokyo",
+			func() *time.Location {
+				loc, err := time.LoadLocation("Asia/Tokyo")
+				require.NoError(t, err)
+				return loc
+			}(),
+			"mypath/2008-11-08_08-22-04.mp4",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			enc := Path{Start: start, Path: "mypath", Location: ca.loc}.Encode(format)
+			require.Equal(t, ca.enc, enc)
+
+			dec := Path{Location: ca.loc}
+			ok := dec.Decode(format, enc)
+			require.Equal(t, true, ok)
+			require.True(t, start.Equal(dec.Start))
+		})
+	}
+}
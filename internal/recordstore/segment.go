@@ -5,7 +5,6 @@ import (
 	"io/fs"
 	"path/filepath"
 	"sort"
-	"strings"
 	"time"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -23,16 +22,14 @@ type Segment struct {
 }
 
 func fixedPathHasSegments(pathConf *conf.Path) bool {
-	recordPath := PathAddExtension(
-		strings.ReplaceAll(pathConf.RecordPath, "%path", pathConf.Name),
-		pathConf.RecordFormat,
-	)
+	recordPath := PathFormatOfPath(pathConf, pathConf.Name)
 
 	// we have to convert to absolute paths
 	// otherwise, recordPath and fpath inside Walk() won't have common elements
 	recordPath, _ = filepath.Abs(recordPath)
 
 	commonPath := CommonPath(recordPath)
+	loc := pathConf.RecordLocation()
 
 	err := filepath.Walk(commonPath, func(fpath string, info fs.FileInfo, err error) error {
 		if err != nil {
@@ -40,7 +37,7 @@ func fixedPathHasSegments(pathConf *conf.Path) bool {
 		}
 
 		if !info.IsDir() {
-			var pa Path
+			pa := Path{Location: loc}
 			ok := pa.Decode(recordPath, fpath)
 			if ok {
 				return errFound
@@ -60,6 +57,7 @@ func regexpPathFindPathsWithSegments(pathConf *conf.Path) map[string]struct{} {
 	recordPath := PathAddExtension(
 		pathConf.RecordPath,
 		pathConf.RecordFormat,
+		pathConf.RecordCompression,
 	)
 
 	// we have to convert to absolute paths
@@ -67,6 +65,7 @@ func regexpPathFindPathsWithSegments(pathConf *conf.Path) map[string]struct{} {
 	recordPath, _ = filepath.Abs(recordPath)
 
 	commonPath := CommonPath(recordPath)
+	loc := pathConf.RecordLocation()
 
 	ret := make(map[string]struct{})
 
@@ -76,10 +75,16 @@ func regexpPathFindPathsWithSegments(pathConf *conf.Path) map[string]struct{} {
 		}
 
 		if !info.IsDir() {
-			var pa Path
+			pa := Path{Location: loc}
 			ok := pa.Decode(recordPath, fpath)
-			if ok && pathConf.Regexp.FindStringSubmatch(pa.Path) != nil {
-				ret[pa.Path] = struct{}{}
+			if ok {
+				if pathConf.RecordPathSanitize {
+					pa.Path = UnsanitizePathName(pa.Path)
+				}
+
+				if pathConf.Regexp.FindStringSubmatch(pa.Path) != nil {
+					ret[pa.Path] = struct{}{}
+				}
 			}
 		}
 
@@ -121,16 +126,14 @@ func FindSegments(
 	pathConf *conf.Path,
 	pathName string,
 ) ([]*Segment, error) {
-	recordPath := PathAddExtension(
-		strings.ReplaceAll(pathConf.RecordPath, "%path", pathName),
-		pathConf.RecordFormat,
-	)
+	recordPath := PathFormatOfPath(pathConf, pathName)
 
 	// we have to convert to absolute paths
 	// otherwise, recordPath and fpath inside Walk() won't have common elements
 	recordPath, _ = filepath.Abs(recordPath)
 
 	commonPath := CommonPath(recordPath)
+	loc := pathConf.RecordLocation()
 	var segments []*Segment
 
 	err := filepath.Walk(commonPath, func(fpath string, info fs.FileInfo, err error) error {
@@ -139,7 +142,7 @@ func FindSegments(
 		}
 
 		if !info.IsDir() {
-			var pa Path
+			pa := Path{Location: loc}
 			ok := pa.Decode(recordPath, fpath)
 			if ok {
 				segments = append(segments, &Segment{
@@ -173,16 +176,14 @@ func FindSegmentsInTimespan(
 	start time.Time,
 	duration time.Duration,
 ) ([]*Segment, error) {
-	recordPath := PathAddExtension(
-		strings.ReplaceAll(pathConf.RecordPath, "%path", pathName),
-		pathConf.RecordFormat,
-	)
+	recordPath := PathFormatOfPath(pathConf, pathName)
 
 	// we have to convert to absolute paths
 	// otherwise, recordPath and fpath inside Walk() won't have common elements
 	recordPath, _ = filepath.Abs(recordPath)
 
 	commonPath := CommonPath(recordPath)
+	loc := pathConf.RecordLocation()
 	end := start.Add(duration)
 	var segments []*Segment
 
@@ -192,7 +193,7 @@ func FindSegmentsInTimespan(
 		}
 
 		if !info.IsDir() {
-			var pa Path
+			pa := Path{Location: loc}
 			ok := pa.Decode(recordPath, fpath)
 
 			// gather all segments that starts before the end of the playback
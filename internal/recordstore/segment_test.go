@@ -44,6 +44,29 @@ func TestFindAllPathsWithSegments(t *testing.T) {
 	require.Equal(t, []string{"path1", "path2"}, paths)
 }
 
+func TestFindAllPathsWithSegmentsSanitize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-recordstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.MkdirAll(filepath.Join(dir, "live", "cam+2E"), 0o755)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "live", "cam+2E", "2015-05-19_22-15-25-000427.mp4"), []byte{1}, 0o644)
+	require.NoError(t, err)
+
+	paths := FindAllPathsWithSegments(map[string]*conf.Path{
+		"~^live/.*$": {
+			Name:               "~^live/.*$",
+			Regexp:             regexp.MustCompile("^live/.*$"),
+			RecordPath:         filepath.Join(dir, "%path/%Y-%m-%d_%H-%M-%S-%f"),
+			RecordPathSanitize: true,
+			RecordFormat:       conf.RecordFormatFMP4,
+		},
+	})
+	require.Equal(t, []string{"live/cam."}, paths)
+}
+
 func TestFindSegments(t *testing.T) {
 	dir, err := os.MkdirTemp("", "mediamtx-recordstore")
 	require.NoError(t, err)
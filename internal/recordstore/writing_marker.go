@@ -0,0 +1,50 @@
+package recordstore
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// WritingMarkerRefreshPeriod is the period at which writers refresh markers.
+	WritingMarkerRefreshPeriod = 30 * time.Second
+
+	// WritingMarkerStaleTimeout is the time after which a marker that has not been refreshed
+	// is considered left behind by a process that crashed.
+	WritingMarkerStaleTimeout = 5 * time.Minute
+)
+
+// WritingMarkerPath returns the path of the marker that signals that a segment is being written.
+func WritingMarkerPath(segmentPath string) string {
+	return segmentPath + ".writing"
+}
+
+// CreateWritingMarker creates a marker that signals that a segment is being written,
+// in order to prevent other processes from deleting it.
+// The marker contains the ID of the writing process.
+func CreateWritingMarker(segmentPath string) error {
+	return os.WriteFile(WritingMarkerPath(segmentPath), []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644)
+}
+
+// RefreshWritingMarker refreshes the modification time of a marker,
+// in order to prevent it from being considered stale.
+func RefreshWritingMarker(segmentPath string, t time.Time) error {
+	return os.Chtimes(WritingMarkerPath(segmentPath), t, t)
+}
+
+// RemoveWritingMarker removes a marker.
+func RemoveWritingMarker(segmentPath string) {
+	os.Remove(WritingMarkerPath(segmentPath))
+}
+
+// SegmentInUse checks whether a segment is being written by this or another process.
+// Markers that have not been refreshed within WritingMarkerStaleTimeout are ignored.
+func SegmentInUse(segmentPath string, now time.Time) bool {
+	fi, err := os.Stat(WritingMarkerPath(segmentPath))
+	if err != nil {
+		return false
+	}
+
+	return now.Sub(fi.ModTime()) < WritingMarkerStaleTimeout
+}
@@ -36,15 +36,18 @@ func mergePathAndQuery(path string, rawQuery string) string {
 }
 
 type httpServer struct {
-	address        string
-	encryption     bool
-	serverKey      string
-	serverCert     string
-	allowOrigin    string
-	trustedProxies conf.IPNetworks
-	readTimeout    conf.StringDuration
-	pathManager    serverPathManager
-	parent         *Server
+	address                          string
+	encryption                       bool
+	serverKey                        string
+	serverCert                       string
+	allowOrigin                      string
+	trustedProxies                   conf.IPNetworks
+	readTimeout                      conf.StringDuration
+	multivariantPlaylistCacheControl string
+	mediaPlaylistCacheControl        string
+	segmentCacheControl              string
+	pathManager                      serverPathManager
+	parent                           *Server
 
 	inner *httpp.Server
 }
@@ -204,6 +207,22 @@ func (s *httpServer) onRequest(ctx *gin.Context) {
 		}
 
 		ctx.Request.URL.Path = fname
-		mi.handleRequest(ctx)
+		mi.handleRequest(ctx, s.cacheControl(fname))
+	}
+}
+
+// cacheControl returns the Cache-Control header of a muxer file.
+func (s *httpServer) cacheControl(fname string) string {
+	switch {
+	case fname == "index.m3u8":
+		return s.multivariantPlaylistCacheControl
+
+	case strings.HasSuffix(fname, ".m3u8"):
+		return s.mediaPlaylistCacheControl
+
+	// initialization segments, segments and parts never change once published,
+	// since their names contain a prefix that changes with every muxer instance.
+	default:
+		return s.segmentCacheControl
 	}
 }
@@ -32,7 +32,15 @@ func emptyTimer() *time.Timer {
 
 type responseWriterWithCounter struct {
 	http.ResponseWriter
-	bytesSent *uint64
+	bytesSent    *uint64
+	cacheControl string
+}
+
+func (w *responseWriterWithCounter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusOK && w.cacheControl != "" {
+		w.ResponseWriter.Header().Set("Cache-Control", w.cacheControl)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
 }
 
 func (w *responseWriterWithCounter) Write(p []byte) (int, error) {
@@ -46,20 +54,21 @@ type muxerGetInstanceReq struct {
 }
 
 type muxer struct {
-	parentCtx       context.Context
-	remoteAddr      string
-	variant         conf.HLSVariant
-	segmentCount    int
-	segmentDuration conf.StringDuration
-	partDuration    conf.StringDuration
-	segmentMaxSize  conf.StringSize
-	directory       string
-	closeAfter      conf.StringDuration
-	wg              *sync.WaitGroup
-	pathName        string
-	pathManager     serverPathManager
-	parent          *Server
-	query           string
+	parentCtx               context.Context
+	remoteAddr              string
+	variant                 conf.HLSVariant
+	segmentCount            int
+	segmentDuration         conf.StringDuration
+	partDuration            conf.StringDuration
+	segmentMaxSize          conf.StringSize
+	directory               string
+	segmentsToDiskDirectory string
+	closeAfter              conf.StringDuration
+	wg                      *sync.WaitGroup
+	pathName                string
+	pathManager             serverPathManager
+	parent                  *Server
+	query                   string
 
 	ctx             context.Context
 	ctxCancel       func()
@@ -140,16 +149,17 @@ func (m *muxer) runInner() error {
 	var recreateTimer *time.Timer
 
 	mi := &muxerInstance{
-		variant:         m.variant,
-		segmentCount:    m.segmentCount,
-		segmentDuration: m.segmentDuration,
-		partDuration:    m.partDuration,
-		segmentMaxSize:  m.segmentMaxSize,
-		directory:       m.directory,
-		pathName:        m.pathName,
-		stream:          stream,
-		bytesSent:       m.bytesSent,
-		parent:          m,
+		variant:                 m.variant,
+		segmentCount:            m.segmentCount,
+		segmentDuration:         m.segmentDuration,
+		partDuration:            m.partDuration,
+		segmentMaxSize:          m.segmentMaxSize,
+		directory:               m.directory,
+		segmentsToDiskDirectory: m.segmentsToDiskDirectory,
+		pathName:                m.pathName,
+		stream:                  stream,
+		bytesSent:               m.bytesSent,
+		parent:                  m,
 	}
 	err = mi.initialize()
 	if err != nil {
@@ -197,16 +207,17 @@ func (m *muxer) runInner() error {
 
 		case <-recreateTimer.C:
 			mi = &muxerInstance{
-				variant:         m.variant,
-				segmentCount:    m.segmentCount,
-				segmentDuration: m.segmentDuration,
-				partDuration:    m.partDuration,
-				segmentMaxSize:  m.segmentMaxSize,
-				directory:       m.directory,
-				pathName:        m.pathName,
-				stream:          stream,
-				bytesSent:       m.bytesSent,
-				parent:          m,
+				variant:                 m.variant,
+				segmentCount:            m.segmentCount,
+				segmentDuration:         m.segmentDuration,
+				partDuration:            m.partDuration,
+				segmentMaxSize:          m.segmentMaxSize,
+				directory:               m.directory,
+				segmentsToDiskDirectory: m.segmentsToDiskDirectory,
+				pathName:                m.pathName,
+				stream:                  stream,
+				bytesSent:               m.bytesSent,
+				parent:                  m,
 			}
 			err := mi.initialize()
 			if err != nil {
@@ -0,0 +1,279 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bluenviron/gohlslib/v2/pkg/playlist"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+// pathDiskDirectory returns the directory in which segments of a path are written.
+// Path names can contain dots, therefore names with '.' or '..' segments are rejected,
+// in order to prevent them from writing and removing files outside of the base directory.
+func pathDiskDirectory(base string, pathName string) (string, error) {
+	for _, seg := range strings.Split(pathName, "/") {
+		if seg == "." || seg == ".." {
+			return "", fmt.Errorf("path name '%s' can't be used to write segments to disk", pathName)
+		}
+	}
+
+	return filepath.Join(base, pathName), nil
+}
+
+type diskResponseWriter struct {
+	header     http.Header
+	statusCode int
+	buf        bytes.Buffer
+}
+
+func (w *diskResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *diskResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+func (w *diskResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.buf.Write(p)
+}
+
+// muxerDiskWriter writes playlists and segments of a muxer into a directory,
+// in order to allow an external web server or CDN to serve them.
+// Files are written into temporary files and then renamed, in order not to expose
+// partially-written files. Low-Latency HLS extensions are removed from playlists,
+// since they require blocking requests that can't be served by static file servers.
+type muxerDiskWriter struct {
+	directory string
+	period    time.Duration
+	handle    func(http.ResponseWriter, *http.Request)
+	parent    logger.Writer
+
+	ctx       context.Context
+	ctxCancel func()
+	files     map[string]struct{}
+	done      chan struct{}
+}
+
+func (d *muxerDiskWriter) initialize() error {
+	err := os.MkdirAll(d.directory, 0o755)
+	if err != nil {
+		return err
+	}
+
+	d.ctx, d.ctxCancel = context.WithCancel(context.Background())
+	d.files = make(map[string]struct{})
+	d.done = make(chan struct{})
+
+	go d.run()
+
+	return nil
+}
+
+// cancel stops the writer. It must be called before closing the muxer,
+// in order not to report errors caused by the closure.
+func (d *muxerDiskWriter) cancel() {
+	d.ctxCancel()
+}
+
+// close waits for the writer to stop and removes written files.
+func (d *muxerDiskWriter) close() {
+	d.ctxCancel()
+	<-d.done
+
+	for name := range d.files {
+		os.Remove(filepath.Join(d.directory, name))
+	}
+	os.Remove(d.directory)
+}
+
+func (d *muxerDiskWriter) run() {
+	defer close(d.done)
+
+	t := time.NewTicker(d.period)
+	defer t.Stop()
+
+	lastErr := ""
+
+	for {
+		err := d.write()
+		if err != nil {
+			if d.ctx.Err() != nil {
+				return
+			}
+
+			// do not flood logs with the same error
+			if err.Error() != lastErr {
+				d.parent.Log(logger.Warn, "unable to write to disk: %v", err)
+				lastErr = err.Error()
+			}
+		} else {
+			lastErr = ""
+		}
+
+		select {
+		case <-t.C:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *muxerDiskWriter) fetch(name string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, "/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &diskResponseWriter{header: make(http.Header)}
+	d.handle(w, req)
+
+	if w.statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get %s: status code %d", name, w.statusCode)
+	}
+
+	return w.buf.Bytes(), nil
+}
+
+func (d *muxerDiskWriter) writeFile(name string, byts []byte) error {
+	fpath := filepath.Join(d.directory, name)
+	tmpPath := fpath + ".tmp"
+
+	err := os.WriteFile(tmpPath, byts, 0o644)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, fpath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	d.files[name] = struct{}{}
+	return nil
+}
+
+func (d *muxerDiskWriter) write() error {
+	buf, err := d.fetch("index.m3u8")
+	if err != nil {
+		return err
+	}
+
+	var mpl playlist.Multivariant
+	err = mpl.Unmarshal(buf)
+	if err != nil {
+		return err
+	}
+
+	used := map[string]struct{}{
+		"index.m3u8": {},
+	}
+
+	var uris []string
+	for _, v := range mpl.Variants {
+		uris = append(uris, v.URI)
+	}
+	for _, r := range mpl.Renditions {
+		if r.URI != nil {
+			uris = append(uris, *r.URI)
+		}
+	}
+
+	// media playlists are written before the multivariant playlist,
+	// in order to make them available as soon as they are referenced.
+	for _, uri := range uris {
+		err = d.writeMediaPlaylist(uri, used)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.writeFile("index.m3u8", buf)
+	if err != nil {
+		return err
+	}
+
+	// remove segments that have been removed from playlists
+	for name := range d.files {
+		if _, ok := used[name]; !ok {
+			os.Remove(filepath.Join(d.directory, name))
+			delete(d.files, name)
+		}
+	}
+
+	return nil
+}
+
+func (d *muxerDiskWriter) writeMediaPlaylist(uri string, used map[string]struct{}) error {
+	buf, err := d.fetch(uri)
+	if err != nil {
+		return err
+	}
+
+	var pl playlist.Media
+	err = pl.Unmarshal(buf)
+	if err != nil {
+		return err
+	}
+
+	pl.ServerControl = nil
+	pl.PartInf = nil
+	pl.Skip = nil
+	pl.Parts = nil
+	pl.PreloadHint = nil
+
+	var names []string
+
+	if pl.Map != nil {
+		names = append(names, pl.Map.URI)
+	}
+
+	for _, seg := range pl.Segments {
+		seg.Parts = nil
+		if !seg.Gap {
+			names = append(names, seg.URI)
+		}
+	}
+
+	// segments and initialization files are written once,
+	// since their content doesn't change.
+	for _, name := range names {
+		used[name] = struct{}{}
+
+		if _, ok := d.files[name]; ok {
+			continue
+		}
+
+		var byts []byte
+		byts, err = d.fetch(name)
+		if err != nil {
+			return err
+		}
+
+		err = d.writeFile(name, byts)
+		if err != nil {
+			return err
+		}
+	}
+
+	buf, err = pl.Marshal()
+	if err != nil {
+		return err
+	}
+
+	used[uri] = struct{}{}
+
+	return d.writeFile(uri, buf)
+}
@@ -15,18 +15,20 @@ import (
 )
 
 type muxerInstance struct {
-	variant         conf.HLSVariant
-	segmentCount    int
-	segmentDuration conf.StringDuration
-	partDuration    conf.StringDuration
-	segmentMaxSize  conf.StringSize
-	directory       string
-	pathName        string
-	stream          *stream.Stream
-	bytesSent       *uint64
-	parent          logger.Writer
-
-	hmuxer *gohlslib.Muxer
+	variant                 conf.HLSVariant
+	segmentCount            int
+	segmentDuration         conf.StringDuration
+	partDuration            conf.StringDuration
+	segmentMaxSize          conf.StringSize
+	directory               string
+	segmentsToDiskDirectory string
+	pathName                string
+	stream                  *stream.Stream
+	bytesSent               *uint64
+	parent                  logger.Writer
+
+	hmuxer     *gohlslib.Muxer
+	diskWriter *muxerDiskWriter
 }
 
 func (mi *muxerInstance) initialize() error {
@@ -59,6 +61,29 @@ func (mi *muxerInstance) initialize() error {
 		return err
 	}
 
+	if mi.segmentsToDiskDirectory != "" {
+		var directory string
+		directory, err = pathDiskDirectory(mi.segmentsToDiskDirectory, mi.pathName)
+		if err != nil {
+			mi.stream.RemoveReader(mi)
+			mi.hmuxer.Close()
+			return err
+		}
+
+		mi.diskWriter = &muxerDiskWriter{
+			directory: directory,
+			period:    time.Duration(mi.partDuration),
+			handle:    mi.hmuxer.Handle,
+			parent:    mi,
+		}
+		err = mi.diskWriter.initialize()
+		if err != nil {
+			mi.stream.RemoveReader(mi)
+			mi.hmuxer.Close()
+			return err
+		}
+	}
+
 	mi.Log(logger.Info, "is converting into HLS, %s",
 		defs.FormatsInfo(mi.stream.ReaderFormats(mi)))
 
@@ -74,7 +99,13 @@ func (mi *muxerInstance) Log(level logger.Level, format string, args ...interfac
 
 func (mi *muxerInstance) close() {
 	mi.stream.RemoveReader(mi)
+	if mi.diskWriter != nil {
+		mi.diskWriter.cancel()
+	}
 	mi.hmuxer.Close()
+	if mi.diskWriter != nil {
+		mi.diskWriter.close()
+	}
 	if mi.hmuxer.Directory != "" {
 		os.Remove(mi.hmuxer.Directory)
 	}
@@ -84,10 +115,11 @@ func (mi *muxerInstance) errorChan() chan error {
 	return mi.stream.ReaderError(mi)
 }
 
-func (mi *muxerInstance) handleRequest(ctx *gin.Context) {
+func (mi *muxerInstance) handleRequest(ctx *gin.Context, cacheControl string) {
 	w := &responseWriterWithCounter{
 		ResponseWriter: ctx.Writer,
 		bytesSent:      mi.bytesSent,
+		cacheControl:   cacheControl,
 	}
 
 	mi.hmuxer.Handle(w, ctx.Request)
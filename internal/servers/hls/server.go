@@ -60,23 +60,28 @@ type serverParent interface {
 
 // Server is a HLS server.
 type Server struct {
-	Address         string
-	Encryption      bool
-	ServerKey       string
-	ServerCert      string
-	AllowOrigin     string
-	TrustedProxies  conf.IPNetworks
-	AlwaysRemux     bool
-	Variant         conf.HLSVariant
-	SegmentCount    int
-	SegmentDuration conf.StringDuration
-	PartDuration    conf.StringDuration
-	SegmentMaxSize  conf.StringSize
-	Directory       string
-	ReadTimeout     conf.StringDuration
-	MuxerCloseAfter conf.StringDuration
-	PathManager     serverPathManager
-	Parent          serverParent
+	Address                          string
+	Encryption                       bool
+	ServerKey                        string
+	ServerCert                       string
+	AllowOrigin                      string
+	TrustedProxies                   conf.IPNetworks
+	AlwaysRemux                      bool
+	Variant                          conf.HLSVariant
+	SegmentCount                     int
+	SegmentDuration                  conf.StringDuration
+	PartDuration                     conf.StringDuration
+	SegmentMaxSize                   conf.StringSize
+	Directory                        string
+	SegmentsToDisk                   bool
+	SegmentsToDiskDirectory          string
+	ReadTimeout                      conf.StringDuration
+	MuxerCloseAfter                  conf.StringDuration
+	MultivariantPlaylistCacheControl string
+	MediaPlaylistCacheControl        string
+	SegmentCacheControl              string
+	PathManager                      serverPathManager
+	Parent                           serverParent
 
 	ctx        context.Context
 	ctxCancel  func()
@@ -108,15 +113,18 @@ func (s *Server) Initialize() error {
 	s.chAPIMuxerGet = make(chan serverAPIMuxersGetReq)
 
 	s.httpServer = &httpServer{
-		address:        s.Address,
-		encryption:     s.Encryption,
-		serverKey:      s.ServerKey,
-		serverCert:     s.ServerCert,
-		allowOrigin:    s.AllowOrigin,
-		trustedProxies: s.TrustedProxies,
-		readTimeout:    s.ReadTimeout,
-		pathManager:    s.PathManager,
-		parent:         s,
+		address:                          s.Address,
+		encryption:                       s.Encryption,
+		serverKey:                        s.ServerKey,
+		serverCert:                       s.ServerCert,
+		allowOrigin:                      s.AllowOrigin,
+		trustedProxies:                   s.TrustedProxies,
+		readTimeout:                      s.ReadTimeout,
+		multivariantPlaylistCacheControl: s.MultivariantPlaylistCacheControl,
+		mediaPlaylistCacheControl:        s.MediaPlaylistCacheControl,
+		segmentCacheControl:              s.SegmentCacheControl,
+		pathManager:                      s.PathManager,
+		parent:                           s,
 	}
 	err := s.httpServer.initialize()
 	if err != nil {
@@ -226,12 +234,18 @@ func (s *Server) createMuxer(pathName string, remoteAddr string, query string) *
 		partDuration:    s.PartDuration,
 		segmentMaxSize:  s.SegmentMaxSize,
 		directory:       s.Directory,
-		wg:              &s.wg,
-		pathName:        pathName,
-		pathManager:     s.PathManager,
-		parent:          s,
-		query:           query,
-		closeAfter:      s.MuxerCloseAfter,
+		segmentsToDiskDirectory: func() string {
+			if s.SegmentsToDisk {
+				return s.SegmentsToDiskDirectory
+			}
+			return ""
+		}(),
+		wg:          &s.wg,
+		pathName:    pathName,
+		pathManager: s.PathManager,
+		parent:      s,
+		query:       query,
+		closeAfter:  s.MuxerCloseAfter,
 	}
 	r.initialize()
 	s.muxers[pathName] = r
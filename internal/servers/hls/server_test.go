@@ -11,10 +11,12 @@ import (
 
 	"github.com/bluenviron/gohlslib/v2"
 	"github.com/bluenviron/gohlslib/v2/pkg/codecs"
+	"github.com/bluenviron/gohlslib/v2/pkg/playlist"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/stream"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/bluenviron/mediamtx/internal/unit"
@@ -48,6 +50,12 @@ func (pa *dummyPath) RemovePublisher(_ defs.PathRemovePublisherReq) {
 func (pa *dummyPath) RemoveReader(_ defs.PathRemoveReaderReq) {
 }
 
+func (pa *dummyPath) AddEvent(_ logger.Level, _ string, _ ...interface{}) {
+}
+
+func (pa *dummyPath) AddDecodeError(_ string) {
+}
+
 type dummyPathManager struct {
 	findPathConf func(req defs.PathFindPathConfReq) (*conf.Path, error)
 	addReader    func(req defs.PathAddReaderReq) (defs.Path, *stream.Stream, error)
@@ -172,6 +180,9 @@ func TestServerRead(t *testing.T) {
 			1460,
 			desc,
 			true,
+			0,
+			0,
+			0,
 			test.NilLogger,
 		)
 		require.NoError(t, err)
@@ -267,6 +278,9 @@ func TestServerRead(t *testing.T) {
 			1460,
 			desc,
 			true,
+			0,
+			0,
+			0,
 			test.NilLogger,
 		)
 		require.NoError(t, err)
@@ -355,6 +369,114 @@ func TestServerRead(t *testing.T) {
 	})
 }
 
+func TestServerCacheControl(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{test.MediaH264}}
+
+	str, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	pm := &dummyPathManager{
+		findPathConf: func(_ defs.PathFindPathConfReq) (*conf.Path, error) {
+			return &conf.Path{}, nil
+		},
+		addReader: func(_ defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+			return &dummyPath{}, str, nil
+		},
+	}
+
+	s := &Server{
+		Address:                          "127.0.0.1:8888",
+		Encryption:                       false,
+		ServerKey:                        "",
+		ServerCert:                       "",
+		AlwaysRemux:                      true,
+		Variant:                          conf.HLSVariant(gohlslib.MuxerVariantLowLatency),
+		SegmentCount:                     7,
+		SegmentDuration:                  conf.StringDuration(1 * time.Second),
+		PartDuration:                     conf.StringDuration(200 * time.Millisecond),
+		SegmentMaxSize:                   50 * 1024 * 1024,
+		AllowOrigin:                      "",
+		TrustedProxies:                   conf.IPNetworks{},
+		Directory:                        "",
+		ReadTimeout:                      conf.StringDuration(10 * time.Second),
+		MultivariantPlaylistCacheControl: "max-age=10",
+		MediaPlaylistCacheControl:        "no-store",
+		SegmentCacheControl:              "public, max-age=86400, immutable",
+		PathManager:                      pm,
+		Parent:                           test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.PathReady(&dummyPath{})
+
+	str.WaitRunningReader()
+
+	for i := 0; i < 4; i++ {
+		str.WriteUnit(test.MediaH264, test.FormatH264, &unit.H264{
+			Base: unit.Base{
+				NTP: time.Time{},
+				PTS: int64(i) * 90000,
+			},
+			AU: [][]byte{
+				{5, 1}, // IDR
+			},
+		})
+	}
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	get := func(fname string) ([]byte, string) {
+		res, err2 := hc.Get("http://127.0.0.1:8888/mystream/" + fname)
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		byts, err2 := io.ReadAll(res.Body)
+		require.NoError(t, err2)
+
+		return byts, res.Header.Get("Cache-Control")
+	}
+
+	byts, cacheControl := get("index.m3u8")
+	require.Equal(t, "max-age=10", cacheControl)
+
+	pl, err := playlist.Unmarshal(byts)
+	require.NoError(t, err)
+	mediaPlaylistURI := pl.(*playlist.Multivariant).Variants[0].URI
+
+	byts, cacheControl = get(mediaPlaylistURI)
+	require.Equal(t, "no-store", cacheControl)
+
+	pl, err = playlist.Unmarshal(byts)
+	require.NoError(t, err)
+	mpl := pl.(*playlist.Media)
+
+	_, cacheControl = get(mpl.Map.URI)
+	require.Equal(t, "public, max-age=86400, immutable", cacheControl)
+
+	seg := mpl.Segments[len(mpl.Segments)-1]
+
+	_, cacheControl = get(seg.URI)
+	require.Equal(t, "public, max-age=86400, immutable", cacheControl)
+
+	_, cacheControl = get(seg.Parts[0].URI)
+	require.Equal(t, "public, max-age=86400, immutable", cacheControl)
+}
+
 func TestDirectory(t *testing.T) {
 	dir, err := os.MkdirTemp("", "mediamtx-playback")
 	require.NoError(t, err)
@@ -367,6 +489,9 @@ func TestDirectory(t *testing.T) {
 		1460,
 		desc,
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -406,3 +531,164 @@ func TestDirectory(t *testing.T) {
 	_, err = os.Stat(filepath.Join(dir, "mydir", "mystream"))
 	require.NoError(t, err)
 }
+
+func TestSegmentsToDisk(t *testing.T) {
+	for _, ca := range []string{
+		"mpegts",
+		"lowLatency",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "mediamtx-hls")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			desc := &description.Session{Medias: []*description.Media{test.MediaH264}}
+
+			str, err := stream.New(
+				512,
+				1460,
+				desc,
+				true,
+				0,
+				0,
+				0,
+				test.NilLogger,
+			)
+			require.NoError(t, err)
+
+			pm := &dummyPathManager{
+				addReader: func(_ defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+					return &dummyPath{}, str, nil
+				},
+			}
+
+			var variant gohlslib.MuxerVariant
+			if ca == "mpegts" {
+				variant = gohlslib.MuxerVariantMPEGTS
+			} else {
+				variant = gohlslib.MuxerVariantLowLatency
+			}
+
+			s := &Server{
+				Address:                 "127.0.0.1:8888",
+				AlwaysRemux:             true,
+				Variant:                 conf.HLSVariant(variant),
+				SegmentCount:            7,
+				SegmentDuration:         conf.StringDuration(1 * time.Second),
+				PartDuration:            conf.StringDuration(200 * time.Millisecond),
+				SegmentMaxSize:          50 * 1024 * 1024,
+				TrustedProxies:          conf.IPNetworks{},
+				SegmentsToDisk:          true,
+				SegmentsToDiskDirectory: dir,
+				ReadTimeout:             conf.StringDuration(10 * time.Second),
+				PathManager:             pm,
+				Parent:                  test.NilLogger,
+			}
+			err = s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			s.PathReady(&dummyPath{})
+
+			time.Sleep(100 * time.Millisecond)
+
+			for i := 0; i < 4; i++ {
+				str.WriteUnit(test.MediaH264, test.FormatH264, &unit.H264{
+					Base: unit.Base{
+						NTP: time.Time{},
+						PTS: int64(i) * 90000,
+					},
+					AU: [][]byte{
+						{5, 1}, // IDR
+					},
+				})
+			}
+
+			pathDir := filepath.Join(dir, "mystream")
+
+			var mpl playlist.Multivariant
+
+			require.Eventually(t, func() bool {
+				var buf []byte
+				buf, err = os.ReadFile(filepath.Join(pathDir, "index.m3u8"))
+				return err == nil && mpl.Unmarshal(buf) == nil
+			}, 5*time.Second, 50*time.Millisecond)
+
+			require.Len(t, mpl.Variants, 1)
+
+			buf, err := os.ReadFile(filepath.Join(pathDir, mpl.Variants[0].URI))
+			require.NoError(t, err)
+
+			var pl playlist.Media
+			err = pl.Unmarshal(buf)
+			require.NoError(t, err)
+
+			require.NotEmpty(t, pl.Segments)
+			require.Nil(t, pl.PreloadHint)
+			require.Empty(t, pl.Parts)
+
+			if pl.Map != nil {
+				_, err = os.Stat(filepath.Join(pathDir, pl.Map.URI))
+				require.NoError(t, err)
+			}
+
+			for _, seg := range pl.Segments {
+				if !seg.Gap {
+					_, err = os.Stat(filepath.Join(pathDir, seg.URI))
+					require.NoError(t, err)
+				}
+			}
+
+			s.PathNotReady(&dummyPath{})
+
+			require.Eventually(t, func() bool {
+				_, err = os.Stat(pathDir)
+				return os.IsNotExist(err)
+			}, 5*time.Second, 50*time.Millisecond)
+		})
+	}
+}
+
+func TestSegmentsToDiskPathName(t *testing.T) {
+	for _, ca := range []struct {
+		name     string
+		pathName string
+		dir      string
+	}{
+		{
+			"plain",
+			"mystream",
+			filepath.Join("base", "mystream"),
+		},
+		{
+			"subpath",
+			"cams/my.stream",
+			filepath.Join("base", "cams", "my.stream"),
+		},
+		{
+			"parent",
+			"a/../../x",
+			"",
+		},
+		{
+			"current",
+			"a/./b",
+			"",
+		},
+		{
+			"dots only",
+			"..",
+			"",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			dir, err := pathDiskDirectory("base", ca.pathName)
+			if ca.dir == "" {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, ca.dir, dir)
+			}
+		})
+	}
+}
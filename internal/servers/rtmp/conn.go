@@ -21,6 +21,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
 	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/waitgroup"
 )
 
 func pathNameAndQuery(inURL *url.URL) (string, url.Values, string) {
@@ -47,7 +48,7 @@ type conn struct {
 	runOnConnect        string
 	runOnConnectRestart bool
 	runOnDisconnect     string
-	wg                  *sync.WaitGroup
+	wg                  *waitgroup.WaitGroup
 	nconn               net.Conn
 	externalCmdPool     *externalcmd.Pool
 	pathManager         serverPathManager
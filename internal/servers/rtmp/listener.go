@@ -2,12 +2,13 @@ package rtmp
 
 import (
 	"net"
-	"sync"
+
+	"github.com/bluenviron/mediamtx/internal/waitgroup"
 )
 
 type listener struct {
 	ln     net.Listener
-	wg     *sync.WaitGroup
+	wg     *waitgroup.WaitGroup
 	parent *Server
 }
 
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"net"
 	"sort"
-	"sync"
 
 	"github.com/google/uuid"
 
@@ -19,6 +18,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/restrictnetwork"
 	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/waitgroup"
 )
 
 // ErrConnNotFound is returned when a connection is not found.
@@ -79,7 +79,7 @@ type Server struct {
 
 	ctx       context.Context
 	ctxCancel func()
-	wg        sync.WaitGroup
+	wg        waitgroup.WaitGroup
 	ln        net.Listener
 	conns     map[*conn]struct{}
 	loader    *certloader.CertLoader
@@ -320,3 +320,8 @@ func (s *Server) APIConnsKick(uuid uuid.UUID) error {
 		return fmt.Errorf("terminated")
 	}
 }
+
+// APIWaitGroupSize is called by api.
+func (s *Server) APIWaitGroupSize() int {
+	return s.wg.Size()
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/rtmp"
 	"github.com/bluenviron/mediamtx/internal/stream"
 	"github.com/bluenviron/mediamtx/internal/test"
@@ -44,6 +45,9 @@ func (p *dummyPath) StartPublisher(req defs.PathStartPublisherReq) (*stream.Stre
 		1460,
 		req.Desc,
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	if err != nil {
@@ -62,6 +66,12 @@ func (p *dummyPath) RemovePublisher(_ defs.PathRemovePublisherReq) {
 func (p *dummyPath) RemoveReader(_ defs.PathRemoveReaderReq) {
 }
 
+func (p *dummyPath) AddEvent(_ logger.Level, _ string, _ ...interface{}) {
+}
+
+func (p *dummyPath) AddDecodeError(_ string) {
+}
+
 type dummyPathManager struct {
 	path *dummyPath
 }
@@ -202,6 +212,9 @@ func TestServerRead(t *testing.T) {
 				1460,
 				desc,
 				true,
+				0,
+				0,
+				0,
 				test.NilLogger,
 			)
 			require.NoError(t, err)
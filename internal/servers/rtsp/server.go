@@ -106,6 +106,8 @@ func (s *Server) Initialize() error {
 		WriteTimeout:   time.Duration(s.WriteTimeout),
 		WriteQueueSize: s.WriteQueueSize,
 		RTSPAddress:    s.Address,
+		// sender reports are generated by streams
+		DisableRTCPSenderReports: true,
 	}
 
 	if s.UseUDP {
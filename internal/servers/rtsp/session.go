@@ -130,6 +130,13 @@ func (s *session) onAnnounce(c *conn, ctx *gortsplib.ServerHandlerOnAnnounceCtx)
 			return c.handleAuthError(terr)
 		}
 
+		var lerr defs.PathPublisherThrottledError
+		if errors.As(err, &lerr) {
+			return &base.Response{
+				StatusCode: base.StatusServiceUnavailable,
+			}, err
+		}
+
 		return &base.Response{
 			StatusCode: base.StatusBadRequest,
 		}, err
@@ -0,0 +1,38 @@
+package srt
+
+import (
+	"math"
+	"time"
+
+	srt "github.com/datarhei/gosrt"
+)
+
+const bitrateSampleInterval = 1 * time.Second
+
+// rawBitrate returns the instantaneous bitrate, in Mbps,
+// in the direction in which media flows.
+func rawBitrate(state connState, s *srt.Statistics) float64 {
+	if state == connStatePublish {
+		return s.Instantaneous.MbpsRecvRate
+	}
+	return s.Instantaneous.MbpsSentRate
+}
+
+// bitrateSmoother computes an exponential moving average of a bitrate.
+type bitrateSmoother struct {
+	window time.Duration
+
+	value       float64
+	initialized bool
+}
+
+func (s *bitrateSmoother) update(v float64, elapsed time.Duration) {
+	if !s.initialized {
+		s.value = v
+		s.initialized = true
+		return
+	}
+
+	alpha := 1 - math.Exp(-float64(elapsed)/float64(s.window))
+	s.value += alpha * (v - s.value)
+}
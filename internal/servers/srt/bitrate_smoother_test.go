@@ -0,0 +1,30 @@
+package srt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitrateSmoother(t *testing.T) {
+	s := &bitrateSmoother{window: 5 * time.Second}
+
+	s.update(0, 0)
+	require.Equal(t, float64(0), s.value)
+
+	// a single spike is attenuated
+	s.update(100, time.Second)
+	require.InDelta(t, 18.13, s.value, 0.01)
+
+	// a constant value is reached after some windows
+	for i := 0; i < 30; i++ {
+		s.update(10, time.Second)
+	}
+	require.InDelta(t, 10, s.value, 0.1)
+
+	// the first sample is used as is
+	s = &bitrateSmoother{window: 5 * time.Second}
+	s.update(42, 0)
+	require.Equal(t, float64(42), s.value)
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package srt
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+func readSysctlInt(fpath string) int {
+	buf, err := os.ReadFile(fpath)
+	if err != nil {
+		return 0
+	}
+
+	v, err := strconv.Atoi(strings.TrimSpace(string(buf)))
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
+
+// osBufferLimits returns the maximum receive and send buffer sizes allowed by the OS.
+// Zero means that the limit is unknown.
+func osBufferLimits() (int, int) {
+	return readSysctlInt("/proc/sys/net/core/rmem_max"),
+		readSysctlInt("/proc/sys/net/core/wmem_max")
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package srt
+
+func osBufferLimits() (int, int) {
+	return 0, 0
+}
@@ -5,11 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
 	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
 	srt "github.com/datarhei/gosrt"
 	"github.com/google/uuid"
@@ -21,26 +25,144 @@ import (
 	"github.com/bluenviron/mediamtx/internal/hooks"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+	"github.com/bluenviron/mediamtx/internal/recordstore"
 	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/waitgroup"
 )
 
-func srtCheckPassphrase(connReq srt.ConnRequest, passphrase string) error {
+var errServerDraining = errors.New("server is shutting down")
+
+func hasRandomAccessFormat(formats []format.Format) bool {
+	for _, forma := range formats {
+		switch forma.(type) {
+		case *format.H265, *format.H264:
+			return true
+		}
+	}
+	return false
+}
+
+// srtCheckPassphrase checks the passphrase of a connection against the primary passphrase
+// and then against grace passphrases, that are accepted during passphrase rotation.
+// It returns the index of the passphrase that matched, where 0 is the primary one,
+// or -1 when no passphrase is required.
+func srtCheckPassphrase(connReq srt.ConnRequest, passphrase string, gracePassphrases []string) (int, error) {
 	if passphrase == "" {
-		return nil
+		return -1, nil
 	}
 
 	if !connReq.IsEncrypted() {
-		return fmt.Errorf("connection is encrypted, but not passphrase is defined in configuration")
+		return -1, fmt.Errorf("connection is encrypted, but not passphrase is defined in configuration")
 	}
 
-	err := connReq.SetPassphrase(passphrase)
-	if err != nil {
-		return fmt.Errorf("invalid passphrase")
+	for i, p := range append([]string{passphrase}, gracePassphrases...) {
+		err := connReq.SetPassphrase(p)
+		if err == nil {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("invalid passphrase")
+}
+
+// srtUserPassphrases returns the passphrases that are accepted from a user.
+// When the user has a dedicated passphrase, it is the only one accepted,
+// otherwise the passphrases of the path are used.
+func srtUserPassphrases(
+	user string,
+	userPassphrases conf.SRTUserPassphrases,
+	passphrase string,
+	gracePassphrases []string,
+) (string, []string) {
+	if p, ok := userPassphrases[user]; ok && user != "" {
+		return p, nil
+	}
+	return passphrase, gracePassphrases
+}
+
+func srtCheckEncryption(connReq srt.ConnRequest, required bool) error {
+	if required && !connReq.IsEncrypted() {
+		return fmt.Errorf("connection is not encrypted, but encryption is required by configuration")
 	}
 
 	return nil
 }
 
+// srtSourceNotAllowedError is returned when the IP of a connection
+// is not allowed by srtSourceAllow or srtSourceDeny.
+type srtSourceNotAllowedError struct {
+	ip net.IP
+}
+
+// Error implements the error interface.
+func (e srtSourceNotAllowedError) Error() string {
+	return fmt.Sprintf("source IP %v is not allowed to access the path", e.ip)
+}
+
+// srtModeMismatchError is returned when the mode of the stream ID
+// is probably the opposite of the one intended by the caller,
+// which is a common mistake when configuring encoders and players.
+type srtModeMismatchError struct {
+	mode streamIDMode
+	path string
+}
+
+// Error implements the error interface.
+func (e srtModeMismatchError) Error() string {
+	if e.mode == streamIDModeRead {
+		return fmt.Sprintf("stream ID is in read mode, but no one is publishing to path '%s'. "+
+			"In order to publish, set the mode to publish (prefix 'publish:' or key 'm=publish')", e.path)
+	}
+	return fmt.Sprintf("stream ID is in publish mode, but path '%s' doesn't accept publishers, "+
+		"since its source is not 'publisher'. In order to read, set the mode to read "+
+		"(prefix 'read:' or key 'm=request')", e.path)
+}
+
+func srtCheckSource(allow conf.IPNetworks, deny conf.IPNetworks, ip net.IP) error {
+	if deny.Contains(ip) {
+		return srtSourceNotAllowedError{ip: ip}
+	}
+
+	if len(allow) != 0 && !allow.Contains(ip) {
+		return srtSourceNotAllowedError{ip: ip}
+	}
+
+	return nil
+}
+
+func srtCheckNamespace(namespace string, streamID *streamID) error {
+	if namespace == "" {
+		return nil
+	}
+
+	if streamID.user == "" {
+		return fmt.Errorf("a user is required in order to publish")
+	}
+
+	prefix := strings.ReplaceAll(namespace, "%user", streamID.user)
+
+	if !strings.HasPrefix(streamID.path, prefix) {
+		return fmt.Errorf("user '%s' is not allowed to publish to path '%s', since it is outside namespace '%s'",
+			streamID.user, streamID.path, prefix)
+	}
+
+	return nil
+}
+
+// decodeErrWriter is a logger.Writer that writes decode errors
+// into both the connection log and path events.
+type decodeErrWriter struct {
+	c    *conn
+	path defs.Path
+}
+
+// Log implements logger.Writer.
+func (w *decodeErrWriter) Log(level logger.Level, format string, args ...interface{}) {
+	w.c.Log(level, format, args...)
+	w.path.AddEvent(level, "SRT connection %v: decode error: "+format,
+		append([]interface{}{w.c.connReq.RemoteAddr()}, args...)...)
+}
+
 type connState int
 
 const (
@@ -54,34 +176,66 @@ type conn struct {
 	readTimeout         conf.StringDuration
 	writeTimeout        conf.StringDuration
 	udpMaxPayloadSize   int
+	bitrateWindow       conf.StringDuration
+	publishNamespace    string
+	autoCreatePaths     bool
+	publishBufferPolicy conf.SRTPublishBufferPolicy
+	publishBufferSize   conf.StringSize
+	publishStartTimeout conf.StringDuration
+	alarmRTT            conf.StringDuration
+	alarmLossRate       float64
+	handshakeTimeout    conf.StringDuration
 	connReq             srt.ConnRequest
 	runOnConnect        string
 	runOnConnectRestart bool
 	runOnDisconnect     string
-	wg                  *sync.WaitGroup
+	wg                  *waitgroup.WaitGroup
 	externalCmdPool     *externalcmd.Pool
 	pathManager         serverPathManager
 	parent              *Server
 
-	ctx       context.Context
-	ctxCancel func()
-	created   time.Time
-	uuid      uuid.UUID
-	mutex     sync.RWMutex
-	state     connState
-	pathName  string
-	query     string
-	sconn     srt.Conn
+	ctx        context.Context
+	ctxCancel  func()
+	chDrain    chan struct{}
+	chDrainEnd chan struct{}
+	created    time.Time
+	uuid       uuid.UUID
+	mutex      sync.RWMutex
+	state      connState
+	pathName   string
+	query      string
+	path       defs.Path
+	sconn      srt.Conn
+	bitrate    bitrateSmoother
+	alarm      linkAlarm
+
+	handshakeMutex sync.Mutex
+	handshakeEnded bool
+	handshakeTimer *time.Timer
 }
 
 func (c *conn) initialize() {
 	c.ctx, c.ctxCancel = context.WithCancel(c.parentCtx)
+	c.chDrain = make(chan struct{})
+	c.chDrainEnd = make(chan struct{})
+	c.bitrate = bitrateSmoother{window: time.Duration(c.bitrateWindow)}
+	c.alarm = linkAlarm{
+		rttThreshold:      time.Duration(c.alarmRTT),
+		lossRateThreshold: c.alarmLossRate,
+	}
 
 	c.created = time.Now()
 	c.uuid = uuid.New()
 
 	c.Log(logger.Info, "opened")
 
+	if c.handshakeTimeout != 0 {
+		// the timer callback reads handshakeTimer, therefore it must be assigned with handshakeMutex locked.
+		c.handshakeMutex.Lock()
+		c.handshakeTimer = time.AfterFunc(time.Duration(c.handshakeTimeout), c.onHandshakeTimeout)
+		c.handshakeMutex.Unlock()
+	}
+
 	c.wg.Add(1)
 	go c.run()
 }
@@ -90,18 +244,115 @@ func (c *conn) Close() {
 	c.ctxCancel()
 }
 
+// drain asks the connection to terminate as soon as possible
+// without truncating the current GOP. It is called by Server.
+func (c *conn) drain() {
+	close(c.chDrain)
+}
+
+// endDrain asks a publishing connection to terminate,
+// once readers have received the rest of the current GOP.
+// It is called by Server and can be called multiple times.
+func (c *conn) endDrain() {
+	select {
+	case <-c.chDrainEnd:
+	default:
+		close(c.chDrainEnd)
+	}
+}
+
+func (c *conn) currentState() connState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.state
+}
+
 // Log implements logger.Writer.
 func (c *conn) Log(level logger.Level, format string, args ...interface{}) {
 	c.parent.Log(level, "[conn %v] "+format, append([]interface{}{c.connReq.RemoteAddr()}, args...)...)
 }
 
+func (c *conn) checkPassphrase(passphrase string, gracePassphrases []string) error {
+	i, err := srtCheckPassphrase(c.connReq, passphrase, gracePassphrases)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case i == 0:
+		c.Log(logger.Debug, "passphrase matched the primary passphrase")
+
+	case i > 0:
+		c.Log(logger.Debug, "passphrase matched grace passphrase %d, the client should switch to the primary one", i)
+	}
+
+	return nil
+}
+
+// endHandshake must be called with handshakeMutex locked.
+func (c *conn) endHandshake() {
+	c.handshakeEnded = true
+	if c.handshakeTimer != nil {
+		c.handshakeTimer.Stop()
+	}
+	c.parent.handshakeDone(c)
+}
+
+func (c *conn) accept() (srt.Conn, error) {
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+
+	if c.handshakeEnded {
+		return nil, fmt.Errorf("handshake not completed within %v", time.Duration(c.handshakeTimeout))
+	}
+
+	c.endHandshake()
+	return c.connReq.Accept()
+}
+
+func (c *conn) reject(reason srt.RejectionReason) {
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+
+	if c.handshakeEnded {
+		return
+	}
+
+	c.endHandshake()
+	c.parent.reject(c.connReq, reason)
+}
+
+func (c *conn) onHandshakeTimeout() {
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+
+	if c.handshakeEnded {
+		return
+	}
+
+	c.endHandshake()
+	c.Log(logger.Warn, "handshake not completed within %v, rejecting", time.Duration(c.handshakeTimeout))
+	c.parent.reject(c.connReq, srt.REJ_PEER)
+}
+
 func (c *conn) ip() net.IP {
 	return c.connReq.RemoteAddr().(*net.UDPAddr).IP
 }
 
+func (c *conn) checkSource(pathConf *conf.Path) error {
+	return srtCheckSource(pathConf.SRTSourceAllow, pathConf.SRTSourceDeny, c.ip())
+}
+
 func (c *conn) run() { //nolint:dupl
 	defer c.wg.Done()
 
+	// the stream ID is parsed before running hooks, in order to provide them the query
+	var streamID streamID
+	err := streamID.unmarshal(c.connReq.StreamId())
+	if err != nil {
+		err = fmt.Errorf("invalid stream ID '%s': %w", c.connReq.StreamId(), err)
+	}
+
 	onDisconnectHook := hooks.OnConnect(hooks.OnConnectParams{
 		Logger:              c,
 		ExternalCmdPool:     c.externalCmdPool,
@@ -110,33 +361,48 @@ func (c *conn) run() { //nolint:dupl
 		RunOnDisconnect:     c.runOnDisconnect,
 		RTSPAddress:         c.rtspAddress,
 		Desc:                c.APIReaderDescribe(),
+		Query:               streamID.query,
 	})
 	defer onDisconnectHook()
 
-	err := c.runInner()
+	if err == nil {
+		err = c.runInner(&streamID)
+	}
+
+	// reject requests that have been left pending
+	c.reject(srt.REJ_PEER)
 
 	c.ctxCancel()
 
 	c.parent.closeConn(c)
 
 	c.Log(logger.Info, "closed: %v", err)
-}
 
-func (c *conn) runInner() error {
-	var streamID streamID
-	err := streamID.unmarshal(c.connReq.StreamId())
-	if err != nil {
-		c.connReq.Reject(srt.REJ_PEER)
-		return fmt.Errorf("invalid stream ID '%s': %w", c.connReq.StreamId(), err)
+	c.mutex.RLock()
+	path := c.path
+	c.mutex.RUnlock()
+
+	if path != nil {
+		path.AddEvent(logger.Info, "SRT connection %v closed: %v", c.connReq.RemoteAddr(), err)
 	}
+}
 
+func (c *conn) runInner(streamID *streamID) error {
 	if streamID.mode == streamIDModePublish {
-		return c.runPublish(&streamID)
+		return c.runPublish(streamID)
 	}
-	return c.runRead(&streamID)
+	return c.runRead(streamID)
 }
 
 func (c *conn) runPublish(streamID *streamID) error {
+	err := srtCheckNamespace(c.publishNamespace, streamID)
+	if err != nil {
+		c.reject(srt.REJ_PEER)
+		return err
+	}
+
+	var pathConf *conf.Path
+
 	path, err := c.pathManager.AddPublisher(defs.PathAddPublisherReq{
 		Author: c,
 		AccessRequest: defs.PathAccessRequest{
@@ -149,66 +415,200 @@ func (c *conn) runPublish(streamID *streamID) error {
 			ID:      &c.uuid,
 			Query:   streamID.query,
 		},
+		NoDynamicPaths: !c.autoCreatePaths,
+		CheckConf: func(pc *conf.Path) error {
+			pathConf = pc
+			return c.checkSource(pc)
+		},
+		ExternalCmdEnv: streamID.metadataEnv(),
 	})
 	if err != nil {
+		var serr srtSourceNotAllowedError
+		if errors.As(err, &serr) {
+			c.reject(srt.REJX_FORBIDDEN)
+			return serr
+		}
+
 		var terr *auth.Error
 		if errors.As(err, &terr) {
 			// wait some seconds to mitigate brute force attacks
 			<-time.After(auth.PauseAfterError)
-			c.connReq.Reject(srt.REJ_PEER)
+			c.reject(srt.REJ_PEER)
 			return terr
 		}
-		c.connReq.Reject(srt.REJ_PEER)
+
+		var nerr conf.PathNotConfiguredError
+		if errors.As(err, &nerr) {
+			c.reject(srt.REJ_PEER)
+			return fmt.Errorf("%w: the stream ID must contain a path listed in 'paths' "+
+				"or matching a regular expression path or 'all_others'", err)
+		}
+
+		var lerr defs.PathPublisherThrottledError
+		if errors.As(err, &lerr) {
+			c.reject(srt.REJX_OVERLOAD)
+			return lerr
+		}
+
+		if pathConf != nil && pathConf.Source != "publisher" {
+			c.reject(srt.REJX_BAD_MODE)
+			return srtModeMismatchError{mode: streamIDModePublish, path: streamID.path}
+		}
+
+		c.reject(srt.REJ_PEER)
 		return err
 	}
 
 	defer path.RemovePublisher(defs.PathRemovePublisherReq{Author: c})
 
-	err = srtCheckPassphrase(c.connReq, path.SafeConf().SRTPublishPassphrase)
+	err = srtCheckEncryption(c.connReq, path.SafeConf().SRTRequireEncryption)
 	if err != nil {
-		c.connReq.Reject(srt.REJ_PEER)
+		c.reject(srt.REJ_UNSECURE)
 		return err
 	}
 
-	sconn, err := c.connReq.Accept()
+	pathConf = path.SafeConf()
+	err = c.checkPassphrase(srtUserPassphrases(streamID.user, pathConf.SRTPublishUserPassphrases,
+		pathConf.SRTPublishPassphrase, pathConf.SRTPublishGracePassphrases))
 	if err != nil {
+		c.reject(srt.REJ_PEER)
 		return err
 	}
 
+	sconn, err := c.accept()
+	if err != nil {
+		return err
+	}
+
+	c.logHandshake(sconn)
+
 	c.mutex.Lock()
 	c.state = connStatePublish
 	c.pathName = streamID.path
 	c.query = streamID.query
+	c.path = path
 	c.sconn = sconn
 	c.mutex.Unlock()
 
+	path.AddEvent(logger.Info, "SRT connection %v opened", c.connReq.RemoteAddr())
+
+	c.startBitrateSampler(sconn)
+	c.startLinkAlarmEvaluator(sconn)
+
+	dump := c.startDump(path.SafeConf(), streamID)
+	if dump != nil {
+		defer dump.close()
+	}
+
+	started := make(chan struct{})
+
 	readerErr := make(chan error)
 	go func() {
-		readerErr <- c.runPublishReader(sconn, path)
+		readerErr <- c.runPublishReader(sconn, dump, path, started)
 	}()
 
-	select {
-	case err := <-readerErr:
-		sconn.Close()
-		return err
+	startTimer := emptyTimer()
+	if c.publishStartTimeout != 0 {
+		startTimer = time.NewTimer(time.Duration(c.publishStartTimeout))
+	}
+	defer startTimer.Stop()
 
-	case <-c.ctx.Done():
-		sconn.Close()
-		<-readerErr
-		return errors.New("terminated")
+	for {
+		select {
+		case <-started:
+			startTimer.Stop()
+			started = nil
+
+		case <-startTimer.C:
+			sconn.Close()
+			<-readerErr
+			return fmt.Errorf("no MPEG-TS data received within %v after the handshake",
+				time.Duration(c.publishStartTimeout))
+
+		case err := <-readerErr:
+			sconn.Close()
+			return err
+
+		case <-c.chDrainEnd:
+			sconn.Close()
+			<-readerErr
+			return errServerDraining
+
+		case <-c.ctx.Done():
+			sconn.Close()
+			<-readerErr
+			return errors.New("terminated")
+		}
+	}
+}
+
+// startDump starts dumping received data when it is enabled in the path configuration
+// or requested by the publisher through the stream ID.
+func (c *conn) startDump(pathConf *conf.Path, streamID *streamID) *streamDump {
+	if pathConf.SRTDebugDumpPath == "" || (!pathConf.SRTDebugDump && !streamID.dump) {
+		return nil
 	}
+
+	dump := &streamDump{
+		path: recordstore.Path{
+			Start: time.Now(),
+			Path:  streamID.path,
+		}.Encode(pathConf.SRTDebugDumpPath) + ".ts",
+		maxSize:     int64(pathConf.SRTDebugDumpMaxSize),
+		maxDuration: time.Duration(pathConf.SRTDebugDumpMaxDuration),
+		parent:      c,
+	}
+	err := dump.initialize()
+	if err != nil {
+		c.Log(logger.Warn, "unable to start dump: %v", err)
+		return nil
+	}
+
+	return dump
 }
 
-func (c *conn) runPublishReader(sconn srt.Conn, path defs.Path) error {
-	sconn.SetReadDeadline(time.Now().Add(time.Duration(c.readTimeout)))
-	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(sconn))
+func (c *conn) runPublishReader(sconn srt.Conn, dump *streamDump, path defs.Path, started chan struct{}) error {
+	dropLogger := logger.NewLimitedLogger(c)
+
+	var connReader io.Reader = sconn
+	if dump != nil {
+		connReader = &streamDumpReader{r: sconn, dump: dump}
+	}
+
+	if pathConf := path.SafeConf(); pathConf.MaxPublishBitrate != 0 {
+		limiter := &publishBitrateLimiter{
+			r:           connReader,
+			maxBitrate:  uint64(pathConf.MaxPublishBitrate),
+			gracePeriod: time.Duration(pathConf.MaxPublishBitrateGracePeriod),
+		}
+		limiter.initialize()
+		connReader = limiter
+	}
+
+	buf := &publishBuffer{
+		r:      connReader,
+		size:   int(c.publishBufferSize),
+		policy: c.publishBufferPolicy,
+		onDrop: func() {
+			dropLogger.Log(logger.Warn, "publish buffer is full, discarding oldest data")
+		},
+	}
+	buf.initialize()
+	defer buf.close()
+
+	r, err := mcmpegts.NewReader(mcmpegts.NewBufferedReader(buf))
 	if err != nil {
 		return err
 	}
 
-	decodeErrLogger := logger.NewLimitedLogger(c)
+	// the program map table has been received
+	close(started)
+
+	decodeErrLogger := logger.NewLimitedLogger(&decodeErrWriter{c: c, path: path})
 
 	r.OnDecodeError(func(err error) {
+		// every occurrence is counted, while logging is rate-limited
+		path.AddDecodeError(err.Error())
 		decodeErrLogger.Log(logger.Warn, err.Error())
 	})
 
@@ -237,6 +637,8 @@ func (c *conn) runPublishReader(sconn srt.Conn, path defs.Path) error {
 }
 
 func (c *conn) runRead(streamID *streamID) error {
+	var pathConf *conf.Path
+
 	path, stream, err := c.pathManager.AddReader(defs.PathAddReaderReq{
 		Author: c,
 		AccessRequest: defs.PathAccessRequest{
@@ -248,43 +650,132 @@ func (c *conn) runRead(streamID *streamID) error {
 			ID:    &c.uuid,
 			Query: streamID.query,
 		},
+		CheckConf: func(pc *conf.Path) error {
+			pathConf = pc
+			return c.checkSource(pc)
+		},
+		Proxy: streamID.proxy,
 	})
 	if err != nil {
+		var serr srtSourceNotAllowedError
+		if errors.As(err, &serr) {
+			c.reject(srt.REJX_FORBIDDEN)
+			return serr
+		}
+
 		var terr *auth.Error
 		if errors.As(err, &terr) {
 			// wait some seconds to mitigate brute force attacks
 			<-time.After(auth.PauseAfterError)
-			c.connReq.Reject(srt.REJ_PEER)
+			c.reject(srt.REJ_PEER)
 			return terr
 		}
-		c.connReq.Reject(srt.REJ_PEER)
+
+		// a path whose source is 'publisher' and that has no publisher
+		// is usually read by mistake by an encoder that should publish to it.
+		var perr defs.PathNoOnePublishingError
+		if errors.As(err, &perr) && pathConf != nil && pathConf.Source == "publisher" {
+			c.reject(srt.REJX_BAD_MODE)
+			return srtModeMismatchError{mode: streamIDModeRead, path: streamID.path}
+		}
+
+		c.reject(srt.REJ_PEER)
 		return err
 	}
 
 	defer path.RemoveReader(defs.PathRemoveReaderReq{Author: c})
 
-	err = srtCheckPassphrase(c.connReq, path.SafeConf().SRTReadPassphrase)
+	err = srtCheckEncryption(c.connReq, path.SafeConf().SRTRequireEncryption)
+	if err != nil {
+		c.reject(srt.REJ_UNSECURE)
+		return err
+	}
+
+	pathConf = path.SafeConf()
+	err = c.checkPassphrase(srtUserPassphrases(streamID.user, pathConf.SRTReadUserPassphrases,
+		pathConf.SRTReadPassphrase, pathConf.SRTReadGracePassphrases))
+	if err != nil {
+		c.reject(srt.REJ_PEER)
+		return err
+	}
+
+	medias, err := mpegts.SelectProgram(stream.ReaderMedias(streamID.proxy), streamID.program)
 	if err != nil {
-		c.connReq.Reject(srt.REJ_PEER)
+		c.reject(srt.REJ_PEER)
 		return err
 	}
 
-	sconn, err := c.connReq.Accept()
+	sconn, err := c.accept()
 	if err != nil {
 		return err
 	}
+
+	c.logHandshake(sconn)
 	defer sconn.Close()
 
 	c.mutex.Lock()
 	c.state = connStateRead
 	c.pathName = streamID.path
 	c.query = streamID.query
+	c.path = path
 	c.sconn = sconn
 	c.mutex.Unlock()
 
-	bw := bufio.NewWriterSize(sconn, srtMaxPayloadSize(c.udpMaxPayloadSize))
+	path.AddEvent(logger.Info, "SRT connection %v opened", c.connReq.RemoteAddr())
+
+	c.startBitrateSampler(sconn)
+	c.startLinkAlarmEvaluator(sconn)
 
-	err = mpegts.FromStream(stream, c, bw, sconn, time.Duration(c.writeTimeout))
+	warmup := path.SafeConf().SRTReadWarmup
+
+	if warmup {
+		select {
+		case <-stream.KeyFrameReceived():
+
+		case <-time.After(time.Duration(path.SafeConf().SRTReadWarmupTimeout)):
+			c.Log(logger.Warn, "no key frame received within %v, starting anyway",
+				path.SafeConf().SRTReadWarmupTimeout)
+
+		case <-c.ctx.Done():
+			return fmt.Errorf("terminated")
+		}
+	}
+
+	var w io.Writer = sconn
+
+	if br := path.SafeConf().SRTCBRBitrate; br != 0 {
+		cw := &mpegts.CBRWriter{
+			W:            sconn,
+			Bitrate:      uint64(br),
+			MaxWriteSize: srtMaxPayloadSize(c.udpMaxPayloadSize),
+			MaxDelay:     time.Duration(c.writeTimeout),
+		}
+		cw.Initialize()
+		w = cw
+	} else if path.SafeConf().SRTPacing {
+		pw := &mpegts.PacedWriter{
+			W:            sconn,
+			MaxWriteSize: srtMaxPayloadSize(c.udpMaxPayloadSize),
+			MaxDelay:     time.Duration(c.writeTimeout),
+			Done:         c.ctx.Done(),
+		}
+		pw.Initialize()
+		w = pw
+	}
+
+	bw := bufio.NewWriterSize(w, srtMaxPayloadSize(c.udpMaxPayloadSize))
+
+	pids := mpegts.PIDs{
+		PMT:    uint16(path.SafeConf().MPEGTSPMTPID),
+		Tracks: path.SafeConf().MPEGTSTrackPIDs,
+	}
+
+	service := mpegts.Service{
+		Name:     path.SafeConf().TSServiceName,
+		Provider: path.SafeConf().TSProviderName,
+	}
+
+	err = mpegts.FromStream(stream, c, medias, pids, service, bw, sconn, time.Duration(c.writeTimeout), c.checkDrain)
 	if err != nil {
 		return err
 	}
@@ -292,11 +783,14 @@ func (c *conn) runRead(streamID *streamID) error {
 	c.Log(logger.Info, "is reading from path '%s', %s",
 		path.Name(), defs.FormatsInfo(stream.ReaderFormats(c)))
 
+	env := path.ExternalCmdEnv()
+	maps.Copy(env, streamID.metadataEnv())
+
 	onUnreadHook := hooks.OnRead(hooks.OnReadParams{
 		Logger:          c,
 		ExternalCmdPool: c.externalCmdPool,
 		Conf:            path.SafeConf(),
-		ExternalCmdEnv:  path.ExternalCmdEnv(),
+		ExternalCmdEnv:  env,
 		Reader:          c.APIReaderDescribe(),
 		Query:           streamID.query,
 	})
@@ -305,7 +799,12 @@ func (c *conn) runRead(streamID *streamID) error {
 	// disable read deadline
 	sconn.SetReadDeadline(time.Time{})
 
-	stream.StartReader(c)
+	if warmup {
+		// send the cached group of pictures, in order to start from a key frame
+		stream.StartReaderFromKeyFrame(c)
+	} else {
+		stream.StartReader(c)
+	}
 	defer stream.RemoveReader(c)
 
 	select {
@@ -314,6 +813,181 @@ func (c *conn) runRead(streamID *streamID) error {
 
 	case err = <-stream.ReaderError(c):
 		return err
+
+	case <-c.chDrain:
+		return c.drainRead(stream, sconn)
+	}
+}
+
+func (c *conn) drainRead(strm *stream.Stream, sconn srt.Conn) error {
+	// without a video track there's no GOP to complete
+	if !hasRandomAccessFormat(strm.ReaderFormats(c)) {
+		c.waitDelivery(sconn)
+		return errServerDraining
+	}
+
+	select {
+	case <-c.ctx.Done():
+		return fmt.Errorf("terminated")
+
+	case err := <-strm.ReaderError(c):
+		if errors.Is(err, errServerDraining) {
+			c.waitDelivery(sconn)
+		}
+		return err
+	}
+}
+
+// waitDelivery waits until the receiver has acknowledged all sent packets
+// and has had the time to deliver them, since closing the connection
+// discards both the send buffer and the receive buffer of the peer.
+func (c *conn) waitDelivery(sconn srt.Conn) {
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+
+	deadline := time.After(time.Duration(c.writeTimeout))
+
+	for {
+		// packets are moved into the send buffer asynchronously,
+		// therefore wait before checking it.
+		select {
+		case <-t.C:
+		case <-deadline:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+
+		var s srt.Statistics
+		sconn.Stats(&s)
+
+		if s.Instantaneous.PktSendBuf == 0 {
+			select {
+			case <-time.After(time.Duration(s.Instantaneous.MsSendTsbPdDelay) * time.Millisecond):
+			case <-deadline:
+			case <-c.ctx.Done():
+			}
+			return
+		}
+	}
+}
+
+// checkDrain is called before writing a random access unit.
+func (c *conn) checkDrain() error {
+	select {
+	case <-c.chDrain:
+		return errServerDraining
+	default:
+		return nil
+	}
+}
+
+// logHandshake logs parameters negotiated during the handshake.
+func (c *conn) logHandshake(sconn srt.Conn) {
+	var stats srt.Statistics
+	sconn.Stats(&stats)
+
+	maxBandwidth := "unlimited"
+	if stats.Instantaneous.MbpsMaxBW > 0 {
+		maxBandwidth = fmt.Sprintf("%.2fMbps", stats.Instantaneous.MbpsMaxBW)
+	}
+
+	c.Log(logger.Debug, "handshake completed: version=%d, encrypted=%v, latency=%dms, peerLatency=%dms, "+
+		"maxBandwidth=%s, mss=%d, flowWindow=%d",
+		sconn.Version(),
+		c.connReq.IsEncrypted(),
+		stats.Instantaneous.MsRecvTsbPdDelay,
+		stats.Instantaneous.MsSendTsbPdDelay,
+		maxBandwidth,
+		stats.Instantaneous.ByteMSS,
+		stats.Instantaneous.PktFlowWindow)
+}
+
+func (c *conn) startBitrateSampler(sconn srt.Conn) {
+	if c.bitrateWindow <= 0 {
+		return
+	}
+
+	c.wg.Add(1)
+	go c.runBitrateSampler(sconn)
+}
+
+func (c *conn) runBitrateSampler(sconn srt.Conn) {
+	defer c.wg.Done()
+
+	t := time.NewTicker(bitrateSampleInterval)
+	defer t.Stop()
+
+	last := time.Now()
+
+	for {
+		select {
+		case now := <-t.C:
+			var s srt.Statistics
+			sconn.Stats(&s)
+
+			c.mutex.Lock()
+			c.bitrate.update(rawBitrate(c.state, &s), now.Sub(last))
+			c.mutex.Unlock()
+
+			last = now
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *conn) startLinkAlarmEvaluator(sconn srt.Conn) {
+	if !c.alarm.enabled() {
+		return
+	}
+
+	c.wg.Add(1)
+	go c.runLinkAlarmEvaluator(sconn)
+}
+
+func (c *conn) runLinkAlarmEvaluator(sconn srt.Conn) {
+	defer c.wg.Done()
+
+	t := time.NewTicker(linkAlarmEvaluateInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			var s srt.Statistics
+			sconn.Stats(&s)
+			c.evaluateLinkAlarm(&s)
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *conn) evaluateLinkAlarm(s *srt.Statistics) {
+	rtt := time.Duration(s.Instantaneous.MsRTT * float64(time.Millisecond))
+
+	c.mutex.Lock()
+	lr := lossRate(c.state, s)
+	changed := c.alarm.update(rtt, lr)
+	active := c.alarm.active
+	path := c.path
+	c.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if active {
+		c.Log(logger.Warn, "link alarm raised (RTT %v, loss rate %.2f%%)", rtt, lr)
+		path.AddEvent(logger.Warn, "SRT connection %v: link alarm raised (RTT %v, loss rate %.2f%%)",
+			c.connReq.RemoteAddr(), rtt, lr)
+	} else {
+		c.Log(logger.Info, "link alarm cleared (RTT %v, loss rate %.2f%%)", rtt, lr)
+		path.AddEvent(logger.Info, "SRT connection %v: link alarm cleared (RTT %v, loss rate %.2f%%)",
+			c.connReq.RemoteAddr(), rtt, lr)
 	}
 }
 
@@ -330,6 +1004,41 @@ func (c *conn) APISourceDescribe() defs.APIPathSourceOrReader {
 	return c.APIReaderDescribe()
 }
 
+// LinkRTT implements defs.LinkRTTSource.
+func (c *conn) LinkRTT() (time.Duration, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.sconn == nil {
+		return 0, false
+	}
+
+	var s srt.Statistics
+	c.sconn.Stats(&s)
+
+	return time.Duration(s.Instantaneous.MsRTT * float64(time.Millisecond)), true
+}
+
+// LinkStats implements defs.LinkStatsReader.
+func (c *conn) LinkStats() (defs.ReaderLinkStats, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.sconn == nil {
+		return defs.ReaderLinkStats{}, false
+	}
+
+	var s srt.Statistics
+	c.sconn.Stats(&s)
+
+	return defs.ReaderLinkStats{
+		RTT:                  time.Duration(s.Instantaneous.MsRTT * float64(time.Millisecond)),
+		PacketsSent:          s.Accumulated.PktSent,
+		PacketsLost:          s.Accumulated.PktSendLoss,
+		PacketsRetransmitted: s.Accumulated.PktRetrans,
+	}, true
+}
+
 func (c *conn) apiItem() *defs.APISRTConn {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
@@ -337,6 +1046,7 @@ func (c *conn) apiItem() *defs.APISRTConn {
 	item := &defs.APISRTConn{
 		ID:         c.uuid,
 		Created:    c.created,
+		Uptime:     conf.StringDuration(time.Since(c.created).Truncate(time.Millisecond)),
 		RemoteAddr: c.connReq.RemoteAddr().String(),
 		State: func() defs.APISRTConnState {
 			switch c.state {
@@ -352,6 +1062,13 @@ func (c *conn) apiItem() *defs.APISRTConn {
 		}(),
 		Path:  c.pathName,
 		Query: c.query,
+		Encryption: func() defs.APISRTConnEncryption {
+			if c.connReq.IsEncrypted() {
+				return defs.APISRTConnEncryptionCTR
+			}
+			return defs.APISRTConnEncryptionNone
+		}(),
+		Alarm: c.alarm.active,
 	}
 
 	if c.sconn != nil {
@@ -411,6 +1128,13 @@ func (c *conn) apiItem() *defs.APISRTConn {
 		item.PacketsReceivedAvgBelatedTime = s.Instantaneous.PktRecvAvgBelatedTime
 		item.PacketsSendLossRate = s.Instantaneous.PktSendLossRate
 		item.PacketsReceivedLossRate = s.Instantaneous.PktRecvLossRate
+		item.BitrateRaw = rawBitrate(c.state, &s)
+
+		if c.bitrateWindow > 0 {
+			item.BitrateSmoothed = c.bitrate.value
+		} else {
+			item.BitrateSmoothed = item.BitrateRaw
+		}
 	}
 
 	return item
@@ -0,0 +1,46 @@
+package srt
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+func mustParseIPNetworks(t *testing.T, v string) conf.IPNetworks {
+	var n conf.IPNetworks
+	err := n.UnmarshalJSON([]byte(v))
+	require.NoError(t, err)
+	return n
+}
+
+func TestSRTCheckSource(t *testing.T) {
+	for _, ca := range []struct {
+		name    string
+		allow   string
+		deny    string
+		ip      string
+		allowed bool
+	}{
+		{"empty lists", `[]`, `[]`, "192.168.1.10", true},
+		{"ipv4 allowed", `["192.168.0.0/16"]`, `[]`, "192.168.1.10", true},
+		{"ipv4 not in allow list", `["192.168.0.0/16"]`, `[]`, "10.0.0.1", false},
+		{"ipv4 denied", `[]`, `["10.0.0.0/8"]`, "10.0.0.1", false},
+		{"ipv4 deny takes precedence", `["10.0.0.0/8"]`, `["10.0.0.1"]`, "10.0.0.1", false},
+		{"ipv6 allowed", `["fd00::/8"]`, `[]`, "fd00::1", true},
+		{"ipv6 not in allow list", `["fd00::/8"]`, `[]`, "2001:db8::1", false},
+		{"ipv6 denied", `[]`, `["2001:db8::/32"]`, "2001:db8::1", false},
+		{"ipv4-mapped ipv6", `["192.168.0.0/16"]`, `[]`, "::ffff:192.168.1.10", true},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			err := srtCheckSource(mustParseIPNetworks(t, ca.allow), mustParseIPNetworks(t, ca.deny), net.ParseIP(ca.ip))
+			if ca.allowed {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, "source IP "+net.ParseIP(ca.ip).String()+" is not allowed to access the path")
+			}
+		})
+	}
+}
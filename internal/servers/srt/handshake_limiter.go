@@ -0,0 +1,39 @@
+package srt
+
+import (
+	"time"
+)
+
+// handshakeLimiter is a token bucket that limits the rate of handshakes.
+// The bucket holds up to one second of handshakes, in order to absorb
+// legitimate bursts. A zero rate disables the limit.
+type handshakeLimiter struct {
+	rate int
+
+	tokens float64
+	last   time.Time
+}
+
+// allow returns true if a handshake received at the given time can be processed.
+func (l *handshakeLimiter) allow(now time.Time) bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	if l.last.IsZero() {
+		l.tokens = float64(l.rate)
+	} else {
+		l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+		if l.tokens > float64(l.rate) {
+			l.tokens = float64(l.rate)
+		}
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
@@ -0,0 +1,36 @@
+package srt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshakeLimiter(t *testing.T) {
+	l := handshakeLimiter{rate: 2}
+	now := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.True(t, l.allow(now))
+	require.True(t, l.allow(now))
+	require.False(t, l.allow(now))
+
+	now = now.Add(500 * time.Millisecond)
+	require.True(t, l.allow(now))
+	require.False(t, l.allow(now))
+
+	// tokens do not accumulate beyond the rate
+	now = now.Add(10 * time.Second)
+	require.True(t, l.allow(now))
+	require.True(t, l.allow(now))
+	require.False(t, l.allow(now))
+}
+
+func TestHandshakeLimiterDisabled(t *testing.T) {
+	l := handshakeLimiter{}
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		require.True(t, l.allow(now))
+	}
+}
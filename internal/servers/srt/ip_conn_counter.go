@@ -0,0 +1,75 @@
+package srt
+
+import (
+	"net"
+	"sort"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+// ipConnCounter counts connections of each IP.
+// IPv6 addresses are grouped by prefix, since a single client
+// can usually pick any address of its network.
+// A zero maximum disables the limit.
+type ipConnCounter struct {
+	max              int
+	ipv6PrefixLength int
+
+	counts map[string]int
+}
+
+func (c *ipConnCounter) initialize() {
+	c.counts = make(map[string]int)
+}
+
+func (c *ipConnCounter) key(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
+	}
+
+	if c.ipv6PrefixLength >= 128 {
+		return ip.String()
+	}
+
+	mask := net.CIDRMask(c.ipv6PrefixLength, 128)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// add counts a connection from the given IP.
+// It returns false if the limit has been reached.
+func (c *ipConnCounter) add(ip net.IP) bool {
+	k := c.key(ip)
+
+	if c.max > 0 && c.counts[k] >= c.max {
+		return false
+	}
+
+	c.counts[k]++
+	return true
+}
+
+func (c *ipConnCounter) remove(ip net.IP) {
+	k := c.key(ip)
+
+	c.counts[k]--
+	if c.counts[k] <= 0 {
+		delete(c.counts, k)
+	}
+}
+
+func (c *ipConnCounter) apiItems() []*defs.APISRTIP {
+	items := []*defs.APISRTIP{}
+
+	for k, n := range c.counts {
+		items = append(items, &defs.APISRTIP{
+			IP:    k,
+			Conns: n,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].IP < items[j].IP
+	})
+
+	return items
+}
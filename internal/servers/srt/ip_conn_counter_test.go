@@ -0,0 +1,64 @@
+package srt
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+)
+
+func TestIPConnCounter(t *testing.T) {
+	c := ipConnCounter{max: 2, ipv6PrefixLength: 128}
+	c.initialize()
+
+	ip1 := net.ParseIP("192.168.1.1")
+	ip2 := net.ParseIP("192.168.1.2")
+
+	require.True(t, c.add(ip1))
+	require.True(t, c.add(ip1))
+	require.False(t, c.add(ip1))
+	require.True(t, c.add(ip2))
+
+	require.Equal(t, []*defs.APISRTIP{
+		{IP: "192.168.1.1", Conns: 2},
+		{IP: "192.168.1.2", Conns: 1},
+	}, c.apiItems())
+
+	c.remove(ip1)
+	require.True(t, c.add(ip1))
+
+	c.remove(ip1)
+	c.remove(ip1)
+	c.remove(ip2)
+	require.Equal(t, []*defs.APISRTIP{}, c.apiItems())
+}
+
+func TestIPConnCounterIPv6Prefix(t *testing.T) {
+	c := ipConnCounter{max: 1, ipv6PrefixLength: 64}
+	c.initialize()
+
+	require.True(t, c.add(net.ParseIP("2001:db8:1:2::1")))
+	require.False(t, c.add(net.ParseIP("2001:db8:1:2::ffff")))
+	require.True(t, c.add(net.ParseIP("2001:db8:1:3::1")))
+
+	// IPv4-mapped addresses are counted as IPv4
+	require.True(t, c.add(net.ParseIP("::ffff:10.0.0.1")))
+	require.False(t, c.add(net.ParseIP("10.0.0.1")))
+
+	require.Equal(t, []*defs.APISRTIP{
+		{IP: "10.0.0.1", Conns: 1},
+		{IP: "2001:db8:1:2::/64", Conns: 1},
+		{IP: "2001:db8:1:3::/64", Conns: 1},
+	}, c.apiItems())
+}
+
+func TestIPConnCounterDisabled(t *testing.T) {
+	c := ipConnCounter{ipv6PrefixLength: 128}
+	c.initialize()
+
+	for i := 0; i < 100; i++ {
+		require.True(t, c.add(net.ParseIP("192.168.1.1")))
+	}
+}
@@ -0,0 +1,56 @@
+package srt
+
+import (
+	"time"
+
+	srt "github.com/datarhei/gosrt"
+)
+
+const (
+	linkAlarmEvaluateInterval = 1 * time.Second
+
+	// an alarm is cleared only when all values fall below
+	// this fraction of their thresholds, in order to avoid flapping.
+	linkAlarmClearRatio = 0.8
+)
+
+// lossRate returns the instantaneous packet loss rate, in percent,
+// in the direction in which media flows.
+func lossRate(state connState, s *srt.Statistics) float64 {
+	if state == connStatePublish {
+		return s.Instantaneous.PktRecvLossRate
+	}
+	return s.Instantaneous.PktSendLossRate
+}
+
+// linkAlarm raises an alarm when the RTT or the loss rate of a link
+// exceed their thresholds. A zero threshold disables the related check.
+type linkAlarm struct {
+	rttThreshold      time.Duration
+	lossRateThreshold float64
+
+	active bool
+}
+
+func (a *linkAlarm) enabled() bool {
+	return a.rttThreshold > 0 || a.lossRateThreshold > 0
+}
+
+// update evaluates the given values and returns true if the state of the alarm has changed.
+func (a *linkAlarm) update(rtt time.Duration, lossRate float64) bool {
+	if !a.active {
+		if (a.rttThreshold > 0 && rtt > a.rttThreshold) ||
+			(a.lossRateThreshold > 0 && lossRate > a.lossRateThreshold) {
+			a.active = true
+			return true
+		}
+		return false
+	}
+
+	if (a.rttThreshold <= 0 || float64(rtt) < float64(a.rttThreshold)*linkAlarmClearRatio) &&
+		(a.lossRateThreshold <= 0 || lossRate < a.lossRateThreshold*linkAlarmClearRatio) {
+		a.active = false
+		return true
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package srt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkAlarm(t *testing.T) {
+	a := &linkAlarm{
+		rttThreshold:      100 * time.Millisecond,
+		lossRateThreshold: 10,
+	}
+
+	for _, ca := range []struct {
+		rtt      time.Duration
+		lossRate float64
+		changed  bool
+		active   bool
+	}{
+		{50 * time.Millisecond, 1, false, false},
+		{150 * time.Millisecond, 1, true, true},
+		{200 * time.Millisecond, 1, false, true},
+		{90 * time.Millisecond, 1, false, true}, // hysteresis
+		{70 * time.Millisecond, 1, true, false},
+		{70 * time.Millisecond, 20, true, true},
+		{70 * time.Millisecond, 9, false, true}, // hysteresis
+		{70 * time.Millisecond, 5, true, false},
+	} {
+		require.Equal(t, ca.changed, a.update(ca.rtt, ca.lossRate))
+		require.Equal(t, ca.active, a.active)
+	}
+}
+
+func TestLinkAlarmDisabledCheck(t *testing.T) {
+	a := &linkAlarm{
+		lossRateThreshold: 10,
+	}
+
+	require.Equal(t, false, a.update(10*time.Second, 1))
+	require.Equal(t, true, a.update(10*time.Second, 20))
+	require.Equal(t, true, a.update(10*time.Second, 1))
+}
@@ -1,14 +1,14 @@
 package srt
 
 import (
-	"sync"
-
 	srt "github.com/datarhei/gosrt"
+
+	"github.com/bluenviron/mediamtx/internal/waitgroup"
 )
 
 type listener struct {
 	ln     srt.Listener
-	wg     *sync.WaitGroup
+	wg     *waitgroup.WaitGroup
 	parent *Server
 }
 
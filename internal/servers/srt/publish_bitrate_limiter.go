@@ -0,0 +1,87 @@
+package srt
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// window in which received bytes are counted in order to measure the bitrate.
+const publishBitrateWindow = 1 * time.Second
+
+// publishBitrateExceededError is returned when the bitrate of a publisher
+// exceeds maxPublishBitrate for longer than the grace period.
+type publishBitrateExceededError struct {
+	bitrate     uint64
+	maxBitrate  uint64
+	gracePeriod time.Duration
+}
+
+// Error implements the error interface.
+func (e publishBitrateExceededError) Error() string {
+	return fmt.Sprintf("publisher bitrate (%d bit/s) exceeded maxPublishBitrate (%d bit/s) for more than %v",
+		e.bitrate, e.maxBitrate, e.gracePeriod)
+}
+
+// publishBitrateLimiter measures the bitrate of read data
+// and returns an error when the bitrate exceeds maxBitrate for longer than gracePeriod.
+// The bitrate is measured in windows of publishBitrateWindow.
+type publishBitrateLimiter struct {
+	r           io.Reader
+	maxBitrate  uint64
+	gracePeriod time.Duration
+	now         func() time.Time
+
+	windowStart time.Time
+	windowBytes uint64
+
+	// time of the first window in which the bitrate is above maxBitrate.
+	exceededSince time.Time
+}
+
+func (l *publishBitrateLimiter) initialize() {
+	if l.now == nil {
+		l.now = time.Now
+	}
+	l.windowStart = l.now()
+}
+
+// Read implements io.Reader.
+func (l *publishBitrateLimiter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if err != nil {
+		return n, err
+	}
+
+	l.windowBytes += uint64(n)
+
+	now := l.now()
+	elapsed := now.Sub(l.windowStart)
+	if elapsed < publishBitrateWindow {
+		return n, nil
+	}
+
+	bitrate := l.windowBytes * 8 * uint64(time.Second) / uint64(elapsed)
+	windowStart := l.windowStart
+	l.windowStart = now
+	l.windowBytes = 0
+
+	if bitrate <= l.maxBitrate {
+		l.exceededSince = time.Time{}
+		return n, nil
+	}
+
+	if l.exceededSince.IsZero() {
+		l.exceededSince = windowStart
+	}
+
+	if now.Sub(l.exceededSince) > l.gracePeriod {
+		return n, publishBitrateExceededError{
+			bitrate:     bitrate,
+			maxBitrate:  l.maxBitrate,
+			gracePeriod: l.gracePeriod,
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,93 @@
+package srt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// clockedPublisher returns a fixed amount of data for each read
+// and advances a fake clock by a fixed interval.
+type clockedPublisher struct {
+	now      time.Time
+	size     func() int
+	interval time.Duration
+}
+
+func (p *clockedPublisher) Read(buf []byte) (int, error) {
+	p.now = p.now.Add(p.interval)
+	return copy(buf, make([]byte, p.size())), nil
+}
+
+func TestPublishBitrateLimiter(t *testing.T) {
+	for _, ca := range []string{
+		"over cap",
+		"under cap",
+		"burst shorter than grace period",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			start := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			pub := &clockedPublisher{
+				now:      start,
+				interval: time.Millisecond,
+			}
+
+			switch ca {
+			case "over cap":
+				// 7*188 bytes every millisecond, about 10.5 Mbit/s
+				pub.size = func() int { return 7 * 188 }
+
+			case "under cap":
+				// 188 bytes every millisecond, about 1.5 Mbit/s
+				pub.size = func() int { return 188 }
+
+			case "burst shorter than grace period":
+				// over cap during the first 2 seconds only
+				pub.size = func() int {
+					if pub.now.Sub(start) <= 2*time.Second {
+						return 7 * 188
+					}
+					return 188
+				}
+			}
+
+			l := &publishBitrateLimiter{
+				r:           pub,
+				maxBitrate:  5000000,
+				gracePeriod: 3 * time.Second,
+				now:         func() time.Time { return pub.now },
+			}
+			l.initialize()
+
+			buf := make([]byte, 1500)
+			var err error
+
+			for pub.now.Sub(start) < 10*time.Second {
+				_, err = l.Read(buf)
+				if err != nil {
+					break
+				}
+			}
+
+			if ca == "over cap" {
+				var berr publishBitrateExceededError
+				require.ErrorAs(t, err, &berr)
+				require.Equal(t, uint64(5000000), berr.maxBitrate)
+				require.Greater(t, berr.bitrate, uint64(5000000))
+
+				// the publisher is disconnected after the grace period,
+				// as soon as the bitrate of the following window is measured
+				elapsed := pub.now.Sub(start)
+				require.Greater(t, elapsed, 3*time.Second)
+				require.LessOrEqual(t, elapsed, 4*time.Second+time.Millisecond)
+
+				require.EqualError(t, err, "publisher bitrate (10528000 bit/s) exceeded "+
+					"maxPublishBitrate (5000000 bit/s) for more than 3s")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
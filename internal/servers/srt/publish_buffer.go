@@ -0,0 +1,123 @@
+package srt
+
+import (
+	"io"
+	"sync"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// publishBuffer is a bounded buffer placed between a publishing connection
+// and the MPEG-TS demuxer.
+// When the buffer is full, depending on the policy, reading from the connection is
+// suspended (and SRT flow control slows down the publisher) or the oldest data is discarded.
+type publishBuffer struct {
+	r      io.Reader
+	size   int
+	policy conf.SRTPublishBufferPolicy
+	onDrop func()
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	packets [][]byte
+	used    int
+	err     error
+	closed  bool
+}
+
+func (b *publishBuffer) initialize() {
+	b.cond = sync.NewCond(&b.mutex)
+
+	go b.run()
+}
+
+// close stops the buffer. The underlying reader must be closed separately.
+func (b *publishBuffer) close() {
+	b.mutex.Lock()
+	b.closed = true
+	b.mutex.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *publishBuffer) run() {
+	for {
+		// SRT messages never exceed the MTU
+		buf := make([]byte, 1500)
+		n, err := b.r.Read(buf)
+
+		if err != nil {
+			b.mutex.Lock()
+			b.err = err
+			b.mutex.Unlock()
+			b.cond.Broadcast()
+			return
+		}
+
+		if !b.push(buf[:n]) {
+			return
+		}
+	}
+}
+
+func (b *publishBuffer) push(pkt []byte) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// a packet is always accepted when the buffer is empty
+	for len(b.packets) != 0 && (b.used+len(pkt)) > b.size {
+		if b.closed {
+			return false
+		}
+
+		if b.policy == conf.SRTPublishBufferPolicyDropOldest {
+			b.used -= len(b.packets[0])
+			b.packets = b.packets[1:]
+			b.onDrop()
+		} else {
+			b.cond.Wait()
+		}
+	}
+
+	if b.closed {
+		return false
+	}
+
+	b.packets = append(b.packets, pkt)
+	b.used += len(pkt)
+	b.cond.Broadcast()
+
+	return true
+}
+
+// Read implements io.Reader.
+// Packets are returned one at a time, in order to preserve their boundaries.
+func (b *publishBuffer) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for len(b.packets) == 0 {
+		if b.closed {
+			return 0, io.ErrClosedPipe
+		}
+
+		if b.err != nil {
+			return 0, b.err
+		}
+
+		b.cond.Wait()
+	}
+
+	n := copy(p, b.packets[0])
+
+	if n < len(b.packets[0]) {
+		b.packets[0] = b.packets[0][n:]
+	} else {
+		b.packets[0] = nil
+		b.packets = b.packets[1:]
+	}
+
+	b.used -= n
+	b.cond.Broadcast()
+
+	return n, nil
+}
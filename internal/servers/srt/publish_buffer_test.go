@@ -0,0 +1,82 @@
+package srt
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+)
+
+// fastPublisher returns packets as fast as possible.
+type fastPublisher struct {
+	count   uint64
+	stopped uint32
+}
+
+func (p *fastPublisher) Read(buf []byte) (int, error) {
+	if atomic.LoadUint32(&p.stopped) == 1 {
+		return 0, io.EOF
+	}
+
+	atomic.AddUint64(&p.count, 1)
+	return copy(buf, make([]byte, 7*188)), nil
+}
+
+func TestPublishBuffer(t *testing.T) {
+	for _, ca := range []string{
+		"block",
+		"drop oldest",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			pub := &fastPublisher{}
+			defer atomic.StoreUint32(&pub.stopped, 1)
+
+			var dropped uint64
+
+			b := &publishBuffer{
+				r:    pub,
+				size: 10 * 7 * 188,
+				policy: func() conf.SRTPublishBufferPolicy {
+					if ca == "block" {
+						return conf.SRTPublishBufferPolicyBlock
+					}
+					return conf.SRTPublishBufferPolicyDropOldest
+				}(),
+				onDrop: func() {
+					atomic.AddUint64(&dropped, 1)
+				},
+			}
+			b.initialize()
+			defer b.close()
+
+			buf := make([]byte, 1500)
+
+			// the consumer is slower than the publisher
+			for i := 0; i < 20; i++ {
+				time.Sleep(5 * time.Millisecond)
+
+				b.mutex.Lock()
+				used := b.used
+				b.mutex.Unlock()
+				require.LessOrEqual(t, used, b.size)
+
+				n, err := b.Read(buf)
+				require.NoError(t, err)
+				require.Equal(t, 7*188, n)
+			}
+
+			if ca == "block" {
+				// reading from the publisher is suspended
+				// until there's space in the buffer
+				require.LessOrEqual(t, atomic.LoadUint64(&pub.count), uint64(20+10+1))
+				require.Equal(t, uint64(0), atomic.LoadUint64(&dropped))
+			} else {
+				require.Greater(t, atomic.LoadUint64(&dropped), uint64(0))
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package srt
+
+import (
+	"strconv"
+	"sync"
+
+	srt "github.com/datarhei/gosrt"
+)
+
+func rejectionReasonName(reason srt.RejectionReason) string {
+	switch reason {
+	case srt.REJ_PEER:
+		return "peer"
+	case srt.REJ_CLOSE:
+		return "close"
+	case srt.REJ_BACKLOG:
+		return "backlog"
+	case srt.REJ_UNSECURE:
+		return "unsecure"
+	case srt.REJX_FORBIDDEN:
+		return "forbidden"
+	case srt.REJX_BAD_MODE:
+		return "bad_mode"
+	case srt.REJX_OVERLOAD:
+		return "overload"
+	}
+	return strconv.FormatUint(uint64(reason), 10)
+}
+
+// rejectionCounter counts rejected connections by reason.
+// It is shared between the server and connections.
+type rejectionCounter struct {
+	mutex  sync.Mutex
+	counts map[string]uint64
+}
+
+func (rc *rejectionCounter) add(reason srt.RejectionReason) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if rc.counts == nil {
+		rc.counts = make(map[string]uint64)
+	}
+	rc.counts[rejectionReasonName(reason)]++
+}
+
+func (rc *rejectionCounter) apiItem() map[string]uint64 {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	ret := make(map[string]uint64, len(rc.counts))
+	for k, v := range rc.counts {
+		ret[k] = v
+	}
+	return ret
+}
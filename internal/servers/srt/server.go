@@ -5,8 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"sort"
-	"sync"
 	"time"
 
 	srt "github.com/datarhei/gosrt"
@@ -17,11 +17,18 @@ import (
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
 	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/waitgroup"
 )
 
 // ErrConnNotFound is returned when a connection is not found.
 var ErrConnNotFound = errors.New("connection not found")
 
+func emptyTimer() *time.Timer {
+	t := time.NewTimer(0)
+	<-t.C
+	return t
+}
+
 func srtMaxPayloadSize(u int) int {
 	return ((u - 16) / 188) * 188 // 16 = SRT header, 188 = MPEG-TS packet
 }
@@ -54,6 +61,28 @@ type serverAPIConnsKickReq struct {
 	res  chan serverAPIConnsKickRes
 }
 
+type serverAPIIPsListRes struct {
+	data *defs.APISRTIPList
+	err  error
+}
+
+type serverAPIIPsListReq struct {
+	res chan serverAPIIPsListRes
+}
+
+type serverAPIListenerStatsRes struct {
+	data *defs.APISRTListenerStats
+	err  error
+}
+
+type serverAPIListenerStatsReq struct {
+	res chan serverAPIListenerStatsRes
+}
+
+type serverDrainReq struct {
+	res chan struct{}
+}
+
 type serverPathManager interface {
 	AddPublisher(req defs.PathAddPublisherReq) (defs.Path, error)
 	AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.Stream, error)
@@ -65,41 +94,67 @@ type serverParent interface {
 
 // Server is a SRT server.
 type Server struct {
-	Address             string
-	RTSPAddress         string
-	ReadTimeout         conf.StringDuration
-	WriteTimeout        conf.StringDuration
-	UDPMaxPayloadSize   int
-	RunOnConnect        string
-	RunOnConnectRestart bool
-	RunOnDisconnect     string
-	ExternalCmdPool     *externalcmd.Pool
-	PathManager         serverPathManager
-	Parent              serverParent
+	Address              string
+	DrainTimeout         conf.StringDuration
+	BitrateWindow        conf.StringDuration
+	PublishNamespace     string
+	AutoCreatePaths      bool
+	PublishBufferPolicy  conf.SRTPublishBufferPolicy
+	PublishBufferSize    conf.StringSize
+	PublishStartTimeout  conf.StringDuration
+	AlarmRTT             conf.StringDuration
+	AlarmLossRate        float64
+	HandshakeTimeout     conf.StringDuration
+	HandshakeRateLimit   int
+	MaxPendingHandshakes int
+	MaxConnsPerIP        int
+	IPv6PrefixLength     int
+	RTSPAddress          string
+	ReadTimeout          conf.StringDuration
+	WriteTimeout         conf.StringDuration
+	UDPMaxPayloadSize    int
+	UDPRecvBufferSize    conf.StringSize
+	UDPSendBufferSize    conf.StringSize
+	TTL                  int
+	RunOnConnect         string
+	RunOnConnectRestart  bool
+	RunOnDisconnect      string
+	ExternalCmdPool      *externalcmd.Pool
+	PathManager          serverPathManager
+	Parent               serverParent
 
 	ctx       context.Context
 	ctxCancel func()
-	wg        sync.WaitGroup
+	wg        waitgroup.WaitGroup
 	ln        srt.Listener
 	conns     map[*conn]struct{}
+	pending   map[*conn]struct{}
+	limiter   handshakeLimiter
+	ipConns   ipConnCounter
+	draining  bool
+	drainRes  chan struct{}
+
+	rejections          rejectionCounter
+	closedBytesReceived uint64
+	closedBytesSent     uint64
 
 	// in
 	chNewConnRequest chan srt.ConnRequest
 	chAcceptErr      chan error
 	chCloseConn      chan *conn
+	chHandshakeDone  chan *conn
 	chAPIConnsList   chan serverAPIConnsListReq
 	chAPIConnsGet    chan serverAPIConnsGetReq
 	chAPIConnsKick   chan serverAPIConnsKickReq
+	chAPIIPsList     chan serverAPIIPsListReq
+	chAPIStats       chan serverAPIListenerStatsReq
+	chDrain          chan serverDrainReq
 }
 
 // Initialize initializes the server.
 func (s *Server) Initialize() error {
-	conf := srt.DefaultConfig()
-	conf.ConnectionTimeout = time.Duration(s.ReadTimeout)
-	conf.PayloadSize = uint32(srtMaxPayloadSize(s.UDPMaxPayloadSize))
-
 	var err error
-	s.ln, err = srt.Listen("srt", s.Address, conf)
+	s.ln, err = srt.Listen("srt", s.Address, s.srtConfig())
 	if err != nil {
 		return err
 	}
@@ -107,12 +162,23 @@ func (s *Server) Initialize() error {
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 
 	s.conns = make(map[*conn]struct{})
+	s.pending = make(map[*conn]struct{})
+	s.limiter = handshakeLimiter{rate: s.HandshakeRateLimit}
+	s.ipConns = ipConnCounter{
+		max:              s.MaxConnsPerIP,
+		ipv6PrefixLength: s.IPv6PrefixLength,
+	}
+	s.ipConns.initialize()
 	s.chNewConnRequest = make(chan srt.ConnRequest)
 	s.chAcceptErr = make(chan error)
 	s.chCloseConn = make(chan *conn)
+	s.chHandshakeDone = make(chan *conn)
 	s.chAPIConnsList = make(chan serverAPIConnsListReq)
 	s.chAPIConnsGet = make(chan serverAPIConnsGetReq)
 	s.chAPIConnsKick = make(chan serverAPIConnsKickReq)
+	s.chAPIIPsList = make(chan serverAPIIPsListReq)
+	s.chAPIStats = make(chan serverAPIListenerStatsReq)
+	s.chDrain = make(chan serverDrainReq)
 
 	s.Log(logger.Info, "listener opened on "+s.Address+" (UDP)")
 
@@ -129,6 +195,40 @@ func (s *Server) Initialize() error {
 	return nil
 }
 
+func (s *Server) srtConfig() srt.Config {
+	// timestamp-based packet delivery (TSBPD) is always enabled, since the SRT library
+	// doesn't support disabling it, nor changing latency of single connections.
+	conf := srt.DefaultConfig()
+	conf.ConnectionTimeout = time.Duration(s.ReadTimeout)
+	conf.PayloadSize = uint32(srtMaxPayloadSize(s.UDPMaxPayloadSize))
+
+	recvLimit, sendLimit := osBufferLimits()
+	conf.ReceiverBufferSize = s.clampBufferSize("receive", s.UDPRecvBufferSize, recvLimit)
+	conf.SendBufferSize = s.clampBufferSize("send", s.UDPSendBufferSize, sendLimit)
+
+	// the UDP socket is shared by all connections, therefore the TTL is applied to all of them.
+	conf.IPTTL = s.TTL
+
+	return conf
+}
+
+// clampBufferSize limits a buffer size to the maximum allowed by the OS,
+// that would otherwise limit it silently, and logs the effective value.
+func (s *Server) clampBufferSize(kind string, size conf.StringSize, limit int) uint32 {
+	if size == 0 {
+		return 0
+	}
+
+	if limit != 0 && size > conf.StringSize(limit) {
+		s.Log(logger.Warn, "UDP %s buffer size has been limited by the OS to %d bytes (requested %d)",
+			kind, limit, size)
+		return uint32(limit)
+	}
+
+	s.Log(logger.Info, "UDP %s buffer size is %d bytes", kind, size)
+	return uint32(size)
+}
+
 // Log implements logger.Writer.
 func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
 	s.Parent.Log(level, "[SRT] "+format, args...)
@@ -137,13 +237,33 @@ func (s *Server) Log(level logger.Level, format string, args ...interface{}) {
 // Close closes the server.
 func (s *Server) Close() {
 	s.Log(logger.Info, "listener is closing")
+
+	if s.DrainTimeout > 0 {
+		s.drain()
+	}
+
 	s.ctxCancel()
 	s.wg.Wait()
 }
 
+func (s *Server) drain() {
+	req := serverDrainReq{
+		res: make(chan struct{}),
+	}
+
+	select {
+	case s.chDrain <- req:
+		<-req.res
+	case <-s.ctx.Done():
+	}
+}
+
 func (s *Server) run() {
 	defer s.wg.Done()
 
+	drainTimer := emptyTimer()
+	defer drainTimer.Stop()
+
 outer:
 	for {
 		select {
@@ -152,12 +272,45 @@ outer:
 			break outer
 
 		case req := <-s.chNewConnRequest:
+			if s.draining {
+				s.reject(req, srt.REJ_CLOSE)
+				continue
+			}
+
+			// excess handshakes are shed before allocating any resource
+			if !s.limiter.allow(time.Now()) {
+				s.Log(logger.Debug, "handshake from %v rejected: rate limit exceeded", req.RemoteAddr())
+				s.reject(req, srt.REJ_BACKLOG)
+				continue
+			}
+
+			if s.MaxPendingHandshakes != 0 && len(s.pending) >= s.MaxPendingHandshakes {
+				s.Log(logger.Debug, "handshake from %v rejected: too many pending handshakes", req.RemoteAddr())
+				s.reject(req, srt.REJ_BACKLOG)
+				continue
+			}
+
+			if !s.ipConns.add(req.RemoteAddr().(*net.UDPAddr).IP) {
+				s.Log(logger.Debug, "handshake from %v rejected: too many connections from the same IP", req.RemoteAddr())
+				s.reject(req, srt.REJX_OVERLOAD)
+				continue
+			}
+
 			c := &conn{
 				parentCtx:           s.ctx,
 				rtspAddress:         s.RTSPAddress,
 				readTimeout:         s.ReadTimeout,
 				writeTimeout:        s.WriteTimeout,
 				udpMaxPayloadSize:   s.UDPMaxPayloadSize,
+				bitrateWindow:       s.BitrateWindow,
+				publishNamespace:    s.PublishNamespace,
+				autoCreatePaths:     s.AutoCreatePaths,
+				publishBufferPolicy: s.PublishBufferPolicy,
+				publishBufferSize:   s.PublishBufferSize,
+				publishStartTimeout: s.PublishStartTimeout,
+				alarmRTT:            s.AlarmRTT,
+				alarmLossRate:       s.AlarmLossRate,
+				handshakeTimeout:    s.HandshakeTimeout,
 				connReq:             req,
 				runOnConnect:        s.RunOnConnect,
 				runOnConnectRestart: s.RunOnConnectRestart,
@@ -169,9 +322,37 @@ outer:
 			}
 			c.initialize()
 			s.conns[c] = struct{}{}
+			s.pending[c] = struct{}{}
+
+		case c := <-s.chHandshakeDone:
+			delete(s.pending, c)
 
 		case c := <-s.chCloseConn:
-			delete(s.conns, c)
+			s.removeConn(c)
+
+			if s.draining {
+				s.checkDrained()
+			}
+
+		case req := <-s.chDrain:
+			s.Log(logger.Info, "draining connections")
+
+			s.draining = true
+			s.drainRes = req.res
+			drainTimer = time.NewTimer(time.Duration(s.DrainTimeout))
+
+			for c := range s.conns {
+				c.drain()
+			}
+
+			s.checkDrained()
+
+		case <-drainTimer.C:
+			if s.drainRes != nil {
+				s.Log(logger.Warn, "drain timeout reached, closing remaining connections")
+				close(s.drainRes)
+				s.drainRes = nil
+			}
 
 		case req := <-s.chAPIConnsList:
 			data := &defs.APISRTConnList{
@@ -204,10 +385,38 @@ outer:
 				continue
 			}
 
-			delete(s.conns, c)
+			s.removeConn(c)
 			c.Close()
 			req.res <- serverAPIConnsKickRes{}
 
+		case req := <-s.chAPIIPsList:
+			req.res <- serverAPIIPsListRes{
+				data: &defs.APISRTIPList{
+					Items: s.ipConns.apiItems(),
+				},
+			}
+
+		case req := <-s.chAPIStats:
+			data := &defs.APISRTListenerStats{
+				Conns: map[defs.APISRTConnState]int{
+					defs.APISRTConnStateIdle:    0,
+					defs.APISRTConnStateRead:    0,
+					defs.APISRTConnStatePublish: 0,
+				},
+				BytesReceived: s.closedBytesReceived,
+				BytesSent:     s.closedBytesSent,
+				Rejections:    s.rejections.apiItem(),
+			}
+
+			for c := range s.conns {
+				item := c.apiItem()
+				data.Conns[item.State]++
+				data.BytesReceived += item.BytesReceived
+				data.BytesSent += item.BytesSent
+			}
+
+			req.res <- serverAPIListenerStatsRes{data: data}
+
 		case <-s.ctx.Done():
 			break outer
 		}
@@ -218,6 +427,63 @@ outer:
 	s.ln.Close()
 }
 
+// checkDrained closes publishers when there are no readers left,
+// since readers need data of publishers in order to receive the rest of the current GOP,
+// and signals the end of the drain period when publishers are closed too.
+// Publishers are removed from their paths before being closed,
+// therefore the current segment of recordings is finalized within the drain period.
+func (s *Server) checkDrained() {
+	if s.drainRes == nil {
+		return
+	}
+
+	for c := range s.conns {
+		if c.currentState() == connStateRead {
+			return
+		}
+	}
+
+	publishing := false
+
+	for c := range s.conns {
+		if c.currentState() == connStatePublish {
+			c.endDrain()
+			publishing = true
+		}
+	}
+
+	if publishing {
+		return
+	}
+
+	close(s.drainRes)
+	s.drainRes = nil
+}
+
+// removeConn removes a connection.
+// It can be called more than once with the same connection,
+// since kicked connections are removed before being closed.
+func (s *Server) removeConn(c *conn) {
+	if _, ok := s.conns[c]; !ok {
+		return
+	}
+
+	// bytes of closed connections are kept in listener statistics
+	item := c.apiItem()
+	s.closedBytesReceived += item.BytesReceived
+	s.closedBytesSent += item.BytesSent
+
+	delete(s.conns, c)
+	delete(s.pending, c)
+	s.ipConns.remove(c.ip())
+}
+
+// reject rejects a connection request and counts the rejection.
+func (s *Server) reject(req srt.ConnRequest, reason srt.RejectionReason) {
+	s.rejections.add(reason)
+	req.Reject(reason)
+}
+
 func (s *Server) findConnByUUID(uuid uuid.UUID) *conn {
 	for sx := range s.conns {
 		if sx.uuid == uuid {
@@ -244,6 +510,14 @@ func (s *Server) acceptError(err error) {
 	}
 }
 
+// handshakeDone is called by conn.
+func (s *Server) handshakeDone(c *conn) {
+	select {
+	case s.chHandshakeDone <- c:
+	case <-s.ctx.Done():
+	}
+}
+
 // closeConn is called by conn.
 func (s *Server) closeConn(c *conn) {
 	select {
@@ -301,3 +575,40 @@ func (s *Server) APIConnsKick(uuid uuid.UUID) error {
 		return fmt.Errorf("terminated")
 	}
 }
+
+// APIIPsList is called by api.
+func (s *Server) APIIPsList() (*defs.APISRTIPList, error) {
+	req := serverAPIIPsListReq{
+		res: make(chan serverAPIIPsListRes),
+	}
+
+	select {
+	case s.chAPIIPsList <- req:
+		res := <-req.res
+		return res.data, res.err
+
+	case <-s.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIListenerStats is called by api.
+func (s *Server) APIListenerStats() (*defs.APISRTListenerStats, error) {
+	req := serverAPIListenerStatsReq{
+		res: make(chan serverAPIListenerStatsRes),
+	}
+
+	select {
+	case s.chAPIStats <- req:
+		res := <-req.res
+		return res.data, res.err
+
+	case <-s.ctx.Done():
+		return nil, fmt.Errorf("terminated")
+	}
+}
+
+// APIWaitGroupSize is called by api.
+func (s *Server) APIWaitGroupSize() int {
+	return s.wg.Size()
+}
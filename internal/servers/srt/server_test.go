@@ -2,23 +2,37 @@ package srt
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
 	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
 	"github.com/bluenviron/mediamtx/internal/auth"
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/stream"
 	"github.com/bluenviron/mediamtx/internal/test"
 	"github.com/bluenviron/mediamtx/internal/unit"
 	srt "github.com/datarhei/gosrt"
+	"github.com/datarhei/gosrt/packet"
 	"github.com/stretchr/testify/require"
 )
 
 type dummyPath struct {
+	conf          *conf.Path
 	stream        *stream.Stream
 	streamCreated chan struct{}
 }
@@ -28,6 +42,9 @@ func (p *dummyPath) Name() string {
 }
 
 func (p *dummyPath) SafeConf() *conf.Path {
+	if p.conf != nil {
+		return p.conf
+	}
 	return &conf.Path{}
 }
 
@@ -42,6 +59,9 @@ func (p *dummyPath) StartPublisher(req defs.PathStartPublisherReq) (*stream.Stre
 		1460,
 		req.Desc,
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	if err != nil {
@@ -60,20 +80,55 @@ func (p *dummyPath) RemovePublisher(_ defs.PathRemovePublisherReq) {
 func (p *dummyPath) RemoveReader(_ defs.PathRemoveReaderReq) {
 }
 
+func (p *dummyPath) AddEvent(_ logger.Level, _ string, _ ...interface{}) {
+}
+
+func (p *dummyPath) AddDecodeError(_ string) {
+}
+
 type dummyPathManager struct {
 	path *dummyPath
+
+	// users that are accepted in addition to myuser, with the same password
+	users []string
+
+	// whether readers are rejected when there's no stream, like the path manager does
+	noOnePublishing bool
+}
+
+func (pm *dummyPathManager) authenticate(req defs.PathAccessRequest) error {
+	if (req.User != "myuser" && !slices.Contains(pm.users, req.User)) || req.Pass != "mypass" {
+		return &auth.Error{}
+	}
+	return nil
 }
 
 func (pm *dummyPathManager) AddPublisher(req defs.PathAddPublisherReq) (defs.Path, error) {
-	if req.AccessRequest.User != "myuser" || req.AccessRequest.Pass != "mypass" {
-		return nil, &auth.Error{}
+	if req.CheckConf != nil && pm.path != nil {
+		if err := req.CheckConf(pm.path.SafeConf()); err != nil {
+			return nil, err
+		}
+	}
+	if err := pm.authenticate(req.AccessRequest); err != nil {
+		return nil, err
+	}
+	if pm.path != nil && pm.path.conf != nil && pm.path.conf.Source != "" && pm.path.conf.Source != "publisher" {
+		return nil, fmt.Errorf("can't publish to path '%s' since 'source' is not 'publisher'", req.AccessRequest.Name)
 	}
 	return pm.path, nil
 }
 
 func (pm *dummyPathManager) AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
-	if req.AccessRequest.User != "myuser" || req.AccessRequest.Pass != "mypass" {
-		return nil, nil, &auth.Error{}
+	if req.CheckConf != nil && pm.path != nil {
+		if err := req.CheckConf(pm.path.SafeConf()); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := pm.authenticate(req.AccessRequest); err != nil {
+		return nil, nil, err
+	}
+	if pm.noOnePublishing && pm.path.stream == nil {
+		return nil, nil, defs.PathNoOnePublishingError{PathName: req.AccessRequest.Name}
 	}
 	return pm.path, pm.path.stream, nil
 }
@@ -170,6 +225,93 @@ func TestServerPublish(t *testing.T) {
 	<-recv
 }
 
+func TestServerPublishDump(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-srt-dump")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	externalCmdPool := externalcmd.NewPool()
+	defer externalCmdPool.Close()
+
+	path := &dummyPath{
+		conf: &conf.Path{
+			SRTDebugDumpPath:        filepath.Join(dir, "%path_%f"),
+			SRTDebugDumpMaxSize:     1024 * 1024,
+			SRTDebugDumpMaxDuration: conf.StringDuration(60 * time.Second),
+		},
+		streamCreated: make(chan struct{}),
+	}
+
+	pathManager := &dummyPathManager{path: path}
+
+	s := &Server{
+		Address:           "127.0.0.1:8890",
+		ReadTimeout:       conf.StringDuration(10 * time.Second),
+		WriteTimeout:      conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize: 1472,
+		ExternalCmdPool:   externalCmdPool,
+		PathManager:       pathManager,
+		Parent:            test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	// the dump is requested through the stream ID
+	u := "srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass:dump=1"
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL(u)
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	publisher, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+
+	track := &mpegts.Track{
+		Codec: &mpegts.CodecH264{},
+	}
+
+	var sent bytes.Buffer
+	bw := bufio.NewWriter(io.MultiWriter(publisher, &sent))
+	w := mpegts.NewWriter(bw, []*mpegts.Track{track})
+
+	for i := 0; i < 3; i++ {
+		err = w.WriteH264(track, int64(i)*90000, int64(i)*90000, true, [][]byte{
+			test.FormatH264.SPS,
+			test.FormatH264.PPS,
+			{0x05, byte(i)}, // IDR
+		})
+		require.NoError(t, err)
+
+		err = bw.Flush()
+		require.NoError(t, err)
+	}
+
+	<-path.streamCreated
+
+	var dumpPath string
+
+	require.Eventually(t, func() bool {
+		files, err2 := filepath.Glob(filepath.Join(dir, "mypath_*.ts"))
+		if err2 != nil || len(files) != 1 {
+			return false
+		}
+		dumpPath = files[0]
+
+		byts, err2 := os.ReadFile(dumpPath)
+		return err2 == nil && len(byts) == sent.Len()
+	}, 5*time.Second, 50*time.Millisecond)
+
+	publisher.Close()
+
+	byts, err := os.ReadFile(dumpPath)
+	require.NoError(t, err)
+	require.Equal(t, sent.Bytes(), byts)
+}
+
 func TestServerRead(t *testing.T) {
 	externalCmdPool := externalcmd.NewPool()
 	defer externalCmdPool.Close()
@@ -181,6 +323,9 @@ func TestServerRead(t *testing.T) {
 		1460,
 		desc,
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	require.NoError(t, err)
@@ -269,3 +414,1587 @@ func TestServerRead(t *testing.T) {
 		}
 	}
 }
+
+func TestServerReadWarmup(t *testing.T) {
+	externalCmdPool := externalcmd.NewPool()
+	defer externalCmdPool.Close()
+
+	desc := &description.Session{Medias: []*description.Media{
+		test.UniqueMediaH264(),
+		test.UniqueMediaMPEG4Audio(),
+	}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{
+		conf: &conf.Path{
+			SRTReadWarmup:        true,
+			SRTReadWarmupTimeout: conf.StringDuration(10 * time.Second),
+		},
+		stream: stream,
+	}
+
+	pathManager := &dummyPathManager{path: path}
+
+	s := &Server{
+		Address:           "127.0.0.1:8890",
+		ReadTimeout:       conf.StringDuration(10 * time.Second),
+		WriteTimeout:      conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize: 1472,
+		ExternalCmdPool:   externalCmdPool,
+		PathManager:       pathManager,
+		Parent:            test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	u := "srt://127.0.0.1:8890?streamid=read:mypath:myuser:mypass"
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL(u)
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	reader, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	// units received before the first key frame are not sent to the reader
+
+	stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.MPEG4Audio{
+		Base: unit.Base{
+			PTS: 0,
+		},
+		AUs: [][]byte{{1, 2}},
+	})
+
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		Base: unit.Base{
+			PTS: 0,
+		},
+		AU: [][]byte{
+			{1, 1}, // non-IDR
+		},
+	})
+
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		Base: unit.Base{
+			PTS: 90000,
+		},
+		AU: [][]byte{
+			{5, 1}, // IDR
+		},
+	})
+
+	stream.WaitRunningReader()
+
+	stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.MPEG4Audio{
+		Base: unit.Base{
+			PTS: 2 * 44100,
+		},
+		AUs: [][]byte{{3, 4}},
+	})
+
+	stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.MPEG4Audio{
+		Base: unit.Base{
+			PTS: 3 * 44100,
+		},
+		AUs: [][]byte{{5, 6}},
+	})
+
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		Base: unit.Base{
+			PTS: 180000,
+		},
+		AU: [][]byte{
+			{1, 2}, // non-IDR
+		},
+	})
+
+	r, err := mpegts.NewReader(reader)
+	require.NoError(t, err)
+	require.Len(t, r.Tracks(), 2)
+
+	var videoPTS []int64
+	var audioPTS []int64
+
+	r.OnDataH264(r.Tracks()[0], func(pts int64, _ int64, au [][]byte) error {
+		if videoPTS == nil {
+			require.Equal(t, [][]byte{
+				test.FormatH264.SPS,
+				test.FormatH264.PPS,
+				{5, 1},
+			}, au)
+		}
+		videoPTS = append(videoPTS, pts)
+		return nil
+	})
+
+	r.OnDataMPEG4Audio(r.Tracks()[1], func(pts int64, aus [][]byte) error {
+		audioPTS = append(audioPTS, pts)
+		return nil
+	})
+
+	for audioPTS == nil || videoPTS == nil {
+		err = r.Read()
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, int64(90000), videoPTS[0])
+	require.Equal(t, int64(180000), audioPTS[0])
+}
+
+func TestServerReadProgram(t *testing.T) {
+	externalCmdPool := externalcmd.NewPool()
+	defer externalCmdPool.Close()
+
+	desc := &description.Session{Medias: []*description.Media{
+		test.MediaH264,
+		{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{test.FormatH265},
+		},
+	}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{stream: stream}
+
+	pathManager := &dummyPathManager{path: path}
+
+	s := &Server{
+		Address:             "127.0.0.1:8890",
+		RTSPAddress:         "",
+		ReadTimeout:         conf.StringDuration(10 * time.Second),
+		WriteTimeout:        conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize:   1472,
+		RunOnConnect:        "",
+		RunOnConnectRestart: false,
+		RunOnDisconnect:     "string",
+		ExternalCmdPool:     externalCmdPool,
+		PathManager:         pathManager,
+		Parent:              test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	u := "srt://127.0.0.1:8890?streamid=read:mypath:myuser:mypass:prog=2"
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL(u)
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	reader, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	stream.WaitRunningReader()
+
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		AU: [][]byte{
+			{5, 1}, // IDR
+		},
+	})
+
+	stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.H265{
+		AU: [][]byte{
+			{byte(h265.NALUType_CRA_NUT) << 1, 0}, // CRA
+		},
+	})
+
+	r, err := mpegts.NewReader(reader)
+	require.NoError(t, err)
+
+	require.Equal(t, []*mpegts.Track{{
+		PID:   256,
+		Codec: &mpegts.CodecH265{},
+	}}, r.Tracks())
+
+	received := false
+
+	r.OnDataH265(r.Tracks()[0], func(_ int64, _ int64, au [][]byte) error {
+		require.Equal(t, []byte{byte(h265.NALUType_CRA_NUT) << 1, 0}, au[len(au)-1])
+		received = true
+		return nil
+	})
+
+	stream.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.H265{
+		AU: [][]byte{
+			{byte(h265.NALUType_CRA_NUT) << 1, 1}, // CRA
+		},
+	})
+
+	for {
+		err = r.Read()
+		require.NoError(t, err)
+		if received {
+			break
+		}
+	}
+}
+
+func TestServerReadDrain(t *testing.T) {
+	externalCmdPool := externalcmd.NewPool()
+	defer externalCmdPool.Close()
+
+	desc := &description.Session{Medias: []*description.Media{test.MediaH264}}
+
+	stream, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{stream: stream}
+
+	pathManager := &dummyPathManager{path: path}
+
+	s := &Server{
+		Address:             "127.0.0.1:8890",
+		DrainTimeout:        conf.StringDuration(10 * time.Second),
+		RTSPAddress:         "",
+		ReadTimeout:         conf.StringDuration(10 * time.Second),
+		WriteTimeout:        conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize:   1472,
+		RunOnConnect:        "",
+		RunOnConnectRestart: false,
+		RunOnDisconnect:     "string",
+		ExternalCmdPool:     externalCmdPool,
+		PathManager:         pathManager,
+		Parent:              test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+
+	u := "srt://127.0.0.1:8890?streamid=read:mypath:myuser:mypass"
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL(u)
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	reader, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	stream.WaitRunningReader()
+
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		AU: [][]byte{
+			{5, 1}, // IDR
+		},
+	})
+
+	r, err := mpegts.NewReader(reader)
+	require.NoError(t, err)
+
+	var received [][][]byte
+	recvDone := make(chan struct{})
+
+	r.OnDataH264(r.Tracks()[0], func(_ int64, _ int64, au [][]byte) error {
+		received = append(received, au)
+		return nil
+	})
+
+	go func() {
+		defer close(recvDone)
+		for {
+			err2 := r.Read()
+			if err2 != nil {
+				return
+			}
+		}
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+
+	// wait for the drain to start
+	time.Sleep(500 * time.Millisecond)
+
+	// this belongs to the current GOP and must be delivered
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		Base: unit.Base{
+			PTS: 90000,
+		},
+		AU: [][]byte{
+			{1, 2}, // non-IDR
+		},
+	})
+
+	// this belongs to the current GOP too
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		Base: unit.Base{
+			PTS: 135000,
+		},
+		AU: [][]byte{
+			{1, 3}, // non-IDR
+		},
+	})
+
+	// this starts a new GOP and must cause the connection to be closed
+	stream.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+		Base: unit.Base{
+			PTS: 180000,
+		},
+		AU: [][]byte{
+			{5, 3}, // IDR
+		},
+	})
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("drain did not end")
+	}
+
+	<-recvDone
+
+	require.Equal(t, [][][]byte{
+		{
+			test.FormatH264.SPS,
+			test.FormatH264.PPS,
+			{5, 1},
+		},
+		{
+			{1, 2},
+		},
+		{
+			{1, 3},
+		},
+	}, received)
+}
+
+type slowRemovalPath struct {
+	*dummyPath
+	removed chan struct{}
+}
+
+func (p *slowRemovalPath) RemovePublisher(_ defs.PathRemovePublisherReq) {
+	// simulate the finalization of the current recording segment
+	time.Sleep(500 * time.Millisecond)
+	close(p.removed)
+}
+
+type slowRemovalPathManager struct {
+	dummyPathManager
+	path *slowRemovalPath
+}
+
+func (pm *slowRemovalPathManager) AddPublisher(req defs.PathAddPublisherReq) (defs.Path, error) {
+	_, err := pm.dummyPathManager.AddPublisher(req)
+	if err != nil {
+		return nil, err
+	}
+	return pm.path, nil
+}
+
+func TestServerPublishDrain(t *testing.T) {
+	path := &slowRemovalPath{
+		dummyPath: &dummyPath{
+			streamCreated: make(chan struct{}),
+		},
+		removed: make(chan struct{}),
+	}
+
+	pathManager := &slowRemovalPathManager{
+		dummyPathManager: dummyPathManager{path: path.dummyPath},
+		path:             path,
+	}
+
+	closedLogs := make(chan string, 2)
+
+	s := &Server{
+		Address:           "127.0.0.1:8890",
+		DrainTimeout:      conf.StringDuration(10 * time.Second),
+		ReadTimeout:       conf.StringDuration(10 * time.Second),
+		WriteTimeout:      conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize: 1472,
+		PathManager:       pathManager,
+		Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+			msg := fmt.Sprintf(format, args...)
+			if strings.Contains(msg, "closed: ") {
+				closedLogs <- msg
+			}
+		}),
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+
+	dial := func(u string) srt.Conn {
+		srtConf := srt.DefaultConfig()
+		address, err2 := srtConf.UnmarshalURL(u)
+		require.NoError(t, err2)
+
+		err2 = srtConf.Validate()
+		require.NoError(t, err2)
+
+		conn, err2 := srt.Dial("srt", address, srtConf)
+		require.NoError(t, err2)
+		return conn
+	}
+
+	publisher := dial("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass")
+	defer publisher.Close()
+
+	track := &mpegts.Track{
+		Codec: &mpegts.CodecH264{},
+	}
+
+	bw := bufio.NewWriter(publisher)
+	w := mpegts.NewWriter(bw, []*mpegts.Track{track})
+
+	writeAU := func(pts int64, au [][]byte) {
+		err2 := w.WriteH264(track, pts, pts, h264.IDRPresent(au), au)
+		require.NoError(t, err2)
+
+		err2 = bw.Flush()
+		require.NoError(t, err2)
+	}
+
+	writeAU(0, [][]byte{
+		test.FormatH264.SPS,
+		test.FormatH264.PPS,
+		{5, 1}, // IDR
+	})
+
+	<-path.streamCreated
+
+	reader := dial("srt://127.0.0.1:8890?streamid=read:mypath:myuser:mypass")
+	defer reader.Close()
+
+	path.stream.WaitRunningReader()
+
+	// access units are decoded by the server when the next one is received
+	writeAU(90000, [][]byte{
+		{1, 2}, // non-IDR
+	})
+
+	r, err := mpegts.NewReader(reader)
+	require.NoError(t, err)
+
+	var received [][][]byte
+	recvDone := make(chan struct{})
+
+	r.OnDataH264(r.Tracks()[0], func(_ int64, _ int64, au [][]byte) error {
+		received = append(received, au)
+		return nil
+	})
+
+	go func() {
+		defer close(recvDone)
+		for {
+			err2 := r.Read()
+			if err2 != nil {
+				return
+			}
+		}
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+
+	// wait for the drain to start
+	time.Sleep(500 * time.Millisecond)
+
+	// the publisher is not closed until readers have received the current GOP
+	writeAU(135000, [][]byte{
+		{5, 3}, // IDR
+	})
+
+	// this causes the IDR to be decoded, that starts a new GOP
+	// and must cause the reader to be closed
+	writeAU(180000, [][]byte{
+		{1, 4}, // non-IDR
+	})
+
+	<-recvDone
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("drain did not end")
+	}
+
+	// the drain ends after the publisher has been removed from the path
+	select {
+	case <-path.removed:
+	default:
+		t.Errorf("publisher has not been removed")
+	}
+
+	// both the reader and the publisher are closed by the drain
+	for i := 0; i < 2; i++ {
+		require.Contains(t, <-closedLogs, "closed: server is shutting down")
+	}
+
+	require.Equal(t, [][][]byte{
+		{
+			test.FormatH264.SPS,
+			test.FormatH264.PPS,
+			{5, 1},
+		},
+		{
+			{1, 2},
+		},
+	}, received)
+
+	// the publisher connection has been closed by the server
+	_, err = publisher.Read(make([]byte, 1500))
+	require.Error(t, err)
+}
+
+func TestServerHandshakeLog(t *testing.T) {
+	path := &dummyPath{
+		streamCreated: make(chan struct{}),
+	}
+
+	pathManager := &dummyPathManager{path: path}
+
+	handshakeLog := make(chan string, 1)
+
+	s := &Server{
+		Address:             "127.0.0.1:8890",
+		RTSPAddress:         "",
+		ReadTimeout:         conf.StringDuration(10 * time.Second),
+		WriteTimeout:        conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize:   1472,
+		RunOnConnect:        "",
+		RunOnConnectRestart: false,
+		RunOnDisconnect:     "",
+		ExternalCmdPool:     nil,
+		PathManager:         pathManager,
+		Parent: test.Logger(func(level logger.Level, format string, args ...interface{}) {
+			msg := fmt.Sprintf(format, args...)
+			if strings.Contains(msg, "handshake") {
+				require.Equal(t, logger.Debug, level)
+				handshakeLog <- msg
+			}
+		}),
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass")
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	publisher, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	msg := <-handshakeLog
+
+	for _, field := range []string{
+		"version=5",
+		"encrypted=false",
+		"latency=120ms",
+		"peerLatency=120ms",
+		"maxBandwidth=",
+		"mss=1500",
+		"flowWindow=25600",
+	} {
+		require.Contains(t, msg, field)
+	}
+}
+
+func TestServerPublishNamespace(t *testing.T) {
+	for _, ca := range []string{
+		"allowed",
+		"outside namespace",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			path := &dummyPath{
+				streamCreated: make(chan struct{}),
+			}
+
+			pathManager := &dummyPathManager{path: path}
+
+			closed := make(chan string, 1)
+
+			s := &Server{
+				Address:             "127.0.0.1:8890",
+				PublishNamespace:    "%user/",
+				RTSPAddress:         "",
+				ReadTimeout:         conf.StringDuration(10 * time.Second),
+				WriteTimeout:        conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize:   1472,
+				RunOnConnect:        "",
+				RunOnConnectRestart: false,
+				RunOnDisconnect:     "",
+				ExternalCmdPool:     nil,
+				PathManager:         pathManager,
+				Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+					msg := fmt.Sprintf(format, args...)
+					if strings.Contains(msg, "closed:") {
+						closed <- msg
+					}
+				}),
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			var u string
+			if ca == "allowed" {
+				u = "srt://127.0.0.1:8890?streamid=publish:myuser/mypath:myuser:mypass"
+			} else {
+				u = "srt://127.0.0.1:8890?streamid=publish:otheruser/mypath:myuser:mypass"
+			}
+
+			srtConf := srt.DefaultConfig()
+			address, err := srtConf.UnmarshalURL(u)
+			require.NoError(t, err)
+
+			err = srtConf.Validate()
+			require.NoError(t, err)
+
+			publisher, err := srt.Dial("srt", address, srtConf)
+
+			if ca == "allowed" {
+				require.NoError(t, err)
+				defer publisher.Close()
+			} else {
+				require.Error(t, err)
+				require.Contains(t, <-closed, "user 'myuser' is not allowed to publish to path 'otheruser/mypath', "+
+					"since it is outside namespace 'myuser/'")
+			}
+		})
+	}
+}
+
+func TestServerRequireEncryption(t *testing.T) {
+	for _, ca := range []string{
+		"encrypted",
+		"plaintext",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			path := &dummyPath{
+				conf: &conf.Path{
+					SRTRequireEncryption: true,
+					SRTPublishPassphrase: "testpassphrase123",
+				},
+				streamCreated: make(chan struct{}),
+			}
+
+			pathManager := &dummyPathManager{path: path}
+
+			closed := make(chan string, 1)
+
+			s := &Server{
+				Address:             "127.0.0.1:8890",
+				RTSPAddress:         "",
+				ReadTimeout:         conf.StringDuration(10 * time.Second),
+				WriteTimeout:        conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize:   1472,
+				RunOnConnect:        "",
+				RunOnConnectRestart: false,
+				RunOnDisconnect:     "",
+				ExternalCmdPool:     nil,
+				PathManager:         pathManager,
+				Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+					msg := fmt.Sprintf(format, args...)
+					if strings.Contains(msg, "closed:") {
+						closed <- msg
+					}
+				}),
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			u := "srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass"
+			if ca == "encrypted" {
+				u += "&passphrase=testpassphrase123"
+			}
+
+			srtConf := srt.DefaultConfig()
+			address, err := srtConf.UnmarshalURL(u)
+			require.NoError(t, err)
+
+			err = srtConf.Validate()
+			require.NoError(t, err)
+
+			publisher, err := srt.Dial("srt", address, srtConf)
+
+			if ca == "encrypted" {
+				require.NoError(t, err)
+				defer publisher.Close()
+			} else {
+				require.EqualError(t, err, "connection rejected: "+
+					packet.HandshakeType(srt.REJ_UNSECURE).String())
+				require.Contains(t, <-closed, "connection is not encrypted, but encryption is required by configuration")
+			}
+		})
+	}
+}
+
+func TestServerEncryptionMode(t *testing.T) {
+	for _, ca := range []string{
+		"encrypted",
+		"plaintext",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			path := &dummyPath{
+				conf:          &conf.Path{},
+				streamCreated: make(chan struct{}),
+			}
+
+			if ca == "encrypted" {
+				path.conf.SRTPublishPassphrase = "testpassphrase123"
+			}
+
+			pathManager := &dummyPathManager{path: path}
+
+			s := &Server{
+				Address:             "127.0.0.1:8890",
+				RTSPAddress:         "",
+				ReadTimeout:         conf.StringDuration(10 * time.Second),
+				WriteTimeout:        conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize:   1472,
+				RunOnConnect:        "",
+				RunOnConnectRestart: false,
+				RunOnDisconnect:     "",
+				ExternalCmdPool:     nil,
+				PathManager:         pathManager,
+				Parent:              test.NilLogger,
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			u := "srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass"
+			if ca == "encrypted" {
+				u += "&passphrase=testpassphrase123"
+			}
+
+			srtConf := srt.DefaultConfig()
+			address, err := srtConf.UnmarshalURL(u)
+			require.NoError(t, err)
+
+			err = srtConf.Validate()
+			require.NoError(t, err)
+
+			publisher, err := srt.Dial("srt", address, srtConf)
+			require.NoError(t, err)
+			defer publisher.Close()
+
+			list, err := s.APIConnsList()
+			require.NoError(t, err)
+			require.Len(t, list.Items, 1)
+
+			if ca == "encrypted" {
+				require.Equal(t, defs.APISRTConnEncryptionCTR, list.Items[0].Encryption)
+			} else {
+				require.Equal(t, defs.APISRTConnEncryptionNone, list.Items[0].Encryption)
+			}
+		})
+	}
+}
+
+func TestServerPassphraseRotation(t *testing.T) {
+	for _, ca := range []string{
+		"primary",
+		"grace",
+		"invalid",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			path := &dummyPath{
+				conf: &conf.Path{
+					SRTPublishPassphrase:       "newpassphrase123",
+					SRTPublishGracePassphrases: []string{"oldpassphrase123"},
+				},
+				streamCreated: make(chan struct{}),
+			}
+
+			pathManager := &dummyPathManager{path: path}
+
+			matched := make(chan string, 1)
+			closed := make(chan string, 1)
+
+			s := &Server{
+				Address:             "127.0.0.1:8890",
+				RTSPAddress:         "",
+				ReadTimeout:         conf.StringDuration(10 * time.Second),
+				WriteTimeout:        conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize:   1472,
+				RunOnConnect:        "",
+				RunOnConnectRestart: false,
+				RunOnDisconnect:     "",
+				ExternalCmdPool:     nil,
+				PathManager:         pathManager,
+				Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+					msg := fmt.Sprintf(format, args...)
+					switch {
+					case strings.Contains(msg, "passphrase matched"):
+						matched <- msg
+					case strings.Contains(msg, "closed:"):
+						closed <- msg
+					}
+				}),
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			var passphrase string
+			switch ca {
+			case "primary":
+				passphrase = "newpassphrase123"
+			case "grace":
+				passphrase = "oldpassphrase123"
+			case "invalid":
+				passphrase = "wrongpassphrase1"
+			}
+
+			srtConf := srt.DefaultConfig()
+			address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass" +
+				"&passphrase=" + passphrase)
+			require.NoError(t, err)
+
+			err = srtConf.Validate()
+			require.NoError(t, err)
+
+			publisher, err := srt.Dial("srt", address, srtConf)
+
+			switch ca {
+			case "primary":
+				require.NoError(t, err)
+				defer publisher.Close()
+				require.Contains(t, <-matched, "passphrase matched the primary passphrase")
+
+			case "grace":
+				require.NoError(t, err)
+				defer publisher.Close()
+				require.Contains(t, <-matched, "passphrase matched grace passphrase 1")
+
+			case "invalid":
+				require.EqualError(t, err, "connection rejected: "+
+					packet.HandshakeType(srt.REJ_PEER).String())
+				require.Contains(t, <-closed, "invalid passphrase")
+			}
+		})
+	}
+}
+
+func TestServerUserPassphrases(t *testing.T) {
+	for _, ca := range []struct {
+		name       string
+		user       string
+		passphrase string
+		ok         bool
+	}{
+		{"user1", "user1", "passphraseuser1", true},
+		{"user2", "user2", "passphraseuser2", true},
+		{"user1 with passphrase of user2", "user1", "passphraseuser2", false},
+		{"user2 with passphrase of user1", "user2", "passphraseuser1", false},
+		{"user without passphrase", "user3", "pathpassphrase1", true},
+		{"user1 with passphrase of path", "user1", "pathpassphrase1", false},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			path := &dummyPath{
+				conf: &conf.Path{
+					SRTPublishPassphrase: "pathpassphrase1",
+					SRTPublishUserPassphrases: conf.SRTUserPassphrases{
+						"user1": "passphraseuser1",
+						"user2": "passphraseuser2",
+					},
+				},
+				streamCreated: make(chan struct{}),
+			}
+
+			pathManager := &dummyPathManager{
+				path:  path,
+				users: []string{"user1", "user2", "user3"},
+			}
+
+			closed := make(chan string, 1)
+
+			s := &Server{
+				Address:             "127.0.0.1:8890",
+				RTSPAddress:         "",
+				ReadTimeout:         conf.StringDuration(10 * time.Second),
+				WriteTimeout:        conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize:   1472,
+				RunOnConnect:        "",
+				RunOnConnectRestart: false,
+				RunOnDisconnect:     "",
+				ExternalCmdPool:     nil,
+				PathManager:         pathManager,
+				Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+					msg := fmt.Sprintf(format, args...)
+					if strings.Contains(msg, "closed:") {
+						closed <- msg
+					}
+				}),
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			srtConf := srt.DefaultConfig()
+			address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=publish:mypath:" + ca.user + ":mypass" +
+				"&passphrase=" + ca.passphrase)
+			require.NoError(t, err)
+
+			err = srtConf.Validate()
+			require.NoError(t, err)
+
+			publisher, err := srt.Dial("srt", address, srtConf)
+
+			if ca.ok {
+				require.NoError(t, err)
+				defer publisher.Close()
+			} else {
+				require.EqualError(t, err, "connection rejected: "+
+					packet.HandshakeType(srt.REJ_PEER).String())
+				require.Contains(t, <-closed, "invalid passphrase")
+			}
+		})
+	}
+}
+
+func TestServerSourceAllowDeny(t *testing.T) {
+	for _, ca := range []string{
+		"allowed",
+		"not in allow list",
+		"denied",
+		"read denied",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			pathConf := &conf.Path{}
+
+			switch ca {
+			case "allowed":
+				pathConf.SRTSourceAllow = mustParseIPNetworks(t, `["127.0.0.0/8"]`)
+
+			case "not in allow list":
+				pathConf.SRTSourceAllow = mustParseIPNetworks(t, `["10.0.0.0/8", "fd00::/8"]`)
+
+			case "denied", "read denied":
+				pathConf.SRTSourceDeny = mustParseIPNetworks(t, `["127.0.0.1"]`)
+			}
+
+			path := &dummyPath{
+				conf:          pathConf,
+				streamCreated: make(chan struct{}),
+			}
+
+			pathManager := &dummyPathManager{path: path}
+
+			closed := make(chan string, 1)
+
+			s := &Server{
+				Address:             "127.0.0.1:8890",
+				RTSPAddress:         "",
+				ReadTimeout:         conf.StringDuration(10 * time.Second),
+				WriteTimeout:        conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize:   1472,
+				RunOnConnect:        "",
+				RunOnConnectRestart: false,
+				RunOnDisconnect:     "",
+				ExternalCmdPool:     nil,
+				PathManager:         pathManager,
+				Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+					msg := fmt.Sprintf(format, args...)
+					if strings.Contains(msg, "closed:") {
+						closed <- msg
+					}
+				}),
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			// wrong credentials, in order to check that the source IP is checked before authentication
+			mode := "publish"
+			if ca == "read denied" {
+				mode = "read"
+			}
+			u := "srt://127.0.0.1:8890?streamid=" + mode + ":mypath:myuser:wrongpass"
+			if ca == "allowed" {
+				u = "srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass"
+			}
+
+			srtConf := srt.DefaultConfig()
+			address, err := srtConf.UnmarshalURL(u)
+			require.NoError(t, err)
+
+			err = srtConf.Validate()
+			require.NoError(t, err)
+
+			conn, err := srt.Dial("srt", address, srtConf)
+
+			if ca == "allowed" {
+				require.NoError(t, err)
+				defer conn.Close()
+			} else {
+				require.EqualError(t, err, "connection rejected: "+
+					packet.HandshakeType(srt.REJX_FORBIDDEN).String())
+				require.Contains(t, <-closed, "source IP 127.0.0.1 is not allowed to access the path")
+			}
+		})
+	}
+}
+
+func TestServerModeMismatch(t *testing.T) {
+	for _, ca := range []string{
+		"read",
+		"publish",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			pathConf := &conf.Path{Source: "publisher"}
+			if ca == "publish" {
+				pathConf.Source = "rtsp://localhost:8554/source"
+			}
+
+			path := &dummyPath{
+				conf:          pathConf,
+				streamCreated: make(chan struct{}),
+			}
+
+			pathManager := &dummyPathManager{
+				path:            path,
+				noOnePublishing: true,
+			}
+
+			closed := make(chan string, 1)
+
+			s := &Server{
+				Address:             "127.0.0.1:8890",
+				RTSPAddress:         "",
+				ReadTimeout:         conf.StringDuration(10 * time.Second),
+				WriteTimeout:        conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize:   1472,
+				RunOnConnect:        "",
+				RunOnConnectRestart: false,
+				RunOnDisconnect:     "",
+				ExternalCmdPool:     nil,
+				PathManager:         pathManager,
+				Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+					msg := fmt.Sprintf(format, args...)
+					if strings.Contains(msg, "closed:") {
+						closed <- msg
+					}
+				}),
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			srtConf := srt.DefaultConfig()
+			address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=" + ca + ":mypath:myuser:mypass")
+			require.NoError(t, err)
+
+			err = srtConf.Validate()
+			require.NoError(t, err)
+
+			_, err = srt.Dial("srt", address, srtConf)
+			require.EqualError(t, err, "connection rejected: "+
+				packet.HandshakeType(srt.REJX_BAD_MODE).String())
+
+			if ca == "read" {
+				require.Contains(t, <-closed, "stream ID is in read mode, but no one is publishing to path 'mypath'")
+			} else {
+				require.Contains(t, <-closed, "stream ID is in publish mode, but path 'mypath' doesn't accept publishers")
+			}
+		})
+	}
+}
+
+func TestServerPublishStartTimeout(t *testing.T) {
+	path := &dummyPath{
+		streamCreated: make(chan struct{}),
+	}
+
+	pathManager := &dummyPathManager{path: path}
+
+	closed := make(chan string, 1)
+
+	s := &Server{
+		Address:             "127.0.0.1:8890",
+		PublishStartTimeout: conf.StringDuration(500 * time.Millisecond),
+		RTSPAddress:         "",
+		ReadTimeout:         conf.StringDuration(10 * time.Second),
+		WriteTimeout:        conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize:   1472,
+		RunOnConnect:        "",
+		RunOnConnectRestart: false,
+		RunOnDisconnect:     "",
+		ExternalCmdPool:     nil,
+		PathManager:         pathManager,
+		Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+			msg := fmt.Sprintf(format, args...)
+			if strings.Contains(msg, "closed:") {
+				closed <- msg
+			}
+		}),
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	u := "srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass"
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL(u)
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	// the publisher completes the handshake but never sends data
+	publisher, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	select {
+	case msg := <-closed:
+		require.Contains(t, msg, "no MPEG-TS data received within 500ms after the handshake")
+	case <-time.After(5 * time.Second):
+		t.Errorf("silent publisher has not been closed")
+	}
+}
+
+func TestServerUDPBufferSizes(t *testing.T) {
+	recvLimit, sendLimit := osBufferLimits()
+
+	for _, ca := range []struct {
+		name         string
+		recv         conf.StringSize
+		send         conf.StringSize
+		expectedRecv uint32
+		expectedSend uint32
+	}{
+		{
+			"default",
+			0,
+			0,
+			0,
+			0,
+		},
+		{
+			"custom",
+			4096,
+			2048,
+			4096,
+			2048,
+		},
+		{
+			"over limit",
+			math.MaxInt32,
+			math.MaxInt32,
+			func() uint32 {
+				if recvLimit == 0 {
+					return math.MaxInt32
+				}
+				return uint32(recvLimit)
+			}(),
+			func() uint32 {
+				if sendLimit == 0 {
+					return math.MaxInt32
+				}
+				return uint32(sendLimit)
+			}(),
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			s := &Server{
+				ReadTimeout:       conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize: 1472,
+				UDPRecvBufferSize: ca.recv,
+				UDPSendBufferSize: ca.send,
+				Parent:            test.NilLogger,
+			}
+
+			srtConf := s.srtConfig()
+			require.Equal(t, ca.expectedRecv, srtConf.ReceiverBufferSize)
+			require.Equal(t, ca.expectedSend, srtConf.SendBufferSize)
+
+			err := srtConf.Validate()
+			require.NoError(t, err)
+		})
+	}
+}
+
+type stallingPathManager struct {
+	unblock chan struct{}
+}
+
+func (pm *stallingPathManager) AddPublisher(_ defs.PathAddPublisherReq) (defs.Path, error) {
+	<-pm.unblock
+	return nil, fmt.Errorf("terminated")
+}
+
+func (pm *stallingPathManager) AddReader(_ defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+	<-pm.unblock
+	return nil, nil, fmt.Errorf("terminated")
+}
+
+func TestServerHandshakeRateLimit(t *testing.T) {
+	path := &dummyPath{
+		streamCreated: make(chan struct{}),
+	}
+
+	s := &Server{
+		Address:            "127.0.0.1:8890",
+		HandshakeRateLimit: 1,
+		ReadTimeout:        conf.StringDuration(10 * time.Second),
+		WriteTimeout:       conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize:  1472,
+		PathManager:        &dummyPathManager{path: path},
+		Parent:             test.NilLogger,
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass")
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	accepted := 0
+	rejected := 0
+
+	for i := 0; i < 3; i++ {
+		var publisher srt.Conn
+		publisher, err = srt.Dial("srt", address, srtConf)
+		if err == nil {
+			defer publisher.Close()
+			accepted++
+		} else {
+			require.EqualError(t, err, "connection rejected: "+
+				packet.HandshakeType(srt.REJ_BACKLOG).String())
+			rejected++
+		}
+	}
+
+	require.Equal(t, 1, accepted)
+	require.Equal(t, 2, rejected)
+}
+
+func TestServerMaxPendingHandshakes(t *testing.T) {
+	pathManager := &stallingPathManager{
+		unblock: make(chan struct{}),
+	}
+
+	opened := make(chan struct{}, 1)
+
+	s := &Server{
+		Address:              "127.0.0.1:8890",
+		MaxPendingHandshakes: 1,
+		ReadTimeout:          conf.StringDuration(10 * time.Second),
+		WriteTimeout:         conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize:    1472,
+		PathManager:          pathManager,
+		Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+			msg := fmt.Sprintf(format, args...)
+			if strings.Contains(msg, "[conn") && strings.HasSuffix(msg, "opened") {
+				opened <- struct{}{}
+			}
+		}),
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+	defer close(pathManager.unblock)
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass")
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	stalledDone := make(chan struct{})
+	go func() {
+		defer close(stalledDone)
+		stalled, err2 := srt.Dial("srt", address, srtConf)
+		if err2 == nil {
+			stalled.Close()
+		}
+	}()
+	defer func() { <-stalledDone }()
+
+	<-opened
+
+	_, err = srt.Dial("srt", address, srtConf)
+	require.EqualError(t, err, "connection rejected: "+
+		packet.HandshakeType(srt.REJ_BACKLOG).String())
+}
+
+func TestServerMaxConnsPerIP(t *testing.T) {
+	path := &dummyPath{
+		streamCreated: make(chan struct{}),
+	}
+
+	s := &Server{
+		Address:           "127.0.0.1:8890",
+		MaxConnsPerIP:     1,
+		IPv6PrefixLength:  128,
+		ReadTimeout:       conf.StringDuration(10 * time.Second),
+		WriteTimeout:      conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize: 1472,
+		PathManager:       &dummyPathManager{path: path},
+		Parent:            test.NilLogger,
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass")
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	publisher, err := srt.Dial("srt", address, srtConf)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = srt.Dial("srt", address, srtConf)
+		require.EqualError(t, err, "connection rejected: "+
+			packet.HandshakeType(srt.REJX_OVERLOAD).String())
+	}
+
+	ips, err := s.APIIPsList()
+	require.NoError(t, err)
+	require.Equal(t, []*defs.APISRTIP{{IP: "127.0.0.1", Conns: 1}}, ips.Items)
+
+	publisher.Close()
+
+	require.Eventually(t, func() bool {
+		ips, err = s.APIIPsList()
+		return err == nil && len(ips.Items) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestServerHandshakeTimeout(t *testing.T) {
+	pathManager := &stallingPathManager{
+		unblock: make(chan struct{}),
+	}
+
+	timedOut := make(chan string, 1)
+
+	s := &Server{
+		Address:           "127.0.0.1:8890",
+		HandshakeTimeout:  conf.StringDuration(500 * time.Millisecond),
+		ReadTimeout:       conf.StringDuration(10 * time.Second),
+		WriteTimeout:      conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize: 1472,
+		PathManager:       pathManager,
+		Parent: test.Logger(func(_ logger.Level, format string, args ...interface{}) {
+			msg := fmt.Sprintf(format, args...)
+			if strings.Contains(msg, "handshake not completed") {
+				timedOut <- msg
+			}
+		}),
+	}
+	err := s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+	defer close(pathManager.unblock)
+
+	srtConf := srt.DefaultConfig()
+	address, err := srtConf.UnmarshalURL("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass")
+	require.NoError(t, err)
+
+	err = srtConf.Validate()
+	require.NoError(t, err)
+
+	// the handshake is stalled by the path manager
+	_, err = srt.Dial("srt", address, srtConf)
+	require.EqualError(t, err, "connection rejected: "+
+		packet.HandshakeType(srt.REJ_PEER).String())
+
+	require.Contains(t, <-timedOut, "handshake not completed within 500ms")
+}
+
+type listenerStatsPathManager struct {
+	dummyPathManager
+	unblock chan struct{}
+}
+
+func (pm *listenerStatsPathManager) AddReader(req defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+	if req.AccessRequest.Name == "stalled" {
+		<-pm.unblock
+		return nil, nil, fmt.Errorf("terminated")
+	}
+	if req.AccessRequest.Name == "nopublisher" {
+		req.CheckConf(&conf.Path{Source: "publisher"}) //nolint:errcheck
+		return nil, nil, defs.PathNoOnePublishingError{PathName: req.AccessRequest.Name}
+	}
+	return pm.dummyPathManager.AddReader(req)
+}
+
+func TestServerListenerStats(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{test.MediaH264}}
+
+	strm, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{
+		stream:        strm,
+		streamCreated: make(chan struct{}),
+	}
+
+	pathManager := &listenerStatsPathManager{
+		dummyPathManager: dummyPathManager{path: path},
+		unblock:          make(chan struct{}),
+	}
+
+	s := &Server{
+		Address:           "127.0.0.1:8890",
+		ReadTimeout:       conf.StringDuration(10 * time.Second),
+		WriteTimeout:      conf.StringDuration(10 * time.Second),
+		UDPMaxPayloadSize: 1472,
+		PathManager:       pathManager,
+		Parent:            test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	dial := func(u string) (srt.Conn, error) {
+		srtConf := srt.DefaultConfig()
+		address, err2 := srtConf.UnmarshalURL(u)
+		require.NoError(t, err2)
+
+		err2 = srtConf.Validate()
+		require.NoError(t, err2)
+
+		return srt.Dial("srt", address, srtConf)
+	}
+
+	publisher, err := dial("srt://127.0.0.1:8890?streamid=publish:mypath:myuser:mypass")
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	reader, err := dial("srt://127.0.0.1:8890?streamid=read:mypath:myuser:mypass")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	// the handshake of this connection is not completed until the path manager is unblocked
+	idleDone := make(chan struct{})
+	go func() {
+		defer close(idleDone)
+		dial("srt://127.0.0.1:8890?streamid=read:stalled:myuser:mypass") //nolint:errcheck
+	}()
+
+	_, err = dial("srt://127.0.0.1:8890?streamid=invalid")
+	require.Error(t, err)
+
+	_, err = dial("srt://127.0.0.1:8890?streamid=read:nopublisher:myuser:mypass")
+	require.EqualError(t, err, "connection rejected: "+
+		packet.HandshakeType(srt.REJX_BAD_MODE).String())
+
+	track := &mpegts.Track{
+		Codec: &mpegts.CodecH264{},
+	}
+
+	bw := bufio.NewWriter(publisher)
+	w := mpegts.NewWriter(bw, []*mpegts.Track{track})
+
+	err = w.WriteH264(track, 0, 0, true, [][]byte{
+		test.FormatH264.SPS,
+		test.FormatH264.PPS,
+		{0x05, 1}, // IDR
+	})
+	require.NoError(t, err)
+
+	err = bw.Flush()
+	require.NoError(t, err)
+
+	<-path.streamCreated
+
+	var stats *defs.APISRTListenerStats
+
+	require.Eventually(t, func() bool {
+		stats, err = s.APIListenerStats()
+		require.NoError(t, err)
+		return stats.BytesReceived != 0 &&
+			stats.Conns[defs.APISRTConnStateIdle] == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.Equal(t, map[defs.APISRTConnState]int{
+		defs.APISRTConnStateIdle:    1,
+		defs.APISRTConnStateRead:    1,
+		defs.APISRTConnStatePublish: 1,
+	}, stats.Conns)
+	require.Equal(t, map[string]uint64{"peer": 1, "bad_mode": 1}, stats.Rejections)
+
+	bytesReceived := stats.BytesReceived
+
+	// bytes of closed connections are kept
+	publisher.Close()
+	close(pathManager.unblock)
+	<-idleDone
+
+	require.Eventually(t, func() bool {
+		stats, err = s.APIListenerStats()
+		require.NoError(t, err)
+		return stats.Conns[defs.APISRTConnStatePublish] == 0 &&
+			stats.Conns[defs.APISRTConnStateIdle] == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.GreaterOrEqual(t, stats.BytesReceived, bytesReceived)
+	require.Equal(t, map[string]uint64{"peer": 2, "bad_mode": 1}, stats.Rejections)
+}
@@ -0,0 +1,143 @@
+package srt
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+const (
+	streamDumpQueueSize = 256
+)
+
+// streamDump writes data received from a publisher into a file, for debugging purposes.
+// Data is written by a dedicated routine, in order not to slow down the connection.
+// The dump stops when it reaches the maximum size or duration,
+// or when the routine can't keep up with the connection.
+type streamDump struct {
+	path        string
+	maxSize     int64
+	maxDuration time.Duration
+	parent      logger.Writer
+
+	f       *os.File
+	start   time.Time
+	mutex   sync.Mutex
+	size    int64
+	stopped bool
+	queue   chan []byte
+	done    chan struct{}
+}
+
+func (d *streamDump) initialize() error {
+	err := os.MkdirAll(filepath.Dir(d.path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	d.f, err = os.Create(d.path)
+	if err != nil {
+		return err
+	}
+
+	d.start = time.Now()
+	d.queue = make(chan []byte, streamDumpQueueSize)
+	d.done = make(chan struct{})
+
+	d.parent.Log(logger.Info, "dumping received data to %s", d.path)
+
+	go d.run()
+
+	return nil
+}
+
+// close stops the dump and waits for pending data to be written.
+func (d *streamDump) close() {
+	d.mutex.Lock()
+	d.stop("")
+	d.mutex.Unlock()
+
+	<-d.done
+}
+
+// stop must be called with the mutex locked.
+func (d *streamDump) stop(reason string) {
+	if d.stopped {
+		return
+	}
+
+	d.stopped = true
+	close(d.queue)
+
+	if reason != "" {
+		d.parent.Log(logger.Info, "dump stopped: %s", reason)
+	}
+}
+
+func (d *streamDump) run() {
+	defer close(d.done)
+	defer d.f.Close()
+
+	failed := false
+
+	for buf := range d.queue {
+		if failed {
+			continue
+		}
+
+		_, err := d.f.Write(buf)
+		if err != nil {
+			d.parent.Log(logger.Warn, "unable to write dump: %v", err)
+			failed = true
+		}
+	}
+}
+
+func (d *streamDump) write(buf []byte) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.stopped {
+		return
+	}
+
+	if time.Since(d.start) >= d.maxDuration {
+		d.stop("maximum duration reached")
+		return
+	}
+
+	if (d.size + int64(len(buf))) > d.maxSize {
+		d.stop("maximum size reached")
+		return
+	}
+
+	// buf may be reused by the caller
+	cpy := make([]byte, len(buf))
+	copy(cpy, buf)
+
+	select {
+	case d.queue <- cpy:
+		d.size += int64(len(buf))
+	default:
+		d.stop("data is received faster than it can be written")
+	}
+}
+
+// streamDumpReader writes read data into a streamDump.
+type streamDumpReader struct {
+	r    io.Reader
+	dump *streamDump
+}
+
+// Read implements io.Reader.
+func (r *streamDumpReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.dump.write(p[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,37 @@
+package srt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/test"
+)
+
+func TestStreamDumpMaxSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mediamtx-srt-dump")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	d := &streamDump{
+		path:        filepath.Join(dir, "sub", "dump.ts"),
+		maxSize:     5,
+		maxDuration: 60 * time.Second,
+		parent:      test.NilLogger,
+	}
+	err = d.initialize()
+	require.NoError(t, err)
+
+	d.write([]byte{1, 2})
+	d.write([]byte{3, 4})
+	d.write([]byte{5, 6}) // exceeds the maximum size
+	d.write([]byte{7})
+	d.close()
+
+	byts, err := os.ReadFile(d.path)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3, 4}, byts)
+}
@@ -2,7 +2,12 @@ package srt
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
 )
 
 type streamIDMode int
@@ -13,11 +18,63 @@ const (
 )
 
 type streamID struct {
-	mode  streamIDMode
-	path  string
-	query string
-	user  string
-	pass  string
+	mode    streamIDMode
+	path    string
+	query   string
+	user    string
+	pass    string
+	program int
+	dump    bool
+	proxy   bool
+
+	// custom keys of the standard syntax
+	metadata map[string]string
+}
+
+func metadataEnvName(key string) string {
+	return "MTX_SRT_STREAMID_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// metadataEnv returns environment variables that contain the custom keys of the stream ID,
+// in the format MTX_SRT_STREAMID_<KEY>. Values of keys that look like secrets are redacted.
+func (s *streamID) metadataEnv() externalcmd.Environment {
+	env := externalcmd.Environment{}
+
+	for key, value := range s.metadata {
+		if defs.IsSecretKey(key) {
+			value = "REDACTED"
+		}
+		env[metadataEnvName(key)] = value
+	}
+
+	return env
+}
+
+func parseProgram(raw string) (int, error) {
+	v, err := strconv.ParseUint(raw, 10, 31)
+	if err != nil || v == 0 {
+		return 0, fmt.Errorf("invalid program '%s'", raw)
+	}
+	return int(v), nil
+}
+
+func parseDump(raw string) (bool, error) {
+	switch raw {
+	case "0":
+		return false, nil
+
+	case "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid dump flag '%s'", raw)
 }
 
 func (s *streamID) unmarshal(raw string) error {
@@ -46,6 +103,27 @@ func (s *streamID) unmarshal(raw string) error {
 
 			case "t":
 
+			case "prog":
+				var err error
+				s.program, err = parseProgram(value)
+				if err != nil {
+					return err
+				}
+
+			case "dump":
+				var err error
+				s.dump, err = parseDump(value)
+				if err != nil {
+					return err
+				}
+
+			case "quality":
+				var err error
+				s.proxy, err = defs.ParseReaderQuality(value)
+				if err != nil {
+					return err
+				}
+
 			case "m":
 				switch value {
 				case "request":
@@ -59,7 +137,14 @@ func (s *streamID) unmarshal(raw string) error {
 				}
 
 			default:
-				return fmt.Errorf("unsupported key '%s'", key)
+				if key == "" {
+					return fmt.Errorf("invalid value")
+				}
+
+				if s.metadata == nil {
+					s.metadata = make(map[string]string)
+				}
+				s.metadata[key] = value
 			}
 		}
 	} else {
@@ -94,6 +179,28 @@ func (s *streamID) unmarshal(raw string) error {
 		} else if len(parts) == 5 {
 			s.query = parts[4]
 		}
+
+		// the program, the dump flag and the quality can be set inside the query
+		if q, err := url.ParseQuery(s.query); err == nil {
+			if q.Has("prog") {
+				s.program, err = parseProgram(q.Get("prog"))
+				if err != nil {
+					return err
+				}
+			}
+
+			if q.Has("dump") {
+				s.dump, err = parseDump(q.Get("dump"))
+				if err != nil {
+					return err
+				}
+			}
+
+			s.proxy, err = defs.ParseReaderQuality(q.Get("quality"))
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
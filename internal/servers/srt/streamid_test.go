@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
 )
 
 func TestStreamIDUnmarshal(t *testing.T) {
@@ -40,6 +42,16 @@ func TestStreamIDUnmarshal(t *testing.T) {
 				query: "myquery",
 			},
 		},
+		{
+			"mediamtx syntax with program",
+			"read:mypath:prog=2&token=abc",
+			streamID{
+				mode:    streamIDModeRead,
+				path:    "mypath",
+				query:   "prog=2&token=abc",
+				program: 2,
+			},
+		},
 		{
 			"standard syntax",
 			"#!::u=johnny,t=file,m=publish,r=results.csv,s=mypass,h=myhost.com",
@@ -50,6 +62,65 @@ func TestStreamIDUnmarshal(t *testing.T) {
 				pass: "mypass",
 			},
 		},
+		{
+			"standard syntax with custom keys",
+			"#!::m=publish,r=mypath,loc=roof,camera=north",
+			streamID{
+				mode: streamIDModePublish,
+				path: "mypath",
+				metadata: map[string]string{
+					"loc":    "roof",
+					"camera": "north",
+				},
+			},
+		},
+		{
+			"standard syntax with program",
+			"#!::m=request,r=mypath,prog=2",
+			streamID{
+				mode:    streamIDModeRead,
+				path:    "mypath",
+				program: 2,
+			},
+		},
+		{
+			"mediamtx syntax with dump",
+			"publish:mypath:dump=1",
+			streamID{
+				mode:  streamIDModePublish,
+				path:  "mypath",
+				query: "dump=1",
+				dump:  true,
+			},
+		},
+		{
+			"standard syntax with dump",
+			"#!::m=publish,r=mypath,dump=1",
+			streamID{
+				mode: streamIDModePublish,
+				path: "mypath",
+				dump: true,
+			},
+		},
+		{
+			"mediamtx syntax with quality",
+			"read:mypath:quality=proxy",
+			streamID{
+				mode:  streamIDModeRead,
+				path:  "mypath",
+				query: "quality=proxy",
+				proxy: true,
+			},
+		},
+		{
+			"standard syntax with quality",
+			"#!::m=request,r=mypath,quality=proxy",
+			streamID{
+				mode:  streamIDModeRead,
+				path:  "mypath",
+				proxy: true,
+			},
+		},
 	} {
 		t.Run(ca.name, func(t *testing.T) {
 			var sid streamID
@@ -59,3 +130,15 @@ func TestStreamIDUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamIDMetadataEnv(t *testing.T) {
+	var sid streamID
+	err := sid.unmarshal("#!::m=publish,r=mypath,loc=roof,camera-name=north,apiToken=abc")
+	require.NoError(t, err)
+
+	require.Equal(t, externalcmd.Environment{
+		"MTX_SRT_STREAMID_LOC":         "roof",
+		"MTX_SRT_STREAMID_CAMERA_NAME": "north",
+		"MTX_SRT_STREAMID_APITOKEN":    "REDACTED",
+	}, sid.metadataEnv())
+}
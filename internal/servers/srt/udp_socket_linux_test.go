@@ -0,0 +1,122 @@
+//go:build linux
+
+package srt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/datarhei/gosrt/circular"
+	"github.com/datarhei/gosrt/packet"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/test"
+)
+
+// receivedTTL sends a handshake induction to a SRT listener
+// and returns the TTL of the response.
+func receivedTTL(t *testing.T, address string) int {
+	pc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer pc.Close()
+
+	rc, err := pc.SyscallConn()
+	require.NoError(t, err)
+
+	var opErr error
+	err = rc.Control(func(fd uintptr) {
+		opErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTTL, 1)
+	})
+	require.NoError(t, err)
+	require.NoError(t, opErr)
+
+	raddr, err := net.ResolveUDPAddr("udp4", address)
+	require.NoError(t, err)
+
+	p := packet.NewPacket(raddr)
+	p.Header().IsControlPacket = true
+	p.Header().ControlType = packet.CTRLTYPE_HANDSHAKE
+
+	cif := &packet.CIFHandshake{
+		IsRequest:                   true,
+		Version:                     4,
+		ExtensionField:              2,
+		InitialPacketSequenceNumber: circular.New(0, packet.MAX_SEQUENCENUMBER),
+		MaxTransmissionUnitSize:     1500,
+		MaxFlowWindowSize:           25600,
+		HandshakeType:               packet.HSTYPE_INDUCTION,
+		SRTSocketId:                 1,
+	}
+	cif.PeerIP.FromNetAddr(pc.LocalAddr())
+	p.MarshalCIF(cif)
+
+	var buf bytes.Buffer
+	err = p.Marshal(&buf)
+	require.NoError(t, err)
+
+	_, err = pc.WriteToUDP(buf.Bytes(), raddr)
+	require.NoError(t, err)
+
+	err = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	require.NoError(t, err)
+
+	b := make([]byte, 1500)
+	oob := make([]byte, 128)
+	_, oobn, _, _, err := pc.ReadMsgUDP(b, oob)
+	require.NoError(t, err)
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	require.NoError(t, err)
+
+	for _, msg := range msgs {
+		if msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_TTL {
+			return int(binary.NativeEndian.Uint32(msg.Data))
+		}
+	}
+
+	t.Fatal("TTL not found")
+	return 0
+}
+
+func TestServerTTL(t *testing.T) {
+	for _, ca := range []struct {
+		name    string
+		address string
+	}{
+		{
+			"ipv4",
+			"127.0.0.1:8890",
+		},
+		{
+			"dual stack",
+			":8890",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			externalCmdPool := externalcmd.NewPool()
+			defer externalCmdPool.Close()
+
+			s := &Server{
+				Address:           ca.address,
+				ReadTimeout:       conf.StringDuration(10 * time.Second),
+				WriteTimeout:      conf.StringDuration(10 * time.Second),
+				UDPMaxPayloadSize: 1472,
+				TTL:               42,
+				ExternalCmdPool:   externalCmdPool,
+				PathManager:       &dummyPathManager{},
+				Parent:            test.NilLogger,
+			}
+			err := s.Initialize()
+			require.NoError(t, err)
+			defer s.Close()
+
+			require.Equal(t, 42, receivedTTL(t, "127.0.0.1:8890"))
+		})
+	}
+}
@@ -366,9 +366,16 @@
         sdpSemantics: 'unified-plan',
       });
 
+      // when a single media is requested, the other one is not offered.
+      const media = new URL(this.conf.url).searchParams.get('media');
+
       const direction = 'sendrecv';
-      this.pc.addTransceiver('video', { direction });
-      this.pc.addTransceiver('audio', { direction });
+      if (media !== 'audio') {
+        this.pc.addTransceiver('video', { direction });
+      }
+      if (media !== 'video') {
+        this.pc.addTransceiver('audio', { direction });
+      }
 
       this.pc.onicecandidate = (evt) => this.onLocalCandidate(evt);
       this.pc.oniceconnectionstatechange = () => this.onConnectionState();
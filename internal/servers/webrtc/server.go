@@ -188,6 +188,7 @@ type Server struct {
 	IPsFromInterfacesList []string
 	AdditionalHosts       []string
 	ICEServers            []conf.WebRTCICEServer
+	ICETransportPolicy    conf.WebRTCICETransportPolicy
 	HandshakeTimeout      conf.StringDuration
 	TrackGatherTimeout    conf.StringDuration
 	ExternalCmdPool       *externalcmd.Pool
@@ -449,6 +450,13 @@ func (s *Server) generateICEServers(clientConfig bool) ([]pwebrtc.ICEServer, err
 	return ret, nil
 }
 
+func (s *Server) iceTransportPolicy() pwebrtc.ICETransportPolicy {
+	if s.ICETransportPolicy == conf.WebRTCICETransportPolicyRelay {
+		return pwebrtc.ICETransportPolicyRelay
+	}
+	return pwebrtc.ICETransportPolicyAll
+}
+
 // newSession is called by webRTCHTTPServer.
 func (s *Server) newSession(req webRTCNewSessionReq) webRTCNewSessionRes {
 	req.res = make(chan webRTCNewSessionRes)
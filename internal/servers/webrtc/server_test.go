@@ -3,10 +3,16 @@ package webrtc
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
 	"io"
 	"net/http"
 	"net/url"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +21,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/conf"
 	"github.com/bluenviron/mediamtx/internal/defs"
 	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
 	"github.com/bluenviron/mediamtx/internal/protocols/webrtc"
 	"github.com/bluenviron/mediamtx/internal/protocols/whip"
 	"github.com/bluenviron/mediamtx/internal/stream"
@@ -22,6 +29,7 @@ import (
 	"github.com/bluenviron/mediamtx/internal/unit"
 	"github.com/google/uuid"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	pwebrtc "github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 )
@@ -58,6 +66,9 @@ func (p *dummyPath) StartPublisher(req defs.PathStartPublisherReq) (*stream.Stre
 		1460,
 		req.Desc,
 		true,
+		0,
+		0,
+		0,
 		test.NilLogger,
 	)
 	if err != nil {
@@ -76,6 +87,12 @@ func (p *dummyPath) RemovePublisher(_ defs.PathRemovePublisherReq) {
 func (p *dummyPath) RemoveReader(_ defs.PathRemoveReaderReq) {
 }
 
+func (p *dummyPath) AddEvent(_ logger.Level, _ string, _ ...interface{}) {
+}
+
+func (p *dummyPath) AddDecodeError(_ string) {
+}
+
 type dummyPathManager struct {
 	findPathConf func(req defs.PathFindPathConfReq) (*conf.Path, error)
 	addPublisher func(req defs.PathAddPublisherReq) (defs.Path, error)
@@ -237,6 +254,117 @@ func TestServerOptionsICEServer(t *testing.T) {
 	}}, iceServers)
 }
 
+func TestServerReadLinkHeader(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{test.MediaH264}}
+
+	str, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{stream: str}
+
+	pathManager := &dummyPathManager{
+		findPathConf: func(_ defs.PathFindPathConfReq) (*conf.Path, error) {
+			return &conf.Path{}, nil
+		},
+		addReader: func(_ defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+			return path, str, nil
+		},
+	}
+
+	s := &Server{
+		Address:               "127.0.0.1:8886",
+		Encryption:            false,
+		ServerKey:             "",
+		ServerCert:            "",
+		AllowOrigin:           "",
+		TrustedProxies:        conf.IPNetworks{},
+		ReadTimeout:           conf.StringDuration(10 * time.Second),
+		LocalUDPAddress:       "127.0.0.1:8887",
+		LocalTCPAddress:       "127.0.0.1:8887",
+		IPsFromInterfaces:     true,
+		IPsFromInterfacesList: []string{},
+		AdditionalHosts:       []string{},
+		ICEServers: []conf.WebRTCICEServer{
+			{
+				URL:        "stun:stun.example.com:3478",
+				ClientOnly: true,
+			},
+			{
+				URL:        "turn:turn.example.com:3478?transport=udp",
+				Username:   "AUTH_SECRET",
+				Password:   "mysecret",
+				ClientOnly: true,
+			},
+		},
+		HandshakeTimeout:   conf.StringDuration(10 * time.Second),
+		TrackGatherTimeout: conf.StringDuration(2 * time.Second),
+		ExternalCmdPool:    nil,
+		PathManager:        pathManager,
+		Parent:             test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	pc, err := pwebrtc.NewPeerConnection(pwebrtc.Configuration{})
+	require.NoError(t, err)
+	defer pc.Close() //nolint:errcheck
+
+	_, err = pc.AddTransceiverFromKind(pwebrtc.RTPCodecTypeVideo,
+		pwebrtc.RTPTransceiverInit{Direction: pwebrtc.RTPTransceiverDirectionRecvonly})
+	require.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost,
+		"http://localhost:8886/teststream/whep", bytes.NewReader([]byte(offer.SDP)))
+	require.NoError(t, err)
+
+	req.Header.Set("Content-Type", "application/sdp")
+
+	res, err := hc.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Contains(t, strings.Split(res.Header.Get("Access-Control-Expose-Headers"), ", "), "Link")
+
+	links := res.Header["Link"]
+	require.Len(t, links, 2)
+	require.Equal(t, `<stun:stun.example.com:3478>; rel="ice-server"`, links[0])
+	require.Regexp(t, regexp.MustCompile(`^<turn:turn\.example\.com:3478\?transport=udp>; rel="ice-server"; `+
+		`username="[0-9]+:[a-z0-9]+"; credential="[A-Za-z0-9+/=]+"; credential-type="password"$`), links[1])
+
+	iceServers, err := whip.LinkHeaderUnmarshal(links)
+	require.NoError(t, err)
+	require.Len(t, iceServers, 2)
+
+	// ephemeral credentials are generated as described in
+	// https://datatracker.ietf.org/doc/html/draft-uberti-behave-turn-rest-00
+	parts := strings.SplitN(iceServers[1].Username, ":", 2)
+	expiration, err := strconv.ParseInt(parts[0], 10, 64)
+	require.NoError(t, err)
+	require.Greater(t, expiration, time.Now().Unix())
+
+	h := hmac.New(sha1.New, []byte("mysecret"))
+	h.Write([]byte(iceServers[1].Username))
+	require.Equal(t, base64.StdEncoding.EncodeToString(h.Sum(nil)), iceServers[1].Credential)
+}
+
 func TestServerPublish(t *testing.T) {
 	path := &dummyPath{
 		streamCreated: make(chan struct{}),
@@ -409,6 +537,34 @@ func TestServerRead(t *testing.T) {
 			},
 			[]byte{0x10, 1, 2},
 		},
+		{
+			"h265",
+			[]*description.Media{{
+				Type:    description.MediaTypeVideo,
+				Formats: []format.Format{test.FormatH265},
+			}},
+			&unit.H265{
+				AU: [][]byte{
+					{0x26, 0x01, 0x01},
+				},
+			},
+			[]byte{
+				0x60, 0x00, 0x00, 0x18, 0x40, 0x01, 0x0c, 0x01,
+				0xff, 0xff, 0x02, 0x20, 0x00, 0x00, 0x03, 0x00,
+				0xb0, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00,
+				0x7b, 0x18, 0xb0, 0x24, 0x00, 0x3c, 0x42, 0x01,
+				0x01, 0x02, 0x20, 0x00, 0x00, 0x03, 0x00, 0xb0,
+				0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x7b,
+				0xa0, 0x07, 0x82, 0x00, 0x88, 0x7d, 0xb6, 0x71,
+				0x8b, 0x92, 0x44, 0x80, 0x53, 0x88, 0x88, 0x92,
+				0xcf, 0x24, 0xa6, 0x92, 0x72, 0xc9, 0x12, 0x49,
+				0x22, 0xdc, 0x91, 0xaa, 0x48, 0xfc, 0xa2, 0x23,
+				0xff, 0x00, 0x01, 0x00, 0x01, 0x6a, 0x02, 0x02,
+				0x02, 0x01, 0x00, 0x08, 0x44, 0x01, 0xc0, 0x25,
+				0x2f, 0x05, 0x32, 0x40, 0x00, 0x03, 0x26, 0x01,
+				0x01,
+			},
+		},
 		{
 			"h264",
 			[]*description.Media{test.MediaH264},
@@ -517,6 +673,9 @@ func TestServerRead(t *testing.T) {
 				1460,
 				desc,
 				reflect.TypeOf(ca.unit) != reflect.TypeOf(&unit.Generic{}),
+				0,
+				0,
+				0,
 				test.NilLogger,
 			)
 			require.NoError(t, err)
@@ -612,6 +771,307 @@ func TestServerRead(t *testing.T) {
 	}
 }
 
+func TestServerReadMediaType(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{
+		test.MediaH264,
+		{
+			Type: description.MediaTypeAudio,
+			Formats: []format.Format{&format.Opus{
+				PayloadTyp:   96,
+				ChannelCount: 2,
+			}},
+		},
+	}}
+
+	str, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{stream: str}
+
+	pathManager := &dummyPathManager{
+		findPathConf: func(_ defs.PathFindPathConfReq) (*conf.Path, error) {
+			return &conf.Path{}, nil
+		},
+		addReader: func(_ defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+			return path, str, nil
+		},
+	}
+
+	s := &Server{
+		Address:               "127.0.0.1:8886",
+		Encryption:            false,
+		ServerKey:             "",
+		ServerCert:            "",
+		AllowOrigin:           "",
+		TrustedProxies:        conf.IPNetworks{},
+		ReadTimeout:           conf.StringDuration(10 * time.Second),
+		LocalUDPAddress:       "127.0.0.1:8887",
+		LocalTCPAddress:       "127.0.0.1:8887",
+		IPsFromInterfaces:     true,
+		IPsFromInterfacesList: []string{},
+		AdditionalHosts:       []string{},
+		ICEServers:            []conf.WebRTCICEServer{},
+		HandshakeTimeout:      conf.StringDuration(10 * time.Second),
+		TrackGatherTimeout:    conf.StringDuration(2 * time.Second),
+		ExternalCmdPool:       nil,
+		PathManager:           pathManager,
+		Parent:                test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	t.Run("invalid", func(t *testing.T) {
+		req, err2 := http.NewRequest(http.MethodPost,
+			"http://localhost:8886/teststream/whep?media=data", bytes.NewReader([]byte("v=0\r\n")))
+		require.NoError(t, err2)
+
+		req.Header.Set("Content-Type", "application/sdp")
+
+		res, err2 := hc.Do(req)
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		byts, err2 := io.ReadAll(res.Body)
+		require.NoError(t, err2)
+		require.Contains(t, string(byts), "invalid media 'data'")
+	})
+
+	t.Run("answer", func(t *testing.T) {
+		pc, err2 := pwebrtc.NewPeerConnection(pwebrtc.Configuration{})
+		require.NoError(t, err2)
+		defer pc.Close() //nolint:errcheck
+
+		_, err2 = pc.AddTransceiverFromKind(pwebrtc.RTPCodecTypeAudio,
+			pwebrtc.RTPTransceiverInit{Direction: pwebrtc.RTPTransceiverDirectionRecvonly})
+		require.NoError(t, err2)
+
+		offer, err2 := pc.CreateOffer(nil)
+		require.NoError(t, err2)
+
+		err2 = pc.SetLocalDescription(offer)
+		require.NoError(t, err2)
+
+		req, err2 := http.NewRequest(http.MethodPost,
+			"http://localhost:8886/teststream/whep?media=audio", bytes.NewReader([]byte(offer.SDP)))
+		require.NoError(t, err2)
+
+		req.Header.Set("Content-Type", "application/sdp")
+
+		res, err2 := hc.Do(req)
+		require.NoError(t, err2)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		byts, err2 := io.ReadAll(res.Body)
+		require.NoError(t, err2)
+
+		var answer sdp.SessionDescription
+		err2 = answer.Unmarshal(byts)
+		require.NoError(t, err2)
+
+		require.Len(t, answer.MediaDescriptions, 1)
+		require.Equal(t, "audio", answer.MediaDescriptions[0].MediaName.Media)
+
+		err2 = pc.SetRemoteDescription(pwebrtc.SessionDescription{
+			Type: pwebrtc.SDPTypeAnswer,
+			SDP:  string(byts),
+		})
+		require.NoError(t, err2)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		u, err2 := url.Parse("http://localhost:8886/teststream/whep?media=audio")
+		require.NoError(t, err2)
+
+		wc := &whip.Client{
+			HTTPClient: hc,
+			URL:        u,
+			Log:        test.NilLogger,
+		}
+
+		writerDone := make(chan struct{})
+
+		go func() {
+			defer close(writerDone)
+
+			str.WaitRunningReader()
+
+			for i := 0; i < 2; i++ {
+				str.WriteUnit(desc.Medias[0], desc.Medias[0].Formats[0], &unit.H264{
+					AU: [][]byte{{5, 1}},
+				})
+				str.WriteUnit(desc.Medias[1], desc.Medias[1].Formats[0], &unit.Opus{
+					Packets: [][]byte{{1, 2}},
+				})
+			}
+		}()
+
+		tracks, err2 := wc.Read(context.Background())
+		require.NoError(t, err2)
+		defer checkClose(t, wc.Close)
+
+		// the video track is not offered
+		require.Len(t, tracks, 1)
+		require.Equal(t, 48000, tracks[0].ClockRate())
+
+		done := make(chan struct{})
+
+		tracks[0].OnPacketRTP = func(pkt *rtp.Packet) {
+			select {
+			case <-done:
+			default:
+				require.Equal(t, []byte{1, 2}, pkt.Payload)
+				close(done)
+			}
+		}
+
+		wc.StartReading()
+
+		<-writerDone
+		<-done
+	})
+}
+
+func TestServerReadH265(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{test.FormatH265},
+	}}}
+
+	str, err := stream.New(
+		512,
+		1460,
+		desc,
+		true,
+		0,
+		0,
+		0,
+		test.NilLogger,
+	)
+	require.NoError(t, err)
+
+	path := &dummyPath{stream: str}
+
+	pm := &dummyPathManager{
+		findPathConf: func(_ defs.PathFindPathConfReq) (*conf.Path, error) {
+			return &conf.Path{}, nil
+		},
+		addReader: func(_ defs.PathAddReaderReq) (defs.Path, *stream.Stream, error) {
+			return path, str, nil
+		},
+	}
+
+	s := &Server{
+		Address:               "127.0.0.1:8886",
+		Encryption:            false,
+		ServerKey:             "",
+		ServerCert:            "",
+		AllowOrigin:           "",
+		TrustedProxies:        conf.IPNetworks{},
+		ReadTimeout:           conf.StringDuration(10 * time.Second),
+		LocalUDPAddress:       "127.0.0.1:8887",
+		LocalTCPAddress:       "127.0.0.1:8887",
+		IPsFromInterfaces:     true,
+		IPsFromInterfacesList: []string{},
+		AdditionalHosts:       []string{},
+		ICEServers:            []conf.WebRTCICEServer{},
+		HandshakeTimeout:      conf.StringDuration(10 * time.Second),
+		TrackGatherTimeout:    conf.StringDuration(2 * time.Second),
+		ExternalCmdPool:       nil,
+		PathManager:           pm,
+		Parent:                test.NilLogger,
+	}
+	err = s.Initialize()
+	require.NoError(t, err)
+	defer s.Close()
+
+	tr := &http.Transport{}
+	defer tr.CloseIdleConnections()
+	hc := &http.Client{Transport: tr}
+
+	// simulate a client that supports H265 with multiple profiles, like Safari
+	mediaEngine := &pwebrtc.MediaEngine{}
+
+	for _, codec := range []pwebrtc.RTPCodecParameters{
+		{
+			RTPCodecCapability: pwebrtc.RTPCodecCapability{
+				MimeType:    pwebrtc.MimeTypeH265,
+				ClockRate:   90000,
+				SDPFmtpLine: "level-id=93;profile-id=2;tier-flag=0;tx-mode=SRST",
+			},
+			PayloadType: 103,
+		},
+		{
+			RTPCodecCapability: pwebrtc.RTPCodecCapability{
+				MimeType:    pwebrtc.MimeTypeH265,
+				ClockRate:   90000,
+				SDPFmtpLine: "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST",
+			},
+			PayloadType: 104,
+		},
+		{
+			RTPCodecCapability: pwebrtc.RTPCodecCapability{
+				MimeType:  pwebrtc.MimeTypeVP8,
+				ClockRate: 90000,
+			},
+			PayloadType: 105,
+		},
+	} {
+		err = mediaEngine.RegisterCodec(codec, pwebrtc.RTPCodecTypeVideo)
+		require.NoError(t, err)
+	}
+
+	pc, err := pwebrtc.NewAPI(pwebrtc.WithMediaEngine(mediaEngine)).NewPeerConnection(pwebrtc.Configuration{})
+	require.NoError(t, err)
+	defer pc.Close() //nolint:errcheck
+
+	_, err = pc.AddTransceiverFromKind(pwebrtc.RTPCodecTypeVideo, pwebrtc.RTPTransceiverInit{
+		Direction: pwebrtc.RTPTransceiverDirectionRecvonly,
+	})
+	require.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost,
+		"http://localhost:8886/teststream/whep", bytes.NewReader([]byte(offer.SDP)))
+	require.NoError(t, err)
+
+	req.Header.Set("Content-Type", "application/sdp")
+
+	res, err := hc.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	answer, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	require.Contains(t, string(answer), "m=video ")
+	// test.FormatH265 is Main 10, therefore profile-id=2 must be picked
+	require.Contains(t, string(answer), "a=rtpmap:103 H265/90000")
+	require.NotContains(t, string(answer), "a=rtpmap:104 H265/90000")
+	require.NotContains(t, string(answer), "VP8")
+}
+
 func TestServerReadNotFound(t *testing.T) {
 	pm := &dummyPathManager{
 		findPathConf: func(_ defs.PathFindPathConfReq) (*conf.Path, error) {
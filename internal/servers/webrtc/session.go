@@ -32,6 +32,22 @@ func whipOffer(body []byte) *pwebrtc.SessionDescription {
 	}
 }
 
+// filterMediasByType returns the medias of the given type.
+// When the type is empty, all medias are returned.
+func filterMediasByType(medias []*description.Media, typ description.MediaType) []*description.Media {
+	if typ == "" {
+		return medias
+	}
+
+	var ret []*description.Media
+	for _, medi := range medias {
+		if medi.Type == typ {
+			ret = append(ret, medi)
+		}
+	}
+	return ret
+}
+
 type session struct {
 	parentCtx             context.Context
 	ipsFromInterfaces     bool
@@ -138,6 +154,11 @@ func (s *session) runPublish() (int, error) {
 		},
 	})
 	if err != nil {
+		var lerr defs.PathPublisherThrottledError
+		if errors.As(err, &lerr) {
+			return http.StatusTooManyRequests, err
+		}
+
 		return http.StatusBadRequest, err
 	}
 
@@ -150,6 +171,7 @@ func (s *session) runPublish() (int, error) {
 
 	pc := &webrtc.PeerConnection{
 		ICEServers:            iceServers,
+		ICETransportPolicy:    s.parent.iceTransportPolicy(),
 		HandshakeTimeout:      s.parent.HandshakeTimeout,
 		TrackGatherTimeout:    s.parent.TrackGatherTimeout,
 		IPsFromInterfaces:     s.ipsFromInterfaces,
@@ -158,6 +180,7 @@ func (s *session) runPublish() (int, error) {
 		ICEUDPMux:             s.iceUDPMux,
 		ICETCPMux:             s.iceTCPMux,
 		Publish:               false,
+		MaxBitrate:            path.SafeConf().WebRTCMaxBitrate,
 		Log:                   s,
 	}
 	err = pc.Start()
@@ -223,6 +246,8 @@ func (s *session) runPublish() (int, error) {
 		return 0, err
 	}
 
+	stream.SetKeyFrameRequester(pc.RequestKeyFrame)
+
 	pc.StartReading()
 
 	select {
@@ -237,6 +262,18 @@ func (s *session) runPublish() (int, error) {
 func (s *session) runRead() (int, error) {
 	ip, _, _ := net.SplitHostPort(s.req.remoteAddr)
 
+	query := s.req.httpRequest.URL.Query()
+
+	proxy, err := defs.ParseReaderQuality(query.Get("quality"))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	mediaType := query.Get("media")
+	if mediaType != "" && mediaType != "audio" && mediaType != "video" {
+		return http.StatusBadRequest, fmt.Errorf("invalid media '%s'", mediaType)
+	}
+
 	path, stream, err := s.pathManager.AddReader(defs.PathAddReaderReq{
 		Author: s,
 		AccessRequest: defs.PathAccessRequest{
@@ -246,6 +283,7 @@ func (s *session) runRead() (int, error) {
 			ID:          &s.uuid,
 			HTTPRequest: s.req.httpRequest,
 		},
+		Proxy: proxy,
 	})
 	if err != nil {
 		var terr2 defs.PathNoOnePublishingError
@@ -265,6 +303,7 @@ func (s *session) runRead() (int, error) {
 
 	pc := &webrtc.PeerConnection{
 		ICEServers:            iceServers,
+		ICETransportPolicy:    s.parent.iceTransportPolicy(),
 		HandshakeTimeout:      s.parent.HandshakeTimeout,
 		TrackGatherTimeout:    s.parent.TrackGatherTimeout,
 		IPsFromInterfaces:     s.ipsFromInterfaces,
@@ -276,7 +315,12 @@ func (s *session) runRead() (int, error) {
 		Log:                   s,
 	}
 
-	err = webrtc.FromStream(stream, s, pc)
+	medias := filterMediasByType(stream.ReaderMedias(proxy), description.MediaType(mediaType))
+	if medias == nil {
+		return http.StatusBadRequest, fmt.Errorf("the stream doesn't contain any %s track", mediaType)
+	}
+
+	err = webrtc.FromStream(stream, s, medias, pc)
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
@@ -323,7 +367,7 @@ func (s *session) runRead() (int, error) {
 	})
 	defer onUnreadHook()
 
-	stream.StartReader(s)
+	stream.StartReaderFromKeyFrame(s)
 	defer stream.RemoveReader(s)
 
 	select {
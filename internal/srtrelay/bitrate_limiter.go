@@ -0,0 +1,45 @@
+package srtrelay
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// bitrateLimiter paces writes in order not to exceed a bitrate.
+// When a write would be delayed by more than maxDelay, an error is returned.
+type bitrateLimiter struct {
+	w        io.Writer
+	bitrate  uint64
+	maxDelay time.Duration
+
+	start time.Time
+	sent  uint64
+}
+
+func (l *bitrateLimiter) due() time.Time {
+	return l.start.Add(time.Duration(float64(l.sent*8) * float64(time.Second) / float64(l.bitrate)))
+}
+
+// Write implements io.Writer.
+func (l *bitrateLimiter) Write(p []byte) (int, error) {
+	now := time.Now()
+
+	// restart pacing after pauses, in order not to accumulate
+	// credit that would be spent in a burst.
+	if l.start.IsZero() || now.Sub(l.due()) > time.Second {
+		l.start = now
+		l.sent = 0
+	}
+
+	if delay := l.due().Sub(now); delay > 0 {
+		if delay > l.maxDelay {
+			return 0, fmt.Errorf("stream bitrate is higher than %d bit/s", l.bitrate)
+		}
+		time.Sleep(delay)
+	}
+
+	l.sent += uint64(len(p))
+
+	return l.w.Write(p)
+}
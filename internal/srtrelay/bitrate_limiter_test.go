@@ -0,0 +1,37 @@
+package srtrelay
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitrateLimiter(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := &bitrateLimiter{
+		w:        &buf,
+		bitrate:  800000,
+		maxDelay: time.Second,
+	}
+
+	start := time.Now()
+
+	for i := 0; i < 10; i++ {
+		_, err := l.Write(make([]byte, 1000))
+		require.NoError(t, err)
+	}
+
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	require.Equal(t, 10000, buf.Len())
+
+	l.maxDelay = 10 * time.Millisecond
+
+	_, err := l.Write(make([]byte, 100000))
+	require.NoError(t, err)
+
+	_, err = l.Write(make([]byte, 1000))
+	require.EqualError(t, err, "stream bitrate is higher than 800000 bit/s")
+}
@@ -0,0 +1,257 @@
+// Package srtrelay contains the SRT relay.
+package srtrelay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	srt "github.com/datarhei/gosrt"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/protocols/mpegts"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+const (
+	defaultMinBackoff = 1 * time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+func srtMaxPayloadSize(u int) int {
+	return ((u - 16) / 188) * 188 // 16 = SRT header, 188 = MPEG-TS packet
+}
+
+// Relay forwards a stream to a SRT destination, in caller mode.
+// The connection is re-opened with an exponential backoff when it fails.
+type Relay struct {
+	URL               string
+	MaxBitrate        uint64
+	WriteTimeout      time.Duration
+	UDPMaxPayloadSize int
+	PIDs              mpegts.PIDs
+	Service           mpegts.Service
+	Stream            *stream.Stream
+	Parent            logger.Writer
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	ctx       context.Context
+	ctxCancel func()
+
+	mutex      sync.Mutex
+	state      defs.APIPathSRTRelayState
+	reconnects uint64
+	lastError  error
+	sconn      srt.Conn
+	bytesSent  uint64
+
+	done chan struct{}
+}
+
+// Initialize initializes Relay.
+func (r *Relay) Initialize() {
+	if r.minBackoff == 0 {
+		r.minBackoff = defaultMinBackoff
+	}
+	if r.maxBackoff == 0 {
+		r.maxBackoff = defaultMaxBackoff
+	}
+
+	r.ctx, r.ctxCancel = context.WithCancel(context.Background())
+	r.state = defs.APIPathSRTRelayStateConnecting
+	r.done = make(chan struct{})
+
+	r.Log(logger.Info, "started")
+
+	go r.run()
+}
+
+// Close closes the relay.
+func (r *Relay) Close() {
+	r.Log(logger.Info, "stopped")
+	r.ctxCancel()
+	<-r.done
+}
+
+// Log implements logger.Writer.
+func (r *Relay) Log(level logger.Level, format string, args ...interface{}) {
+	r.Parent.Log(level, "[SRT relay] "+format, args...)
+}
+
+// APIItem returns the health of the relay.
+func (r *Relay) APIItem() *defs.APIPathSRTRelay {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	item := &defs.APIPathSRTRelay{
+		State:      r.state,
+		Reconnects: r.reconnects,
+		BytesSent:  r.bytesSent,
+	}
+
+	if r.lastError != nil {
+		v := r.lastError.Error()
+		item.LastError = &v
+	}
+
+	if r.sconn != nil {
+		var s srt.Statistics
+		r.sconn.Stats(&s)
+		item.BytesSent += s.Accumulated.ByteSent
+		item.MsRTT = s.Instantaneous.MsRTT
+	}
+
+	return item
+}
+
+func (r *Relay) run() {
+	defer close(r.done)
+
+	backoff := r.minBackoff
+
+	for {
+		connected, err := r.runInner()
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		r.Log(logger.Warn, "%v", err)
+
+		if connected {
+			backoff = r.minBackoff
+		}
+
+		r.mutex.Lock()
+		r.state = defs.APIPathSRTRelayStateWaiting
+		r.lastError = err
+		r.mutex.Unlock()
+
+		select {
+		case <-time.After(backoff):
+		case <-r.ctx.Done():
+			return
+		}
+
+		backoff = min(backoff*2, r.maxBackoff)
+
+		r.mutex.Lock()
+		r.state = defs.APIPathSRTRelayStateConnecting
+		r.reconnects++
+		r.mutex.Unlock()
+	}
+}
+
+func (r *Relay) runInner() (bool, error) {
+	conf := srt.DefaultConfig()
+	address, err := conf.UnmarshalURL(r.URL)
+	if err != nil {
+		return false, err
+	}
+
+	err = conf.Validate()
+	if err != nil {
+		return false, err
+	}
+
+	sconn, err := r.dial(address, conf)
+	if err != nil {
+		return false, err
+	}
+
+	r.mutex.Lock()
+	r.state = defs.APIPathSRTRelayStateConnected
+	r.sconn = sconn
+	r.mutex.Unlock()
+
+	defer func() {
+		var s srt.Statistics
+		sconn.Stats(&s)
+
+		r.mutex.Lock()
+		r.sconn = nil
+		r.bytesSent += s.Accumulated.ByteSent
+		r.mutex.Unlock()
+
+		sconn.Close()
+	}()
+
+	r.Log(logger.Info, "connected to %s", address)
+
+	var w io.Writer = sconn
+
+	if r.MaxBitrate != 0 {
+		w = &bitrateLimiter{
+			w:        sconn,
+			bitrate:  r.MaxBitrate,
+			maxDelay: r.WriteTimeout,
+		}
+	}
+
+	bw := bufio.NewWriterSize(w, srtMaxPayloadSize(r.UDPMaxPayloadSize))
+
+	err = mpegts.FromStream(r.Stream, r, r.Stream.Desc().Medias, r.PIDs, r.Service, bw, sconn, r.WriteTimeout, nil)
+	if err != nil {
+		return true, err
+	}
+
+	// the destination is not supposed to send data,
+	// therefore reads are used to detect when the connection is closed.
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			_, err := sconn.Read(buf)
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	r.Stream.StartReader(r)
+	defer r.Stream.RemoveReader(r)
+
+	select {
+	case err = <-r.Stream.ReaderError(r):
+		return true, err
+
+	case err = <-readErr:
+		return true, err
+
+	case <-r.ctx.Done():
+		return true, fmt.Errorf("terminated")
+	}
+}
+
+func (r *Relay) dial(address string, conf srt.Config) (srt.Conn, error) {
+	type dialRes struct {
+		sconn srt.Conn
+		err   error
+	}
+
+	ch := make(chan dialRes, 1)
+	go func() {
+		sconn, err := srt.Dial("srt", address, conf)
+		ch <- dialRes{sconn, err}
+	}()
+
+	select {
+	case res := <-ch:
+		return res.sconn, res.err
+
+	case <-r.ctx.Done():
+		go func() {
+			res := <-ch
+			if res.err == nil {
+				res.sconn.Close()
+			}
+		}()
+		return nil, fmt.Errorf("terminated")
+	}
+}
@@ -0,0 +1,85 @@
+package srtrelay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	srt "github.com/datarhei/gosrt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/defs"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func TestRelay(t *testing.T) {
+	ln, err := srt.Listen("srt", "127.0.0.1:9003", srt.DefaultConfig())
+	require.NoError(t, err)
+	defer ln.Close()
+
+	desc := &description.Session{Medias: []*description.Media{test.MediaH264}}
+
+	strm, err := stream.New(512, 1460, desc, true, 0, 0, 0, test.NilLogger)
+	require.NoError(t, err)
+	defer strm.Close()
+
+	r := &Relay{
+		URL:               "srt://127.0.0.1:9003?streamid=publish:mypath",
+		WriteTimeout:      10 * time.Second,
+		UDPMaxPayloadSize: 1472,
+		Stream:            strm,
+		Parent:            test.NilLogger,
+		minBackoff:        100 * time.Millisecond,
+	}
+	r.Initialize()
+	defer r.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			strm.WriteUnit(test.MediaH264, test.FormatH264, &unit.H264{
+				Base: unit.Base{
+					PTS: int64(i) * 4500,
+				},
+				AU: [][]byte{{5, 1}},
+			})
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		req, err := ln.Accept2()
+		require.NoError(t, err)
+		require.Equal(t, "publish:mypath", req.StreamId())
+
+		conn, err := req.Accept()
+		require.NoError(t, err)
+
+		buf := make([]byte, 1500)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		require.NotZero(t, n)
+		require.Zero(t, n%188)
+		require.Equal(t, byte(0x47), buf[0])
+
+		item := r.APIItem()
+		require.Equal(t, defs.APIPathSRTRelayStateConnected, item.State)
+		require.Equal(t, uint64(i), item.Reconnects)
+		require.NotZero(t, item.BytesSent)
+
+		// closing the connection causes the relay to reconnect
+		conn.Close()
+	}
+
+	item := r.APIItem()
+	require.NotNil(t, item.LastError)
+}
@@ -2,7 +2,9 @@
 package hls
 
 import (
+	"errors"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/gohlslib/v2"
@@ -16,6 +18,52 @@ import (
 	"github.com/bluenviron/mediamtx/internal/stream"
 )
 
+// statusCodeRecorder is a http.RoundTripper that records the status code of the last response.
+type statusCodeRecorder struct {
+	http.RoundTripper
+	statusCode int64
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *statusCodeRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := r.RoundTripper.RoundTrip(req)
+	if err == nil {
+		atomic.StoreInt64(&r.statusCode, int64(res.StatusCode))
+	}
+	return res, err
+}
+
+func isPermanentStatusCode(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 &&
+		statusCode != http.StatusRequestTimeout &&
+		statusCode != http.StatusTooManyRequests
+}
+
+// classifyError marks errors that can't be solved by retrying as permanent.
+// Once the stream is ready, errors are always considered transient,
+// since the playlist may be temporarily unavailable.
+func classifyError(err error, ready bool, statusCode int) error {
+	if ready {
+		return err
+	}
+
+	if isPermanentStatusCode(statusCode) {
+		return defs.StaticSourcePermanentError{Err: err}
+	}
+
+	// errors returned by gohlslib when no track can be read
+	switch err.Error() {
+	case "no variants with supported codecs found", "no supported tracks found":
+		return defs.StaticSourcePermanentError{Err: err}
+	}
+
+	if errors.Is(err, hls.ErrNoSupportedCodecs) {
+		return defs.StaticSourcePermanentError{Err: err}
+	}
+
+	return err
+}
+
 // Source is a HLS static source.
 type Source struct {
 	ReadTimeout conf.StringDuration
@@ -44,12 +92,14 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 	}
 	defer tr.CloseIdleConnections()
 
+	rec := &statusCodeRecorder{RoundTripper: tr}
+
 	var c *gohlslib.Client
 	c = &gohlslib.Client{
 		URI: params.ResolvedSource,
 		HTTPClient: &http.Client{
 			Timeout:   time.Duration(s.ReadTimeout),
-			Transport: tr,
+			Transport: rec,
 		},
 		OnDownloadPrimaryPlaylist: func(u string) {
 			s.Log(logger.Debug, "downloading primary playlist %v", u)
@@ -88,14 +138,14 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 	err := c.Start()
 	if err != nil {
-		return err
+		return defs.StaticSourcePermanentError{Err: err}
 	}
 
 	for {
 		select {
 		case err := <-c.Wait():
 			c.Close()
-			return err
+			return classifyError(err, stream != nil, int(atomic.LoadInt64(&rec.statusCode)))
 
 		case <-params.ReloadConf:
 
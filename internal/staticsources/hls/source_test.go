@@ -2,9 +2,12 @@ package hls
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/bluenviron/mediacommon/pkg/codecs/mpeg4audio"
 	"github.com/bluenviron/mediacommon/pkg/formats/mpegts"
@@ -100,3 +103,46 @@ func TestSource(t *testing.T) {
 
 	<-te.Unit
 }
+
+func TestSourceErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name       string
+		statusCode int
+		permanent  bool
+	}{
+		{"not found", http.StatusNotFound, true},
+		{"service unavailable", http.StatusServiceUnavailable, false},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			gin.SetMode(gin.ReleaseMode)
+			router := gin.New()
+
+			router.GET("/stream.m3u8", func(ctx *gin.Context) {
+				ctx.AbortWithStatus(ca.statusCode)
+			})
+
+			s := &http.Server{Handler: router}
+
+			ln, err := net.Listen("tcp", "localhost:5780")
+			require.NoError(t, err)
+
+			go s.Serve(ln)
+			defer s.Shutdown(context.Background())
+
+			so := &Source{
+				ReadTimeout: conf.StringDuration(10 * time.Second),
+				Parent:      &test.SourceTester{},
+			}
+
+			err = so.Run(defs.StaticSourceRunParams{
+				Context:        context.Background(),
+				ResolvedSource: "http://localhost:5780/stream.m3u8",
+				Conf:           &conf.Path{},
+			})
+			require.EqualError(t, err, fmt.Sprintf("bad status code: %d", ca.statusCode))
+
+			var perr defs.StaticSourcePermanentError
+			require.Equal(t, ca.permanent, errors.As(err, &perr))
+		})
+	}
+}
@@ -2,11 +2,14 @@
 package rtsp
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/headers"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 
 	"github.com/bluenviron/mediamtx/internal/conf"
@@ -137,12 +140,27 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 
 			defer s.Parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
 
+			// SSRCs of video medias, needed to request key frames
+			videoSSRCs := make(map[*description.Media]*int64)
+
+			for _, medi := range desc.Medias {
+				if medi.Type == description.MediaTypeVideo {
+					ssrc := int64(-1)
+					videoSSRCs[medi] = &ssrc
+				}
+			}
+
 			for _, medi := range desc.Medias {
 				for _, forma := range medi.Formats {
 					cmedi := medi
 					cforma := forma
+					ssrc := videoSSRCs[cmedi]
 
 					c.OnPacketRTP(cmedi, cforma, func(pkt *rtp.Packet) {
+						if ssrc != nil {
+							atomic.StoreInt64(ssrc, int64(pkt.SSRC))
+						}
+
 						pts, ok := c.PacketPTS2(cmedi, pkt)
 						if !ok {
 							return
@@ -163,6 +181,17 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 				return err
 			}
 
+			res.Stream.SetKeyFrameRequester(func() {
+				for medi, ssrc := range videoSSRCs {
+					v := atomic.LoadInt64(ssrc)
+					if v >= 0 {
+						c.WritePacketRTCP(medi, &rtcp.PictureLossIndication{ //nolint:errcheck
+							MediaSSRC: uint32(v),
+						})
+					}
+				}
+			})
+
 			return c.Wait()
 		}()
 	}()
@@ -50,8 +50,9 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 			Timeout:   time.Duration(s.ReadTimeout),
 			Transport: tr,
 		},
-		URL: u,
-		Log: s,
+		URL:        u,
+		MaxBitrate: params.Conf.WebRTCMaxBitrate,
+		Log:        s,
 	}
 
 	_, err = client.Read(params.Context)
@@ -76,6 +77,7 @@ func (s *Source) Run(params defs.StaticSourceRunParams) error {
 	}
 
 	stream = rres.Stream
+	stream.SetKeyFrameRequester(client.PeerConnection().RequestKeyFrame)
 
 	defer s.Parent.SetNotReady(defs.PathSourceStaticSetNotReadyReq{})
 
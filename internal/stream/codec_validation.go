@@ -0,0 +1,167 @@
+package stream
+
+import (
+	"fmt"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/bits"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+)
+
+var h264Profiles = map[uint8]struct{}{
+	44:  {},
+	66:  {},
+	77:  {},
+	83:  {},
+	86:  {},
+	88:  {},
+	100: {},
+	110: {},
+	118: {},
+	122: {},
+	128: {},
+	134: {},
+	135: {},
+	138: {},
+	139: {},
+	144: {},
+	244: {},
+}
+
+// h264PPSSPSID returns the ID of the SPS referenced by a H264 PPS.
+func h264PPSSPSID(pps []byte) (uint32, error) {
+	if len(pps) < 2 {
+		return 0, fmt.Errorf("not enough bits")
+	}
+
+	if h264.NALUType(pps[0]&0x1F) != h264.NALUTypePPS {
+		return 0, fmt.Errorf("not a PPS")
+	}
+
+	buf := h264.EmulationPreventionRemove(pps[1:])
+	pos := 0
+
+	// pic_parameter_set_id
+	_, err := bits.ReadGolombUnsigned(buf, &pos)
+	if err != nil {
+		return 0, err
+	}
+
+	// seq_parameter_set_id
+	return bits.ReadGolombUnsigned(buf, &pos)
+}
+
+func validateH264(forma *format.H264) error {
+	sps, pps := forma.SafeParams()
+
+	if sps == nil {
+		if pps != nil {
+			return fmt.Errorf("PPS is present but SPS is not")
+		}
+		return nil
+	}
+
+	var spsp h264.SPS
+	err := spsp.Unmarshal(sps)
+	if err != nil {
+		return fmt.Errorf("invalid SPS: %w", err)
+	}
+
+	if _, ok := h264Profiles[spsp.ProfileIdc]; !ok {
+		return fmt.Errorf("SPS contains an invalid profile (%d)", spsp.ProfileIdc)
+	}
+
+	if spsp.Width() <= 0 || spsp.Height() <= 0 {
+		return fmt.Errorf("SPS contains an invalid resolution (%dx%d)", spsp.Width(), spsp.Height())
+	}
+
+	if pps != nil {
+		spsID, err := h264PPSSPSID(pps)
+		if err != nil {
+			return fmt.Errorf("invalid PPS: %w", err)
+		}
+
+		if spsID != spsp.ID {
+			return fmt.Errorf("PPS refers to SPS %d, while SPS has ID %d", spsID, spsp.ID)
+		}
+	}
+
+	return nil
+}
+
+func validateH265(forma *format.H265) error {
+	vps, sps, pps := forma.SafeParams()
+
+	if sps == nil {
+		if vps != nil || pps != nil {
+			return fmt.Errorf("VPS or PPS are present but SPS is not")
+		}
+		return nil
+	}
+
+	var spsp h265.SPS
+	err := spsp.Unmarshal(sps)
+	if err != nil {
+		return fmt.Errorf("invalid SPS: %w", err)
+	}
+
+	if profile := spsp.ProfileTierLevel.GeneralProfileIdc; profile > 11 {
+		return fmt.Errorf("SPS contains an invalid profile (%d)", profile)
+	}
+
+	if spsp.Width() <= 0 || spsp.Height() <= 0 {
+		return fmt.Errorf("SPS contains an invalid resolution (%dx%d)", spsp.Width(), spsp.Height())
+	}
+
+	if vps != nil {
+		if len(vps) < 3 || h265.NALUType((vps[0]>>1)&0b111111) != h265.NALUType_VPS_NUT {
+			return fmt.Errorf("invalid VPS")
+		}
+
+		vpsID := vps[2] >> 4
+		if vpsID != spsp.VPSID {
+			return fmt.Errorf("SPS refers to VPS %d, while VPS has ID %d", spsp.VPSID, vpsID)
+		}
+	}
+
+	if pps != nil {
+		var ppsp h265.PPS
+		err = ppsp.Unmarshal(pps)
+		if err != nil {
+			return fmt.Errorf("invalid PPS: %w", err)
+		}
+
+		if ppsp.SPSID != uint32(spsp.ID) {
+			return fmt.Errorf("PPS refers to SPS %d, while SPS has ID %d", ppsp.SPSID, spsp.ID)
+		}
+	}
+
+	return nil
+}
+
+// ValidateCodecs checks that the parameter sets of the tracks of a stream
+// can be decoded and are consistent with each other.
+// Parameter sets that are not provided are not checked.
+func ValidateCodecs(desc *description.Session) error {
+	for i, medi := range desc.Medias {
+		for _, forma := range medi.Formats {
+			var err error
+
+			switch forma := forma.(type) {
+			case *format.H264:
+				err = validateH264(forma)
+
+			case *format.H265:
+				err = validateH265(forma)
+			}
+
+			if err != nil {
+				return fmt.Errorf("track %d (%s): %w", i+1, forma.Codec(), err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/stretchr/testify/require"
+)
+
+var testSPS = []byte{ // 1920x1080 baseline
+	0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02,
+	0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+	0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20,
+}
+
+var testPPS = []byte{0x68, 0xce, 0x3c, 0x80}
+
+func TestValidateCodecs(t *testing.T) {
+	for _, ca := range []struct {
+		name  string
+		forma format.Format
+		err   string
+	}{
+		{
+			"h264 valid",
+			&format.H264{PayloadTyp: 96, SPS: testSPS, PPS: testPPS},
+			"",
+		},
+		{
+			"h264 without parameters",
+			&format.H264{PayloadTyp: 96},
+			"",
+		},
+		{
+			"h264 invalid sps",
+			&format.H264{PayloadTyp: 96, SPS: []byte{0x67, 0x42, 0xc0}, PPS: testPPS},
+			"track 1 (H264): invalid SPS: not enough bits",
+		},
+		{
+			"h264 invalid profile",
+			&format.H264{PayloadTyp: 96, SPS: append([]byte{0x67, 0x01}, testSPS[2:]...), PPS: testPPS},
+			"track 1 (H264): SPS contains an invalid profile (1)",
+		},
+		{
+			"h264 pps of another sps",
+			&format.H264{PayloadTyp: 96, SPS: testSPS, PPS: []byte{0x68, 0xa3, 0x3c, 0x80}},
+			"track 1 (H264): PPS refers to SPS 1, while SPS has ID 0",
+		},
+		{
+			"h264 pps without sps",
+			&format.H264{PayloadTyp: 96, PPS: testPPS},
+			"track 1 (H264): PPS is present but SPS is not",
+		},
+		{
+			"h265 valid",
+			&format.H265{
+				PayloadTyp: 96,
+				VPS: []byte{
+					0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x02, 0x20,
+					0x00, 0x00, 0x03, 0x00, 0xb0, 0x00, 0x00, 0x03,
+					0x00, 0x00, 0x03, 0x00, 0x7b, 0x18, 0xb0, 0x24,
+				},
+				SPS: []byte{
+					0x42, 0x01, 0x01, 0x02, 0x20, 0x00, 0x00, 0x03,
+					0x00, 0xb0, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03,
+					0x00, 0x7b, 0xa0, 0x07, 0x82, 0x00, 0x88, 0x7d,
+					0xb6, 0x71, 0x8b, 0x92, 0x44, 0x80, 0x53, 0x88,
+					0x88, 0x92, 0xcf, 0x24, 0xa6, 0x92, 0x72, 0xc9,
+					0x12, 0x49, 0x22, 0xdc, 0x91, 0xaa, 0x48, 0xfc,
+					0xa2, 0x23, 0xff, 0x00, 0x01, 0x00, 0x01, 0x6a,
+					0x02, 0x02, 0x02, 0x01,
+				},
+				PPS: []byte{
+					0x44, 0x01, 0xc0, 0x25, 0x2f, 0x05, 0x32, 0x40,
+				},
+			},
+			"",
+		},
+		{
+			"h265 invalid sps",
+			&format.H265{PayloadTyp: 96, SPS: []byte{0x42, 0x01, 0x01}},
+			"track 1 (H265): invalid SPS: not enough bits",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			err := ValidateCodecs(&description.Session{Medias: []*description.Media{{
+				Type:    description.MediaTypeVideo,
+				Formats: []format.Format{ca.forma},
+			}}})
+			if ca.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, ca.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+const (
+	gopCacheMaxUnits = 256
+	gopCacheMaxSize  = 16 * 1024 * 1024
+)
+
+// formatHasGOPCache returns whether units of a format can be cached starting from a random access unit.
+func formatHasGOPCache(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.H264, *format.H265:
+		return true
+	}
+	return false
+}
+
+// gopCache stores the units received since the last random access unit,
+// in order to allow new readers to start decoding without waiting for the next one.
+// When the group of pictures is too long, the cache is emptied
+// until the next random access unit.
+type gopCache struct {
+	units []unit.Unit
+	size  uint64
+}
+
+func (c *gopCache) push(u unit.Unit, size uint64) {
+	complete, randomAccess := unitRandomAccess(u)
+	if !complete {
+		return
+	}
+
+	if randomAccess {
+		c.units = []unit.Unit{u}
+		c.size = size
+		return
+	}
+
+	if c.units == nil {
+		return
+	}
+
+	if len(c.units) >= gopCacheMaxUnits || (c.size+size) > gopCacheMaxSize {
+		c.reset()
+		return
+	}
+
+	c.units = append(c.units, u)
+	c.size += size
+}
+
+func (c *gopCache) reset() {
+	c.units = nil
+	c.size = 0
+}
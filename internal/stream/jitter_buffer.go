@@ -0,0 +1,183 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// maximum number of packets that can be held by a jitter buffer.
+	// When exceeded, the oldest packet is released without waiting.
+	jitterBufferMaxPackets = 1024
+)
+
+func seqDiff(a uint16, b uint16) int16 {
+	return int16(a - b)
+}
+
+type jitterBufferEntry struct {
+	pkt      *rtp.Packet
+	ntp      time.Time
+	pts      int64
+	deadline time.Time
+}
+
+// jitterBuffer reorders RTP packets by sequence number.
+// Packets that follow the last released one are released immediately,
+// while the others are held until missing packets arrive or the delay expires.
+// Packets that arrive after a following packet has been released are discarded.
+type jitterBuffer struct {
+	delay    time.Duration
+	onPacket func(pkt *rtp.Packet, ntp time.Time, pts int64, lost bool)
+	onLate   func()
+
+	mutex       sync.Mutex
+	queue       []*jitterBufferEntry
+	initialized bool
+	expectedSeq uint16
+
+	chNew     chan struct{}
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+func (jb *jitterBuffer) initialize() {
+	jb.chNew = make(chan struct{}, 1)
+	jb.terminate = make(chan struct{})
+	jb.done = make(chan struct{})
+
+	go jb.run()
+}
+
+func (jb *jitterBuffer) close() {
+	close(jb.terminate)
+	<-jb.done
+}
+
+func (jb *jitterBuffer) push(pkt *rtp.Packet, ntp time.Time, pts int64) {
+	jb.mutex.Lock()
+
+	if jb.initialized && seqDiff(pkt.SequenceNumber, jb.expectedSeq) < 0 {
+		jb.mutex.Unlock()
+		jb.onLate()
+		return
+	}
+
+	// find insertion point, starting from the end since packets are usually in order
+	i := len(jb.queue)
+	for i > 0 {
+		d := seqDiff(pkt.SequenceNumber, jb.queue[i-1].pkt.SequenceNumber)
+		if d == 0 { // duplicate
+			jb.mutex.Unlock()
+			return
+		}
+		if d > 0 {
+			break
+		}
+		i--
+	}
+
+	jb.queue = append(jb.queue, nil)
+	copy(jb.queue[i+1:], jb.queue[i:])
+	jb.queue[i] = &jitterBufferEntry{
+		pkt:      pkt,
+		ntp:      ntp,
+		pts:      pts,
+		deadline: time.Now().Add(jb.delay),
+	}
+
+	jb.mutex.Unlock()
+
+	select {
+	case jb.chNew <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the packets that can be released and the time at which
+// the next packet has to be released.
+func (jb *jitterBuffer) pop(now time.Time) ([]*jitterBufferEntry, []bool, time.Time) {
+	jb.mutex.Lock()
+	defer jb.mutex.Unlock()
+
+	// release every packet up to the last one whose deadline has expired
+	expired := -1
+	for i, e := range jb.queue {
+		if !now.Before(e.deadline) {
+			expired = i
+		}
+	}
+
+	if over := len(jb.queue) - jitterBufferMaxPackets - 1; over > expired {
+		expired = over
+	}
+
+	var entries []*jitterBufferEntry
+	var lost []bool
+
+	for len(jb.queue) > 0 {
+		e := jb.queue[0]
+
+		if jb.initialized && e.pkt.SequenceNumber != jb.expectedSeq && expired < 0 {
+			break
+		}
+
+		entries = append(entries, e)
+		lost = append(lost, jb.initialized && e.pkt.SequenceNumber != jb.expectedSeq)
+
+		jb.initialized = true
+		jb.expectedSeq = e.pkt.SequenceNumber + 1
+		jb.queue[0] = nil
+		jb.queue = jb.queue[1:]
+		expired--
+	}
+
+	var next time.Time
+	for _, e := range jb.queue {
+		if next.IsZero() || e.deadline.Before(next) {
+			next = e.deadline
+		}
+	}
+
+	return entries, lost, next
+}
+
+func (jb *jitterBuffer) run() {
+	defer close(jb.done)
+
+	timer := emptyTimer()
+	defer timer.Stop()
+
+	for {
+		entries, lost, next := jb.pop(time.Now())
+
+		for i, e := range entries {
+			jb.onPacket(e.pkt, e.ntp, e.pts, lost[i])
+		}
+
+		timer.Stop()
+		select {
+		case <-timer.C:
+		default:
+		}
+
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+
+		select {
+		case <-jb.chNew:
+		case <-timer.C:
+		case <-jb.terminate:
+			return
+		}
+	}
+}
+
+func emptyTimer() *time.Timer {
+	t := time.NewTimer(0)
+	<-t.C
+	return t
+}
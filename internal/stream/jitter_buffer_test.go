@@ -0,0 +1,143 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+type nilLogger struct{}
+
+func (nilLogger) Log(logger.Level, string, ...interface{}) {
+}
+
+type jitterBufferOutput struct {
+	seq  uint16
+	lost bool
+}
+
+func TestJitterBuffer(t *testing.T) {
+	out := make(chan jitterBufferOutput, 10)
+	late := make(chan struct{}, 10)
+
+	jb := &jitterBuffer{
+		delay: 200 * time.Millisecond,
+		onPacket: func(pkt *rtp.Packet, _ time.Time, _ int64, lost bool) {
+			out <- jitterBufferOutput{pkt.SequenceNumber, lost}
+		},
+		onLate: func() {
+			late <- struct{}{}
+		},
+	}
+	jb.initialize()
+	defer jb.close()
+
+	push := func(seq uint16) {
+		jb.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: seq}}, time.Time{}, 0)
+	}
+
+	// out-of-order packets are reordered within the window
+	for _, seq := range []uint16{65534, 0, 65535, 2, 1} {
+		push(seq)
+	}
+
+	for _, seq := range []uint16{65534, 65535, 0, 1, 2} {
+		require.Equal(t, jitterBufferOutput{seq, false}, <-out)
+	}
+
+	// missing packets are skipped when the window expires
+	start := time.Now()
+	push(5)
+	push(4)
+
+	require.Equal(t, jitterBufferOutput{4, true}, <-out)
+	require.Equal(t, jitterBufferOutput{5, false}, <-out)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	// packets later than the window are discarded
+	push(3)
+	<-late
+
+	push(6)
+	require.Equal(t, jitterBufferOutput{6, false}, <-out)
+
+	select {
+	case o := <-out:
+		t.Errorf("unexpected packet %v", o)
+	default:
+	}
+}
+
+func TestStreamJitterBufferRandomAccess(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+	desc := &description.Session{Medias: []*description.Media{medi}}
+
+	strm, err := New(512, 1460, desc, false, 50*time.Millisecond, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	received := make(chan byte, 10)
+
+	r := nilLogger{}
+	strm.AddReader(r, medi, forma, func(u unit.Unit) error {
+		tunit := u.(*unit.H264)
+		if tunit.AU != nil {
+			received <- tunit.AU[len(tunit.AU)-1][1]
+		}
+		return nil
+	})
+	strm.StartReader(r)
+	defer strm.RemoveReader(r)
+
+	write := func(seq uint16, idr bool) {
+		nalu := []byte{0x41, byte(seq)}
+		if idr {
+			nalu[0] = 0x65
+		}
+
+		strm.WriteRTPPacket(medi, forma, &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: seq,
+				Timestamp:      uint32(seq) * 3000,
+				SSRC:           1234,
+			},
+			Payload: nalu,
+		}, time.Now(), int64(seq)*3000)
+	}
+
+	write(1, true)
+	write(2, false)
+	// packet 3 is lost
+	write(4, false)
+	write(5, false)
+	write(6, true)
+	write(7, false)
+
+	// after the loss, the stream is resumed from the next IDR
+	for _, seq := range []byte{1, 2, 6, 7} {
+		select {
+		case v := <-received:
+			require.Equal(t, seq, v)
+		case <-time.After(2 * time.Second):
+			t.Errorf("timed out waiting for unit %d", seq)
+			return
+		}
+	}
+}
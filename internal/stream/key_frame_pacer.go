@@ -0,0 +1,46 @@
+package stream
+
+import (
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// keyFramePacer detects when no random access unit has been received
+// for the target interval, in order to ask the publisher for a key frame.
+// Requests are repeated every interval until a key frame is received,
+// since publishers can ignore them.
+type keyFramePacer struct {
+	interval int64 // in clock rate units
+
+	initialized      bool
+	lastRandomAccess int64
+	lastRequest      int64
+}
+
+// process returns whether a key frame has to be requested.
+func (p *keyFramePacer) process(u unit.Unit) bool {
+	complete, randomAccess := unitRandomAccess(u)
+	if !complete {
+		return false
+	}
+
+	pts := u.GetPTS()
+
+	if randomAccess {
+		p.initialized = true
+		p.lastRandomAccess = pts
+		p.lastRequest = pts
+		return false
+	}
+
+	// the first key frame is requested by readers when needed
+	if !p.initialized {
+		return false
+	}
+
+	if (pts - p.lastRequest) < p.interval {
+		return false
+	}
+
+	p.lastRequest = pts
+	return true
+}
@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+// reordering depths greater than this are considered timestamp discontinuities.
+const maxReorderDepth = 5 * time.Second
+
+// reorderMeter measures the reordering depth of a video track, that is
+// the maximum distance between the PTS of a unit and the greatest PTS
+// received before it. Depth is non-zero when the track contains B-frames,
+// that are transmitted in decoding order, and equals the maximum PTS-DTS spread.
+type reorderMeter struct {
+	clockRate int
+
+	initialized bool
+	maxPTS      int64
+	depth       int64 // in clock rate units, accessed atomically
+}
+
+func (m *reorderMeter) process(u unit.Unit) {
+	switch u.(type) {
+	case *unit.H264, *unit.H265:
+	default:
+		return
+	}
+
+	complete, randomAccess := unitRandomAccess(u)
+	if !complete {
+		return
+	}
+
+	pts := u.GetPTS()
+
+	// units that follow a random access unit cannot reference units that precede it,
+	// therefore the greatest PTS is reset.
+	if !m.initialized || randomAccess {
+		m.initialized = true
+		m.maxPTS = pts
+		return
+	}
+
+	if pts >= m.maxPTS {
+		m.maxPTS = pts
+		return
+	}
+
+	depth := m.maxPTS - pts
+
+	if depth > int64(maxReorderDepth)*int64(m.clockRate)/int64(time.Second) {
+		m.maxPTS = pts
+		return
+	}
+
+	if depth > atomic.LoadInt64(&m.depth) {
+		atomic.StoreInt64(&m.depth, depth)
+	}
+}
+
+// value returns the measured depth.
+func (m *reorderMeter) value() time.Duration {
+	return time.Duration(atomic.LoadInt64(&m.depth) * int64(time.Second) / int64(m.clockRate))
+}
@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtp"
+)
+
+// rtpContinuity rewrites SSRC, sequence numbers and timestamps of RTP packets
+// routed to readers, in order to provide them with a single uninterrupted RTP flow
+// when the stream is taken over by another publisher.
+type rtpContinuity struct {
+	clockRate int
+	isAudio   bool
+
+	initialized   bool
+	source        format.Format
+	ssrc          uint32
+	seqOffset     uint16
+	tsOffset      uint32
+	lastSeq       uint16
+	lastTimestamp uint32
+	lastTime      time.Time
+}
+
+// process rewrites packets written by the publisher that owns the given format.
+func (c *rtpContinuity) process(source format.Format, pkts []*rtp.Packet, now time.Time) {
+	if len(pkts) == 0 {
+		return
+	}
+
+	switch {
+	case !c.initialized:
+		c.initialized = true
+		c.source = source
+		c.ssrc = pkts[0].SSRC
+
+	case source != c.source:
+		c.source = source
+
+		// continue from the last packet, with a timestamp gap equal to the elapsed time.
+		elapsed := uint32(int64(now.Sub(c.lastTime)) * int64(c.clockRate) / int64(time.Second))
+		if elapsed == 0 {
+			elapsed = 1
+		}
+
+		c.seqOffset = c.lastSeq + 1 - pkts[0].SequenceNumber
+		c.tsOffset = c.lastTimestamp + elapsed - pkts[0].Timestamp
+
+		// in audio streams, the marker signals the beginning of a talkspurt,
+		// that is, a discontinuity in timestamps.
+		if c.isAudio {
+			pkts[0].Marker = true
+		}
+	}
+
+	for _, pkt := range pkts {
+		pkt.SSRC = c.ssrc
+		pkt.SequenceNumber += c.seqOffset
+		pkt.Timestamp += c.tsOffset
+	}
+
+	last := pkts[len(pkts)-1]
+	c.lastSeq = last.SequenceNumber
+	c.lastTimestamp = last.Timestamp
+	c.lastTime = now
+}
@@ -0,0 +1,122 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func TestRTPContinuity(t *testing.T) {
+	c := rtpContinuity{clockRate: 48000, isAudio: true}
+	source1 := &format.Opus{PayloadTyp: 96, ChannelCount: 2}
+	source2 := &format.Opus{PayloadTyp: 96, ChannelCount: 2}
+	now := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	pkt := &rtp.Packet{Header: rtp.Header{SequenceNumber: 100, Timestamp: 1000, SSRC: 1}}
+	c.process(source1, []*rtp.Packet{pkt}, now)
+	require.Equal(t, rtp.Header{SequenceNumber: 100, Timestamp: 1000, SSRC: 1}, pkt.Header)
+
+	now = now.Add(500 * time.Millisecond)
+
+	pkt = &rtp.Packet{Header: rtp.Header{SequenceNumber: 5000, Timestamp: 80000, SSRC: 2}}
+	c.process(source2, []*rtp.Packet{pkt}, now)
+	require.Equal(t, rtp.Header{Marker: true, SequenceNumber: 101, Timestamp: 1000 + 24000, SSRC: 1}, pkt.Header)
+
+	now = now.Add(20 * time.Millisecond)
+
+	pkt = &rtp.Packet{Header: rtp.Header{SequenceNumber: 5001, Timestamp: 80960, SSRC: 2}}
+	c.process(source2, []*rtp.Packet{pkt}, now)
+	require.Equal(t, rtp.Header{SequenceNumber: 102, Timestamp: 1000 + 24000 + 960, SSRC: 1}, pkt.Header)
+}
+
+func TestStreamPublisherChurn(t *testing.T) {
+	newDesc := func() (*description.Session, *description.Media, format.Format) {
+		forma := &format.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}
+		medi := &description.Media{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{forma},
+		}
+		return &description.Session{Medias: []*description.Media{medi}}, medi, forma
+	}
+
+	desc, medi, forma := newDesc()
+
+	strm, err := New(512, 1460, desc, false, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	received := make(chan rtp.Header, 100)
+
+	r := nilLogger{}
+	strm.AddReader(r, medi, forma, func(u unit.Unit) error {
+		for _, pkt := range u.GetRTPPackets() {
+			received <- pkt.Header
+		}
+		return nil
+	})
+	strm.StartReader(r)
+	defer strm.RemoveReader(r)
+
+	// each publisher uses its own SSRC, sequence numbers and timestamps
+	publish := func(pubMedi *description.Media, pubForma format.Format, ssrc uint32, seq uint16, ts uint32) {
+		for i := 0; i < 3; i++ {
+			strm.WriteRTPPacket(pubMedi, pubForma, &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					Marker:         true,
+					PayloadType:    96,
+					SequenceNumber: seq + uint16(i),
+					Timestamp:      ts + uint32(i)*3000,
+					SSRC:           ssrc,
+				},
+				Payload: []byte{0x65, byte(i)},
+			}, time.Now(), int64(i)*3000)
+		}
+	}
+
+	publish(medi, forma, 1234, 65534, 4294967000)
+
+	for _, pub := range []struct {
+		ssrc uint32
+		seq  uint16
+		ts   uint32
+	}{
+		{5678, 100, 1000},
+		{9012, 30000, 500000},
+	} {
+		pubDesc, pubMedi, pubForma := newDesc()
+		strm.AddDescAlias(pubDesc)
+		publish(pubMedi, pubForma, pub.ssrc, pub.seq, pub.ts)
+	}
+
+	var prev rtp.Header
+
+	for i := 0; i < 9; i++ {
+		var cur rtp.Header
+
+		select {
+		case cur = <-received:
+		case <-time.After(2 * time.Second):
+			t.Errorf("timed out waiting for packet %d", i)
+			return
+		}
+
+		require.Equal(t, uint32(1234), cur.SSRC)
+
+		if i != 0 {
+			require.Equal(t, prev.SequenceNumber+1, cur.SequenceNumber)
+			require.Greater(t, int32(cur.Timestamp-prev.Timestamp), int32(0))
+		}
+
+		prev = cur
+	}
+}
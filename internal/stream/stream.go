@@ -36,16 +36,32 @@ type Stream struct {
 	rtspStream    *gortsplib.ServerStream
 	rtspsStream   *gortsplib.ServerStream
 	streamReaders map[Reader]*streamReader
-
-	readerRunning chan struct{}
+	mediaAliases  map[*description.Media]*description.Media
+	formatAliases map[format.Format]format.Format
+
+	readerRunning     chan struct{}
+	keyFrameReceived  chan struct{}
+	keyFrameOnce      sync.Once
+	keyFrameRequester func()
+	proxySourceMedia  *description.Media
+	proxyMedia        *description.Media
 }
 
 // New allocates a Stream.
+// When jitterBufferDelay is not zero, RTP packets are reordered
+// and held for up to jitterBufferDelay before being routed.
+// When maxTimestampCorrection is not zero, timestamps are made non-negative and monotonic,
+// and decreases greater than maxTimestampCorrection are treated as discontinuities.
+// When rtcpSenderReportPeriod is not zero, RTCP sender reports are sent to RTSP readers
+// with this period, mapping RTP timestamps to the NTP timestamps of units.
 func New(
 	writeQueueSize int,
 	udpMaxPayloadSize int,
 	desc *description.Session,
 	generateRTPPackets bool,
+	jitterBufferDelay time.Duration,
+	maxTimestampCorrection time.Duration,
+	rtcpSenderReportPeriod time.Duration,
 	decodeErrLogger logger.Writer,
 ) (*Stream, error) {
 	s := &Stream{
@@ -58,13 +74,43 @@ func New(
 	s.streamMedias = make(map[*description.Media]*streamMedia)
 	s.streamReaders = make(map[Reader]*streamReader)
 	s.readerRunning = make(chan struct{})
+	s.keyFrameReceived = make(chan struct{})
+
+	hasKeyFrames := false
 
 	for _, media := range desc.Medias {
 		var err error
-		s.streamMedias[media], err = newStreamMedia(udpMaxPayloadSize, media, generateRTPPackets, decodeErrLogger)
+		s.streamMedias[media], err = newStreamMedia(udpMaxPayloadSize, media, generateRTPPackets,
+			jitterBufferDelay, maxTimestampCorrection, decodeErrLogger)
 		if err != nil {
 			return nil, err
 		}
+
+		for _, forma := range media.Formats {
+			if formatHasGOPCache(forma) {
+				hasKeyFrames = true
+			}
+		}
+	}
+
+	if !hasKeyFrames {
+		s.setKeyFrameReceived()
+	}
+
+	if jitterBufferDelay != 0 {
+		for medi, sm := range s.streamMedias {
+			for _, sf := range sm.formats {
+				sf.initializeJitterBuffer(s, medi)
+			}
+		}
+	}
+
+	if rtcpSenderReportPeriod != 0 {
+		for medi, sm := range s.streamMedias {
+			for _, sf := range sm.formats {
+				sf.initializeRTCPSender(s, medi, rtcpSenderReportPeriod)
+			}
+		}
 	}
 
 	return s, nil
@@ -72,6 +118,12 @@ func New(
 
 // Close closes all resources of the stream.
 func (s *Stream) Close() {
+	for _, sm := range s.streamMedias {
+		for _, sf := range sm.formats {
+			sf.close()
+		}
+	}
+
 	if s.rtspStream != nil {
 		s.rtspStream.Close()
 	}
@@ -85,11 +137,77 @@ func (s *Stream) Desc() *description.Session {
 	return s.desc
 }
 
+// AddDescAlias allows to write data with the medias and formats of desc,
+// that must contain the same tracks of the stream.
+// It is used to switch from a publisher to another without interrupting readers.
+func (s *Stream) AddDescAlias(desc *description.Session) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.mediaAliases == nil {
+		s.mediaAliases = make(map[*description.Media]*description.Media)
+		s.formatAliases = make(map[format.Format]format.Format)
+	}
+
+	for i, medi := range desc.Medias {
+		s.mediaAliases[medi] = s.desc.Medias[i]
+
+		for j, forma := range medi.Formats {
+			s.formatAliases[forma] = s.desc.Medias[i].Formats[j]
+		}
+	}
+}
+
+// resolveAlias returns the media and format of the stream corresponding to the given ones.
+// It must be called with the mutex locked.
+func (s *Stream) resolveAlias(medi *description.Media, forma format.Format) (*description.Media, format.Format) {
+	if aliasMedi, ok := s.mediaAliases[medi]; ok {
+		return aliasMedi, s.formatAliases[forma]
+	}
+	return medi, forma
+}
+
 // BytesReceived returns received bytes.
 func (s *Stream) BytesReceived() uint64 {
 	return atomic.LoadUint64(s.bytesReceived)
 }
 
+// TracksLastReceived returns the time at which each track last received data,
+// in the same order of Desc(). Tracks that never received data have a zero time.
+func (s *Stream) TracksLastReceived() []time.Time {
+	var ret []time.Time
+
+	for _, medi := range s.desc.Medias {
+		sm := s.streamMedias[medi]
+
+		for _, forma := range medi.Formats {
+			var t time.Time
+			if v := atomic.LoadInt64(sm.formats[forma].lastReceived); v != 0 {
+				t = time.Unix(0, v)
+			}
+			ret = append(ret, t)
+		}
+	}
+
+	return ret
+}
+
+// TracksReorderDepth returns the reordering depth of each track, that is the maximum spread
+// between PTS and DTS caused by B-frames, in the same order of Desc().
+func (s *Stream) TracksReorderDepth() []time.Duration {
+	var ret []time.Duration
+
+	for _, medi := range s.desc.Medias {
+		sm := s.streamMedias[medi]
+
+		for _, forma := range medi.Formats {
+			ret = append(ret, sm.formats[forma].reorderMeter.value())
+		}
+	}
+
+	return ret
+}
+
 // BytesSent returns sent bytes.
 func (s *Stream) BytesSent() uint64 {
 	s.mutex.RLock()
@@ -191,8 +309,123 @@ func (s *Stream) StartReader(reader Reader) {
 	}
 }
 
+// StartReaderFromKeyFrame starts a reader.
+// Reading starts from the last random access unit of video tracks, in order to allow
+// the reader to start decoding without waiting for the next one.
+// When a random access unit is not available, a key frame is requested to the publisher.
+// Used by all protocols except RTSP.
+func (s *Stream) StartReaderFromKeyFrame(reader Reader) {
+	requestKeyFrame := func() bool {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		sr := s.streamReaders[reader]
+
+		sr.start()
+
+		available := true
+
+		for _, sm := range s.streamMedias {
+			for _, sf := range sm.formats {
+				if !sf.startReaderFromKeyFrame(s, sr) {
+					available = false
+				}
+			}
+		}
+
+		select {
+		case <-s.readerRunning:
+		default:
+			close(s.readerRunning)
+		}
+
+		return !available
+	}()
+
+	if requestKeyFrame {
+		s.RequestKeyFrame()
+	}
+}
+
+// SetKeyFrameRequester sets a callback that asks the publisher to send a key frame.
+// It is used by publishers that support key frame requests.
+func (s *Stream) SetKeyFrameRequester(cb func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keyFrameRequester = cb
+}
+
+// RequestKeyFrame asks the publisher to send a key frame, if supported.
+func (s *Stream) RequestKeyFrame() {
+	s.mutex.RLock()
+	cb := s.keyFrameRequester
+	s.mutex.RUnlock()
+
+	if cb != nil {
+		cb()
+	}
+}
+
+// SetTargetKeyFrameInterval asks the publisher for a key frame
+// when no key frame has been received for the given interval.
+// It is effective with publishers that support key frame requests only.
+func (s *Stream) SetTargetKeyFrameInterval(interval time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, sm := range s.streamMedias {
+		for _, sf := range sm.formats {
+			if formatHasGOPCache(sf.format) {
+				sf.keyFramePacer = &keyFramePacer{
+					interval: int64(interval) * int64(sf.format.ClockRate()) / int64(time.Second),
+				}
+			}
+		}
+	}
+}
+
+// SetProxyMedia marks a media as a downscaled copy of another media.
+// The proxy media is provided only to readers that ask for it.
+func (s *Stream) SetProxyMedia(sourceMedia *description.Media, proxyMedia *description.Media) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.proxySourceMedia = sourceMedia
+	s.proxyMedia = proxyMedia
+}
+
+// ReaderMedias returns the medias that are provided to a reader.
+// When proxy is true, the source media is replaced by its proxy.
+// Otherwise, the proxy media is left out.
+func (s *Stream) ReaderMedias(proxy bool) []*description.Media {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.proxyMedia == nil {
+		return s.desc.Medias
+	}
+
+	medias := make([]*description.Media, 0, len(s.desc.Medias)-1)
+
+	for _, medi := range s.desc.Medias {
+		switch {
+		case medi == s.proxyMedia:
+		case medi == s.proxySourceMedia && proxy:
+			medias = append(medias, s.proxyMedia)
+		default:
+			medias = append(medias, medi)
+		}
+	}
+
+	return medias
+}
+
 // ReaderError returns whenever there's an error.
 func (s *Stream) ReaderError(reader Reader) chan error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	sr := s.streamReaders[reader]
 	return sr.error()
 }
@@ -218,6 +451,19 @@ func (s *Stream) ReaderFormats(reader Reader) []format.Format {
 	return formats
 }
 
+// KeyFrameReceived returns a channel that is closed when the first random access unit
+// of a H264 or H265 track is received.
+// When there are no such tracks, the channel is closed immediately.
+func (s *Stream) KeyFrameReceived() <-chan struct{} {
+	return s.keyFrameReceived
+}
+
+func (s *Stream) setKeyFrameReceived() {
+	s.keyFrameOnce.Do(func() {
+		close(s.keyFrameReceived)
+	})
+}
+
 // WaitRunningReader waits for a running reader.
 func (s *Stream) WaitRunningReader() {
 	<-s.readerRunning
@@ -225,13 +471,15 @@ func (s *Stream) WaitRunningReader() {
 
 // WriteUnit writes a Unit.
 func (s *Stream) WriteUnit(medi *description.Media, forma format.Format, u unit.Unit) {
-	sm := s.streamMedias[medi]
-	sf := sm.formats[forma]
-
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	sf.writeUnit(s, medi, u)
+	source := forma
+	medi, forma = s.resolveAlias(medi, forma)
+	sm := s.streamMedias[medi]
+	sf := sm.formats[forma]
+
+	sf.writeUnit(s, medi, source, u)
 }
 
 // WriteRTPPacket writes a RTP packet.
@@ -242,11 +490,13 @@ func (s *Stream) WriteRTPPacket(
 	ntp time.Time,
 	pts int64,
 ) {
-	sm := s.streamMedias[medi]
-	sf := sm.formats[forma]
-
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	sf.writeRTPPacket(s, medi, pkt, ntp, pts)
+	source := forma
+	medi, forma = s.resolveAlias(medi, forma)
+	sm := s.streamMedias[medi]
+	sf := sm.formats[forma]
+
+	sf.writeRTPPacket(s, medi, source, pkt, ntp, pts)
 }
@@ -6,6 +6,10 @@ import (
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/rtcpsender"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 
 	"github.com/bluenviron/mediamtx/internal/formatprocessor"
@@ -21,20 +25,61 @@ func unitSize(u unit.Unit) uint64 {
 	return n
 }
 
+// unitRandomAccess returns whether a unit contains a complete access unit
+// and whether the access unit is a random access one.
+func unitRandomAccess(u unit.Unit) (bool, bool) {
+	switch tunit := u.(type) {
+	case *unit.H264:
+		return tunit.AU != nil, h264.IDRPresent(tunit.AU)
+
+	case *unit.H265:
+		return tunit.AU != nil, h265.IsRandomAccess(tunit.AU)
+	}
+	return true, false
+}
+
 type streamFormat struct {
-	udpMaxPayloadSize  int
-	format             format.Format
-	generateRTPPackets bool
-	decodeErrLogger    logger.Writer
+	udpMaxPayloadSize      int
+	format                 format.Format
+	generateRTPPackets     bool
+	decodeErrLogger        logger.Writer
+	jitterBufferDelay      time.Duration
+	maxTimestampCorrection time.Duration
 
-	proc           formatprocessor.Processor
-	pausedReaders  map[*streamReader]ReadFunc
-	runningReaders map[*streamReader]ReadFunc
+	proc                      formatprocessor.Processor
+	pausedReaders             map[*streamReader]ReadFunc
+	runningReaders            map[*streamReader]ReadFunc
+	jitterBuffer              *jitterBuffer
+	waitingRandomAccess       bool
+	pendingUnits              []unit.Unit
+	continuity                rtpContinuity
+	lastReceived              *int64
+	reorderMeter              *reorderMeter
+	timestampCorrector        *timestampCorrector
+	timestampCorrectionLogger logger.Writer
+	gopCache                  *gopCache
+	keyFramePacer             *keyFramePacer
+	rtcpSender                *rtcpsender.RTCPSender
 }
 
-func (sf *streamFormat) initialize() error {
+func (sf *streamFormat) initialize(medi *description.Media) error {
 	sf.pausedReaders = make(map[*streamReader]ReadFunc)
 	sf.runningReaders = make(map[*streamReader]ReadFunc)
+	sf.lastReceived = new(int64)
+	sf.reorderMeter = &reorderMeter{clockRate: sf.format.ClockRate()}
+	sf.continuity = rtpContinuity{
+		clockRate: sf.format.ClockRate(),
+		isAudio:   medi.Type == description.MediaTypeAudio,
+	}
+
+	if sf.maxTimestampCorrection != 0 {
+		sf.timestampCorrector = newTimestampCorrector(sf.maxTimestampCorrection, sf.format)
+		sf.timestampCorrectionLogger = logger.NewLimitedLogger(sf.decodeErrLogger)
+	}
+
+	if formatHasGOPCache(sf.format) {
+		sf.gopCache = &gopCache{}
+	}
 
 	var err error
 	sf.proc, err = formatprocessor.New(sf.udpMaxPayloadSize, sf.format, sf.generateRTPPackets)
@@ -45,6 +90,54 @@ func (sf *streamFormat) initialize() error {
 	return nil
 }
 
+func (sf *streamFormat) initializeJitterBuffer(s *Stream, medi *description.Media) {
+	lateLogger := logger.NewLimitedLogger(sf.decodeErrLogger)
+
+	sf.jitterBuffer = &jitterBuffer{
+		delay: sf.jitterBufferDelay,
+		onPacket: func(pkt *rtp.Packet, ntp time.Time, pts int64, lost bool) {
+			s.mutex.RLock()
+			defer s.mutex.RUnlock()
+
+			sf.writeRTPPacketInner(s, medi, sf.format, pkt, ntp, pts, lost)
+		},
+		onLate: func() {
+			lateLogger.Log(logger.Warn, "RTP packet arrived after the jitter buffer delay, discarding")
+		},
+	}
+	sf.jitterBuffer.initialize()
+}
+
+// initializeRTCPSender allocates a RTCP sender that replaces the one of RTSP streams,
+// in order to fill NTP timestamps of sender reports with the ones of units.
+func (sf *streamFormat) initializeRTCPSender(s *Stream, medi *description.Media, period time.Duration) {
+	sf.rtcpSender = rtcpsender.New(
+		sf.format.ClockRate(),
+		period,
+		nil,
+		func(pkt rtcp.Packet) {
+			s.mutex.RLock()
+			defer s.mutex.RUnlock()
+
+			if s.rtspStream != nil {
+				s.rtspStream.WritePacketRTCP(medi, pkt) //nolint:errcheck
+			}
+			if s.rtspsStream != nil {
+				s.rtspsStream.WritePacketRTCP(medi, pkt) //nolint:errcheck
+			}
+		},
+	)
+}
+
+func (sf *streamFormat) close() {
+	if sf.jitterBuffer != nil {
+		sf.jitterBuffer.close()
+	}
+	if sf.rtcpSender != nil {
+		sf.rtcpSender.Close()
+	}
+}
+
 func (sf *streamFormat) addReader(sr *streamReader, cb ReadFunc) {
 	sf.pausedReaders[sr] = cb
 }
@@ -61,39 +154,188 @@ func (sf *streamFormat) startReader(sr *streamReader) {
 	}
 }
 
-func (sf *streamFormat) writeUnit(s *Stream, medi *description.Media, u unit.Unit) {
+// startReaderFromKeyFrame starts a reader and sends to it the units cached since the last random access unit.
+// It returns false when the format supports caching but the cache is empty.
+func (sf *streamFormat) startReaderFromKeyFrame(s *Stream, sr *streamReader) bool {
+	cb, ok := sf.pausedReaders[sr]
+	if !ok {
+		return true
+	}
+
+	delete(sf.pausedReaders, sr)
+	sf.runningReaders[sr] = cb
+
+	if sf.gopCache == nil {
+		return true
+	}
+
+	if sf.gopCache.units == nil {
+		return false
+	}
+
+	for _, u := range sf.gopCache.units {
+		cu := u
+		size := unitSize(u)
+		sr.push(func() error {
+			atomic.AddUint64(s.bytesSent, size)
+			return cb(cu)
+		})
+	}
+
+	return true
+}
+
+func (sf *streamFormat) writeUnit(s *Stream, medi *description.Media, source format.Format, u unit.Unit) {
 	err := sf.proc.ProcessUnit(u)
 	if err != nil {
 		sf.decodeErrLogger.Log(logger.Warn, err.Error())
 		return
 	}
 
-	sf.writeUnitInner(s, medi, u)
+	sf.writeUnitInner(s, medi, source, u)
 }
 
 func (sf *streamFormat) writeRTPPacket(
 	s *Stream,
 	medi *description.Media,
+	source format.Format,
+	pkt *rtp.Packet,
+	ntp time.Time,
+	pts int64,
+) {
+	if sf.jitterBuffer != nil {
+		sf.jitterBuffer.push(pkt, ntp, pts)
+		return
+	}
+
+	sf.writeRTPPacketInner(s, medi, source, pkt, ntp, pts, false)
+}
+
+func (sf *streamFormat) writeRTPPacketInner(
+	s *Stream,
+	medi *description.Media,
+	source format.Format,
 	pkt *rtp.Packet,
 	ntp time.Time,
 	pts int64,
+	lost bool,
 ) {
+	// after packets of a video track are lost, decoding is broken until the next random access unit.
+	if lost {
+		switch sf.format.(type) {
+		case *format.H264, *format.H265:
+			if !sf.waitingRandomAccess {
+				sf.decodeErrLogger.Log(logger.Warn, "RTP packets lost, waiting for the next random access unit")
+				sf.waitingRandomAccess = true
+			}
+			sf.pendingUnits = nil
+			sf.gopCache.reset()
+		}
+	}
+
 	hasNonRTSPReaders := len(sf.pausedReaders) > 0 || len(sf.runningReaders) > 0
 
-	u, err := sf.proc.ProcessRTPPacket(pkt, ntp, pts, hasNonRTSPReaders)
+	// units must be decoded in order to find random access units
+	u, err := sf.proc.ProcessRTPPacket(pkt, ntp, pts, hasNonRTSPReaders || sf.waitingRandomAccess)
 	if err != nil {
 		sf.decodeErrLogger.Log(logger.Warn, err.Error())
 		return
 	}
 
-	sf.writeUnitInner(s, medi, u)
+	if sf.waitingRandomAccess {
+		complete, randomAccess := unitRandomAccess(u)
+
+		// hold units until the access unit is complete
+		if !complete {
+			if len(sf.pendingUnits) < jitterBufferMaxPackets {
+				sf.pendingUnits = append(sf.pendingUnits, u)
+			}
+			return
+		}
+
+		if !randomAccess {
+			sf.pendingUnits = nil
+			return
+		}
+
+		sf.waitingRandomAccess = false
+
+		for _, pu := range sf.pendingUnits {
+			sf.writeUnitInner(s, medi, source, pu)
+		}
+		sf.pendingUnits = nil
+	}
+
+	sf.writeUnitInner(s, medi, source, u)
+}
+
+// correctTimestamp makes the PTS of a unit non-negative and monotonic,
+// and applies the same correction to its RTP packets.
+func (sf *streamFormat) correctTimestamp(u unit.Unit) {
+	pts, corrected := sf.timestampCorrector.process(u.GetPTS())
+
+	if corrected {
+		sf.timestampCorrectionLogger.Log(logger.Debug, "timestamps corrected (%d clamped, %d discontinuities)",
+			sf.timestampCorrector.clamped, sf.timestampCorrector.discontinuities)
+	}
+
+	delta := pts - u.GetPTS()
+	if delta == 0 {
+		return
+	}
+
+	u.SetPTS(pts)
+
+	for _, pkt := range u.GetRTPPackets() {
+		pkt.Timestamp += uint32(delta)
+	}
 }
 
-func (sf *streamFormat) writeUnitInner(s *Stream, medi *description.Media, u unit.Unit) {
+// writeUnitInner routes a unit to readers.
+// source is the format of the publisher, that differs from the one of the stream
+// when the stream has been taken over by another publisher.
+func (sf *streamFormat) writeUnitInner(s *Stream, medi *description.Media, source format.Format, u unit.Unit) {
+	now := time.Now()
+
+	atomic.StoreInt64(sf.lastReceived, now.UnixNano())
+
+	if sf.timestampCorrector != nil {
+		sf.correctTimestamp(u)
+	}
+
+	sf.reorderMeter.process(u)
+
+	sf.continuity.process(source, u.GetRTPPackets(), now)
+
 	size := unitSize(u)
 
 	atomic.AddUint64(s.bytesReceived, size)
 
+	if sf.gopCache != nil {
+		sf.gopCache.push(u, size)
+
+		if sf.gopCache.units != nil {
+			s.setKeyFrameReceived()
+		}
+	}
+
+	// the stream mutex is already locked, therefore the requester is called directly
+	if sf.keyFramePacer != nil && sf.keyFramePacer.process(u) && s.keyFrameRequester != nil {
+		s.keyFrameRequester()
+	}
+
+	if sf.rtcpSender != nil {
+		// server clock is used when the source doesn't provide NTP timestamps
+		ntp := u.GetNTP()
+		if ntp.IsZero() {
+			ntp = now
+		}
+
+		for _, pkt := range u.GetRTPPackets() {
+			sf.rtcpSender.ProcessPacket(pkt, ntp, sf.format.PTSEqualsDTS(pkt))
+		}
+	}
+
 	if s.rtspStream != nil {
 		for _, pkt := range u.GetRTPPackets() {
 			s.rtspStream.WritePacketRTPWithNTP(medi, pkt, u.GetNTP()) //nolint:errcheck
@@ -1,6 +1,8 @@
 package stream
 
 import (
+	"time"
+
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
 
@@ -14,6 +16,8 @@ type streamMedia struct {
 func newStreamMedia(udpMaxPayloadSize int,
 	medi *description.Media,
 	generateRTPPackets bool,
+	jitterBufferDelay time.Duration,
+	maxTimestampCorrection time.Duration,
 	decodeErrLogger logger.Writer,
 ) (*streamMedia, error) {
 	sm := &streamMedia{
@@ -22,12 +26,14 @@ func newStreamMedia(udpMaxPayloadSize int,
 
 	for _, forma := range medi.Formats {
 		sf := &streamFormat{
-			udpMaxPayloadSize:  udpMaxPayloadSize,
-			format:             forma,
-			generateRTPPackets: generateRTPPackets,
-			decodeErrLogger:    decodeErrLogger,
+			udpMaxPayloadSize:      udpMaxPayloadSize,
+			format:                 forma,
+			generateRTPPackets:     generateRTPPackets,
+			decodeErrLogger:        decodeErrLogger,
+			jitterBufferDelay:      jitterBufferDelay,
+			maxTimestampCorrection: maxTimestampCorrection,
 		}
-		err := sf.initialize()
+		err := sf.initialize(medi)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,309 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func TestStreamTracksLastReceived(t *testing.T) {
+	forma0 := &format.Generic{PayloadTyp: 96, RTPMa: "private/90000"}
+	forma1 := &format.Generic{PayloadTyp: 97, RTPMa: "private/90000"}
+	require.NoError(t, forma0.Init())
+	require.NoError(t, forma1.Init())
+
+	medi0 := &description.Media{Type: "application", Formats: []format.Format{forma0}}
+	medi1 := &description.Media{Type: "application", Formats: []format.Format{forma1}}
+
+	strm, err := New(512, 1460, &description.Session{Medias: []*description.Media{medi0, medi1}},
+		false, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	lr := strm.TracksLastReceived()
+	require.Equal(t, 2, len(lr))
+	require.True(t, lr[0].IsZero())
+	require.True(t, lr[1].IsZero())
+
+	strm.WriteRTPPacket(medi0, forma0, &rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 96},
+		Payload: []byte{1, 2, 3, 4},
+	}, time.Now(), 0)
+
+	lr = strm.TracksLastReceived()
+	require.False(t, lr[0].IsZero())
+	require.True(t, lr[1].IsZero())
+
+	time.Sleep(10 * time.Millisecond)
+
+	strm.WriteRTPPacket(medi1, forma1, &rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 97},
+		Payload: []byte{1, 2, 3, 4},
+	}, time.Now(), 0)
+
+	lr2 := strm.TracksLastReceived()
+	require.Equal(t, lr[0], lr2[0])
+	require.True(t, lr2[1].After(lr2[0]))
+}
+
+func TestStreamReaderMedias(t *testing.T) {
+	medias := make([]*description.Media, 3)
+	for i := range medias {
+		forma := &format.Generic{PayloadTyp: 96, RTPMa: "private/90000"}
+		require.NoError(t, forma.Init())
+		medias[i] = &description.Media{Type: "application", Formats: []format.Format{forma}}
+	}
+
+	strm, err := New(512, 1460, &description.Session{Medias: medias}, false, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	require.Equal(t, medias, strm.ReaderMedias(true))
+
+	strm.SetProxyMedia(medias[0], medias[2])
+
+	require.Equal(t, []*description.Media{medias[0], medias[1]}, strm.ReaderMedias(false))
+	require.Equal(t, []*description.Media{medias[2], medias[1]}, strm.ReaderMedias(true))
+}
+
+func TestStreamStartReaderFromKeyFrame(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	strm, err := New(512, 1460, &description.Session{Medias: []*description.Media{medi}},
+		true, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	keyFrameRequested := make(chan struct{}, 1)
+	strm.SetKeyFrameRequester(func() {
+		keyFrameRequested <- struct{}{}
+	})
+
+	// the cache is empty, therefore a key frame is requested
+	r := nilLogger{}
+	strm.AddReader(r, medi, forma, func(unit.Unit) error {
+		return nil
+	})
+	strm.StartReaderFromKeyFrame(r)
+
+	select {
+	case <-keyFrameRequested:
+	case <-time.After(2 * time.Second):
+		t.Errorf("key frame not requested")
+	}
+
+	strm.RemoveReader(r)
+
+	for i, au := range [][][]byte{
+		{{1, 1}}, // non-IDR, discarded
+		{{5, 1}}, // IDR
+		{{1, 2}}, // non-IDR
+		{{1, 3}}, // non-IDR
+	} {
+		strm.WriteUnit(medi, forma, &unit.H264{
+			Base: unit.Base{PTS: int64(i) * 3000},
+			AU:   au,
+		})
+	}
+
+	received := make(chan [][]byte, 10)
+
+	strm.AddReader(r, medi, forma, func(u unit.Unit) error {
+		received <- u.(*unit.H264).AU
+		return nil
+	})
+	strm.StartReaderFromKeyFrame(r)
+	defer strm.RemoveReader(r)
+
+	for _, ca := range [][][]byte{
+		{{5, 1}},
+		{{1, 2}},
+		{{1, 3}},
+	} {
+		select {
+		case au := <-received:
+			require.Equal(t, ca, au)
+		case <-time.After(2 * time.Second):
+			t.Errorf("unit not received")
+			return
+		}
+	}
+
+	select {
+	case <-keyFrameRequested:
+		t.Errorf("unexpected key frame request")
+	default:
+	}
+}
+
+func TestStreamKeyFrameReceived(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	strm, err := New(512, 1460, &description.Session{Medias: []*description.Media{medi}},
+		true, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	strm.WriteUnit(medi, forma, &unit.H264{
+		AU: [][]byte{{1, 1}}, // non-IDR
+	})
+
+	select {
+	case <-strm.KeyFrameReceived():
+		t.Errorf("unexpected key frame")
+	default:
+	}
+
+	strm.WriteUnit(medi, forma, &unit.H264{
+		Base: unit.Base{PTS: 3000},
+		AU:   [][]byte{{5, 1}}, // IDR
+	})
+
+	select {
+	case <-strm.KeyFrameReceived():
+	default:
+		t.Errorf("key frame not received")
+	}
+
+	// streams without video tracks do not need to wait for a key frame
+	audio := &format.Opus{
+		PayloadTyp:   96,
+		ChannelCount: 2,
+	}
+
+	strm2, err := New(512, 1460, &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeAudio,
+		Formats: []format.Format{audio},
+	}}}, true, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm2.Close()
+
+	select {
+	case <-strm2.KeyFrameReceived():
+	default:
+		t.Errorf("key frame not received")
+	}
+}
+
+func TestStreamTargetKeyFrameInterval(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+
+	strm, err := New(512, 1460, &description.Session{Medias: []*description.Media{medi}},
+		true, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	requests := 0
+	strm.SetKeyFrameRequester(func() {
+		requests++
+	})
+
+	strm.SetTargetKeyFrameInterval(2 * time.Second)
+
+	for _, ca := range []struct {
+		pts      float64
+		idr      bool
+		requests int
+	}{
+		{0, false, 0}, // key frames are not requested before the first one
+		{0.5, true, 0},
+		{2, false, 0},
+		{2.5, false, 1},
+		{4, false, 1},
+		{4.5, false, 2}, // the publisher ignored the request
+		{5, true, 2},
+		{6.5, false, 2},
+		{7, false, 3},
+	} {
+		au := [][]byte{{1, 1}} // non-IDR
+		if ca.idr {
+			au = [][]byte{{5, 1}} // IDR
+		}
+
+		strm.WriteUnit(medi, forma, &unit.H264{
+			Base: unit.Base{PTS: int64(ca.pts * 90000)},
+			AU:   au,
+		})
+
+		require.Equal(t, ca.requests, requests)
+	}
+}
+
+func TestStreamTracksReorderDepth(t *testing.T) {
+	forma := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+	medi := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{forma},
+	}
+	audio := &format.Opus{
+		PayloadTyp:   97,
+		ChannelCount: 2,
+	}
+	medi2 := &description.Media{
+		Type:    description.MediaTypeAudio,
+		Formats: []format.Format{audio},
+	}
+
+	strm, err := New(512, 1460, &description.Session{Medias: []*description.Media{medi, medi2}},
+		true, 0, 0, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	for _, ca := range []struct {
+		pts int64
+		idr bool
+	}{
+		{0, true},
+		{9000, false},
+		{3000, false},
+		{6000, false},
+		{18000, false},
+		{-1000000, false}, // discontinuity
+		{-997000, false},
+		{-2000000, true},
+		{-2000000 + 30000, false},
+		{-2000000 + 3000, false}, // reordering after a random access unit
+	} {
+		au := [][]byte{{1, 1}} // non-IDR
+		if ca.idr {
+			au = [][]byte{{5, 1}} // IDR
+		}
+
+		strm.WriteUnit(medi, forma, &unit.H264{
+			Base: unit.Base{PTS: ca.pts},
+			AU:   au,
+		})
+	}
+
+	require.Equal(t, []time.Duration{300 * time.Millisecond, 0}, strm.TracksReorderDepth())
+}
@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+)
+
+// formatCanReorder returns whether frames of a format can be presented
+// in a different order than the one they are decoded, i.e. B-frames.
+func formatCanReorder(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.H264, *format.H265, *format.MPEG4Video, *format.MPEG1Video:
+		return true
+	}
+	return false
+}
+
+// timestampCorrector makes timestamps of a track non-negative and monotonic.
+// A timestamp that is lower than the previous one is clamped when the difference
+// is within the maximum correction, otherwise the decrease is considered a discontinuity
+// and subsequent timestamps are offset in order to continue from the previous one.
+// Timestamps of formats that support frame reordering are allowed to decrease
+// within the maximum correction.
+type timestampCorrector struct {
+	maxCorrection int64
+	canReorder    bool
+
+	initialized     bool
+	offset          int64
+	maxPTS          int64
+	clamped         uint64
+	discontinuities uint64
+}
+
+func newTimestampCorrector(maxCorrection time.Duration, forma format.Format) *timestampCorrector {
+	return &timestampCorrector{
+		maxCorrection: int64(maxCorrection) * int64(forma.ClockRate()) / int64(time.Second),
+		canReorder:    formatCanReorder(forma),
+	}
+}
+
+// process returns the corrected timestamp and whether a new correction has been performed.
+func (c *timestampCorrector) process(pts int64) (int64, bool) {
+	pts += c.offset
+	corrected := false
+
+	if c.initialized && c.canReorder && pts < (c.maxPTS-c.maxCorrection) {
+		c.offset += c.maxPTS - pts
+		pts = c.maxPTS
+		c.discontinuities++
+		corrected = true
+	}
+
+	var floor int64
+	if c.initialized && !c.canReorder {
+		floor = c.maxPTS
+	}
+
+	if pts < floor {
+		diff := floor - pts
+		if diff > c.maxCorrection {
+			c.offset += diff
+			c.discontinuities++
+		} else {
+			c.clamped++
+		}
+		pts = floor
+		corrected = true
+	}
+
+	c.initialized = true
+
+	if pts > c.maxPTS {
+		c.maxPTS = pts
+	}
+
+	return pts, corrected
+}
@@ -0,0 +1,122 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+func TestTimestampCorrector(t *testing.T) {
+	for _, ca := range []struct {
+		name     string
+		forma    format.Format
+		in       []int64
+		out      []int64
+		clamped  uint64
+		discont  uint64
+		modified []bool
+	}{
+		{
+			"audio",
+			&format.Opus{PayloadTyp: 96, ChannelCount: 2},
+			[]int64{-100, 860, 1820, 1500, 2780, -50000, -49040},
+			[]int64{0, 860, 1820, 1820, 2780, 2780, 3740},
+			2,
+			1,
+			[]bool{true, false, false, true, false, true, false},
+		},
+		{
+			"video with reordering",
+			&format.H264{PayloadTyp: 96, PacketizationMode: 1},
+			[]int64{0, 9000, 3000, 6000, 18000, -90000000, -89991000},
+			[]int64{0, 9000, 3000, 6000, 18000, 18000, 27000},
+			0,
+			1,
+			[]bool{false, false, false, false, false, true, false},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			c := newTimestampCorrector(time.Second, ca.forma)
+
+			for i, pts := range ca.in {
+				out, corrected := c.process(pts)
+				require.Equal(t, ca.out[i], out)
+				require.Equal(t, ca.modified[i], corrected)
+			}
+
+			require.Equal(t, ca.clamped, c.clamped)
+			require.Equal(t, ca.discont, c.discontinuities)
+		})
+	}
+}
+
+func TestStreamTimestampCorrection(t *testing.T) {
+	forma := &format.Opus{PayloadTyp: 96, ChannelCount: 2}
+	medi := &description.Media{Type: description.MediaTypeAudio, Formats: []format.Format{forma}}
+
+	strm, err := New(512, 1460, &description.Session{Medias: []*description.Media{medi}},
+		true, 0, time.Second, 0, nilLogger{})
+	require.NoError(t, err)
+	defer strm.Close()
+
+	type timestamps struct {
+		pts int64
+		rtp uint32
+	}
+
+	received := make(chan timestamps, 100)
+
+	r := nilLogger{}
+	strm.AddReader(r, medi, forma, func(u unit.Unit) error {
+		received <- timestamps{u.GetPTS(), u.GetRTPPackets()[0].Timestamp}
+		return nil
+	})
+	strm.StartReader(r)
+	defer strm.RemoveReader(r)
+
+	// jittery and negative timestamps, followed by a reset
+	in := []int64{-480, 960, 800, 2880, 2000, 4800, -96000, -95040}
+
+	for _, pts := range in {
+		strm.WriteUnit(medi, forma, &unit.Opus{
+			Base: unit.Base{
+				PTS: pts,
+			},
+			Packets: [][]byte{{1, 2, 3}},
+		})
+	}
+
+	var first timestamps
+	var prev timestamps
+
+	for i := range in {
+		var cur timestamps
+
+		select {
+		case cur = <-received:
+		case <-time.After(2 * time.Second):
+			t.Errorf("timed out waiting for unit %d", i)
+			return
+		}
+
+		require.GreaterOrEqual(t, cur.pts, int64(0))
+
+		if i == 0 {
+			first = cur
+		} else {
+			require.GreaterOrEqual(t, cur.pts, prev.pts)
+		}
+
+		// RTP timestamps are corrected too
+		require.Equal(t, uint32(cur.pts-first.pts), cur.rtp-first.rtp)
+
+		prev = cur
+	}
+
+	require.Equal(t, int64(4800+960), prev.pts)
+}
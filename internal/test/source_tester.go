@@ -69,6 +69,9 @@ func (t *SourceTester) SetReady(req defs.PathSourceStaticSetReadyReq) defs.PathS
 		1460,
 		req.Desc,
 		req.GenerateRTPPackets,
+		0,
+		0,
+		0,
 		t,
 	)
 
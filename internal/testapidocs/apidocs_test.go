@@ -36,6 +36,22 @@ func TestAPIDocs(t *testing.T) {
 			"AuthInternalUserPermission",
 			conf.AuthInternalUserPermission{},
 		},
+		{
+			"ConfigDiff",
+			defs.APIConfigDiff{},
+		},
+		{
+			"ConfigDiffField",
+			defs.APIConfigDiffField{},
+		},
+		{
+			"ConfigDiffPath",
+			defs.APIConfigDiffPath{},
+		},
+		{
+			"DebugResources",
+			defs.APIDebugResources{},
+		},
 		{
 			"GlobalConf",
 			conf.Conf{},
@@ -64,6 +80,46 @@ func TestAPIDocs(t *testing.T) {
 			"PathReader",
 			defs.APIPathSourceOrReader{},
 		},
+		{
+			"PathEvent",
+			defs.APIPathEvent{},
+		},
+		{
+			"PathEventList",
+			defs.APIPathEventList{},
+		},
+		{
+			"PathHistorySample",
+			defs.APIPathHistorySample{},
+		},
+		{
+			"PathHistory",
+			defs.APIPathHistory{},
+		},
+		{
+			"PathSRTRelay",
+			defs.APIPathSRTRelay{},
+		},
+		{
+			"PathPublisherLimit",
+			defs.APIPathPublisherLimit{},
+		},
+		{
+			"PathCounters",
+			defs.APIPathCounters{},
+		},
+		{
+			"PathReaderHealth",
+			defs.APIPathReaderHealth{},
+		},
+		{
+			"PathSignURLReq",
+			defs.APIPathSignURLReq{},
+		},
+		{
+			"PathSignedURL",
+			defs.APIPathSignedURL{},
+		},
 		{
 			"HLSMuxer",
 			defs.APIHLSMuxer{},
@@ -116,6 +172,10 @@ func TestAPIDocs(t *testing.T) {
 			"SRTConnList",
 			defs.APISRTConnList{},
 		},
+		{
+			"SRTListenerStats",
+			defs.APISRTListenerStats{},
+		},
 		{
 			"WebRTCSession",
 			defs.APIWebRTCSession{},
@@ -140,7 +200,8 @@ func TestAPIDocs(t *testing.T) {
 			for i := 0; i < ty.NumField(); i++ {
 				sf := ty.Field(i)
 				js := sf.Tag.Get("json")
-				if js != "-" && js != "paths" && js != "pathDefaults" && !strings.Contains(js, ",omitempty") {
+				if js != "-" && !strings.Contains(js, ",omitempty") &&
+					(ca.yamlKey != "GlobalConf" || (js != "paths" && js != "pathDefaults")) {
 					key2 = append(key2, js)
 				}
 			}
@@ -0,0 +1,193 @@
+package transcoder
+
+import (
+	"bufio"
+	"encoding/binary"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h265"
+	"github.com/bluenviron/mediacommon/pkg/codecs/vp9"
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+const (
+	ivfFileHeaderSize  = 32
+	ivfFrameHeaderSize = 12
+)
+
+func vp8IsKeyFrame(frame []byte) bool {
+	return len(frame) >= 10 && (frame[0]&0x01) == 0
+}
+
+func vp8Size(frame []byte) (int, int) {
+	return int(binary.LittleEndian.Uint16(frame[6:]) & 0x3FFF),
+		int(binary.LittleEndian.Uint16(frame[8:]) & 0x3FFF)
+}
+
+// ivfWriter writes VP8 and VP9 frames in the IVF format.
+type ivfWriter struct {
+	bw     *bufio.Writer
+	fourCC string
+
+	headerWritten bool
+}
+
+func (w *ivfWriter) writeFrame(pts int64, width int, height int, frame []byte) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+
+		header := make([]byte, ivfFileHeaderSize)
+		copy(header[0:], "DKIF")
+		binary.LittleEndian.PutUint16(header[4:], 0)
+		binary.LittleEndian.PutUint16(header[6:], ivfFileHeaderSize)
+		copy(header[8:], w.fourCC)
+		binary.LittleEndian.PutUint16(header[12:], uint16(width))
+		binary.LittleEndian.PutUint16(header[14:], uint16(height))
+		binary.LittleEndian.PutUint32(header[16:], 90000) // time base denominator
+		binary.LittleEndian.PutUint32(header[20:], 1)     // time base numerator
+
+		_, err := w.bw.Write(header)
+		if err != nil {
+			return err
+		}
+	}
+
+	header := make([]byte, ivfFrameHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(frame)))
+	binary.LittleEndian.PutUint64(header[4:], uint64(pts))
+
+	_, err := w.bw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.bw.Write(frame)
+	if err != nil {
+		return err
+	}
+
+	return w.bw.Flush()
+}
+
+// setupInput adds a reader to the stream that writes the source track into bw,
+// in MPEG-TS format for H264 and H265 and in IVF format for VP8 and VP9.
+// Writing starts from the first random access unit.
+func setupInput(
+	strea *stream.Stream,
+	reader stream.Reader,
+	medi *description.Media,
+	forma format.Format,
+	bw *bufio.Writer,
+) {
+	switch forma.(type) {
+	case *format.H265: //nolint:dupl
+		track := &mcmpegts.Track{Codec: &mcmpegts.CodecH265{}}
+		w := mcmpegts.NewWriter(bw, []*mcmpegts.Track{track})
+		var dtsExtractor *h265.DTSExtractor2
+
+		strea.AddReader(reader, medi, forma, func(u unit.Unit) error {
+			tunit := u.(*unit.H265)
+			if tunit.AU == nil {
+				return nil
+			}
+
+			randomAccess := h265.IsRandomAccess(tunit.AU)
+
+			if dtsExtractor == nil {
+				if !randomAccess {
+					return nil
+				}
+				dtsExtractor = h265.NewDTSExtractor2()
+			}
+
+			dts, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
+			if err != nil {
+				return err
+			}
+
+			err = w.WriteH265(track, tunit.PTS, dts, randomAccess, tunit.AU)
+			if err != nil {
+				return err
+			}
+			return bw.Flush()
+		})
+
+	case *format.H264: //nolint:dupl
+		track := &mcmpegts.Track{Codec: &mcmpegts.CodecH264{}}
+		w := mcmpegts.NewWriter(bw, []*mcmpegts.Track{track})
+		var dtsExtractor *h264.DTSExtractor2
+
+		strea.AddReader(reader, medi, forma, func(u unit.Unit) error {
+			tunit := u.(*unit.H264)
+			if tunit.AU == nil {
+				return nil
+			}
+
+			idrPresent := h264.IDRPresent(tunit.AU)
+
+			if dtsExtractor == nil {
+				if !idrPresent {
+					return nil
+				}
+				dtsExtractor = h264.NewDTSExtractor2()
+			}
+
+			dts, err := dtsExtractor.Extract(tunit.AU, tunit.PTS)
+			if err != nil {
+				return err
+			}
+
+			err = w.WriteH264(track, tunit.PTS, dts, idrPresent, tunit.AU)
+			if err != nil {
+				return err
+			}
+			return bw.Flush()
+		})
+
+	case *format.VP8:
+		w := &ivfWriter{bw: bw, fourCC: "VP80"}
+
+		strea.AddReader(reader, medi, forma, func(u unit.Unit) error {
+			tunit := u.(*unit.VP8)
+			if tunit.Frame == nil {
+				return nil
+			}
+
+			if !w.headerWritten {
+				if !vp8IsKeyFrame(tunit.Frame) {
+					return nil
+				}
+				width, height := vp8Size(tunit.Frame)
+				return w.writeFrame(tunit.PTS, width, height, tunit.Frame)
+			}
+
+			return w.writeFrame(tunit.PTS, 0, 0, tunit.Frame)
+		})
+
+	case *format.VP9:
+		w := &ivfWriter{bw: bw, fourCC: "VP90"}
+
+		strea.AddReader(reader, medi, forma, func(u unit.Unit) error {
+			tunit := u.(*unit.VP9)
+			if tunit.Frame == nil {
+				return nil
+			}
+
+			if !w.headerWritten {
+				var h vp9.Header
+				err := h.Unmarshal(tunit.Frame)
+				if err != nil || h.NonKeyFrame || h.ShowExistingFrame {
+					return nil //nolint:nilerr
+				}
+				return w.writeFrame(tunit.PTS, h.Width(), h.Height(), tunit.Frame)
+			}
+
+			return w.writeFrame(tunit.PTS, 0, 0, tunit.Frame)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package transcoder contains the transcoder.
+package transcoder
+
+import (
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
+	"github.com/bluenviron/mediamtx/internal/stream"
+)
+
+func isSourceFormat(forma format.Format) bool {
+	switch forma.(type) {
+	case *format.H264, *format.H265, *format.VP8, *format.VP9:
+		return true
+	}
+	return false
+}
+
+func isTargetCodec(forma format.Format, codec conf.TranscodeCodec) bool {
+	switch forma.(type) {
+	case *format.H264:
+		return codec == conf.TranscodeCodecH264
+
+	case *format.H265:
+		return codec == conf.TranscodeCodecH265
+	}
+	return false
+}
+
+func newTargetFormat(codec conf.TranscodeCodec) format.Format {
+	switch codec {
+	case conf.TranscodeCodecH265:
+		return &format.H265{
+			PayloadTyp: 96,
+		}
+
+	default:
+		return &format.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+		}
+	}
+}
+
+// findSourceTrack returns the first video track, if it can be transcoded.
+func findSourceTrack(desc *description.Session) (*description.Media, format.Format) {
+	for _, medi := range desc.Medias {
+		if medi.Type != description.MediaTypeVideo {
+			continue
+		}
+
+		for _, forma := range medi.Formats {
+			if isSourceFormat(forma) {
+				return medi, forma
+			}
+		}
+
+		return nil, nil
+	}
+
+	return nil, nil
+}
+
+func appendTargetTrack(
+	desc *description.Session,
+	codec conf.TranscodeCodec,
+) (*description.Session, *description.Media) {
+	targetMedia := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{newTargetFormat(codec)},
+	}
+
+	newDesc := *desc
+	newDesc.Medias = append(append([]*description.Media(nil), desc.Medias...), targetMedia)
+
+	return &newDesc, targetMedia
+}
+
+// ExtendDesc looks for the first video track that can be transcoded into the given codec.
+// If found, it returns the track and a copy of the description with an additional track,
+// in which the transcoded stream is written.
+// Otherwise, including when the video track is already encoded with the given codec,
+// it returns a nil description.
+func ExtendDesc(
+	desc *description.Session,
+	codec conf.TranscodeCodec,
+) (*description.Session, *description.Media, format.Format, *description.Media) {
+	sourceMedia, sourceFormat := findSourceTrack(desc)
+	if sourceMedia == nil {
+		return nil, nil, nil, nil
+	}
+
+	for _, forma := range sourceMedia.Formats {
+		if isTargetCodec(forma, codec) {
+			return nil, nil, nil, nil
+		}
+	}
+
+	newDesc, targetMedia := appendTargetTrack(desc, codec)
+
+	return newDesc, sourceMedia, sourceFormat, targetMedia
+}
+
+// ExtendDescProxy looks for the first video track that can be transcoded.
+// If found, it returns the track and a copy of the description with an additional H264 track,
+// in which the downscaled stream is written. The additional track is added
+// even when the video track is already encoded with H264.
+// Otherwise, it returns a nil description.
+func ExtendDescProxy(
+	desc *description.Session,
+) (*description.Session, *description.Media, format.Format, *description.Media) {
+	sourceMedia, sourceFormat := findSourceTrack(desc)
+	if sourceMedia == nil {
+		return nil, nil, nil, nil
+	}
+
+	newDesc, targetMedia := appendTargetTrack(desc, conf.TranscodeCodecH264)
+
+	return newDesc, sourceMedia, sourceFormat, targetMedia
+}
+
+// Transcoder sends a track of a stream to an external encoder
+// and writes the encoded track into another track of the same stream.
+type Transcoder struct {
+	Command         string
+	ExternalCmdPool *externalcmd.Pool
+	ExternalCmdEnv  externalcmd.Environment
+	Stream          *stream.Stream
+	SourceMedia     *description.Media
+	SourceFormat    format.Format
+	TargetMedia     *description.Media
+	Parent          logger.Writer
+
+	// name of the transcoder in logs (optional).
+	Name string
+
+	restartPause time.Duration
+
+	currentInstance *transcoderInstance
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+// Initialize initializes Transcoder.
+func (t *Transcoder) Initialize() {
+	if t.Name == "" {
+		t.Name = "transcoder"
+	}
+	if t.restartPause == 0 {
+		t.restartPause = 2 * time.Second
+	}
+
+	t.terminate = make(chan struct{})
+	t.done = make(chan struct{})
+
+	t.Log(logger.Info, "transcoding %s into %s",
+		t.SourceFormat.Codec(), t.TargetMedia.Formats[0].Codec())
+
+	t.currentInstance = &transcoderInstance{
+		t: t,
+	}
+	t.currentInstance.initialize()
+
+	go t.run()
+}
+
+// Log implements logger.Writer.
+func (t *Transcoder) Log(level logger.Level, format string, args ...interface{}) {
+	t.Parent.Log(level, "["+t.Name+"] "+format, args...)
+}
+
+// Close closes the transcoder.
+func (t *Transcoder) Close() {
+	t.Log(logger.Info, "transcoding stopped")
+	close(t.terminate)
+	<-t.done
+}
+
+func (t *Transcoder) run() {
+	defer close(t.done)
+
+	for {
+		select {
+		case <-t.currentInstance.done:
+			t.currentInstance.close()
+		case <-t.terminate:
+			t.currentInstance.close()
+			return
+		}
+
+		select {
+		case <-time.After(t.restartPause):
+		case <-t.terminate:
+			return
+		}
+
+		t.currentInstance = &transcoderInstance{
+			t: t,
+		}
+		t.currentInstance.initialize()
+	}
+}
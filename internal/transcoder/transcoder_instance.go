@@ -0,0 +1,189 @@
+package transcoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+	"github.com/pion/rtp"
+
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/logger"
+)
+
+type rtpEncoder interface {
+	Encode([][]byte) ([]*rtp.Packet, error)
+}
+
+type transcoderInstance struct {
+	t *Transcoder
+
+	stdinReader  *io.PipeReader
+	stdoutReader *io.PipeReader
+	stdoutWriter *io.PipeWriter
+	cmd          *externalcmd.Cmd
+	cmdExit      chan error
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+// Log implements logger.Writer.
+func (ti *transcoderInstance) Log(level logger.Level, format string, args ...interface{}) {
+	ti.t.Log(level, format, args...)
+}
+
+func (ti *transcoderInstance) initialize() {
+	var stdinWriter *io.PipeWriter
+	ti.stdinReader, stdinWriter = io.Pipe()
+	ti.stdoutReader, ti.stdoutWriter = io.Pipe()
+	ti.cmdExit = make(chan error, 1)
+
+	ti.terminate = make(chan struct{})
+	ti.done = make(chan struct{})
+
+	setupInput(ti.t.Stream, ti, ti.t.SourceMedia, ti.t.SourceFormat, bufio.NewWriter(stdinWriter))
+	ti.t.Stream.StartReader(ti)
+
+	ti.cmd = externalcmd.NewPipedCmd(
+		ti.t.ExternalCmdPool,
+		ti.t.Command,
+		ti.t.ExternalCmdEnv,
+		ti.stdinReader,
+		ti.stdoutWriter,
+		func(err error) {
+			ti.cmdExit <- err
+		})
+
+	go ti.run()
+}
+
+func (ti *transcoderInstance) close() {
+	close(ti.terminate)
+	<-ti.done
+}
+
+func (ti *transcoderInstance) run() {
+	defer close(ti.done)
+
+	outputErr := make(chan error)
+	go func() {
+		outputErr <- ti.runOutput()
+	}()
+
+	outputDone := false
+
+	select {
+	case err := <-ti.t.Stream.ReaderError(ti):
+		ti.Log(logger.Error, err.Error())
+
+	case err := <-outputErr:
+		ti.Log(logger.Error, err.Error())
+		outputDone = true
+
+	case err := <-ti.cmdExit:
+		ti.Log(logger.Error, "encoder exited: %v", err)
+
+	case <-ti.terminate:
+	}
+
+	ti.cmd.Close()
+
+	// unblock pending reads and writes
+	ti.stdinReader.Close()
+	ti.stdoutReader.Close()
+	ti.stdoutWriter.Close()
+
+	ti.t.Stream.RemoveReader(ti)
+
+	if !outputDone {
+		<-outputErr
+	}
+}
+
+// runOutput reads the output of the encoder
+// and writes it into the target track.
+func (ti *transcoderInstance) runOutput() error {
+	r, err := mcmpegts.NewReader(bufio.NewReader(ti.stdoutReader))
+	if err != nil {
+		return err
+	}
+
+	decodeErrLogger := logger.NewLimitedLogger(ti)
+
+	r.OnDecodeError(func(err error) {
+		decodeErrLogger.Log(logger.Warn, err.Error())
+	})
+
+	medi := ti.t.TargetMedia
+	forma := medi.Formats[0]
+	td := mcmpegts.NewTimeDecoder2()
+
+	writeAU := func(enc rtpEncoder, pts int64, au [][]byte) error {
+		pts = td.Decode(pts)
+
+		pkts, err := enc.Encode(au)
+		if err != nil {
+			return err
+		}
+
+		ntp := time.Now()
+
+		for _, pkt := range pkts {
+			pkt.Timestamp = uint32(pts) // no conversion is needed since clock rate is 90khz in both MPEG-TS and RTSP
+			ti.t.Stream.WriteRTPPacket(medi, forma, pkt, ntp, pts)
+		}
+
+		return nil
+	}
+
+	found := false
+
+	for _, track := range r.Tracks() {
+		switch forma := forma.(type) {
+		case *format.H264:
+			if _, ok := track.Codec.(*mcmpegts.CodecH264); ok {
+				enc, err := forma.CreateEncoder()
+				if err != nil {
+					return err
+				}
+
+				r.OnDataH264(track, func(pts int64, _ int64, au [][]byte) error {
+					return writeAU(enc, pts, au)
+				})
+				found = true
+			}
+
+		case *format.H265:
+			if _, ok := track.Codec.(*mcmpegts.CodecH265); ok {
+				enc, err := forma.CreateEncoder()
+				if err != nil {
+					return err
+				}
+
+				r.OnDataH265(track, func(pts int64, _ int64, au [][]byte) error {
+					return writeAU(enc, pts, au)
+				})
+				found = true
+			}
+		}
+
+		if found {
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("the encoder output doesn't contain a %s track", forma.Codec())
+	}
+
+	for {
+		err = r.Read()
+		if err != nil {
+			return err
+		}
+	}
+}
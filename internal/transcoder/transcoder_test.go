@@ -0,0 +1,227 @@
+package transcoder
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	mcmpegts "github.com/bluenviron/mediacommon/pkg/formats/mpegts"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bluenviron/mediamtx/internal/conf"
+	"github.com/bluenviron/mediamtx/internal/externalcmd"
+	"github.com/bluenviron/mediamtx/internal/stream"
+	"github.com/bluenviron/mediamtx/internal/test"
+	"github.com/bluenviron/mediamtx/internal/unit"
+)
+
+var vp8KeyFrame = []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a, 0x80, 0x07, 0x38, 0x04, 0x00}
+
+func TestExtendDesc(t *testing.T) {
+	vp8Media := &description.Media{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{&format.VP8{PayloadTyp: 96}},
+	}
+	audioMedia := &description.Media{
+		Type:    description.MediaTypeAudio,
+		Formats: []format.Format{&format.Opus{PayloadTyp: 97, ChannelCount: 2}},
+	}
+
+	desc := &description.Session{Medias: []*description.Media{audioMedia, vp8Media}}
+
+	newDesc, sourceMedia, sourceFormat, targetMedia := ExtendDesc(desc, conf.TranscodeCodecH265)
+	require.NotNil(t, newDesc)
+	require.Equal(t, []*description.Media{audioMedia, vp8Media, targetMedia}, newDesc.Medias)
+	require.Equal(t, []*description.Media{audioMedia, vp8Media}, desc.Medias)
+	require.Equal(t, vp8Media, sourceMedia)
+	require.Equal(t, vp8Media.Formats[0], sourceFormat)
+	require.Equal(t, &format.H265{PayloadTyp: 96}, targetMedia.Formats[0])
+
+	newDesc, _, _, _ = ExtendDesc(&description.Session{Medias: []*description.Media{test.MediaH264}},
+		conf.TranscodeCodecH264)
+	require.Nil(t, newDesc)
+
+	newDesc, _, _, _ = ExtendDesc(&description.Session{Medias: []*description.Media{audioMedia}},
+		conf.TranscodeCodecH264)
+	require.Nil(t, newDesc)
+}
+
+func TestExtendDescProxy(t *testing.T) {
+	desc := &description.Session{Medias: []*description.Media{test.MediaMPEG4Audio, test.MediaH264}}
+
+	newDesc, sourceMedia, sourceFormat, targetMedia := ExtendDescProxy(desc)
+	require.NotNil(t, newDesc)
+	require.Equal(t, []*description.Media{test.MediaMPEG4Audio, test.MediaH264, targetMedia}, newDesc.Medias)
+	require.Equal(t, test.MediaH264, sourceMedia)
+	require.Equal(t, test.MediaH264.Formats[0], sourceFormat)
+	require.Equal(t, &format.H264{PayloadTyp: 96, PacketizationMode: 1}, targetMedia.Formats[0])
+
+	newDesc, _, _, _ = ExtendDescProxy(&description.Session{Medias: []*description.Media{test.MediaMPEG4Audio}})
+	require.Nil(t, newDesc)
+}
+
+func TestTranscoder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test encoder requires a POSIX shell")
+	}
+
+	dir, err := os.MkdirTemp("", "mediamtx-transcoder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the test encoder ignores its input and returns a pre-encoded H264 stream
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	track := &mcmpegts.Track{Codec: &mcmpegts.CodecH264{}}
+	w := mcmpegts.NewWriter(bw, []*mcmpegts.Track{track})
+
+	for i := 0; i < 3; i++ {
+		au := [][]byte{{1, 2}}
+		if i == 0 {
+			au = [][]byte{test.FormatH264.SPS, test.FormatH264.PPS, {5, 1}}
+		}
+
+		err = w.WriteH264(track, int64(i)*3000, int64(i)*3000, i == 0, au)
+		require.NoError(t, err)
+	}
+
+	err = bw.Flush()
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "output.ts"), buf.Bytes(), 0o644)
+	require.NoError(t, err)
+
+	desc := &description.Session{Medias: []*description.Media{{
+		Type:    description.MediaTypeVideo,
+		Formats: []format.Format{&format.VP8{PayloadTyp: 96}},
+	}}}
+
+	newDesc, sourceMedia, sourceFormat, targetMedia := ExtendDesc(desc, conf.TranscodeCodecH264)
+	require.NotNil(t, newDesc)
+
+	strm, err := stream.New(512, 1460, newDesc, true, 0, 0, 0, test.NilLogger)
+	require.NoError(t, err)
+	defer strm.Close()
+
+	received := make(chan [][]byte, 10)
+
+	reader := test.NilLogger
+	strm.AddReader(reader, targetMedia, targetMedia.Formats[0], func(u unit.Unit) error {
+		tunit := u.(*unit.H264)
+		if tunit.AU != nil {
+			received <- tunit.AU
+		}
+		return nil
+	})
+	strm.StartReader(reader)
+	defer strm.RemoveReader(reader)
+
+	pool := externalcmd.NewPool()
+	defer pool.Close()
+
+	tr := &Transcoder{
+		Command:         "sh -c 'cat output.ts; cat > input.ivf'",
+		ExternalCmdPool: pool,
+		ExternalCmdEnv:  externalcmd.Environment{},
+		Stream:          strm,
+		SourceMedia:     sourceMedia,
+		SourceFormat:    sourceFormat,
+		TargetMedia:     targetMedia,
+		Parent:          test.NilLogger,
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	err = os.Chdir(dir)
+	require.NoError(t, err)
+	defer os.Chdir(wd) //nolint:errcheck
+
+	tr.Initialize()
+	defer tr.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			strm.WriteUnit(sourceMedia, sourceFormat, &unit.VP8{
+				Base: unit.Base{
+					PTS: int64(i) * 4500,
+				},
+				Frame: vp8KeyFrame,
+			})
+		}
+	}()
+
+	select {
+	case au := <-received:
+		require.Equal(t, [][]byte{test.FormatH264.SPS, test.FormatH264.PPS, {5, 1}}, au)
+	case <-time.After(5 * time.Second):
+		t.Fatal("transcoded track not received")
+	}
+
+	require.Eventually(t, func() bool {
+		byts, err := os.ReadFile(filepath.Join(dir, "input.ivf"))
+		return err == nil &&
+			len(byts) >= ivfFileHeaderSize+ivfFrameHeaderSize+len(vp8KeyFrame) &&
+			bytes.HasPrefix(byts, []byte("DKIF")) &&
+			string(byts[8:12]) == "VP80"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestTranscoderRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test encoder requires a POSIX shell")
+	}
+
+	dir, err := os.MkdirTemp("", "mediamtx-transcoder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	countPath := filepath.Join(dir, "count")
+
+	newDesc, sourceMedia, sourceFormat, targetMedia := ExtendDesc(&description.Session{
+		Medias: []*description.Media{test.MediaH264},
+	}, conf.TranscodeCodecH265)
+	require.NotNil(t, newDesc)
+
+	strm, err := stream.New(512, 1460, newDesc, true, 0, 0, 0, test.NilLogger)
+	require.NoError(t, err)
+	defer strm.Close()
+
+	pool := externalcmd.NewPool()
+	defer pool.Close()
+
+	tr := &Transcoder{
+		// the test encoder crashes immediately
+		Command:         "sh -c 'echo started >> " + countPath + "; exit 1'",
+		ExternalCmdPool: pool,
+		ExternalCmdEnv:  externalcmd.Environment{},
+		Stream:          strm,
+		SourceMedia:     sourceMedia,
+		SourceFormat:    sourceFormat,
+		TargetMedia:     targetMedia,
+		Parent:          test.NilLogger,
+		restartPause:    10 * time.Millisecond,
+	}
+	tr.Initialize()
+	defer tr.Close()
+
+	require.Eventually(t, func() bool {
+		byts, err := os.ReadFile(countPath)
+		return err == nil && strings.Count(string(byts), "started") >= 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
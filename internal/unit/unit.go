@@ -17,4 +17,7 @@ type Unit interface {
 
 	// returns the PTS of the unit.
 	GetPTS() int64
+
+	// sets the PTS of the unit.
+	SetPTS(int64)
 }
@@ -0,0 +1,36 @@
+// Package waitgroup contains a wait group that can be inspected.
+package waitgroup
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WaitGroup is a sync.WaitGroup that keeps track of the number of goroutines
+// it is waiting for, in order to detect leaks.
+type WaitGroup struct {
+	wg   sync.WaitGroup
+	size int64
+}
+
+// Add adds delta to the wait group.
+func (w *WaitGroup) Add(delta int) {
+	atomic.AddInt64(&w.size, int64(delta))
+	w.wg.Add(delta)
+}
+
+// Done decrements the wait group by one.
+func (w *WaitGroup) Done() {
+	atomic.AddInt64(&w.size, -1)
+	w.wg.Done()
+}
+
+// Wait blocks until the wait group is zero.
+func (w *WaitGroup) Wait() {
+	w.wg.Wait()
+}
+
+// Size returns the number of goroutines the wait group is waiting for.
+func (w *WaitGroup) Size() int {
+	return int(atomic.LoadInt64(&w.size))
+}
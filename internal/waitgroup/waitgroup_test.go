@@ -0,0 +1,29 @@
+package waitgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitGroup(t *testing.T) {
+	var wg WaitGroup
+	require.Equal(t, 0, wg.Size())
+
+	release := make(chan struct{})
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-release
+		}()
+	}
+
+	require.Equal(t, 3, wg.Size())
+
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, 0, wg.Size())
+}